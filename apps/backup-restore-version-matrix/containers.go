@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"time"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate"
+)
+
+const backupsMountPath = "/var/lib/backups"
+
+// node is a single Weaviate container. All nodes share the same backups
+// directory on the host, so that a backup created by one version can be read
+// by any other version, but each node gets its own data directory.
+type node struct {
+	container testcontainers.Container
+	client    *weaviate.Client
+	version   string
+}
+
+func startNode(ctx context.Context, rootDir, name, version string) (*node, error) {
+	dataPath := path.Join(rootDir, "data", name)
+	backupsPath := path.Join(rootDir, "data", "backups")
+	for _, dir := range []string{dataPath, backupsPath} {
+		if err := os.MkdirAll(dir, 0o777); err != nil {
+			return nil, err
+		}
+	}
+
+	image := fmt.Sprintf("semitechnologies/weaviate:%s", version)
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		Logger: log.Default(),
+		ContainerRequest: testcontainers.ContainerRequest{
+			Name:         name,
+			Image:        image,
+			Cmd:          []string{"--host", "0.0.0.0", "--port", "8080", "--scheme", "http"},
+			ExposedPorts: []string{"8080/tcp"},
+			Env: map[string]string{
+				"QUERY_DEFAULTS_LIMIT":                    "25",
+				"AUTHENTICATION_ANONYMOUS_ACCESS_ENABLED": "true",
+				"PERSISTENCE_DATA_PATH":                   "/var/lib/weaviate",
+				"DEFAULT_VECTORIZER_MODULE":               "none",
+				"ENABLE_MODULES":                          "backup-filesystem",
+				"BACKUP_FILESYSTEM_PATH":                  backupsMountPath,
+				"CLUSTER_HOSTNAME":                        "node1",
+				"PERSISTENCE_LSM_ACCESS_STRATEGY":         os.Getenv("PERSISTENCE_LSM_ACCESS_STRATEGY"),
+			},
+			Mounts: testcontainers.Mounts(
+				testcontainers.BindMount(dataPath, "/var/lib/weaviate"),
+				testcontainers.BindMount(backupsPath, backupsMountPath),
+			),
+			WaitingFor: wait.
+				ForHTTP("/v1/.well-known/ready").
+				WithPort(nat.Port("8080")).
+				WithStatusCodeMatcher(func(status int) bool {
+					return status >= 200 && status <= 299
+				}).
+				WithStartupTimeout(60 * time.Second),
+		},
+		Started: true,
+	})
+	if err != nil {
+		if container != nil {
+			dumpLogs(container)
+			terminate(container)
+		}
+		return nil, fmt.Errorf("start %s on %s: %w", name, version, err)
+	}
+
+	httpUri, err := container.PortEndpoint(ctx, nat.Port("8080/tcp"), "")
+	if err != nil {
+		terminate(container)
+		return nil, err
+	}
+
+	client := weaviate.New(weaviate.Config{
+		Host:   httpUri,
+		Scheme: "http",
+	})
+
+	return &node{container: container, client: client, version: version}, nil
+}
+
+func (n *node) stop(ctx context.Context) error {
+	return n.container.Terminate(ctx)
+}
+
+// terminate removes a container that never became a node, so that the next
+// node can bind its data directory and port
+func terminate(container testcontainers.Container) {
+	if err := container.Terminate(context.Background()); err != nil {
+		log.Print(err)
+	}
+}
+
+func dumpLogs(container testcontainers.Container) {
+	logReader, err := container.Logs(context.Background())
+	if err != nil {
+		log.Print(err)
+		return
+	}
+
+	io.Copy(os.Stdout, logReader)
+}
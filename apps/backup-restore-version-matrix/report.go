@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+type restoreResult struct {
+	BackupVersion  string        `json:"backupVersion"`
+	RestoreVersion string        `json:"restoreVersion"`
+	Success        bool          `json:"success"`
+	Skipped        bool          `json:"skipped,omitempty"`
+	Error          string        `json:"error,omitempty"`
+	Took           time.Duration `json:"took"`
+}
+
+type matrix struct {
+	Versions []string        `json:"versions"`
+	Results  []restoreResult `json:"results"`
+
+	// BackupErrors holds why the backup of a version could not be created
+	BackupErrors map[string]string `json:"backupErrors,omitempty"`
+}
+
+func newMatrix(versions []string) *matrix {
+	return &matrix{Versions: versions, BackupErrors: map[string]string{}}
+}
+
+func (m *matrix) recordBackup(version string, err error) {
+	if err != nil {
+		m.BackupErrors[version] = err.Error()
+		log.Printf("create backup on %s failed: %v", version, err)
+	}
+}
+
+// recordSkipped records a restore that was not attempted because there is
+// no backup to restore
+func (m *matrix) recordSkipped(from, to, backupErr string) {
+	m.Results = append(m.Results, restoreResult{
+		BackupVersion:  from,
+		RestoreVersion: to,
+		Skipped:        true,
+		Error:          fmt.Sprintf("skipped, create backup on %s failed: %s", from, backupErr),
+	})
+}
+
+func (m *matrix) record(from, to string, took time.Duration, err error) {
+	res := restoreResult{
+		BackupVersion:  from,
+		RestoreVersion: to,
+		Success:        err == nil,
+		Took:           took,
+	}
+	if err != nil {
+		res.Error = err.Error()
+		log.Printf("restore of %s on %s failed: %v", from, to, err)
+	}
+
+	m.Results = append(m.Results, res)
+}
+
+func (m *matrix) lookup(from, to string) (restoreResult, bool) {
+	for _, res := range m.Results {
+		if res.BackupVersion == from && res.RestoreVersion == to {
+			return res, true
+		}
+	}
+
+	return restoreResult{}, false
+}
+
+func (m *matrix) failures() []string {
+	var out []string
+	for _, res := range m.Results {
+		if !res.Success {
+			out = append(out, fmt.Sprintf("%s -> %s: %s", res.BackupVersion,
+				res.RestoreVersion, res.Error))
+		}
+	}
+
+	return out
+}
+
+// markdown renders the matrix with the backup version as rows and the
+// restore version as columns. Combinations that were not attempted, because
+// the restore version is older than the backup version, are left empty.
+// Restores of a version whose backup failed are marked as skipped.
+func (m *matrix) markdown() string {
+	var sb strings.Builder
+
+	sb.WriteString("| backup \\ restore |")
+	for _, to := range m.Versions {
+		fmt.Fprintf(&sb, " %s |", to)
+	}
+	sb.WriteString("\n|---|")
+	for range m.Versions {
+		sb.WriteString("---|")
+	}
+	sb.WriteString("\n")
+
+	for _, from := range m.Versions {
+		fmt.Fprintf(&sb, "| %s |", from)
+		for _, to := range m.Versions {
+			res, ok := m.lookup(from, to)
+			switch {
+			case !ok:
+				sb.WriteString(" |")
+			case res.Success:
+				sb.WriteString(" ✅ |")
+			case res.Skipped:
+				sb.WriteString(" ⏭️ |")
+			default:
+				sb.WriteString(" ❌ |")
+			}
+		}
+		sb.WriteString("\n")
+	}
+
+	for _, version := range m.Versions {
+		if reason, failed := m.BackupErrors[version]; failed {
+			fmt.Fprintf(&sb, "\nbackup on %s failed: %s\n", version, reason)
+		}
+	}
+
+	return sb.String()
+}
+
+func (m *matrix) write(rootDir string) error {
+	dir := path.Join(rootDir, "report")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	asJSON, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path.Join(dir, "matrix.json"), asJSON, 0o644); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path.Join(dir, "matrix.md"), []byte(m.markdown()), 0o644)
+}
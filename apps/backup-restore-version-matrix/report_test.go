@@ -0,0 +1,24 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMatrixSkipsRestoresOfFailedBackup(t *testing.T) {
+	m := newMatrix([]string{"1.20.0", "1.21.0"})
+	m.recordBackup("1.20.0", errors.New("unknown backend"))
+	m.recordBackup("1.21.0", nil)
+	m.recordSkipped("1.20.0", "1.20.0", m.BackupErrors["1.20.0"])
+	m.recordSkipped("1.20.0", "1.21.0", m.BackupErrors["1.20.0"])
+	m.record("1.21.0", "1.21.0", 0, nil)
+
+	if failed := m.failures(); len(failed) != 2 {
+		t.Errorf("wanted both restores of 1.20.0 as failures, got %v", failed)
+	}
+	md := m.markdown()
+	if !strings.Contains(md, "| 1.20.0 | ⏭️ | ⏭️ |") || !strings.Contains(md, "backup on 1.20.0 failed") {
+		t.Errorf("wanted the skipped row and the backup failure, got:\n%s", md)
+	}
+}
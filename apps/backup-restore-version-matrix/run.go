@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/weaviate/weaviate-go-client/v4/weaviate"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate/backup"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate/graphql"
+	"github.com/weaviate/weaviate/entities/models"
+)
+
+const (
+	className       = "MatrixCollection"
+	objectsPerClass = 100
+	vectorDims      = 32
+)
+
+func main() {
+	ctx := context.Background()
+	targetW, ok := os.LookupEnv("WEAVIATE_VERSION")
+	if !ok {
+		log.Fatal("missing WEAVIATE_VERSION")
+	}
+
+	minimumW, ok := os.LookupEnv("MINIMUM_WEAVIATE_VERSION")
+	if !ok {
+		log.Fatal("missing MINIMUM_WEAVIATE_VERSION")
+	}
+
+	versions, err := buildVersionList(minimumW, targetW)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("configured minimum version is %s", minimumW)
+	log.Printf("configured target version is %s", targetW)
+	log.Printf("identified the following versions, the latest patch of every minor version: %v", versions)
+
+	rootDir, err := os.Getwd()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	m := do(ctx, rootDir, versions)
+	if err := m.write(rootDir); err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("compatibility matrix:\n%s", m.markdown())
+
+	if failed := m.failures(); len(failed) > 0 {
+		log.Fatalf("%d of %d restores failed:\n%s", len(failed), len(m.Results),
+			strings.Join(failed, "\n"))
+	}
+}
+
+// do creates one backup on every version and then restores each backup on
+// its own and every newer version. Each restore happens on a fresh node, so
+// the results are independent of each other. A version that cannot create a
+// backup is an incompatibility like a failed restore: its restores are
+// recorded as skipped and the matrix goes on with the other versions.
+func do(ctx context.Context, rootDir string, versions []string) *matrix {
+	rand.Seed(time.Now().UnixNano())
+
+	m := newMatrix(versions)
+	for _, version := range versions {
+		m.recordBackup(version, createBackupOnVersion(ctx, rootDir, version))
+	}
+
+	for i, from := range versions {
+		for _, to := range versions[i:] {
+			if reason, failed := m.BackupErrors[from]; failed {
+				m.recordSkipped(from, to, reason)
+				continue
+			}
+
+			start := time.Now()
+			err := restoreOnVersion(ctx, rootDir, from, to)
+			m.record(from, to, time.Since(start), err)
+		}
+	}
+
+	return m
+}
+
+func createBackupOnVersion(ctx context.Context, rootDir, version string) error {
+	log.Printf("creating backup on %s", version)
+
+	n, err := startNode(ctx, rootDir, nodeName("backup", version), version)
+	if err != nil {
+		return err
+	}
+	defer n.stop(ctx)
+
+	if err := createSchema(ctx, n.client); err != nil {
+		return fmt.Errorf("schema: %w", err)
+	}
+
+	if err := importObjects(ctx, n.client, version); err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+
+	res, err := n.client.Backup().Creator().
+		WithIncludeClassNames(className).
+		WithBackend(backup.BACKEND_FILESYSTEM).
+		WithBackupID(backupID(version)).
+		WithWaitForCompletion(true).
+		Do(ctx)
+	if err != nil {
+		return err
+	}
+
+	if res.Status == nil || *res.Status != models.BackupCreateResponseStatusSUCCESS {
+		return fmt.Errorf("backup did not succeed: %s", res.Error)
+	}
+
+	return nil
+}
+
+func restoreOnVersion(ctx context.Context, rootDir, from, to string) error {
+	log.Printf("restoring backup from %s on %s", from, to)
+
+	n, err := startNode(ctx, rootDir, nodeName(fmt.Sprintf("restore-%s", from), to), to)
+	if err != nil {
+		return err
+	}
+	defer n.stop(ctx)
+
+	res, err := n.client.Backup().Restorer().
+		WithIncludeClassNames(className).
+		WithBackend(backup.BACKEND_FILESYSTEM).
+		WithBackupID(backupID(from)).
+		WithWaitForCompletion(true).
+		Do(ctx)
+	if err != nil {
+		return err
+	}
+
+	if res.Status == nil || *res.Status != models.BackupRestoreResponseStatusSUCCESS {
+		return fmt.Errorf("restore did not succeed: %s", res.Error)
+	}
+
+	return verifyRestoredObjects(ctx, n.client, from)
+}
+
+func createSchema(ctx context.Context, client *weaviate.Client) error {
+	classObj := &models.Class{
+		Class: className,
+		Properties: []*models.Property{
+			{
+				DataType: []string{"string"},
+				Name:     "version",
+			},
+			{
+				DataType: []string{"int"},
+				Name:     "object_count",
+			},
+		},
+	}
+
+	return client.Schema().ClassCreator().WithClass(classObj).Do(ctx)
+}
+
+func importObjects(ctx context.Context, client *weaviate.Client, version string) error {
+	objects := make([]*models.Object, objectsPerClass)
+	for i := range objects {
+		objects[i] = &models.Object{
+			Class: className,
+			Properties: map[string]interface{}{
+				"version":      version,
+				"object_count": i,
+			},
+			Vector: randomVector(),
+		}
+	}
+
+	res, err := client.Batch().ObjectsBatcher().WithObjects(objects...).Do(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, obj := range res {
+		if obj.Result != nil && obj.Result.Errors != nil {
+			return fmt.Errorf("%v", obj.Result.Errors.Error[0].Message)
+		}
+	}
+
+	return nil
+}
+
+// verifyRestoredObjects checks that all objects are present and that the
+// vector index as well as the inverted index of the restored class work
+func verifyRestoredObjects(ctx context.Context, client *weaviate.Client, version string) error {
+	result, err := client.GraphQL().Aggregate().
+		WithClassName(className).
+		WithFields(graphql.Field{Name: "meta", Fields: []graphql.Field{{Name: "count"}}}).
+		Do(ctx)
+	if err != nil {
+		return err
+	}
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("%v", result.Errors[0])
+	}
+
+	count := result.Data["Aggregate"].(map[string]interface{})[className].([]interface{})[0].(map[string]interface{})["meta"].(map[string]interface{})["count"].(float64)
+	if int(count) != objectsPerClass {
+		return fmt.Errorf("aggregation: wanted %d, got %d", objectsPerClass, int(count))
+	}
+
+	nearVector := client.GraphQL().NearVectorArgBuilder().
+		WithVector(randomVector())
+
+	vecResult, err := client.GraphQL().Get().
+		WithClassName(className).
+		WithFields(graphql.Field{Name: "version"}).
+		WithNearVector(nearVector).
+		WithLimit(objectsPerClass).
+		Do(ctx)
+	if err != nil {
+		return err
+	}
+	if len(vecResult.Errors) > 0 {
+		return fmt.Errorf("%v", vecResult.Errors[0])
+	}
+
+	objs := vecResult.Data["Get"].(map[string]interface{})[className].([]interface{})
+	if len(objs) != objectsPerClass {
+		return fmt.Errorf("vector search: wanted %d, got %d", objectsPerClass, len(objs))
+	}
+
+	for _, obj := range objs {
+		actual := obj.(map[string]interface{})["version"].(string)
+		if actual != version {
+			return fmt.Errorf("vector search: wanted version %s, got %s", version, actual)
+		}
+	}
+
+	return nil
+}
+
+func randomVector() []float32 {
+	vec := make([]float32, vectorDims)
+	for i := range vec {
+		vec[i] = rand.Float32()
+	}
+	return vec
+}
+
+func backupID(version string) string {
+	return fmt.Sprintf("matrix-%s", sanitize(version))
+}
+
+func nodeName(prefix, version string) string {
+	return fmt.Sprintf("weaviate-matrix-%s-%s", sanitize(prefix), sanitize(version))
+}
+
+// backup ids and container names are restricted to lowercase alphanumeric
+// characters and dashes
+func sanitize(in string) string {
+	return strings.ToLower(strings.NewReplacer(".", "-", "_", "-").Replace(in))
+}
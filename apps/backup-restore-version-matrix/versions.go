@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+
+	hashicorpversion "github.com/hashicorp/go-version"
+)
+
+// buildVersionList returns the latest patch release of every minor version
+// between min and target. Testing every patch would make the matrix grow
+// quadratically without adding much signal, as the backup format is only
+// ever changed in minor releases.
+func buildVersionList(min, target string) ([]string, error) {
+	ghReleases, err := retrieveVersionListFromGH()
+	if err != nil {
+		return nil, err
+	}
+
+	versions := parseSemverList(ghReleases)
+	versions = sortSemverAndTrimToMinimum(versions, min, target)
+	versions = latestPatchPerMinor(versions)
+	list := versions.toStringList()
+
+	return append(list, target), nil
+}
+
+func retrieveVersionListFromGH() ([]string, error) {
+	// ignore pagination, for now we assume that the first page contains enough
+	// versions.
+	res, err := http.Get("https://api.github.com/repos/weaviate/weaviate/releases?per_page=100")
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	type githubRelease struct {
+		TagName string `json:"tag_name"`
+	}
+
+	resBytes, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed []githubRelease
+	if err := json.Unmarshal(resBytes, &parsed); err != nil {
+		return nil, err
+	}
+
+	out := make([]string, len(parsed))
+	for i := range parsed {
+		out[i] = parsed[i].TagName
+	}
+
+	return out, nil
+}
+
+// trim anything that's not a vx.y.z release, such as pre- and rc-releases or
+// other malformed tags
+func parseSemverList(input []string) semverList {
+	r := regexp.MustCompile(`^v([0-9]+)\.([0-9]+)\.([0-9]+)$`)
+
+	out := make(semverList, 0, len(input))
+	for _, version := range input {
+		if !r.MatchString(version) {
+			continue
+		}
+
+		ver, err := hashicorpversion.NewSemver(version)
+		if err != nil {
+			panic(fmt.Errorf("cannot parse version %q: %w", version, err))
+		}
+
+		out = append(out, semver{version: ver})
+	}
+
+	return out
+}
+
+type semver struct {
+	version *hashicorpversion.Version
+}
+
+type semverList []semver
+
+func (self semver) major() int64 {
+	return self.version.Segments64()[0]
+}
+
+func (self semver) minor() int64 {
+	return self.version.Segments64()[1]
+}
+
+// sortSemverAndTrimToMinimum sorts the list and drops everything below min
+// as well as everything that is equal to or larger than max. The max version
+// is appended separately, so that it can also be a non-release tag.
+func sortSemverAndTrimToMinimum(versions semverList, min, max string) semverList {
+	sort.Slice(versions, func(a, b int) bool {
+		return versions[a].version.LessThan(versions[b].version)
+	})
+
+	minV := hashicorpversion.Must(hashicorpversion.NewSemver(min))
+	maxV, err := hashicorpversion.NewSemver(max)
+	if err != nil {
+		// the target is not a semver, e.g. a preview tag, so there is no upper
+		// bound
+		maxV = nil
+	}
+
+	out := make(semverList, 0, len(versions))
+	for _, version := range versions {
+		if version.version.LessThan(minV) {
+			continue
+		}
+		if maxV != nil && version.version.GreaterThanOrEqual(maxV) {
+			break
+		}
+
+		out = append(out, version)
+	}
+
+	return out
+}
+
+// latestPatchPerMinor expects a sorted list and keeps only the last entry of
+// each major.minor group
+func latestPatchPerMinor(versions semverList) semverList {
+	out := make(semverList, 0, len(versions))
+	for i, version := range versions {
+		if i+1 < len(versions) &&
+			versions[i+1].major() == version.major() &&
+			versions[i+1].minor() == version.minor() {
+			continue
+		}
+		out = append(out, version)
+	}
+
+	return out
+}
+
+func (s semverList) toStringList() []string {
+	out := make([]string, len(s))
+	for i, ver := range s {
+		out[i] = ver.version.String()
+	}
+	return out
+}
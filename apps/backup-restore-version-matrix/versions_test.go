@@ -0,0 +1,47 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_latestPatchPerMinor(t *testing.T) {
+	ghReleases := []string{
+		"v1.23.0", "v1.22.8", "v1.23.0-rc.1", "v1.22.7", "v1.22.6", "v1.21.9", "v1.22.0", "v1.21.8",
+		"v1.22.0-rc.0", "v1.20.6", "v1.19.13", "v1.21.0", "v1.20.5", "v1.20.0", "v1.19.12", "v1.19.0",
+	}
+
+	tests := []struct {
+		name string
+		min  string
+		max  string
+		want []string
+	}{
+		{
+			name: "from 1.19.0 to 1.23.0",
+			min:  "1.19.0",
+			max:  "1.23.0",
+			want: []string{"1.19.13", "1.20.6", "1.21.9", "1.22.8"},
+		},
+		{
+			name: "from 1.22.0 to 1.22.8",
+			min:  "1.22.0",
+			max:  "1.22.8",
+			want: []string{"1.22.7"},
+		},
+		{
+			name: "non-semver target has no upper bound",
+			min:  "1.22.7",
+			max:  "preview-abcdef",
+			want: []string{"1.22.8", "1.23.0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			versions := sortSemverAndTrimToMinimum(parseSemverList(ghReleases), tt.min, tt.max)
+			if got := latestPatchPerMinor(versions).toStringList(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("latestPatchPerMinor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -11,7 +11,7 @@ import os
 import_error_count = 0
 
 
-def reset_schema(client: weaviate.Client, repl_factor: int):
+def reset_schema(client: weaviate.Client, repl_factor: int, shards: Optional[int]):
     client.schema.delete_all()
     class_obj = {
         "vectorizer": "none",
@@ -31,6 +31,8 @@ def reset_schema(client: weaviate.Client, repl_factor: int):
             },
         ],
     }
+    if shards is not None:
+        class_obj["shardingConfig"] = {"desiredCount": shards}
 
     client.schema.create_class(class_obj)
 
@@ -176,12 +178,20 @@ def config_repl_factor() -> int:
     return int(repl_factor)
 
 
+def config_shards() -> Optional[int]:
+    shards = os.environ.get("CONFIG_SHARDS")
+    if shards is None or shards == "":
+        return None
+    return int(shards)
+
+
 if __name__ == "__main__":
     host = config_host()
     object_count = config_object_count()
     batch_size = config_batch_size()
     uuid_offset = config_uuid_offset()
     repl_factor = config_repl_factor()
+    shards = config_shards()
     random_picks = int(object_count / 10)
 
     client = weaviate.Client(host, timeout_config=int(30))
@@ -197,8 +207,8 @@ if __name__ == "__main__":
         # load_references(client, 400, ids_class_1, ids_class_2)
         validate_objects(client, object_count, random_picks, uuid_offset)
     elif args.action == "schema":
-        logger.info(f"CONFIG: host={host}; repl_factor={repl_factor}")
-        reset_schema(client, repl_factor)
+        logger.info(f"CONFIG: host={host}; repl_factor={repl_factor}; shards={shards}")
+        reset_schema(client, repl_factor, shards)
         logger.info("schema reset")
     else:
         logger.error("unknown --action option")
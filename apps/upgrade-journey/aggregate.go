@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strconv"
+
+	"github.com/weaviate/weaviate-chaos-engineering/apps/upgrade-journey/pkg/workloads"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate/graphql"
+	"github.com/weaviate/weaviate/entities/models"
+)
+
+const groupByProp = "minor_version"
+
+var groupedFields = []graphql.Field{
+	{Name: "groupedBy", Fields: []graphql.Field{{Name: "value"}}},
+	{Name: "meta", Fields: []graphql.Field{{Name: "count"}}},
+}
+
+// expectedGroups counts the imported Collection objects by minor version,
+// the way importSourceObject stores it
+func expectedGroups(imported []string) map[string]int {
+	groups := map[string]int{}
+	for _, version := range imported {
+		parsed, _ := parseVersionForImport(version)
+		groups[strconv.FormatInt(parsed.minor(), 10)]++
+	}
+	return groups
+}
+
+// groupedAggregation runs Aggregate with groupBy, once on its own and once
+// combined with a nearVector search that covers all objects. Both have to
+// return exactly one group per imported minor version with the right count.
+func groupedAggregation(ctx context.Context, client *weaviate.Client,
+	posOfMaxVersion int,
+) error {
+	want := expectedGroups(versions[:posOfMaxVersion+1])
+
+	reqCtx, cancel := requestContext(ctx)
+	result, err := client.GraphQL().Aggregate().
+		WithClassName("Collection").
+		WithGroupBy(groupByProp).
+		WithLimit(10000).
+		WithFields(groupedFields...).
+		Do(reqCtx)
+	cancel()
+	if err != nil {
+		return requestError(ctx, "groupBy aggregation", err)
+	}
+	if err := compareGroups("groupBy", result, want); err != nil {
+		return err
+	}
+
+	searchVec := make([]float32, 32)
+	for i := range searchVec {
+		searchVec[i] = rand.Float32()
+	}
+
+	reqCtx, cancel = requestContext(ctx)
+	result, err = client.GraphQL().Aggregate().
+		WithClassName("Collection").
+		WithGroupBy(groupByProp).
+		WithNearVector(client.GraphQL().NearVectorArgBuilder().WithVector(searchVec)).
+		WithObjectLimit(posOfMaxVersion + 1).
+		WithLimit(10000).
+		WithFields(groupedFields...).
+		Do(reqCtx)
+	cancel()
+	if err != nil {
+		return requestError(ctx, "nearVector groupBy aggregation", err)
+	}
+	return compareGroups("nearVector groupBy", result, want)
+}
+
+func compareGroups(kind string, result *models.GraphQLResponse, want map[string]int) error {
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("%s: %v", kind, result.Errors[0])
+	}
+
+	raw, err := json.Marshal(result.Data)
+	if err != nil {
+		return err
+	}
+	return compareRawGroups(kind, raw, want)
+}
+
+// compareRawGroups compares the data section of a grouped Aggregate
+// response against the expected groups
+func compareRawGroups(kind string, raw []byte, want map[string]int) error {
+	got, err := workloads.ParseGroups(raw, "Collection")
+	if err != nil {
+		return fmt.Errorf("%s: %w", kind, err)
+	}
+
+	if err := workloads.DiffGroups(want, got); err != nil {
+		return fmt.Errorf("%s: %w", kind, err)
+	}
+	return nil
+}
+
+// groupedAggregation is the groupedAggregation of --transport=raw, with the
+// same queries built by hand
+func (v *rawVerifier) groupedAggregation(ctx context.Context, posOfMaxVersion int) error {
+	want := expectedGroups(versions[:posOfMaxVersion+1])
+	fields := "groupedBy{value} meta{count}"
+
+	var data json.RawMessage
+	query := fmt.Sprintf(`{Aggregate{Collection(groupBy:[%q],limit:10000){%s}}}`, groupByProp, fields)
+	if err := v.graphQL(ctx, query, &data); err != nil {
+		return fmt.Errorf("groupBy: %w", err)
+	}
+	if err := compareRawGroups("groupBy", data, want); err != nil {
+		return err
+	}
+
+	query = fmt.Sprintf(`{Aggregate{Collection(groupBy:[%q],nearVector:{vector:%s},objectLimit:%d,limit:10000){%s}}}`,
+		groupByProp, rawVector(), posOfMaxVersion+1, fields)
+	if err := v.graphQL(ctx, query, &data); err != nil {
+		return fmt.Errorf("nearVector groupBy: %w", err)
+	}
+	return compareRawGroups("nearVector groupBy", data, want)
+}
@@ -0,0 +1,14 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpectedGroups(t *testing.T) {
+	got := expectedGroups([]string{"1.24.1", "1.24.8", "1.25.0", "preview-abc"})
+	want := map[string]int{"24": 2, "25": 1, "0": 1}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wanted %v, got %v", want, got)
+	}
+}
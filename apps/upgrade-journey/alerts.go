@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// alertRules are thresholds that a healthy node never crosses. They are
+// evaluated while the journey runs, so that a soak run fails as soon as a
+// node is clearly unhealthy instead of timing out hours later:
+//
+//	alerts:
+//	  interval: 15s
+//	  max_heap_mb: 2048
+//	  max_startup_time: 2m
+//	  max_repair_duration: 5m
+//	  metrics:
+//	    - series: vector_index_queue_size
+//	      labels: {class_name: Load}
+//	      max: 100000
+type alertRules struct {
+	// Interval is how often the metric rules are evaluated, defaults to 15s
+	Interval time.Duration `yaml:"interval"`
+
+	// MaxHeapMB limits the Go heap in use of every node
+	MaxHeapMB float64 `yaml:"max_heap_mb"`
+
+	// MaxStartupTime limits how long a node takes to become ready
+	MaxStartupTime time.Duration `yaml:"max_startup_time"`
+
+	// MaxRepairDuration limits how long it takes to heal a fault
+	MaxRepairDuration time.Duration `yaml:"max_repair_duration"`
+
+	// Metrics limit arbitrary series, summed over the series that carry
+	// the labels, on every node
+	Metrics []metricRule `yaml:"metrics"`
+}
+
+type metricRule struct {
+	Series string            `yaml:"series"`
+	Labels map[string]string `yaml:"labels"`
+	Max    float64           `yaml:"max"`
+}
+
+func (r alertRules) validate() error {
+	if r.Interval < 0 || r.MaxHeapMB < 0 || r.MaxStartupTime < 0 || r.MaxRepairDuration < 0 {
+		return fmt.Errorf("alerts: thresholds must not be negative")
+	}
+	for _, rule := range r.Metrics {
+		if rule.Series == "" {
+			return fmt.Errorf("alerts: metric rule without series")
+		}
+	}
+	return nil
+}
+
+// metricRules returns every rule that is evaluated against the metrics,
+// including the heap limit
+func (r alertRules) metricRules() []metricRule {
+	rules := append([]metricRule{}, r.Metrics...)
+	if r.MaxHeapMB > 0 {
+		rules = append(rules, metricRule{Series: "go_memstats_heap_inuse_bytes", Max: r.MaxHeapMB * (1 << 20)})
+	}
+	return rules
+}
+
+// violations returns the metric rules the samples of a node break
+func (r alertRules) violations(samples metricSamples) []string {
+	var broken []string
+	for _, rule := range r.metricRules() {
+		if value := samples.sum(rule.Series, rule.Labels); value > rule.Max {
+			broken = append(broken, fmt.Sprintf("%s%v is %g, above %g", rule.Series, labelsOrEmpty(rule.Labels),
+				value, rule.Max))
+		}
+	}
+	return broken
+}
+
+func labelsOrEmpty(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	return fmt.Sprint(labels)
+}
+
+// errAlert is the cause of a run that was stopped by an alert rule
+var errAlert = errors.New("alert")
+
+// alertMonitor stops the run through its context once a rule is broken
+type alertMonitor struct {
+	sync.Mutex
+	rules  alertRules
+	cancel context.CancelCauseFunc
+	err    error
+}
+
+// scenarioAlerts is set from --scenario-file
+var scenarioAlerts = &alertMonitor{}
+
+// trip stops the run with the first broken rule
+func (m *alertMonitor) trip(format string, args ...interface{}) {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.err != nil {
+		return
+	}
+	m.err = fmt.Errorf("%w: %s", errAlert, fmt.Sprintf(format, args...))
+	logger.Error("alert rule broken, stopping the run", "err", m.err)
+	if m.cancel != nil {
+		m.cancel(m.err)
+	}
+}
+
+// checkStartup is called for every node that became ready
+func (m *alertMonitor) checkStartup(nodeId int, version string, took time.Duration) {
+	if max := m.rules.MaxStartupTime; max > 0 && took > max {
+		m.trip("weaviate-%d took %s to start on %s, above %s", nodeId, took.Round(time.Second), version, max)
+	}
+}
+
+// checkRepair is called for every healed fault
+func (m *alertMonitor) checkRepair(fault string, took time.Duration) {
+	if max := m.rules.MaxRepairDuration; max > 0 && took > max {
+		m.trip("%s took %s to heal, above %s", fault, took.Round(time.Second), max)
+	}
+}
+
+// watch evaluates the metric rules on every interval until the returned
+// function is called. The returned context is cancelled with the broken
+// rule as its cause.
+func (m *alertMonitor) watch(ctx context.Context, c *cluster) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	m.Lock()
+	m.cancel = cancel
+	m.Unlock()
+
+	interval := m.rules.Interval
+	if interval == 0 {
+		interval = 15 * time.Second
+	}
+
+	done := make(chan struct{})
+	stop := make(chan struct{})
+	go func() {
+		defer close(done)
+		if len(m.rules.metricRules()) == 0 {
+			<-stop
+			return
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.evaluate(ctx, c)
+			}
+		}
+	}()
+
+	return ctx, func() {
+		close(stop)
+		<-done
+		cancel(nil)
+	}
+}
+
+// evaluate checks the metric rules on every node that can be scraped, a
+// node that is down for an upgrade or a fault is skipped
+func (m *alertMonitor) evaluate(ctx context.Context, c *cluster) {
+	for i := 0; i < c.NodeCount; i++ {
+		_, samples, err := metrics.scrape(ctx, c, i)
+		if err != nil {
+			nodeLogger(c, i).Debug("cannot evaluate alert rules", "err", err)
+			continue
+		}
+
+		if broken := m.rules.violations(samples); len(broken) > 0 {
+			m.trip("%s: %v", c.Hostname(i), broken)
+			return
+		}
+	}
+}
+
+// cause returns the broken rule that stopped the run, if any
+func (m *alertMonitor) cause() error {
+	m.Lock()
+	defer m.Unlock()
+	return m.err
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadScenarioConfig(t *testing.T) {
+	file := path.Join(t.TempDir(), "scenario.yaml")
+	os.WriteFile(file, []byte(`
+alerts:
+  interval: 5s
+  max_heap_mb: 512
+  max_startup_time: 2m
+  metrics:
+    - series: vector_index_tombstones
+      labels: {class_name: Load}
+      max: 100
+`), 0o644)
+
+	config, err := loadScenarioConfig(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := alertRules{
+		Interval:       5 * time.Second,
+		MaxHeapMB:      512,
+		MaxStartupTime: 2 * time.Minute,
+		Metrics: []metricRule{
+			{Series: "vector_index_tombstones", Labels: map[string]string{"class_name": "Load"}, Max: 100},
+		},
+	}
+	if !reflect.DeepEqual(config.Alerts, want) {
+		t.Errorf("wanted %+v, got %+v", want, config.Alerts)
+	}
+
+	os.WriteFile(file, []byte("alerts:\n  max_heap: 512\n"), 0o644)
+	if _, err := loadScenarioConfig(file); err == nil {
+		t.Errorf("wanted an error for an unknown key")
+	}
+}
+
+func TestAlertViolations(t *testing.T) {
+	samples, err := parseMetrics(strings.NewReader(`go_memstats_heap_inuse_bytes 6.291456e+08
+vector_index_tombstones{class_name="Load",shard_name="a"} 60
+vector_index_tombstones{class_name="Load",shard_name="b"} 50
+vector_index_tombstones{class_name="Other",shard_name="c"} 500
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rules := alertRules{
+		MaxHeapMB: 1024,
+		Metrics: []metricRule{
+			{Series: "vector_index_tombstones", Labels: map[string]string{"class_name": "Load"}, Max: 100},
+		},
+	}
+	if got := rules.violations(samples); len(got) != 1 || !strings.HasPrefix(got[0], "vector_index_tombstones") {
+		t.Errorf("wanted the tombstone rule to be broken, got %v", got)
+	}
+
+	rules.MaxHeapMB = 512
+	if got := rules.violations(samples); len(got) != 2 {
+		t.Errorf("wanted the heap and the tombstone rule to be broken, got %v", got)
+	}
+}
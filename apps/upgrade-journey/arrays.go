@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const arraysClass = "ArrayProperties"
+
+// arrayVariants are the objects every hop writes, one of each, with a
+// value for every array type. The values overlap so that ContainsAny and
+// ContainsAll select different variants. The properties of "empty" are
+// empty arrays, which no filter may match.
+var arrayVariants = []struct {
+	name       string
+	properties map[string]interface{}
+}{
+	{name: "first", properties: map[string]interface{}{
+		"texts":    []string{"red", "green"},
+		"ints":     []int{1, 2},
+		"numbers":  []float64{0.5, 1.5},
+		"booleans": []bool{true},
+		"dates":    []string{dayDate(0), dayDate(1)},
+	}},
+	{name: "second", properties: map[string]interface{}{
+		"texts":    []string{"green", "blue"},
+		"ints":     []int{2, 3},
+		"numbers":  []float64{1.5, 2.5},
+		"booleans": []bool{false},
+		"dates":    []string{dayDate(1), dayDate(2)},
+	}},
+	{name: "third", properties: map[string]interface{}{
+		"texts":    []string{"blue"},
+		"ints":     []int{3},
+		"numbers":  []float64{2.5},
+		"booleans": []bool{true, false},
+		"dates":    []string{dayDate(2)},
+	}},
+	{name: "empty", properties: map[string]interface{}{
+		"texts":    []string{},
+		"ints":     []int{},
+		"numbers":  []float64{},
+		"booleans": []bool{},
+		"dates":    []string{},
+	}},
+}
+
+// arrayFilter is a filter on an array property with the variants it must
+// match, all others must not match. Equal on an array matches objects that
+// have the value anywhere in it.
+type arrayFilter struct {
+	name  string
+	where string
+	match []string
+
+	// contains is set for ContainsAny and ContainsAll, which only newer
+	// versions have
+	contains bool
+}
+
+var arrayFilters = []arrayFilter{
+	{
+		name:  "texts Equal green",
+		where: `{path:["texts"],operator:Equal,valueText:"green"}`,
+		match: []string{"first", "second"},
+	},
+	{
+		name:  "ints Equal 3",
+		where: `{path:["ints"],operator:Equal,valueInt:3}`,
+		match: []string{"second", "third"},
+	},
+	{
+		name:  "numbers Equal 2.5",
+		where: `{path:["numbers"],operator:Equal,valueNumber:2.5}`,
+		match: []string{"second", "third"},
+	},
+	{
+		name:  "booleans Equal true",
+		where: `{path:["booleans"],operator:Equal,valueBoolean:true}`,
+		match: []string{"first", "third"},
+	},
+	{
+		name:  "dates Equal day 1",
+		where: fmt.Sprintf(`{path:["dates"],operator:Equal,valueDate:%q}`, dayDate(1)),
+		match: []string{"first", "second"},
+	},
+	{
+		name:     "texts ContainsAny red blue",
+		where:    `{path:["texts"],operator:ContainsAny,valueText:["red","blue"]}`,
+		match:    []string{"first", "second", "third"},
+		contains: true,
+	},
+	{
+		name:     "texts ContainsAll green blue",
+		where:    `{path:["texts"],operator:ContainsAll,valueText:["green","blue"]}`,
+		match:    []string{"second"},
+		contains: true,
+	},
+	{
+		name:     "ints ContainsAny 1 3",
+		where:    `{path:["ints"],operator:ContainsAny,valueInt:[1,3]}`,
+		match:    []string{"first", "second", "third"},
+		contains: true,
+	},
+	{
+		name:     "ints ContainsAll 2 3",
+		where:    `{path:["ints"],operator:ContainsAll,valueInt:[2,3]}`,
+		match:    []string{"second"},
+		contains: true,
+	},
+	{
+		name:     "numbers ContainsAny 0.5 2.5",
+		where:    `{path:["numbers"],operator:ContainsAny,valueNumber:[0.5,2.5]}`,
+		match:    []string{"first", "second", "third"},
+		contains: true,
+	},
+	{
+		name:     "numbers ContainsAll 1.5",
+		where:    `{path:["numbers"],operator:ContainsAll,valueNumber:[1.5]}`,
+		match:    []string{"first", "second"},
+		contains: true,
+	},
+	{
+		name:     "booleans ContainsAny false",
+		where:    `{path:["booleans"],operator:ContainsAny,valueBoolean:[false]}`,
+		match:    []string{"second", "third"},
+		contains: true,
+	},
+	{
+		name:     "booleans ContainsAll true false",
+		where:    `{path:["booleans"],operator:ContainsAll,valueBoolean:[true,false]}`,
+		match:    []string{"third"},
+		contains: true,
+	},
+	{
+		name:     "dates ContainsAny day 0",
+		where:    fmt.Sprintf(`{path:["dates"],operator:ContainsAny,valueDate:[%q]}`, dayDate(0)),
+		match:    []string{"first"},
+		contains: true,
+	},
+	{
+		name:     "dates ContainsAll day 1 day 2",
+		where:    fmt.Sprintf(`{path:["dates"],operator:ContainsAll,valueDate:[%q,%q]}`, dayDate(1), dayDate(2)),
+		match:    []string{"second"},
+		contains: true,
+	},
+}
+
+// arrayProbe writes objects with every array type on every hop
+var arrayProbe probeImports
+
+// arraysVerifier checks that filters on the arrays find exactly the
+// expected objects of all hops. ContainsAny and ContainsAll run from the
+// first version that has them on, over the objects of the older hops too.
+type arraysVerifier struct{}
+
+func (v *arraysVerifier) name() string {
+	return "array properties"
+}
+
+func (v *arraysVerifier) verify(ctx context.Context, posOfMaxVersion int) error {
+	contains := supports(versions[posOfMaxVersion], featureContainsFilters)
+	for _, filter := range arrayFilters {
+		if filter.contains && !contains {
+			continue
+		}
+		counts, err := variantCounts(ctx, arraysClass, filter.where)
+		if err != nil {
+			return fmt.Errorf("%s: %w", filter.name, err)
+		}
+		if err := checkArrayFilter(filter, arrayVariantNames(), counts, arrayProbe.rounds()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func arrayVariantNames() []string {
+	var names []string
+	for _, variant := range arrayVariants {
+		names = append(names, variant.name)
+	}
+	return names
+}
+
+// checkArrayFilter checks that a filter matched the objects of every round
+// of the variants it should match and none of the other variants
+func checkArrayFilter(filter arrayFilter, variants []string, counts map[string]int, rounds int) error {
+	want := map[string]bool{}
+	for _, name := range filter.match {
+		want[name] = true
+	}
+
+	var diff []string
+	for _, name := range variants {
+		switch {
+		case want[name] && counts[name] != rounds:
+			diff = append(diff, fmt.Sprintf("%d of the %d %q objects", counts[name], rounds, name))
+		case !want[name] && counts[name] != 0:
+			diff = append(diff, fmt.Sprintf("%d unexpected %q objects", counts[name], name))
+		}
+	}
+	if len(diff) > 0 {
+		return fmt.Errorf("filter %s matched %s", filter.name, strings.Join(diff, ", "))
+	}
+	return nil
+}
+
+func createArraysClass(ctx context.Context) error {
+	if _, err := postRaw(ctx, 0, "/v1/schema", map[string]interface{}{
+		"class":      arraysClass,
+		"vectorizer": "none",
+		"properties": []map[string]interface{}{
+			{"name": "variant", "dataType": []string{"text"}},
+			{"name": "texts", "dataType": []string{"text[]"}},
+			{"name": "ints", "dataType": []string{"int[]"}},
+			{"name": "numbers", "dataType": []string{"number[]"}},
+			{"name": "booleans", "dataType": []string{"boolean[]"}},
+			{"name": "dates", "dataType": []string{"date[]"}},
+		},
+	}); err != nil {
+		return fmt.Errorf("create class %s: %w", arraysClass, err)
+	}
+	return nil
+}
+
+func importArrays(ctx context.Context, hop int) error {
+	var objects []map[string]interface{}
+	for i, variant := range arrayVariants {
+		properties := map[string]interface{}{"variant": variant.name}
+		for k, v := range variant.properties {
+			properties[k] = v
+		}
+
+		objects = append(objects, map[string]interface{}{
+			"class":      arraysClass,
+			"id":         probeID(arraysClass, hop, i),
+			"vector":     []float32{float32(i), float32(hop), 1},
+			"properties": properties,
+		})
+	}
+	if _, err := postRaw(ctx, 0, "/v1/batch/objects", map[string]interface{}{"objects": objects}); err != nil {
+		return fmt.Errorf("import %s: %w", arraysClass, err)
+	}
+	return nil
+}
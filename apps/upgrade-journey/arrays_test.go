@@ -0,0 +1,25 @@
+package main
+
+import "testing"
+
+func Test_checkArrayFilter(t *testing.T) {
+	filter := arrayFilters[0] // texts Equal green
+	tests := []struct {
+		name    string
+		counts  map[string]int
+		wantErr bool
+	}{
+		{name: "exact", counts: map[string]int{"first": 3, "second": 3}},
+		{name: "older hops lost", counts: map[string]int{"first": 1, "second": 3}, wantErr: true},
+		{name: "variant not found", counts: map[string]int{"first": 3}, wantErr: true},
+		{name: "empty array matched", counts: map[string]int{"first": 3, "second": 3, "empty": 1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkArrayFilter(filter, arrayVariantNames(), tt.counts, 3)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkArrayFilter() err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/weaviate/weaviate-chaos-engineering/apps/upgrade-journey/pkg/faults"
+)
+
+// The async indexing scenario imports faster than the vector index can keep
+// up with on versions that index asynchronously, and kills the node with the
+// longest indexing queue. The queue is persisted, so after the restart it
+// must resume where it stopped and drain, and searches must find the
+// objects once it did.
+var (
+	// withAsyncIndexingChaos is set by --async-indexing-chaos
+	withAsyncIndexingChaos bool
+
+	// asyncObjects is set by --async-indexing-objects
+	asyncObjects int
+
+	// asyncDrainTimeout is set by --async-indexing-drain-timeout
+	asyncDrainTimeout time.Duration
+)
+
+const (
+	asyncClass = "AsyncIndexed"
+	asyncBatch = 1000
+	asyncDims  = 64
+
+	// objects of every hop whose vectors are searched for
+	asyncSamples = 100
+
+	// share of the samples that must be found as their own nearest
+	// neighbor
+	asyncMinRecall = 0.95
+)
+
+// asyncImported counts the objects of all hops, asyncClassCreated is set on
+// the first hop that supports async indexing
+var (
+	asyncImported     int
+	asyncClassCreated bool
+)
+
+// asyncIndexingEnv turns on async indexing on the versions that have it
+func asyncIndexingEnv(version string) map[string]string {
+	if !withAsyncIndexingChaos || !supports(version, featureAsyncIndexing) {
+		return nil
+	}
+	return map[string]string{"ASYNC_INDEXING": "true"}
+}
+
+type asyncSample struct {
+	id     string
+	vector []float32
+}
+
+// asyncIndexingChaos runs the scenario once the whole cluster runs a version
+// with async indexing
+func (c *cluster) asyncIndexingChaos(ctx context.Context, posOfVersion int) error {
+	if !supports(versions[posOfVersion], featureAsyncIndexing) {
+		hopLogger(posOfVersion).Debug("skipping async indexing chaos", "version", versions[posOfVersion])
+		return nil
+	}
+
+	if !asyncClassCreated {
+		if _, err := postRaw(ctx, 0, "/v1/schema", map[string]interface{}{
+			"class":      asyncClass,
+			"vectorizer": "none",
+			"properties": []map[string]interface{}{
+				{"name": "hop", "dataType": []string{"int"}},
+			},
+		}); err != nil {
+			return fmt.Errorf("create class %s: %w", asyncClass, err)
+		}
+		asyncClassCreated = true
+	}
+
+	samples, err := importAsync(ctx, posOfVersion)
+	if err != nil {
+		return err
+	}
+
+	queues, err := vectorQueues(ctx)
+	if err != nil {
+		return err
+	}
+	killed, backlog := longestQueue(queues)
+	if backlog == 0 {
+		hopLogger(posOfVersion).Warn("indexing queue drained before the kill, raise --async-indexing-objects")
+	}
+	hopLogger(posOfVersion).Info("killing node with a backlogged indexing queue", "node", c.Hostname(killed),
+		"queue", backlog, "queues", queues)
+
+	if err := applyFault(ctx, c, posOfVersion, &faults.Kill[*cluster]{Node: killed}, 0, nil); err != nil {
+		return err
+	}
+
+	took, err := c.awaitIndexed(ctx, posOfVersion, asyncClass, asyncDrainTimeout)
+	if err != nil {
+		return err
+	}
+	runReport.recordLatency(posOfVersion, "async indexing drain", took)
+
+	if err := checkAsyncCount(ctx); err != nil {
+		return err
+	}
+	return checkAsyncRecall(ctx, posOfVersion, samples)
+}
+
+// importAsync imports --async-indexing-objects objects and returns a sample
+// of them
+func importAsync(ctx context.Context, posOfVersion int) ([]asyncSample, error) {
+	var samples []asyncSample
+	for from := 0; from < asyncObjects; from += asyncBatch {
+		var objects []map[string]interface{}
+		for i := from; i < from+asyncBatch && i < asyncObjects; i++ {
+			vector := make([]float32, asyncDims)
+			for d := range vector {
+				vector[d] = rand.Float32()
+			}
+			id := uuid.New().String()
+			objects = append(objects, map[string]interface{}{
+				"class":      asyncClass,
+				"id":         id,
+				"vector":     vector,
+				"properties": map[string]interface{}{"hop": posOfVersion},
+			})
+			if len(samples) < asyncSamples && rand.Intn(asyncObjects) < asyncSamples {
+				samples = append(samples, asyncSample{id: id, vector: vector})
+			}
+		}
+
+		if _, err := postRaw(ctx, 0, "/v1/batch/objects", map[string]interface{}{"objects": objects}); err != nil {
+			return nil, fmt.Errorf("import %s: %w", asyncClass, err)
+		}
+		asyncImported += len(objects)
+	}
+	return samples, nil
+}
+
+type asyncQueue struct {
+	length int
+	ready  bool
+}
+
+// vectorQueues returns the indexing queue of every node. A node is ready
+// once all of its shards of the class finished indexing.
+func vectorQueues(ctx context.Context) (map[int]asyncQueue, error) {
+	body, err := getRaw(ctx, 0, "/v1/nodes?output=verbose")
+	if err != nil {
+		return nil, fmt.Errorf("indexing queues: %w", err)
+	}
+	return parseVectorQueues(body, asyncClass)
+}
+
+// parseVectorQueues parses the queues of a class, of every class if
+// className is empty
+func parseVectorQueues(body []byte, className string) (map[int]asyncQueue, error) {
+	var parsed struct {
+		Nodes []struct {
+			Name   string `json:"name"`
+			Shards []struct {
+				Class                string `json:"class"`
+				VectorQueueLength    int    `json:"vectorQueueLength"`
+				VectorIndexingStatus string `json:"vectorIndexingStatus"`
+			} `json:"shards"`
+		} `json:"nodes"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return nil, err
+	}
+
+	queues := map[int]asyncQueue{}
+	for _, node := range parsed.Nodes {
+		var nodeId int
+		if _, err := fmt.Sscanf(node.Name, "weaviate-%d", &nodeId); err != nil {
+			return nil, fmt.Errorf("unexpected node name %q", node.Name)
+		}
+
+		queue := asyncQueue{ready: true}
+		for _, shard := range node.Shards {
+			if className != "" && shard.Class != className {
+				continue
+			}
+			queue.length += shard.VectorQueueLength
+			if shard.VectorIndexingStatus != "READY" {
+				queue.ready = false
+			}
+		}
+		queues[nodeId] = queue
+	}
+	return queues, nil
+}
+
+// longestQueue returns the node with the longest queue, the lowest one if
+// several are equally long
+func longestQueue(queues map[int]asyncQueue) (int, int) {
+	node, longest := -1, -1
+	for nodeId, queue := range queues {
+		if queue.length > longest || (queue.length == longest && nodeId < node) {
+			node, longest = nodeId, queue.length
+		}
+	}
+	if node < 0 {
+		return 0, 0
+	}
+	return node, longest
+}
+
+func checkAsyncCount(ctx context.Context) error {
+	body, err := postRaw(ctx, 0, "/v1/graphql", map[string]interface{}{
+		"query": fmt.Sprintf("{Aggregate{%s{meta{count}}}}", asyncClass),
+	})
+	if err != nil {
+		return err
+	}
+
+	var res struct {
+		Data struct {
+			Aggregate map[string][]struct {
+				Meta struct {
+					Count int `json:"count"`
+				} `json:"meta"`
+			} `json:"Aggregate"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return err
+	}
+
+	groups := res.Data.Aggregate[asyncClass]
+	if len(groups) != 1 || groups[0].Meta.Count != asyncImported {
+		return fmt.Errorf("%s has %v objects, want %d", asyncClass, groups, asyncImported)
+	}
+	return nil
+}
+
+// checkAsyncRecall searches for the vectors of the samples, which must be
+// found as their own nearest neighbor
+func checkAsyncRecall(ctx context.Context, posOfVersion int, samples []asyncSample) error {
+	if len(samples) == 0 {
+		return nil
+	}
+
+	found := 0
+	for _, sample := range samples {
+		vector, _ := json.Marshal(sample.vector)
+		body, err := postRaw(ctx, 0, "/v1/graphql", map[string]interface{}{
+			"query": fmt.Sprintf("{Get{%s(nearVector:{vector:%s},limit:1){_additional{id}}}}", asyncClass, vector),
+		})
+		if err != nil {
+			return fmt.Errorf("search %s: %w", asyncClass, err)
+		}
+
+		var res struct {
+			Data struct {
+				Get map[string][]struct {
+					Additional struct {
+						ID string `json:"id"`
+					} `json:"_additional"`
+				} `json:"Get"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(body, &res); err != nil {
+			return err
+		}
+		if hits := res.Data.Get[asyncClass]; len(hits) > 0 && hits[0].Additional.ID == sample.id {
+			found++
+		}
+	}
+
+	recall := float64(found) / float64(len(samples))
+	runReport.recordRecall(posOfVersion, recallAsyncIndexing, recall)
+	if recall < asyncMinRecall {
+		return fmt.Errorf("recall of %s after the indexing queue drained is %.2f, want %.2f", asyncClass,
+			recall, asyncMinRecall)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_parseVectorQueues(t *testing.T) {
+	body := []byte(`{"nodes":[
+		{"name":"weaviate-0","shards":[
+			{"class":"AsyncIndexed","vectorQueueLength":300,"vectorIndexingStatus":"INDEXING"},
+			{"class":"AsyncIndexed","vectorQueueLength":200,"vectorIndexingStatus":"READY"},
+			{"class":"Collection","vectorQueueLength":1000,"vectorIndexingStatus":"INDEXING"}]},
+		{"name":"weaviate-1","shards":[
+			{"class":"AsyncIndexed","vectorQueueLength":0,"vectorIndexingStatus":"READY"}]}
+	]}`)
+
+	got, err := parseVectorQueues(body, "AsyncIndexed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[int]asyncQueue{0: {length: 500}, 1: {ready: true}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseVectorQueues() = %v, want %v", got, want)
+	}
+}
+
+func Test_longestQueue(t *testing.T) {
+	tests := []struct {
+		name       string
+		queues     map[int]asyncQueue
+		wantNode   int
+		wantLength int
+	}{
+		{name: "none"},
+		{name: "longest", queues: map[int]asyncQueue{0: {length: 10}, 1: {length: 30}, 2: {length: 20}},
+			wantNode: 1, wantLength: 30},
+		{name: "tie", queues: map[int]asyncQueue{2: {length: 10}, 1: {length: 10}},
+			wantNode: 1, wantLength: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, length := longestQueue(tt.queues)
+			if node != tt.wantNode || length != tt.wantLength {
+				t.Errorf("longestQueue() = %d, %d, want %d, %d", node, length, tt.wantNode, tt.wantLength)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// The backup scenario starts a backup right before every rolling update. A
+// backup holds a lock on the classes it includes, and a node that goes
+// down in the middle of it must neither keep the lock forever nor block
+// the upgrade.
+var (
+	// withUpgradeBackup is set by --backup-during-upgrade
+	withUpgradeBackup bool
+
+	// backupTimeout is set by --backup-timeout
+	backupTimeout time.Duration
+)
+
+const (
+	backupBackend = "filesystem"
+	backupPath    = "/var/lib/weaviate/backups"
+)
+
+// backupEnabled is true if a scenario needs the backup module
+func backupEnabled() bool {
+	return withUpgradeBackup || withRestoreUnderLoad
+}
+
+// backupEnv enables the filesystem backend on every node. The backups are
+// kept in the data directory, so that a node finds the backups it
+// coordinated after a restart.
+func backupEnv() map[string]string {
+	return map[string]string{"BACKUP_FILESYSTEM_PATH": backupPath}
+}
+
+type backupStatus struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Error  string `json:"error"`
+}
+
+// done is true once a backup can't change anymore
+func (s backupStatus) done() bool {
+	return s.Status == "SUCCESS" || s.Status == "FAILED"
+}
+
+// clean is true for a backup that succeeded or failed with a reason
+func (s backupStatus) clean() bool {
+	return s.Status == "SUCCESS" || (s.Status == "FAILED" && s.Error != "")
+}
+
+func backupID(posOfVersion int, suffix string) string {
+	return fmt.Sprintf("upgrade-%02d-%s", posOfVersion, suffix)
+}
+
+// upgradeDuringBackup starts a backup of the journey classes and runs the
+// upgrade while it is in progress. The upgrade must finish within
+// --backup-timeout, after which the backup must have succeeded or failed
+// with a reason, and a new backup must succeed, which proves that the lock
+// of the first one was released.
+func upgradeDuringBackup(ctx context.Context, posOfVersion int, upgrade func(ctx context.Context) error) error {
+	during := backupID(posOfVersion, "during")
+	if err := startBackup(ctx, during, journeyClassNames()); err != nil {
+		return err
+	}
+	hopLogger(posOfVersion).Info("started backup before the upgrade", "backup", during)
+
+	upgradeCtx, cancel := context.WithTimeout(ctx, backupTimeout)
+	defer cancel()
+	if err := upgrade(upgradeCtx); err != nil {
+		if ctx.Err() == nil && errors.Is(upgradeCtx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("upgrade blocked for %s while backup %s was in progress: %w", backupTimeout,
+				during, err)
+		}
+		return err
+	}
+
+	status, err := awaitBackup(ctx, during)
+	if err != nil {
+		return err
+	}
+	if !status.clean() {
+		return fmt.Errorf("backup %s failed without a reason", during)
+	}
+	hopLogger(posOfVersion).Info("backup during the upgrade finished", "backup", during,
+		"status", status.Status, "reason", status.Error)
+
+	after := backupID(posOfVersion, "after")
+	if err := startBackup(ctx, after, journeyClassNames()); err != nil {
+		return fmt.Errorf("backup lock of %s was not released: %w", during, err)
+	}
+	status, err = awaitBackup(ctx, after)
+	if err != nil {
+		return err
+	}
+	if status.Status != "SUCCESS" {
+		return fmt.Errorf("backup %s after the upgrade: %s: %s", after, status.Status, status.Error)
+	}
+
+	return nil
+}
+
+func journeyClassNames() []string {
+	var names []string
+	for _, class := range journeyClasses() {
+		names = append(names, class.Class)
+	}
+	return names
+}
+
+func startBackup(ctx context.Context, id string, include []string) error {
+	if _, err := postRaw(ctx, 0, "/v1/backups/"+backupBackend, map[string]interface{}{
+		"id":      id,
+		"include": include,
+	}); err != nil {
+		return fmt.Errorf("start backup %s: %w", id, err)
+	}
+	return nil
+}
+
+// awaitBackup polls the status of a backup until it is done, a backup that
+// is still in progress after --backup-timeout is stuck
+func awaitBackup(ctx context.Context, id string) (backupStatus, error) {
+	return awaitStatus(ctx, fmt.Sprintf("/v1/backups/%s/%s", backupBackend, id), "backup "+id)
+}
+
+// awaitRestore is awaitBackup for the restore of a backup
+func awaitRestore(ctx context.Context, id string) (backupStatus, error) {
+	return awaitStatus(ctx, fmt.Sprintf("/v1/backups/%s/%s/restore", backupBackend, id), "restore of "+id)
+}
+
+func awaitStatus(ctx context.Context, endpoint, what string) (backupStatus, error) {
+	var status backupStatus
+	deadline := time.Now().Add(backupTimeout)
+	for {
+		body, err := getRaw(ctx, 0, endpoint)
+		if err != nil {
+			return status, fmt.Errorf("status of %s: %w", what, err)
+		}
+		if err := json.Unmarshal(body, &status); err != nil {
+			return status, fmt.Errorf("status of %s: %w", what, err)
+		}
+
+		if status.done() {
+			return status, nil
+		}
+		if time.Now().After(deadline) {
+			return status, fmt.Errorf("%s is stuck in %s after %s", what, status.Status, backupTimeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			return status, ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func Test_backupStatus(t *testing.T) {
+	tests := []struct {
+		status      backupStatus
+		done, clean bool
+	}{
+		{status: backupStatus{Status: "STARTED"}},
+		{status: backupStatus{Status: "TRANSFERRING"}},
+		{status: backupStatus{Status: "SUCCESS"}, done: true, clean: true},
+		{status: backupStatus{Status: "FAILED", Error: "node weaviate-1 went down"}, done: true, clean: true},
+		{status: backupStatus{Status: "FAILED"}, done: true},
+	}
+	for _, tt := range tests {
+		if got := tt.status.done(); got != tt.done {
+			t.Errorf("%+v: done() = %v, want %v", tt.status, got, tt.done)
+		}
+		if got := tt.status.clean(); got != tt.clean {
+			t.Errorf("%+v: clean() = %v, want %v", tt.status, got, tt.clean)
+		}
+	}
+}
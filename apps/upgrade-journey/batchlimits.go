@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// withBatchLimits is set by --batch-limits
+var withBatchLimits bool
+
+// batchProbe is a batch that is sent on every hop, by the number of
+// objects and the size of the text of every object
+type batchProbe struct {
+	name      string
+	objects   int
+	textBytes int
+}
+
+// batchProbes are batches near and above what a node is expected to take
+// in a single request, by object count and by payload size
+var batchProbes = []batchProbe{
+	{name: "1000 objects", objects: 1000, textBytes: 16},
+	{name: "20000 objects", objects: 20000, textBytes: 16},
+	{name: "8 MB", objects: 8, textBytes: 1 << 20},
+	{name: "64 MB", objects: 64, textBytes: 1 << 20},
+	{name: "one 32 MB object", objects: 1, textBytes: 32 << 20},
+}
+
+const (
+	batchAccepted = "accepted"
+	batchPartial  = "partial"
+	batchRejected = "rejected"
+)
+
+// batchLimitOutcomes are the outcomes of the first hop by probe, every
+// later hop has to treat the probes the same way
+var batchLimitOutcomes map[string]batchLimitOutcome
+
+type batchLimitOutcome struct {
+	version string
+	outcome string
+}
+
+// batchLimits sends every probe to a class of the hop as a single batch.
+// Whatever a node does with a batch, it must not write half of a batch it
+// rejected, nor lose objects of a batch it accepted. Every version has to
+// accept or reject a probe like the first version of the journey did.
+func batchLimits(ctx context.Context, posOfVersion int) error {
+	version := versions[posOfVersion]
+	class := fmt.Sprintf("BatchLimits%02d", posOfVersion)
+	if _, err := postRaw(ctx, 0, "/v1/schema", map[string]interface{}{
+		"class":      class,
+		"vectorizer": "none",
+		"properties": []map[string]interface{}{
+			{"name": "probe", "dataType": []string{"int"}},
+			{"name": "text", "dataType": []string{"text"}},
+		},
+	}); err != nil {
+		return fmt.Errorf("create class %s: %w", class, err)
+	}
+
+	first := batchLimitOutcomes == nil
+	if first {
+		batchLimitOutcomes = map[string]batchLimitOutcome{}
+	}
+
+	for i, probe := range batchProbes {
+		status, body, sendErr := doRaw(ctx, http.MethodPost, 0, "/v1/batch/objects", map[string]interface{}{
+			"objects": probe.batch(class, i),
+		})
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		outcome, failed, err := parseBatchOutcome(status, body, sendErr)
+		if err != nil {
+			return fmt.Errorf("batch of %s: %w", probe.name, err)
+		}
+
+		stored, err := aggregateCountWhere(ctx, class, fmt.Sprintf(`{path:["probe"],operator:Equal,valueInt:%d}`, i))
+		if err != nil {
+			return fmt.Errorf("count objects of the batch of %s: %w", probe.name, err)
+		}
+		if err := checkBatchWrites(outcome, probe.objects, failed, stored); err != nil {
+			return fmt.Errorf("batch of %s: %w", probe.name, err)
+		}
+
+		if first {
+			batchLimitOutcomes[probe.name] = batchLimitOutcome{version: version, outcome: outcome}
+		} else if want := batchLimitOutcomes[probe.name]; want.outcome != outcome {
+			return fmt.Errorf("batch of %s was %s on %s, but %s on %s", probe.name, want.outcome, want.version,
+				outcome, version)
+		}
+
+		hopLogger(posOfVersion).Info("batch limit probe", "probe", probe.name, "outcome", outcome,
+			"status", status, "stored", stored)
+	}
+	return nil
+}
+
+// batch returns the objects of the probe, marked with its index
+func (p batchProbe) batch(class string, index int) []map[string]interface{} {
+	text := strings.Repeat("chaos ", p.textBytes/6+1)[:p.textBytes]
+	objects := make([]map[string]interface{}, p.objects)
+	for i := range objects {
+		objects[i] = map[string]interface{}{
+			"class":      class,
+			"id":         uuid.New().String(),
+			"vector":     []float32{float32(i), 1},
+			"properties": map[string]interface{}{"probe": index, "text": text},
+		}
+	}
+	return objects
+}
+
+// parseBatchOutcome classifies the response to a batch. A node that closes
+// the connection on a batch rejects it as much as one that answers with
+// an error status, one that did not answer in time did not decide yet. An
+// accepted batch may still have failed objects, which are returned.
+func parseBatchOutcome(status int, body []byte, sendErr error) (string, int, error) {
+	if errors.Is(sendErr, context.DeadlineExceeded) {
+		return "", 0, sendErr
+	}
+	if sendErr != nil || status < 200 || status > 299 {
+		return batchRejected, 0, nil
+	}
+
+	var results []struct {
+		Result struct {
+			Errors *struct {
+				Error []struct {
+					Message string `json:"message"`
+				} `json:"error"`
+			} `json:"errors"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(body, &results); err != nil {
+		return "", 0, fmt.Errorf("status %d: %w", status, err)
+	}
+
+	failed := 0
+	for _, r := range results {
+		if r.Result.Errors != nil && len(r.Result.Errors.Error) > 0 {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return batchPartial, failed, nil
+	}
+	return batchAccepted, 0, nil
+}
+
+// checkBatchWrites compares the objects of a batch that were stored with
+// the outcome of the batch
+func checkBatchWrites(outcome string, sent, failed, stored int) error {
+	switch outcome {
+	case batchRejected:
+		if stored != 0 {
+			return fmt.Errorf("rejected, but %d of %d objects were written", stored, sent)
+		}
+	case batchPartial, batchAccepted:
+		if want := sent - failed; stored != want {
+			return fmt.Errorf("%s with %d failed objects, wanted %d of %d objects written, got %d", outcome,
+				failed, want, sent, stored)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func Test_parseBatchOutcome(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		sendErr    error
+		want       string
+		wantFailed int
+		wantErr    bool
+	}{
+		{name: "all written", status: 200, body: `[{"result":{}},{"result":{}}]`, want: batchAccepted},
+		{
+			name:   "some failed",
+			status: 200,
+			body:   `[{"result":{}},{"result":{"errors":{"error":[{"message":"too long"}]}}}]`,
+			want:   batchPartial, wantFailed: 1,
+		},
+		{name: "too large", status: 413, body: `request entity too large`, want: batchRejected},
+		{name: "connection closed", sendErr: errors.New("connection reset by peer"), want: batchRejected},
+		{name: "no answer", sendErr: context.DeadlineExceeded, wantErr: true},
+		{name: "garbage", status: 200, body: `<html>`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, failed, err := parseBatchOutcome(tt.status, []byte(tt.body), tt.sendErr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseBatchOutcome() err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want || failed != tt.wantFailed {
+				t.Errorf("parseBatchOutcome() = %q, %d, want %q, %d", got, failed, tt.want, tt.wantFailed)
+			}
+		})
+	}
+}
+
+func Test_checkBatchWrites(t *testing.T) {
+	tests := []struct {
+		outcome string
+		sent    int
+		failed  int
+		stored  int
+		wantErr bool
+	}{
+		{outcome: batchAccepted, sent: 10, stored: 10},
+		{outcome: batchAccepted, sent: 10, stored: 9, wantErr: true},
+		{outcome: batchPartial, sent: 10, failed: 3, stored: 7},
+		{outcome: batchPartial, sent: 10, failed: 3, stored: 10, wantErr: true},
+		{outcome: batchRejected, sent: 10, stored: 0},
+		{outcome: batchRejected, sent: 10, stored: 4, wantErr: true},
+	}
+	for _, tt := range tests {
+		err := checkBatchWrites(tt.outcome, tt.sent, tt.failed, tt.stored)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkBatchWrites(%s, %d sent, %d failed, %d stored) err = %v, wantErr %v", tt.outcome,
+				tt.sent, tt.failed, tt.stored, err, tt.wantErr)
+		}
+	}
+}
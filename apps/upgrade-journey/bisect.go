@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A failed hop of a long journey names the version the hop went to, but
+// the journey may have skipped several patch releases on the way. Bisection
+// narrows the failure down to a single release: it re-runs the harness as
+// a two-hop journey from the last version that passed to a release in
+// between, on a fresh cluster, and halves the releases in question after
+// every run.
+var (
+	// withBisect is set by --bisect
+	withBisect bool
+
+	// pinnedVersions is set by --versions, the reduced journeys of a
+	// bisection pin their versions with it
+	pinnedVersions string
+)
+
+// bisectDroppedFlags are the flags of the failed run that are not passed on
+// to the reduced journeys, they select versions or only make sense once,
+// such as an archive of the images of the versions of the failed run.
+// The value is whether the flag takes a value, which may be the next
+// argument.
+var bisectDroppedFlags = map[string]bool{
+	"bisect":               false,
+	"versions":             true,
+	"latest-releases":      true,
+	"channels":             true,
+	"path-policy":          true,
+	"check-forbidden-jump": false,
+	"results-store":        true,
+	"progress":             false,
+	"plan":                 false,
+	"preload-images":       true,
+}
+
+// bisectResult is what a bisection found, it is written to the artifacts
+type bisectResult struct {
+	FailedHop   int      `json:"failed_hop"`
+	Good        string   `json:"good"`
+	Bad         string   `json:"bad"`
+	Candidates  []string `json:"candidates"`
+	Reproduced  bool     `json:"reproduced"`
+	FirstBad    string   `json:"first_bad,omitempty"`
+	LastGood    string   `json:"last_good,omitempty"`
+	Runs        int      `json:"runs"`
+	TookSeconds float64  `json:"took_seconds"`
+}
+
+// bisectCandidates returns the releases after good and before bad in order,
+// followed by bad itself. A bad version that is not a release, such as a
+// channel or a preview image, comes after every release.
+func bisectCandidates(releases []string, good, bad string) []string {
+	parsed := parseSemverList(releases)
+	sort.Slice(parsed, func(a, b int) bool {
+		return parsed[a].less(parsed[b])
+	})
+
+	var candidates []string
+	for _, release := range parsed.toStringList() {
+		if versionLess(good, release) && versionLess(release, bad) {
+			candidates = append(candidates, release)
+		}
+	}
+	return append(candidates, bad)
+}
+
+// bisectArgs are the arguments of a reduced journey: the ones of the failed
+// run without bisectDroppedFlags, pinned to the two versions
+func bisectArgs(args []string, good, candidate string) []string {
+	return append(stripFlags(args, bisectDroppedFlags), fmt.Sprintf("--versions=%s,%s", good, candidate))
+}
+
+// stripFlags removes flags from arguments, the value of every flag tells
+// whether it takes a value, which may be the next argument
+func stripFlags(args []string, flags map[string]bool) []string {
+	var out []string
+	for i := 0; i < len(args); i++ {
+		name, hasValue := flagName(args[i])
+		takesValue, drop := flags[name]
+		if !drop {
+			out = append(out, args[i])
+			continue
+		}
+		if takesValue && !hasValue {
+			i++ // the value is the next argument
+		}
+	}
+	return out
+}
+
+// flagName returns the name of a flag argument such as --x or -x=1 and
+// whether it carries its value, an empty name if it is no flag
+func flagName(arg string) (string, bool) {
+	if !strings.HasPrefix(arg, "-") || arg == "-" || arg == "--" {
+		return "", false
+	}
+	name := strings.TrimLeft(arg, "-")
+	name, _, hasValue := strings.Cut(name, "=")
+	return name, hasValue
+}
+
+// bisect narrows a failure at a hop down to the first release that fails
+// a two-hop journey from the version before the hop. It assumes that a
+// release that fails keeps failing on every later one.
+func bisect(ctx context.Context, rootDir string, failedHop int) (bisectResult, error) {
+	start := time.Now()
+	result := bisectResult{FailedHop: failedHop}
+	if failedHop == 0 {
+		return result, fmt.Errorf("the journey failed on its first version %s, there is nothing to bisect",
+			versions[0])
+	}
+
+	result.Good, result.Bad = versions[failedHop-1], versions[failedHop]
+	releases, err := retrieveVersionListFromGH()
+	if err != nil {
+		return result, fmt.Errorf("list releases: %w", err)
+	}
+	result.Candidates = bisectCandidates(releases, result.Good, result.Bad)
+
+	fails := func(candidate string) (bool, error) {
+		result.Runs++
+		return reducedJourneyFails(ctx, rootDir, result.Good, candidate)
+	}
+
+	// the failure may depend on the hops before, then it does not show in a
+	// two-hop journey and bisection has no answer
+	result.Reproduced, err = fails(result.Bad)
+	if err != nil || !result.Reproduced {
+		result.TookSeconds = time.Since(start).Seconds()
+		return result, err
+	}
+
+	lo, hi := 0, len(result.Candidates)-1
+	for lo < hi {
+		mid := (lo + hi) / 2
+		failed, err := fails(result.Candidates[mid])
+		if err != nil {
+			result.TookSeconds = time.Since(start).Seconds()
+			return result, err
+		}
+		if failed {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+
+	result.FirstBad, result.LastGood = result.Candidates[lo], result.Good
+	if lo > 0 {
+		result.LastGood = result.Candidates[lo-1]
+	}
+	result.TookSeconds = time.Since(start).Seconds()
+	return result, nil
+}
+
+// reducedJourneyFails runs the harness as a journey from good to candidate
+// and reports whether it failed. Its artifacts go to a directory of their
+// own, an error means the journey could not be run at all.
+func reducedJourneyFails(ctx context.Context, rootDir, good, candidate string) (bool, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return false, err
+	}
+
+	// the nodes of the previous journey left their data behind, a fresh
+	// cluster must not start on it. The files belong to the nodes, so they
+	// are moved aside instead of deleted.
+	data := path.Join(rootDir, "data")
+	if _, err := os.Stat(data); err == nil {
+		aside := path.Join(rootDir, "bisect-data", fmt.Sprintf("%d", time.Now().UnixNano()))
+		if err := os.MkdirAll(path.Dir(aside), 0o755); err != nil {
+			return false, err
+		}
+		if err := os.Rename(data, aside); err != nil {
+			return false, fmt.Errorf("move data of the previous journey aside: %w", err)
+		}
+	}
+
+	dir := path.Join(artifactsDir, "bisect", fmt.Sprintf("%s-%s", good, candidate))
+	logger.Info("running reduced journey", "from", good, "to", candidate, "artifacts", dir)
+
+	cmd := exec.CommandContext(ctx, executable, bisectArgs(os.Args[1:], good, candidate)...)
+	cmd.Env = append(os.Environ(), "ARTIFACTS_DIR="+dir)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	err = cmd.Run()
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		logger.Info("reduced journey passed", "from", good, "to", candidate)
+		return false, nil
+	case errors.As(err, &exitErr):
+		logger.Info("reduced journey failed", "from", good, "to", candidate, "exit_code", exitErr.ExitCode())
+		return true, nil
+	default:
+		return false, fmt.Errorf("run reduced journey to %s: %w", candidate, err)
+	}
+}
+
+// reportBisect logs what a bisection found and writes it to the artifacts
+func reportBisect(result bisectResult, err error) {
+	switch {
+	case err != nil:
+		logger.Error("bisection failed", "err", err)
+	case !result.Reproduced:
+		logger.Warn("failure does not reproduce in a journey from the previous version, cannot bisect",
+			"from", result.Good, "to", result.Bad)
+	default:
+		logger.Error("bisected regression", "first_bad", result.FirstBad, "last_good", result.LastGood,
+			"runs", result.Runs)
+	}
+
+	raw, jsonErr := json.MarshalIndent(result, "", "  ")
+	if jsonErr == nil {
+		jsonErr = os.MkdirAll(artifactsDir, 0o755)
+	}
+	if jsonErr == nil {
+		jsonErr = os.WriteFile(path.Join(artifactsDir, "bisect.json"), raw, 0o644)
+	}
+	if jsonErr != nil {
+		logger.Error("cannot write bisection result", "err", jsonErr)
+	}
+}
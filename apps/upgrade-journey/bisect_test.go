@@ -0,0 +1,38 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_bisectCandidates(t *testing.T) {
+	releases := []string{"v1.24.2", "v1.24.0", "v1.24.1", "v1.23.9", "v1.25.0-rc.0", "v1.25.0", "v1.24.3"}
+	tests := []struct {
+		name      string
+		good, bad string
+		want      []string
+	}{
+		{name: "patches between", good: "1.24.0", bad: "1.24.3", want: []string{"1.24.1", "1.24.2", "1.24.3"}},
+		{name: "adjacent", good: "1.24.2", bad: "1.24.3", want: []string{"1.24.3"}},
+		{name: "channel", good: "1.24.3", bad: "nightly", want: []string{"1.25.0", "nightly"}},
+		{name: "after a channel", good: "latest", bad: "nightly", want: []string{"nightly"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bisectCandidates(releases, tt.good, tt.bad); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("wanted %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_bisectArgs(t *testing.T) {
+	args := []string{
+		"--bisect", "--nodes=5", "--latest-releases", "5", "--channels=latest", "-progress",
+		"--faults", "kill:node=1", "--path-policy", "minor",
+	}
+	want := []string{"--nodes=5", "--faults", "kill:node=1", "--versions=1.24.0,1.24.2"}
+	if got := bisectArgs(args, "1.24.0", "1.24.2"); !reflect.DeepEqual(got, want) {
+		t.Errorf("wanted %v, got %v", want, got)
+	}
+}
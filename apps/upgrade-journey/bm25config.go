@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+const bm25ConfigClass = "BM25Config"
+
+// bm25Config is the part of the inverted index config of a class that
+// changes how BM25 ranks and which words it ignores
+type bm25Config struct {
+	K1        float64
+	B         float64
+	Preset    string
+	Additions []string
+	Removals  []string
+}
+
+// customBM25Config is far enough from the defaults, k1 1.2, b 0.75 and the
+// plain en preset, that a class which falls back to them ranks and filters
+// the probe documents differently. Without length normalization the long
+// document, which has the term twice, ranks above the short one.
+var customBM25Config = bm25Config{
+	K1:        1.5,
+	B:         0,
+	Preset:    "en",
+	Additions: []string{"chaos"},
+	Removals:  []string{"the"},
+}
+
+// bm25ConfigDocs are the documents of the class by name
+var bm25ConfigDocs = map[string]string{
+	"short": "apple",
+	"long":  "apple apple " + bm25Filler(40),
+	"the":   "the quick fox",
+	"chaos": "chaos monkey",
+}
+
+func bm25Filler(words int) string {
+	filler := make([]string, words)
+	for i := range filler {
+		filler[i] = fmt.Sprintf("pear%02d", i)
+	}
+	return strings.Join(filler, " ")
+}
+
+// bm25ConfigSearches are the searches of the probe with the documents they
+// return in rank order under the custom config. With the defaults the
+// short document would rank first, "chaos" would not be a stopword and
+// "the" would be one.
+var bm25ConfigSearches = []struct {
+	query string
+	want  []string
+}{
+	{query: "apple", want: []string{"long", "short"}},
+	{query: "chaos", want: nil},
+	{query: "the", want: []string{"the"}},
+}
+
+// bm25ConfigProbe writes the class with custom BM25 parameters and its
+// documents once, on the first hop of a version that has hybrid search
+var bm25ConfigProbe probeImports
+
+// bm25ConfigVerifier checks on every hop that a class with custom BM25
+// parameters and stopwords still reports them in its schema and still
+// ranks by them, instead of silently falling back to the defaults
+type bm25ConfigVerifier struct{}
+
+func (v *bm25ConfigVerifier) name() string {
+	return "bm25 config"
+}
+
+func (v *bm25ConfigVerifier) verify(ctx context.Context, posOfMaxVersion int) error {
+	if !supports(versions[posOfMaxVersion], featureHybrid) {
+		return nil
+	}
+
+	got, err := storedBM25Config(ctx)
+	if err != nil {
+		return err
+	}
+	if diff := diffBM25Config(customBM25Config, got); len(diff) > 0 {
+		return fmt.Errorf("class %s lost its BM25 config: %s", bm25ConfigClass, strings.Join(diff, ", "))
+	}
+
+	for _, search := range bm25ConfigSearches {
+		names, err := bm25Names(ctx, search.query)
+		if err != nil {
+			return fmt.Errorf("bm25 %q: %w", search.query, err)
+		}
+		if !reflect.DeepEqual(names, search.want) {
+			return fmt.Errorf("bm25 %q on %s: wanted %v, got %v", search.query, bm25ConfigClass, search.want,
+				names)
+		}
+	}
+	return nil
+}
+
+func createBM25ConfigClass(ctx context.Context) error {
+	if _, err := postRaw(ctx, 0, "/v1/schema", map[string]interface{}{
+		"class":      bm25ConfigClass,
+		"vectorizer": "none",
+		"invertedIndexConfig": map[string]interface{}{
+			"bm25": map[string]interface{}{"k1": customBM25Config.K1, "b": customBM25Config.B},
+			"stopwords": map[string]interface{}{
+				"preset":    customBM25Config.Preset,
+				"additions": customBM25Config.Additions,
+				"removals":  customBM25Config.Removals,
+			},
+		},
+		"properties": []map[string]interface{}{
+			{"name": "name", "dataType": []string{"text"}},
+			{"name": "text", "dataType": []string{"text"}},
+		},
+	}); err != nil {
+		return fmt.Errorf("create class %s: %w", bm25ConfigClass, err)
+	}
+	return nil
+}
+
+func importBM25ConfigDocs(ctx context.Context) error {
+	var objects []map[string]interface{}
+	for i, name := range sortedMapKeys(bm25ConfigDocs) {
+		objects = append(objects, map[string]interface{}{
+			"class":      bm25ConfigClass,
+			"id":         probeID(bm25ConfigClass, 0, i),
+			"vector":     []float32{float32(i), 1},
+			"properties": map[string]interface{}{"name": name, "text": bm25ConfigDocs[name]},
+		})
+	}
+	if _, err := postRaw(ctx, 0, "/v1/batch/objects", map[string]interface{}{"objects": objects}); err != nil {
+		return fmt.Errorf("import %s: %w", bm25ConfigClass, err)
+	}
+	return nil
+}
+
+func storedBM25Config(ctx context.Context) (bm25Config, error) {
+	body, err := getRaw(ctx, 0, "/v1/schema/"+bm25ConfigClass)
+	if err != nil {
+		return bm25Config{}, err
+	}
+
+	var class struct {
+		InvertedIndexConfig struct {
+			BM25 struct {
+				K1 float64 `json:"k1"`
+				B  float64 `json:"b"`
+			} `json:"bm25"`
+			Stopwords struct {
+				Preset    string   `json:"preset"`
+				Additions []string `json:"additions"`
+				Removals  []string `json:"removals"`
+			} `json:"stopwords"`
+		} `json:"invertedIndexConfig"`
+	}
+	if err := json.Unmarshal(body, &class); err != nil {
+		return bm25Config{}, err
+	}
+
+	config := class.InvertedIndexConfig
+	return bm25Config{
+		K1:        config.BM25.K1,
+		B:         config.BM25.B,
+		Preset:    config.Stopwords.Preset,
+		Additions: config.Stopwords.Additions,
+		Removals:  config.Stopwords.Removals,
+	}, nil
+}
+
+// diffBM25Config describes every setting that differs from the one the
+// class was created with
+func diffBM25Config(want, got bm25Config) []string {
+	var diff []string
+	if got.K1 != want.K1 {
+		diff = append(diff, fmt.Sprintf("k1 is %g, wanted %g", got.K1, want.K1))
+	}
+	if got.B != want.B {
+		diff = append(diff, fmt.Sprintf("b is %g, wanted %g", got.B, want.B))
+	}
+	if got.Preset != want.Preset {
+		diff = append(diff, fmt.Sprintf("stopword preset is %q, wanted %q", got.Preset, want.Preset))
+	}
+	if strings.Join(got.Additions, ",") != strings.Join(want.Additions, ",") {
+		diff = append(diff, fmt.Sprintf("stopword additions are %v, wanted %v", got.Additions, want.Additions))
+	}
+	if strings.Join(got.Removals, ",") != strings.Join(want.Removals, ",") {
+		diff = append(diff, fmt.Sprintf("stopword removals are %v, wanted %v", got.Removals, want.Removals))
+	}
+	return diff
+}
+
+// bm25Names returns the names of the documents a BM25 search finds, in rank
+// order
+func bm25Names(ctx context.Context, query string) ([]string, error) {
+	body, err := postRaw(ctx, 0, "/v1/graphql", map[string]interface{}{
+		"query": fmt.Sprintf(`{Get{%s(bm25:{query:%q,properties:["text"]}){name}}}`, bm25ConfigClass, query),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var res struct {
+		Data struct {
+			Get map[string][]struct {
+				Name string `json:"name"`
+			} `json:"Get"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, err
+	}
+	if len(res.Errors) > 0 {
+		return nil, fmt.Errorf("graphql: %s", res.Errors[0].Message)
+	}
+
+	var names []string
+	for _, hit := range res.Data.Get[bm25ConfigClass] {
+		names = append(names, hit.Name)
+	}
+	return names, nil
+}
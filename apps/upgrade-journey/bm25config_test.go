@@ -0,0 +1,31 @@
+package main
+
+import "testing"
+
+func Test_diffBM25Config(t *testing.T) {
+	tests := []struct {
+		name     string
+		got      bm25Config
+		wantDiff int
+	}{
+		{name: "kept", got: customBM25Config},
+		{
+			name:     "defaults",
+			got:      bm25Config{K1: 1.2, B: 0.75, Preset: "en"},
+			wantDiff: 4,
+		},
+		{
+			name: "stopwords reset",
+			got: bm25Config{K1: customBM25Config.K1, B: customBM25Config.B, Preset: "none",
+				Additions: customBM25Config.Additions},
+			wantDiff: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := diffBM25Config(customBM25Config, tt.got); len(diff) != tt.wantDiff {
+				t.Errorf("wanted %d differences, got %v", tt.wantDiff, diff)
+			}
+		})
+	}
+}
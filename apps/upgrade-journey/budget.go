@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type phase string
+
+const (
+	phaseStart   phase = "start"
+	phaseUpgrade phase = "upgrade"
+	phaseImport  phase = "import"
+	phaseVerify  phase = "verify"
+)
+
+// phases are the phases a hop runs. A hop either starts or upgrades the
+// cluster, never both.
+var phases = map[phase]bool{
+	phaseStart:   true,
+	phaseUpgrade: true,
+	phaseImport:  true,
+	phaseVerify:  true,
+}
+
+// budget enforces a deadline for the whole run as well as a time limit for
+// every single phase, so that a hung node fails the run with an error that
+// names the phase, instead of the CI job being killed without context.
+//
+// The hops are sized by the work the plan estimates for them, so that the
+// first hop, which pulls images and starts the nodes, and the hop that
+// migrates to raft get more of the run than a rolling update. Every phase of
+// a hop, including the checks and faults that run after its verification,
+// may use what is left of the hop, and a hop may use what the hops before
+// it did not. A phase always gets at least the estimate of its hop, so that
+// a run that is behind schedule only fails on the deadline of the run.
+type budget struct {
+	deadline time.Time
+
+	// ends is when every hop is due, estimates the estimate of every hop
+	ends      []time.Time
+	estimates []time.Duration
+}
+
+// newBudget splits maxDuration across the hops in proportion to their
+// estimates. A zero maxDuration disables all limits.
+func newBudget(maxDuration time.Duration, estimates []time.Duration) *budget {
+	if maxDuration == 0 {
+		return &budget{}
+	}
+
+	var total time.Duration
+	for _, estimate := range estimates {
+		total += estimate
+	}
+
+	start := time.Now()
+	b := &budget{deadline: start.Add(maxDuration), estimates: estimates}
+	var done time.Duration
+	for _, estimate := range estimates {
+		done += estimate
+		b.ends = append(b.ends, start.Add(time.Duration(float64(maxDuration)*float64(done)/float64(total))))
+	}
+	return b
+}
+
+// phaseLimit is how long a phase of a hop may take
+func (b *budget) phaseLimit(hop int) time.Duration {
+	remaining := time.Until(b.deadline)
+	if len(b.ends) == 0 {
+		return remaining
+	}
+	if hop >= len(b.ends) {
+		hop = len(b.ends) - 1
+	}
+
+	limit := time.Until(b.ends[hop])
+	if limit < b.estimates[hop] {
+		limit = b.estimates[hop]
+	}
+	if remaining < limit {
+		return remaining
+	}
+	return limit
+}
+
+// run executes fn for the given hop with a context that is cancelled once
+// the phase exceeds its budget
+func (b *budget) run(ctx context.Context, hop int, p phase, fn func(ctx context.Context) error) error {
+	runProgress.set(hop, p)
+	log := hopLogger(hop).With("phase", p)
+	log.Debug("phase started")
+
+	start := time.Now()
+	err := b.runWithLimit(ctx, hop, p, withHooks(hop, p, fn))
+	runReport.recordPhase(hop, p, start, err)
+	if err != nil {
+		log.Error("phase failed", "took", time.Since(start), "err", err)
+		return err
+	}
+
+	log.Info("phase completed", "took", time.Since(start))
+	return nil
+}
+
+func (b *budget) runWithLimit(ctx context.Context, hop int, p phase, fn func(ctx context.Context) error) error {
+	if b.deadline.IsZero() {
+		return requestTimedOut(p, fn(ctx))
+	}
+
+	limit := b.phaseLimit(hop)
+	if limit <= 0 {
+		return fmt.Errorf("run exceeded max duration before phase %s", p)
+	}
+
+	phaseCtx, cancel := context.WithTimeout(ctx, limit)
+	defer cancel()
+
+	err := fn(phaseCtx)
+	if err != nil && errors.Is(phaseCtx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("phase %s exceeded budget of %s: %w", p, limit.Round(time.Second), err)
+	}
+
+	return requestTimedOut(p, err)
+}
+
+// requestTimedOut names the phase of a request that ran into its own
+// deadline while the phase still had time left
+func requestTimedOut(p phase, err error) error {
+	if err == nil || !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	return fmt.Errorf("phase %s: %w", p, err)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_newBudget(t *testing.T) {
+	estimates := []time.Duration{3 * time.Minute, time.Minute, 2 * time.Minute, time.Minute, time.Minute}
+	b := newBudget(8*time.Minute, estimates)
+
+	// the hops are due in proportion to their estimates
+	wantDue := []time.Duration{3 * time.Minute, 4 * time.Minute, 6 * time.Minute, 7 * time.Minute, 8 * time.Minute}
+	for i, want := range wantDue {
+		if got := time.Until(b.ends[i]); got > want || got < want-time.Second {
+			t.Errorf("hop %d: wanted due in %s, got %s", i, want, got)
+		}
+	}
+	if got := time.Until(b.deadline); got > 8*time.Minute || got < 8*time.Minute-time.Second {
+		t.Errorf("wanted the deadline in 8m, got %s", got)
+	}
+}
+
+func Test_budget_phaseLimit(t *testing.T) {
+	now := time.Now()
+	b := &budget{
+		deadline:  now.Add(10 * time.Minute),
+		ends:      []time.Time{now.Add(4 * time.Minute), now.Add(5 * time.Minute), now.Add(-time.Minute)},
+		estimates: []time.Duration{8 * time.Minute, time.Minute, 2 * time.Minute},
+	}
+
+	tests := []struct {
+		name string
+		hop  int
+		want time.Duration
+	}{
+		{name: "the estimate of a hop is the least a phase gets", hop: 0, want: 8 * time.Minute},
+		{name: "a phase gets what is left of its hop", hop: 1, want: 5 * time.Minute},
+		{name: "a hop behind schedule still gets its estimate", hop: 2, want: 2 * time.Minute},
+		{name: "checks after the last hop count towards it", hop: 3, want: 2 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := b.phaseLimit(tt.hop); got > tt.want || got < tt.want-time.Second {
+				t.Errorf("wanted %s, got %s", tt.want, got)
+			}
+		})
+	}
+
+	b.deadline = now.Add(3 * time.Minute)
+	if got := b.phaseLimit(0); got > 3*time.Minute {
+		t.Errorf("wanted the deadline of the run to cap the phase, got %s", got)
+	}
+}
+
+func Test_budget_runWithLimit(t *testing.T) {
+	unlimited := newBudget(0, []time.Duration{time.Minute})
+	if err := unlimited.runWithLimit(context.Background(), 0, phaseVerify, func(ctx context.Context) error {
+		if _, ok := ctx.Deadline(); ok {
+			t.Errorf("wanted no deadline without max duration")
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	exceeded := &budget{deadline: time.Now().Add(-time.Second)}
+	err := exceeded.runWithLimit(context.Background(), 0, phaseVerify, func(ctx context.Context) error {
+		t.Errorf("wanted the phase not to run after the deadline")
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "exceeded max duration before phase verify") {
+		t.Errorf("wanted the run to exceed its max duration, got %v", err)
+	}
+}
+
+func Test_planEstimates(t *testing.T) {
+	defer func(v []string, n int) { versions, nodeCount = v, n }(versions, nodeCount)
+	versions, nodeCount = []string{"1.24.0", "1.24.1", "1.25.0", "1.25.1"}, 3
+
+	estimates := planEstimates([]verifier{&goVerifier{}}, nil, 0)
+	if len(estimates) != len(versions) {
+		t.Fatalf("wanted %d estimates, got %d", len(versions), len(estimates))
+	}
+	update := estimates[1]
+	if estimates[0] <= update {
+		t.Errorf("wanted the first hop above a rolling update of %s, got %s", update, estimates[0])
+	}
+	if estimates[2] <= update {
+		t.Errorf("wanted the raft migration above a rolling update of %s, got %s", update, estimates[2])
+	}
+
+	withFaults := planEstimates([]verifier{&goVerifier{}}, []string{"pause"}, 10*time.Second)
+	if withFaults[1] < update+10*time.Second {
+		t.Errorf("wanted a fault to add its hold to the hop, got %s", withFaults[1])
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	wcluster "github.com/weaviate/weaviate-chaos-engineering/apps/upgrade-journey/pkg/cluster"
+)
+
+// runCleanup implements the cleanup subcommand. It removes the containers,
+// networks and volumes that earlier runs left behind, e.g. when a CI runner
+// killed the job before it could tear down its cluster.
+func runCleanup(args []string) {
+	fs := flag.NewFlagSet("cleanup", flag.ExitOnError)
+	olderThan := fs.Duration("older-than", time.Hour,
+		"only remove objects created before this long ago, so that runs in progress are kept")
+	runID := fs.String("run-id", "", "only remove the objects of this run, as logged at its start")
+	dryRun := fs.Bool("dry-run", false, "list the leftovers without removing them")
+	fs.Parse(args)
+
+	removed, err := wcluster.CollectGarbage(context.Background(), *runID, *olderThan, *dryRun)
+	verb := "removed"
+	if *dryRun {
+		verb = "would remove"
+	}
+	for _, g := range removed {
+		fmt.Printf("%s %s\n", verb, g)
+	}
+	if err != nil {
+		fatal("cannot clean up", "err", err)
+	}
+
+	fmt.Printf("%s %d leftovers of earlier runs\n", verb, len(removed))
+}
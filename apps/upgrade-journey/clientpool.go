@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/weaviate/weaviate-go-client/v4/weaviate"
+)
+
+// routing decides which node a workload talks to
+type routing struct {
+	mode string
+	node int
+}
+
+const (
+	routeNode       = "node"
+	routeAny        = "any"
+	routeRoundRobin = "round-robin"
+)
+
+// parseRouting parses any, round-robin or node:N
+func parseRouting(value string) (routing, error) {
+	switch {
+	case value == routeAny, value == routeRoundRobin:
+		return routing{mode: value}, nil
+	case strings.HasPrefix(value, routeNode+":"):
+		node, err := strconv.Atoi(strings.TrimPrefix(value, routeNode+":"))
+		if err != nil || node < 0 || node >= nodeCount {
+			return routing{}, fmt.Errorf("routing %q: node must be between 0 and %d", value, nodeCount-1)
+		}
+		return routing{mode: routeNode, node: node}, nil
+	default:
+		return routing{}, fmt.Errorf("routing %q: must be any, round-robin or node:N", value)
+	}
+}
+
+func (r routing) String() string {
+	if r.mode == routeNode {
+		return fmt.Sprintf("%s:%d", routeNode, r.node)
+	}
+	return r.mode
+}
+
+// clientPool holds one client per node together with whether the node was
+// ready the last time it was checked
+type clientPool struct {
+	sync.Mutex
+	clients []*weaviate.Client
+	hosts   []string
+	healthy []bool
+	next    int
+
+	readiness *http.Client
+}
+
+func newClientPool(nodeCount int) *clientPool {
+	p := &clientPool{
+		clients:   make([]*weaviate.Client, nodeCount),
+		hosts:     make([]string, nodeCount),
+		healthy:   make([]bool, nodeCount),
+		readiness: &http.Client{Timeout: 2 * time.Second},
+	}
+
+	for i := range p.clients {
+		p.hosts[i] = nodeHost(i)
+		p.clients[i] = weaviate.New(weaviate.Config{Host: p.hosts[i], Scheme: "http"})
+		p.healthy[i] = true
+	}
+	return p
+}
+
+func (p *clientPool) node(nodeId int) *weaviate.Client {
+	return p.clients[nodeId]
+}
+
+// refresh checks the readiness of every node
+func (p *clientPool) refresh(ctx context.Context) {
+	for i, host := range p.hosts {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+			fmt.Sprintf("http://%s/v1/.well-known/ready", host), nil)
+		if err != nil {
+			p.setHealthy(i, false)
+			continue
+		}
+
+		res, err := p.readiness.Do(req)
+		if err != nil {
+			p.setHealthy(i, false)
+			continue
+		}
+		res.Body.Close()
+		p.setHealthy(i, res.StatusCode >= 200 && res.StatusCode <= 299)
+	}
+}
+
+func (p *clientPool) setHealthy(nodeId int, healthy bool) {
+	p.Lock()
+	defer p.Unlock()
+	p.healthy[nodeId] = healthy
+}
+
+// pick returns the client to use for the routing and the node it talks to.
+// A specific node is returned even if it is unhealthy, so that node-targeted
+// verification fails loudly instead of silently moving elsewhere.
+func (p *clientPool) pick(r routing) (*weaviate.Client, int, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	switch r.mode {
+	case routeNode:
+		return p.clients[r.node], r.node, nil
+	case routeAny:
+		for i, healthy := range p.healthy {
+			if healthy {
+				return p.clients[i], i, nil
+			}
+		}
+	case routeRoundRobin:
+		for offset := 0; offset < len(p.clients); offset++ {
+			i := (p.next + offset) % len(p.clients)
+			if p.healthy[i] {
+				p.next = i + 1
+				return p.clients[i], i, nil
+			}
+		}
+	default:
+		return nil, 0, fmt.Errorf("unknown routing %q", r.mode)
+	}
+
+	return nil, 0, fmt.Errorf("routing %s: no healthy node", r)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRouting(t *testing.T) {
+	for value, want := range map[string]routing{
+		"any":         {mode: routeAny},
+		"round-robin": {mode: routeRoundRobin},
+		"node:2":      {mode: routeNode, node: 2},
+	} {
+		got, err := parseRouting(value)
+		if err != nil {
+			t.Fatalf("%s: %v", value, err)
+		}
+		if got != want {
+			t.Errorf("%s: wanted %+v, got %+v", value, want, got)
+		}
+	}
+
+	for _, value := range []string{"", "node", "node:x", "node:-1", "node:3", "random"} {
+		if _, err := parseRouting(value); err == nil {
+			t.Errorf("%q: wanted an error", value)
+		}
+	}
+}
+
+func TestClientPoolPick(t *testing.T) {
+	p := newClientPool(3)
+	p.setHealthy(0, false)
+
+	if _, node, err := p.pick(routing{mode: routeAny}); err != nil || node != 1 {
+		t.Errorf("any: wanted node 1, got %d (%v)", node, err)
+	}
+
+	var picked []int
+	for i := 0; i < 4; i++ {
+		_, node, err := p.pick(routing{mode: routeRoundRobin})
+		if err != nil {
+			t.Fatal(err)
+		}
+		picked = append(picked, node)
+	}
+	if want := []int{1, 2, 1, 2}; !reflect.DeepEqual(picked, want) {
+		t.Errorf("round-robin: wanted %v, got %v", want, picked)
+	}
+
+	if _, node, err := p.pick(routing{mode: routeNode, node: 0}); err != nil || node != 0 {
+		t.Errorf("node:0 is picked even when unhealthy, got %d (%v)", node, err)
+	}
+
+	for i := range p.healthy {
+		p.setHealthy(i, false)
+	}
+	if _, _, err := p.pick(routing{mode: routeAny}); err == nil {
+		t.Error("wanted an error without healthy nodes")
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/testcontainers/testcontainers-go"
+	wcluster "github.com/weaviate/weaviate-chaos-engineering/apps/upgrade-journey/pkg/cluster"
+)
+
+// verifier runs the read-only verification of a single hop. Every hop is
+// verified by each configured verifier, so that a compatibility break
+// between a client and a server version shows up as a failing hop.
+type verifier interface {
+	name() string
+	verify(ctx context.Context, posOfMaxVersion int) error
+}
+
+// parseClientMatrix turns the value of --client-matrix into verifiers. Each
+// entry is one of
+//
+//	go               the in-process verifier, see --transport
+//	go:<version>     a specific v4 Go client release, built in a container
+//	python:<version> a specific v3 Python client release, run in a container
+//
+// Python clients from v4 on are rejected, as verify.py uses the v3 API.
+func parseClientMatrix(matrix string, rootDir string, inProcess verifier) ([]verifier, error) {
+	var out []verifier
+	for _, entry := range strings.Split(matrix, ",") {
+		entry = strings.TrimSpace(entry)
+		kind, version, _ := strings.Cut(entry, ":")
+
+		switch {
+		case kind == "go" && version == "":
+			out = append(out, inProcess)
+		case kind == "go":
+			out = append(out, &containerVerifier{
+				language: "go",
+				version:  version,
+				rootDir:  rootDir,
+			})
+		case kind == "python" && version != "":
+			major, _, _ := strings.Cut(version, ".")
+			if n, err := strconv.Atoi(major); err == nil && n >= 4 {
+				return nil, fmt.Errorf("invalid --client-matrix entry %q: only v3 python clients are supported", entry)
+			}
+			out = append(out, &containerVerifier{
+				language: "python",
+				version:  version,
+				rootDir:  rootDir,
+			})
+		default:
+			return nil, fmt.Errorf("invalid --client-matrix entry %q", entry)
+		}
+	}
+
+	return out, nil
+}
+
+// goVerifier runs verify with the Go client against the node picked by its
+// routing, re-picked on every verification
+type goVerifier struct {
+	pool    *clientPool
+	routing routing
+}
+
+func (v *goVerifier) name() string {
+	return "go"
+}
+
+func (v *goVerifier) verify(ctx context.Context, posOfMaxVersion int) error {
+	v.pool.refresh(ctx)
+	client, nodeId, err := v.pool.pick(v.routing)
+	if err != nil {
+		return err
+	}
+
+	if err := verify(ctx, client, posOfMaxVersion); err != nil {
+		return fmt.Errorf("weaviate-%d: %w", nodeId, err)
+	}
+	return nil
+}
+
+// containerVerifier runs one of the standalone verification programs in
+// clients/ against node 0. These programs only cover the object lookup by
+// version and the aggregation, as they have to work with every client
+// version in the matrix.
+type containerVerifier struct {
+	language string
+	version  string
+	rootDir  string
+}
+
+func (v *containerVerifier) name() string {
+	return fmt.Sprintf("%s:%s", v.language, v.version)
+}
+
+// goClientImage is set by --go-client-image
+var goClientImage string
+
+// image is the image the client of the language runs in
+func (v *containerVerifier) image() string {
+	if v.language == "python" {
+		return "python:3.11-slim"
+	}
+	return goClientImage
+}
+
+func (v *containerVerifier) verify(ctx context.Context, posOfMaxVersion int) error {
+	req := testcontainers.ContainerRequest{
+		Env: map[string]string{
+			"WEAVIATE_HOST":     nodeHost(0),
+			"EXPECTED_VERSIONS": strings.Join(versions[:posOfMaxVersion+1], ","),
+			"EXPECTED_COUNT":    fmt.Sprintf("%d", objectsCreated),
+			"CLIENT_VERSION":    v.version,
+			// lets go switch to the toolchain the go.mod of the client
+			// requires if the image is older
+			"GOTOOLCHAIN": "auto",
+		},
+		HostConfigModifier: func(hc *container.HostConfig) {
+			hc.NetworkMode = "host"
+		},
+		Labels: runLabels(),
+		Image:  v.image(),
+	}
+
+	src := path.Join(v.rootDir, "clients", v.language)
+	switch v.language {
+	case "go":
+		cache := path.Join(v.rootDir, "data", "gomodcache")
+		if err := os.MkdirAll(cache, 0o777); err != nil {
+			return err
+		}
+
+		req.Mounts = testcontainers.Mounts(
+			testcontainers.BindMount(src, "/src"),
+			testcontainers.BindMount(cache, "/go/pkg/mod"),
+		)
+		req.Cmd = []string{"sh", "-c", "cp -r /src /work && cd /work && " +
+			"go mod init verifier && " +
+			"go get github.com/weaviate/weaviate-go-client/v4@$CLIENT_VERSION && " +
+			"go mod tidy && go run ."}
+	case "python":
+		req.Mounts = testcontainers.Mounts(testcontainers.BindMount(src, "/src"))
+		req.Cmd = []string{"sh", "-c", "pip install -q weaviate-client==$CLIENT_VERSION && " +
+			"python /src/verify.py"}
+	}
+
+	_, err := wcluster.RunToCompletion(ctx, req)
+	return err
+}
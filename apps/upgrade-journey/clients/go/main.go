@@ -0,0 +1,92 @@
+// This program verifies a hop of the upgrade journey with an arbitrary v4 Go
+// client release. It is built inside a container with the requested client
+// version, so it must only use APIs that exist in all v4 releases.
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/weaviate/weaviate-go-client/v4/weaviate"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate/filters"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate/graphql"
+)
+
+func main() {
+	ctx := context.Background()
+	client := weaviate.New(weaviate.Config{
+		Host:   os.Getenv("WEAVIATE_HOST"),
+		Scheme: "http",
+	})
+
+	for _, version := range strings.Split(os.Getenv("EXPECTED_VERSIONS"), ",") {
+		if err := findObject(ctx, client, version); err != nil {
+			log.Fatalf("version %s: %v", version, err)
+		}
+	}
+
+	expected, err := strconv.Atoi(os.Getenv("EXPECTED_COUNT"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := aggregate(ctx, client, expected); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func findObject(ctx context.Context, client *weaviate.Client, version string) error {
+	where := filters.Where().
+		WithPath([]string{"version"}).
+		WithOperator(filters.Equal).
+		WithValueString(version)
+
+	result, err := client.GraphQL().Get().
+		WithClassName("Collection").
+		WithFields(graphql.Field{Name: "version"}, graphql.Field{Name: "ref_prop { ... on RefTarget {version} }"}).
+		WithWhere(where).
+		Do(ctx)
+	if err != nil {
+		return err
+	}
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("%v", result.Errors[0])
+	}
+
+	objs := result.Data["Get"].(map[string]interface{})["Collection"].([]interface{})
+	if len(objs) != 1 {
+		return fmt.Errorf("wanted 1 object, got %d", len(objs))
+	}
+
+	obj := objs[0].(map[string]interface{})
+	refVersion := obj["ref_prop"].([]interface{})[0].(map[string]interface{})["version"].(string)
+	if refVersion != version {
+		return fmt.Errorf("ref object: wanted %s got %s", version, refVersion)
+	}
+
+	return nil
+}
+
+func aggregate(ctx context.Context, client *weaviate.Client, expected int) error {
+	result, err := client.GraphQL().Aggregate().
+		WithClassName("Collection").
+		WithFields(graphql.Field{Name: "meta", Fields: []graphql.Field{{Name: "count"}}}).
+		Do(ctx)
+	if err != nil {
+		return err
+	}
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("%v", result.Errors[0])
+	}
+
+	count := result.Data["Aggregate"].(map[string]interface{})["Collection"].([]interface{})[0].(map[string]interface{})["meta"].(map[string]interface{})["count"].(float64)
+	if int(count) != expected {
+		return fmt.Errorf("aggregation: wanted %d, got %d", expected, int(count))
+	}
+
+	return nil
+}
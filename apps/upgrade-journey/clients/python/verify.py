@@ -0,0 +1,56 @@
+"""Verifies a hop of the upgrade journey with an arbitrary v3 Python client
+release. Only APIs that exist in all v3 releases may be used."""
+
+import os
+import sys
+
+import weaviate
+
+
+def find_object(client, version):
+    result = (
+        client.query.get("Collection", ["version", "ref_prop { ... on RefTarget {version} }"])
+        .with_where({"path": ["version"], "operator": "Equal", "valueString": version})
+        .do()
+    )
+    if "errors" in result:
+        raise Exception(result["errors"])
+
+    objs = result["data"]["Get"]["Collection"]
+    if len(objs) != 1:
+        raise Exception(f"wanted 1 object, got {len(objs)}")
+
+    ref_version = objs[0]["ref_prop"][0]["version"]
+    if ref_version != version:
+        raise Exception(f"ref object: wanted {version} got {ref_version}")
+
+
+def aggregate(client, expected):
+    result = client.query.aggregate("Collection").with_meta_count().do()
+    if "errors" in result:
+        raise Exception(result["errors"])
+
+    count = result["data"]["Aggregate"]["Collection"][0]["meta"]["count"]
+    if count != expected:
+        raise Exception(f"aggregation: wanted {expected}, got {count}")
+
+
+def main():
+    client = weaviate.Client(f"http://{os.environ['WEAVIATE_HOST']}")
+
+    for version in os.environ["EXPECTED_VERSIONS"].split(","):
+        try:
+            find_object(client, version)
+        except Exception as e:
+            print(f"version {version}: {e}")
+            sys.exit(1)
+
+    try:
+        aggregate(client, int(os.environ["EXPECTED_COUNT"]))
+    except Exception as e:
+        print(e)
+        sys.exit(1)
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,40 @@
+package main
+
+import "testing"
+
+func Test_parseClientMatrix(t *testing.T) {
+	tests := []struct {
+		matrix    string
+		wantNames []string
+		wantErr   bool
+	}{
+		{matrix: "go", wantNames: []string{"go"}},
+		{matrix: "go, go:v4.15.1, python:3.26.7", wantNames: []string{"go", "go:v4.15.1", "python:3.26.7"}},
+		{matrix: "python:4.0.0", wantErr: true},
+		{matrix: "python:4.4.0b1", wantErr: true},
+		{matrix: "python", wantErr: true},
+		{matrix: "java:5.0.0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.matrix, func(t *testing.T) {
+			got, err := parseClientMatrix(tt.matrix, t.TempDir(), &goVerifier{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("wanted error %t, got %v", tt.wantErr, err)
+			}
+
+			var names []string
+			for _, v := range got {
+				names = append(names, v.name())
+			}
+			if len(names) != len(tt.wantNames) {
+				t.Fatalf("wanted %v, got %v", tt.wantNames, names)
+			}
+			for i := range names {
+				if names[i] != tt.wantNames[i] {
+					t.Errorf("wanted %v, got %v", tt.wantNames, names)
+				}
+			}
+		})
+	}
+}
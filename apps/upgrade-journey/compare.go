@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// runCompare implements the compare subcommand. It exits with a non-zero
+// code if the latest run of the scenario regressed compared to the runs
+// before it.
+func runCompare(args []string) {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	store := fs.String("results-store", "", "local JSON file or s3://bucket/key with the run history")
+	scenario := fs.String("scenario", "default", "scenario whose runs are compared")
+	last := fs.Int("last", 5, "number of previous runs the latest run is compared to")
+	threshold := fs.Float64("threshold", 1.25,
+		"factor by which a metric may exceed the average of the previous runs")
+	fs.Parse(args)
+
+	if *store == "" {
+		fatal("invalid flags", "err", "missing --results-store")
+	}
+
+	runs, err := resultsStore{location: *store}.load(context.Background())
+	if err != nil {
+		fatal("cannot load results", "err", err)
+	}
+
+	regressions, err := compareRuns(runs, *scenario, *last, *threshold)
+	if err != nil {
+		fatal("cannot compare runs", "err", err)
+	}
+
+	if len(regressions) == 0 {
+		fmt.Printf("no regressions in scenario %s\n", *scenario)
+		return
+	}
+
+	for _, r := range regressions {
+		fmt.Printf("regression: %s\n", r)
+	}
+	os.Exit(1)
+}
+
+// compareRuns compares the latest run of the scenario to the average of up
+// to last runs before it
+func compareRuns(runs []runSummary, scenario string, last int, threshold float64) ([]string, error) {
+	var history []runSummary
+	for _, run := range runs {
+		if run.Scenario == scenario {
+			history = append(history, run)
+		}
+	}
+
+	if len(history) < 2 {
+		return nil, fmt.Errorf("scenario %s needs at least 2 runs, got %d", scenario, len(history))
+	}
+
+	latest := history[len(history)-1]
+	previous := history[:len(history)-1]
+	if len(previous) > last {
+		previous = previous[len(previous)-last:]
+	}
+
+	var regressions []string
+	if !latest.Passed {
+		passed := 0
+		for _, run := range previous {
+			if run.Passed {
+				passed++
+			}
+		}
+		regressions = append(regressions, fmt.Sprintf(
+			"latest run failed (%s), %d of the %d previous runs passed", latest.Error, passed, len(previous)))
+	}
+
+	// metrics that are better when higher, such as recall, regress once they
+	// fall below the average divided by the threshold. A run that did not
+	// measure such a metric records it as zero and is left out.
+	metrics := []struct {
+		name           string
+		get            func(runSummary) float64
+		higherIsBetter bool
+	}{
+		{"duration_seconds", func(r runSummary) float64 { return r.DurationSeconds }, false},
+		{"verify_latency_ms", func(r runSummary) float64 { return r.VerifyLatencyMs }, false},
+		{"max_memory_mib", func(r runSummary) float64 { return r.MaxMemoryMiB }, false},
+		{"max_disk_mib", func(r runSummary) float64 { return r.MaxDiskMiB }, false},
+		{"failed_phases", func(r runSummary) float64 { return float64(r.FailedPhases) }, false},
+		{"failed_faults", func(r runSummary) float64 { return float64(r.FailedFaults) }, false},
+		{"async_indexing_recall", func(r runSummary) float64 { return r.Recall[recallAsyncIndexing] }, true},
+		{"filtered_search_recall", func(r runSummary) float64 { return r.Recall[recallFilteredSearch] }, true},
+	}
+
+	for _, m := range metrics {
+		if m.higherIsBetter {
+			value := m.get(latest)
+			var measured []float64
+			for _, run := range previous {
+				if v := m.get(run); v > 0 {
+					measured = append(measured, v)
+				}
+			}
+			if value == 0 || len(measured) == 0 {
+				continue
+			}
+
+			avg := 0.0
+			for _, v := range measured {
+				avg += v / float64(len(measured))
+			}
+			if value < avg/threshold {
+				regressions = append(regressions, fmt.Sprintf(
+					"%s is %.2f, the average of the previous %d runs is %.2f", m.name, value, len(measured), avg))
+			}
+			continue
+		}
+
+		avg := 0.0
+		for _, run := range previous {
+			avg += m.get(run) / float64(len(previous))
+		}
+
+		// a metric that was zero before regresses as soon as it is non-zero
+		if value := m.get(latest); value > avg*threshold && value > 0 {
+			regressions = append(regressions, fmt.Sprintf(
+				"%s is %.1f, the average of the previous %d runs is %.1f", m.name, value, len(previous), avg))
+		}
+	}
+
+	return regressions, nil
+}
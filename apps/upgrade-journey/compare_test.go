@@ -0,0 +1,101 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_compareRuns(t *testing.T) {
+	runs := []runSummary{
+		{Scenario: "default", Passed: true, DurationSeconds: 100, VerifyLatencyMs: 10},
+		{Scenario: "other", Passed: true, DurationSeconds: 1000, VerifyLatencyMs: 10},
+		{Scenario: "default", Passed: true, DurationSeconds: 110, VerifyLatencyMs: 12},
+		{Scenario: "default", Passed: true, DurationSeconds: 100, VerifyLatencyMs: 30},
+	}
+
+	regressions, err := compareRuns(runs, "default", 5, 1.25)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(regressions) != 1 || !strings.HasPrefix(regressions[0], "verify_latency_ms") {
+		t.Errorf("expected a latency regression only, got %v", regressions)
+	}
+
+	// with only the previous run as the baseline, the duration is fine
+	regressions, err = compareRuns(append(runs, runSummary{
+		Scenario: "default", Error: "boom", DurationSeconds: 105, VerifyLatencyMs: 30, FailedPhases: 1,
+	}), "default", 1, 1.25)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(regressions) != 2 {
+		t.Errorf("expected the failure and failed phases, got %v", regressions)
+	}
+
+	if _, err := compareRuns(runs, "other", 5, 1.25); err == nil {
+		t.Errorf("expected an error with a single run")
+	}
+}
+
+func Test_compareRuns_recall(t *testing.T) {
+	recall := func(asyncIndexing float64) map[string]float64 {
+		return map[string]float64{recallAsyncIndexing: asyncIndexing}
+	}
+
+	tests := []struct {
+		name   string
+		runs   []runSummary
+		wanted int
+	}{
+		{
+			name: "recall within the threshold",
+			runs: []runSummary{
+				{Scenario: "default", Passed: true, Recall: recall(1)},
+				{Scenario: "default", Passed: true, Recall: recall(0.9)},
+			},
+		},
+		{
+			name: "recall below the threshold",
+			runs: []runSummary{
+				{Scenario: "default", Passed: true, Recall: recall(1)},
+				{Scenario: "default", Passed: true, Recall: recall(0.7)},
+			},
+			wanted: 1,
+		},
+		{
+			name: "runs that did not measure recall are left out",
+			runs: []runSummary{
+				{Scenario: "default", Passed: true},
+				{Scenario: "default", Passed: true, Recall: recall(0.7)},
+				{Scenario: "default", Passed: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			regressions, err := compareRuns(tt.runs, "default", 5, 1.25)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(regressions) != tt.wanted {
+				t.Errorf("wanted %d regressions, got %v", tt.wanted, regressions)
+			}
+		})
+	}
+}
+
+func Test_report_summary_recall(t *testing.T) {
+	r := &report{started: time.Now()}
+	r.recordRecall(0, recallAsyncIndexing, 1)
+	r.recordRecall(1, recallAsyncIndexing, 0.9)
+	r.recordRecall(1, recallFilteredSearch, 0.95)
+
+	got := r.summary("default", nil).Recall
+	want := map[string]float64{recallAsyncIndexing: 0.9, recallFilteredSearch: 0.95}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wanted %v, got %v", want, got)
+	}
+}
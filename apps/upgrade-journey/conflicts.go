@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/weaviate/weaviate-chaos-engineering/apps/upgrade-journey/pkg/faults"
+)
+
+// withConflictingUpdates is set by --conflicting-updates
+var withConflictingUpdates bool
+
+const (
+	// objects of every round that are updated on both sides of the split
+	conflictObjects = 10
+
+	// the gap between the two updates of an object, so that the later one
+	// has a newer timestamp on every version
+	conflictWriteGap = 20 * time.Millisecond
+
+	// how long the replicas may take to agree on the later update
+	conflictRepairTimeout = time.Minute
+)
+
+// conflictRound is one hop of the conflict scenario, with a class of its
+// own. winners holds the value of the later update of every object.
+type conflictRound struct {
+	class   string
+	winners map[string]string
+}
+
+// conflictRounds are the rounds of all hops so far, every hop checks that
+// the replicas of the earlier ones still agree
+var conflictRounds []conflictRound
+
+// conflictingUpdates isolates the last node from its peers and updates the
+// same objects through a node on either side of the split, one side after
+// the other. Once the split is healed, reads at ALL have to repair every
+// replica to the later of the two updates, whichever side it was written
+// on: conflicts are resolved by the last write, not by the node that wins
+// a race during the repair. The rounds of the earlier hops are checked
+// again, an upgrade must not change the outcome.
+func (c *cluster) conflictingUpdates(ctx context.Context, posOfVersion int) error {
+	version := versions[posOfVersion]
+	if !supports(version, featureReplication) || c.NodeCount < 3 {
+		hopLogger(posOfVersion).Info("skipping conflicting updates, they need replication on at least 3 nodes")
+		return nil
+	}
+
+	for _, round := range conflictRounds {
+		if err := round.checkConverged(ctx, c); err != nil {
+			return fmt.Errorf("updated on an earlier hop: %w", err)
+		}
+	}
+
+	round, ids, err := createConflictRound(ctx, c, posOfVersion)
+	if err != nil {
+		return err
+	}
+
+	isolated := c.NodeCount - 1
+	split := &faults.Isolate[*cluster]{Node: isolated}
+	if err := applyFault(ctx, c, posOfVersion, split, 0, func(ctx context.Context) error {
+		for i, id := range ids {
+			// the isolated side writes last on every other object
+			first, second := 0, isolated
+			if i%2 == 1 {
+				first, second = isolated, 0
+			}
+
+			if err := round.update(ctx, c, first, id, i); err != nil {
+				return err
+			}
+			time.Sleep(conflictWriteGap)
+			if err := round.update(ctx, c, second, id, i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if err := c.WaitForHealthyNodes(ctx, 0, time.Minute); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	if err := round.checkConverged(ctx, c); err != nil {
+		return err
+	}
+	conflictRounds = append(conflictRounds, round)
+
+	took := time.Since(start)
+	runReport.recordLatency(posOfVersion, "conflict repair", took)
+	hopLogger(posOfVersion).Info("conflicting updates converged", "class", round.class, "objects", len(ids),
+		"took", took.Round(time.Millisecond))
+	return nil
+}
+
+// createConflictRound creates the class of a round and writes its objects
+// to every replica
+func createConflictRound(ctx context.Context, c *cluster, posOfVersion int) (conflictRound, []string, error) {
+	round := conflictRound{
+		class:   fmt.Sprintf("ConflictingUpdates%02d", posOfVersion),
+		winners: map[string]string{},
+	}
+
+	if _, err := postRaw(ctx, 0, "/v1/schema", map[string]interface{}{
+		"class":             round.class,
+		"vectorizer":        "none",
+		"replicationConfig": map[string]interface{}{"factor": c.NodeCount},
+		"properties": []map[string]interface{}{
+			{"name": "value", "dataType": []string{"text"}},
+		},
+	}); err != nil {
+		return round, nil, fmt.Errorf("create class %s: %w", round.class, err)
+	}
+
+	var ids []string
+	for i := 0; i < conflictObjects; i++ {
+		id := uuid.New().String()
+		if _, err := postRaw(ctx, 0, "/v1/objects?consistency_level=ALL", map[string]interface{}{
+			"class":      round.class,
+			"id":         id,
+			"vector":     []float32{float32(i), 1},
+			"properties": map[string]interface{}{"value": "initial"},
+		}); err != nil {
+			return round, nil, fmt.Errorf("import %s: %w", round.class, err)
+		}
+		ids = append(ids, id)
+	}
+	return round, ids, nil
+}
+
+// update replaces an object through a node at ONE, which only needs the
+// replica on the side of the split the node is on. The value of the last
+// update of an object is its expected winner.
+func (r conflictRound) update(ctx context.Context, c *cluster, nodeId int, id string, i int) error {
+	value := fmt.Sprintf("%s-%d", c.Hostname(nodeId), i)
+	if _, err := sendRaw(ctx, http.MethodPut, nodeId, fmt.Sprintf("/v1/objects/%s/%s?consistency_level=ONE",
+		r.class, id), map[string]interface{}{
+		"class":      r.class,
+		"id":         id,
+		"vector":     []float32{float32(i), 1},
+		"properties": map[string]interface{}{"value": value},
+	}); err != nil {
+		return fmt.Errorf("update %s/%s through %s: %w", r.class, id, c.Hostname(nodeId), err)
+	}
+
+	r.winners[id] = value
+	return nil
+}
+
+// checkConverged reads every object of the round at ALL, which repairs the
+// replicas, and waits until every node holds the later update in its own
+// replica
+func (r conflictRound) checkConverged(ctx context.Context, c *cluster) error {
+	for id, want := range r.winners {
+		body, err := getRaw(ctx, 0, fmt.Sprintf("/v1/objects/%s/%s?consistency_level=ALL", r.class, id))
+		if err != nil {
+			return fmt.Errorf("read %s/%s at ALL: %w", r.class, id, err)
+		}
+		got, err := parseConflictValue(body)
+		if err != nil {
+			return err
+		}
+		if got != want {
+			return fmt.Errorf("read %s/%s at ALL: wanted the later update %q, got %q", r.class, id, want, got)
+		}
+	}
+
+	deadline := time.Now().Add(conflictRepairTimeout)
+	for {
+		err := r.checkReplicas(ctx, c)
+		if err == nil {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("replicas did not converge within %s: %w", conflictRepairTimeout, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// checkReplicas compares the replica of every node with the later update
+func (r conflictRound) checkReplicas(ctx context.Context, c *cluster) error {
+	for nodeId := 0; nodeId < c.NodeCount; nodeId++ {
+		for id, want := range r.winners {
+			body, err := getRaw(ctx, 0, fmt.Sprintf("/v1/objects/%s/%s?node_name=%s", r.class, id,
+				c.Hostname(nodeId)))
+			if err != nil {
+				return fmt.Errorf("read %s/%s on %s: %w", r.class, id, c.Hostname(nodeId), err)
+			}
+			got, err := parseConflictValue(body)
+			if err != nil {
+				return err
+			}
+			if got != want {
+				return fmt.Errorf("%s holds %q for %s/%s, wanted the later update %q", c.Hostname(nodeId), got,
+					r.class, id, want)
+			}
+		}
+	}
+	return nil
+}
+
+func parseConflictValue(body []byte) (string, error) {
+	var object struct {
+		Properties struct {
+			Value string `json:"value"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(body, &object); err != nil {
+		return "", err
+	}
+	return object.Properties.Value, nil
+}
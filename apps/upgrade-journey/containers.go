@@ -3,157 +3,124 @@ package main
 import (
 	"context"
 	"fmt"
-	"io"
-	"log"
-	"math/rand"
-	"os"
-	"path"
-	"strings"
 	"time"
 
-	"github.com/docker/go-connections/nat"
-	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/wait"
+	wcluster "github.com/weaviate/weaviate-chaos-engineering/apps/upgrade-journey/pkg/cluster"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate"
 )
 
-var counter int
-
+// cluster is the cluster of the journey, together with the state that only
+// the journey's own faults need
 type cluster struct {
-	nodeCount   int
-	networkName string
-	rootDir     string
-	containers  []testcontainers.Container
-}
+	*wcluster.Cluster
 
-func newCluster(nodeCount int) *cluster {
-	rootDir, err := os.Getwd()
-	if err != nil {
-		log.Fatal(err)
-	}
+	// staleSnapshots are old copies of data directories, see staleFault
+	staleSnapshots map[int]staleSnapshot
 
-	return &cluster{
-		nodeCount:   nodeCount,
-		networkName: fmt.Sprintf("weaviate-upgrade-journey-%d", rand.Int()),
-		rootDir:     rootDir,
-		containers:  make([]testcontainers.Container, nodeCount),
-	}
+	// updateLeader is the raft leader during an ordered rolling update, -1
+	// outside of one, see updateOrder
+	updateLeader int
 }
 
-func (c *cluster) startAllNodes(ctx context.Context, version string) error {
-	for i := 0; i < c.nodeCount; i++ {
-		container, err := c.startWeaviateNode(ctx, i, version)
-		if err != nil {
-			return err
-		}
-
-		c.containers[i] = container
-	}
+// portOffset is set by --port-offset
+var portOffset int
 
-	return nil
-}
+func newCluster(ctx context.Context, nodeCount int) *cluster {
+	c := &cluster{staleSnapshots: map[int]staleSnapshot{}, updateLeader: -1}
 
-func (c *cluster) rollingUpdate(ctx context.Context, version string) error {
-	log.Printf("starting rolling update to %s", version)
-	for i := 0; i < c.nodeCount; i++ {
-		if err := c.containers[i].Terminate(ctx); err != nil {
-			return err
-		}
-
-		container, err := c.startWeaviateNode(ctx, i, version)
-		if err != nil {
-			log.Print(err)
-			logReader, logErr := container.Logs(context.Background())
-			if logErr != nil {
-				log.Fatal(logErr)
-			}
-
-			io.Copy(os.Stdout, logReader)
-			return err
-		}
-
-		c.containers[i] = container
+	resources, err := nodeLimits(ctx, nodeCount)
+	if err != nil {
+		fatal("cannot determine node resources", "err", err)
 	}
 
-	log.Printf("completed rolling update to %s", version)
-	return nil
-}
-
-func (c *cluster) startNetwork(ctx context.Context) error {
-	_, err := testcontainers.GenericNetwork(ctx, testcontainers.GenericNetworkRequest{
-		NetworkRequest: testcontainers.NetworkRequest{
-			Name:     c.networkName,
-			Internal: false,
+	base, err := wcluster.New(wcluster.Options{
+		NodeCount:  nodeCount,
+		RunID:      runID,
+		PortOffset: portOffset,
+		Labels:     map[string]string{wcluster.LabelScenario: scenarioName},
+		Image:      images.image,
+		Platform:   images.platform,
+		Env: func(nodeId int, version string) map[string]string {
+			env := moduleEnv()
+			for key, value := range asyncIndexingEnv(version) {
+				env[key] = value
+			}
+			for key, value := range debugEnv(scenarioDebug, versions, nodeId, version) {
+				env[key] = value
+			}
+			for key, value := range egressEnv() {
+				env[key] = value
+			}
+			return env
+		},
+		WaitFor: func(nodeId int, version string) wait.Strategy {
+			return c.startupWait(nodeId, version)
+		},
+		// the next node may only go down once this one follows a leader,
+		// then every node is written to while their versions differ
+		AfterRestart: func(ctx context.Context, nodeId int, version string) error {
+			if err := c.afterRestart(ctx, nodeId, version); err != nil {
+				return err
+			}
+			return mixedWrites.write(ctx, c)
 		},
+		UpdateOrder:     c.updateOrder,
+		Host:            nodeHost,
+		Topology:        clusterTopology,
+		Resources:       resources,
+		Profiling:       profileInterval > 0 || debugProfiling(),
+		Metrics:         metricsEnabled(),
+		OnStart:         c.onStart,
+		Logger:          logger,
+		ContainerLogger: testcontainersLogger{},
 	})
 	if err != nil {
-		return fmt.Errorf("network %s: %w", c.networkName, err)
+		fatal("cannot create cluster", "err", err)
 	}
 
-	return nil
+	c.Cluster = base
+	return c
 }
 
-func (c *cluster) volumePath(nodeId int) string {
-	return path.Join(c.rootDir, "data/", c.hostname(nodeId))
+// onStart records the startup time of every node and checks it against the
+// alert rules
+func (c *cluster) onStart(nodeId int, version string, took time.Duration) {
+	runReport.recordStartup(c.Hostname(nodeId), version, took)
+	scenarioAlerts.checkStartup(nodeId, version, took)
 }
 
-func (c *cluster) startWeaviateNode(ctx context.Context, nodeId int, version string) (testcontainers.Container, error) {
-	if err := os.MkdirAll(c.volumePath(nodeId), 0o777); err != nil {
-		log.Print(err)
-		return nil, err
-	}
-
-	image := fmt.Sprintf("semitechnologies/weaviate:%s", version)
-	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
-		Logger: log.Default(),
-		ContainerRequest: testcontainers.ContainerRequest{
-			Name:         fmt.Sprintf("%s-%d", c.hostname(nodeId), counter),
-			Image:        image,
-			Cmd:          []string{"--host", "0.0.0.0", "--port", "8080", "--scheme", "http"},
-			Networks:     []string{c.networkName},
-			ExposedPorts: []string{fmt.Sprintf("%d:8080", 8080+nodeId)},
-			AutoRemove:   false,
-			Env: map[string]string{
-				"QUERY_DEFAULTS_LIMIT":                    "25",
-				"AUTHENTICATION_ANONYMOUS_ACCESS_ENABLED": "true",
-				"PERSISTENCE_DATA_PATH":                   "/var/lib/weaviate",
-				"DEFAULT_VECTORIZER_MODULE":               "none",
-				"ENABLE_MODULES":                          "",
-				"CLUSTER_GOSSIP_BIND_PORT":                "7100",
-				"CLUSTER_DATA_BIND_PORT":                  "7101",
-				"CLUSTER_HOSTNAME":                        c.hostname(nodeId),
-				"CLUSTER_JOIN":                            c.allNodes(),
-				"PERSISTENCE_LSM_ACCESS_STRATEGY":         os.Getenv("PERSISTENCE_LSM_ACCESS_STRATEGY"),
-			},
-			Mounts: testcontainers.Mounts(testcontainers.BindMount(
-				c.volumePath(nodeId), "/var/lib/weaviate",
-			)),
-			WaitingFor: wait.
-				ForHTTP("/v1/.well-known/ready").
-				WithPort(nat.Port("8080")).
-				WithStatusCodeMatcher(func(status int) bool {
-					return status >= 200 && status <= 299
-				}).
-				WithStartupTimeout(30 * time.Second),
-		},
-		Started: true,
+// runLabels are the labels of the Docker objects of the run that are not
+// part of the cluster
+func runLabels() map[string]string {
+	return wcluster.Labels(map[string]string{
+		wcluster.LabelRunID:    runID,
+		wcluster.LabelScenario: scenarioName,
 	})
-	counter++
-	if err != nil {
-		return container, err
-	}
-
-	return container, nil
 }
 
-func (c *cluster) hostname(nodeId int) string {
-	return fmt.Sprintf("weaviate-%d", nodeId)
+func (c *cluster) nodeClient(nodeId int) *weaviate.Client {
+	return weaviate.New(weaviate.Config{
+		Host:   nodeHost(nodeId),
+		Scheme: "http",
+	})
 }
 
-func (c *cluster) allNodes() string {
-	hosts := []string{}
-	for i := 0; i < c.nodeCount; i++ {
-		hosts = append(hosts, fmt.Sprintf("weaviate-%d:7100", i))
+// nodeHost is the address under which a node's REST API is exposed on the
+// host machine, or its proxy if --toxiproxy is set. With --external-url
+// every request goes to the external cluster, with --remote-cluster to the
+// address of the remote node.
+func nodeHost(nodeId int) string {
+	if externalTarget != nil {
+		return externalTarget.Host
+	}
+	if remoteNodes != nil {
+		return remoteNodes.Host(nodeId)
+	}
+
+	if useToxiproxy {
+		return fmt.Sprintf("localhost:%d", proxiedClientPort+portOffset+nodeId)
 	}
 
-	return strings.Join(hosts, ",")
+	return fmt.Sprintf("localhost:%d", 8080+portOffset+nodeId)
 }
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// With --vectorizer=contextionary the nodes vectorize text with a pinned
+// contextionary. Identical text must then result in identical vectors on
+// every hop, a drift means that a server upgrade changed how text is
+// vectorized, e.g. through a changed module or compound splitting.
+const (
+	contextionaryImage = "semitechnologies/contextionary:en0.16.0-v1.2.1"
+	contextionaryHost  = "contextionary"
+	relevanceClass     = "Relevance"
+)
+
+// relevanceTexts deliberately contain compound words, which the
+// contextionary splits into their parts
+var relevanceTexts = []string{
+	"The lighthouse keeper watched the sailboats return to the harbour",
+	"A quick brown fox jumps over the lazy sheepdog",
+	"Firefighters rescued the schoolchildren from the burning warehouse",
+	"Database upgrades should never change the meaning of stored text",
+}
+
+// vectorizerModule and vectorDriftTolerance are set from the flags in main.
+// An empty module means none, the tolerance is the largest accepted cosine
+// distance between the vectors of identical text.
+var (
+	vectorizerModule     string
+	vectorDriftTolerance float64
+)
+
+func (c *cluster) startContextionary(ctx context.Context) error {
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		Logger: testcontainersLogger{},
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:    contextionaryImage,
+			Networks: []string{c.NetworkName},
+			NetworkAliases: map[string][]string{
+				c.NetworkName: {contextionaryHost},
+			},
+			Env: map[string]string{
+				"OCCURRENCE_WEIGHT_LINEAR_FACTOR":       "0.75",
+				"EXTENSIONS_STORAGE_MODE":               "weaviate",
+				"EXTENSIONS_STORAGE_ORIGIN":             fmt.Sprintf("http://%s:8080", c.Hostname(0)),
+				"NEIGHBOR_OCCURRENCE_IGNORE_PERCENTILE": "5",
+				"ENABLE_COMPOUND_SPLITTING":             "true",
+			},
+			ExposedPorts: []string{"9999/tcp"},
+			Labels:       c.Labels(),
+			WaitingFor:   wait.ForListeningPort("9999/tcp").WithStartupTimeout(2 * time.Minute),
+		},
+		Started: true,
+	})
+	if err != nil {
+		return fmt.Errorf("start contextionary: %w", err)
+	}
+	c.AddSidecar(container)
+
+	return nil
+}
+
+// moduleEnv is the module configuration of every node
+func moduleEnv() map[string]string {
+	env := map[string]string{}
+	var modules []string
+	if vectorizerModule != "" {
+		modules = append(modules, "text2vec-contextionary")
+		env["CONTEXTIONARY_URL"] = fmt.Sprintf("%s:9999", contextionaryHost)
+	}
+	if backupEnabled() {
+		modules = append(modules, "backup-"+backupBackend)
+		for key, value := range backupEnv() {
+			env[key] = value
+		}
+	}
+	if withGenerativeMock {
+		modules = append(modules, "generative-openai")
+		for key, value := range generativeEnv() {
+			env[key] = value
+		}
+	}
+
+	env["ENABLE_MODULES"] = strings.Join(modules, ",")
+	return env
+}
+
+type relevanceWorkload struct {
+	sync.Mutex
+
+	// baseline are the vectors of the texts on the first hop, stored are
+	// the ids of the objects holding them
+	baseline [][]float32
+	stored   []string
+}
+
+var relevance = &relevanceWorkload{}
+
+func (w *relevanceWorkload) createClass(ctx context.Context) error {
+	_, err := postRaw(ctx, 0, "/v1/schema", map[string]interface{}{
+		"class":      relevanceClass,
+		"vectorizer": "text2vec-contextionary",
+		"properties": []map[string]interface{}{
+			{"name": "text", "dataType": []string{"text"}},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("create class %s: %w", relevanceClass, err)
+	}
+
+	return nil
+}
+
+// importTexts imports all texts once more. The first import becomes the
+// baseline.
+func (w *relevanceWorkload) importTexts(ctx context.Context) error {
+	w.Lock()
+	defer w.Unlock()
+
+	for i, text := range relevanceTexts {
+		id := uuid.New().String()
+		if _, err := postRaw(ctx, 0, "/v1/objects", map[string]interface{}{
+			"class":      relevanceClass,
+			"id":         id,
+			"properties": map[string]interface{}{"text": text},
+		}); err != nil {
+			return fmt.Errorf("import text: %w", err)
+		}
+
+		vector, err := objectVector(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if len(w.baseline) < len(relevanceTexts) {
+			w.baseline = append(w.baseline, vector)
+			w.stored = append(w.stored, id)
+			continue
+		}
+
+		if distance := cosineDistance(w.baseline[i], vector); distance > vectorDriftTolerance {
+			return fmt.Errorf("vector of %q drifted by a cosine distance of %g", text, distance)
+		}
+	}
+
+	return nil
+}
+
+// relevanceVerifier checks that the vectors stored on the first hop did not
+// change since
+type relevanceVerifier struct{}
+
+func (v *relevanceVerifier) name() string {
+	return "vector-drift"
+}
+
+func (v *relevanceVerifier) verify(ctx context.Context, posOfMaxVersion int) error {
+	relevance.Lock()
+	defer relevance.Unlock()
+
+	for i, id := range relevance.stored {
+		vector, err := objectVector(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if distance := cosineDistance(relevance.baseline[i], vector); distance > vectorDriftTolerance {
+			return fmt.Errorf("stored vector of %q changed by a cosine distance of %g",
+				relevanceTexts[i], distance)
+		}
+	}
+
+	return nil
+}
+
+// textSearchVerifier runs nearText and Explore for every text. Both go
+// through the vectorizer at query time and through resolvers that Get with
+// nearVector and Aggregate do not use, so they can regress independently.
+type textSearchVerifier struct{}
+
+func (v *textSearchVerifier) name() string {
+	return "text-search"
+}
+
+func (v *textSearchVerifier) verify(ctx context.Context, posOfMaxVersion int) error {
+	raw := &rawVerifier{host: nodeHost(0)}
+	for _, text := range relevanceTexts {
+		if err := nearTextFindsItself(ctx, raw, text); err != nil {
+			return fmt.Errorf("nearText %q: %w", text, err)
+		}
+
+		if err := exploreFindsItself(ctx, raw, text); err != nil {
+			return fmt.Errorf("explore %q: %w", text, err)
+		}
+	}
+
+	return nil
+}
+
+// nearTextFindsItself searches for the exact text of an object, which has
+// to be the closest result
+func nearTextFindsItself(ctx context.Context, raw *rawVerifier, text string) error {
+	var res struct {
+		Get map[string][]struct {
+			Text string `json:"text"`
+		} `json:"Get"`
+	}
+	if err := raw.graphQL(ctx, fmt.Sprintf(`{Get{%s(nearText:{concepts:[%q]},limit:1){text}}}`,
+		relevanceClass, text), &res); err != nil {
+		return err
+	}
+
+	if got := res.Get[relevanceClass]; len(got) != 1 || got[0].Text != text {
+		return fmt.Errorf("wanted the text itself as closest result, got %v", got)
+	}
+
+	return nil
+}
+
+// exploreFindsItself does the same search across all classes. Explore only
+// returns beacons, so the object behind the top result is read to compare
+// its text. Explore is not available on every cluster setup, a refusal is
+// logged, wrong results are not accepted.
+func exploreFindsItself(ctx context.Context, raw *rawVerifier, text string) error {
+	var res struct {
+		Explore []struct {
+			Beacon    string `json:"beacon"`
+			ClassName string `json:"className"`
+		} `json:"Explore"`
+	}
+	err := raw.graphQL(ctx, fmt.Sprintf(`{Explore(nearText:{concepts:[%q]},limit:1){beacon className}}`,
+		text), &res)
+	if err != nil && strings.Contains(strings.ToLower(err.Error()), "not supported") {
+		logger.Info("explore is not supported on this cluster", "err", err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(res.Explore) != 1 || res.Explore[0].ClassName != relevanceClass {
+		return fmt.Errorf("wanted a %s object as closest result, got %v", relevanceClass, res.Explore)
+	}
+
+	beacon := res.Explore[0].Beacon
+	id := beacon[strings.LastIndex(beacon, "/")+1:]
+	body, err := getRaw(ctx, 0, fmt.Sprintf("/v1/objects/%s/%s", relevanceClass, id))
+	if err != nil {
+		return err
+	}
+
+	var obj struct {
+		Properties struct {
+			Text string `json:"text"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(body, &obj); err != nil {
+		return err
+	}
+
+	if obj.Properties.Text != text {
+		return fmt.Errorf("closest result %s has text %q", beacon, obj.Properties.Text)
+	}
+
+	return nil
+}
+
+func objectVector(ctx context.Context, id string) ([]float32, error) {
+	raw, err := getRaw(ctx, 0, fmt.Sprintf("/v1/objects/%s?include=vector", id))
+	if err != nil {
+		return nil, err
+	}
+
+	var res struct {
+		Vector []float32 `json:"vector"`
+	}
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return nil, err
+	}
+
+	if len(res.Vector) == 0 {
+		return nil, fmt.Errorf("object %s has no vector", id)
+	}
+	return res.Vector, nil
+}
+
+func cosineDistance(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 1
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+
+	if normA == 0 || normB == 0 {
+		return 1
+	}
+	return 1 - dot/(math.Sqrt(normA)*math.Sqrt(normB))
+}
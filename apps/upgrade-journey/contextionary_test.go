@@ -0,0 +1,29 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCosineDistance(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []float32
+		want float64
+	}{
+		{name: "identical", a: []float32{1, 2, 3}, b: []float32{1, 2, 3}, want: 0},
+		{name: "scaled", a: []float32{1, 2, 3}, b: []float32{2, 4, 6}, want: 0},
+		{name: "orthogonal", a: []float32{1, 0}, b: []float32{0, 1}, want: 1},
+		{name: "opposite", a: []float32{1, 0}, b: []float32{-1, 0}, want: 2},
+		{name: "different dimensions", a: []float32{1, 0}, b: []float32{1, 0, 0}, want: 1},
+		{name: "zero vector", a: []float32{0, 0}, b: []float32{1, 0}, want: 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := cosineDistance(test.a, test.b); math.Abs(got-test.want) > 1e-9 {
+				t.Errorf("wanted %g, got %g", test.want, got)
+			}
+		})
+	}
+}
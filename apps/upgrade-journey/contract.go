@@ -0,0 +1,311 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+const contractProbeClass = "ContractProbe"
+
+// contractCase is an invalid request that every version must reject
+type contractCase struct {
+	name     string
+	method   string
+	endpoint string
+	payload  interface{}
+}
+
+func invalidObject(id string, vector []float32, properties map[string]interface{}) contractCase {
+	object := map[string]interface{}{"class": contractProbeClass, "id": id, "vector": vector}
+	if properties != nil {
+		object["properties"] = properties
+	}
+	return contractCase{method: http.MethodPost, endpoint: "/v1/objects", payload: object}
+}
+
+func named(name string, tc contractCase) contractCase {
+	tc.name = name
+	return tc
+}
+
+var contractCases = []contractCase{
+	named("vector dimensions", invalidObject("2a1f6bb4-3b1e-4d0c-9b0e-2f7a3c1d9e01",
+		[]float32{0.1, 0.2, 0.3, 0.4}, nil)),
+	named("property type", invalidObject("2a1f6bb4-3b1e-4d0c-9b0e-2f7a3c1d9e02",
+		[]float32{0.1, 0.2, 0.3}, map[string]interface{}{"count": "not a number"})),
+	named("malformed uuid", invalidObject("not-a-uuid", []float32{0.1, 0.2, 0.3}, nil)),
+	{
+		name:     "unknown class object",
+		method:   http.MethodPost,
+		endpoint: "/v1/objects",
+		payload:  map[string]interface{}{"class": "UnknownClass", "vector": []float32{0.1, 0.2, 0.3}},
+	},
+	{
+		name:     "unknown class schema",
+		method:   http.MethodGet,
+		endpoint: "/v1/schema/UnknownClass",
+	},
+	{
+		name:     "bad filter",
+		method:   http.MethodPost,
+		endpoint: "/v1/graphql",
+		payload: map[string]interface{}{"query": fmt.Sprintf(
+			`{Get{%s(where:{path:["count"],operator:Equal,valueText:"x"}){count}}}`, contractProbeClass)},
+	},
+	{
+		name:     "unknown filter operator",
+		method:   http.MethodPost,
+		endpoint: "/v1/graphql",
+		payload: map[string]interface{}{"query": fmt.Sprintf(
+			`{Get{%s(where:{path:["count"],operator:Nearly,valueInt:1}){count}}}`, contractProbeClass)},
+	},
+	{
+		// past QUERY_MAXIMUM_RESULTS, which defaults to 10000
+		name:     "oversized page",
+		method:   http.MethodGet,
+		endpoint: fmt.Sprintf("/v1/objects?class=%s&limit=1000000", contractProbeClass),
+	},
+	{
+		name:     "invalid batch",
+		method:   http.MethodPost,
+		endpoint: "/v1/batch/objects",
+		payload:  map[string]interface{}{"objects": invalidBatch()},
+	},
+}
+
+// contractBatchSize is the size of the invalid batch. Weaviate does not
+// limit the size of a batch, instead every object of a large batch must be
+// rejected on its own while the batch as a whole succeeds.
+const contractBatchSize = 1000
+
+func invalidBatch() []map[string]interface{} {
+	objects := make([]map[string]interface{}, contractBatchSize)
+	for i := range objects {
+		objects[i] = map[string]interface{}{
+			"class":  contractProbeClass,
+			"id":     "not-a-uuid",
+			"vector": []float32{0.1, 0.2, 0.3},
+		}
+	}
+	return objects
+}
+
+// expectedContractChanges lists cases whose error message is allowed to
+// change between versions. Status codes and response shapes must never
+// change.
+var expectedContractChanges = []string{}
+
+type contractResult struct {
+	status  int
+	shape   []string
+	message string
+}
+
+// contractProbe writes the probe class the invalid requests target once,
+// on the first hop
+var contractProbe probeImports
+
+// contractVerifier issues invalid requests and checks that every version
+// answers them with the same status, the same response shape and the same
+// message, as client integrations depend on all of them
+type contractVerifier struct {
+	baseline map[string]contractResult
+}
+
+func (v *contractVerifier) name() string {
+	return "contract"
+}
+
+func (v *contractVerifier) verify(ctx context.Context, posOfMaxVersion int) error {
+	if v.baseline == nil {
+		v.baseline = map[string]contractResult{}
+	}
+
+	var broken []string
+	for _, tc := range contractCases {
+		status, body, err := doRaw(ctx, tc.method, 0, tc.endpoint, tc.payload)
+		if err != nil {
+			return fmt.Errorf("%s: %w", tc.name, err)
+		}
+
+		got := contractResult{status: status, shape: responseShape(body), message: errorMessage(body)}
+		hopLogger(posOfMaxVersion).Debug("invalid request answered", "case", tc.name,
+			"status", got.status, "message", got.message)
+
+		want, ok := v.baseline[tc.name]
+		if !ok {
+			if status >= 200 && status <= 299 && got.message == "" {
+				return fmt.Errorf("%s: invalid request accepted with status %d", tc.name, status)
+			}
+			v.baseline[tc.name] = got
+			continue
+		}
+
+		broken = append(broken, want.breakingChanges(tc.name, got)...)
+	}
+
+	if len(broken) > 0 {
+		return fmt.Errorf("error behavior changed on %s:\n%s", versions[posOfMaxVersion],
+			strings.Join(broken, "\n"))
+	}
+
+	return nil
+}
+
+func (want contractResult) breakingChanges(name string, got contractResult) []string {
+	var broken []string
+	if got.status != want.status {
+		broken = append(broken, fmt.Sprintf("%s: status %d -> %d", name, want.status, got.status))
+	}
+	if removed := missingPaths(want.shape, got.shape); len(removed) > 0 {
+		broken = append(broken, fmt.Sprintf("%s: response lost %s", name, strings.Join(removed, ", ")))
+	}
+	if got.message != want.message && !matchesAnyPath(name, expectedContractChanges) {
+		broken = append(broken, fmt.Sprintf("%s: message %q -> %q", name, want.message, got.message))
+	}
+	return broken
+}
+
+func createContractClass(ctx context.Context) error {
+	if _, err := postRaw(ctx, 0, "/v1/schema", map[string]interface{}{
+		"class":      contractProbeClass,
+		"vectorizer": "none",
+		"properties": []map[string]interface{}{
+			{"name": "count", "dataType": []string{"int"}},
+		},
+	}); err != nil {
+		return fmt.Errorf("create class %s: %w", contractProbeClass, err)
+	}
+	return nil
+}
+
+// importContractObject writes the single object of the probe class, which
+// fixes the dimensions of its vector index
+func importContractObject(ctx context.Context) error {
+	if _, err := postRaw(ctx, 0, "/v1/batch/objects", map[string]interface{}{
+		"objects": []map[string]interface{}{{
+			"class":      contractProbeClass,
+			"id":         probeID(contractProbeClass, 0, 0),
+			"vector":     []float32{0.1, 0.2, 0.3},
+			"properties": map[string]interface{}{"count": 1},
+		}},
+	}); err != nil {
+		return fmt.Errorf("import %s: %w", contractProbeClass, err)
+	}
+	return nil
+}
+
+type errorList []struct {
+	Message string `json:"message"`
+}
+
+// errorMessage extracts the distinct messages of a REST, GraphQL or batch
+// error response, or returns a body that is not JSON as is
+func errorMessage(body []byte) string {
+	var res struct {
+		Error  errorList `json:"error"`
+		Errors errorList `json:"errors"`
+	}
+	var batch []struct {
+		Result struct {
+			Errors struct {
+				Error errorList `json:"error"`
+			} `json:"errors"`
+		} `json:"result"`
+	}
+
+	var all errorList
+	if err := json.Unmarshal(body, &res); err == nil {
+		all = append(res.Error, res.Errors...)
+	} else if err := json.Unmarshal(body, &batch); err == nil {
+		for _, object := range batch {
+			all = append(all, object.Result.Errors.Error...)
+		}
+	} else {
+		return strings.TrimSpace(string(body))
+	}
+
+	seen := map[string]bool{}
+	var messages []string
+	for _, e := range all {
+		if !seen[e.Message] {
+			seen[e.Message] = true
+			messages = append(messages, e.Message)
+		}
+	}
+	return strings.Join(messages, "; ")
+}
+
+// responseShape returns the paths and types of every value in a JSON
+// response, with array elements collapsed, so that responses of different
+// lengths have the same shape
+func responseShape(body []byte) []string {
+	var parsed interface{}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return []string{"text"}
+	}
+
+	seen := map[string]bool{}
+	collectShape("", parsed, seen)
+
+	shape := make([]string, 0, len(seen))
+	for p := range seen {
+		shape = append(shape, p)
+	}
+	sort.Strings(shape)
+	return shape
+}
+
+func collectShape(prefix string, in interface{}, seen map[string]bool) {
+	switch typed := in.(type) {
+	case map[string]interface{}:
+		for key, value := range typed {
+			collectShape(joinPath(prefix, key), value, seen)
+		}
+	case []interface{}:
+		for _, value := range typed {
+			collectShape(prefix+"[]", value, seen)
+		}
+		if len(typed) == 0 {
+			seen[prefix+"[]"] = true
+		}
+	default:
+		seen[fmt.Sprintf("%s %s", prefix, jsonType(typed))] = true
+	}
+}
+
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// missingPaths returns the paths of want that got does not have. New
+// fields are not a breaking change.
+func missingPaths(want, got []string) []string {
+	have := map[string]bool{}
+	for _, p := range got {
+		have[p] = true
+	}
+
+	var missing []string
+	for _, p := range want {
+		if !have[p] {
+			missing = append(missing, p)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_errorMessage(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{body: `{"error":[{"message":"id is not a valid uuid"}]}`, want: "id is not a valid uuid"},
+		{body: `{"error":[{"message":"a"},{"message":"b"}]}`, want: "a; b"},
+		{body: `{"data":{"Get":{"A":null}},"errors":[{"message":"invalid filter"}]}`, want: "invalid filter"},
+		{
+			body: `[{"result":{"errors":{"error":[{"message":"bad id"}]}}},{"result":{"errors":{"error":[{"message":"bad id"}]}}}]`,
+			want: "bad id",
+		},
+		{body: "internal error\n", want: "internal error"},
+	}
+	for _, tt := range tests {
+		if got := errorMessage([]byte(tt.body)); got != tt.want {
+			t.Errorf("errorMessage(%s) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
+
+func Test_responseShape(t *testing.T) {
+	tests := []struct {
+		body string
+		want []string
+	}{
+		{
+			body: `{"error":[{"message":"a"},{"message":"b"}]}`,
+			want: []string{"error[].message string"},
+		},
+		{
+			body: `{"data":{"Get":{"A":null}},"errors":[{"message":"a","locations":[]}]}`,
+			want: []string{"data.Get.A null", "errors[].locations[]", "errors[].message string"},
+		},
+		{body: "not json", want: []string{"text"}},
+	}
+	for _, tt := range tests {
+		if got := responseShape([]byte(tt.body)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("responseShape(%s) = %v, want %v", tt.body, got, tt.want)
+		}
+	}
+}
+
+func Test_breakingChanges(t *testing.T) {
+	want := contractResult{status: 422, shape: []string{"error[].message string"}, message: "bad id"}
+
+	tests := []struct {
+		name string
+		got  contractResult
+		want int
+	}{
+		{name: "same", got: want},
+		{
+			name: "new fields",
+			got:  contractResult{status: 422, shape: []string{"code number", "error[].message string"}, message: "bad id"},
+		},
+		{name: "status", got: contractResult{status: 500, shape: want.shape, message: "bad id"}, want: 1},
+		{name: "shape and message", got: contractResult{status: 422, shape: []string{"text"}, message: "oops"}, want: 2},
+	}
+	for _, tt := range tests {
+		if got := want.breakingChanges(tt.name, tt.got); len(got) != tt.want {
+			t.Errorf("%s: breakingChanges() = %v, want %d changes", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/weaviate/weaviate-chaos-engineering/apps/upgrade-journey/pkg/workloads"
+)
+
+// driftWindow is set by --drift-window
+var driftWindow time.Duration
+
+// the Aggregate count of the ledger is sampled this often after a fault
+const driftPollInterval = time.Second
+
+// awaitLedgerConvergence samples the Aggregate count of the ledger after a
+// fault was healed until it is within the range the ledger expects. The
+// drift of every sample ends up in the report as the convergence curve of
+// the fault, a drift that is left after --drift-window fails the run.
+// Failed aggregations are retried, they are not a sample.
+func awaitLedgerConvergence(ctx context.Context, hop int, fault string) error {
+	start := time.Now()
+	var curve []float64
+	defer func() {
+		runReport.recordConvergence(hop, fault, curve)
+	}()
+
+	for {
+		count, err := workloads.LedgerAggregateCount(ctx, nodeHost(0))
+		drift := 0
+		if err == nil {
+			drift = writeLedger.CountDrift(count)
+			curve = append(curve, float64(drift))
+			if drift == 0 {
+				hopLogger(hop).Info("ledger count converged", "fault", fault, "count", count,
+					"took", time.Since(start).Round(time.Millisecond))
+				return nil
+			}
+		}
+
+		if time.Since(start) > driftWindow {
+			if err != nil {
+				return fmt.Errorf("ledger count within %s of healing %s: %w", driftWindow, fault, err)
+			}
+			min, max := writeLedger.ExpectedCount()
+			return fmt.Errorf("ledger count is %d %s after healing %s, wanted %d to %d", count, driftWindow, fault,
+				min, max)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(driftPollInterval):
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+const countsTimeout = time.Minute
+
+// shardReplica is the object count of a shard as a single node reports it
+type shardReplica struct {
+	class string
+	shard string
+	node  string
+	count int
+}
+
+// runCounts implements the counts subcommand. It compares the object count
+// of every shard replica with the cluster-wide count of its class and
+// exits with a non-zero code if anything diverged.
+func runCounts(args []string) {
+	fs := flag.NewFlagSet("counts", flag.ExitOnError)
+	target := fs.String("url", "http://localhost:8080",
+		"any node of the cluster; the API key is read from WEAVIATE_API_KEY")
+	fs.Parse(args)
+
+	var err error
+	externalTarget, err = parseExternalURL(*target)
+	if err != nil {
+		fatal("invalid flags", "err", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), countsTimeout)
+	defer cancel()
+	if _, err := setupExternal(ctx, os.Getenv("WEAVIATE_API_KEY")); err != nil {
+		fatal("cannot reach the cluster", "err", err)
+	}
+
+	lines, diverged, err := compareClusterCounts(ctx)
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+	if err != nil {
+		fatal("cannot compare counts", "err", err)
+	}
+	if diverged {
+		os.Exit(1)
+	}
+}
+
+// saveCountComparison writes the comparison into the artifacts of a failed
+// run, next to the logs of the nodes
+func saveCountComparison() {
+	ctx, cancel := context.WithTimeout(context.Background(), countsTimeout)
+	defer cancel()
+
+	lines, _, err := compareClusterCounts(ctx)
+	if err != nil {
+		lines = append(lines, fmt.Sprintf("incomplete: %s", err))
+	}
+
+	if err := os.MkdirAll(artifactsDir, 0o755); err != nil {
+		logger.Warn("cannot save count comparison", "err", err)
+		return
+	}
+	if err := os.WriteFile(path.Join(artifactsDir, "counts.txt"), []byte(strings.Join(lines, "\n")+"\n"),
+		0o644); err != nil {
+		logger.Warn("cannot save count comparison", "err", err)
+	}
+}
+
+// compareClusterCounts fetches the shards of every node and the Aggregate
+// count of every class they belong to
+func compareClusterCounts(ctx context.Context) ([]string, bool, error) {
+	body, err := getRaw(ctx, 0, "/v1/nodes?output=verbose")
+	if err != nil {
+		return nil, false, err
+	}
+	replicas, err := parseShardReplicas(body)
+	if err != nil {
+		return nil, false, err
+	}
+
+	aggregated := map[string]int{}
+	failed := map[string]error{}
+	for _, r := range replicas {
+		if _, ok := aggregated[r.class]; ok || failed[r.class] != nil {
+			continue
+		}
+		count, err := aggregateCount(ctx, r.class)
+		if err != nil {
+			// e.g. a multi-tenant class, which can only be aggregated per
+			// tenant
+			failed[r.class] = err
+			continue
+		}
+		aggregated[r.class] = count
+	}
+
+	lines, diverged := compareCounts(replicas, aggregated)
+	for _, class := range sortedMapKeys(failed) {
+		lines = append(lines, fmt.Sprintf("class %s: cannot aggregate: %s", class, failed[class]))
+	}
+	return lines, diverged, nil
+}
+
+func aggregateCount(ctx context.Context, class string) (int, error) {
+	return aggregateCountWhere(ctx, class, "")
+}
+
+// aggregateCountWhere counts the objects of a class that match a where
+// filter, all of them if where is empty
+func aggregateCountWhere(ctx context.Context, class, where string) (int, error) {
+	if where != "" {
+		where = fmt.Sprintf("(where:%s)", where)
+	}
+	body, err := postRaw(ctx, 0, "/v1/graphql", map[string]interface{}{
+		"query": fmt.Sprintf("{Aggregate{%s%s{meta{count}}}}", class, where),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var res struct {
+		Data struct {
+			Aggregate map[string][]struct {
+				Meta struct {
+					Count int `json:"count"`
+				} `json:"meta"`
+			} `json:"Aggregate"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return 0, err
+	}
+	if len(res.Errors) > 0 {
+		return 0, fmt.Errorf("%s", res.Errors[0].Message)
+	}
+
+	if groups := res.Data.Aggregate[class]; len(groups) > 0 {
+		return groups[0].Meta.Count, nil
+	}
+	return 0, nil
+}
+
+// parseShardReplicas reads the object count of every shard on every node
+// from a verbose nodes response
+func parseShardReplicas(body []byte) ([]shardReplica, error) {
+	var res struct {
+		Nodes []struct {
+			Name   string `json:"name"`
+			Shards []struct {
+				Name        string `json:"name"`
+				Class       string `json:"class"`
+				ObjectCount int    `json:"objectCount"`
+			} `json:"shards"`
+		} `json:"nodes"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, err
+	}
+
+	var replicas []shardReplica
+	for _, node := range res.Nodes {
+		for _, shard := range node.Shards {
+			replicas = append(replicas, shardReplica{
+				class: shard.Class, shard: shard.Name, node: node.Name, count: shard.ObjectCount,
+			})
+		}
+	}
+	return replicas, nil
+}
+
+// compareCounts reports every replica whose count differs from the count
+// most replicas of its shard agree on, and every class whose Aggregate
+// count differs from the sum of those agreed counts. Classes without an
+// Aggregate count are only checked for diverged replicas.
+func compareCounts(replicas []shardReplica, aggregated map[string]int) ([]string, bool) {
+	shards := map[string]map[string][]shardReplica{}
+	for _, r := range replicas {
+		if shards[r.class] == nil {
+			shards[r.class] = map[string][]shardReplica{}
+		}
+		shards[r.class][r.shard] = append(shards[r.class][r.shard], r)
+	}
+
+	var lines []string
+	diverged := false
+	for _, class := range sortedMapKeys(shards) {
+		sum := 0
+		for _, shard := range sortedMapKeys(shards[class]) {
+			agreed := agreedCount(shards[class][shard])
+			sum += agreed
+
+			for _, r := range shards[class][shard] {
+				if r.count != agreed {
+					diverged = true
+					lines = append(lines, fmt.Sprintf("class %s, shard %s: %s holds %d objects, the other "+
+						"replicas %d", class, shard, r.node, r.count, agreed))
+				}
+			}
+		}
+
+		count, ok := aggregated[class]
+		switch {
+		case !ok:
+			lines = append(lines, fmt.Sprintf("class %s: shards hold %d objects", class, sum))
+		case count != sum:
+			diverged = true
+			lines = append(lines, fmt.Sprintf("class %s: Aggregate counts %d objects, shards hold %d", class,
+				count, sum))
+		default:
+			lines = append(lines, fmt.Sprintf("class %s: %d objects", class, count))
+		}
+	}
+	return lines, diverged
+}
+
+// agreedCount is the count most replicas report, the largest one on a tie
+func agreedCount(replicas []shardReplica) int {
+	votes := map[int]int{}
+	for _, r := range replicas {
+		votes[r.count]++
+	}
+
+	agreed, most := 0, 0
+	for count, n := range votes {
+		if n > most || (n == most && count > agreed) {
+			agreed, most = count, n
+		}
+	}
+	return agreed
+}
+
+func sortedMapKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_parseShardReplicas(t *testing.T) {
+	body := []byte(`{"nodes":[
+		{"name":"weaviate-0","shards":[{"name":"s1","class":"Replicated","objectCount":20}]},
+		{"name":"weaviate-1","shards":[{"name":"s1","class":"Replicated","objectCount":18}]}
+	]}`)
+
+	got, err := parseShardReplicas(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []shardReplica{
+		{class: "Replicated", shard: "s1", node: "weaviate-0", count: 20},
+		{class: "Replicated", shard: "s1", node: "weaviate-1", count: 18},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseShardReplicas() = %v, want %v", got, want)
+	}
+}
+
+func Test_compareCounts(t *testing.T) {
+	replicas := []shardReplica{
+		{class: "Replicated", shard: "s1", node: "weaviate-0", count: 20},
+		{class: "Replicated", shard: "s1", node: "weaviate-1", count: 18},
+		{class: "Replicated", shard: "s1", node: "weaviate-2", count: 20},
+		{class: "Collection", shard: "a", node: "weaviate-0", count: 3},
+		{class: "Collection", shard: "b", node: "weaviate-1", count: 4},
+	}
+
+	tests := []struct {
+		name         string
+		aggregated   map[string]int
+		wantLines    []string
+		wantDiverged bool
+	}{
+		{
+			name:       "diverged replica",
+			aggregated: map[string]int{"Replicated": 20, "Collection": 7},
+			wantLines: []string{
+				"class Collection: 7 objects",
+				"class Replicated, shard s1: weaviate-1 holds 18 objects, the other replicas 20",
+				"class Replicated: 20 objects",
+			},
+			wantDiverged: true,
+		},
+		{
+			name:       "Aggregate differs and a class without it",
+			aggregated: map[string]int{"Collection": 6},
+			wantLines: []string{
+				"class Collection: Aggregate counts 6 objects, shards hold 7",
+				"class Replicated, shard s1: weaviate-1 holds 18 objects, the other replicas 20",
+				"class Replicated: shards hold 20 objects",
+			},
+			wantDiverged: true,
+		},
+	}
+	for _, tt := range tests {
+		lines, diverged := compareCounts(replicas, tt.aggregated)
+		if !reflect.DeepEqual(lines, tt.wantLines) || diverged != tt.wantDiverged {
+			t.Errorf("%s: compareCounts() = %q, %t, want %q, %t", tt.name, lines, diverged, tt.wantLines,
+				tt.wantDiverged)
+		}
+	}
+
+	if _, diverged := compareCounts(replicas[3:], map[string]int{"Collection": 7}); diverged {
+		t.Error("compareCounts() diverged for matching counts")
+	}
+}
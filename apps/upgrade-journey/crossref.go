@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/weaviate/weaviate-go-client/v4/weaviate"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate/filters"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate/graphql"
+)
+
+// crossClassChain chains queries across Collection and RefTarget the way a
+// join would: the source object of a version is looked up, then used as
+// nearObject anchor together with a filter on the referenced class, and the
+// reference is finally resolved from the RefTarget side. A plain Get with a
+// where filter never combines a vector search with a cross-reference
+// filter, so those query planner paths are otherwise not covered.
+func crossClassChain(ctx context.Context, client *weaviate.Client,
+	posOfMaxVersion int,
+) error {
+	for i := 0; i <= posOfMaxVersion; i++ {
+		if err := crossClassChainForVersion(ctx, client, versions[i]); err != nil {
+			return fmt.Errorf("cross-class chain for %s: %w", versions[i], err)
+		}
+	}
+
+	return nil
+}
+
+func crossClassChainForVersion(ctx context.Context, client *weaviate.Client,
+	version string,
+) error {
+	sourceID, targetID, err := crossClassSource(ctx, client, version)
+	if err != nil {
+		return err
+	}
+
+	refFilter := filters.Where().
+		WithPath([]string{"ref_prop", "RefTarget", "version"}).
+		WithOperator(filters.Equal).
+		WithValueString(version)
+
+	reqCtx, cancel := requestContext(ctx)
+	result, err := client.GraphQL().Get().
+		WithClassName("Collection").
+		WithFields(
+			graphql.Field{Name: "_additional { id }"},
+			graphql.Field{Name: "ref_prop { ... on RefTarget { _additional { id } } }"},
+		).
+		WithNearObject(client.GraphQL().NearObjectArgBuilder().WithID(sourceID)).
+		WithWhere(refFilter).
+		Do(reqCtx)
+	cancel()
+	if err != nil {
+		return requestError(ctx, "nearObject with ref filter", err)
+	}
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("nearObject with ref filter: %v", result.Errors[0])
+	}
+
+	objs := result.Data["Get"].(map[string]interface{})["Collection"].([]interface{})
+	if len(objs) != 1 {
+		return fmt.Errorf("nearObject with ref filter: wanted 1 object, got %d", len(objs))
+	}
+
+	obj := objs[0].(map[string]interface{})
+	if id := additionalID(obj); id != sourceID {
+		return fmt.Errorf("nearObject with ref filter: wanted %s, got %s", sourceID, id)
+	}
+
+	refs, _ := obj["ref_prop"].([]interface{})
+	if len(refs) != 1 || additionalID(refs[0].(map[string]interface{})) != targetID {
+		return fmt.Errorf("nearObject with ref filter: wanted ref to %s, got %v", targetID, refs)
+	}
+
+	return crossClassTarget(ctx, client, version, targetID)
+}
+
+// crossClassSource returns the ids of the Collection object of the version
+// and of the RefTarget it references
+func crossClassSource(ctx context.Context, client *weaviate.Client,
+	version string,
+) (string, string, error) {
+	where := filters.Where().
+		WithPath([]string{"version"}).
+		WithOperator(filters.Equal).
+		WithValueString(version)
+
+	reqCtx, cancel := requestContext(ctx)
+	result, err := client.GraphQL().Get().
+		WithClassName("Collection").
+		WithFields(
+			graphql.Field{Name: "_additional { id }"},
+			graphql.Field{Name: "ref_prop { ... on RefTarget { _additional { id } } }"},
+		).
+		WithWhere(where).
+		Do(reqCtx)
+	cancel()
+	if err != nil {
+		return "", "", requestError(ctx, "get source object", err)
+	}
+	if len(result.Errors) > 0 {
+		return "", "", fmt.Errorf("source object: %v", result.Errors[0])
+	}
+
+	objs := result.Data["Get"].(map[string]interface{})["Collection"].([]interface{})
+	if len(objs) != 1 {
+		return "", "", fmt.Errorf("source object: wanted 1 object, got %d", len(objs))
+	}
+
+	obj := objs[0].(map[string]interface{})
+	refs, _ := obj["ref_prop"].([]interface{})
+	if len(refs) != 1 {
+		return "", "", fmt.Errorf("source object: wanted 1 ref, got %d", len(refs))
+	}
+
+	return additionalID(obj), additionalID(refs[0].(map[string]interface{})), nil
+}
+
+// crossClassTarget looks the referenced object up from its own class
+func crossClassTarget(ctx context.Context, client *weaviate.Client,
+	version, targetID string,
+) error {
+	where := filters.Where().
+		WithPath([]string{"id"}).
+		WithOperator(filters.Equal).
+		WithValueString(targetID)
+
+	reqCtx, cancel := requestContext(ctx)
+	result, err := client.GraphQL().Get().
+		WithClassName("RefTarget").
+		WithFields(graphql.Field{Name: "version"}).
+		WithWhere(where).
+		Do(reqCtx)
+	cancel()
+	if err != nil {
+		return requestError(ctx, "get ref target", err)
+	}
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("ref target: %v", result.Errors[0])
+	}
+
+	objs := result.Data["Get"].(map[string]interface{})["RefTarget"].([]interface{})
+	if len(objs) != 1 {
+		return fmt.Errorf("ref target: wanted 1 object, got %d", len(objs))
+	}
+
+	if actual := objs[0].(map[string]interface{})["version"]; actual != version {
+		return fmt.Errorf("ref target: wanted %s got %v", version, actual)
+	}
+
+	return nil
+}
+
+func additionalID(obj map[string]interface{}) string {
+	additional, _ := obj["_additional"].(map[string]interface{})
+	id, _ := additional["id"].(string)
+	return id
+}
+
+// crossClassChain is the raw counterpart of crossClassChain
+func (v *rawVerifier) crossClassChain(ctx context.Context, version string) error {
+	type ref struct {
+		Additional struct {
+			ID string `json:"id"`
+		} `json:"_additional"`
+	}
+	type collection struct {
+		Get struct {
+			Collection []struct {
+				ref
+				RefProp []ref `json:"ref_prop"`
+			} `json:"Collection"`
+		} `json:"Get"`
+	}
+
+	var source collection
+	if err := v.graphQL(ctx, fmt.Sprintf(`{Get{Collection(`+
+		`where:{path:["version"],operator:Equal,valueString:%q})`+
+		`{_additional{id} ref_prop{... on RefTarget{_additional{id}}}}}}`, version), &source); err != nil {
+		return err
+	}
+	if len(source.Get.Collection) != 1 || len(source.Get.Collection[0].RefProp) != 1 {
+		return fmt.Errorf("source object: wanted 1 object with 1 ref, got %v", source.Get.Collection)
+	}
+	sourceID := source.Get.Collection[0].Additional.ID
+	targetID := source.Get.Collection[0].RefProp[0].Additional.ID
+
+	var near collection
+	if err := v.graphQL(ctx, fmt.Sprintf(`{Get{Collection(nearObject:{id:%q},`+
+		`where:{path:["ref_prop","RefTarget","version"],operator:Equal,valueString:%q})`+
+		`{_additional{id} ref_prop{... on RefTarget{_additional{id}}}}}}`, sourceID, version), &near); err != nil {
+		return err
+	}
+	if len(near.Get.Collection) != 1 || near.Get.Collection[0].Additional.ID != sourceID ||
+		len(near.Get.Collection[0].RefProp) != 1 || near.Get.Collection[0].RefProp[0].Additional.ID != targetID {
+		return fmt.Errorf("nearObject with ref filter: wanted %s referencing %s, got %v",
+			sourceID, targetID, near.Get.Collection)
+	}
+
+	var target struct {
+		Get struct {
+			RefTarget []struct {
+				Version string `json:"version"`
+			} `json:"RefTarget"`
+		} `json:"Get"`
+	}
+	if err := v.graphQL(ctx, fmt.Sprintf(`{Get{RefTarget(`+
+		`where:{path:["id"],operator:Equal,valueString:%q}){version}}}`, targetID), &target); err != nil {
+		return err
+	}
+	if len(target.Get.RefTarget) != 1 || target.Get.RefTarget[0].Version != version {
+		return fmt.Errorf("ref target: wanted %s got %v", version, target.Get.RefTarget)
+	}
+
+	return nil
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// Debug windows of a scenario file raise the verbosity of some nodes for
+// some versions only, so that a long soak run does not drown in debug logs
+// but the hops that matter come with them:
+//
+//	debug:
+//	  - nodes: [1]
+//	    from: 1.25.0
+//	    until: 1.26.1
+//	    log_level: trace
+//	    pprof: true
+//	    trace: 5s
+//
+// Every hop restarts the nodes on the next version, a node that starts on a
+// version within from and until, both inclusive and defaulting to the first
+// and last version of the journey, starts with the log level of the window.
+// With pprof the node also records block and mutex profiles, which are
+// captured with its heap and goroutine profiles after every hop of the
+// window, together with an execution trace of the given length. No nodes
+// means all of them.
+type debugWindow struct {
+	Nodes    []int         `yaml:"nodes"`
+	From     string        `yaml:"from"`
+	Until    string        `yaml:"until"`
+	LogLevel string        `yaml:"log_level"`
+	Pprof    bool          `yaml:"pprof"`
+	Trace    time.Duration `yaml:"trace"`
+}
+
+// scenarioDebug is set from --scenario-file
+var scenarioDebug []debugWindow
+
+// debugLogLevels are the values of LOG_LEVEL a window may set
+var debugLogLevels = []string{"trace", "debug", "info", "warning", "error"}
+
+// debugProfileKinds are captured in a window with pprof on top of
+// profileKinds, the rates make the nodes record them
+var (
+	debugProfileKinds = []string{"block", "mutex"}
+	debugProfileRates = map[string]string{
+		"GO_BLOCK_PROFILE_RATE":     "1000",
+		"GO_MUTEX_PROFILE_FRACTION": "100",
+	}
+)
+
+// maxDebugTrace keeps an execution trace within the timeout of a capture
+const maxDebugTrace = 20 * time.Second
+
+func (w debugWindow) name() string {
+	return fmt.Sprintf("%s..%s", w.From, w.Until)
+}
+
+// validate checks what a window sets, validateJourney whether it fits the
+// journey and cluster of the run
+func (w debugWindow) validate() error {
+	if w.LogLevel == "" && !w.Pprof {
+		return fmt.Errorf("debug window %s: needs a log_level or pprof", w.name())
+	}
+	if w.LogLevel != "" && logLevelRank(w.LogLevel) == len(debugLogLevels) {
+		return fmt.Errorf("debug window %s: log_level must be one of %s, got %q", w.name(),
+			strings.Join(debugLogLevels, ", "), w.LogLevel)
+	}
+	if w.Trace < 0 || w.Trace > maxDebugTrace {
+		return fmt.Errorf("debug window %s: trace must be between 0 and %s", w.name(), maxDebugTrace)
+	}
+	if w.Trace > 0 && !w.Pprof {
+		return fmt.Errorf("debug window %s: trace needs pprof", w.name())
+	}
+	return nil
+}
+
+func (w debugWindow) validateJourney(versions []string, nodeCount int) error {
+	from, until := w.span(versions)
+	if from < 0 || until < 0 {
+		return fmt.Errorf("debug window %s: versions are not part of the journey %v", w.name(), versions)
+	}
+	if from > until {
+		return fmt.Errorf("debug window %s: from is after until in the journey", w.name())
+	}
+	for _, node := range w.Nodes {
+		if node < 0 || node >= nodeCount {
+			return fmt.Errorf("debug window %s: node %d does not exist, nodes are 0 to %d", w.name(), node,
+				nodeCount-1)
+		}
+	}
+	return nil
+}
+
+// span returns the positions of the first and last version of the window in
+// the journey, -1 for a version that is not part of it
+func (w debugWindow) span(versions []string) (int, int) {
+	from, until := 0, len(versions)-1
+	if w.From != "" {
+		from = versionPos(versions, w.From)
+	}
+	if w.Until != "" {
+		until = versionPos(versions, w.Until)
+	}
+	return from, until
+}
+
+// covers is true if the window applies to a node started on a version of
+// the journey
+func (w debugWindow) covers(versions []string, nodeId int, version string) bool {
+	pos := versionPos(versions, version)
+	from, until := w.span(versions)
+	if pos < 0 || pos < from || pos > until {
+		return false
+	}
+	if len(w.Nodes) == 0 {
+		return true
+	}
+	for _, node := range w.Nodes {
+		if node == nodeId {
+			return true
+		}
+	}
+	return false
+}
+
+// debugEnv is the environment of a node started on a version by the windows
+// that cover it. The most verbose log level wins if windows overlap.
+func debugEnv(windows []debugWindow, versions []string, nodeId int, version string) map[string]string {
+	env := map[string]string{}
+	for _, w := range windows {
+		if !w.covers(versions, nodeId, version) {
+			continue
+		}
+		level, ok := env["LOG_LEVEL"]
+		if w.LogLevel != "" && (!ok || logLevelRank(w.LogLevel) < logLevelRank(level)) {
+			env["LOG_LEVEL"] = w.LogLevel
+		}
+		if w.Pprof {
+			for key, value := range debugProfileRates {
+				env[key] = value
+			}
+		}
+	}
+	return env
+}
+
+// logLevelRank orders the log levels from the most verbose, an unknown
+// level comes last
+func logLevelRank(level string) int {
+	for i, l := range debugLogLevels {
+		if l == level {
+			return i
+		}
+	}
+	return len(debugLogLevels)
+}
+
+// debugProfiling is true if a window needs the pprof port of the nodes
+func debugProfiling() bool {
+	for _, w := range scenarioDebug {
+		if w.Pprof {
+			return true
+		}
+	}
+	return false
+}
+
+// captureDebug captures the profiles and traces of the nodes that a window
+// with pprof covers on a hop. It is best effort like captureProfiles.
+func (c *cluster) captureDebug(ctx context.Context, hop int) {
+	at := time.Now()
+	label := fmt.Sprintf("debug-hop%02d", hop)
+	for i, container := range c.Containers {
+		if container == nil {
+			continue
+		}
+
+		var trace time.Duration
+		covered := false
+		for _, w := range scenarioDebug {
+			if w.Pprof && w.covers(versions, i, versions[hop]) {
+				covered = true
+				if w.Trace > trace {
+					trace = w.Trace
+				}
+			}
+		}
+		if !covered {
+			continue
+		}
+
+		kinds := append(append([]string(nil), profileKinds...), debugProfileKinds...)
+		for _, kind := range kinds {
+			name := profileFileName(c.Hostname(i), kind, at, label)
+			if err := c.captureProfile(ctx, i, kind, path.Join(profilesDir(), name)); err != nil {
+				nodeLogger(c, i).Debug("cannot capture profile", "kind", kind, "err", err)
+			}
+		}
+		if trace > 0 {
+			name := strings.TrimSuffix(profileFileName(c.Hostname(i), "trace", at, label), ".pb.gz") + ".out"
+			kind := fmt.Sprintf("trace?seconds=%d", int(trace.Seconds()))
+			if err := c.captureProfile(ctx, i, kind, path.Join(profilesDir(), name)); err != nil {
+				nodeLogger(c, i).Debug("cannot capture trace", "err", err)
+			}
+		}
+	}
+}
+
+// debugSummary lists the debug windows of the scenario for the plan
+func debugSummary() []string {
+	var lines []string
+	for _, w := range scenarioDebug {
+		nodes := "all nodes"
+		if len(w.Nodes) > 0 {
+			nodes = fmt.Sprintf("nodes %v", w.Nodes)
+		}
+		from, until := w.From, w.Until
+		if from == "" {
+			from = "the first version"
+		}
+		if until == "" {
+			until = "the last version"
+		}
+
+		var what []string
+		if w.LogLevel != "" {
+			what = append(what, "log level "+w.LogLevel)
+		}
+		if w.Pprof {
+			what = append(what, "pprof")
+		}
+		if w.Trace > 0 {
+			what = append(what, fmt.Sprintf("%s traces", w.Trace))
+		}
+		lines = append(lines, fmt.Sprintf("debug: %s from %s until %s with %s", nodes, from, until,
+			strings.Join(what, ", ")))
+	}
+	return lines
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_debugWindow_validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		window  debugWindow
+		wantErr bool
+	}{
+		{name: "log level", window: debugWindow{LogLevel: "debug"}},
+		{name: "pprof with trace", window: debugWindow{Pprof: true, Trace: 5 * time.Second}},
+		{name: "nothing", window: debugWindow{From: "1.25.0"}, wantErr: true},
+		{name: "unknown log level", window: debugWindow{LogLevel: "verbose"}, wantErr: true},
+		{name: "trace without pprof", window: debugWindow{LogLevel: "debug", Trace: time.Second}, wantErr: true},
+		{name: "long trace", window: debugWindow{Pprof: true, Trace: time.Minute}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.window.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_debugWindow_validateJourney(t *testing.T) {
+	journey := []string{"1.24.0", "1.25.0", "1.26.0"}
+	tests := []struct {
+		name    string
+		window  debugWindow
+		wantErr bool
+	}{
+		{name: "whole journey", window: debugWindow{LogLevel: "debug"}},
+		{name: "some nodes", window: debugWindow{Nodes: []int{0, 2}, From: "1.25.0", LogLevel: "debug"}},
+		{name: "unknown version", window: debugWindow{Until: "1.25.1", LogLevel: "debug"}, wantErr: true},
+		{name: "reversed", window: debugWindow{From: "1.26.0", Until: "1.24.0", LogLevel: "debug"}, wantErr: true},
+		{name: "unknown node", window: debugWindow{Nodes: []int{3}, LogLevel: "debug"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.window.validateJourney(journey, 3); (err != nil) != tt.wantErr {
+				t.Errorf("validateJourney() err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_debugEnv(t *testing.T) {
+	journey := []string{"1.24.0", "1.25.0", "1.26.0"}
+	windows := []debugWindow{
+		{Nodes: []int{1}, From: "1.25.0", Until: "1.25.0", LogLevel: "trace", Pprof: true},
+		{Until: "1.25.0", LogLevel: "debug"},
+	}
+	profiling := map[string]string{
+		"LOG_LEVEL":                 "trace",
+		"GO_BLOCK_PROFILE_RATE":     "1000",
+		"GO_MUTEX_PROFILE_FRACTION": "100",
+	}
+	tests := []struct {
+		node    int
+		version string
+		want    map[string]string
+	}{
+		{node: 0, version: "1.24.0", want: map[string]string{"LOG_LEVEL": "debug"}},
+		{node: 1, version: "1.25.0", want: profiling},
+		{node: 0, version: "1.25.0", want: map[string]string{"LOG_LEVEL": "debug"}},
+		{node: 1, version: "1.26.0", want: map[string]string{}},
+		{node: 1, version: "1.27.0", want: map[string]string{}},
+	}
+	for _, tt := range tests {
+		if got := debugEnv(windows, journey, tt.node, tt.version); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("wanted %v for node %d on %s, got %v", tt.want, tt.node, tt.version, got)
+		}
+	}
+}
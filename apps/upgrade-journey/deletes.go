@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// withDeleteWhileDown is set by --delete-while-down
+var withDeleteWhileDown bool
+
+const (
+	// objects written to the class of every round, half of them are
+	// deleted while a replica is down
+	deleteRoundObjects = 20
+
+	// how long the returning node may take to drop the deleted objects
+	deleteRepairTimeout = time.Minute
+)
+
+// deleteRound is one hop of the deletion scenario, with a class of its own
+type deleteRound struct {
+	class   string
+	deleted []string
+	kept    []string
+
+	// resolved is true if the class resolves deletion conflicts, so that
+	// the returning node has to drop the objects as well
+	resolved bool
+}
+
+// deleteRounds are the rounds of all hops so far, every hop checks that
+// the deletions of the earlier ones still hold
+var deleteRounds []deleteRound
+
+// deleteWhileDown creates a replicated class, takes the last node down and
+// deletes half of the objects at QUORUM. Once the node is back, reads at
+// ALL must never return a deleted object, which repairs the node on the
+// versions that resolve deletion conflicts: there, the node has to drop
+// the objects on its own replica too. The deletions of every earlier hop
+// are checked again, an upgrade must not bring them back either.
+func (c *cluster) deleteWhileDown(ctx context.Context, posOfVersion int) error {
+	version := versions[posOfVersion]
+	if !supports(version, featureReplication) || c.NodeCount < 3 {
+		hopLogger(posOfVersion).Info("skipping deletes while a replica is down, they need replication on " +
+			"at least 3 nodes")
+		return nil
+	}
+
+	for _, round := range deleteRounds {
+		if err := round.checkDeleted(ctx, c, -1); err != nil {
+			return fmt.Errorf("deleted on an earlier hop: %w", err)
+		}
+	}
+
+	round, err := createDeleteRound(ctx, c, posOfVersion)
+	if err != nil {
+		return err
+	}
+
+	down := c.NodeCount - 1
+	if err := c.StopNode(ctx, down); err != nil {
+		return err
+	}
+
+	for _, id := range round.deleted {
+		if _, err := sendRaw(ctx, http.MethodDelete, 0, fmt.Sprintf("/v1/objects/%s/%s?consistency_level=QUORUM",
+			round.class, id), nil); err != nil {
+			return fmt.Errorf("delete %s while %s is down: %w", id, c.Hostname(down), err)
+		}
+	}
+
+	if err := c.StartStoppedNode(ctx, down, c.NodeVersions[down]); err != nil {
+		return fmt.Errorf("restart %s: %w", c.Hostname(down), err)
+	}
+	if err := c.WaitForHealthyNodes(ctx, 0, time.Minute); err != nil {
+		return err
+	}
+
+	if err := round.checkDeleted(ctx, c, down); err != nil {
+		return err
+	}
+	deleteRounds = append(deleteRounds, round)
+
+	hopLogger(posOfVersion).Info("deleted objects stayed deleted", "class", round.class,
+		"deleted", len(round.deleted), "resolved", round.resolved)
+	return nil
+}
+
+// createDeleteRound creates the class of a round and writes its objects to
+// every replica
+func createDeleteRound(ctx context.Context, c *cluster, posOfVersion int) (deleteRound, error) {
+	round := deleteRound{
+		class:    fmt.Sprintf("DeleteWhileDown%02d", posOfVersion),
+		resolved: supports(versions[posOfVersion], featureDeletionStrategy),
+	}
+
+	replication := map[string]interface{}{"factor": c.NodeCount}
+	if round.resolved {
+		replication["deletionStrategy"] = "DeleteOnConflict"
+	}
+	if _, err := postRaw(ctx, 0, "/v1/schema", map[string]interface{}{
+		"class":             round.class,
+		"vectorizer":        "none",
+		"replicationConfig": replication,
+		"properties": []map[string]interface{}{
+			{"name": "index", "dataType": []string{"int"}},
+		},
+	}); err != nil {
+		return round, fmt.Errorf("create class %s: %w", round.class, err)
+	}
+
+	for i := 0; i < deleteRoundObjects; i++ {
+		id := uuid.New().String()
+		if _, err := postRaw(ctx, 0, "/v1/objects?consistency_level=ALL", map[string]interface{}{
+			"class":      round.class,
+			"id":         id,
+			"vector":     []float32{float32(i), 1},
+			"properties": map[string]interface{}{"index": i},
+		}); err != nil {
+			return round, fmt.Errorf("import %s: %w", round.class, err)
+		}
+
+		if i%2 == 0 {
+			round.deleted = append(round.deleted, id)
+		} else {
+			round.kept = append(round.kept, id)
+		}
+	}
+	return round, nil
+}
+
+// checkDeleted reads every object of the round at ALL, which repairs the
+// replicas. A deleted object must never be returned, a kept one always.
+// If the round resolves conflicts, repaired is the node that missed the
+// deletions and has to drop its own copies, -1 skips that check.
+func (r deleteRound) checkDeleted(ctx context.Context, c *cluster, repaired int) error {
+	for _, id := range r.deleted {
+		status, _, err := doRaw(ctx, http.MethodGet, 0, fmt.Sprintf("/v1/objects/%s/%s?consistency_level=ALL",
+			r.class, id), nil)
+		if err != nil {
+			return fmt.Errorf("read deleted %s/%s at ALL: %w", r.class, id, err)
+		}
+		if status == http.StatusOK {
+			return fmt.Errorf("deleted object %s/%s was resurrected", r.class, id)
+		}
+	}
+
+	for _, id := range r.kept {
+		if _, err := getRaw(ctx, 0, fmt.Sprintf("/v1/objects/%s/%s?consistency_level=ALL", r.class,
+			id)); err != nil {
+			return fmt.Errorf("read kept %s/%s at ALL: %w", r.class, id, err)
+		}
+	}
+
+	if !r.resolved || repaired < 0 {
+		return nil
+	}
+
+	deadline := time.Now().Add(deleteRepairTimeout)
+	for {
+		left, err := r.localCopies(ctx, c, repaired)
+		if err == nil && left == 0 {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			if err != nil {
+				return fmt.Errorf("drop deleted objects on %s: %w", c.Hostname(repaired), err)
+			}
+			return fmt.Errorf("%s still holds %d of %d deleted objects of %s after %s", c.Hostname(repaired),
+				left, len(r.deleted), r.class, deleteRepairTimeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// localCopies returns how many deleted objects of the round a node still
+// holds in its own replica
+func (r deleteRound) localCopies(ctx context.Context, c *cluster, nodeId int) (int, error) {
+	left := 0
+	for _, id := range r.deleted {
+		status, body, err := doRaw(ctx, http.MethodGet, 0, fmt.Sprintf("/v1/objects/%s/%s?node_name=%s",
+			r.class, id, c.Hostname(nodeId)), nil)
+		if err != nil {
+			return 0, err
+		}
+
+		switch status {
+		case http.StatusOK:
+			left++
+		case http.StatusNotFound:
+		default:
+			return 0, fmt.Errorf("read %s/%s on %s: status %d: %s", r.class, id, c.Hostname(nodeId), status,
+				body)
+		}
+	}
+	return left, nil
+}
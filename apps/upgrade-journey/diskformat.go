@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// diskFormatRule describes the on-disk layout a version range must have.
+// Paths are globs relative to the data directory of a node. A migration
+// that only half finished often still serves reads, so this is checked on
+// the file system rather than through the API.
+type diskFormatRule struct {
+	description string
+
+	// since and until (exclusive) limit the versions the rule applies to,
+	// empty means unbounded
+	since, until string
+
+	mustExist    []string
+	mustNotExist []string
+
+	// shardMustExist are files of a shard of Collection. With --shards
+	// below the node count not every node holds one, so they are only
+	// expected on the nodes that do.
+	shardMustExist []string
+}
+
+// diskFormatRules encode the known on-disk migrations
+var diskFormatRules = []diskFormatRule{
+	{
+		description:    "flat shard layout before the hierarchy migration",
+		until:          "1.22.0",
+		shardMustExist: []string{"collection_*_lsm/objects"},
+	},
+	{
+		description:    "hierarchical shard layout",
+		since:          firstVersions[featureShardHierarchy],
+		mustExist:      []string{"migration1.22.fs.hierarchy"},
+		shardMustExist: []string{"collection/*/lsm/objects"},
+		mustNotExist:   []string{"collection_*_lsm"},
+	},
+	{
+		description: "schema in RAFT",
+		since:       firstVersions[featureRaft],
+		mustExist:   []string{"raft/raft.db"},
+	},
+}
+
+func (r diskFormatRule) appliesTo(version string) bool {
+	parsed, ok := parseVersion(version)
+	if !ok {
+		// like preview images, treated as newer than any release
+		return r.until == ""
+	}
+
+	if r.since != "" && !parsed.largerOrEqual(mustParseVersion(r.since)) {
+		return false
+	}
+
+	if r.until != "" && parsed.largerOrEqual(mustParseVersion(r.until)) {
+		return false
+	}
+
+	return true
+}
+
+// checkDiskFormat returns every violation of the rules for the data
+// directory of a node running the given version, holdsShard tells whether
+// the node holds a shard of Collection
+func checkDiskFormat(dataDir, version string, holdsShard bool, rules []diskFormatRule) ([]string, error) {
+	var violations []string
+	for _, rule := range rules {
+		if !rule.appliesTo(version) {
+			continue
+		}
+
+		mustExist := rule.mustExist
+		if holdsShard {
+			mustExist = append(append([]string(nil), mustExist...), rule.shardMustExist...)
+		}
+		for _, pattern := range mustExist {
+			matches, err := filepath.Glob(filepath.Join(dataDir, pattern))
+			if err != nil {
+				return nil, err
+			}
+			if len(matches) == 0 {
+				violations = append(violations, fmt.Sprintf("%s: %s is missing", rule.description, pattern))
+			}
+		}
+
+		for _, pattern := range rule.mustNotExist {
+			matches, err := filepath.Glob(filepath.Join(dataDir, pattern))
+			if err != nil {
+				return nil, err
+			}
+			if len(matches) > 0 {
+				violations = append(violations, fmt.Sprintf("%s: %s was not cleaned up", rule.description, pattern))
+			}
+		}
+	}
+
+	return violations, nil
+}
+
+// shardHolders returns the nodes that hold a shard of a class
+func shardHolders(body []byte, className string) (map[string]bool, error) {
+	placement, err := shardPlacement(body, className)
+	if err != nil {
+		return nil, err
+	}
+
+	holders := map[string]bool{}
+	for _, nodes := range placement {
+		for _, node := range nodes {
+			holders[node] = true
+		}
+	}
+	return holders, nil
+}
+
+// verifyDiskFormat checks the data directory of every node, the shard files
+// only on the nodes that hold a shard of Collection
+func (c *cluster) verifyDiskFormat(ctx context.Context, posOfVersion int) error {
+	body, err := getRaw(ctx, 0, "/v1/nodes?output=verbose")
+	if err != nil {
+		return fmt.Errorf("disk format: %w", err)
+	}
+	holders, err := shardHolders(body, "Collection")
+	if err != nil {
+		return fmt.Errorf("disk format: %w", err)
+	}
+
+	for i := 0; i < c.NodeCount; i++ {
+		violations, err := checkDiskFormat(c.VolumePath(i), c.NodeVersions[i], holders[c.Hostname(i)],
+			diskFormatRules)
+		if err != nil {
+			return err
+		}
+
+		if len(violations) > 0 {
+			return fmt.Errorf("data directory of %s on %s:\n%s", c.Hostname(i),
+				versions[posOfVersion], strings.Join(violations, "\n"))
+		}
+	}
+
+	return nil
+}
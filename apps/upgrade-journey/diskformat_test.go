@@ -0,0 +1,104 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_checkDiskFormat(t *testing.T) {
+	touch := func(t *testing.T, dir string, paths ...string) {
+		for _, p := range paths {
+			if err := os.MkdirAll(filepath.Join(dir, p), 0o777); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	tests := []struct {
+		name    string
+		version string
+		paths   []string
+		noShard bool
+		want    []string
+	}{
+		{
+			name:    "flat layout before the migration",
+			version: "1.21.8",
+			paths:   []string{"collection_abc_lsm/objects"},
+		},
+		{
+			name:    "migrated layout",
+			version: "1.22.0",
+			paths:   []string{"migration1.22.fs.hierarchy", "collection/abc/lsm/objects"},
+		},
+		{
+			name:    "half finished migration",
+			version: "1.22.0",
+			paths:   []string{"collection/abc/lsm/objects", "collection_abc_lsm/objects"},
+			want: []string{
+				"hierarchical shard layout: migration1.22.fs.hierarchy is missing",
+				"hierarchical shard layout: collection_*_lsm was not cleaned up",
+			},
+		},
+		{
+			name:    "missing raft state on a preview image",
+			version: "preview-abc",
+			paths:   []string{"migration1.22.fs.hierarchy", "collection/abc/lsm/objects"},
+			want:    []string{"schema in RAFT: raft/raft.db is missing"},
+		},
+		{
+			name:    "node without a shard before the migration",
+			version: "1.21.8",
+			noShard: true,
+		},
+		{
+			name:    "node without a shard after the migration",
+			version: "1.22.0",
+			paths:   []string{"migration1.22.fs.hierarchy"},
+			noShard: true,
+		},
+		{
+			name:    "node without a shard that did not migrate",
+			version: "1.22.0",
+			paths:   []string{"collection_abc_lsm/objects"},
+			noShard: true,
+			want: []string{
+				"hierarchical shard layout: migration1.22.fs.hierarchy is missing",
+				"hierarchical shard layout: collection_*_lsm was not cleaned up",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			touch(t, dir, tt.paths...)
+
+			got, err := checkDiskFormat(dir, tt.version, !tt.noShard, diskFormatRules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("checkDiskFormat() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_shardHolders(t *testing.T) {
+	// --shards=1 on three nodes
+	body := []byte(`{"nodes":[
+		{"name":"weaviate-0","shards":[{"name":"r1","class":"RefTarget"}]},
+		{"name":"weaviate-1","shards":[{"name":"s1","class":"Collection"}]},
+		{"name":"weaviate-2","shards":[]}
+	]}`)
+
+	got, err := shardHolders(body, "Collection")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]bool{"weaviate-1": true}; !reflect.DeepEqual(got, want) {
+		t.Errorf("shardHolders() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// resolvedConfigSections are the parts of a class whose effective values are
+// filled in with server-side defaults at creation time
+var resolvedConfigSections = []string{
+	"vectorIndexConfig",
+	"invertedIndexConfig",
+	"shardingConfig",
+}
+
+// expectedConfigDrift lists flattened paths (relative to the class) that are
+// allowed to differ from the value at creation time. A trailing "*" matches
+// any suffix.
+var expectedConfigDrift = []string{}
+
+// classConfigBaseline holds the fully resolved config of every class right
+// after it was created
+var classConfigBaseline = map[string][]byte{}
+
+// recordClassConfigBaseline must be called right after the schema was
+// created on the first version
+func recordClassConfigBaseline(ctx context.Context, classNames ...string) error {
+	for _, className := range classNames {
+		resolved, err := resolvedClassConfig(ctx, className)
+		if err != nil {
+			return fmt.Errorf("baseline for %s: %w", className, err)
+		}
+
+		classConfigBaseline[className] = resolved
+	}
+
+	return nil
+}
+
+// detectConfigDrift compares the resolved config of every class against the
+// baseline. Fields that only exist on the newer version are fine, but any
+// field that existed at creation time must still have the same value, as a
+// changed value means that a new default was applied retroactively.
+func detectConfigDrift(ctx context.Context, posOfVersion int) error {
+	var report strings.Builder
+	var drifted []string
+
+	for className, baseline := range classConfigBaseline {
+		resolved, err := resolvedClassConfig(ctx, className)
+		if err != nil {
+			return fmt.Errorf("config of %s: %w", className, err)
+		}
+
+		diff, err := diffSnapshots(baseline, resolved)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(&report, "class %s:\n%s", className, diff)
+		for _, line := range diff.unexpected(expectedConfigDrift) {
+			drifted = append(drifted, fmt.Sprintf("%s: %s", className, line))
+		}
+	}
+
+	dir := hopArtifactsDir(posOfVersion)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path.Join(dir, "config-drift.txt"), []byte(report.String()), 0o644); err != nil {
+		return err
+	}
+
+	if len(drifted) > 0 {
+		return fmt.Errorf("class config drifted from creation time on %s:\n%s",
+			versions[posOfVersion], strings.Join(drifted, "\n"))
+	}
+
+	return nil
+}
+
+// resolvedClassConfig returns only the default-resolved sections of a class
+// as JSON
+func resolvedClassConfig(ctx context.Context, className string) ([]byte, error) {
+	body, err := getRaw(ctx, 0, fmt.Sprintf("/v1/schema/%s", className))
+	if err != nil {
+		return nil, err
+	}
+
+	var class map[string]interface{}
+	if err := json.Unmarshal(body, &class); err != nil {
+		return nil, err
+	}
+
+	out := map[string]interface{}{}
+	for _, section := range resolvedConfigSections {
+		if value, ok := class[section]; ok {
+			out[section] = value
+		}
+	}
+
+	return json.Marshal(out)
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// With --check-egress the nodes run with telemetry disabled and nothing in
+// the cluster may call a host outside of it, as in an air-gapped
+// installation. A sidecar on the host network captures every connection
+// that a container of the cluster opens to an address outside of the
+// subnets of its network, from before the first node starts, so that the
+// calls of a node at startup are seen as well. Every hop fails on the calls
+// made since the hop before.
+const egressImage = "nicolaka/netshoot"
+
+// withEgressCheck is set by --check-egress
+var withEgressCheck bool
+
+// egress is the capture of the run, it is only started with --check-egress
+var egress egressMonitor
+
+type egressMonitor struct {
+	capture testcontainers.Container
+
+	// seen is the number of calls reported by earlier hops
+	seen int
+}
+
+// egressCall is a packet that opened a connection to outside the cluster
+type egressCall struct {
+	Src, Dst, Detail string
+}
+
+func (e egressCall) String() string {
+	return fmt.Sprintf("%s > %s (%s)", e.Src, e.Dst, e.Detail)
+}
+
+// srcIP is the address of the sender without the port, which an IPv4
+// address carries as a fifth part
+func (e egressCall) srcIP() string {
+	if parts := strings.Split(e.Src, "."); len(parts) == 5 {
+		return strings.Join(parts[:4], ".")
+	}
+	return e.Src
+}
+
+// egressEnv disables the telemetry of the nodes, it is the only call home a
+// node makes by default
+func egressEnv() map[string]string {
+	if !withEgressCheck {
+		return nil
+	}
+	return map[string]string{"DISABLE_TELEMETRY": "true"}
+}
+
+// egressFilter matches what a container of the subnets sends outside of
+// them to open a connection: the SYN of TCP, and any UDP or ICMP. The
+// answers to connections from the harness are not matched.
+func egressFilter(subnets []string) string {
+	nets := make([]string, len(subnets))
+	for i, subnet := range subnets {
+		nets[i] = "net " + subnet
+	}
+	within := strings.Join(nets, " or ")
+	return fmt.Sprintf("src (%s) and not dst (%s) and (udp or icmp or tcp[tcpflags] & (tcp-syn|tcp-ack) == tcp-syn)",
+		within, within)
+}
+
+// parseEgress parses a line of tcpdump -n -q, such as
+//
+//	10:01:02.345678 IP 172.18.0.2.41234 > 35.190.1.2.443: tcp 0
+//
+// lines that are no packet, such as the banner of tcpdump, are not ok
+func parseEgress(line string) (egressCall, bool) {
+	fields := strings.Fields(line)
+	for i := 0; i+3 < len(fields); i++ {
+		if (fields[i] != "IP" && fields[i] != "IP6") || fields[i+2] != ">" {
+			continue
+		}
+		return egressCall{
+			Src:    fields[i+1],
+			Dst:    strings.TrimSuffix(fields[i+3], ":"),
+			Detail: strings.Join(fields[i+4:], " "),
+		}, true
+	}
+	return egressCall{}, false
+}
+
+// startEgressCapture starts the capture on the bridge of the network of the
+// cluster, the network must exist
+func (c *cluster) startEgressCapture(ctx context.Context) error {
+	iface, subnets, err := c.Bridge(ctx)
+	if err != nil {
+		return fmt.Errorf("start egress capture: %w", err)
+	}
+
+	capture, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		Logger: testcontainersLogger{},
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image: egressImage,
+			Cmd:   []string{"tcpdump", "-n", "-q", "-l", "-i", iface, egressFilter(subnets)},
+			HostConfigModifier: func(hc *container.HostConfig) {
+				hc.NetworkMode = "host"
+				hc.CapAdd = []string{"NET_ADMIN", "NET_RAW"}
+			},
+			Labels:     c.Labels(),
+			WaitingFor: wait.ForLog("listening on").WithStartupTimeout(time.Minute),
+		},
+		Started: true,
+	})
+	if err != nil {
+		return fmt.Errorf("start egress capture: %w", err)
+	}
+	c.AddSidecar(capture)
+	egress.capture = capture
+
+	logger.Info("capturing egress of the cluster", "interface", iface, "subnets", subnets)
+	return nil
+}
+
+// checkEgress fails on the calls outside of the cluster since the last
+// check, naming the nodes that made them
+func (c *cluster) checkEgress(ctx context.Context, hop int) error {
+	logs, err := egress.capture.Logs(ctx)
+	if err != nil {
+		return fmt.Errorf("read egress capture: %w", err)
+	}
+	raw, err := io.ReadAll(logs)
+	logs.Close()
+	if err != nil {
+		return fmt.Errorf("read egress capture: %w", err)
+	}
+
+	var calls []egressCall
+	for _, line := range strings.Split(string(raw), "\n") {
+		if call, ok := parseEgress(line); ok {
+			calls = append(calls, call)
+		}
+	}
+	if len(calls) <= egress.seen {
+		return nil
+	}
+	calls, egress.seen = calls[egress.seen:], len(calls)
+
+	// the address of a node changes when it is recreated, so the names are
+	// resolved for the nodes as they are now
+	names := map[string]string{}
+	for i, node := range c.Containers {
+		if node == nil {
+			continue
+		}
+		if ip, err := node.ContainerIP(ctx); err == nil {
+			names[ip] = c.Hostname(i)
+		}
+	}
+
+	described := make([]string, 0, len(calls))
+	for _, call := range calls {
+		if name, ok := names[call.srcIP()]; ok {
+			call.Src = name + " " + call.Src
+		}
+		hopLogger(hop).Warn("call outside of the cluster", "call", call.String())
+		described = append(described, call.String())
+	}
+	if len(described) > 5 {
+		described = append(described[:5], fmt.Sprintf("and %d more", len(described)-5))
+	}
+	return fmt.Errorf("%d calls outside of the cluster: %s", len(calls), strings.Join(described, "; "))
+}
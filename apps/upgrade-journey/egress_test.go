@@ -0,0 +1,46 @@
+package main
+
+import "testing"
+
+func Test_egressFilter(t *testing.T) {
+	got := egressFilter([]string{"172.18.0.0/16", "fd00::/64"})
+	want := "src (net 172.18.0.0/16 or net fd00::/64) and not dst (net 172.18.0.0/16 or net fd00::/64) and " +
+		"(udp or icmp or tcp[tcpflags] & (tcp-syn|tcp-ack) == tcp-syn)"
+	if got != want {
+		t.Errorf("wanted %q, got %q", want, got)
+	}
+}
+
+func Test_parseEgress(t *testing.T) {
+	tests := []struct {
+		line   string
+		want   egressCall
+		wantOk bool
+		wantIP string
+	}{
+		{
+			line:   "10:01:02.345678 IP 172.18.0.2.41234 > 35.190.1.2.443: tcp 0",
+			want:   egressCall{Src: "172.18.0.2.41234", Dst: "35.190.1.2.443", Detail: "tcp 0"},
+			wantOk: true,
+			wantIP: "172.18.0.2",
+		},
+		{
+			line:   "10:01:02.345678 IP 172.18.0.3 > 8.8.8.8: ICMP echo request, id 1, seq 1, length 64",
+			want:   egressCall{Src: "172.18.0.3", Dst: "8.8.8.8", Detail: "ICMP echo request, id 1, seq 1, length 64"},
+			wantOk: true,
+			wantIP: "172.18.0.3",
+		},
+		{line: "tcpdump: verbose output suppressed, use -v[v]... for full protocol decode"},
+		{line: "listening on br-0123456789ab, link-type EN10MB (Ethernet), snapshot length 262144 bytes"},
+		{line: ""},
+	}
+	for _, tt := range tests {
+		got, ok := parseEgress(tt.line)
+		if ok != tt.wantOk || got != tt.want {
+			t.Errorf("wanted %+v, %t for %q, got %+v, %t", tt.want, tt.wantOk, tt.line, got, ok)
+		}
+		if ok && got.srcIP() != tt.wantIP {
+			t.Errorf("wanted source %s for %q, got %s", tt.wantIP, tt.line, got.srcIP())
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"context"
+
+	"github.com/weaviate/weaviate-chaos-engineering/apps/upgrade-journey/pkg/workloads"
+)
+
+var expiring = workloads.NewExpiry()
+
+// expiryVerifier checks that exactly the objects that did not expire yet
+// exist
+type expiryVerifier struct{}
+
+func (v *expiryVerifier) name() string {
+	return "expiry"
+}
+
+func (v *expiryVerifier) verify(ctx context.Context, posOfMaxVersion int) error {
+	return expiring.Verify(ctx, nodeHost(0))
+}
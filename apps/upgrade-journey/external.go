@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// With --external-url the harness starts no containers at all and runs the
+// workloads and verifiers against a cluster that is managed elsewhere, e.g.
+// a Weaviate Cloud or a staging cluster, as a single hop on the version the
+// cluster reports. Only the client-side faults of the fault proxy can be
+// injected. The API key is read from WEAVIATE_API_KEY, so that it never
+// shows up in the process list or in the logs of a CI job.
+var (
+	// externalURL is set by --external-url
+	externalURL string
+
+	externalTarget *url.URL
+)
+
+// externalFlags are the flags that work without Docker, every other flag
+// is rejected together with --external-url instead of being ignored
+var externalFlags = map[string]bool{
+	"external-url":           true,
+	"client-matrix":          true,
+	"transport":              true,
+	"max-duration":           true,
+	"log-format":             true,
+	"progress":               true,
+	"results-store":          true,
+	"scenario":               true,
+	"verify-retries":         true,
+	"faults":                 true,
+	"verify-under-fault":     true,
+	"fault-hold":             true,
+	"verify-routing":         true,
+	"import-routing":         true,
+	"vector-drift-tolerance": true,
+	"load-objects":           true,
+	"load-workers":           true,
+	"load-batch-size":        true,
+	"load-dimensions":        true,
+	"load-target-latency":    true,
+	"import-error-budget":    true,
+	"shards":                 true,
+	"revector-objects":       true,
+	"unicode-texts":          true,
+	"tokenization-matrix":    true,
+	"request-timeout":        true,
+	"ledger-clock-skew":      true,
+	"drift-window":           true,
+	"harness-check-interval": true,
+	"max-harness-goroutines": true,
+	"max-harness-heap-mb":    true,
+	"max-harness-fds":        true,
+}
+
+// flagConflicts returns the flags of set that are not allowed, in the order
+// given
+func flagConflicts(set []string, allowed map[string]bool) []string {
+	var conflicts []string
+	for _, name := range set {
+		if !allowed[name] {
+			conflicts = append(conflicts, "--"+name)
+		}
+	}
+	return conflicts
+}
+
+// externalFaultConflicts returns the fault specs that are not client-side
+func externalFaultConflicts(specs []string) []string {
+	var conflicts []string
+	for _, spec := range specs {
+		if name, _, _ := strings.Cut(spec, ":"); name != "http" {
+			conflicts = append(conflicts, spec)
+		}
+	}
+	return conflicts
+}
+
+// parseExternalURL accepts a full URL or a bare host, which defaults to
+// https
+func parseExternalURL(value string) (*url.URL, error) {
+	if !strings.Contains(value, "://") {
+		value = "https://" + value
+	}
+	target, err := url.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("--external-url: %w", err)
+	}
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return nil, fmt.Errorf("--external-url: scheme must be http or https, got %q", target.Scheme)
+	}
+	if target.Host == "" || (target.Path != "" && target.Path != "/") {
+		return nil, fmt.Errorf("--external-url: want the root of the cluster, e.g. https://host, got %q", value)
+	}
+	return target, nil
+}
+
+// externalTransport sends the requests the harness addresses to the
+// external host with the scheme and the API key of the cluster. Everything
+// in the harness speaks plain HTTP to nodeHost, so this is the one place
+// that knows about TLS and authentication.
+type externalTransport struct {
+	target *url.URL
+	apiKey string
+	base   http.RoundTripper
+}
+
+func (t externalTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != t.target.Host {
+		return t.base.RoundTrip(req)
+	}
+
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	// requests through the fault proxy carry the address of the proxy
+	req.Host = ""
+	if t.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+t.apiKey)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// setupExternal routes all requests to the external cluster and returns
+// the version it runs
+func setupExternal(ctx context.Context, apiKey string) (string, error) {
+	http.DefaultTransport = externalTransport{target: externalTarget, apiKey: apiKey, base: http.DefaultTransport}
+
+	body, err := getRaw(ctx, 0, "/v1/meta")
+	if err != nil {
+		return "", fmt.Errorf("external cluster %s: %w", externalTarget, err)
+	}
+	var meta struct {
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(body, &meta); err != nil {
+		return "", fmt.Errorf("external cluster %s: %w", externalTarget, err)
+	}
+	if meta.Version == "" {
+		return "", fmt.Errorf("external cluster %s reports no version", externalTarget)
+	}
+	return meta.Version, nil
+}
+
+// doExternal runs a single hop against the external cluster: it creates
+// the classes of the workloads, imports, verifies and applies the client
+// faults. The classes the run created are removed again after a successful
+// run and kept for inspection after a failed one.
+func doExternal(ctx context.Context, pool *clientPool, importRouting routing, loadObjects int,
+	verifiers []verifier, faults faultSchedule, b *budget,
+) (err error) {
+	ctx, stopSelfCheck := harnessHealth.watch(ctx)
+	defer func() {
+		stopSelfCheck()
+		if cause := harnessHealth.cause(); cause != nil {
+			err = cause
+		}
+	}()
+
+	existing, err := externalClasses(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			logger.Warn("keeping the classes of the failed run on the external cluster")
+			saveCountComparison()
+			return
+		}
+		err = removeExternalClasses(ctx, existing)
+	}()
+
+	if err := clientFaultProxy.start(nodeHost(0)); err != nil {
+		return err
+	}
+
+	const i = 0
+	if err := b.run(ctx, i, phaseImport, func(ctx context.Context) error {
+		if err := createWorkloadClasses(ctx, pool); err != nil {
+			return err
+		}
+		return importWorkloads(ctx, pool, importRouting, i, loadObjects)
+	}); err != nil {
+		return err
+	}
+
+	if err := b.run(ctx, i, phaseVerify, func(ctx context.Context) error {
+		return runVerifiers(ctx, verifiers, i)
+	}); err != nil {
+		return err
+	}
+
+	// the client faults never touch the cluster
+	return applyHopFaults(ctx, nil, verifiers, faults, b, i, loadObjects)
+}
+
+// createWorkloadClasses creates the classes of the journey and of the
+// workloads that run without Docker
+func createWorkloadClasses(ctx context.Context, pool *clientPool) error {
+	if err := createSchema(ctx, pool.node(0)); err != nil {
+		return err
+	}
+
+	if err := writeLedger.CreateClass(ctx, nodeHost(0)); err != nil {
+		return err
+	}
+
+	if err := expiring.CreateClass(ctx, nodeHost(0)); err != nil {
+		return err
+	}
+
+	if bulkLoad != nil {
+		if err := bulkLoad.CreateClass(ctx, nodeHost(0)); err != nil {
+			return err
+		}
+	}
+
+	if revectoring != nil {
+		if err := revectoring.CreateClass(ctx, nodeHost(0), revectorObjects); err != nil {
+			return err
+		}
+	}
+
+	if unicodeTexts != nil {
+		if err := unicodeTexts.CreateClass(ctx, nodeHost(0)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importWorkloads runs the imports of a hop of the workloads that run
+// without Docker
+func importWorkloads(ctx context.Context, pool *clientPool, importRouting routing, i, loadObjects int) error {
+	if err := expiring.ImportAndSweep(ctx, nodeHost(0), i); err != nil {
+		return err
+	}
+
+	if err := writeLedger.Write(ctx, clientFaultProxy.host(), i, ledgerWritesPerHop); err != nil {
+		return err
+	}
+
+	if err := writeLedger.Mutate(ctx, clientFaultProxy.host(), i, ledgerMutationsPerHop); err != nil {
+		return err
+	}
+
+	if err := updateVectors(ctx, i); err != nil {
+		return err
+	}
+
+	if unicodeTexts != nil {
+		if err := unicodeTexts.Import(ctx, nodeHost(0), i); err != nil {
+			return err
+		}
+	}
+
+	if err := importTokenizations(ctx, i); err != nil {
+		return err
+	}
+
+	if err := importProbes(ctx, i); err != nil {
+		return err
+	}
+
+	pool.refresh(ctx)
+	client, nodeId, err := pool.pick(importRouting)
+	if err != nil {
+		return err
+	}
+
+	if bulkLoad != nil {
+		if err := bulkLoad.Import(ctx, nodeHost(nodeId), i, loadObjects); err != nil {
+			return err
+		}
+	}
+
+	return importForVersion(ctx, client, versions[i])
+}
+
+func externalClasses(ctx context.Context) (map[string]bool, error) {
+	body, err := getRaw(ctx, 0, "/v1/schema")
+	if err != nil {
+		return nil, fmt.Errorf("schema of the external cluster: %w", err)
+	}
+	var schema struct {
+		Classes []struct {
+			Class string `json:"class"`
+		} `json:"classes"`
+	}
+	if err := json.Unmarshal(body, &schema); err != nil {
+		return nil, err
+	}
+
+	classes := map[string]bool{}
+	for _, class := range schema.Classes {
+		classes[class.Class] = true
+	}
+	return classes, nil
+}
+
+// removeExternalClasses removes every class that did not exist before the
+// run
+func removeExternalClasses(ctx context.Context, existing map[string]bool) error {
+	current, err := externalClasses(ctx)
+	if err != nil {
+		return err
+	}
+
+	var created []string
+	for class := range current {
+		if !existing[class] {
+			created = append(created, class)
+		}
+	}
+	sort.Strings(created)
+
+	for _, class := range created {
+		if _, err := sendRaw(ctx, http.MethodDelete, 0, "/v1/schema/"+class, nil); err != nil {
+			return fmt.Errorf("remove class %s from the external cluster: %w", class, err)
+		}
+	}
+	logger.Info("removed the classes of the run from the external cluster", "classes", created)
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestExternalConflicts(t *testing.T) {
+	set := []string{"external-url", "faults", "nodes", "load-objects", "toxiproxy"}
+	want := []string{"--nodes", "--toxiproxy"}
+	if got := flagConflicts(set, externalFlags); !reflect.DeepEqual(got, want) {
+		t.Errorf("wanted %v, got %v", want, got)
+	}
+
+	specs := []string{"http:mode=stall,rate=0.5", "kill:node=1", "http"}
+	if got := externalFaultConflicts(specs); !reflect.DeepEqual(got, []string{"kill:node=1"}) {
+		t.Errorf("wanted the kill fault, got %v", got)
+	}
+}
+
+func TestParseExternalURL(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "abc.weaviate.cloud", want: "https://abc.weaviate.cloud"},
+		{value: "http://localhost:8080/", want: "http://localhost:8080/"},
+		{value: "https://abc.weaviate.cloud/v1", wantErr: true},
+		{value: "grpc://abc.weaviate.cloud", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseExternalURL(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: unexpected error %v", tt.value, err)
+			continue
+		}
+		if err == nil && got.String() != tt.want {
+			t.Errorf("%s: wanted %s, got %s", tt.value, tt.want, got)
+		}
+	}
+}
+
+func TestExternalTransport(t *testing.T) {
+	var gotAuth, gotHost string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth, gotHost = r.Header.Get("Authorization"), r.Host
+	}))
+	defer server.Close()
+
+	target, _ := url.Parse(server.URL)
+	client := &http.Client{Transport: externalTransport{target: target, apiKey: "secret", base: http.DefaultTransport}}
+
+	// like a request forwarded by the fault proxy
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/v1/meta", nil)
+	req.Host = "127.0.0.1:1"
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if gotAuth != "Bearer secret" || gotHost != target.Host {
+		t.Errorf("wanted the API key and host %s, got %q and %s", target.Host, gotAuth, gotHost)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/weaviate/weaviate-chaos-engineering/apps/upgrade-journey/pkg/faults"
+	"github.com/weaviate/weaviate-chaos-engineering/apps/upgrade-journey/pkg/workloads"
+)
+
+// httpFaultMode is what the fault proxy does to a request it picked
+type httpFaultMode string
+
+const (
+	// httpFaultNone forwards every request untouched
+	httpFaultNone httpFaultMode = ""
+
+	// httpFaultUnavailable answers with a 503 without forwarding, the
+	// request has no effect
+	httpFaultUnavailable httpFaultMode = "unavailable"
+
+	// httpFaultAmbiguous forwards the request, but answers with a 502, so
+	// the client can't know whether the request had an effect
+	httpFaultAmbiguous httpFaultMode = "ambiguous"
+
+	// httpFaultStall forwards the request and holds back the response, like
+	// a slowloris, until the client gives up
+	httpFaultStall httpFaultMode = "stall"
+)
+
+// faultProxy is an in-process reverse proxy in front of the client-facing
+// port of a node. The ledger workload writes through it, so that its
+// handling of failed and ambiguous writes can be tested.
+type faultProxy struct {
+	sync.Mutex
+	mode     httpFaultMode
+	rate     float64
+	stall    time.Duration
+	listener net.Listener
+	proxy    *httputil.ReverseProxy
+}
+
+var clientFaultProxy = &faultProxy{}
+
+// start listens on a random local port and forwards to upstream
+func (p *faultProxy) start(upstream string) error {
+	target, err := url.Parse("http://" + upstream)
+	if err != nil {
+		return err
+	}
+
+	p.listener, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("listen for fault proxy: %w", err)
+	}
+
+	p.proxy = httputil.NewSingleHostReverseProxy(target)
+	go http.Serve(p.listener, p)
+	return nil
+}
+
+func (p *faultProxy) host() string {
+	return p.listener.Addr().String()
+}
+
+func (p *faultProxy) set(mode httpFaultMode, rate float64, stall time.Duration) {
+	p.Lock()
+	defer p.Unlock()
+	p.mode, p.rate, p.stall = mode, rate, stall
+}
+
+func (p *faultProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.Lock()
+	mode, rate, stall := p.mode, p.rate, p.stall
+	p.Unlock()
+
+	if mode == httpFaultNone || rand.Float64() >= rate {
+		p.proxy.ServeHTTP(w, r)
+		return
+	}
+
+	switch mode {
+	case httpFaultUnavailable:
+		http.Error(w, "injected fault", http.StatusServiceUnavailable)
+	case httpFaultAmbiguous:
+		p.proxy.ServeHTTP(httptest.NewRecorder(), r)
+		http.Error(w, "injected fault", http.StatusBadGateway)
+	case httpFaultStall:
+		rec := httptest.NewRecorder()
+		p.proxy.ServeHTTP(rec, r)
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(stall):
+		}
+		for key, values := range rec.Header() {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(rec.Code)
+		w.Write(rec.Body.Bytes())
+	}
+}
+
+// httpFault makes the fault proxy fail a share of the requests
+type httpFault struct {
+	mode  httpFaultMode
+	rate  float64
+	stall time.Duration
+}
+
+func newHTTPFault(p faults.Params) (faultInjector, error) {
+	f := &httpFault{mode: httpFaultMode(p.String("mode", string(httpFaultAmbiguous)))}
+	switch f.mode {
+	case httpFaultUnavailable, httpFaultAmbiguous, httpFaultStall:
+	default:
+		return nil, fmt.Errorf("mode must be unavailable, ambiguous or stall")
+	}
+
+	rate, err := strconv.ParseFloat(p.String("rate", "0.3"), 64)
+	if err != nil || rate <= 0 || rate > 1 {
+		return nil, fmt.Errorf("rate must be in (0, 1]")
+	}
+	f.rate = rate
+
+	f.stall, err = p.Duration("stall", 2*workloads.LedgerWriteTimeout)
+	return f, err
+}
+
+func (f *httpFault) Describe() string {
+	return fmt.Sprintf("%s HTTP responses for %.0f%% of the requests", f.mode, f.rate*100)
+}
+
+func (f *httpFault) Inject(ctx context.Context, c *cluster) error {
+	clientFaultProxy.set(f.mode, f.rate, f.stall)
+	return nil
+}
+
+func (f *httpFault) Heal(ctx context.Context, c *cluster) error {
+	clientFaultProxy.set(httpFaultNone, 0, 0)
+	return nil
+}
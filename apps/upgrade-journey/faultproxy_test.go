@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/weaviate/weaviate-chaos-engineering/apps/upgrade-journey/pkg/workloads"
+)
+
+// fakeObjectStore accepts object creations the way Weaviate does: a second
+// creation with the same id is rejected
+type fakeObjectStore struct {
+	sync.Mutex
+	ids map[string]bool
+}
+
+func (s *fakeObjectStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var obj struct {
+		ID string `json:"id"`
+	}
+	json.NewDecoder(r.Body).Decode(&obj)
+
+	s.Lock()
+	defer s.Unlock()
+	if s.ids[obj.ID] {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(`{"error":[{"message":"id '` + obj.ID + `' already exists"}]}`))
+		return
+	}
+	s.ids[obj.ID] = true
+	w.Write([]byte(`{}`))
+}
+
+func Test_ledgerThroughFaultProxy(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode       httpFaultMode
+		wantStatus workloads.WriteStatus
+		wantStored bool
+	}{
+		{name: "no fault", mode: httpFaultNone, wantStatus: workloads.WriteAcknowledged, wantStored: true},
+		{name: "unavailable", mode: httpFaultUnavailable, wantStatus: workloads.WriteUnacknowledged, wantStored: false},
+		{name: "ambiguous", mode: httpFaultAmbiguous, wantStatus: workloads.WriteUnacknowledged, wantStored: true},
+		{name: "stall", mode: httpFaultStall, wantStatus: workloads.WriteUnacknowledged, wantStored: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeObjectStore{ids: map[string]bool{}}
+			server := httptest.NewServer(store)
+			defer server.Close()
+
+			proxy := &faultProxy{}
+			if err := proxy.start(strings.TrimPrefix(server.URL, "http://")); err != nil {
+				t.Fatal(err)
+			}
+			defer proxy.listener.Close()
+			proxy.set(tt.mode, 1, time.Second)
+
+			l := workloads.NewLedger(100*time.Millisecond, logger)
+			if err := l.Write(context.Background(), proxy.host(), 0, 1); err != nil {
+				t.Fatal(err)
+			}
+
+			for id, status := range l.Entries() {
+				if status != tt.wantStatus {
+					t.Errorf("status = %s, want %s", status, tt.wantStatus)
+				}
+				if store.ids[id] != tt.wantStored {
+					t.Errorf("stored = %v, want %v", store.ids[id], tt.wantStored)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/weaviate/weaviate-chaos-engineering/apps/upgrade-journey/pkg/faults"
+)
+
+// faultInjector is a single, reversible fault on the cluster of the journey
+type faultInjector = faults.Injector[*cluster]
+
+// faultWindows are the times faults were active. Workload errors outside of
+// all of them fail the run.
+var faultWindows = &faults.Windows{}
+
+// faultRegistry maps the name used in a fault spec to its implementation.
+// Scenarios and schedulers only ever create faults through it. Next to the
+// built-in faults it holds the ones that need state of the journey.
+func faultRegistry() faults.Registry[*cluster] {
+	if remoteNodes != nil {
+		return remoteFaultRegistry()
+	}
+
+	registry := faults.Builtin[*cluster](nodeCount)
+	registry["replace"] = func(p faults.Params) (faultInjector, error) {
+		node, err := p.Node(nodeCount)
+		return &replaceFault{node: node}, err
+	}
+	registry["stale"] = func(p faults.Params) (faultInjector, error) {
+		node, err := p.Node(nodeCount)
+		if err != nil {
+			return nil, err
+		}
+		minAge, err := strconv.Atoi(p.String("age", "2"))
+		if err != nil || minAge < 1 {
+			return nil, fmt.Errorf("age must be at least 1 hop")
+		}
+		return &staleFault{node: node, minAge: minAge}, nil
+	}
+	registry["toxic"] = newToxicFault
+	registry["http"] = newHTTPFault
+	return registry
+}
+
+// newFault parses a spec of the form name:key=value,key=value, for example
+// "latency:node=1,delay=500ms"
+func newFault(spec string) (faultInjector, error) {
+	return faultRegistry().New(spec)
+}
+
+// applyFault injects the fault, keeps it for the hold duration, runs during
+// (if set) while the fault is still active and heals it again. The fault is
+// recorded in the report either way, and heal is attempted even if the
+// injection failed half-way.
+func applyFault(ctx context.Context, c *cluster, hop int, f faultInjector, hold time.Duration,
+	during func(ctx context.Context) error,
+) (err error) {
+	defer func(start time.Time) {
+		runReport.recordFault(hop, f.Describe(), start, err)
+	}(time.Now())
+
+	logger.Info("injecting fault", "hop", hop, "fault", f.Describe())
+	defer faultWindows.Open(f.Describe())()
+	injectErr := f.Inject(ctx, c)
+	if injectErr == nil {
+		select {
+		case <-ctx.Done():
+			injectErr = ctx.Err()
+		case <-time.After(hold):
+		}
+	}
+
+	var duringErr error
+	if injectErr == nil && during != nil {
+		duringErr = during(ctx)
+	}
+
+	healStart := time.Now()
+	if err := f.Heal(ctx, c); err != nil {
+		return fmt.Errorf("heal %s: %w", f.Describe(), err)
+	}
+	scenarioAlerts.checkRepair(f.Describe(), time.Since(healStart))
+
+	if injectErr != nil {
+		return fmt.Errorf("inject %s: %w", f.Describe(), injectErr)
+	}
+
+	if duringErr != nil {
+		return fmt.Errorf("during %s: %w", f.Describe(), duringErr)
+	}
+
+	logger.Info("healed fault", "hop", hop, "fault", f.Describe())
+	return nil
+}
+
+// faultSchedule decides which faults are applied on every hop and how
+type faultSchedule struct {
+	specs         []string
+	hold          time.Duration
+	verifyDuring  bool
+	duringUpgrade bool
+}
+
+// create returns new faults for a single hop, the specs were validated when
+// the schedule was built and a fault can't be reused
+func (s faultSchedule) create() []faultInjector {
+	out := make([]faultInjector, len(s.specs))
+	for i, spec := range s.specs {
+		out[i], _ = newFault(spec)
+	}
+	return out
+}
+
+// withFaults runs fn while all faults are active
+func withFaults(ctx context.Context, c *cluster, hop int, faults []faultInjector,
+	fn func(ctx context.Context) error,
+) error {
+	if len(faults) == 0 {
+		return fn(ctx)
+	}
+
+	return applyFault(ctx, c, hop, faults[0], 0, func(ctx context.Context) error {
+		return withFaults(ctx, c, hop, faults[1:], fn)
+	})
+}
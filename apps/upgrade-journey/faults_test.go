@@ -0,0 +1,52 @@
+package main
+
+import "testing"
+
+func Test_newFault(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    string
+		wantErr bool
+	}{
+		{spec: "kill:node=1", want: "kill weaviate-1"},
+		{spec: "pause", want: "pause weaviate-2"},
+		{spec: " latency:node=0,delay=500ms", want: "delay packets of weaviate-0 by 500ms"},
+		{spec: "disk:size=2g", want: "fill disk of weaviate-2 with 2g"},
+		{spec: "wipe:node=1,path=raft/raft.db", want: "wipe raft/raft.db of weaviate-1"},
+		{spec: "cpu:node=1", want: "throttle weaviate-1 to 0.2 CPUs"},
+		{spec: "cpu:cpus=0.05", want: "throttle weaviate-2 to 0.05 CPUs"},
+		{spec: "cpu:cpus=0", wantErr: true},
+		{spec: "dns:node=0", want: "break DNS of weaviate-0"},
+		{spec: "toxic:node=1,latency=500", want: "latency toxic on downstream link to weaviate-1 map[latency:500]"},
+		{spec: "toxic:type=reset_peer,stream=upstream,timeout=0", want: "reset_peer toxic on upstream link to weaviate-2 map[timeout:0]"},
+		{spec: "toxic:type=meteor", wantErr: true},
+		{spec: "toxic:latency=fast", wantErr: true},
+		{spec: "http:rate=0.5", want: "ambiguous HTTP responses for 50% of the requests"},
+		{spec: "http:mode=stall,rate=1,stall=10s", want: "stall HTTP responses for 100% of the requests"},
+		{spec: "http:rate=2", wantErr: true},
+		{spec: "http:mode=teapot", wantErr: true},
+		{spec: "replace:node=1", want: "replace weaviate-1 with an empty data directory"},
+		{spec: "stale:age=3", want: "restart weaviate-2 on a data directory at least 3 hops old"},
+		{spec: "stale:age=0", wantErr: true},
+		{spec: "partition:node=3", wantErr: true},
+		{spec: "latency:delay=soon", wantErr: true},
+		{spec: "kill:node", wantErr: true},
+		{spec: "meteor", wantErr: true},
+	}
+	for _, tt := range tests {
+		f, err := newFault(tt.spec)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("newFault(%q) expected an error", tt.spec)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("newFault(%q): %v", tt.spec, err)
+			continue
+		}
+		if got := f.Describe(); got != tt.want {
+			t.Errorf("newFault(%q).Describe() = %q, want %q", tt.spec, got, tt.want)
+		}
+	}
+}
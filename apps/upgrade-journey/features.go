@@ -0,0 +1,90 @@
+package main
+
+// feature is a capability of Weaviate that only some versions of the
+// journey have. Workloads ask supports instead of comparing versions on
+// their own, so that every first version is kept in one place.
+type feature string
+
+const (
+	featureReplication      feature = "replication"
+	featureHybrid           feature = "hybrid search"
+	featureCursor           feature = "cursor"
+	featureGRPC             feature = "grpc"
+	featureMultiTenancy     feature = "multi-tenancy"
+	featureTenantActivity   feature = "tenant activity"
+	featureShardHierarchy   feature = "hierarchical shard layout"
+	featureAsyncIndexing    feature = "async indexing"
+	featureGenerativeURL    feature = "generative base url"
+	featureNamedVectors     feature = "named vectors"
+	featureRaft             feature = "raft schema"
+	featureRBAC             feature = "rbac"
+	featureDeletionStrategy feature = "deletion conflict resolution"
+	featureTokenization     feature = "text tokenization"
+	featureTrigram          feature = "trigram tokenization"
+	featureNullState        feature = "null state index"
+	featureNestedObjects    feature = "nested objects"
+	featureContainsFilters  feature = "contains filters"
+	featureUUIDType         feature = "uuid data type"
+	featureMultiVector      feature = "multi-vector embeddings"
+)
+
+// firstVersions is the first release that has a feature
+var firstVersions = map[feature]string{
+	featureReplication:      "1.17.0",
+	featureHybrid:           "1.17.0",
+	featureCursor:           "1.18.0",
+	featureGRPC:             "1.19.0",
+	featureMultiTenancy:     "1.20.0",
+	featureTenantActivity:   "1.21.0",
+	featureShardHierarchy:   "1.22.0",
+	featureAsyncIndexing:    "1.22.0",
+	featureGenerativeURL:    "1.23.0",
+	featureNamedVectors:     "1.24.0",
+	featureRaft:             "1.25.0",
+	featureRBAC:             "1.28.0",
+	featureDeletionStrategy: "1.28.0",
+	featureTokenization:     "1.19.0",
+	featureTrigram:          "1.24.0",
+	featureNullState:        "1.16.0",
+	featureNestedObjects:    "1.22.0",
+	featureContainsFilters:  "1.21.0",
+	featureUUIDType:         "1.19.0",
+	featureMultiVector:      "1.29.0",
+}
+
+// supports is true if the version has the feature
+func supports(version string, f feature) bool {
+	since, ok := firstVersions[f]
+	if !ok {
+		panic("unknown feature " + string(f))
+	}
+	return atLeast(version, since)
+}
+
+// atLeast is true if the version is the same as or newer than since.
+// Anything that is not a valid semver, such as a preview image, is assumed
+// to be newer than every release.
+func atLeast(version, since string) bool {
+	parsed, ok := parseVersion(version)
+	if !ok {
+		return true
+	}
+	return parsed.largerOrEqual(mustParseVersion(since))
+}
+
+// supportedFeatures lists the features of a version, for the log of a hop
+func supportedFeatures(version string) []string {
+	var features []string
+	for _, f := range []feature{
+		featureReplication, featureHybrid, featureCursor, featureGRPC, featureMultiTenancy, featureTenantActivity,
+		featureShardHierarchy, featureAsyncIndexing, featureGenerativeURL, featureNamedVectors, featureRaft,
+		featureRBAC, featureDeletionStrategy, featureTokenization, featureTrigram, featureNullState,
+		featureNestedObjects, featureContainsFilters, featureUUIDType,
+		featureMultiVector,
+	} {
+		if supports(version, f) {
+			features = append(features, string(f))
+		}
+	}
+	return features
+}
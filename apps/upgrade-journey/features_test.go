@@ -0,0 +1,39 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSupports(t *testing.T) {
+	tests := []struct {
+		version string
+		feature feature
+		want    bool
+	}{
+		{version: "1.24.9", feature: featureRaft, want: false},
+		{version: "1.25.0", feature: featureRaft, want: true},
+		{version: "1.16.9", feature: featureReplication, want: false},
+		{version: "1.17.0", feature: featureReplication, want: true},
+		{version: "preview-abc123", feature: featureRBAC, want: true},
+		{version: "1.27.9", feature: featureDeletionStrategy, want: false},
+		{version: "1.23.16", feature: featureTrigram, want: false},
+		{version: "1.20.5", feature: featureContainsFilters, want: false},
+		{version: "1.28.4", feature: featureMultiVector, want: false},
+	}
+
+	for _, tt := range tests {
+		if got := supports(tt.version, tt.feature); got != tt.want {
+			t.Errorf("supports(%s, %s) = %t, want %t", tt.version, tt.feature, got, tt.want)
+		}
+	}
+}
+
+func TestSupportedFeatures(t *testing.T) {
+	want := []string{"replication", "hybrid search", "cursor", "grpc", "multi-tenancy", "tenant activity",
+		"text tokenization", "null state index", "contains filters",
+		"uuid data type"}
+	if got := supportedFeatures("1.21.3"); !reflect.DeepEqual(got, want) {
+		t.Errorf("wanted %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+type outcome string
+
+const (
+	outcomePass  outcome = "pass"
+	outcomeFlaky outcome = "flaky"
+	outcomeFail  outcome = "fail"
+)
+
+// flakePolicy retries a step that failed. It must only be used for steps
+// that are idempotent, such as read-only verification, never for imports or
+// upgrades, so that a real finding can't be retried away. A step that
+// passes after a retry is reported as flaky rather than passed, together
+// with the error of its first attempt.
+type flakePolicy struct {
+	retries int
+	backoff time.Duration
+}
+
+// verifyRetries is replaced in main once the flags are parsed
+var verifyRetries = flakePolicy{backoff: 2 * time.Second}
+
+func (p flakePolicy) run(ctx context.Context, fn func(ctx context.Context) error) (outcome, int, error) {
+	var firstErr error
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		switch {
+		case err == nil && firstErr == nil:
+			return outcomePass, attempt, nil
+		case err == nil:
+			return outcomeFlaky, attempt, firstErr
+		case firstErr == nil:
+			firstErr = err
+		}
+
+		if attempt > p.retries || ctx.Err() != nil {
+			return outcomeFail, attempt, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return outcomeFail, attempt, err
+		case <-time.After(p.backoff):
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func Test_flakePolicy(t *testing.T) {
+	failTimes := func(n int) func(context.Context) error {
+		calls := 0
+		return func(context.Context) error {
+			calls++
+			if calls <= n {
+				return errors.New("hiccup")
+			}
+			return nil
+		}
+	}
+
+	tests := []struct {
+		name         string
+		retries      int
+		failures     int
+		wantOutcome  outcome
+		wantAttempts int
+	}{
+		{name: "passes right away", retries: 2, failures: 0, wantOutcome: outcomePass, wantAttempts: 1},
+		{name: "passes on retry", retries: 2, failures: 2, wantOutcome: outcomeFlaky, wantAttempts: 3},
+		{name: "runs out of retries", retries: 2, failures: 3, wantOutcome: outcomeFail, wantAttempts: 3},
+		{name: "no retries", retries: 0, failures: 1, wantOutcome: outcomeFail, wantAttempts: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := flakePolicy{retries: tt.retries}
+			got, attempts, err := p.run(context.Background(), failTimes(tt.failures))
+			if got != tt.wantOutcome || attempts != tt.wantAttempts {
+				t.Errorf("run() = %s after %d attempts, want %s after %d",
+					got, attempts, tt.wantOutcome, tt.wantAttempts)
+			}
+			if (err == nil) != (tt.failures == 0) {
+				t.Errorf("run() err = %v", err)
+			}
+		})
+	}
+}
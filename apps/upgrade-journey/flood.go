@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// The connection flood is a client gone wrong: it opens connections to a
+// single node as fast as it can and gives up on every request after a few
+// milliseconds, so that the node is left with requests nobody waits for
+// anymore.
+var (
+	// withConnectionFlood is set by --connection-flood
+	withConnectionFlood bool
+
+	// floodRequests is set by --flood-requests
+	floodRequests int
+
+	// floodConnections is set by --flood-connections
+	floodConnections int
+
+	// floodTimeout is set by --flood-timeout
+	floodTimeout time.Duration
+)
+
+const (
+	// the other nodes are probed this often while the flood runs
+	floodProbeInterval = 100 * time.Millisecond
+
+	// a probe of another node that takes longer fails the flood
+	floodProbeTimeout = 2 * time.Second
+
+	// how long the flooded node may take to serve requests again
+	floodRecoveryTimeout = time.Minute
+)
+
+// floodOutcomes counts how the requests of a flood ended, by the kind of
+// error
+type floodOutcomes map[string]int
+
+// floodOutcome names how a single request of the flood ended. Timeouts are
+// the point of the flood, refused and reset connections are how a node
+// protects itself, neither fails the run.
+func floodOutcome(err error) string {
+	switch {
+	case err == nil:
+		return "completed"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return "refused"
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return "reset"
+	default:
+		return "other"
+	}
+}
+
+// connectionFlood floods the last node with --flood-requests requests,
+// --flood-connections of them at a time, each with a fresh connection and
+// a deadline of --flood-timeout. Every other node is probed throughout and
+// has to answer every probe in time. Afterwards the flooded node has to
+// serve requests again within floodRecoveryTimeout, the time it takes is
+// reported per hop.
+func (c *cluster) connectionFlood(ctx context.Context, hop int) error {
+	target := c.NodeCount - 1
+
+	probeCtx, stopProbes := context.WithCancel(ctx)
+	probeErr := make(chan error, 1)
+	go func() {
+		probeErr <- probeOtherNodes(probeCtx, c, target, hop)
+	}()
+
+	outcomes := flood(ctx, nodeHost(target))
+	stopProbes()
+	if err := <-probeErr; err != nil {
+		return fmt.Errorf("while flooding %s: %w", c.Hostname(target), err)
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	start := time.Now()
+	if err := awaitFloodRecovery(ctx, target); err != nil {
+		return fmt.Errorf("%s after the flood: %w", c.Hostname(target), err)
+	}
+
+	took := time.Since(start)
+	runReport.recordLatency(hop, "connection flood recovery", took)
+	hopLogger(hop).Info("node recovered from a connection flood", "node", c.Hostname(target),
+		"outcomes", outcomes, "took", took.Round(time.Millisecond))
+	return nil
+}
+
+// validateFlood keeps the connections of the flood within the limits the
+// harness watches itself with, a flood must not stop the run as a leak
+func validateFlood() error {
+	if floodRequests < 1 || floodConnections < 1 {
+		return fmt.Errorf("--flood-requests and --flood-connections must be at least 1")
+	}
+	if floodTimeout <= 0 {
+		return fmt.Errorf("--flood-timeout must be positive")
+	}
+	if harnessLimits.fds > 0 && floodConnections >= harnessLimits.fds {
+		return fmt.Errorf("--flood-connections %d must stay below --max-harness-fds %d", floodConnections,
+			harnessLimits.fds)
+	}
+	return nil
+}
+
+// flood sends the requests of the flood and counts their outcomes
+func flood(ctx context.Context, host string) floodOutcomes {
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	defer client.CloseIdleConnections()
+
+	var mu sync.Mutex
+	outcomes := floodOutcomes{}
+	requests := make(chan struct{})
+	var wg sync.WaitGroup
+	for w := 0; w < floodConnections; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range requests {
+				outcome := floodOutcome(floodRequest(ctx, client, host))
+				mu.Lock()
+				outcomes[outcome]++
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for i := 0; i < floodRequests && ctx.Err() == nil; i++ {
+		requests <- struct{}{}
+	}
+	close(requests)
+	wg.Wait()
+	return outcomes
+}
+
+func floodRequest(ctx context.Context, client *http.Client, host string) error {
+	ctx, cancel := context.WithTimeout(ctx, floodTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("http://%s/v1/objects?limit=100",
+		host), nil)
+	if err != nil {
+		return err
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	_, err = io.Copy(io.Discard, res.Body)
+	return err
+}
+
+// probeOtherNodes checks the readiness of every node but the flooded one
+// until ctx is done and returns the first probe that failed. The slowest
+// probe is reported.
+func probeOtherNodes(ctx context.Context, c *cluster, flooded, hop int) error {
+	var slowest time.Duration
+	defer func() {
+		runReport.recordLatency(hop, "connection flood probe", slowest)
+	}()
+
+	for {
+		for nodeId := 0; nodeId < c.NodeCount; nodeId++ {
+			if nodeId == flooded {
+				continue
+			}
+
+			probeCtx, cancel := context.WithTimeout(ctx, floodProbeTimeout)
+			start := time.Now()
+			status, _, err := doRaw(probeCtx, http.MethodGet, nodeId, "/v1/.well-known/ready", nil)
+			cancel()
+			if ctx.Err() != nil {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("probe %s: %w", c.Hostname(nodeId), err)
+			}
+			if status != http.StatusOK {
+				return fmt.Errorf("probe %s: status %d", c.Hostname(nodeId), status)
+			}
+			if took := time.Since(start); took > slowest {
+				slowest = took
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(floodProbeInterval):
+		}
+	}
+}
+
+// awaitFloodRecovery waits until the node is ready and serves objects again
+func awaitFloodRecovery(ctx context.Context, nodeId int) error {
+	deadline := time.Now().Add(floodRecoveryTimeout)
+	for {
+		_, err := getRaw(ctx, nodeId, "/v1/.well-known/ready")
+		if err == nil {
+			_, err = getRaw(ctx, nodeId, "/v1/objects?limit=1")
+		}
+		if err == nil {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("not recovered within %s: %w", floodRecoveryTimeout, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
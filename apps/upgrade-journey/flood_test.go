@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func Test_floodOutcome(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{err: nil, want: "completed"},
+		{err: &url.Error{Op: "Get", URL: "http://localhost", Err: context.DeadlineExceeded}, want: "timeout"},
+		{err: fmt.Errorf("dial: %w", syscall.ECONNREFUSED), want: "refused"},
+		{err: fmt.Errorf("read: %w", syscall.ECONNRESET), want: "reset"},
+		{err: io.ErrUnexpectedEOF, want: "reset"},
+		{err: fmt.Errorf("no such host"), want: "other"},
+	}
+	for _, tt := range tests {
+		if got := floodOutcome(tt.err); got != tt.want {
+			t.Errorf("floodOutcome(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func Test_validateFlood(t *testing.T) {
+	defer func(r, c, fds int, timeout time.Duration) {
+		floodRequests, floodConnections, harnessLimits.fds, floodTimeout = r, c, fds, timeout
+	}(floodRequests, floodConnections, harnessLimits.fds, floodTimeout)
+	floodTimeout = 10 * time.Millisecond
+
+	tests := []struct {
+		connections int
+		fds         int
+		wantErr     bool
+	}{
+		{connections: 1000, fds: 4096},
+		{connections: 5000, fds: 4096, wantErr: true},
+		{connections: 5000, fds: 0},
+		{connections: 0, fds: 0, wantErr: true},
+	}
+	for _, tt := range tests {
+		floodRequests, floodConnections, harnessLimits.fds = 10, tt.connections, tt.fds
+		if err := validateFlood(); (err != nil) != tt.wantErr {
+			t.Errorf("validateFlood() with %d connections and %d fds: err = %v, wantErr %v", tt.connections,
+				tt.fds, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// With --generative-mock the nodes run the generative-openai module against
+// a mock of the OpenAI API, so that a search followed by a generation can
+// be checked on every hop without a real API key. The mock answers with the
+// prompt it received, which shows that the results of the search made it
+// into the prompt.
+var withGenerativeMock bool
+
+const (
+	generativeMockImage = "python:3.11-alpine"
+	generativeMockHost  = "openai-mock"
+	generativeMockKey   = "mock-key"
+	generativeClass     = "RagProbe"
+	generativeModel     = "gpt-3.5-turbo"
+
+	// the mock counts the completions it served, GET returns the count
+	generativeMockScript = `
+import http.server, json
+
+served = 0
+
+class Handler(http.server.BaseHTTPRequestHandler):
+    def do_GET(self):
+        self.reply(200, {"requests": served})
+
+    def do_POST(self):
+        global served
+        body = json.loads(self.rfile.read(int(self.headers.get("Content-Length", 0))))
+        if self.headers.get("Authorization") != "Bearer ` + generativeMockKey + `":
+            return self.reply(401, {"error": {"message": "invalid api key", "type": "invalid_request_error"}})
+        served += 1
+        prompt = body["messages"][-1]["content"]
+        self.reply(200, {
+            "id": "chatcmpl-mock", "object": "chat.completion", "created": 0, "model": body.get("model", ""),
+            "choices": [{"index": 0, "finish_reason": "stop",
+                         "message": {"role": "assistant", "content": "mock: " + prompt}}],
+            "usage": {"prompt_tokens": 1, "completion_tokens": 1, "total_tokens": 2},
+        })
+
+    def reply(self, status, payload):
+        raw = json.dumps(payload).encode()
+        self.send_response(status)
+        self.send_header("Content-Type", "application/json")
+        self.send_header("Content-Length", str(len(raw)))
+        self.end_headers()
+        self.wfile.write(raw)
+
+http.server.ThreadingHTTPServer(("", 8000), Handler).serve_forever()
+`
+)
+
+// generativeMockURL is where this process reaches the mock, the nodes reach
+// it by its network alias
+var generativeMockURL string
+
+var generativeTexts = []string{
+	"Rolling updates replace one node at a time",
+	"Backups are written to the filesystem of every node",
+	"The schema is replicated with RAFT since 1.25",
+}
+
+func (c *cluster) startGenerativeMock(ctx context.Context) error {
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		Logger: testcontainersLogger{},
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:    generativeMockImage,
+			Cmd:      []string{"python", "-c", generativeMockScript},
+			Networks: []string{c.NetworkName},
+			NetworkAliases: map[string][]string{
+				c.NetworkName: {generativeMockHost},
+			},
+			ExposedPorts: []string{"8000/tcp"},
+			Labels:       c.Labels(),
+			WaitingFor:   wait.ForListeningPort("8000/tcp").WithStartupTimeout(time.Minute),
+		},
+		Started: true,
+	})
+	if err != nil {
+		return fmt.Errorf("start generative mock: %w", err)
+	}
+	c.AddSidecar(container)
+
+	endpoint, err := container.PortEndpoint(ctx, nat.Port("8000/tcp"), "http")
+	if err != nil {
+		return fmt.Errorf("generative mock: %w", err)
+	}
+	generativeMockURL = endpoint
+	return nil
+}
+
+// generativeEnv gives the module the key the mock expects
+func generativeEnv() map[string]string {
+	return map[string]string{"OPENAI_APIKEY": generativeMockKey}
+}
+
+// generativeProbe writes the probe class and its texts once, on the first
+// hop of a version that can point the module to the mock
+var generativeProbe probeImports
+
+// generativeVerifier searches the probe class and generates from the
+// results through the mock, once per result and once for all of them
+type generativeVerifier struct{}
+
+func (v *generativeVerifier) name() string {
+	return "generative"
+}
+
+func (v *generativeVerifier) verify(ctx context.Context, posOfMaxVersion int) error {
+	if !supports(versions[posOfMaxVersion], featureGenerativeURL) {
+		return nil
+	}
+
+	if err := checkGenerativeConfig(ctx); err != nil {
+		return err
+	}
+
+	before, err := generativeMockRequests(ctx)
+	if err != nil {
+		return err
+	}
+
+	results, err := generate(ctx)
+	if err != nil {
+		return err
+	}
+	if broken := checkGenerations(results); len(broken) > 0 {
+		return fmt.Errorf("generation on %s:\n%s", versions[posOfMaxVersion], strings.Join(broken, "\n"))
+	}
+
+	after, err := generativeMockRequests(ctx)
+	if err != nil {
+		return err
+	}
+	// one completion per result and one for the group
+	if want := len(results.hits) + 1; after-before < want {
+		return fmt.Errorf("the mock served %d completions for the search, want %d", after-before, want)
+	}
+	return nil
+}
+
+func generativeModuleConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"generative-openai": map[string]interface{}{
+			"baseURL": fmt.Sprintf("http://%s:8000", generativeMockHost),
+			"model":   generativeModel,
+		},
+	}
+}
+
+func createGenerativeClass(ctx context.Context) error {
+	if _, err := postRaw(ctx, 0, "/v1/schema", map[string]interface{}{
+		"class":        generativeClass,
+		"vectorizer":   "none",
+		"moduleConfig": generativeModuleConfig(),
+		"properties": []map[string]interface{}{
+			{"name": "text", "dataType": []string{"text"}},
+		},
+	}); err != nil {
+		return fmt.Errorf("create class %s: %w", generativeClass, err)
+	}
+	return nil
+}
+
+func importGenerativeTexts(ctx context.Context) error {
+	var objects []map[string]interface{}
+	for i, text := range generativeTexts {
+		objects = append(objects, map[string]interface{}{
+			"class":      generativeClass,
+			"id":         probeID(generativeClass, 0, i),
+			"vector":     []float32{float32(i + 1), 1, 0},
+			"properties": map[string]interface{}{"text": text},
+		})
+	}
+	if _, err := postRaw(ctx, 0, "/v1/batch/objects", map[string]interface{}{"objects": objects}); err != nil {
+		return fmt.Errorf("import %s: %w", generativeClass, err)
+	}
+	return nil
+}
+
+// checkGenerativeConfig checks that the class still points the module to
+// the mock
+func checkGenerativeConfig(ctx context.Context) error {
+	body, err := getRaw(ctx, 0, "/v1/schema/"+generativeClass)
+	if err != nil {
+		return fmt.Errorf("class %s: %w", generativeClass, err)
+	}
+
+	var class struct {
+		ModuleConfig map[string]map[string]interface{} `json:"moduleConfig"`
+	}
+	if err := json.Unmarshal(body, &class); err != nil {
+		return err
+	}
+
+	got := class.ModuleConfig["generative-openai"]
+	for key, want := range generativeModuleConfig()["generative-openai"].(map[string]interface{}) {
+		if got[key] != want {
+			return fmt.Errorf("module config %s of %s is %v, want %v", key, generativeClass, got[key], want)
+		}
+	}
+	return nil
+}
+
+const (
+	generativePrompt = "Summarize: {text}"
+	generativeTask   = "List the topics of these texts"
+)
+
+type generativeHit struct {
+	text         string
+	singleResult string
+	err          string
+}
+
+type generativeResults struct {
+	hits          []generativeHit
+	groupedResult string
+}
+
+// generate searches the probe class and generates from every result and
+// from all of them together
+func generate(ctx context.Context) (generativeResults, error) {
+	var results generativeResults
+	body, err := postRaw(ctx, 0, "/v1/graphql", map[string]interface{}{
+		"query": fmt.Sprintf(`{Get{%s(nearVector:{vector:[1,1,0]},limit:2){text `+
+			`_additional{generate(singleResult:{prompt:%q},groupedResult:{task:%q})`+
+			`{singleResult groupedResult error}}}}}`, generativeClass, generativePrompt, generativeTask),
+	})
+	if err != nil {
+		return results, fmt.Errorf("generate: %w", err)
+	}
+	return parseGenerations(body)
+}
+
+func parseGenerations(body []byte) (generativeResults, error) {
+	var results generativeResults
+	var res struct {
+		Data struct {
+			Get map[string][]struct {
+				Text       string `json:"text"`
+				Additional struct {
+					Generate struct {
+						SingleResult  string `json:"singleResult"`
+						GroupedResult string `json:"groupedResult"`
+						Error         string `json:"error"`
+					} `json:"generate"`
+				} `json:"_additional"`
+			} `json:"Get"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return results, err
+	}
+	if len(res.Errors) > 0 {
+		return results, fmt.Errorf("generate: %s", res.Errors[0].Message)
+	}
+
+	for _, hit := range res.Data.Get[generativeClass] {
+		generated := hit.Additional.Generate
+		results.hits = append(results.hits, generativeHit{
+			text: hit.Text, singleResult: generated.SingleResult, err: generated.Error,
+		})
+		// the grouped result comes with the first hit only
+		if generated.GroupedResult != "" {
+			results.groupedResult = generated.GroupedResult
+		}
+	}
+	return results, nil
+}
+
+// checkGenerations checks that every prompt the mock echoed holds what the
+// search found
+func checkGenerations(results generativeResults) []string {
+	if len(results.hits) == 0 {
+		return []string{"the search found nothing"}
+	}
+
+	var broken []string
+	for _, hit := range results.hits {
+		want := "mock: " + strings.ReplaceAll(generativePrompt, "{text}", hit.text)
+		switch {
+		case hit.err != "":
+			broken = append(broken, fmt.Sprintf("%q: %s", hit.text, hit.err))
+		case hit.singleResult != want:
+			broken = append(broken, fmt.Sprintf("%q: generated %q, want %q", hit.text, hit.singleResult, want))
+		}
+		if !strings.Contains(results.groupedResult, hit.text) {
+			broken = append(broken, fmt.Sprintf("grouped result %q misses %q", results.groupedResult, hit.text))
+		}
+	}
+	if !strings.Contains(results.groupedResult, generativeTask) {
+		broken = append(broken, fmt.Sprintf("grouped result %q misses the task", results.groupedResult))
+	}
+	return broken
+}
+
+// generativeMockRequests returns how many completions the mock served
+func generativeMockRequests(ctx context.Context) (int, error) {
+	reqCtx, cancel := requestContext(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, generativeMockURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, requestError(ctx, "GET "+generativeMockURL, err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return 0, err
+	}
+	var served struct {
+		Requests int `json:"requests"`
+	}
+	if err := json.Unmarshal(body, &served); err != nil {
+		return 0, fmt.Errorf("generative mock: %w", err)
+	}
+	return served.Requests, nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_parseGenerations(t *testing.T) {
+	body := []byte(`{"data":{"Get":{"RagProbe":[
+		{"text":"a","_additional":{"generate":{"singleResult":"mock: Summarize: a","groupedResult":"mock: all","error":null}}},
+		{"text":"b","_additional":{"generate":{"singleResult":"mock: Summarize: b","groupedResult":null,"error":null}}}
+	]}}}`)
+
+	got, err := parseGenerations(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := generativeResults{
+		hits: []generativeHit{
+			{text: "a", singleResult: "mock: Summarize: a"},
+			{text: "b", singleResult: "mock: Summarize: b"},
+		},
+		groupedResult: "mock: all",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseGenerations() = %+v, want %+v", got, want)
+	}
+
+	if _, err := parseGenerations([]byte(`{"errors":[{"message":"no api key"}]}`)); err == nil {
+		t.Error("wanted an error for a GraphQL error")
+	}
+}
+
+func Test_checkGenerations(t *testing.T) {
+	grouped := "mock: " + generativeTask + ` [{"text":"a"},{"text":"b"}]`
+	tests := []struct {
+		name    string
+		results generativeResults
+		broken  int
+	}{
+		{name: "echoed", results: generativeResults{
+			hits: []generativeHit{
+				{text: "a", singleResult: "mock: Summarize: a"},
+				{text: "b", singleResult: "mock: Summarize: b"},
+			},
+			groupedResult: grouped,
+		}},
+		{name: "nothing found", broken: 1},
+		{name: "wrong prompt", results: generativeResults{
+			hits:          []generativeHit{{text: "a", singleResult: "mock: Summarize: b"}},
+			groupedResult: grouped,
+		}, broken: 1},
+		{name: "module error", results: generativeResults{
+			hits:          []generativeHit{{text: "a", err: "connection refused"}},
+			groupedResult: grouped,
+		}, broken: 1},
+		{name: "grouped result misses a hit", results: generativeResults{
+			hits:          []generativeHit{{text: "zebra", singleResult: "mock: Summarize: zebra"}},
+			groupedResult: grouped,
+		}, broken: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkGenerations(tt.results); len(got) != tt.broken {
+				t.Errorf("checkGenerations() = %v, want %d problems", got, tt.broken)
+			}
+		})
+	}
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"plugin"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Hooks of a scenario file run before or after every step of a phase, e.g.
+// to dump state the harness does not look at or to ask an external checker
+// whether the cluster is fine:
+//
+//	hooks:
+//	  - phase: verify
+//	    when: post
+//	    run: ./check-cluster.sh "$WEAVIATE_HOSTS"
+//	  - phase: upgrade
+//	    when: pre
+//	    func: dump-schema
+//	    versions: ["1.25.0"]
+//	  - phase: verify
+//	    when: post
+//	    func: ./hooks.so:Check
+//	    optional: true
+//
+// run is a bash command, func is either one of hookFuncs or a symbol of a Go
+// plugin, as file:Symbol, of type func(context.Context, map[string]string)
+// error. Both get the hop, version, phase and nodes of the step through the
+// environment, see hookEnv. A failed hook fails its step unless optional.
+type stepHook struct {
+	Phase    phase         `yaml:"phase"`
+	When     string        `yaml:"when"`
+	Run      string        `yaml:"run"`
+	Func     string        `yaml:"func"`
+	Versions []string      `yaml:"versions"`
+	Timeout  time.Duration `yaml:"timeout"`
+	Optional bool          `yaml:"optional"`
+}
+
+const (
+	hookPre  = "pre"
+	hookPost = "post"
+
+	defaultHookTimeout = 5 * time.Minute
+)
+
+// scenarioHooks is set from --scenario-file
+var scenarioHooks []stepHook
+
+// hookFunc is a hook implemented in Go, env is the environment of the step
+type hookFunc func(ctx context.Context, env map[string]string) error
+
+// hookFuncs are the hooks a scenario can name without a plugin
+var hookFuncs = map[string]hookFunc{
+	"dump-schema": dumpHook("schema", "/v1/schema"),
+	"dump-nodes":  dumpHook("nodes", "/v1/nodes?output=verbose"),
+}
+
+func (h stepHook) name() string {
+	if h.Run != "" {
+		return h.Run
+	}
+	return h.Func
+}
+
+func (h stepHook) validate() error {
+	if _, ok := phases[h.Phase]; !ok {
+		return fmt.Errorf("hook %q: unknown phase %q", h.name(), h.Phase)
+	}
+	if h.When != hookPre && h.When != hookPost {
+		return fmt.Errorf("hook %q: when must be %s or %s, got %q", h.name(), hookPre, hookPost, h.When)
+	}
+	if (h.Run == "") == (h.Func == "") {
+		return fmt.Errorf("hook of phase %s: needs either run or func", h.Phase)
+	}
+	if _, _, isPlugin := strings.Cut(h.Func, ":"); h.Func != "" && !isPlugin && hookFuncs[h.Func] == nil {
+		return fmt.Errorf("hook %q: unknown func, known are %s", h.Func, strings.Join(sortedMapKeys(hookFuncs),
+			", "))
+	}
+	if h.Timeout < 0 {
+		return fmt.Errorf("hook %q: timeout must not be negative", h.name())
+	}
+	return nil
+}
+
+// matches is true if the hook runs at when around a step of the phase on a
+// version
+func (h stepHook) matches(p phase, when, version string) bool {
+	if h.Phase != p || h.When != when {
+		return false
+	}
+	if len(h.Versions) == 0 {
+		return true
+	}
+	for _, v := range h.Versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// withHooks wraps the step of a phase with the hooks of the scenario that
+// match it, so that they run within the budget of the phase
+func withHooks(hop int, p phase, fn func(ctx context.Context) error) func(ctx context.Context) error {
+	if len(scenarioHooks) == 0 {
+		return fn
+	}
+	return func(ctx context.Context) error {
+		if err := runHooks(ctx, hop, p, hookPre); err != nil {
+			return err
+		}
+		if err := fn(ctx); err != nil {
+			return err
+		}
+		return runHooks(ctx, hop, p, hookPost)
+	}
+}
+
+func runHooks(ctx context.Context, hop int, p phase, when string) error {
+	version := ""
+	if hop < len(versions) {
+		version = versions[hop]
+	}
+
+	for _, h := range scenarioHooks {
+		if !h.matches(p, when, version) {
+			continue
+		}
+
+		timeout := h.Timeout
+		if timeout == 0 {
+			timeout = defaultHookTimeout
+		}
+		hookCtx, cancel := context.WithTimeout(ctx, timeout)
+		start := time.Now()
+		err := h.run(hookCtx, hookEnv(hop, version, p, when))
+		cancel()
+
+		log := hopLogger(hop).With("phase", p, "hook", h.name(), "when", when)
+		switch {
+		case err == nil:
+			log.Info("hook completed", "took", time.Since(start).Round(time.Millisecond))
+		case h.Optional:
+			log.Warn("optional hook failed", "err", err)
+		default:
+			return fmt.Errorf("%s hook %q of phase %s: %w", when, h.name(), p, err)
+		}
+	}
+	return nil
+}
+
+// hookEnv is the environment of a hook, on top of the one of the harness
+func hookEnv(hop int, version string, p phase, when string) map[string]string {
+	hosts := make([]string, nodeCount)
+	for i := range hosts {
+		hosts[i] = nodeHost(i)
+	}
+	return map[string]string{
+		"JOURNEY_RUN_ID":  runID,
+		"JOURNEY_HOP":     strconv.Itoa(hop),
+		"JOURNEY_VERSION": version,
+		"JOURNEY_PHASE":   string(p),
+		"JOURNEY_WHEN":    when,
+		"WEAVIATE_HOSTS":  strings.Join(hosts, ","),
+		"ARTIFACTS_DIR":   artifactsDir,
+	}
+}
+
+func (h stepHook) run(ctx context.Context, env map[string]string) error {
+	if h.Run != "" {
+		cmd := exec.CommandContext(ctx, "bash", "-c", h.Run)
+		cmd.Env = os.Environ()
+		for _, key := range sortedMapKeys(env) {
+			cmd.Env = append(cmd.Env, key+"="+env[key])
+		}
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		return cmd.Run()
+	}
+
+	fn, err := resolveHookFunc(h.Func)
+	if err != nil {
+		return err
+	}
+	return fn(ctx, env)
+}
+
+// resolveHookFunc returns one of hookFuncs, or loads the symbol of a Go
+// plugin for a name of the form file:Symbol
+func resolveHookFunc(name string) (hookFunc, error) {
+	file, symbol, isPlugin := strings.Cut(name, ":")
+	if !isPlugin {
+		return hookFuncs[name], nil
+	}
+
+	p, err := plugin.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	sym, err := p.Lookup(symbol)
+	if err != nil {
+		return nil, err
+	}
+	fn, ok := sym.(func(context.Context, map[string]string) error)
+	if !ok {
+		return nil, fmt.Errorf("%s is a %T, not a func(context.Context, map[string]string) error", name, sym)
+	}
+	return fn, nil
+}
+
+// dumpHook writes the response of an endpoint of every node to the
+// artifacts, named after the step
+func dumpHook(what, endpoint string) hookFunc {
+	return func(ctx context.Context, env map[string]string) error {
+		dir := path.Join(artifactsDir, "hooks")
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+
+		hop, _ := strconv.Atoi(env["JOURNEY_HOP"])
+		for i := 0; i < nodeCount; i++ {
+			body, err := getRaw(ctx, i, endpoint)
+			if err != nil {
+				return fmt.Errorf("%s of weaviate-%d: %w", what, i, err)
+			}
+			name := fmt.Sprintf("hop%02d-%s-%s-weaviate-%d-%s.json", hop, env["JOURNEY_PHASE"], env["JOURNEY_WHEN"],
+				i, what)
+			if err := os.WriteFile(path.Join(dir, name), body, 0o644); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// hookSummary lists the hooks of the scenario for the plan
+func hookSummary() []string {
+	var lines []string
+	for _, h := range scenarioHooks {
+		line := fmt.Sprintf("hook: %s every %s step: %s", h.When, h.Phase, h.name())
+		if len(h.Versions) > 0 {
+			versions := append([]string(nil), h.Versions...)
+			sort.Strings(versions)
+			line += fmt.Sprintf(" (on %s)", strings.Join(versions, ", "))
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,51 @@
+package main
+
+import "testing"
+
+func Test_stepHook_validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		hook    stepHook
+		wantErr bool
+	}{
+		{name: "shell", hook: stepHook{Phase: phaseVerify, When: hookPost, Run: "true"}},
+		{name: "builtin", hook: stepHook{Phase: phaseUpgrade, When: hookPre, Func: "dump-schema"}},
+		{name: "plugin", hook: stepHook{Phase: phaseImport, When: hookPre, Func: "./hooks.so:Check"}},
+		{name: "unknown phase", hook: stepHook{Phase: "restore", When: hookPre, Run: "true"}, wantErr: true},
+		{name: "unknown when", hook: stepHook{Phase: phaseVerify, When: "during", Run: "true"}, wantErr: true},
+		{name: "neither", hook: stepHook{Phase: phaseVerify, When: hookPre}, wantErr: true},
+		{
+			name:    "both",
+			hook:    stepHook{Phase: phaseVerify, When: hookPre, Run: "true", Func: "dump-schema"},
+			wantErr: true,
+		},
+		{name: "unknown func", hook: stepHook{Phase: phaseVerify, When: hookPre, Func: "dump-all"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.hook.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_stepHook_matches(t *testing.T) {
+	hook := stepHook{Phase: phaseUpgrade, When: hookPre, Run: "true", Versions: []string{"1.25.0"}}
+	tests := []struct {
+		phase   phase
+		when    string
+		version string
+		want    bool
+	}{
+		{phase: phaseUpgrade, when: hookPre, version: "1.25.0", want: true},
+		{phase: phaseUpgrade, when: hookPre, version: "1.24.9", want: false},
+		{phase: phaseUpgrade, when: hookPost, version: "1.25.0", want: false},
+		{phase: phaseVerify, when: hookPre, version: "1.25.0", want: false},
+	}
+	for _, tt := range tests {
+		if got := hook.matches(tt.phase, tt.when, tt.version); got != tt.want {
+			t.Errorf("matches(%s, %s, %s) = %t, want %t", tt.phase, tt.when, tt.version, got, tt.want)
+		}
+	}
+}
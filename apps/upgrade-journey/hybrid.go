@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+const (
+	hybridProbeClass = "HybridProbe"
+	hybridDims       = 8
+	hybridLimit      = 10
+)
+
+// hybridTexts are the documents of the probe class. Their words overlap,
+// so that keyword scores differ from document to document.
+var hybridTexts = []string{
+	"the quick brown fox jumps over the lazy dog",
+	"a quick brown dog outpaces a lazy fox",
+	"foxes and dogs are both quick animals",
+	"the lazy cat sleeps all day long",
+	"brown bears are not as quick as foxes",
+	"a dog and a cat share a brown blanket",
+	"quick thinking saves the day",
+	"the fox hides in a brown burrow",
+	"lazy afternoons call for a long nap",
+	"dogs chase cats and cats chase mice",
+	"the brown river flows past the lazy town",
+	"an old fox knows every trick",
+}
+
+// hybridQuery is a fixed hybrid search, its vector is derived from seed
+type hybridQuery struct {
+	text  string
+	alpha float64
+	seed  int64
+}
+
+func (q hybridQuery) name() string {
+	return fmt.Sprintf("%q alpha=%g", q.text, q.alpha)
+}
+
+var hybridQueries = []hybridQuery{
+	{text: "quick fox", alpha: 0, seed: 1},
+	{text: "quick fox", alpha: 0.25, seed: 1},
+	{text: "quick fox", alpha: 0.5, seed: 1},
+	{text: "quick fox", alpha: 0.75, seed: 1},
+	{text: "lazy brown dog", alpha: 0.5, seed: 2},
+	{text: "cat", alpha: 0.3, seed: 3},
+}
+
+// expectedFusionChanges lists the versions that changed the ranking of
+// hybrid searches on purpose. A hop to or past one of them may reorder the
+// results, any other hop must not.
+var expectedFusionChanges = map[string]string{
+	"1.24.0": "relativeScoreFusion became the default fusion",
+}
+
+// fusionChangesBetween returns the intentional changes of a hop from one
+// version to the other
+func fusionChangesBetween(from, to string) []string {
+	var reasons []string
+	for version, reason := range expectedFusionChanges {
+		if atLeast(to, version) && !atLeast(from, version) {
+			reasons = append(reasons, fmt.Sprintf("%s: %s", version, reason))
+		}
+	}
+	sort.Strings(reasons)
+	return reasons
+}
+
+func hybridVector(seed int64) []float32 {
+	rng := rand.New(rand.NewSource(seed))
+	vector := make([]float32, hybridDims)
+	for i := range vector {
+		vector[i] = rng.Float32()
+	}
+	return vector
+}
+
+// hybridHit is a result of a hybrid search
+type hybridHit struct {
+	name  string
+	score string
+}
+
+// hybridRanking returns the names of the hits in rank order. Hits with the
+// same score may come in any order, they are sorted by name.
+func hybridRanking(hits []hybridHit) []string {
+	ranked := make([]string, 0, len(hits))
+	for start := 0; start < len(hits); {
+		end := start + 1
+		for end < len(hits) && hits[end].score == hits[start].score {
+			end++
+		}
+
+		var tied []string
+		for _, hit := range hits[start:end] {
+			tied = append(tied, hit.name)
+		}
+		sort.Strings(tied)
+		ranked = append(ranked, tied...)
+		start = end
+	}
+	return ranked
+}
+
+// rankChanges describes how the ranking differs from the previous one
+func rankChanges(prev, cur []string) []string {
+	position := map[string]int{}
+	for i, name := range prev {
+		position[name] = i
+	}
+
+	var changes []string
+	seen := map[string]bool{}
+	for i, name := range cur {
+		seen[name] = true
+		was, ok := position[name]
+		switch {
+		case !ok:
+			changes = append(changes, fmt.Sprintf("%s entered at %d", name, i+1))
+		case was != i:
+			changes = append(changes, fmt.Sprintf("%s moved from %d to %d", name, was+1, i+1))
+		}
+	}
+	for _, name := range prev {
+		if !seen[name] {
+			changes = append(changes, fmt.Sprintf("%s dropped from %d", name, position[name]+1))
+		}
+	}
+	return changes
+}
+
+// hybridProbe writes the documents of the hybrid searches once, on the
+// first hop of a version that has hybrid search
+var hybridProbe probeImports
+
+// hybridVerifier runs the fixed hybrid searches and compares their ranking
+// with the previous run. Unless the hop crosses an intentional change of
+// the fusion, any difference fails the run.
+type hybridVerifier struct {
+	previous        map[string][]string
+	previousVersion string
+}
+
+func (v *hybridVerifier) name() string {
+	return "hybrid"
+}
+
+func (v *hybridVerifier) verify(ctx context.Context, posOfMaxVersion int) error {
+	version := versions[posOfMaxVersion]
+	if !supports(version, featureHybrid) {
+		return nil
+	}
+
+	rankings := map[string][]string{}
+	var report strings.Builder
+	var changed []string
+	for _, q := range hybridQueries {
+		hits, err := hybridSearch(ctx, q)
+		if err != nil {
+			return fmt.Errorf("hybrid search %s: %w", q.name(), err)
+		}
+		ranking := hybridRanking(hits)
+		rankings[q.name()] = ranking
+		fmt.Fprintf(&report, "%s: %s\n", q.name(), strings.Join(ranking, ", "))
+
+		if prev, ok := v.previous[q.name()]; ok {
+			for _, change := range rankChanges(prev, ranking) {
+				changed = append(changed, fmt.Sprintf("%s: %s", q.name(), change))
+				fmt.Fprintf(&report, "  %s\n", change)
+			}
+		}
+	}
+
+	dir := hopArtifactsDir(posOfMaxVersion)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path.Join(dir, "hybrid-ranking.txt"), []byte(report.String()), 0o644); err != nil {
+		return err
+	}
+
+	from := v.previousVersion
+	v.previous, v.previousVersion = rankings, version
+	if len(changed) == 0 {
+		return nil
+	}
+
+	if reasons := fusionChangesBetween(from, version); len(reasons) > 0 {
+		hopLogger(posOfMaxVersion).Info("hybrid ranking changed on purpose", "from", from, "to", version,
+			"reasons", reasons, "changes", changed)
+		return nil
+	}
+	return fmt.Errorf("hybrid ranking changed from %s to %s:\n%s", from, version, strings.Join(changed, "\n"))
+}
+
+// hybridNameProperty is the property that names a document of the probe.
+// Text only takes field tokenization from 1.19 on, before that the name is a
+// string, which 1.19 migrates to text with field tokenization.
+func hybridNameProperty(version string) map[string]interface{} {
+	if !supports(version, featureTokenization) {
+		return map[string]interface{}{"name": "name", "dataType": []string{"string"}, "tokenization": "field"}
+	}
+	return map[string]interface{}{"name": "name", "dataType": []string{"text"}, "tokenization": "field"}
+}
+
+func createHybridClass(ctx context.Context, version string) error {
+	if _, err := postRaw(ctx, 0, "/v1/schema", map[string]interface{}{
+		"class":      hybridProbeClass,
+		"vectorizer": "none",
+		"properties": []map[string]interface{}{
+			hybridNameProperty(version),
+			{"name": "text", "dataType": []string{"text"}},
+		},
+	}); err != nil {
+		return fmt.Errorf("create class %s: %w", hybridProbeClass, err)
+	}
+	return nil
+}
+
+func importHybridTexts(ctx context.Context) error {
+	var objects []map[string]interface{}
+	for i, text := range hybridTexts {
+		objects = append(objects, map[string]interface{}{
+			"class":      hybridProbeClass,
+			"id":         probeID(hybridProbeClass, 0, i),
+			"vector":     hybridVector(int64(100 + i)),
+			"properties": map[string]interface{}{"name": fmt.Sprintf("doc%02d", i), "text": text},
+		})
+	}
+	if _, err := postRaw(ctx, 0, "/v1/batch/objects", map[string]interface{}{"objects": objects}); err != nil {
+		return fmt.Errorf("import %s: %w", hybridProbeClass, err)
+	}
+	return nil
+}
+
+func hybridSearch(ctx context.Context, q hybridQuery) ([]hybridHit, error) {
+	vector, _ := json.Marshal(hybridVector(q.seed))
+	body, err := postRaw(ctx, 0, "/v1/graphql", map[string]interface{}{
+		"query": fmt.Sprintf(`{Get{%s(hybrid:{query:%q,alpha:%g,vector:%s},limit:%d){name _additional{score}}}}`,
+			hybridProbeClass, q.text, q.alpha, vector, hybridLimit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var res struct {
+		Data struct {
+			Get map[string][]struct {
+				Name       string `json:"name"`
+				Additional struct {
+					Score string `json:"score"`
+				} `json:"_additional"`
+			} `json:"Get"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, err
+	}
+	if len(res.Errors) > 0 {
+		return nil, fmt.Errorf("graphql: %s", res.Errors[0].Message)
+	}
+
+	var hits []hybridHit
+	for _, hit := range res.Data.Get[hybridProbeClass] {
+		hits = append(hits, hybridHit{name: hit.Name, score: hit.Additional.Score})
+	}
+	return hits, nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_hybridRanking(t *testing.T) {
+	hits := []hybridHit{
+		{name: "doc03", score: "0.9"},
+		{name: "doc07", score: "0.5"},
+		{name: "doc01", score: "0.5"},
+		{name: "doc02", score: "0.1"},
+	}
+	want := []string{"doc03", "doc01", "doc07", "doc02"}
+	if got := hybridRanking(hits); !reflect.DeepEqual(got, want) {
+		t.Errorf("hybridRanking() = %v, want %v", got, want)
+	}
+}
+
+func Test_rankChanges(t *testing.T) {
+	tests := []struct {
+		name string
+		prev []string
+		cur  []string
+		want []string
+	}{
+		{name: "same", prev: []string{"a", "b"}, cur: []string{"a", "b"}},
+		{name: "swapped", prev: []string{"a", "b"}, cur: []string{"b", "a"},
+			want: []string{"b moved from 2 to 1", "a moved from 1 to 2"}},
+		{name: "replaced", prev: []string{"a", "b"}, cur: []string{"a", "c"},
+			want: []string{"c entered at 2", "b dropped from 2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rankChanges(tt.prev, tt.cur); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rankChanges() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_fusionChangesBetween(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     int
+	}{
+		{from: "1.23.7", to: "1.24.0", want: 1},
+		{from: "1.22.0", to: "1.25.1", want: 1},
+		{from: "1.24.0", to: "1.24.5"},
+		{from: "1.21.0", to: "1.23.9"},
+	}
+	for _, tt := range tests {
+		if got := fusionChangesBetween(tt.from, tt.to); len(got) != tt.want {
+			t.Errorf("fusionChangesBetween(%s, %s) = %v, want %d changes", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func Test_hybridNameProperty(t *testing.T) {
+	tests := []struct {
+		version  string
+		dataType string
+	}{
+		{version: "1.17.0", dataType: "string"},
+		{version: "1.18.3", dataType: "string"},
+		{version: "1.19.0", dataType: "text"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			prop := hybridNameProperty(tt.version)
+			if got := prop["dataType"].([]string)[0]; got != tt.dataType {
+				t.Errorf("wanted %s, got %s", tt.dataType, got)
+			}
+			if got := prop["tokenization"]; got != "field" {
+				t.Errorf("wanted field tokenization, got %v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/client"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/testcontainers/testcontainers-go"
+	wcluster "github.com/weaviate/weaviate-chaos-engineering/apps/upgrade-journey/pkg/cluster"
+)
+
+// imageConfig decides which image is started for a version. The defaults
+// resolve to the official release images. Registry credentials are picked up
+// from the Docker config, so a private registry only requires a prior
+// "docker login".
+type imageConfig struct {
+	// repo is the image repository without a tag, e.g.
+	// "semitechnologies/weaviate" or "registry.example.com/weaviate"
+	repo string
+
+	// tagTemplate builds the tag from a version, "{version}" is replaced with
+	// the version and "{arch}" with the architecture of the platform, e.g.
+	// "{version}-{arch}"
+	tagTemplate string
+
+	// platform is the platform the nodes run on, e.g. "linux/arm64". It
+	// defaults to the platform of the Docker host, see resolvePlatform.
+	platform string
+
+	// finalImage, if set, is the complete image reference used for the last
+	// hop instead of the templated one. It can point to an unreleased build,
+	// such as a PR image or one that only exists locally.
+	finalImage string
+
+	// finalVersion is the version label of the last hop
+	finalVersion string
+}
+
+var images = imageConfig{
+	repo:        "semitechnologies/weaviate",
+	tagTemplate: "{version}",
+}
+
+func (ic imageConfig) image(version string) string {
+	if ic.finalImage != "" && version == ic.finalVersion {
+		return ic.finalImage
+	}
+
+	tag := strings.ReplaceAll(ic.tagTemplate, "{version}", version)
+	tag = strings.ReplaceAll(tag, "{arch}", ic.arch())
+	return fmt.Sprintf("%s:%s", ic.repo, tag)
+}
+
+// arch is the architecture part of the platform, e.g. "arm64"
+func (ic imageConfig) arch() string {
+	_, arch, _ := strings.Cut(ic.platform, "/")
+	return arch
+}
+
+// normalizeArch maps the architecture names of the kernel, as reported by
+// the Docker daemon, to the names used in image manifests
+func normalizeArch(arch string) string {
+	switch arch {
+	case "x86_64", "x86-64":
+		return "amd64"
+	case "aarch64", "arm64/v8":
+		return "arm64"
+	default:
+		return arch
+	}
+}
+
+// resolvePlatform defaults the platform to the one of the Docker host, so
+// that an Apple Silicon laptop or an ARM runner pulls native images
+func (ic *imageConfig) resolvePlatform(ctx context.Context) error {
+	if ic.platform != "" {
+		if _, arch, ok := strings.Cut(ic.platform, "/"); !ok || arch == "" {
+			return fmt.Errorf("platform %q: must be os/arch, e.g. linux/arm64", ic.platform)
+		}
+		return nil
+	}
+
+	return wcluster.WithDockerClient(func(cli *client.Client) error {
+		info, err := cli.Info(ctx)
+		if err != nil {
+			return err
+		}
+		ic.platform = fmt.Sprintf("%s/%s", info.OSType, normalizeArch(info.Architecture))
+		return nil
+	})
+}
+
+// platformsInclude is true if an image is available for the architecture.
+// An empty list comes from an image without a manifest list, which can't
+// be told apart from a native one.
+func platformsInclude(platforms []v1.Platform, goos, arch string) bool {
+	if len(platforms) == 0 {
+		return true
+	}
+	for _, p := range platforms {
+		if p.OS == goos && normalizeArch(p.Architecture) == arch {
+			return true
+		}
+	}
+	return false
+}
+
+// checkImages verifies that the image of every version exists for the
+// platform before the first node starts, instead of failing hours into a
+// journey on a missing or emulated image. Images that exist locally are
+// checked without asking the registry, once it is blocked they must exist.
+func (ic imageConfig) checkImages(ctx context.Context, versions []string) error {
+	goos, arch, _ := strings.Cut(ic.platform, "/")
+	return wcluster.WithDockerClient(func(cli *client.Client) error {
+		checked := map[string]bool{}
+		for _, version := range versions {
+			image := ic.image(version)
+			if checked[image] {
+				continue
+			}
+			checked[image] = true
+
+			if local, _, err := cli.ImageInspectWithRaw(ctx, image); err == nil &&
+				local.Os == goos && normalizeArch(local.Architecture) == arch {
+				continue
+			}
+
+			if registryBlocked {
+				return fmt.Errorf("image %s of version %s is not preloaded for %s and the registry is blocked",
+					image, version, ic.platform)
+			}
+
+			dist, err := cli.DistributionInspect(ctx, image, registryAuth(ctx, image))
+			if err != nil {
+				return fmt.Errorf("image %s of version %s is not available: %w", image, version, err)
+			}
+
+			if !platformsInclude(dist.Platforms, goos, arch) {
+				var available []string
+				for _, p := range dist.Platforms {
+					available = append(available, p.OS+"/"+p.Architecture)
+				}
+				return fmt.Errorf("image %s of version %s is not available for %s, only for %s; "+
+					"pick another tag with --image-tag-template or run emulated with --image-platform",
+					image, version, ic.platform, strings.Join(available, ", "))
+			}
+		}
+		return nil
+	})
+}
+
+// registryAuth returns the encoded credentials for the registry of an
+// image from the Docker config, or nothing for anonymous access
+func registryAuth(ctx context.Context, image string) string {
+	_, auth, err := testcontainers.DockerImageAuth(ctx, image)
+	if err != nil {
+		return ""
+	}
+
+	raw, err := json.Marshal(auth)
+	if err != nil {
+		return ""
+	}
+	return base64.URLEncoding.EncodeToString(raw)
+}
+
+// tagOf returns the tag of an image reference, or "latest" if there is none
+func tagOf(image string) string {
+	// the last colon separates the tag, unless it is part of a registry host
+	// with a port, in which case a slash follows it
+	pos := strings.LastIndex(image, ":")
+	if pos == -1 || strings.Contains(image[pos:], "/") {
+		return "latest"
+	}
+
+	return image[pos+1:]
+}
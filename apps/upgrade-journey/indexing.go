@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// With async indexing, an import returns before its vectors are in the
+// index, so searches right after it miss objects. Instead of sleeping for
+// a while, checks wait until the nodes report that nothing is left to
+// index.
+const (
+	indexingPollInterval = 2 * time.Second
+
+	// the backlog must be empty this many times in a row, a queue is
+	// briefly empty between two batches that are still being pushed
+	indexingStableReadings = 3
+
+	// the backlog must shrink at least once in this time
+	indexingStallTimeout = 2 * time.Minute
+
+	// shards that are still being loaded, their queues did not resume yet
+	shardsLoadingMetric = "shards_loading"
+)
+
+// indexQueueMetrics are the gauges of the vector indexing queue, the name
+// changed when the queue was rewritten
+var indexQueueMetrics = []string{"index_queue_size", "queue_size"}
+
+// metricsEnabled is true if the nodes expose their metrics to this process
+func metricsEnabled() bool {
+	return withMetrics || len(scenarioAlerts.rules.metricRules()) > 0
+}
+
+// awaitIndexing is true if imports of the version are indexed
+// asynchronously
+func awaitIndexing(version string) bool {
+	return withAsyncIndexingChaos && supports(version, featureAsyncIndexing)
+}
+
+// metricsBacklog counts the queued vectors of a class, of every class if
+// className is empty, and the shards that are still loading
+func metricsBacklog(samples metricSamples, className string) int {
+	var labels map[string]string
+	if className != "" {
+		labels = map[string]string{"class_name": className}
+	}
+
+	backlog := 0.0
+	for _, name := range indexQueueMetrics {
+		backlog += samples.sum(name, labels)
+	}
+	backlog += samples.sum(shardsLoadingMetric, nil)
+	return int(backlog)
+}
+
+// queuesBacklog is metricsBacklog for the indexing status of the nodes API,
+// every node that is not ready counts as one
+func queuesBacklog(queues map[int]asyncQueue) int {
+	backlog := 0
+	for _, queue := range queues {
+		backlog += queue.length
+		if !queue.ready {
+			backlog++
+		}
+	}
+	return backlog
+}
+
+// indexingBacklog sums the backlog of all nodes, from their metrics if they
+// expose them and from the nodes API otherwise
+func (c *cluster) indexingBacklog(ctx context.Context, className string) (int, error) {
+	if !metricsEnabled() {
+		body, err := getRaw(ctx, 0, "/v1/nodes?output=verbose")
+		if err != nil {
+			return 0, fmt.Errorf("indexing queues: %w", err)
+		}
+		queues, err := parseVectorQueues(body, className)
+		if err != nil {
+			return 0, fmt.Errorf("indexing queues: %w", err)
+		}
+		return queuesBacklog(queues), nil
+	}
+
+	backlog := 0
+	for i := 0; i < c.NodeCount; i++ {
+		_, samples, err := metrics.scrape(ctx, c, i)
+		if err != nil {
+			return 0, fmt.Errorf("metrics of %s: %w", c.Hostname(i), err)
+		}
+		backlog += metricsBacklog(samples, className)
+	}
+	return backlog, nil
+}
+
+// awaitIndexed waits until nothing of a class is left to index, of every
+// class if className is empty. It fails if the backlog stops shrinking or
+// is not gone within the timeout.
+func (c *cluster) awaitIndexed(ctx context.Context, posOfVersion int, className string,
+	timeout time.Duration,
+) (time.Duration, error) {
+	what := "all classes"
+	if className != "" {
+		what = className
+	}
+
+	start := time.Now()
+	smallest, shrunk, empty := -1, time.Now(), 0
+	for {
+		backlog, err := c.indexingBacklog(ctx, className)
+		if err != nil {
+			return 0, err
+		}
+
+		if backlog == 0 {
+			empty++
+		} else {
+			empty = 0
+		}
+		if empty >= indexingStableReadings {
+			took := time.Since(start)
+			hopLogger(posOfVersion).Info("indexing caught up", "class", what, "took", took.Round(time.Second))
+			return took, nil
+		}
+
+		if smallest < 0 || backlog < smallest {
+			smallest, shrunk = backlog, time.Now()
+		}
+		switch {
+		case time.Since(shrunk) > indexingStallTimeout:
+			return 0, fmt.Errorf("indexing of %s stalled with a backlog of %d", what, backlog)
+		case time.Since(start) > timeout:
+			return 0, fmt.Errorf("indexing of %s still has a backlog of %d after %s", what, backlog, timeout)
+		}
+		hopLogger(posOfVersion).Debug("waiting for indexing", "class", what, "backlog", backlog)
+
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(indexingPollInterval):
+		}
+	}
+}
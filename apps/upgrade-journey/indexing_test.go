@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_metricsBacklog(t *testing.T) {
+	samples, err := parseMetrics(strings.NewReader(`
+index_queue_size{class_name="AsyncIndexed",shard_name="a"} 120
+index_queue_size{class_name="Collection",shard_name="b"} 30
+queue_size{class_name="AsyncIndexed",shard_name="c"} 5
+shards_loading 2
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		className string
+		want      int
+	}{
+		{className: "AsyncIndexed", want: 127},
+		{className: "Collection", want: 32},
+		{className: "", want: 157},
+		{className: "Other", want: 2},
+	}
+	for _, tt := range tests {
+		if got := metricsBacklog(samples, tt.className); got != tt.want {
+			t.Errorf("metricsBacklog(%q) = %d, want %d", tt.className, got, tt.want)
+		}
+	}
+}
+
+func Test_queuesBacklog(t *testing.T) {
+	tests := []struct {
+		name   string
+		queues map[int]asyncQueue
+		want   int
+	}{
+		{name: "empty and ready", queues: map[int]asyncQueue{0: {ready: true}, 1: {ready: true}}},
+		{name: "queue left", queues: map[int]asyncQueue{0: {ready: true}, 1: {length: 40, ready: true}}, want: 40},
+		{name: "still indexing", queues: map[int]asyncQueue{0: {ready: true}, 1: {}}, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := queuesBacklog(tt.queues); got != tt.want {
+				t.Errorf("queuesBacklog() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+)
+
+// deprecatedDataTypes maps data types that a server replaces with their
+// successor, which is not a rewrite of what the user intended
+var deprecatedDataTypes = map[string]string{
+	"string": "text",
+}
+
+// checkIntendedSchema fetches every class of the journey back and compares
+// it against the class the client created. The server may add defaults, but
+// every field the client set must still have the value it was set to.
+func checkIntendedSchema(ctx context.Context, posOfVersion int) error {
+	var report strings.Builder
+	var rewritten []string
+
+	for _, class := range journeyClasses() {
+		intended, err := json.Marshal(class)
+		if err != nil {
+			return err
+		}
+
+		actual, err := getRaw(ctx, 0, fmt.Sprintf("/v1/schema/%s", class.Class))
+		if err != nil {
+			return fmt.Errorf("fetch class %s: %w", class.Class, err)
+		}
+
+		diff, err := diffIntended(intended, actual)
+		if err != nil {
+			return fmt.Errorf("diff class %s: %w", class.Class, err)
+		}
+
+		fmt.Fprintf(&report, "class %s:\n", class.Class)
+		for _, line := range diff {
+			fmt.Fprintf(&report, "%s\n", line)
+			rewritten = append(rewritten, fmt.Sprintf("%s: %s", class.Class, line))
+		}
+	}
+
+	dir := hopArtifactsDir(posOfVersion)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path.Join(dir, "intended-schema.txt"), []byte(report.String()), 0o644); err != nil {
+		return err
+	}
+
+	if len(rewritten) > 0 {
+		return fmt.Errorf("server rewrote the intended schema on %s:\n%s",
+			versions[posOfVersion], strings.Join(rewritten, "\n"))
+	}
+
+	return nil
+}
+
+// diffIntended returns every field of the intended class that is missing or
+// has a different value in the class the server returned. Fields that only
+// the server sets are ignored.
+func diffIntended(intended, actual []byte) ([]string, error) {
+	var intendedParsed, actualParsed interface{}
+	if err := json.Unmarshal(intended, &intendedParsed); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(actual, &actualParsed); err != nil {
+		return nil, err
+	}
+
+	intendedFlat, actualFlat := map[string]interface{}{}, map[string]interface{}{}
+	flatten("", intendedParsed, intendedFlat)
+	flatten("", actualParsed, actualFlat)
+
+	var diff []string
+	for _, p := range sortedKeys(intendedFlat) {
+		want := intendedFlat[p]
+		got, ok := actualFlat[p]
+		if !ok {
+			diff = append(diff, fmt.Sprintf("- %s %v", p, want))
+			continue
+		}
+		if !reflect.DeepEqual(want, got) && !replacedDataType(p, want, got) {
+			diff = append(diff, fmt.Sprintf("~ %s %v -> %v", p, want, got))
+		}
+	}
+
+	return diff, nil
+}
+
+func replacedDataType(p string, want, got interface{}) bool {
+	if !strings.Contains(p, ".dataType.") {
+		return false
+	}
+
+	wantType, ok := want.(string)
+	if !ok {
+		return false
+	}
+	successor, ok := deprecatedDataTypes[wantType]
+	return ok && got == successor
+}
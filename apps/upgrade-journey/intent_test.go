@@ -0,0 +1,45 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_diffIntended(t *testing.T) {
+	intended := []byte(`{"class":"A","properties":[{"name":"p1","dataType":["string"]},{"name":"p2","dataType":["int"]}]}`)
+
+	tests := []struct {
+		name   string
+		actual []byte
+		want   []string
+	}{
+		{
+			name:   "server defaults and reordered properties are fine",
+			actual: []byte(`{"class":"A","vectorIndexConfig":{"ef":-1},"properties":[{"name":"p2","dataType":["int"]},{"name":"p1","dataType":["string"],"tokenization":"word"}]}`),
+		},
+		{
+			name:   "deprecated data types may be replaced",
+			actual: []byte(`{"class":"A","properties":[{"name":"p1","dataType":["text"]},{"name":"p2","dataType":["int"]}]}`),
+		},
+		{
+			name:   "rewritten and dropped fields are reported",
+			actual: []byte(`{"class":"A","properties":[{"name":"p2","dataType":["number"]}]}`),
+			want: []string{
+				"- properties.[p1].dataType.[0] string",
+				"- properties.[p1].name p1",
+				"~ properties.[p2].dataType.[0] int -> number",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := diffIntended(intended, tt.actual)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffIntended() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path"
+	"syscall"
+	"time"
+)
+
+const tearDownTimeout = 2 * time.Minute
+
+// interruptible cancels the context on Ctrl-C or when CI cancels the job.
+// The run then stops wherever it is, the load generators with it, and
+// still writes its report and tears down the cluster. A second interrupt
+// exits right away.
+func interruptible(parent context.Context) context.Context {
+	ctx, stop := signal.NotifyContext(parent, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+		if parent.Err() == nil {
+			logger.Warn("interrupted, cleaning up; interrupt again to exit right away")
+		}
+	}()
+	return ctx
+}
+
+// tearDown saves the logs of every node into the artifacts and removes all
+// containers and the network of the run. It has a context of its own, as
+// the one of the run may have been cancelled by an interrupt.
+func (c *cluster) tearDown() {
+	ctx, cancel := context.WithTimeout(context.Background(), tearDownTimeout)
+	defer cancel()
+
+	if err := c.SaveLogs(ctx, path.Join(artifactsDir, "logs")); err != nil {
+		logger.Warn("cannot save node logs", "err", err)
+	}
+
+	if err := c.Teardown(ctx); err != nil {
+		logger.Warn("cannot tear down cluster", "err", err)
+		return
+	}
+	logger.Info("tore down cluster", "network", c.NetworkName)
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/docker/docker/client"
+	wcluster "github.com/weaviate/weaviate-chaos-engineering/apps/upgrade-journey/pkg/cluster"
+	"github.com/weaviate/weaviate-chaos-engineering/apps/upgrade-journey/pkg/faults"
+)
+
+const (
+	// largeClusterNodes is the size from which a cluster is limited in its
+	// resources and the journey checks raft quorum and update ordering
+	largeClusterNodes = 5
+
+	minNodeCPUs        = 0.5
+	quorumProbeTimeout = 10 * time.Second
+)
+
+var (
+	// nodeCPUs and nodeMemoryMB are set by --node-cpus and
+	// --node-memory-mb, 0 shares the machine evenly on large clusters
+	nodeCPUs     float64
+	nodeMemoryMB int
+)
+
+// nodeLimits returns the limits of every node. Small clusters run
+// unlimited unless a limit is set explicitly. A large cluster shares the
+// machine evenly, so that one busy node can't starve its peers on a CI
+// runner.
+func nodeLimits(ctx context.Context, nodeCount int) (wcluster.Resources, error) {
+	res := wcluster.Resources{CPUs: nodeCPUs, MemoryBytes: int64(nodeMemoryMB) << 20}
+	if nodeCount < largeClusterNodes || (res.CPUs > 0 && res.MemoryBytes > 0) {
+		return res, nil
+	}
+
+	err := wcluster.WithDockerClient(func(cli *client.Client) error {
+		info, err := cli.Info(ctx)
+		if err != nil {
+			return err
+		}
+
+		shared := shareResources(nodeCount, info.NCPU, info.MemTotal)
+		if res.CPUs == 0 {
+			res.CPUs = shared.CPUs
+		}
+		if res.MemoryBytes == 0 {
+			res.MemoryBytes = shared.MemoryBytes
+		}
+		return nil
+	})
+	return res, err
+}
+
+// shareResources splits the CPUs and the memory of the machine between the
+// nodes. One share of the memory is kept for the journey itself and the
+// helper containers.
+func shareResources(nodeCount, cpus int, memory int64) wcluster.Resources {
+	res := wcluster.Resources{
+		CPUs:        float64(cpus) / float64(nodeCount),
+		MemoryBytes: memory / int64(nodeCount+1),
+	}
+	if res.CPUs < minNodeCPUs {
+		res.CPUs = minNodeCPUs
+	}
+	return res
+}
+
+// raftLeader returns the node that node 0 follows as the raft leader
+func (c *cluster) raftLeader(ctx context.Context) (int, error) {
+	stats, err := getClusterStatistics(ctx, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, stat := range stats.Statistics {
+		if stat.LeaderID == "" {
+			continue
+		}
+		for i := 0; i < c.NodeCount; i++ {
+			if c.Hostname(i) == stat.LeaderID {
+				return i, nil
+			}
+		}
+		return 0, fmt.Errorf("unknown raft leader %q", stat.LeaderID)
+	}
+	return 0, fmt.Errorf("no raft leader")
+}
+
+// leaderLast orders the nodes of a rolling update so that the leader is
+// restarted last, leadership then changes exactly once per update
+func leaderLast(nodeCount, leader int) []int {
+	order := make([]int, 0, nodeCount)
+	for i := 0; i < nodeCount; i++ {
+		if i != leader {
+			order = append(order, i)
+		}
+	}
+	return append(order, leader)
+}
+
+// updateOrder restarts the raft leader last on a large cluster. The leader
+// is remembered, so that afterRestart can check that restarting a follower
+// does not move the leadership.
+func (c *cluster) updateOrder(ctx context.Context, version string) ([]int, error) {
+	c.updateLeader = -1
+	if c.NodeCount < largeClusterNodes || !isRaftVersion(c.NodeVersions[0]) {
+		// the last node as the leader keeps the ascending order
+		return leaderLast(c.NodeCount, c.NodeCount-1), nil
+	}
+
+	leader, err := c.raftLeader(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.updateLeader = leader
+	nodeLogger(c, leader).Info("restarting raft leader last", "version", version)
+	return leaderLast(c.NodeCount, leader), nil
+}
+
+// afterRestart waits until a restarted node follows a leader. During an
+// ordered update the leader must stay the same until it is restarted itself.
+func (c *cluster) afterRestart(ctx context.Context, nodeId int, version string) error {
+	if err := c.waitForRaftLeader(ctx, nodeId, version); err != nil {
+		return err
+	}
+	if c.updateLeader < 0 {
+		return nil
+	}
+	if nodeId == c.updateLeader {
+		c.updateLeader = -1
+		return nil
+	}
+
+	leader, err := c.raftLeader(ctx)
+	if err != nil {
+		return fmt.Errorf("after restart of %s: %w", c.Hostname(nodeId), err)
+	}
+	if leader != c.updateLeader {
+		return fmt.Errorf("raft leadership moved from %s to %s when follower %s restarted",
+			c.Hostname(c.updateLeader), c.Hostname(leader), c.Hostname(nodeId))
+	}
+	return nil
+}
+
+// quorumSplit returns the voters that can go down with the schema still
+// writable, and the one that takes the quorum with it. Node 0 serves the
+// probes and is never picked.
+func quorumSplit(voters []int, quorum int) (minority []int, last int) {
+	down := len(voters) - quorum
+	candidates := []int{}
+	for i := len(voters) - 1; i >= 0 && len(candidates) < down+1; i-- {
+		if voters[i] != 0 {
+			candidates = append(candidates, voters[i])
+		}
+	}
+	return candidates[1:], candidates[0]
+}
+
+// raftQuorumCheck takes down as many voters as the quorum allows and
+// checks that the schema still accepts writes. Taking down one more voter
+// must block schema writes, until the voters are back and raft is
+// synchronized again.
+func raftQuorumCheck(ctx context.Context, c *cluster, hop int) error {
+	voters := c.Voters()
+	if len(voters) < largeClusterNodes {
+		return nil
+	}
+
+	minority, last := quorumSplit(voters, c.Quorum())
+	hopLogger(hop).Info("checking raft quorum", "voters", len(voters), "quorum", c.Quorum(),
+		"minority", minority, "last", last)
+
+	probe := fmt.Sprintf("QuorumProbe%d", hop)
+	blocked := fmt.Sprintf("QuorumProbeBlocked%d", hop)
+	err := applyFault(ctx, c, hop, &faults.Down[*cluster]{Nodes: wcluster.SelectNodes(minority...)}, 0,
+		func(ctx context.Context) error {
+			if err := createProbeClass(ctx, probe); err != nil {
+				return fmt.Errorf("schema write with %d of %d voters down: %w",
+					len(minority), len(voters), err)
+			}
+
+			return applyFault(ctx, c, hop, &faults.Down[*cluster]{Nodes: wcluster.SelectNodes(last)}, 0,
+				func(ctx context.Context) error {
+					if err := createProbeClass(ctx, blocked); err == nil {
+						return fmt.Errorf("schema write succeeded with %d of %d voters down, "+
+							"which is below the quorum of %d", len(minority)+1, len(voters), c.Quorum())
+					}
+					return nil
+				})
+		})
+	if err != nil {
+		return err
+	}
+
+	if err := waitForRaftSynchronized(ctx, c, time.Minute); err != nil {
+		return fmt.Errorf("after quorum check: %w", err)
+	}
+
+	// a blocked write may still be committed once the quorum is back, a
+	// class that does not exist can't be deleted and is ignored
+	for _, class := range []string{probe, blocked} {
+		sendRaw(ctx, http.MethodDelete, 0, "/v1/schema/"+class, nil)
+	}
+	return nil
+}
+
+func createProbeClass(ctx context.Context, class string) error {
+	ctx, cancel := context.WithTimeout(ctx, quorumProbeTimeout)
+	defer cancel()
+
+	_, err := postRaw(ctx, 0, "/v1/schema", map[string]interface{}{
+		"class":      class,
+		"vectorizer": "none",
+	})
+	return err
+}
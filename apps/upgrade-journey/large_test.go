@@ -0,0 +1,49 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestShareResources(t *testing.T) {
+	res := shareResources(5, 4, 12<<30)
+	if res.CPUs != 0.8 {
+		t.Errorf("wanted 0.8 CPUs per node, got %g", res.CPUs)
+	}
+	if res.MemoryBytes != 2<<30 {
+		t.Errorf("wanted 2GiB per node, got %d", res.MemoryBytes)
+	}
+
+	if res := shareResources(7, 2, 8<<30); res.CPUs != minNodeCPUs {
+		t.Errorf("wanted at least %g CPUs per node, got %g", minNodeCPUs, res.CPUs)
+	}
+}
+
+func TestLeaderLast(t *testing.T) {
+	if got := leaderLast(5, 2); !reflect.DeepEqual(got, []int{0, 1, 3, 4, 2}) {
+		t.Errorf("wanted leader 2 last, got %v", got)
+	}
+	if got := leaderLast(3, 2); !reflect.DeepEqual(got, []int{0, 1, 2}) {
+		t.Errorf("wanted ascending order, got %v", got)
+	}
+}
+
+func TestQuorumSplit(t *testing.T) {
+	tests := []struct {
+		voters   []int
+		minority []int
+		last     int
+	}{
+		{voters: []int{0, 1, 2, 3, 4}, minority: []int{3, 2}, last: 4},
+		{voters: []int{0, 1, 2, 3, 4, 5, 6}, minority: []int{5, 4, 3}, last: 6},
+		{voters: []int{0, 1, 2}, minority: []int{1}, last: 2},
+	}
+
+	for _, tt := range tests {
+		minority, last := quorumSplit(tt.voters, len(tt.voters)/2+1)
+		if !reflect.DeepEqual(minority, tt.minority) || last != tt.last {
+			t.Errorf("voters %v: wanted %v and %d, got %v and %d",
+				tt.voters, tt.minority, tt.last, minority, last)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+
+	"github.com/weaviate/weaviate-chaos-engineering/apps/upgrade-journey/pkg/workloads"
+)
+
+// writeLedger is created in main once the logger is set up
+var writeLedger *workloads.Ledger
+
+// ledgerVerifier checks the stored ledger objects against the ledger. It
+// talks to the node directly, never through the fault proxy.
+type ledgerVerifier struct{}
+
+func (v *ledgerVerifier) name() string {
+	return "ledger"
+}
+
+func (v *ledgerVerifier) verify(ctx context.Context, posOfMaxVersion int) error {
+	stats, err := writeLedger.Verify(ctx, nodeHost(0))
+	if err != nil {
+		return err
+	}
+
+	hopLogger(posOfMaxVersion).Info("ledger verified", "writes", stats.Writes,
+		"stored", stats.Stored, "unacknowledged_but_stored", stats.UnacknowledgedButStored,
+		"updates", stats.Updates, "deletes", stats.Deletes, "filtered_recall", stats.FilteredRecall)
+	runReport.recordRecall(posOfMaxVersion, recallFilteredSearch, stats.FilteredRecall)
+	return nil
+}
+
+// ledgerCursorWrites is the number of ledger writes that race every cursor
+// pass
+const ledgerCursorWrites = 20
+
+// cursorVerifier pages through the ledger with the cursor API while new
+// entries are written through the fault proxy
+type cursorVerifier struct{}
+
+func (v *cursorVerifier) name() string {
+	return "cursor"
+}
+
+func (v *cursorVerifier) verify(ctx context.Context, posOfMaxVersion int) error {
+	if !supports(versions[posOfMaxVersion], featureCursor) {
+		return nil
+	}
+
+	stats, err := writeLedger.VerifyCursor(ctx, nodeHost(0), clientFaultProxy.host(), posOfMaxVersion,
+		ledgerCursorWrites)
+	if err != nil {
+		return err
+	}
+
+	hopLogger(posOfMaxVersion).Info("cursor verified", "pages", stats.Pages, "seen", stats.Seen,
+		"settled", stats.Settled)
+	return nil
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"context"
+
+	"github.com/weaviate/weaviate-chaos-engineering/apps/upgrade-journey/pkg/workloads"
+)
+
+// bulkLoad is nil unless --load-objects is set
+var bulkLoad *workloads.Load
+
+// loadVerifier checks that every object of the load workload is still
+// there
+type loadVerifier struct{}
+
+func (v *loadVerifier) name() string {
+	return "load"
+}
+
+func (v *loadVerifier) verify(ctx context.Context, posOfMaxVersion int) error {
+	return bulkLoad.Verify(ctx, nodeHost(0))
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/slog"
+)
+
+// logger is replaced in main once the flags are parsed
+var logger = slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+func newLogger(format string, w io.Writer) (*slog.Logger, error) {
+	switch format {
+	case "text":
+		return slog.New(slog.NewTextHandler(w, nil)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, nil)), nil
+	default:
+		return nil, fmt.Errorf("invalid --log-format %q", format)
+	}
+}
+
+// fatal logs at error level and exits, replacing log.Fatal
+func fatal(msg string, args ...interface{}) {
+	logger.Error(msg, args...)
+	os.Exit(1)
+}
+
+func hopLogger(posOfVersion int) *slog.Logger {
+	return logger.With("hop", posOfVersion, "version", versions[posOfVersion])
+}
+
+func nodeLogger(c *cluster, nodeId int) *slog.Logger {
+	return logger.With("node", c.Hostname(nodeId))
+}
+
+// testcontainersLogger routes the testcontainers output through the
+// structured logger
+type testcontainersLogger struct{}
+
+func (testcontainersLogger) Printf(format string, v ...interface{}) {
+	logger.Debug(fmt.Sprintf(format, v...), "component", "testcontainers")
+}
+
+// progress tracks the state of the run for the optional terminal display
+type progress struct {
+	sync.Mutex
+	started time.Time
+	hop     int
+	phase   phase
+	objects int
+}
+
+var runProgress = &progress{started: time.Now()}
+
+func (p *progress) set(hop int, ph phase) {
+	p.Lock()
+	defer p.Unlock()
+	p.hop = hop
+	p.phase = ph
+}
+
+func (p *progress) currentHop() int {
+	p.Lock()
+	defer p.Unlock()
+	return p.hop
+}
+
+func (p *progress) setObjects(objects int) {
+	p.Lock()
+	defer p.Unlock()
+	p.objects = objects
+}
+
+func (p *progress) String() string {
+	p.Lock()
+	defer p.Unlock()
+
+	version := ""
+	if p.hop < len(versions) {
+		version = versions[p.hop]
+	}
+
+	return fmt.Sprintf("hop %d/%d (%s) | phase %s | objects imported %d | elapsed %s",
+		p.hop+1, len(versions), version, p.phase, p.objects,
+		time.Since(p.started).Round(time.Second))
+}
+
+// displayProgress redraws a single status line on w every second until stop
+// is closed. It is meant for humans running the journey locally, the logs
+// should be written to a different stream.
+func displayProgress(w io.Writer, stop <-chan struct{}) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			fmt.Fprintln(w)
+			return
+		case <-ticker.C:
+			fmt.Fprintf(w, "\r\033[K%s", runProgress)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+const logPollInterval = 250 * time.Millisecond
+
+// logSignal is a line a node logs once it reached a certain point of its
+// startup. Waiting for it replaces guessing how long the startup takes: the
+// wait ends as soon as the line shows up and fails with the name of the
+// missing signal once its timeout is hit.
+type logSignal struct {
+	name    string
+	pattern *regexp.Regexp
+
+	// since is the first version that logs the line, empty means all
+	since   string
+	timeout time.Duration
+}
+
+func (s logSignal) appliesTo(version string) bool {
+	if s.since == "" {
+		return true
+	}
+
+	return atLeast(version, s.since)
+}
+
+// startupSignals are logged by a node on its own, independent of its peers.
+// They only apply to a node that has shards on disk, a fresh node has
+// nothing to load.
+var startupSignals = []logSignal{
+	{
+		name:    "shards loaded",
+		pattern: regexp.MustCompile(`(?i)completed loading shard`),
+		since:   "1.22.0",
+		timeout: 2 * time.Minute,
+	},
+}
+
+// raftLeaderSignals require a quorum of peers, so they are only waited for
+// once the rest of the cluster is up
+var raftLeaderSignals = []logSignal{
+	{
+		name:    "raft leader elected",
+		pattern: regexp.MustCompile(`(?i)(entering leader state|leader.*(elected|changed|found))`),
+		since:   firstVersions[featureRaft],
+		timeout: time.Minute,
+	},
+}
+
+// logSignalWait waits until every signal that applies to the version was
+// logged. It implements wait.Strategy, so it can be combined with the
+// readiness check of a container.
+type logSignalWait struct {
+	signals []logSignal
+}
+
+var _ wait.Strategy = &logSignalWait{}
+
+func waitForLogSignals(version string, signals []logSignal) *logSignalWait {
+	var applicable []logSignal
+	for _, signal := range signals {
+		if signal.appliesTo(version) {
+			applicable = append(applicable, signal)
+		}
+	}
+	return &logSignalWait{signals: applicable}
+}
+
+func (w *logSignalWait) WaitUntilReady(ctx context.Context, target wait.StrategyTarget) error {
+	start := time.Now()
+	for {
+		pending, err := w.pending(ctx, target)
+		if err == nil && len(pending) == 0 {
+			return nil
+		}
+
+		for _, signal := range pending {
+			if time.Since(start) > signal.timeout {
+				return fmt.Errorf("%q (%s) not logged within %s", signal.name, signal.pattern, signal.timeout)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(logPollInterval):
+		}
+	}
+}
+
+func (w *logSignalWait) pending(ctx context.Context, target wait.StrategyTarget) ([]logSignal, error) {
+	if len(w.signals) == 0 {
+		return nil, nil
+	}
+
+	reader, err := target.Logs(ctx)
+	if err != nil {
+		return w.signals, err
+	}
+	defer reader.Close()
+
+	return pendingSignals(reader, w.signals)
+}
+
+// pendingSignals returns the signals that do not show up in the log
+func pendingSignals(log io.Reader, signals []logSignal) ([]logSignal, error) {
+	seen := make([]bool, len(signals))
+	scanner := bufio.NewScanner(log)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		for i, signal := range signals {
+			if !seen[i] && signal.pattern.MatchString(line) {
+				seen[i] = true
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return signals, err
+	}
+
+	var pending []logSignal
+	for i, signal := range signals {
+		if !seen[i] {
+			pending = append(pending, signal)
+		}
+	}
+	return pending, nil
+}
+
+// hasShards tells whether the data directory of a node holds any shard, in
+// the flat or the hierarchical layout
+func (c *cluster) hasShards(nodeId int) bool {
+	for _, pattern := range []string{"*_lsm", "*/*/lsm"} {
+		if matches, _ := filepath.Glob(filepath.Join(c.VolumePath(nodeId), pattern)); len(matches) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// startupWait is the log part of the wait for a starting node
+func (c *cluster) startupWait(nodeId int, version string) *logSignalWait {
+	if !c.hasShards(nodeId) {
+		return waitForLogSignals(version, nil)
+	}
+	return waitForLogSignals(version, startupSignals)
+}
+
+// waitForRaftLeader waits until a restarted node logged that it follows a
+// leader. It is called once the rest of the cluster is up.
+func (c *cluster) waitForRaftLeader(ctx context.Context, nodeId int, version string) error {
+	if err := waitForLogSignals(version, raftLeaderSignals).
+		WaitUntilReady(ctx, c.Containers[nodeId]); err != nil {
+		return fmt.Errorf("%s: %w", c.Hostname(nodeId), err)
+	}
+
+	return nil
+}
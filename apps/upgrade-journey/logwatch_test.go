@@ -0,0 +1,54 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestPendingSignals(t *testing.T) {
+	signals := []logSignal{
+		{name: "loaded", pattern: regexp.MustCompile(`(?i)completed loading shard`)},
+		{name: "leader", pattern: regexp.MustCompile(`entering leader state`)},
+	}
+
+	log := strings.Join([]string{
+		`{"level":"info","msg":"starting"}`,
+		`{"level":"info","msg":"Completed loading shard collection_abc"}`,
+	}, "\n")
+
+	pending, err := pendingSignals(strings.NewReader(log), signals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].name != "leader" {
+		t.Errorf("wanted only leader pending, got %v", pending)
+	}
+
+	pending, err = pendingSignals(strings.NewReader(log+"\nraft: entering leader state"), signals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("wanted nothing pending, got %v", pending)
+	}
+}
+
+func TestLogSignalAppliesTo(t *testing.T) {
+	signal := logSignal{since: "1.25.0"}
+
+	for version, want := range map[string]bool{
+		"1.24.9":      false,
+		"1.25.0":      true,
+		"1.26.1":      true,
+		"preview-abc": true,
+	} {
+		if got := signal.appliesTo(version); got != want {
+			t.Errorf("%s: wanted %v, got %v", version, want, got)
+		}
+	}
+
+	if !(logSignal{}).appliesTo("1.0.0") {
+		t.Error("a signal without since applies to every version")
+	}
+}
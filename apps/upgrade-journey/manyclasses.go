@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The large schema scenario creates thousands of classes before the first
+// hop. Large schemas go through very different code paths than the few
+// classes of the other checks: the schema is loaded and, for raft versions,
+// replayed on every startup, and every class adds shards to every node.
+var (
+	// manyClasses is set by --many-classes, 0 disables the scenario
+	manyClasses int
+
+	// manyClassesShards is set by --many-classes-shards
+	manyClassesShards int
+
+	// maxSchemaLoadTime is set by --max-schema-load-time
+	maxSchemaLoadTime time.Duration
+
+	// manyClassesMemoryGrowth is set by --many-classes-memory-growth
+	manyClassesMemoryGrowth float64
+)
+
+const (
+	manyClassesPrefix  = "Stress"
+	manyClassesWorkers = 8
+)
+
+func manyClassName(i int) string {
+	return fmt.Sprintf("%s%05d", manyClassesPrefix, i)
+}
+
+// createManyClasses creates the classes of the scenario in parallel
+func createManyClasses(ctx context.Context) error {
+	start := time.Now()
+	work := make(chan int)
+	errs := make(chan error, manyClassesWorkers)
+
+	var wg sync.WaitGroup
+	for w := 0; w < manyClassesWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				if err := createManyClass(ctx, i); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+
+	var err error
+	for i := 0; i < manyClasses && err == nil; i++ {
+		select {
+		case work <- i:
+		case err = <-errs:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	}
+	close(work)
+	wg.Wait()
+
+	if err == nil {
+		select {
+		case err = <-errs:
+		default:
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	logger.Info("created large schema", "classes", manyClasses, "shards_per_class", manyClassesShards,
+		"took", time.Since(start).Round(time.Second))
+	return nil
+}
+
+func createManyClass(ctx context.Context, i int) error {
+	name := manyClassName(i)
+	if _, err := postRaw(ctx, i%nodeCount, "/v1/schema", map[string]interface{}{
+		"class":      name,
+		"vectorizer": "none",
+		"properties": []map[string]interface{}{
+			{"name": "name", "dataType": []string{"text"}},
+		},
+		"shardingConfig": map[string]interface{}{"desiredCount": manyClassesShards},
+	}); err != nil {
+		return fmt.Errorf("create class %s: %w", name, err)
+	}
+	return nil
+}
+
+// checkLargeSchema is called once the resources of a hop were sampled. It
+// loads the schema from every node and checks that none of the classes of
+// the scenario got lost and that loading them stays below
+// --max-schema-load-time. The memory of a node must not grow beyond
+// --many-classes-memory-growth times its memory after the first hop, as it
+// is dominated by the schema.
+func (c *cluster) checkLargeSchema(ctx context.Context, posOfVersion int) error {
+	for i := 0; i < c.NodeCount; i++ {
+		start := time.Now()
+		body, err := getRaw(ctx, i, "/v1/schema")
+		if err != nil {
+			return fmt.Errorf("load schema of %s: %w", c.Hostname(i), err)
+		}
+		took := time.Since(start)
+
+		count, err := countManyClasses(body)
+		if err != nil {
+			return fmt.Errorf("schema of %s: %w", c.Hostname(i), err)
+		}
+
+		runReport.recordSchemaLoad(posOfVersion, c.Hostname(i), took)
+		nodeLogger(c, i).Info("loaded large schema", "classes", count, "took", took.Round(time.Millisecond))
+
+		if count != manyClasses {
+			return fmt.Errorf("%s has %d of %d classes of the large schema", c.Hostname(i), count, manyClasses)
+		}
+		if maxSchemaLoadTime > 0 && took > maxSchemaLoadTime {
+			return fmt.Errorf("%s took %s to load the schema, above %s", c.Hostname(i),
+				took.Round(time.Millisecond), maxSchemaLoadTime)
+		}
+	}
+
+	if manyClassesMemoryGrowth <= 0 || posOfVersion == 0 {
+		return nil
+	}
+
+	first, cur := runReport.nodeMemory(0), runReport.nodeMemory(posOfVersion)
+	for node, memory := range cur {
+		if memoryGrew(first[node], memory, manyClassesMemoryGrowth) {
+			return fmt.Errorf("memory of %s grew from %d MiB on %s to %d MiB", node, first[node]>>20,
+				versions[0], memory>>20)
+		}
+	}
+	return nil
+}
+
+// countManyClasses counts the classes of the scenario in a schema response
+func countManyClasses(body []byte) (int, error) {
+	var schema struct {
+		Classes []struct {
+			Class string `json:"class"`
+		} `json:"classes"`
+	}
+	if err := json.Unmarshal(body, &schema); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, class := range schema.Classes {
+		if strings.HasPrefix(class.Class, manyClassesPrefix) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// memoryGrew is true if cur is more than factor times first, a node without
+// a sample of the first hop is never compared
+func memoryGrew(first, cur uint64, factor float64) bool {
+	return first > 0 && float64(cur) > factor*float64(first)
+}
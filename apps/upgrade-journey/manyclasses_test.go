@@ -0,0 +1,30 @@
+package main
+
+import "testing"
+
+func Test_countManyClasses(t *testing.T) {
+	body := []byte(`{"classes":[{"class":"Stress00000"},{"class":"Collection"},{"class":"Stress00001"}]}`)
+	count, err := countManyClasses(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("countManyClasses() = %d, want 2", count)
+	}
+}
+
+func Test_memoryGrew(t *testing.T) {
+	tests := []struct {
+		first, cur uint64
+		want       bool
+	}{
+		{first: 100, cur: 200, want: false},
+		{first: 100, cur: 201, want: true},
+		{first: 0, cur: 1000, want: false},
+	}
+	for _, tt := range tests {
+		if got := memoryGrew(tt.first, tt.cur, 2); got != tt.want {
+			t.Errorf("memoryGrew(%d, %d) = %v, want %v", tt.first, tt.cur, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// The many tenants scenario creates a single class with tens of thousands
+// of tenants before the first hop, most of them inactive. Every startup has
+// to discover all of them, and activating a tenant loads its shard from
+// disk, which is what users of multi-tenancy wait on.
+var (
+	// manyTenants is set by --many-tenants, 0 disables the scenario
+	manyTenants int
+
+	// activeTenants is set by --active-tenants
+	activeTenants int
+
+	// maxActivationLatency is set by --max-activation-latency
+	maxActivationLatency time.Duration
+)
+
+const (
+	manyTenantsClass = "TenantStress"
+
+	// tenants are created in batches of this size
+	tenantBatchSize = 1000
+
+	// inactive tenants activated on every hop
+	tenantActivationsPerHop = 5
+)
+
+func tenantName(i int) string {
+	return fmt.Sprintf("tenant%06d", i)
+}
+
+// tenantActive is true for the activity status of an active tenant, which
+// was renamed from HOT to ACTIVE
+func tenantActive(status string) bool {
+	return status == "HOT" || status == "ACTIVE"
+}
+
+// createManyTenants creates the class, its tenants and one object in every
+// active tenant
+func createManyTenants(ctx context.Context) error {
+	start := time.Now()
+	if _, err := postRaw(ctx, 0, "/v1/schema", map[string]interface{}{
+		"class":              manyTenantsClass,
+		"vectorizer":         "none",
+		"multiTenancyConfig": map[string]interface{}{"enabled": true},
+		"properties": []map[string]interface{}{
+			{"name": "name", "dataType": []string{"text"}},
+		},
+	}); err != nil {
+		return fmt.Errorf("create class %s: %w", manyTenantsClass, err)
+	}
+
+	for from := 0; from < manyTenants; from += tenantBatchSize {
+		to := from + tenantBatchSize
+		if to > manyTenants {
+			to = manyTenants
+		}
+
+		tenants := make([]map[string]interface{}, 0, to-from)
+		for i := from; i < to; i++ {
+			status := "COLD"
+			if i < activeTenants {
+				status = "HOT"
+			}
+			tenants = append(tenants, map[string]interface{}{"name": tenantName(i), "activityStatus": status})
+		}
+
+		if _, err := postRaw(ctx, 0, fmt.Sprintf("/v1/schema/%s/tenants", manyTenantsClass), tenants); err != nil {
+			return fmt.Errorf("create tenants %d to %d: %w", from, to, err)
+		}
+	}
+
+	for i := 0; i < activeTenants; i++ {
+		if err := insertTenantObject(ctx, tenantName(i)); err != nil {
+			return err
+		}
+	}
+
+	logger.Info("created many tenants", "tenants", manyTenants, "active", activeTenants,
+		"took", time.Since(start).Round(time.Second))
+	return nil
+}
+
+func insertTenantObject(ctx context.Context, tenant string) error {
+	if _, err := postRaw(ctx, 0, "/v1/objects", map[string]interface{}{
+		"class":      manyTenantsClass,
+		"tenant":     tenant,
+		"vector":     []float32{0.1, 0.2, 0.3},
+		"properties": map[string]interface{}{"name": tenant},
+	}); err != nil {
+		return fmt.Errorf("insert into tenant %s: %w", tenant, err)
+	}
+	return nil
+}
+
+// checkManyTenants checks that every node still knows every tenant with its
+// activity status, then activates a few inactive tenants and checks how
+// long it takes until they accept writes. They are deactivated again, so
+// that the number of active tenants stays the same from hop to hop.
+func (c *cluster) checkManyTenants(ctx context.Context, posOfVersion int) error {
+	for i := 0; i < c.NodeCount; i++ {
+		body, err := getRaw(ctx, i, fmt.Sprintf("/v1/schema/%s/tenants", manyTenantsClass))
+		if err != nil {
+			return fmt.Errorf("tenants of %s: %w", c.Hostname(i), err)
+		}
+
+		total, active, err := countTenants(body)
+		if err != nil {
+			return fmt.Errorf("tenants of %s: %w", c.Hostname(i), err)
+		}
+		if total != manyTenants || active != activeTenants {
+			return fmt.Errorf("%s has %d tenants of which %d are active, want %d and %d", c.Hostname(i),
+				total, active, manyTenants, activeTenants)
+		}
+	}
+
+	for _, i := range tenantsToActivate(posOfVersion) {
+		tenant := tenantName(i)
+		took, err := activateTenant(ctx, tenant)
+		if err != nil {
+			return err
+		}
+
+		runReport.recordActivation(posOfVersion, tenant, took)
+		hopLogger(posOfVersion).Info("activated tenant", "tenant", tenant, "took", took.Round(time.Millisecond))
+		if maxActivationLatency > 0 && took > maxActivationLatency {
+			return fmt.Errorf("activating %s took %s, above %s", tenant, took.Round(time.Millisecond),
+				maxActivationLatency)
+		}
+
+		if err := setTenantStatus(ctx, tenant, "COLD"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// tenantsToActivate picks different inactive tenants on every hop, so that
+// every hop activates tenants whose shards were never loaded before
+func tenantsToActivate(posOfVersion int) []int {
+	inactive := manyTenants - activeTenants
+	if inactive <= 0 {
+		return nil
+	}
+
+	var out []int
+	for k := 0; k < tenantActivationsPerHop && k < inactive; k++ {
+		out = append(out, activeTenants+(posOfVersion*tenantActivationsPerHop+k)%inactive)
+	}
+	return out
+}
+
+// activateTenant returns how long it took until the tenant accepted a write
+func activateTenant(ctx context.Context, tenant string) (time.Duration, error) {
+	start := time.Now()
+	if err := setTenantStatus(ctx, tenant, "HOT"); err != nil {
+		return 0, err
+	}
+	if err := insertTenantObject(ctx, tenant); err != nil {
+		return 0, err
+	}
+	return time.Since(start), nil
+}
+
+func setTenantStatus(ctx context.Context, tenant, status string) error {
+	if _, err := sendRaw(ctx, http.MethodPut, 0, fmt.Sprintf("/v1/schema/%s/tenants", manyTenantsClass),
+		[]map[string]interface{}{{"name": tenant, "activityStatus": status}}); err != nil {
+		return fmt.Errorf("set %s to %s: %w", tenant, status, err)
+	}
+	return nil
+}
+
+func countTenants(body []byte) (total, active int, err error) {
+	var tenants []struct {
+		Name           string `json:"name"`
+		ActivityStatus string `json:"activityStatus"`
+	}
+	if err := json.Unmarshal(body, &tenants); err != nil {
+		return 0, 0, err
+	}
+
+	for _, tenant := range tenants {
+		if tenantActive(tenant.ActivityStatus) {
+			active++
+		}
+	}
+	return len(tenants), active, nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_countTenants(t *testing.T) {
+	body := []byte(`[{"name":"a","activityStatus":"HOT"},{"name":"b","activityStatus":"COLD"},` +
+		`{"name":"c","activityStatus":"ACTIVE"},{"name":"d","activityStatus":"INACTIVE"}]`)
+	total, active, err := countTenants(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 4 || active != 2 {
+		t.Errorf("countTenants() = %d, %d, want 4, 2", total, active)
+	}
+}
+
+func Test_tenantsToActivate(t *testing.T) {
+	manyTenants, activeTenants = 12, 4
+	defer func() { manyTenants, activeTenants = 0, 100 }()
+
+	tests := []struct {
+		hop  int
+		want []int
+	}{
+		{hop: 0, want: []int{4, 5, 6, 7, 8}},
+		{hop: 1, want: []int{9, 10, 11, 4, 5}},
+	}
+	for _, tt := range tests {
+		if got := tenantsToActivate(tt.hop); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("tenantsToActivate(%d) = %v, want %v", tt.hop, got, tt.want)
+		}
+	}
+}
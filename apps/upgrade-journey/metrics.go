@@ -0,0 +1,326 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The metrics oracle turns the Prometheus telemetry of the nodes into
+// checks: error counters must not move while no fault is active, the
+// goroutines of a node must stay in the same range from hop to hop, and
+// the tombstones of deleted vectors must be cleaned up.
+const (
+	metricsProbeClass   = "MetricsProbe"
+	metricsProbeObjects = 20
+
+	// a node may end a hop with twice the goroutines of the previous hop,
+	// plus some slack for small nodes
+	goroutineGrowthFactor = 2
+	goroutineGrowthSlack  = 200
+)
+
+var (
+	// withMetrics is set by --metrics
+	withMetrics bool
+
+	// tombstoneCleanupTimeout is set by --tombstone-cleanup-timeout
+	tombstoneCleanupTimeout time.Duration
+)
+
+type metricSeries struct {
+	name   string
+	labels map[string]string
+	value  float64
+}
+
+type metricSamples []metricSeries
+
+// parseMetrics parses the Prometheus text exposition format, which is all
+// the checks need. Comments, timestamps and malformed lines are skipped.
+func parseMetrics(r io.Reader) (metricSamples, error) {
+	var samples metricSamples
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		series, rest := line, ""
+		labels := map[string]string{}
+		if open := strings.IndexByte(line, '{'); open >= 0 {
+			end := strings.LastIndexByte(line, '}')
+			if end < open {
+				continue
+			}
+			series, rest = line[:open], line[end+1:]
+			labels = parseLabels(line[open+1 : end])
+		} else if space := strings.IndexByte(line, ' '); space >= 0 {
+			series, rest = line[:space], line[space:]
+		}
+
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			continue
+		}
+		value, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			continue
+		}
+		samples = append(samples, metricSeries{name: series, labels: labels, value: value})
+	}
+
+	return samples, scanner.Err()
+}
+
+func parseLabels(raw string) map[string]string {
+	labels := map[string]string{}
+	for raw != "" {
+		eq := strings.IndexByte(raw, '=')
+		if eq < 0 || eq+1 >= len(raw) || raw[eq+1] != '"' {
+			break
+		}
+		key := strings.TrimSpace(raw[:eq])
+		raw = raw[eq+2:]
+
+		var value strings.Builder
+		i := 0
+		for ; i < len(raw) && raw[i] != '"'; i++ {
+			if raw[i] == '\\' && i+1 < len(raw) {
+				i++
+			}
+			value.WriteByte(raw[i])
+		}
+		labels[key] = value.String()
+
+		if i < len(raw) {
+			i++
+		}
+		raw = strings.TrimPrefix(strings.TrimSpace(raw[i:]), ",")
+	}
+	return labels
+}
+
+// sum adds up every series of the metric that carries all of the labels
+func (s metricSamples) sum(name string, labels map[string]string) float64 {
+	total := 0.0
+	for _, series := range s {
+		if series.name != name || !hasLabels(series.labels, labels) {
+			continue
+		}
+		total += series.value
+	}
+	return total
+}
+
+func hasLabels(have, want map[string]string) bool {
+	for key, value := range want {
+		if have[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// errorCounters sums every series whose name marks it as counting errors or
+// failures, by metric name
+func (s metricSamples) errorCounters() map[string]float64 {
+	counters := map[string]float64{}
+	for _, series := range s {
+		name := strings.ToLower(series.name)
+		if strings.HasSuffix(name, "_bucket") || strings.HasSuffix(name, "_sum") {
+			continue
+		}
+		if strings.Contains(name, "error") || strings.Contains(name, "failed") {
+			counters[series.name] += series.value
+		}
+	}
+	return counters
+}
+
+// increasedErrors returns the error counters that grew between two samples
+// of the same node
+func increasedErrors(before, after metricSamples) []string {
+	prev := before.errorCounters()
+	var increased []string
+	for name, value := range after.errorCounters() {
+		if value > prev[name] {
+			increased = append(increased, fmt.Sprintf("%s: %g -> %g", name, prev[name], value))
+		}
+	}
+	return increased
+}
+
+// goroutinesGrew is true if a node's goroutines grew by more than the
+// tolerated factor since the previous hop
+func goroutinesGrew(prev, cur float64) bool {
+	return prev > 0 && cur > goroutineGrowthFactor*prev+goroutineGrowthSlack
+}
+
+// metricsOracle keeps the samples the checks compare against
+type metricsOracle struct {
+	// baseline is sampled at baselineAt, once every node runs the version
+	// of the hop
+	baseline   map[int]metricSamples
+	baselineAt time.Time
+
+	// goroutines at the end of the previous hop, by node
+	goroutines map[int]float64
+}
+
+var metrics = &metricsOracle{goroutines: map[int]float64{}}
+
+func (m *metricsOracle) scrape(ctx context.Context, c *cluster, nodeId int) ([]byte, metricSamples, error) {
+	reqCtx, cancel := requestContext(ctx)
+	defer cancel()
+
+	url := fmt.Sprintf("http://%s/metrics", c.MetricsHost(nodeId))
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, nil, requestError(ctx, "GET "+url, err)
+	}
+	defer res.Body.Close()
+
+	raw, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, nil, requestError(ctx, "GET "+url, err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("GET %s: status %d", url, res.StatusCode)
+	}
+
+	samples, err := parseMetrics(bytes.NewReader(raw))
+	return raw, samples, err
+}
+
+// recordBaseline samples every node once all of them run the version of
+// the hop, restarts reset the counters
+func (m *metricsOracle) recordBaseline(ctx context.Context, c *cluster) error {
+	m.baseline = map[int]metricSamples{}
+	m.baselineAt = time.Now()
+	for i := 0; i < c.NodeCount; i++ {
+		_, samples, err := m.scrape(ctx, c, i)
+		if err != nil {
+			return fmt.Errorf("metrics baseline of %s: %w", c.Hostname(i), err)
+		}
+		m.baseline[i] = samples
+	}
+	return nil
+}
+
+// check samples every node at the end of a hop, stores the samples with the
+// hop's artifacts and runs the checks against the baseline and the
+// previous hop
+func (m *metricsOracle) check(ctx context.Context, c *cluster, hop int) error {
+	dir := path.Join(hopArtifactsDir(hop), "metrics")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	fault, faulted := faultWindows.During(m.baselineAt, time.Now())
+	for i := 0; i < c.NodeCount; i++ {
+		raw, samples, err := m.scrape(ctx, c, i)
+		if err != nil {
+			return fmt.Errorf("metrics of %s: %w", c.Hostname(i), err)
+		}
+		if err := os.WriteFile(path.Join(dir, c.Hostname(i)+".prom"), raw, 0o644); err != nil {
+			return err
+		}
+
+		if increased := increasedErrors(m.baseline[i], samples); len(increased) > 0 {
+			if !faulted {
+				return fmt.Errorf("%s: error counters increased while no fault was active: %s",
+					c.Hostname(i), strings.Join(increased, ", "))
+			}
+			nodeLogger(c, i).Info("error counters increased during a fault", "fault", fault,
+				"counters", increased)
+		}
+
+		goroutines := samples.sum("go_goroutines", nil)
+		if goroutinesGrew(m.goroutines[i], goroutines) {
+			return fmt.Errorf("%s: goroutines grew from %g to %g since the previous hop",
+				c.Hostname(i), m.goroutines[i], goroutines)
+		}
+		m.goroutines[i] = goroutines
+	}
+
+	return m.checkTombstoneCleanup(ctx, c, hop)
+}
+
+// checkTombstoneCleanup imports and deletes the vectors of the probe class
+// and waits until the cleanup removed their tombstones on every node
+func (m *metricsOracle) checkTombstoneCleanup(ctx context.Context, c *cluster, hop int) error {
+	if hop == 0 {
+		if _, err := postRaw(ctx, 0, "/v1/schema", map[string]interface{}{
+			"class":             metricsProbeClass,
+			"vectorizer":        "none",
+			"vectorIndexConfig": map[string]interface{}{"cleanupIntervalSeconds": 5},
+		}); err != nil {
+			return fmt.Errorf("create class %s: %w", metricsProbeClass, err)
+		}
+	}
+
+	ids := make([]string, metricsProbeObjects)
+	objects := make([]map[string]interface{}, metricsProbeObjects)
+	for i := range objects {
+		ids[i] = uuid.New().String()
+		objects[i] = map[string]interface{}{
+			"class":  metricsProbeClass,
+			"id":     ids[i],
+			"vector": []float32{rand.Float32(), rand.Float32(), rand.Float32()},
+		}
+	}
+	if _, err := postRaw(ctx, 0, "/v1/batch/objects", map[string]interface{}{"objects": objects}); err != nil {
+		return fmt.Errorf("import %s: %w", metricsProbeClass, err)
+	}
+	for _, id := range ids {
+		if _, err := sendRaw(ctx, http.MethodDelete, 0,
+			fmt.Sprintf("/v1/objects/%s/%s", metricsProbeClass, id), nil); err != nil {
+			return fmt.Errorf("delete %s object: %w", metricsProbeClass, err)
+		}
+	}
+
+	probe := map[string]string{"class_name": metricsProbeClass}
+	deadline := time.Now().Add(tombstoneCleanupTimeout)
+	for {
+		remaining := 0.0
+		for i := 0; i < c.NodeCount; i++ {
+			_, samples, err := m.scrape(ctx, c, i)
+			if err != nil {
+				return fmt.Errorf("metrics of %s: %w", c.Hostname(i), err)
+			}
+			remaining += samples.sum("vector_index_tombstones", probe)
+		}
+
+		if remaining == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%g tombstones of %s not cleaned up within %s",
+				remaining, metricsProbeClass, tombstoneCleanupTimeout)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const exposition = `# HELP go_goroutines Number of goroutines that currently exist.
+# TYPE go_goroutines gauge
+go_goroutines 143
+vector_index_tombstones{class_name="MetricsProbe",shard_name="a1"} 12
+vector_index_tombstones{class_name="MetricsProbe",shard_name="b2"} 3
+vector_index_tombstones{class_name="Load",shard_name="c3"} 7
+lsm_bucket_errors_total{operation="put",strategy="replace"} 2 1700000000000
+batch_durations_ms_bucket{le="10",operation="object_storage"} 1
+vector_index_failed_operations{class_name="Load",msg="with \"quotes\", commas"} 1
+`
+
+func TestParseMetrics(t *testing.T) {
+	samples, err := parseMetrics(strings.NewReader(exposition))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := samples.sum("go_goroutines", nil); got != 143 {
+		t.Errorf("wanted 143 goroutines, got %g", got)
+	}
+	if got := samples.sum("vector_index_tombstones", map[string]string{"class_name": "MetricsProbe"}); got != 15 {
+		t.Errorf("wanted 15 probe tombstones, got %g", got)
+	}
+
+	labels := samples[len(samples)-1].labels
+	if want := map[string]string{"class_name": "Load", "msg": `with "quotes", commas`}; !reflect.DeepEqual(labels, want) {
+		t.Errorf("wanted labels %v, got %v", want, labels)
+	}
+}
+
+func TestIncreasedErrors(t *testing.T) {
+	before, _ := parseMetrics(strings.NewReader(exposition))
+	after, _ := parseMetrics(strings.NewReader(strings.Replace(exposition,
+		`strategy="replace"} 2`, `strategy="replace"} 5`, 1)))
+
+	if got := increasedErrors(before, before); len(got) != 0 {
+		t.Errorf("wanted no increased errors, got %v", got)
+	}
+	if got := increasedErrors(before, after); !reflect.DeepEqual(got, []string{"lsm_bucket_errors_total: 2 -> 5"}) {
+		t.Errorf("wanted the lsm errors to increase, got %v", got)
+	}
+}
+
+func TestGoroutinesGrew(t *testing.T) {
+	tests := []struct {
+		prev, cur float64
+		want      bool
+	}{
+		{prev: 0, cur: 5000, want: false},
+		{prev: 150, cur: 500, want: false},
+		{prev: 150, cur: 501, want: true},
+	}
+
+	for _, tt := range tests {
+		if got := goroutinesGrew(tt.prev, tt.cur); got != tt.want {
+			t.Errorf("%g -> %g: wanted %t, got %t", tt.prev, tt.cur, tt.want, got)
+		}
+	}
+}
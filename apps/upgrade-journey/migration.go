@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	wcluster "github.com/weaviate/weaviate-chaos-engineering/apps/upgrade-journey/pkg/cluster"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate"
+)
+
+// migrateToRaft replaces the regular rolling update on the hop where the
+// schema moves from the pre-RAFT two-phase-commit implementation to RAFT.
+// The last node is kept down for the entire migration. The remaining nodes
+// are upgraded together, as none of them becomes ready before a quorum of
+// voters bootstrapped RAFT, and have to complete the migration on their own.
+// Then the last node returns on the new version and has to catch up.
+func migrateToRaft(ctx context.Context, c *cluster, posOfVersion int) error {
+	prev, version := versions[posOfVersion-1], versions[posOfVersion]
+	hopLogger(posOfVersion).Info("hop migrates the schema to raft", "from", prev)
+
+	before, err := classFingerprints(ctx, c.nodeClient(0))
+	if err != nil {
+		return fmt.Errorf("schema before migration: %w", err)
+	}
+
+	downNode := c.NodeCount - 1
+	nodeLogger(c, downNode).Info("keeping node down during the migration")
+	if err := c.StopNode(ctx, downNode); err != nil {
+		return err
+	}
+
+	for i := 0; i < downNode; i++ {
+		if err := c.StopNode(ctx, i); err != nil {
+			return err
+		}
+	}
+
+	errs := make(chan error, downNode)
+	var wg sync.WaitGroup
+	for i := 0; i < downNode; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := c.StartStoppedNode(ctx, i, version); err != nil {
+				errs <- fmt.Errorf("migrate %s: %w", c.Hostname(i), err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	if err := <-errs; err != nil {
+		return err
+	}
+
+	for i := 0; i < downNode; i++ {
+		if err := assertMigrated(ctx, c, i, before); err != nil {
+			return err
+		}
+	}
+
+	nodeLogger(c, downNode).Info("bringing node back after the migration")
+	if err := c.StartStoppedNode(ctx, downNode, version); err != nil {
+		return fmt.Errorf("start %s after migration: %w", c.Hostname(downNode), err)
+	}
+
+	if err := waitForSchemaConvergence(ctx, c.nodeClient(0), c.nodeClient(downNode),
+		30*time.Second); err != nil {
+		return fmt.Errorf("%s did not catch up: %w", c.Hostname(downNode), err)
+	}
+
+	if err := assertMigrated(ctx, c, downNode, before); err != nil {
+		return err
+	}
+
+	if err := waitForRaftSynchronized(ctx, c, 30*time.Second); err != nil {
+		return err
+	}
+
+	hopLogger(posOfVersion).Info("completed raft migration")
+	return nil
+}
+
+// assertMigrated checks that a node serves the schema from RAFT and that
+// every class kept the config it had before the migration
+func assertMigrated(ctx context.Context, c *cluster, nodeId int, before map[string]classFingerprint) error {
+	stats, err := getClusterStatistics(ctx, nodeId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", c.Hostname(nodeId), err)
+	}
+
+	self, ok := stats.node(c.Hostname(nodeId))
+	if !ok {
+		return fmt.Errorf("%s: not part of its own raft statistics", c.Hostname(nodeId))
+	}
+	if !self.Ready {
+		return fmt.Errorf("%s: raft is not ready", c.Hostname(nodeId))
+	}
+
+	after, err := classFingerprints(ctx, c.nodeClient(nodeId))
+	if err != nil {
+		return fmt.Errorf("%s: schema after migration: %w", c.Hostname(nodeId), err)
+	}
+
+	if len(before) != len(after) {
+		return fmt.Errorf("%s: wanted %d classes after migration, got %d",
+			c.Hostname(nodeId), len(before), len(after))
+	}
+
+	for name, want := range before {
+		got, ok := after[name]
+		if !ok {
+			return fmt.Errorf("%s: class %s lost in migration", c.Hostname(nodeId), name)
+		}
+
+		if want != got {
+			return fmt.Errorf("%s: class %s changed in migration: wanted %+v, got %+v",
+				c.Hostname(nodeId), name, want, got)
+		}
+	}
+
+	return nil
+}
+
+func waitForRaftSynchronized(ctx context.Context, c *cluster, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		stats, err := getClusterStatistics(ctx, 0)
+		if err == nil && stats.Synchronized && len(stats.Statistics) == c.NodeCount {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			if err != nil {
+				return fmt.Errorf("raft not synchronized within %s: %w", timeout, err)
+			}
+			return fmt.Errorf("raft not synchronized within %s: %d of %d nodes, synchronized=%t",
+				timeout, len(stats.Statistics), c.NodeCount, stats.Synchronized)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("raft not synchronized: %w", ctx.Err())
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// validateRaftMigration checks that the voters that stay up while the last
+// node is down during the migration to RAFT can bootstrap it on their own
+func validateRaftMigration(topology wcluster.Topology, nodeCount int) error {
+	if nodeCount < 2 {
+		return nil
+	}
+
+	// nodes 0 to Voters-1 vote, the last node only if all of them do
+	voters, up := topology.Voters, topology.Voters
+	if voters == 0 || voters == nodeCount {
+		voters, up = nodeCount, nodeCount-1
+	}
+	if quorum := voters/2 + 1; up < quorum {
+		return fmt.Errorf("the migration to raft keeps weaviate-%d down, which leaves %d of %d voters, "+
+			"fewer than the quorum of %d that bootstraps raft; use at least 3 nodes", nodeCount-1, up,
+			voters, quorum)
+	}
+	return nil
+}
+
+// classFingerprint contains the parts of a class that a migration must never
+// change. It is intentionally not the entire class, because a new version
+// may add new config fields with default values.
+type classFingerprint struct {
+	properties      string
+	vectorIndexType string
+	vectorizer      string
+}
+
+func classFingerprints(ctx context.Context, client *weaviate.Client) (map[string]classFingerprint, error) {
+	reqCtx, cancel := requestContext(ctx)
+	dump, err := client.Schema().Getter().Do(reqCtx)
+	cancel()
+	if err != nil {
+		return nil, requestError(ctx, "get schema", err)
+	}
+
+	out := map[string]classFingerprint{}
+	for _, class := range dump.Classes {
+		props := map[string][]string{}
+		for _, prop := range class.Properties {
+			props[prop.Name] = prop.DataType
+		}
+
+		propsJSON, err := json.Marshal(props)
+		if err != nil {
+			return nil, err
+		}
+
+		out[class.Class] = classFingerprint{
+			properties:      string(propsJSON),
+			vectorIndexType: class.VectorIndexType,
+			vectorizer:      class.Vectorizer,
+		}
+	}
+
+	return out, nil
+}
+
+type raftNodeStatistics struct {
+	Name     string `json:"name"`
+	Ready    bool   `json:"ready"`
+	Status   string `json:"status"`
+	LeaderID string `json:"leaderId"`
+}
+
+type clusterStatistics struct {
+	Statistics   []raftNodeStatistics `json:"statistics"`
+	Synchronized bool                 `json:"synchronized"`
+}
+
+func (s clusterStatistics) node(name string) (raftNodeStatistics, bool) {
+	for _, stat := range s.Statistics {
+		if stat.Name == name {
+			return stat, true
+		}
+	}
+
+	return raftNodeStatistics{}, false
+}
+
+// getClusterStatistics uses plain HTTP, because the endpoint only exists
+// since the introduction of RAFT and is not supported by the client
+func getClusterStatistics(ctx context.Context, nodeId int) (clusterStatistics, error) {
+	var stats clusterStatistics
+
+	body, err := getRaw(ctx, nodeId, "/v1/cluster/statistics")
+	if err != nil {
+		return stats, err
+	}
+
+	err = json.Unmarshal(body, &stats)
+	return stats, err
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+
+	wcluster "github.com/weaviate/weaviate-chaos-engineering/apps/upgrade-journey/pkg/cluster"
+)
+
+func Test_validateRaftMigration(t *testing.T) {
+	tests := []struct {
+		name      string
+		nodes     int
+		topology  wcluster.Topology
+		wantError bool
+	}{
+		{name: "single node", nodes: 1},
+		{name: "two nodes", nodes: 2, wantError: true},
+		{name: "three nodes", nodes: 3},
+		{name: "four nodes", nodes: 4},
+		{name: "five nodes", nodes: 5},
+		{name: "last node does not vote", nodes: 4, topology: wcluster.Topology{Voters: 3}},
+		{name: "two voters", nodes: 4, topology: wcluster.Topology{Voters: 2}},
+		{name: "every node votes", nodes: 2, topology: wcluster.Topology{Voters: 2}, wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRaftMigration(tt.topology, tt.nodes)
+			if (err != nil) != tt.wantError {
+				t.Errorf("wanted error %t, got %v", tt.wantError, err)
+			}
+		})
+	}
+}
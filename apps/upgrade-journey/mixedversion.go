@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// The mixed version workload writes to every node after each restart of a
+// rolling update, while the nodes run different versions. Every object is
+// tagged with the version of the node that accepted it, so that once the
+// whole cluster runs the new version, a lost or changed object points to
+// the version that wrote it.
+var mixedVersionWrites int
+
+const mixedVersionClass = "MixedVersionWrite"
+
+// mixedWrite is an acknowledged write of the workload
+type mixedWrite struct {
+	id      string
+	node    int
+	version string
+}
+
+type mixedVersionWorkload struct {
+	sync.Mutex
+	writes   []mixedWrite
+	rejected int
+}
+
+var mixedWrites = &mixedVersionWorkload{}
+
+func (w *mixedVersionWorkload) createClass(ctx context.Context) error {
+	if _, err := postRaw(ctx, 0, "/v1/schema", map[string]interface{}{
+		"class":      mixedVersionClass,
+		"vectorizer": "none",
+		"properties": []map[string]interface{}{
+			{"name": "acceptedBy", "dataType": []string{"text"}, "tokenization": "field"},
+			{"name": "node", "dataType": []string{"int"}},
+		},
+	}); err != nil {
+		return fmt.Errorf("create class %s: %w", mixedVersionClass, err)
+	}
+	return nil
+}
+
+// write sends --mixed-version-writes objects to every node. It runs after
+// every restart of a rolling update, a rejected write is counted but is
+// not a failure, only what a node acknowledged has to survive.
+func (w *mixedVersionWorkload) write(ctx context.Context, c *cluster) error {
+	if mixedVersionWrites == 0 {
+		return nil
+	}
+
+	for node := 0; node < c.NodeCount; node++ {
+		version := c.NodeVersions[node]
+		for n := 0; n < mixedVersionWrites; n++ {
+			id := uuid.New().String()
+			status, body, err := doRaw(ctx, http.MethodPost, node, "/v1/objects", map[string]interface{}{
+				"class":      mixedVersionClass,
+				"id":         id,
+				"properties": map[string]interface{}{"acceptedBy": version, "node": node},
+			})
+			if err != nil {
+				return fmt.Errorf("write to %s on %s: %w", c.Hostname(node), version, err)
+			}
+
+			w.Lock()
+			if status >= 200 && status <= 299 {
+				w.writes = append(w.writes, mixedWrite{id: id, node: node, version: version})
+			} else {
+				w.rejected++
+				nodeLogger(c, node).Debug("mixed version write rejected", "version", version, "status", status,
+					"body", string(body))
+			}
+			w.Unlock()
+		}
+	}
+	return nil
+}
+
+// mixedFailure is an acknowledged write that did not survive as written
+type mixedFailure struct {
+	write  mixedWrite
+	reason string
+}
+
+// groupMixedFailures reports the failures by the version that accepted the
+// writes, together with how many writes that version accepted
+func groupMixedFailures(writes []mixedWrite, failures []mixedFailure) []string {
+	accepted := map[string]int{}
+	for _, write := range writes {
+		accepted[write.version]++
+	}
+
+	byVersion := map[string][]string{}
+	for _, f := range failures {
+		byVersion[f.write.version] = append(byVersion[f.write.version],
+			fmt.Sprintf("%s via weaviate-%d: %s", f.write.id, f.write.node, f.reason))
+	}
+
+	var versions []string
+	for version := range byVersion {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+
+	var report []string
+	for _, version := range versions {
+		report = append(report, fmt.Sprintf("accepted by %s: %d of %d writes failed:\n  %s", version,
+			len(byVersion[version]), accepted[version], strings.Join(byVersion[version], "\n  ")))
+	}
+	return report
+}
+
+// mixedVersionVerifier reads back every acknowledged write once the cluster
+// runs a single version again
+type mixedVersionVerifier struct{}
+
+func (v *mixedVersionVerifier) name() string {
+	return "mixed version writes"
+}
+
+func (v *mixedVersionVerifier) verify(ctx context.Context, posOfMaxVersion int) error {
+	mixedWrites.Lock()
+	writes := append([]mixedWrite{}, mixedWrites.writes...)
+	rejected := mixedWrites.rejected
+	mixedWrites.Unlock()
+
+	var failures []mixedFailure
+	for _, write := range writes {
+		status, body, err := doRaw(ctx, http.MethodGet, 0,
+			fmt.Sprintf("/v1/objects/%s/%s", mixedVersionClass, write.id), nil)
+		if err != nil {
+			return err
+		}
+		if reason := checkMixedWrite(write, status, body); reason != "" {
+			failures = append(failures, mixedFailure{write: write, reason: reason})
+		}
+	}
+
+	hopLogger(posOfMaxVersion).Info("mixed version writes verified", "acknowledged", len(writes),
+		"rejected", rejected, "failed", len(failures))
+	if len(failures) > 0 {
+		return fmt.Errorf("%d of %d mixed version writes failed on %s:\n%s", len(failures), len(writes),
+			versions[posOfMaxVersion], strings.Join(groupMixedFailures(writes, failures), "\n"))
+	}
+	return nil
+}
+
+// checkMixedWrite returns why a stored object does not match its write, or
+// an empty string if it does
+func checkMixedWrite(write mixedWrite, status int, body []byte) string {
+	if status == http.StatusNotFound {
+		return "missing"
+	}
+	if status != http.StatusOK {
+		return fmt.Sprintf("status %d", status)
+	}
+
+	var object struct {
+		Properties struct {
+			AcceptedBy string  `json:"acceptedBy"`
+			Node       float64 `json:"node"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(body, &object); err != nil {
+		return err.Error()
+	}
+	if object.Properties.AcceptedBy != write.version || int(object.Properties.Node) != write.node {
+		return fmt.Sprintf("stored as accepted by %q via weaviate-%d", object.Properties.AcceptedBy,
+			int(object.Properties.Node))
+	}
+	return ""
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestCheckMixedWrite(t *testing.T) {
+	write := mixedWrite{id: "a", node: 1, version: "1.24.0"}
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   string
+	}{
+		{name: "stored", status: http.StatusOK, body: `{"properties":{"acceptedBy":"1.24.0","node":1}}`},
+		{name: "missing", status: http.StatusNotFound, want: "missing"},
+		{
+			name: "changed", status: http.StatusOK, body: `{"properties":{"acceptedBy":"1.25.0","node":1}}`,
+			want: `stored as accepted by "1.25.0" via weaviate-1`,
+		},
+		{name: "error", status: http.StatusInternalServerError, want: "status 500"},
+	}
+
+	for _, tt := range tests {
+		if got := checkMixedWrite(write, tt.status, []byte(tt.body)); got != tt.want {
+			t.Errorf("%s: wanted %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestGroupMixedFailures(t *testing.T) {
+	writes := []mixedWrite{
+		{id: "a", node: 0, version: "1.25.0"},
+		{id: "b", node: 1, version: "1.24.0"},
+		{id: "c", node: 2, version: "1.24.0"},
+		{id: "d", node: 2, version: "1.24.0"},
+	}
+	failures := []mixedFailure{
+		{write: writes[3], reason: "missing"},
+		{write: writes[0], reason: "status 500"},
+		{write: writes[1], reason: "missing"},
+	}
+
+	want := []string{
+		"accepted by 1.24.0: 2 of 3 writes failed:\n  d via weaviate-2: missing\n  b via weaviate-1: missing",
+		"accepted by 1.25.0: 1 of 1 writes failed:\n  a via weaviate-0: status 500",
+	}
+	if got := groupMixedFailures(writes, failures); !reflect.DeepEqual(got, want) {
+		t.Errorf("wanted %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// The module swap scenario changes the vectorizer settings of a class on
+// every hop. Whether a version allows that differs between versions, but a
+// version must not reject what an older one allowed, a rejection must be a
+// validation error that leaves the class as it was, and the vectors
+// vectorized with the old settings must stay as they are and searchable.
+var withVectorizerSwap bool
+
+const (
+	moduleSwapClass   = "ModuleSwap"
+	moduleSwapSetting = "vectorizeClassName"
+)
+
+type moduleSwapWorkload struct {
+	// vectors of the objects imported on the first hop, by id
+	vectors map[string][]float32
+
+	// setting is the value of the setting the class has
+	setting bool
+
+	// acceptedOn is the first version that accepted the change
+	acceptedOn string
+}
+
+var moduleSwap = &moduleSwapWorkload{vectors: map[string][]float32{}}
+
+func (w *moduleSwapWorkload) createClass(ctx context.Context) error {
+	if _, err := postRaw(ctx, 0, "/v1/schema", map[string]interface{}{
+		"class":      moduleSwapClass,
+		"vectorizer": "text2vec-contextionary",
+		"moduleConfig": map[string]interface{}{
+			"text2vec-contextionary": map[string]interface{}{moduleSwapSetting: w.setting},
+		},
+		"properties": []map[string]interface{}{
+			{"name": "text", "dataType": []string{"text"}},
+		},
+	}); err != nil {
+		return fmt.Errorf("create class %s: %w", moduleSwapClass, err)
+	}
+
+	for _, text := range relevanceTexts {
+		id, err := w.insert(ctx, text)
+		if err != nil {
+			return err
+		}
+		vector, err := objectVector(ctx, id)
+		if err != nil {
+			return err
+		}
+		w.vectors[id] = vector
+	}
+	return nil
+}
+
+func (w *moduleSwapWorkload) insert(ctx context.Context, text string) (string, error) {
+	id := uuid.New().String()
+	if _, err := postRaw(ctx, 0, "/v1/objects", map[string]interface{}{
+		"class":      moduleSwapClass,
+		"id":         id,
+		"properties": map[string]interface{}{"text": text},
+	}); err != nil {
+		return "", fmt.Errorf("import into %s: %w", moduleSwapClass, err)
+	}
+	return id, nil
+}
+
+// swapOutcome checks the answer to a change of the settings. It returns
+// whether the change was accepted.
+func swapOutcome(status int, version, acceptedOn string) (bool, error) {
+	switch {
+	case status >= 200 && status <= 299:
+		return true, nil
+	case status != http.StatusUnprocessableEntity:
+		return false, fmt.Errorf("changing the vectorizer settings answered %d instead of a validation error",
+			status)
+	case acceptedOn != "":
+		return false, fmt.Errorf("%s rejects changing the vectorizer settings, which %s accepted", version,
+			acceptedOn)
+	default:
+		return false, nil
+	}
+}
+
+// swap flips the setting on the class and checks that every node reports
+// the setting the class must have afterwards
+func (w *moduleSwapWorkload) swap(ctx context.Context, c *cluster, posOfVersion int) error {
+	version := versions[posOfVersion]
+	if posOfVersion == 0 {
+		if err := w.createClass(ctx); err != nil {
+			return err
+		}
+	}
+
+	body, err := getRaw(ctx, 0, "/v1/schema/"+moduleSwapClass)
+	if err != nil {
+		return fmt.Errorf("class %s: %w", moduleSwapClass, err)
+	}
+	var class map[string]interface{}
+	if err := json.Unmarshal(body, &class); err != nil {
+		return err
+	}
+	class["moduleConfig"] = map[string]interface{}{
+		"text2vec-contextionary": map[string]interface{}{moduleSwapSetting: !w.setting},
+	}
+
+	status, body, err := doRaw(ctx, http.MethodPut, 0, "/v1/schema/"+moduleSwapClass, class)
+	if err != nil {
+		return err
+	}
+	accepted, err := swapOutcome(status, version, w.acceptedOn)
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, body)
+	}
+	if accepted {
+		w.setting = !w.setting
+		if w.acceptedOn == "" {
+			w.acceptedOn = version
+		}
+	}
+	hopLogger(posOfVersion).Info("changed vectorizer settings", "class", moduleSwapClass, "accepted", accepted,
+		"status", status)
+
+	for i := 0; i < c.NodeCount; i++ {
+		body, err := getRaw(ctx, i, "/v1/schema/"+moduleSwapClass)
+		if err != nil {
+			return fmt.Errorf("class %s on %s: %w", moduleSwapClass, c.Hostname(i), err)
+		}
+		setting, err := vectorizerSetting(body)
+		if err != nil {
+			return fmt.Errorf("class %s on %s: %w", moduleSwapClass, c.Hostname(i), err)
+		}
+		if setting != w.setting {
+			return fmt.Errorf("%s of %s on %s is %t, want %t", moduleSwapSetting, moduleSwapClass,
+				c.Hostname(i), setting, w.setting)
+		}
+	}
+
+	// new objects are vectorized with the settings the class has now
+	if _, err := w.insert(ctx, fmt.Sprintf("Imported on %s after the settings changed", version)); err != nil {
+		return err
+	}
+	return w.checkVectors(ctx)
+}
+
+func vectorizerSetting(class []byte) (bool, error) {
+	var parsed struct {
+		ModuleConfig map[string]map[string]interface{} `json:"moduleConfig"`
+	}
+	if err := json.Unmarshal(class, &parsed); err != nil {
+		return false, err
+	}
+
+	setting, ok := parsed.ModuleConfig["text2vec-contextionary"][moduleSwapSetting].(bool)
+	if !ok {
+		return false, fmt.Errorf("class has no %s", moduleSwapSetting)
+	}
+	return setting, nil
+}
+
+// checkVectors checks that the objects of the first hop kept their vector
+// and are still found by it
+func (w *moduleSwapWorkload) checkVectors(ctx context.Context) error {
+	for id, want := range w.vectors {
+		got, err := objectVector(ctx, id)
+		if err != nil {
+			return err
+		}
+		if distance := cosineDistance(want, got); distance > vectorDriftTolerance {
+			return fmt.Errorf("vector of %s changed by a cosine distance of %g after the settings changed",
+				id, distance)
+		}
+
+		vector, _ := json.Marshal(got)
+		body, err := postRaw(ctx, 0, "/v1/graphql", map[string]interface{}{
+			"query": fmt.Sprintf("{Get{%s(nearVector:{vector:%s},limit:1){_additional{id}}}}", moduleSwapClass,
+				vector),
+		})
+		if err != nil {
+			return fmt.Errorf("search %s: %w", moduleSwapClass, err)
+		}
+
+		var res struct {
+			Data struct {
+				Get map[string][]struct {
+					Additional struct {
+						ID string `json:"id"`
+					} `json:"_additional"`
+				} `json:"Get"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(body, &res); err != nil {
+			return err
+		}
+		if hits := res.Data.Get[moduleSwapClass]; len(hits) == 0 || hits[0].Additional.ID != id {
+			return fmt.Errorf("searching %s by the vector of %s found %v", moduleSwapClass, id, hits)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+func Test_swapOutcome(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		acceptedOn   string
+		wantAccepted bool
+		wantErr      bool
+	}{
+		{name: "accepted", status: 200, wantAccepted: true},
+		{name: "accepted again", status: 200, acceptedOn: "1.24.0", wantAccepted: true},
+		{name: "rejected", status: 422},
+		{name: "rejected after it was accepted", status: 422, acceptedOn: "1.24.0", wantErr: true},
+		{name: "server error", status: 500, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accepted, err := swapOutcome(tt.status, "1.25.0", tt.acceptedOn)
+			if accepted != tt.wantAccepted || (err != nil) != tt.wantErr {
+				t.Errorf("swapOutcome() = %t, %v, want %t and an error: %t", accepted, err, tt.wantAccepted,
+					tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_vectorizerSetting(t *testing.T) {
+	got, err := vectorizerSetting([]byte(`{"moduleConfig":{"text2vec-contextionary":{"vectorizeClassName":true}}}`))
+	if err != nil || !got {
+		t.Errorf("vectorizerSetting() = %t, %v, want true", got, err)
+	}
+
+	if _, err := vectorizerSetting([]byte(`{"moduleConfig":{}}`)); err == nil {
+		t.Error("wanted an error for a class without the setting")
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+)
+
+const (
+	multiVectorClass  = "MultiVector"
+	multiVectorTarget = "colbert"
+	multiVectorDims   = 8
+
+	// documents written on every hop, every one with a different number
+	// of token vectors
+	multiVectorDocsPerHop = 4
+)
+
+// multiVectorTokens returns the token vectors of a document, one per token
+// like a ColBERT embedding. They are derived from the hop and the document,
+// so that the verifier does not need to keep them.
+func multiVectorTokens(hop, doc int) [][]float32 {
+	rng := rand.New(rand.NewSource(int64(hop*1000 + doc)))
+	tokens := make([][]float32, 2+doc)
+	for i := range tokens {
+		tokens[i] = make([]float32, multiVectorDims)
+		for j := range tokens[i] {
+			tokens[i][j] = rng.Float32()*2 - 1
+		}
+	}
+	return tokens
+}
+
+// multiVectorProbe writes documents with multi-vector embeddings on every
+// hop of a version that has them
+var multiVectorProbe probeImports
+
+// multiVectorVerifier checks that every document reads back with all of its
+// token vectors and that a late interaction search with the tokens of a
+// document finds that document first. Under MaxSim with cosine distance a
+// document matches its own tokens better than any other.
+type multiVectorVerifier struct{}
+
+func (v *multiVectorVerifier) name() string {
+	return "multi-vector"
+}
+
+func (v *multiVectorVerifier) verify(ctx context.Context, posOfMaxVersion int) error {
+	if !supports(versions[posOfMaxVersion], featureMultiVector) {
+		return nil
+	}
+
+	for _, hop := range multiVectorProbe.hops {
+		for doc := 0; doc < multiVectorDocsPerHop; doc++ {
+			id, want := probeID(multiVectorClass, hop, doc), multiVectorTokens(hop, doc)
+
+			got, err := storedMultiVector(ctx, id)
+			if err != nil {
+				return err
+			}
+			if err := diffMultiVector(want, got); err != nil {
+				return fmt.Errorf("document %d of hop %d: %w", doc, hop, err)
+			}
+
+			first, err := multiVectorSearch(ctx, want)
+			if err != nil {
+				return fmt.Errorf("search for document %d of hop %d: %w", doc, hop, err)
+			}
+			if first != id {
+				return fmt.Errorf("search with the tokens of document %d of hop %d found %q first, wanted %s",
+					doc, hop, first, id)
+			}
+		}
+	}
+	return nil
+}
+
+func createMultiVectorClass(ctx context.Context) error {
+	if _, err := postRaw(ctx, 0, "/v1/schema", map[string]interface{}{
+		"class": multiVectorClass,
+		"vectorConfig": map[string]interface{}{
+			multiVectorTarget: map[string]interface{}{
+				"vectorizer":      map[string]interface{}{"none": map[string]interface{}{}},
+				"vectorIndexType": "hnsw",
+				"vectorIndexConfig": map[string]interface{}{
+					"distance":    "cosine",
+					"multivector": map[string]interface{}{"enabled": true, "aggregation": "maxSim"},
+				},
+			},
+		},
+		"properties": []map[string]interface{}{
+			{"name": "hop", "dataType": []string{"int"}},
+			{"name": "doc", "dataType": []string{"int"}},
+		},
+	}); err != nil {
+		return fmt.Errorf("create class %s: %w", multiVectorClass, err)
+	}
+	return nil
+}
+
+func importMultiVectors(ctx context.Context, hop int) error {
+	var objects []map[string]interface{}
+	for doc := 0; doc < multiVectorDocsPerHop; doc++ {
+		objects = append(objects, map[string]interface{}{
+			"class":      multiVectorClass,
+			"id":         probeID(multiVectorClass, hop, doc),
+			"vectors":    map[string]interface{}{multiVectorTarget: multiVectorTokens(hop, doc)},
+			"properties": map[string]interface{}{"hop": hop, "doc": doc},
+		})
+	}
+	if _, err := postRaw(ctx, 0, "/v1/batch/objects", map[string]interface{}{"objects": objects}); err != nil {
+		return fmt.Errorf("import %s: %w", multiVectorClass, err)
+	}
+	return nil
+}
+
+// storedMultiVector returns the token vectors of a document as a node
+// returns them
+func storedMultiVector(ctx context.Context, id string) ([][]float32, error) {
+	body, err := getRaw(ctx, 0, fmt.Sprintf("/v1/objects/%s/%s?include=vector", multiVectorClass, id))
+	if err != nil {
+		return nil, err
+	}
+
+	var obj struct {
+		Vectors map[string][][]float32 `json:"vectors"`
+	}
+	if err := json.Unmarshal(body, &obj); err != nil {
+		return nil, err
+	}
+	return obj.Vectors[multiVectorTarget], nil
+}
+
+// diffMultiVector checks that a document kept every token vector it was
+// written with, in order
+func diffMultiVector(want, got [][]float32) error {
+	if len(got) != len(want) {
+		return fmt.Errorf("wanted %d token vectors, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if len(got[i]) != len(want[i]) {
+			return fmt.Errorf("token %d has %d dimensions, wanted %d", i, len(got[i]), len(want[i]))
+		}
+		for j := range want[i] {
+			if math.Abs(float64(got[i][j]-want[i][j])) > 1e-6 {
+				return fmt.Errorf("token %d is %v, wanted %v", i, got[i], want[i])
+			}
+		}
+	}
+	return nil
+}
+
+// multiVectorSearch runs a late interaction search with the tokens of a
+// query and returns the id of the first hit
+func multiVectorSearch(ctx context.Context, tokens [][]float32) (string, error) {
+	vectors := make([]string, len(tokens))
+	for i, token := range tokens {
+		raw, err := json.Marshal(token)
+		if err != nil {
+			return "", err
+		}
+		vectors[i] = string(raw)
+	}
+
+	body, err := postRaw(ctx, 0, "/v1/graphql", map[string]interface{}{
+		"query": fmt.Sprintf(`{Get{%s(nearVector:{vector:[%s],targetVectors:[%q]},limit:1){_additional{id}}}}`,
+			multiVectorClass, strings.Join(vectors, ","), multiVectorTarget),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var res struct {
+		Data struct {
+			Get map[string][]struct {
+				Additional struct {
+					ID string `json:"id"`
+				} `json:"_additional"`
+			} `json:"Get"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return "", err
+	}
+	if len(res.Errors) > 0 {
+		return "", fmt.Errorf("graphql: %s", res.Errors[0].Message)
+	}
+
+	hits := res.Data.Get[multiVectorClass]
+	if len(hits) == 0 {
+		return "", nil
+	}
+	return hits[0].Additional.ID, nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_multiVectorTokens(t *testing.T) {
+	tokens := multiVectorTokens(3, 2)
+	if len(tokens) != 4 {
+		t.Fatalf("wanted 4 token vectors, got %d", len(tokens))
+	}
+	if !reflect.DeepEqual(tokens, multiVectorTokens(3, 2)) {
+		t.Errorf("token vectors of the same document differ")
+	}
+	if reflect.DeepEqual(tokens[:2], multiVectorTokens(4, 0)) {
+		t.Errorf("token vectors of different documents are the same")
+	}
+}
+
+func Test_diffMultiVector(t *testing.T) {
+	want := [][]float32{{0.25, -0.5}, {1, 0}}
+	tests := []struct {
+		name    string
+		got     [][]float32
+		wantErr bool
+	}{
+		{name: "same", got: [][]float32{{0.25, -0.5}, {1, 0}}},
+		{name: "lost a token", got: [][]float32{{0.25, -0.5}}, wantErr: true},
+		{name: "single vector", got: [][]float32{{0.25, -0.5, 1, 0}}, wantErr: true},
+		{name: "reordered", got: [][]float32{{1, 0}, {0.25, -0.5}}, wantErr: true},
+		{name: "no vectors", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := diffMultiVector(want, tt.got)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("diffMultiVector() err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
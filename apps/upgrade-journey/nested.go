@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+const (
+	nestedClass = "NestedObjects"
+
+	// objects with nested properties written on every hop
+	nestedObjectsPerHop = 3
+)
+
+// nestedProperties is the schema of the class: an object with an object
+// inside and an array of objects, with the scalar and array types a nested
+// property can have
+var nestedProperties = []map[string]interface{}{
+	{
+		"name":     "profile",
+		"dataType": []string{"object"},
+		"nestedProperties": []map[string]interface{}{
+			{"name": "name", "dataType": []string{"text"}},
+			{"name": "age", "dataType": []string{"int"}},
+			{"name": "tags", "dataType": []string{"text[]"}},
+			{
+				"name":     "address",
+				"dataType": []string{"object"},
+				"nestedProperties": []map[string]interface{}{
+					{"name": "city", "dataType": []string{"text"}},
+					{"name": "zip", "dataType": []string{"text"}},
+				},
+			},
+		},
+	},
+	{
+		"name":     "events",
+		"dataType": []string{"object[]"},
+		"nestedProperties": []map[string]interface{}{
+			{"name": "kind", "dataType": []string{"text"}},
+			{"name": "at", "dataType": []string{"date"}},
+			{"name": "score", "dataType": []string{"number"}},
+			{"name": "flags", "dataType": []string{"boolean[]"}},
+		},
+	},
+}
+
+// nestedQuery selects every nested field of the class
+const nestedQuery = `{Get{%s(limit:10000){profile{name age tags address{city zip}} ` +
+	`events{kind at score flags} _additional{id}}}}`
+
+// dayDate is a date that only depends on the day, in the format nodes
+// return dates in
+func dayDate(day int) string {
+	return time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, day).Format(time.RFC3339)
+}
+
+// nestedObject returns the properties of an object of a hop
+func nestedObject(hop, i int) map[string]interface{} {
+	return map[string]interface{}{
+		"profile": map[string]interface{}{
+			"name": fmt.Sprintf("user %d-%d", hop, i),
+			"age":  20 + hop + i,
+			"tags": []string{fmt.Sprintf("hop%d", hop), "nested"},
+			"address": map[string]interface{}{
+				"city": fmt.Sprintf("City %d", i),
+				"zip":  fmt.Sprintf("%05d", hop*100+i),
+			},
+		},
+		"events": []map[string]interface{}{
+			{"kind": "created", "at": dayDate(hop), "score": 0.5 + float64(i), "flags": []bool{true, false}},
+			{"kind": "updated", "at": dayDate(hop + 1), "score": 1.25, "flags": []bool{false}},
+		},
+	}
+}
+
+// nestedProbe writes objects with nested properties on every hop of a
+// version that has them
+var nestedProbe probeImports
+
+// nestedVerifier checks that both REST and GraphQL return every nested
+// field of the objects of all hops as written
+type nestedVerifier struct{}
+
+func (v *nestedVerifier) name() string {
+	return "nested objects"
+}
+
+func (v *nestedVerifier) verify(ctx context.Context, posOfMaxVersion int) error {
+	if !supports(versions[posOfMaxVersion], featureNestedObjects) {
+		return nil
+	}
+
+	written, err := nestedWritten(nestedProbe.hops)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range sortedMapKeys(written) {
+		body, err := getRaw(ctx, 0, fmt.Sprintf("/v1/objects/%s/%s", nestedClass, id))
+		if err != nil {
+			return err
+		}
+		var obj struct {
+			Properties map[string]interface{} `json:"properties"`
+		}
+		if err := json.Unmarshal(body, &obj); err != nil {
+			return err
+		}
+		if diff := diffNested("", written[id], obj.Properties); diff != "" {
+			return fmt.Errorf("REST object %s: %s", id, diff)
+		}
+	}
+
+	body, err := postRaw(ctx, 0, "/v1/graphql", map[string]interface{}{
+		"query": fmt.Sprintf(nestedQuery, nestedClass),
+	})
+	if err != nil {
+		return err
+	}
+	got, err := parseNestedGet(body)
+	if err != nil {
+		return err
+	}
+	if len(got) != len(written) {
+		return fmt.Errorf("GraphQL returned %d objects of %s, wanted %d", len(got), nestedClass, len(written))
+	}
+	for _, id := range sortedMapKeys(written) {
+		if diff := diffNested("", written[id], got[id]); diff != "" {
+			return fmt.Errorf("GraphQL object %s: %s", id, diff)
+		}
+	}
+	return nil
+}
+
+func createNestedClass(ctx context.Context) error {
+	if _, err := postRaw(ctx, 0, "/v1/schema", map[string]interface{}{
+		"class":      nestedClass,
+		"vectorizer": "none",
+		"properties": nestedProperties,
+	}); err != nil {
+		return fmt.Errorf("create class %s: %w", nestedClass, err)
+	}
+	return nil
+}
+
+func importNested(ctx context.Context, hop int) error {
+	var objects []map[string]interface{}
+	for i := 0; i < nestedObjectsPerHop; i++ {
+		objects = append(objects, map[string]interface{}{
+			"class":      nestedClass,
+			"id":         probeID(nestedClass, hop, i),
+			"vector":     []float32{float32(i), float32(hop), 1},
+			"properties": nestedObject(hop, i),
+		})
+	}
+	if _, err := postRaw(ctx, 0, "/v1/batch/objects", map[string]interface{}{"objects": objects}); err != nil {
+		return fmt.Errorf("import %s: %w", nestedClass, err)
+	}
+	return nil
+}
+
+// nestedWritten returns the properties of every object the hops wrote by
+// id, in the types JSON decodes to, numbers become float64
+func nestedWritten(hops []int) (map[string]interface{}, error) {
+	written := map[string]interface{}{}
+	for _, hop := range hops {
+		for i := 0; i < nestedObjectsPerHop; i++ {
+			raw, err := json.Marshal(nestedObject(hop, i))
+			if err != nil {
+				return nil, err
+			}
+			var properties interface{}
+			if err := json.Unmarshal(raw, &properties); err != nil {
+				return nil, err
+			}
+			written[probeID(nestedClass, hop, i)] = properties
+		}
+	}
+	return written, nil
+}
+
+// parseNestedGet returns the nested properties of every object of a Get
+// query by id
+func parseNestedGet(body []byte) (map[string]interface{}, error) {
+	var res struct {
+		Data struct {
+			Get map[string][]map[string]interface{} `json:"Get"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, err
+	}
+	if len(res.Errors) > 0 {
+		return nil, fmt.Errorf("graphql: %s", res.Errors[0].Message)
+	}
+
+	objects := map[string]interface{}{}
+	for _, hit := range res.Data.Get[nestedClass] {
+		additional, _ := hit["_additional"].(map[string]interface{})
+		id, _ := additional["id"].(string)
+		delete(hit, "_additional")
+		objects[id] = hit
+	}
+	return objects, nil
+}
+
+// diffNested describes the first difference between two decoded JSON
+// values by its path, an empty string if they are the same
+func diffNested(path string, want, got interface{}) string {
+	switch w := want.(type) {
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok {
+			return fmt.Sprintf("%s: wanted an object, got %v", pathOrRoot(path), got)
+		}
+		keys := map[string]bool{}
+		for k := range w {
+			keys[k] = true
+		}
+		for k := range g {
+			keys[k] = true
+		}
+		for _, k := range sortedMapKeys(keys) {
+			if diff := diffNested(path+"."+k, w[k], g[k]); diff != "" {
+				return diff
+			}
+		}
+		return ""
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok || len(g) != len(w) {
+			return fmt.Sprintf("%s: wanted %v, got %v", pathOrRoot(path), want, got)
+		}
+		for i := range w {
+			if diff := diffNested(fmt.Sprintf("%s[%d]", path, i), w[i], g[i]); diff != "" {
+				return diff
+			}
+		}
+		return ""
+	default:
+		if !reflect.DeepEqual(want, got) {
+			return fmt.Sprintf("%s: wanted %v, got %v", pathOrRoot(path), want, got)
+		}
+		return ""
+	}
+}
+
+func pathOrRoot(path string) string {
+	if path == "" {
+		return "properties"
+	}
+	return path[1:]
+}
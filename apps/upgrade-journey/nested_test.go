@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func Test_diffNested(t *testing.T) {
+	decode := func(raw string) interface{} {
+		var v interface{}
+		if err := json.Unmarshal([]byte(raw), &v); err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	want := decode(`{"profile":{"age":21,"address":{"city":"a"}},"events":[{"flags":[true,false]}]}`)
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{
+			name: "same",
+			got:  `{"profile":{"address":{"city":"a"},"age":21},"events":[{"flags":[true,false]}]}`,
+		},
+		{
+			name: "nested value",
+			got:  `{"profile":{"age":21,"address":{"city":"b"}},"events":[{"flags":[true,false]}]}`,
+			want: "profile.address.city: wanted a, got b",
+		},
+		{
+			name: "array element",
+			got:  `{"profile":{"age":21,"address":{"city":"a"}},"events":[{"flags":[true,true]}]}`,
+			want: "events[0].flags[1]: wanted false, got true",
+		},
+		{
+			name: "flattened object",
+			got:  `{"profile":"{\"age\":21}","events":[{"flags":[true,false]}]}`,
+			want: `profile: wanted an object, got {"age":21}`,
+		},
+		{
+			name: "missing field",
+			got:  `{"profile":{"address":{"city":"a"}},"events":[{"flags":[true,false]}]}`,
+			want: "profile.age: wanted 21, got <nil>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffNested("", want, decode(tt.got)); got != tt.want {
+				t.Errorf("wanted %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const nullValuesClass = "NullValues"
+
+// nullVariants are the ways an object can lack a value, every hop writes
+// one object of each. The properties of "missing" are left out entirely.
+var nullVariants = []struct {
+	name       string
+	properties map[string]interface{}
+}{
+	{name: "missing", properties: map[string]interface{}{}},
+	{name: "null", properties: map[string]interface{}{"text": nil, "number": nil}},
+	{name: "empty", properties: map[string]interface{}{"text": "", "number": 0}},
+	{name: "set", properties: map[string]interface{}{"text": "value", "number": 7}},
+}
+
+// nullFilter is a filter on the null state or the length of a property.
+// The variants in match must be found, the ones in miss must not. The
+// others, such as whether an empty string counts as null, are up to the
+// first version and must not change on a later one.
+type nullFilter struct {
+	name  string
+	where string
+	match []string
+	miss  []string
+}
+
+var nullFilters = []nullFilter{
+	{
+		name:  "text is null",
+		where: `{path:["text"],operator:IsNull,valueBoolean:true}`,
+		match: []string{"missing", "null"},
+		miss:  []string{"set"},
+	},
+	{
+		name:  "text is not null",
+		where: `{path:["text"],operator:IsNull,valueBoolean:false}`,
+		match: []string{"set"},
+		miss:  []string{"missing", "null"},
+	},
+	{
+		name:  "number is null",
+		where: `{path:["number"],operator:IsNull,valueBoolean:true}`,
+		match: []string{"missing", "null"},
+		miss:  []string{"empty", "set"},
+	},
+	{
+		name:  "number is not null",
+		where: `{path:["number"],operator:IsNull,valueBoolean:false}`,
+		match: []string{"empty", "set"},
+		miss:  []string{"missing", "null"},
+	},
+	{
+		name:  "number is 0",
+		where: `{path:["number"],operator:Equal,valueInt:0}`,
+		match: []string{"empty"},
+		miss:  []string{"missing", "null", "set"},
+	},
+	{
+		name:  "text has length 5",
+		where: `{path:["len(text)"],operator:Equal,valueInt:5}`,
+		match: []string{"set"},
+		miss:  []string{"missing", "null", "empty"},
+	},
+	{
+		name:  "text is shorter than 5",
+		where: `{path:["len(text)"],operator:LessThan,valueInt:5}`,
+		miss:  []string{"set"},
+	},
+}
+
+// nullValuesProbe writes objects with missing, null and empty values on
+// every hop of a version that has null state filters
+var nullValuesProbe probeImports
+
+// nullValuesVerifier checks that null state and length filters find the
+// objects of all hops alike
+type nullValuesVerifier struct {
+	// baseline holds the variants every filter matched on the first
+	// verification
+	baseline map[string][]string
+}
+
+func (v *nullValuesVerifier) name() string {
+	return "null values"
+}
+
+func (v *nullValuesVerifier) verify(ctx context.Context, posOfMaxVersion int) error {
+	if !supports(versions[posOfMaxVersion], featureNullState) {
+		return nil
+	}
+
+	matches := map[string][]string{}
+	for _, filter := range nullFilters {
+		counts, err := variantCounts(ctx, nullValuesClass, filter.where)
+		if err != nil {
+			return fmt.Errorf("%s: %w", filter.name, err)
+		}
+		matched, err := checkNullFilter(filter, counts, nullValuesProbe.rounds())
+		if err != nil {
+			return err
+		}
+		matches[filter.name] = matched
+	}
+
+	if v.baseline == nil {
+		v.baseline = matches
+		return nil
+	}
+	for _, filter := range nullFilters {
+		want, got := v.baseline[filter.name], matches[filter.name]
+		if strings.Join(want, ",") != strings.Join(got, ",") {
+			return fmt.Errorf("filter %s matched %v before, now %v", filter.name, want, got)
+		}
+	}
+	return nil
+}
+
+// checkNullFilter checks the number of objects of every variant a filter
+// matched and returns the matched variants. A variant is matched on all
+// rounds or on none, anything in between means that the objects of some
+// hops were indexed differently.
+func checkNullFilter(filter nullFilter, counts map[string]int, rounds int) ([]string, error) {
+	var matched []string
+	for _, variant := range nullVariants {
+		switch counts[variant.name] {
+		case 0:
+		case rounds:
+			matched = append(matched, variant.name)
+		default:
+			return nil, fmt.Errorf("filter %s matched %d of the %d %q objects", filter.name,
+				counts[variant.name], rounds, variant.name)
+		}
+	}
+
+	for _, name := range filter.match {
+		if counts[name] != rounds {
+			return nil, fmt.Errorf("filter %s did not match the %q objects", filter.name, name)
+		}
+	}
+	for _, name := range filter.miss {
+		if counts[name] != 0 {
+			return nil, fmt.Errorf("filter %s matched the %q objects", filter.name, name)
+		}
+	}
+	return matched, nil
+}
+
+func createNullValuesClass(ctx context.Context) error {
+	if _, err := postRaw(ctx, 0, "/v1/schema", map[string]interface{}{
+		"class":      nullValuesClass,
+		"vectorizer": "none",
+		"invertedIndexConfig": map[string]interface{}{
+			"indexNullState":      true,
+			"indexPropertyLength": true,
+		},
+		"properties": []map[string]interface{}{
+			{"name": "variant", "dataType": []string{"text"}},
+			{"name": "text", "dataType": []string{"text"}},
+			{"name": "number", "dataType": []string{"int"}},
+		},
+	}); err != nil {
+		return fmt.Errorf("create class %s: %w", nullValuesClass, err)
+	}
+	return nil
+}
+
+func importNullValues(ctx context.Context, hop int) error {
+	var objects []map[string]interface{}
+	for i, variant := range nullVariants {
+		properties := map[string]interface{}{"variant": variant.name}
+		for k, v := range variant.properties {
+			properties[k] = v
+		}
+
+		objects = append(objects, map[string]interface{}{
+			"class":      nullValuesClass,
+			"id":         probeID(nullValuesClass, hop, i),
+			"vector":     []float32{float32(i), float32(hop), 1},
+			"properties": properties,
+		})
+	}
+	if _, err := postRaw(ctx, 0, "/v1/batch/objects", map[string]interface{}{"objects": objects}); err != nil {
+		return fmt.Errorf("import %s: %w", nullValuesClass, err)
+	}
+	return nil
+}
+
+// variantCounts returns how many objects of every variant of a class match
+// the filter, by their variant property
+func variantCounts(ctx context.Context, class, where string) (map[string]int, error) {
+	body, err := postRaw(ctx, 0, "/v1/graphql", map[string]interface{}{
+		"query": fmt.Sprintf(`{Get{%s(where:%s,limit:10000){variant}}}`, class, where),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var res struct {
+		Data struct {
+			Get map[string][]struct {
+				Variant string `json:"variant"`
+			} `json:"Get"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, err
+	}
+	if len(res.Errors) > 0 {
+		return nil, fmt.Errorf("graphql: %s", res.Errors[0].Message)
+	}
+
+	counts := map[string]int{}
+	for _, hit := range res.Data.Get[class] {
+		counts[hit.Variant]++
+	}
+	return counts, nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_checkNullFilter(t *testing.T) {
+	filter := nullFilters[0] // text is null
+	tests := []struct {
+		name    string
+		counts  map[string]int
+		want    []string
+		wantErr bool
+	}{
+		{name: "empty is not null", counts: map[string]int{"missing": 3, "null": 3}, want: []string{"missing", "null"}},
+		{
+			name:   "empty is null",
+			counts: map[string]int{"missing": 3, "null": 3, "empty": 3},
+			want:   []string{"missing", "null", "empty"},
+		},
+		{name: "older hops lost their null state", counts: map[string]int{"missing": 1, "null": 3}, wantErr: true},
+		{name: "null not found", counts: map[string]int{"missing": 3}, wantErr: true},
+		{name: "set matched", counts: map[string]int{"missing": 3, "null": 3, "set": 3}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := checkNullFilter(filter, tt.counts, 3)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkNullFilter() err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("wanted %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
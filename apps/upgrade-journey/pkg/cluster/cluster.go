@@ -0,0 +1,673 @@
+// Package cluster runs a multi-node Weaviate cluster in Docker containers,
+// one container per node with its data directory bind-mounted from the
+// host, so that nodes can be stopped, upgraded and started again on the same
+// data.
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/nat"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"golang.org/x/exp/slog"
+)
+
+// profilingPort and metricsPort are the host ports of the pprof and the
+// Prometheus endpoints of node 0, Weaviate serves them on 6060 and 2112
+// inside the container
+const (
+	profilingPort = 16060
+	metricsPort   = 12112
+)
+
+// Options configure a cluster. Only NodeCount and Image are required.
+type Options struct {
+	NodeCount int
+
+	// Image returns the image to run for a version
+	Image func(version string) string
+
+	// Platform of the images, e.g. "linux/arm64", defaults to the one the
+	// Docker daemon picks
+	Platform string
+
+	// RootDir holds the data directories under data/, defaults to the
+	// working directory
+	RootDir string
+
+	// RunID tells the Docker objects of concurrent runs apart, it is part of
+	// the network and container names and set as a label. Defaults to a
+	// random id.
+	RunID string
+
+	// Labels are added to the labels of every Docker object of the cluster,
+	// e.g. the scenario
+	Labels map[string]string
+
+	// NetworkName defaults to a name derived from the run id
+	NetworkName string
+
+	// Env returns variables that are added to, or override, the default
+	// environment of a node
+	Env func(nodeId int, version string) map[string]string
+
+	// WaitFor is waited for after the node reports ready, e.g. for log lines
+	WaitFor func(nodeId int, version string) wait.Strategy
+
+	// OnStart is called whenever a node became ready, with the time it took
+	// from creating its container
+	OnStart func(nodeId int, version string, took time.Duration)
+
+	// AfterRestart runs after every node of a rolling update came back and
+	// has to succeed before the next node goes down
+	AfterRestart func(ctx context.Context, nodeId int, version string) error
+
+	// Host returns the address of the REST API of a node as seen from this
+	// process, defaults to localhost:8080+PortOffset+nodeId
+	Host func(nodeId int) string
+
+	// PortOffset shifts every host port of the nodes, so that runs on the
+	// same host don't collide
+	PortOffset int
+
+	// Topology defaults to a single zone in which every node votes
+	Topology Topology
+
+	// Resources limit every node, the zero value leaves them unlimited
+	Resources Resources
+
+	// Profiling exposes the pprof endpoints of every node, see ProfilingHost
+	Profiling bool
+
+	// Metrics enables and exposes the Prometheus endpoint of every node, see
+	// MetricsHost
+	Metrics bool
+
+	// UpdateOrder returns the order in which a rolling update restarts the
+	// nodes, defaults to ascending node ids
+	UpdateOrder func(ctx context.Context, version string) ([]int, error)
+
+	Logger          *slog.Logger
+	ContainerLogger testcontainers.Logging
+}
+
+// Resources are the limits of a single node
+type Resources struct {
+	CPUs        float64
+	MemoryBytes int64
+}
+
+// Cluster is a set of Weaviate nodes on a shared Docker network. Nodes are
+// addressed by their id, from 0 to NodeCount-1.
+type Cluster struct {
+	opts Options
+
+	NodeCount   int
+	RunID       string
+	NetworkName string
+	RootDir     string
+	Containers  []testcontainers.Container
+
+	// NodeVersions is the version every node was last started with, so
+	// that a fault can bring a node back on the same version
+	NodeVersions []string
+
+	// NanoCPUs limits the CPU of a node when it is non-zero. It is applied
+	// whenever the node is started, so that a throttled node stays throttled
+	// across a rolling update.
+	NanoCPUs []int64
+
+	// started numbers the containers, nodes may be started concurrently
+	started atomic.Int64
+
+	network  testcontainers.Network
+	sidecars []testcontainers.Container
+}
+
+func New(opts Options) (*Cluster, error) {
+	if opts.NodeCount < 1 {
+		return nil, fmt.Errorf("node count must be at least 1")
+	}
+	if opts.Image == nil {
+		return nil, fmt.Errorf("image is required")
+	}
+	if err := opts.Topology.Validate(opts.NodeCount); err != nil {
+		return nil, err
+	}
+
+	if opts.RootDir == "" {
+		rootDir, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("cannot determine working directory: %w", err)
+		}
+		opts.RootDir = rootDir
+	}
+	if opts.RunID == "" {
+		opts.RunID = NewRunID()
+	}
+	if opts.NetworkName == "" {
+		opts.NetworkName = fmt.Sprintf("weaviate-upgrade-journey-%s", opts.RunID)
+	}
+	if opts.Host == nil {
+		opts.Host = func(nodeId int) string {
+			return fmt.Sprintf("localhost:%d", 8080+opts.PortOffset+nodeId)
+		}
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+
+	c := &Cluster{
+		opts:        opts,
+		NodeCount:   opts.NodeCount,
+		RunID:       opts.RunID,
+		NetworkName: opts.NetworkName,
+		RootDir:     opts.RootDir,
+		Containers:  make([]testcontainers.Container, opts.NodeCount),
+
+		NodeVersions: make([]string, opts.NodeCount),
+		NanoCPUs:     make([]int64, opts.NodeCount),
+	}
+	for i := range c.NanoCPUs {
+		c.NanoCPUs[i] = c.BaseNanoCPUs()
+	}
+	return c, nil
+}
+
+// NewRunID returns a random id for a run
+func NewRunID() string {
+	return fmt.Sprintf("%08x", rand.Uint32())
+}
+
+// BaseNanoCPUs is the CPU limit of a node without any fault, 0 if the
+// nodes are not limited
+func (c *Cluster) BaseNanoCPUs() int64 {
+	return int64(c.opts.Resources.CPUs * 1e9)
+}
+
+// Host is the address of the REST API of a node
+func (c *Cluster) Host(nodeId int) string {
+	return c.opts.Host(nodeId)
+}
+
+func (c *Cluster) StartNetwork(ctx context.Context) error {
+	network, err := testcontainers.GenericNetwork(ctx, testcontainers.GenericNetworkRequest{
+		NetworkRequest: testcontainers.NetworkRequest{
+			Name:     c.NetworkName,
+			Internal: false,
+			Labels:   c.Labels(),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("network %s: %w", c.NetworkName, err)
+	}
+	c.network = network
+
+	return nil
+}
+
+func (c *Cluster) StartAllNodes(ctx context.Context, version string) error {
+	for i := 0; i < c.NodeCount; i++ {
+		container, err := c.StartNode(ctx, i, version)
+		if container != nil {
+			c.Containers[i] = container
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RollingUpdate restarts the nodes one by one on the version
+func (c *Cluster) RollingUpdate(ctx context.Context, version string) error {
+	order := make([]int, c.NodeCount)
+	for i := range order {
+		order[i] = i
+	}
+	if c.opts.UpdateOrder != nil {
+		var err error
+		if order, err = c.opts.UpdateOrder(ctx, version); err != nil {
+			return fmt.Errorf("rolling update order: %w", err)
+		}
+	}
+
+	c.opts.Logger.Info("starting rolling update", "version", version, "order", order)
+	for _, i := range order {
+		if err := c.StopNode(ctx, i); err != nil {
+			return err
+		}
+
+		if err := c.StartStoppedNode(ctx, i, version); err != nil {
+			c.opts.Logger.Error("node did not start", "node", c.Hostname(i), "version", version, "err", err)
+			return err
+		}
+
+		if c.opts.AfterRestart != nil {
+			if err := c.opts.AfterRestart(ctx, i, version); err != nil {
+				return err
+			}
+		}
+	}
+
+	c.opts.Logger.Info("completed rolling update", "version", version)
+	return nil
+}
+
+// RestartNode stops a single node and starts it again on the same version
+// and with the same volume, so that all state comes from disk
+func (c *Cluster) RestartNode(ctx context.Context, nodeId int, version string) error {
+	if err := c.Containers[nodeId].Terminate(ctx); err != nil {
+		return err
+	}
+
+	return c.StartStoppedNode(ctx, nodeId, version)
+}
+
+func (c *Cluster) StopNode(ctx context.Context, nodeId int) error {
+	return c.Containers[nodeId].Terminate(ctx)
+}
+
+// StartStoppedNode starts the node again and replaces its container in
+// Containers. A container that was created but did not become ready
+// replaces it as well, so that Teardown removes it and later faults act on
+// the container the node now runs in rather than on the stopped one.
+func (c *Cluster) StartStoppedNode(ctx context.Context, nodeId int, version string) error {
+	container, err := c.StartNode(ctx, nodeId, version)
+	if err != nil {
+		if container != nil {
+			c.DumpContainerLogs(container)
+			c.Containers[nodeId] = container
+		}
+		return err
+	}
+
+	c.Containers[nodeId] = container
+	return nil
+}
+
+// StartNode starts a node and waits until it is ready. On failure the
+// container is returned if it was created, so that its state and logs can
+// be inspected. It does not replace the container of the node in
+// Containers.
+func (c *Cluster) StartNode(ctx context.Context, nodeId int, version string) (testcontainers.Container, error) {
+	if err := os.MkdirAll(c.VolumePath(nodeId), 0o777); err != nil {
+		return nil, err
+	}
+
+	env := c.NodeEnv(nodeId, version)
+
+	waitFor := []wait.Strategy{
+		wait.
+			ForHTTP("/v1/.well-known/ready").
+			WithPort(nat.Port("8080")).
+			WithStatusCodeMatcher(func(status int) bool {
+				return status >= 200 && status <= 299
+			}).
+			WithStartupTimeout(30 * time.Second),
+	}
+	if c.opts.WaitFor != nil {
+		waitFor = append(waitFor, c.opts.WaitFor(nodeId, version))
+	}
+
+	start := time.Now()
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		Logger: c.opts.ContainerLogger,
+		ContainerRequest: testcontainers.ContainerRequest{
+			Name:          fmt.Sprintf("%s-%s-%d", c.Hostname(nodeId), c.RunID, c.started.Add(1)-1),
+			Image:         c.opts.Image(version),
+			ImagePlatform: c.opts.Platform,
+			Cmd:           []string{"--host", "0.0.0.0", "--port", "8080", "--scheme", "http"},
+			Networks:      []string{c.NetworkName},
+			NetworkAliases: map[string][]string{
+				c.NetworkName: {c.Hostname(nodeId)},
+			},
+			ExposedPorts: c.exposedPorts(nodeId),
+			Labels:       c.nodeLabels(nodeId, version),
+			AutoRemove:   false,
+			Env:          env,
+			Mounts: testcontainers.Mounts(testcontainers.BindMount(
+				c.VolumePath(nodeId), "/var/lib/weaviate",
+			)),
+			HostConfigModifier: func(hc *container.HostConfig) {
+				hc.NanoCPUs = c.NanoCPUs[nodeId]
+				hc.Memory = c.opts.Resources.MemoryBytes
+			},
+			WaitingFor: wait.ForAll(waitFor...),
+		},
+		Started: true,
+	})
+	if err != nil {
+		return container, err
+	}
+
+	c.NodeVersions[nodeId] = version
+	if c.opts.OnStart != nil {
+		c.opts.OnStart(nodeId, version, time.Since(start))
+	}
+
+	return container, nil
+}
+
+// NodeEnv returns the environment a node is started with on a version
+func (c *Cluster) NodeEnv(nodeId int, version string) map[string]string {
+	env := map[string]string{
+		"QUERY_DEFAULTS_LIMIT":                    "25",
+		"AUTHENTICATION_ANONYMOUS_ACCESS_ENABLED": "true",
+		"PERSISTENCE_DATA_PATH":                   "/var/lib/weaviate",
+		"DEFAULT_VECTORIZER_MODULE":               "none",
+		"CLUSTER_GOSSIP_BIND_PORT":                "7100",
+		"CLUSTER_DATA_BIND_PORT":                  "7101",
+		"CLUSTER_HOSTNAME":                        c.Hostname(nodeId),
+		"CLUSTER_JOIN":                            c.allNodes(),
+		"RAFT_JOIN":                               c.voterHostnames(),
+		"RAFT_BOOTSTRAP_EXPECT":                   fmt.Sprintf("%d", c.Quorum()),
+		"PERSISTENCE_LSM_ACCESS_STRATEGY":         os.Getenv("PERSISTENCE_LSM_ACCESS_STRATEGY"),
+	}
+	if c.opts.Metrics {
+		env["PROMETHEUS_MONITORING_ENABLED"] = "true"
+	}
+	if c.opts.Env != nil {
+		for key, value := range c.opts.Env(nodeId, version) {
+			env[key] = value
+		}
+	}
+	for key, value := range c.opts.Topology.Env[nodeId] {
+		env[key] = value
+	}
+	return env
+}
+
+func (c *Cluster) VolumePath(nodeId int) string {
+	return path.Join(c.RootDir, "data/", c.Hostname(nodeId))
+}
+
+// ProfilingHost is the address of the pprof endpoints of a node as seen
+// from this process, if Options.Profiling is set
+func (c *Cluster) ProfilingHost(nodeId int) string {
+	return fmt.Sprintf("localhost:%d", profilingPort+c.opts.PortOffset+nodeId)
+}
+
+func (c *Cluster) exposedPorts(nodeId int) []string {
+	offset := c.opts.PortOffset
+	ports := []string{fmt.Sprintf("%d:8080", 8080+offset+nodeId)}
+	if c.opts.Profiling {
+		ports = append(ports, fmt.Sprintf("%d:6060", profilingPort+offset+nodeId))
+	}
+	if c.opts.Metrics {
+		ports = append(ports, fmt.Sprintf("%d:2112", metricsPort+offset+nodeId))
+	}
+	return ports
+}
+
+// MetricsHost is the address of the Prometheus endpoint of a node as seen
+// from this process, if Options.Metrics is set
+func (c *Cluster) MetricsHost(nodeId int) string {
+	return fmt.Sprintf("localhost:%d", metricsPort+c.opts.PortOffset+nodeId)
+}
+
+func (c *Cluster) Hostname(nodeId int) string {
+	return fmt.Sprintf("weaviate-%d", nodeId)
+}
+
+// Quorum is the number of voters that need to be present to bootstrap RAFT.
+// Expecting all voters would block the bootstrap whenever a single voter is
+// down while the cluster is migrated to RAFT.
+func (c *Cluster) Quorum() int {
+	return len(c.Voters())/2 + 1
+}
+
+// voterHostnames is the list of raft voters, the other nodes join as
+// non-voters. Versions before v1.25 ignore the RAFT_* variables, so they can
+// be set unconditionally.
+func (c *Cluster) voterHostnames() string {
+	hosts := []string{}
+	for _, i := range c.Voters() {
+		hosts = append(hosts, c.Hostname(i))
+	}
+
+	return strings.Join(hosts, ",")
+}
+
+func (c *Cluster) allNodes() string {
+	hosts := []string{}
+	for i := 0; i < c.NodeCount; i++ {
+		hosts = append(hosts, fmt.Sprintf("%s:7100", c.Hostname(i)))
+	}
+
+	return strings.Join(hosts, ",")
+}
+
+// WipeNodePath removes a path relative to the data directory of a node. The
+// files are owned by the user inside the Weaviate container, which is not
+// necessarily the user running this test, so the removal happens in a
+// short-lived helper container that mounts the same volume. The node must be
+// stopped.
+func (c *Cluster) WipeNodePath(ctx context.Context, nodeId int, relPath string) error {
+	target := path.Join("/data", relPath)
+	_, err := RunToCompletion(ctx, testcontainers.ContainerRequest{
+		Image:  "alpine:3",
+		Cmd:    []string{"rm", "-rf", target},
+		Mounts: testcontainers.Mounts(testcontainers.BindMount(c.VolumePath(nodeId), "/data")),
+		Labels: c.Labels(),
+	})
+	if err != nil {
+		return fmt.Errorf("wipe %s on node %d: %w", relPath, nodeId, err)
+	}
+
+	return nil
+}
+
+// WipeNodeData empties the entire data directory of a stopped node
+func (c *Cluster) WipeNodeData(ctx context.Context, nodeId int) error {
+	_, err := RunToCompletion(ctx, testcontainers.ContainerRequest{
+		Image:  "alpine:3",
+		Cmd:    []string{"find", "/data", "-mindepth", "1", "-delete"},
+		Mounts: testcontainers.Mounts(testcontainers.BindMount(c.VolumePath(nodeId), "/data")),
+		Labels: c.Labels(),
+	})
+	if err != nil {
+		return fmt.Errorf("wipe data of node %d: %w", nodeId, err)
+	}
+
+	return nil
+}
+
+// AddSidecar hands a helper container on the cluster network, such as a
+// proxy, over to the cluster, so that Teardown removes it with the nodes
+func (c *Cluster) AddSidecar(container testcontainers.Container) {
+	c.sidecars = append(c.sidecars, container)
+}
+
+// SaveLogs writes the log of every node that still has a container to
+// dir/<hostname>.log
+func (c *Cluster) SaveLogs(ctx context.Context, dir string) error {
+	if err := os.MkdirAll(dir, 0o777); err != nil {
+		return err
+	}
+
+	for i, container := range c.Containers {
+		if !exists(ctx, container) {
+			continue
+		}
+
+		logReader, err := container.Logs(ctx)
+		if err != nil {
+			return fmt.Errorf("logs of %s: %w", c.Hostname(i), err)
+		}
+		raw, err := io.ReadAll(logReader)
+		logReader.Close()
+		if err != nil {
+			return fmt.Errorf("logs of %s: %w", c.Hostname(i), err)
+		}
+		if err := os.WriteFile(path.Join(dir, c.Hostname(i)+".log"), raw, 0o666); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Teardown removes every node, every sidecar and the network. It carries
+// on past failures, so that as little as possible is left behind, and
+// returns all of them. The data directories are kept.
+func (c *Cluster) Teardown(ctx context.Context) error {
+	var errs []error
+	for _, container := range append(append([]testcontainers.Container{}, c.Containers...), c.sidecars...) {
+		if !exists(ctx, container) {
+			continue
+		}
+		if err := container.Terminate(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if c.network != nil {
+		if err := c.network.Remove(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("network %s: %w", c.NetworkName, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// exists is false for nodes that were never started and for containers
+// that were already terminated
+func exists(ctx context.Context, container testcontainers.Container) bool {
+	if container == nil {
+		return false
+	}
+	_, err := container.State(ctx)
+	return err == nil
+}
+
+// InNetworkNamespace runs a command in a sidecar that shares the network
+// namespace of the node. The Weaviate image ships neither tc nor iptables.
+func (c *Cluster) InNetworkNamespace(ctx context.Context, nodeId int, cmd ...string) error {
+	_, err := RunToCompletion(ctx, testcontainers.ContainerRequest{
+		Image: "nicolaka/netshoot",
+		Cmd:   cmd,
+		HostConfigModifier: func(hc *container.HostConfig) {
+			hc.NetworkMode = container.NetworkMode("container:" + c.Containers[nodeId].GetContainerID())
+			hc.CapAdd = []string{"NET_ADMIN"}
+		},
+		Labels: c.Labels(),
+	})
+	if err != nil {
+		return fmt.Errorf("%s on node %d: %w", cmd[0], nodeId, err)
+	}
+	return nil
+}
+
+// Bridge returns the host interface of the network of the cluster and its
+// subnets, every container of the cluster sends its traffic through it
+func (c *Cluster) Bridge(ctx context.Context) (string, []string, error) {
+	var iface string
+	var subnets []string
+	err := WithDockerClient(func(cli *client.Client) error {
+		network, err := cli.NetworkInspect(ctx, c.NetworkName, types.NetworkInspectOptions{})
+		if err != nil {
+			return err
+		}
+		iface = network.Options["com.docker.network.bridge.name"]
+		if iface == "" && len(network.ID) >= 12 {
+			iface = "br-" + network.ID[:12]
+		}
+		for _, config := range network.IPAM.Config {
+			subnets = append(subnets, config.Subnet)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("network %s: %w", c.NetworkName, err)
+	}
+	if iface == "" || len(subnets) == 0 {
+		return "", nil, fmt.Errorf("network %s: no bridge or subnet", c.NetworkName)
+	}
+	return iface, subnets, nil
+}
+
+// UpdateNanoCPUs changes the CPU limit of a running node
+func (c *Cluster) UpdateNanoCPUs(ctx context.Context, nodeId int, nanoCPUs int64) error {
+	return WithDockerClient(func(cli *client.Client) error {
+		_, err := cli.ContainerUpdate(ctx, c.Containers[nodeId].GetContainerID(), container.UpdateConfig{
+			Resources: container.Resources{NanoCPUs: nanoCPUs},
+		})
+		return err
+	})
+}
+
+func (c *Cluster) DumpContainerLogs(container testcontainers.Container) {
+	logReader, err := container.Logs(context.Background())
+	if err != nil {
+		c.opts.Logger.Error("cannot read container logs", "err", err)
+		return
+	}
+
+	io.Copy(os.Stdout, logReader)
+}
+
+// RunToCompletion starts a one-off container, waits for it to exit and
+// returns its output. A non-zero exit code is returned as an error that
+// contains the output.
+func RunToCompletion(ctx context.Context, req testcontainers.ContainerRequest) (string, error) {
+	req.WaitingFor = wait.ForExit().WithExitTimeout(10 * time.Minute)
+	req.Labels = Labels(req.Labels)
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: req,
+		Started:          true,
+	})
+	if err != nil {
+		return "", err
+	}
+	defer container.Terminate(ctx)
+
+	logReader, err := container.Logs(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	output, err := io.ReadAll(logReader)
+	if err != nil {
+		return "", err
+	}
+
+	state, err := container.State(ctx)
+	if err != nil {
+		return string(output), err
+	}
+
+	if state.ExitCode != 0 {
+		return string(output), fmt.Errorf("%s exited with code %d:\n%s", req.Image,
+			state.ExitCode, output)
+	}
+
+	return string(output), nil
+}
+
+func WithDockerClient(fn func(cli *client.Client) error) error {
+	cli, err := testcontainers.NewDockerClient()
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	return fn(cli)
+}
+
+// Base makes the cluster, and every type embedding it, a faults.Target
+func (c *Cluster) Base() *Cluster {
+	return c
+}
@@ -0,0 +1,30 @@
+package cluster
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPortOffset(t *testing.T) {
+	c, err := New(Options{
+		NodeCount:  2,
+		Image:      func(version string) string { return "semitechnologies/weaviate:" + version },
+		RootDir:    t.TempDir(),
+		PortOffset: 100,
+		Profiling:  true,
+		Metrics:    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := c.exposedPorts(1), []string{"8181:8080", "16161:6060", "12213:2112"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("exposed ports: wanted %v, got %v", want, got)
+	}
+	if got, want := c.opts.Host(1), "localhost:8181"; got != want {
+		t.Errorf("host: wanted %s, got %s", want, got)
+	}
+	if got, want := c.MetricsHost(1), "localhost:12213"; got != want {
+		t.Errorf("metrics host: wanted %s, got %s", want, got)
+	}
+}
@@ -0,0 +1,143 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+)
+
+// Label marks every container, network and volume that a cluster creates,
+// so that what an aborted run left behind can be found again. The other
+// labels associate an object with its run.
+const (
+	Label         = "io.weaviate.chaos-engineering"
+	LabelRunID    = Label + ".run-id"
+	LabelScenario = Label + ".scenario"
+	LabelNode     = Label + ".node"
+	LabelVersion  = Label + ".version"
+)
+
+// Labels returns the labels of a Docker object that is not created through
+// a cluster, such as a single node, merged with extra
+func Labels(extra map[string]string) map[string]string {
+	merged := map[string]string{Label: "true"}
+	for key, value := range extra {
+		merged[key] = value
+	}
+	return merged
+}
+
+// Labels returns the labels the cluster sets on its own Docker objects,
+// for helper containers that are created outside of the cluster
+func (c *Cluster) Labels() map[string]string {
+	labels := Labels(c.opts.Labels)
+	labels[LabelRunID] = c.RunID
+	return labels
+}
+
+func (c *Cluster) nodeLabels(nodeId int, version string) map[string]string {
+	labels := c.Labels()
+	labels[LabelNode] = c.Hostname(nodeId)
+	labels[LabelVersion] = version
+	return labels
+}
+
+// Garbage is a leftover Docker object of an earlier run
+type Garbage struct {
+	Kind    string
+	Name    string
+	Created time.Time
+}
+
+func (g Garbage) String() string {
+	return fmt.Sprintf("%s %s (created %s)", g.Kind, g.Name, g.Created.Format(time.RFC3339))
+}
+
+// CollectGarbage removes every labeled container, network and volume that
+// was created more than olderThan ago, only those of a single run if runID
+// is set. The age keeps it from removing the cluster of a run that is still
+// in progress on the same machine. With dryRun the objects are only
+// returned.
+func CollectGarbage(ctx context.Context, runID string, olderThan time.Duration, dryRun bool) ([]Garbage, error) {
+	var removed []Garbage
+	err := WithDockerClient(func(cli *client.Client) error {
+		cutoff := time.Now().Add(-olderThan)
+		filter := filters.NewArgs(filters.Arg("label", Label))
+		if runID != "" {
+			filter.Add("label", LabelRunID+"="+runID)
+		}
+
+		// containers first, a network can't be removed while in use
+		containers, err := cli.ContainerList(ctx, types.ContainerListOptions{All: true, Filters: filter})
+		if err != nil {
+			return err
+		}
+		for _, container := range containers {
+			g := Garbage{Kind: "container", Name: containerName(container), Created: time.Unix(container.Created, 0)}
+			if g.Created.After(cutoff) {
+				continue
+			}
+			if !dryRun {
+				if err := cli.ContainerRemove(ctx, container.ID, types.ContainerRemoveOptions{
+					Force: true, RemoveVolumes: true,
+				}); err != nil {
+					return fmt.Errorf("remove %s: %w", g, err)
+				}
+			}
+			removed = append(removed, g)
+		}
+
+		networks, err := cli.NetworkList(ctx, types.NetworkListOptions{Filters: filter})
+		if err != nil {
+			return err
+		}
+		for _, network := range networks {
+			g := Garbage{Kind: "network", Name: network.Name, Created: network.Created}
+			if g.Created.After(cutoff) {
+				continue
+			}
+			if !dryRun {
+				if err := cli.NetworkRemove(ctx, network.ID); err != nil {
+					return fmt.Errorf("remove %s: %w", g, err)
+				}
+			}
+			removed = append(removed, g)
+		}
+
+		volumes, err := cli.VolumeList(ctx, filter)
+		if err != nil {
+			return err
+		}
+		for _, volume := range volumes.Volumes {
+			created, err := time.Parse(time.RFC3339, volume.CreatedAt)
+			if err != nil {
+				return fmt.Errorf("volume %s: %w", volume.Name, err)
+			}
+			g := Garbage{Kind: "volume", Name: volume.Name, Created: created}
+			if g.Created.After(cutoff) {
+				continue
+			}
+			if !dryRun {
+				if err := cli.VolumeRemove(ctx, volume.Name, true); err != nil {
+					return fmt.Errorf("remove %s: %w", g, err)
+				}
+			}
+			removed = append(removed, g)
+		}
+
+		return nil
+	})
+	return removed, err
+}
+
+func containerName(container types.Container) string {
+	if len(container.Names) == 0 {
+		return container.ID[:12]
+	}
+	return strings.TrimPrefix(container.Names[0], "/")
+}
@@ -0,0 +1,13 @@
+package cluster
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLabels(t *testing.T) {
+	want := map[string]string{Label: "true", "role": "proxy"}
+	if got := Labels(map[string]string{"role": "proxy"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("wanted %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,76 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// WaitForHealthyNodes polls a node until it reports every node of the
+// cluster as healthy
+func (c *Cluster) WaitForHealthyNodes(ctx context.Context, nodeId int, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		healthy, err := c.HealthyNodes(ctx, nodeId)
+		if err == nil && healthy == c.NodeCount {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			if err != nil {
+				return fmt.Errorf("nodes not healthy within %s: %w", timeout, err)
+			}
+			return fmt.Errorf("%s sees %d of %d nodes healthy after %s",
+				c.Hostname(nodeId), healthy, c.NodeCount, timeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// HealthyNodes is the number of nodes a node reports as healthy
+func (c *Cluster) HealthyNodes(ctx context.Context, nodeId int) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		fmt.Sprintf("http://%s/v1/nodes", c.Host(nodeId)), nil)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	raw, err := io.ReadAll(res.Body)
+	if err != nil {
+		return 0, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("GET /v1/nodes: status %d: %s", res.StatusCode, raw)
+	}
+
+	var nodes struct {
+		Nodes []struct {
+			Status string `json:"status"`
+		} `json:"nodes"`
+	}
+	if err := json.Unmarshal(raw, &nodes); err != nil {
+		return 0, err
+	}
+
+	healthy := 0
+	for _, n := range nodes.Nodes {
+		if n.Status == "HEALTHY" {
+			healthy++
+		}
+	}
+	return healthy, nil
+}
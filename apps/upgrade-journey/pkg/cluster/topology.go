@@ -0,0 +1,174 @@
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Topology describes the roles and the placement of the nodes. The zero
+// value is a cluster in a single zone in which every node votes.
+type Topology struct {
+	// Voters is the number of raft voters, nodes 0 to Voters-1 vote and the
+	// others join as non-voters. 0 makes every node a voter.
+	Voters int
+
+	// Zones is the zone of every node by node id. Zones are labels of the
+	// harness only, they let faults target all nodes of a zone at once.
+	Zones []string
+
+	// Env overrides the environment of single nodes, by node id
+	Env map[int]map[string]string
+}
+
+// Validate checks the topology against the number of nodes
+func (t Topology) Validate(nodeCount int) error {
+	if t.Voters < 0 || t.Voters > nodeCount {
+		return fmt.Errorf("topology: voters must be between 0 and %d", nodeCount)
+	}
+	if len(t.Zones) > 0 && len(t.Zones) != nodeCount {
+		return fmt.Errorf("topology: %d zones for %d nodes, every node needs a zone", len(t.Zones), nodeCount)
+	}
+	for nodeId := range t.Env {
+		if nodeId < 0 || nodeId >= nodeCount {
+			return fmt.Errorf("topology: environment for node %d, which does not exist", nodeId)
+		}
+	}
+	return nil
+}
+
+// IsVoter tells whether a node is a raft voter
+func (c *Cluster) IsVoter(nodeId int) bool {
+	return c.opts.Topology.Voters == 0 || nodeId < c.opts.Topology.Voters
+}
+
+// Voters returns the ids of the raft voters
+func (c *Cluster) Voters() []int {
+	var voters []int
+	for i := 0; i < c.NodeCount; i++ {
+		if c.IsVoter(i) {
+			voters = append(voters, i)
+		}
+	}
+	return voters
+}
+
+// Zone returns the zone of a node, empty if the topology has no zones
+func (c *Cluster) Zone(nodeId int) string {
+	if len(c.opts.Topology.Zones) == 0 {
+		return ""
+	}
+	return c.opts.Topology.Zones[nodeId]
+}
+
+// Zones returns the distinct zones in alphabetical order
+func (c *Cluster) Zones() []string {
+	seen := map[string]bool{}
+	var zones []string
+	for _, zone := range c.opts.Topology.Zones {
+		if !seen[zone] {
+			seen[zone] = true
+			zones = append(zones, zone)
+		}
+	}
+	sort.Strings(zones)
+	return zones
+}
+
+// Selector picks nodes by their role and placement. It is parsed from terms
+// joined by "&", all of which a node has to match:
+//
+//	2             node 2
+//	voter         every raft voter
+//	non-voter     every node that does not vote
+//	zone:a        every node in zone a
+//
+// Alternatives are separated by "|". For example "voter&zone:a" selects
+// the voters in zone a, and "1|2" selects nodes 1 and 2.
+type Selector struct {
+	alternatives [][]string
+}
+
+func ParseSelector(value string) (Selector, error) {
+	var s Selector
+	for _, alternative := range strings.Split(value, "|") {
+		var terms []string
+		for _, term := range strings.Split(alternative, "&") {
+			term = strings.TrimSpace(term)
+			switch {
+			case term == "voter", term == "non-voter":
+			case strings.HasPrefix(term, "zone:") && len(term) > len("zone:"):
+			default:
+				if _, err := strconv.Atoi(term); err != nil {
+					return Selector{}, fmt.Errorf("selector %q: unknown term %q, "+
+						"must be a node id, voter, non-voter or zone:<name>", value, term)
+				}
+			}
+			terms = append(terms, term)
+		}
+		s.alternatives = append(s.alternatives, terms)
+	}
+	return s, nil
+}
+
+// SelectNodes returns a selector for exactly the nodes
+func SelectNodes(nodes ...int) Selector {
+	var s Selector
+	for _, node := range nodes {
+		s.alternatives = append(s.alternatives, []string{strconv.Itoa(node)})
+	}
+	return s
+}
+
+func (s Selector) String() string {
+	alternatives := make([]string, len(s.alternatives))
+	for i, terms := range s.alternatives {
+		alternatives[i] = strings.Join(terms, "&")
+	}
+	return strings.Join(alternatives, "|")
+}
+
+// Nodes returns the ids of the matching nodes in ascending order. It fails
+// if no node matches, a selector that targets nothing is a mistake in the
+// scenario.
+func (s Selector) Nodes(c *Cluster) ([]int, error) {
+	var nodes []int
+	for i := 0; i < c.NodeCount; i++ {
+		for _, terms := range s.alternatives {
+			if matches(c, i, terms) {
+				nodes = append(nodes, i)
+				break
+			}
+		}
+	}
+
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("selector %s matches no node", s)
+	}
+	return nodes, nil
+}
+
+func matches(c *Cluster, nodeId int, terms []string) bool {
+	for _, term := range terms {
+		switch {
+		case term == "voter":
+			if !c.IsVoter(nodeId) {
+				return false
+			}
+		case term == "non-voter":
+			if c.IsVoter(nodeId) {
+				return false
+			}
+		case strings.HasPrefix(term, "zone:"):
+			if c.Zone(nodeId) != strings.TrimPrefix(term, "zone:") {
+				return false
+			}
+		default:
+			if id, _ := strconv.Atoi(term); id != nodeId {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,95 @@
+package cluster
+
+import (
+	"reflect"
+	"testing"
+)
+
+func newTestCluster(t *testing.T, nodeCount int, topology Topology) *Cluster {
+	t.Helper()
+	c, err := New(Options{
+		NodeCount: nodeCount,
+		Image:     func(version string) string { return "semitechnologies/weaviate:" + version },
+		RootDir:   t.TempDir(),
+		Topology:  topology,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestTopologyVoters(t *testing.T) {
+	c := newTestCluster(t, 5, Topology{Voters: 3})
+	if got, want := c.Voters(), []int{0, 1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("voters: wanted %v, got %v", want, got)
+	}
+	if got := c.Quorum(); got != 2 {
+		t.Errorf("quorum: wanted 2, got %d", got)
+	}
+	if got := c.voterHostnames(); got != "weaviate-0,weaviate-1,weaviate-2" {
+		t.Errorf("raft join: got %s", got)
+	}
+
+	c = newTestCluster(t, 3, Topology{})
+	if got := c.Quorum(); got != 2 || len(c.Voters()) != 3 {
+		t.Errorf("every node votes by default, got voters %v and quorum %d", c.Voters(), got)
+	}
+}
+
+func TestTopologyValidation(t *testing.T) {
+	for _, topology := range []Topology{
+		{Voters: 4},
+		{Zones: []string{"a", "b"}},
+		{Env: map[int]map[string]string{3: {"LOG_LEVEL": "debug"}}},
+	} {
+		_, err := New(Options{NodeCount: 3, Image: func(string) string { return "" }, Topology: topology})
+		if err == nil {
+			t.Errorf("%+v: expected an error", topology)
+		}
+	}
+}
+
+func TestSelector(t *testing.T) {
+	c := newTestCluster(t, 5, Topology{Voters: 3, Zones: []string{"a", "b", "b", "a", "b"}})
+
+	tests := []struct {
+		selector string
+		want     []int
+		wantErr  bool
+	}{
+		{selector: "voter", want: []int{0, 1, 2}},
+		{selector: "non-voter", want: []int{3, 4}},
+		{selector: "zone:a", want: []int{0, 3}},
+		{selector: "voter&zone:a", want: []int{0}},
+		{selector: "non-voter&zone:b", want: []int{4}},
+		{selector: "2", want: []int{2}},
+		{selector: "1|non-voter&zone:b", want: []int{1, 4}},
+		{selector: "zone:c", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		s, err := ParseSelector(tt.selector)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.selector, err)
+		}
+
+		got, err := s.Nodes(c)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: wanted error %v, got %v", tt.selector, tt.wantErr, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: wanted %v, got %v", tt.selector, tt.want, got)
+		}
+	}
+
+	if got, err := SelectNodes(3, 1).Nodes(c); err != nil || !reflect.DeepEqual(got, []int{1, 3}) {
+		t.Errorf("SelectNodes: wanted [1 3], got %v, %v", got, err)
+	}
+
+	for _, invalid := range []string{"leader", "zone:", "voter&", "1|"} {
+		if _, err := ParseSelector(invalid); err == nil {
+			t.Errorf("%q: expected an error", invalid)
+		}
+	}
+}
@@ -0,0 +1,318 @@
+package faults
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/weaviate/weaviate-chaos-engineering/apps/upgrade-journey/pkg/cluster"
+)
+
+// Kill kills a node without giving it a chance to shut down and starts it
+// again on the same data
+type Kill[T Target] struct {
+	Node int
+}
+
+func (f *Kill[T]) Describe() string {
+	return fmt.Sprintf("kill weaviate-%d", f.Node)
+}
+
+func (f *Kill[T]) Inject(ctx context.Context, t T) error {
+	c := t.Base()
+	return cluster.WithDockerClient(func(cli *client.Client) error {
+		return cli.ContainerKill(ctx, c.Containers[f.Node].GetContainerID(), "SIGKILL")
+	})
+}
+
+func (f *Kill[T]) Heal(ctx context.Context, t T) error {
+	c := t.Base()
+	if err := c.StopNode(ctx, f.Node); err != nil {
+		return err
+	}
+	return c.StartStoppedNode(ctx, f.Node, c.NodeVersions[f.Node])
+}
+
+// Pause freezes all processes of a node, so that it still holds its
+// connections but does not respond
+type Pause[T Target] struct {
+	Node int
+}
+
+func (f *Pause[T]) Describe() string {
+	return fmt.Sprintf("pause weaviate-%d", f.Node)
+}
+
+func (f *Pause[T]) Inject(ctx context.Context, t T) error {
+	c := t.Base()
+	return cluster.WithDockerClient(func(cli *client.Client) error {
+		return cli.ContainerPause(ctx, c.Containers[f.Node].GetContainerID())
+	})
+}
+
+func (f *Pause[T]) Heal(ctx context.Context, t T) error {
+	c := t.Base()
+	return cluster.WithDockerClient(func(cli *client.Client) error {
+		return cli.ContainerUnpause(ctx, c.Containers[f.Node].GetContainerID())
+	})
+}
+
+// Partition disconnects a node from the cluster network, the node keeps
+// running but can't reach any of its peers
+type Partition[T Target] struct {
+	Node int
+}
+
+func (f *Partition[T]) Describe() string {
+	return fmt.Sprintf("partition weaviate-%d", f.Node)
+}
+
+func (f *Partition[T]) Inject(ctx context.Context, t T) error {
+	c := t.Base()
+	return cluster.WithDockerClient(func(cli *client.Client) error {
+		return cli.NetworkDisconnect(ctx, c.NetworkName, c.Containers[f.Node].GetContainerID(), true)
+	})
+}
+
+func (f *Partition[T]) Heal(ctx context.Context, t T) error {
+	c := t.Base()
+	return cluster.WithDockerClient(func(cli *client.Client) error {
+		return cli.NetworkConnect(ctx, c.NetworkName, c.Containers[f.Node].GetContainerID(),
+			&network.EndpointSettings{Aliases: []string{c.Hostname(f.Node)}})
+	})
+}
+
+// Isolate drops all traffic between a node and its peers. Unlike Partition
+// the node stays attached to the cluster network, so the harness can still
+// send requests to either side of the split.
+type Isolate[T Target] struct {
+	Node int
+
+	peers []string
+}
+
+func (f *Isolate[T]) Describe() string {
+	return fmt.Sprintf("isolate weaviate-%d from its peers", f.Node)
+}
+
+func (f *Isolate[T]) Inject(ctx context.Context, t T) error {
+	c := t.Base()
+	for i, container := range c.Containers {
+		if i == f.Node {
+			continue
+		}
+		ip, err := container.ContainerIP(ctx)
+		if err != nil {
+			return fmt.Errorf("address of weaviate-%d: %w", i, err)
+		}
+		f.peers = append(f.peers, ip)
+	}
+	return c.InNetworkNamespace(ctx, f.Node, "sh", "-c", isolateRules("-I", f.peers))
+}
+
+func (f *Isolate[T]) Heal(ctx context.Context, t T) error {
+	if len(f.peers) == 0 {
+		return nil
+	}
+	return t.Base().InNetworkNamespace(ctx, f.Node, "sh", "-c", isolateRules("-D", f.peers))
+}
+
+// isolateRules inserts or deletes the rules that drop the traffic to and
+// from every peer
+func isolateRules(action string, peers []string) string {
+	var rules []string
+	for _, ip := range peers {
+		rules = append(rules,
+			fmt.Sprintf("iptables %s INPUT -s %s -j DROP", action, ip),
+			fmt.Sprintf("iptables %s OUTPUT -d %s -j DROP", action, ip))
+	}
+	return strings.Join(rules, " && ")
+}
+
+// Latency delays all outgoing packets of a node
+type Latency[T Target] struct {
+	Node  int
+	Delay time.Duration
+}
+
+func (f *Latency[T]) Describe() string {
+	return fmt.Sprintf("delay packets of weaviate-%d by %s", f.Node, f.Delay)
+}
+
+func (f *Latency[T]) Inject(ctx context.Context, t T) error {
+	return netem(ctx, t.Base(), f.Node, "add", "dev", "eth0", "root", "netem", "delay",
+		fmt.Sprintf("%dms", f.Delay.Milliseconds()))
+}
+
+func (f *Latency[T]) Heal(ctx context.Context, t T) error {
+	return netem(ctx, t.Base(), f.Node, "del", "dev", "eth0", "root")
+}
+
+func netem(ctx context.Context, c *cluster.Cluster, nodeId int, args ...string) error {
+	return c.InNetworkNamespace(ctx, nodeId, append([]string{"tc", "qdisc"}, args...)...)
+}
+
+// DNS makes the peers of a node unresolvable by dropping everything sent to
+// the embedded Docker DNS server from within the node's network namespace.
+// Connections that are already established are not affected. Heal lifts the
+// block without a restart, and the node has to find its peers again on its
+// own.
+type DNS[T Target] struct {
+	Node int
+}
+
+// dockerDNS is the address of the DNS server Docker embeds in every
+// container attached to a user-defined network
+const dockerDNS = "127.0.0.11"
+
+func (f *DNS[T]) Describe() string {
+	return fmt.Sprintf("break DNS of weaviate-%d", f.Node)
+}
+
+func (f *DNS[T]) Inject(ctx context.Context, t T) error {
+	return t.Base().InNetworkNamespace(ctx, f.Node, "iptables", "-I", "OUTPUT", "-d", dockerDNS, "-j", "DROP")
+}
+
+func (f *DNS[T]) Heal(ctx context.Context, t T) error {
+	c := t.Base()
+	if err := c.InNetworkNamespace(ctx, f.Node, "iptables", "-D", "OUTPUT", "-d", dockerDNS, "-j", "DROP"); err != nil {
+		return err
+	}
+
+	return c.WaitForHealthyNodes(ctx, f.Node, time.Minute)
+}
+
+// Disk takes away disk space from a node by placing a filler file in its
+// data directory
+type Disk[T Target] struct {
+	Node int
+	Size string
+}
+
+func (f *Disk[T]) Describe() string {
+	return fmt.Sprintf("fill disk of weaviate-%d with %s", f.Node, f.Size)
+}
+
+func (f *Disk[T]) Inject(ctx context.Context, t T) error {
+	_, err := cluster.RunToCompletion(ctx, testcontainers.ContainerRequest{
+		Image:  "alpine:3",
+		Cmd:    []string{"fallocate", "-l", f.Size, "/data/chaos-filler"},
+		Mounts: testcontainers.Mounts(testcontainers.BindMount(t.Base().VolumePath(f.Node), "/data")),
+		Labels: t.Base().Labels(),
+	})
+	return err
+}
+
+func (f *Disk[T]) Heal(ctx context.Context, t T) error {
+	return t.Base().WipeNodePath(ctx, f.Node, "chaos-filler")
+}
+
+// CPU throttles a node to a fraction of the CPUs through its cgroup, like a
+// noisy neighbor would. The limit is kept across restarts of the node until
+// the fault is healed, which restores the limit of the cluster resources.
+type CPU[T Target] struct {
+	Node int
+	CPUs float64
+}
+
+func (f *CPU[T]) Describe() string {
+	return fmt.Sprintf("throttle weaviate-%d to %g CPUs", f.Node, f.CPUs)
+}
+
+func (f *CPU[T]) Inject(ctx context.Context, t T) error {
+	c := t.Base()
+	c.NanoCPUs[f.Node] = int64(f.CPUs * 1e9)
+	return c.UpdateNanoCPUs(ctx, f.Node, c.NanoCPUs[f.Node])
+}
+
+func (f *CPU[T]) Heal(ctx context.Context, t T) error {
+	c := t.Base()
+	c.NanoCPUs[f.Node] = c.BaseNanoCPUs()
+	if c.NanoCPUs[f.Node] > 0 {
+		return c.UpdateNanoCPUs(ctx, f.Node, c.NanoCPUs[f.Node])
+	}
+
+	// an update can't remove a limit, it is raised to all CPUs instead
+	return cluster.WithDockerClient(func(cli *client.Client) error {
+		info, err := cli.Info(ctx)
+		if err != nil {
+			return err
+		}
+		return c.UpdateNanoCPUs(ctx, f.Node, int64(info.NCPU)*1e9)
+	})
+}
+
+// Wipe stops a node and deletes a path in its data directory. Heal starts
+// the node again, it has to recover the lost state from its peers.
+type Wipe[T Target] struct {
+	Node int
+	Path string
+}
+
+func (f *Wipe[T]) Describe() string {
+	return fmt.Sprintf("wipe %s of weaviate-%d", f.Path, f.Node)
+}
+
+func (f *Wipe[T]) Inject(ctx context.Context, t T) error {
+	c := t.Base()
+	if err := c.StopNode(ctx, f.Node); err != nil {
+		return err
+	}
+	return c.WipeNodePath(ctx, f.Node, f.Path)
+}
+
+func (f *Wipe[T]) Heal(ctx context.Context, t T) error {
+	c := t.Base()
+	return c.StartStoppedNode(ctx, f.Node, c.NodeVersions[f.Node])
+}
+
+// Down stops every node a selector matches at once, like the outage of a
+// zone or the loss of all non-voters. Heal starts them again concurrently,
+// as a node may not become ready before enough of its peers are back.
+type Down[T Target] struct {
+	Nodes cluster.Selector
+
+	stopped []int
+}
+
+func (f *Down[T]) Describe() string {
+	return fmt.Sprintf("take down %s", f.Nodes)
+}
+
+func (f *Down[T]) Inject(ctx context.Context, t T) error {
+	c := t.Base()
+	nodes, err := f.Nodes.Nodes(c)
+	if err != nil {
+		return err
+	}
+
+	for _, node := range nodes {
+		if err := c.StopNode(ctx, node); err != nil {
+			return err
+		}
+		f.stopped = append(f.stopped, node)
+	}
+	return nil
+}
+
+func (f *Down[T]) Heal(ctx context.Context, t T) error {
+	c := t.Base()
+	errs := make(chan error, len(f.stopped))
+	for _, node := range f.stopped {
+		go func(node int) {
+			errs <- c.StartStoppedNode(ctx, node, c.NodeVersions[node])
+		}(node)
+	}
+
+	var firstErr error
+	for range f.stopped {
+		if err := <-errs; err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
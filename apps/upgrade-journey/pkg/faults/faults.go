@@ -0,0 +1,164 @@
+// Package faults contains reversible faults for a cluster.Cluster and a
+// registry that creates them from specs like "latency:node=1,delay=500ms".
+//
+// Faults are generic over the target, so that a harness that wraps the
+// cluster with state of its own can register faults that need that state
+// next to the built-in ones.
+package faults
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/weaviate/weaviate-chaos-engineering/apps/upgrade-journey/pkg/cluster"
+)
+
+// Target is what a fault is applied to. *cluster.Cluster is a Target, as is
+// every type that embeds it.
+type Target interface {
+	Base() *cluster.Cluster
+}
+
+// Injector is a single, reversible fault. Inject breaks something on the
+// cluster, Heal undoes it and must leave every node running and on the
+// version it had before. A fault is created for a single use.
+type Injector[T Target] interface {
+	Inject(ctx context.Context, t T) error
+	Heal(ctx context.Context, t T) error
+	Describe() string
+}
+
+// Params are the key=value parameters of a fault spec
+type Params map[string]string
+
+type Factory[T Target] func(p Params) (Injector[T], error)
+
+// Registry maps the name used in a fault spec to its implementation
+type Registry[T Target] map[string]Factory[T]
+
+// New parses a spec of the form name:key=value,key=value, for example
+// "latency:node=1,delay=500ms"
+func (r Registry[T]) New(spec string) (Injector[T], error) {
+	name, rawParams, _ := strings.Cut(strings.TrimSpace(spec), ":")
+	factory, ok := r[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown fault %q, available: %s", name, strings.Join(r.Names(), ", "))
+	}
+
+	params := Params{}
+	for _, kv := range strings.Split(rawParams, ",") {
+		if strings.TrimSpace(kv) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimSpace(kv), "=")
+		if !ok {
+			return nil, fmt.Errorf("fault %s: invalid parameter %q", name, kv)
+		}
+		params[key] = value
+	}
+
+	f, err := factory(params)
+	if err != nil {
+		return nil, fmt.Errorf("fault %s: %w", name, err)
+	}
+
+	return f, nil
+}
+
+func (r Registry[T]) Names() []string {
+	names := make([]string, 0, len(r))
+	for name := range r {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (p Params) String(key, fallback string) string {
+	if value, ok := p[key]; ok {
+		return value
+	}
+	return fallback
+}
+
+// Node returns the node parameter, by default the last node
+func (p Params) Node(nodeCount int) (int, error) {
+	node, err := strconv.Atoi(p.String("node", strconv.Itoa(nodeCount-1)))
+	if err != nil || node < 0 || node >= nodeCount {
+		return 0, fmt.Errorf("node must be between 0 and %d", nodeCount-1)
+	}
+	return node, nil
+}
+
+func (p Params) Duration(key string, fallback time.Duration) (time.Duration, error) {
+	value, ok := p[key]
+	if !ok {
+		return fallback, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// Builtin returns a registry with all faults that only need the cluster
+func Builtin[T Target](nodeCount int) Registry[T] {
+	return Registry[T]{
+		"kill": func(p Params) (Injector[T], error) {
+			node, err := p.Node(nodeCount)
+			return &Kill[T]{Node: node}, err
+		},
+		"pause": func(p Params) (Injector[T], error) {
+			node, err := p.Node(nodeCount)
+			return &Pause[T]{Node: node}, err
+		},
+		"partition": func(p Params) (Injector[T], error) {
+			node, err := p.Node(nodeCount)
+			return &Partition[T]{Node: node}, err
+		},
+		"isolate": func(p Params) (Injector[T], error) {
+			node, err := p.Node(nodeCount)
+			return &Isolate[T]{Node: node}, err
+		},
+		"latency": func(p Params) (Injector[T], error) {
+			node, err := p.Node(nodeCount)
+			if err != nil {
+				return nil, err
+			}
+			delay, err := p.Duration("delay", 200*time.Millisecond)
+			return &Latency[T]{Node: node, Delay: delay}, err
+		},
+		"disk": func(p Params) (Injector[T], error) {
+			node, err := p.Node(nodeCount)
+			return &Disk[T]{Node: node, Size: p.String("size", "1g")}, err
+		},
+		"dns": func(p Params) (Injector[T], error) {
+			node, err := p.Node(nodeCount)
+			return &DNS[T]{Node: node}, err
+		},
+		"cpu": func(p Params) (Injector[T], error) {
+			node, err := p.Node(nodeCount)
+			if err != nil {
+				return nil, err
+			}
+			cpus, err := strconv.ParseFloat(p.String("cpus", "0.2"), 64)
+			if err != nil || cpus <= 0 {
+				return nil, fmt.Errorf("cpus must be a positive number")
+			}
+			return &CPU[T]{Node: node, CPUs: cpus}, nil
+		},
+		"wipe": func(p Params) (Injector[T], error) {
+			node, err := p.Node(nodeCount)
+			return &Wipe[T]{Node: node, Path: p.String("path", "raft")}, err
+		},
+		"down": func(p Params) (Injector[T], error) {
+			nodes, ok := p["nodes"]
+			if !ok {
+				return nil, fmt.Errorf("nodes is required, e.g. nodes=non-voter or nodes=voter&zone:a")
+			}
+			selector, err := cluster.ParseSelector(nodes)
+			return &Down[T]{Nodes: selector}, err
+		},
+	}
+}
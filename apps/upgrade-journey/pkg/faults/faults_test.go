@@ -0,0 +1,45 @@
+package faults
+
+import (
+	"testing"
+
+	"github.com/weaviate/weaviate-chaos-engineering/apps/upgrade-journey/pkg/cluster"
+)
+
+func TestRegistryNew(t *testing.T) {
+	registry := Builtin[*cluster.Cluster](3)
+
+	tests := []struct {
+		spec    string
+		want    string
+		wantErr bool
+	}{
+		{spec: "kill", want: "kill weaviate-2"},
+		{spec: "latency:node=0,delay=1s", want: "delay packets of weaviate-0 by 1s"},
+		{spec: "wipe:node=1", want: "wipe raft of weaviate-1"},
+		{spec: "isolate:node=0", want: "isolate weaviate-0 from its peers"},
+		{spec: "pause:node=3", wantErr: true},
+		{spec: "kill:node", wantErr: true},
+		{spec: "down:nodes=voter&zone:a", want: "take down voter&zone:a"},
+		{spec: "down", wantErr: true},
+		{spec: "down:nodes=leader", wantErr: true},
+		{spec: "meteor", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		f, err := registry.New(tt.spec)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("New(%q) expected an error", tt.spec)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("New(%q): %v", tt.spec, err)
+			continue
+		}
+		if got := f.Describe(); got != tt.want {
+			t.Errorf("New(%q).Describe() = %q, want %q", tt.spec, got, tt.want)
+		}
+	}
+}
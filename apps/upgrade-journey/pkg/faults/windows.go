@@ -0,0 +1,60 @@
+package faults
+
+import (
+	"sync"
+	"time"
+)
+
+// Window is the time a fault was active, from right before it was injected
+// until it was healed. End is zero while the fault is still active.
+type Window struct {
+	Fault string
+	Start time.Time
+	End   time.Time
+}
+
+func (w Window) overlaps(from, to time.Time) bool {
+	return !to.Before(w.Start) && (w.End.IsZero() || !from.After(w.End))
+}
+
+// Windows records when faults were active, so that errors can be
+// correlated with them: an error during a fault window may be caused by the
+// fault, an error outside of all windows can't be.
+type Windows struct {
+	sync.Mutex
+	windows []Window
+}
+
+// Open starts the window of a fault, the returned function ends it
+func (w *Windows) Open(fault string) func() {
+	w.Lock()
+	defer w.Unlock()
+
+	i := len(w.windows)
+	w.windows = append(w.windows, Window{Fault: fault, Start: time.Now()})
+	return func() {
+		w.Lock()
+		defer w.Unlock()
+		w.windows[i].End = time.Now()
+	}
+}
+
+// During returns the first fault that was active at any time between from
+// and to
+func (w *Windows) During(from, to time.Time) (string, bool) {
+	w.Lock()
+	defer w.Unlock()
+
+	for _, window := range w.windows {
+		if window.overlaps(from, to) {
+			return window.Fault, true
+		}
+	}
+	return "", false
+}
+
+func (w *Windows) All() []Window {
+	w.Lock()
+	defer w.Unlock()
+	return append([]Window(nil), w.windows...)
+}
@@ -0,0 +1,32 @@
+package faults
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWindowsDuring(t *testing.T) {
+	w := &Windows{}
+	before := time.Now()
+	time.Sleep(time.Millisecond)
+
+	end := w.Open("kill weaviate-1")
+	during := time.Now()
+	if fault, ok := w.During(during, during); !ok || fault != "kill weaviate-1" {
+		t.Errorf("open window: got %q, %v", fault, ok)
+	}
+
+	end()
+	time.Sleep(time.Millisecond)
+	after := time.Now()
+
+	if _, ok := w.During(before, before); ok {
+		t.Error("wanted no fault before the window")
+	}
+	if _, ok := w.During(after, after); ok {
+		t.Error("wanted no fault after the window")
+	}
+	if _, ok := w.During(before, after); !ok {
+		t.Error("wanted the fault for a range that spans the window")
+	}
+}
@@ -0,0 +1,409 @@
+// Package remote controls Weaviate nodes on remote hosts over SSH, so that
+// the same journeys and faults run against VMs and bare metal, not only
+// against the Docker cluster of package cluster. How a node is started,
+// stopped and wiped is given as shell commands, with presets for systemd
+// units and Docker containers on the host.
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/slog"
+	"gopkg.in/yaml.v3"
+)
+
+// Commands run on the host of a node. {version}, {name} and {data} are
+// replaced with the version to run, the name and the data directory of the
+// node.
+type Commands struct {
+	// Start starts the node on a version and returns once it was launched,
+	// readiness is polled over HTTP afterwards
+	Start string `yaml:"start"`
+
+	// Stop shuts the node down gracefully, Kill without giving it a chance
+	// to
+	Stop string `yaml:"stop"`
+	Kill string `yaml:"kill"`
+
+	// Wipe removes the data of a stopped node
+	Wipe string `yaml:"wipe"`
+
+	// Logs prints the logs of the node
+	Logs string `yaml:"logs"`
+}
+
+// presets are the commands for a node that runs as the systemd unit
+// weaviate@<version>, or as the Docker container <name> on the host. The
+// Docker preset reads the environment of the node, e.g. its cluster
+// settings, from /etc/weaviate/<name>.env.
+var presets = map[string]Commands{
+	"systemd": {
+		Start: "sudo systemctl start weaviate@{version}",
+		Stop:  "sudo systemctl stop 'weaviate@*'",
+		Kill:  "sudo systemctl kill --signal=SIGKILL 'weaviate@*' && sudo systemctl stop 'weaviate@*'",
+		Wipe:  "sudo rm -rf {data}",
+		Logs:  "sudo journalctl --no-pager -u 'weaviate@*'",
+	},
+	"docker": {
+		Start: "docker rm -f {name} >/dev/null 2>&1; docker run -d --name {name} --network host " +
+			"--env-file /etc/weaviate/{name}.env -v {data}:/var/lib/weaviate " +
+			"semitechnologies/weaviate:{version}",
+		Stop: "docker stop {name}",
+		Kill: "docker kill {name}",
+		Wipe: "docker rm -f {name} >/dev/null 2>&1; sudo rm -rf {data}",
+		Logs: "docker logs {name}",
+	},
+}
+
+// Node is a Weaviate node on a remote host
+type Node struct {
+	// Name is the hostname the node has in the cluster
+	Name string `yaml:"name"`
+
+	// SSH is the destination passed to ssh, e.g. ubuntu@10.0.0.1 or a
+	// host of ~/.ssh/config
+	SSH string `yaml:"ssh"`
+
+	// Address is where this process reaches the REST API of the node
+	Address string `yaml:"address"`
+
+	// Data is the data directory of the node on its host
+	Data string `yaml:"data"`
+}
+
+// Config is the YAML file that describes a remote cluster:
+//
+//	preset: systemd
+//	ssh_args: ["-i", "~/.ssh/chaos"]
+//	ready_timeout: 5m
+//	nodes:
+//	  - name: weaviate-0
+//	    ssh: ubuntu@10.0.0.10
+//	    address: 10.0.0.10:8080
+//	    data: /var/lib/weaviate
+//
+// Commands that are set override the ones of the preset.
+type Config struct {
+	Preset       string        `yaml:"preset"`
+	Commands     Commands      `yaml:"commands"`
+	SSHArgs      []string      `yaml:"ssh_args"`
+	ReadyTimeout time.Duration `yaml:"ready_timeout"`
+	Nodes        []Node        `yaml:"nodes"`
+}
+
+// LoadConfig reads a remote cluster file. Unknown keys are rejected, so
+// that a typo does not silently fall back to a preset.
+func LoadConfig(file string) (Config, error) {
+	var cfg Config
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return cfg, err
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(raw))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return cfg, fmt.Errorf("remote cluster %s: %w", file, err)
+	}
+
+	if err := cfg.complete(); err != nil {
+		return cfg, fmt.Errorf("remote cluster %s: %w", file, err)
+	}
+	return cfg, nil
+}
+
+// complete applies the preset and the defaults and validates the result
+func (cfg *Config) complete() error {
+	if cfg.Preset != "" {
+		preset, ok := presets[cfg.Preset]
+		if !ok {
+			return fmt.Errorf("unknown preset %q, available: docker, systemd", cfg.Preset)
+		}
+		for _, cmd := range []struct{ set, from *string }{
+			{&cfg.Commands.Start, &preset.Start},
+			{&cfg.Commands.Stop, &preset.Stop},
+			{&cfg.Commands.Kill, &preset.Kill},
+			{&cfg.Commands.Wipe, &preset.Wipe},
+			{&cfg.Commands.Logs, &preset.Logs},
+		} {
+			if *cmd.set == "" {
+				*cmd.set = *cmd.from
+			}
+		}
+	}
+	if cfg.Commands.Start == "" || cfg.Commands.Stop == "" || cfg.Commands.Kill == "" || cfg.Commands.Wipe == "" {
+		return fmt.Errorf("commands: start, stop, kill and wipe are required without a preset")
+	}
+	if cfg.ReadyTimeout == 0 {
+		cfg.ReadyTimeout = 5 * time.Minute
+	}
+
+	if len(cfg.Nodes) == 0 {
+		return fmt.Errorf("no nodes")
+	}
+	for i, node := range cfg.Nodes {
+		if node.SSH == "" || node.Address == "" || node.Data == "" {
+			return fmt.Errorf("node %d: ssh, address and data are required", i)
+		}
+		if node.Name == "" {
+			cfg.Nodes[i].Name = fmt.Sprintf("weaviate-%d", i)
+		}
+	}
+	return nil
+}
+
+// render fills in the placeholders of a command
+func render(command string, node Node, version string) string {
+	return strings.NewReplacer("{version}", version, "{name}", node.Name, "{data}", node.Data).Replace(command)
+}
+
+// Runner runs a shell command on a host and returns its output
+type Runner func(ctx context.Context, dest, command string) ([]byte, error)
+
+// SSH runs commands with the ssh binary, so that keys, agents and jump
+// hosts are configured the way they are for interactive use
+func SSH(args []string) Runner {
+	return func(ctx context.Context, dest, command string) ([]byte, error) {
+		argv := append([]string{"-o", "BatchMode=yes"}, args...)
+		argv = append(argv, dest, command)
+
+		var stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, "ssh", argv...)
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return out, fmt.Errorf("ssh %s %q: %w: %s", dest, command, err, strings.TrimSpace(stderr.String()))
+		}
+		return out, nil
+	}
+}
+
+// Cluster is a set of nodes on remote hosts, addressed by their id from 0
+// to NodeCount-1 like the nodes of a Docker cluster
+type Cluster struct {
+	cfg    Config
+	run    Runner
+	logger *slog.Logger
+
+	NodeCount int
+
+	// NodeVersions is the version every node was last started with, so
+	// that a fault can bring a node back on the same version
+	NodeVersions []string
+}
+
+func New(cfg Config, run Runner, logger *slog.Logger) *Cluster {
+	return &Cluster{
+		cfg:          cfg,
+		run:          run,
+		logger:       logger,
+		NodeCount:    len(cfg.Nodes),
+		NodeVersions: make([]string, len(cfg.Nodes)),
+	}
+}
+
+// Host is the address of the REST API of a node
+func (c *Cluster) Host(nodeId int) string {
+	return c.cfg.Nodes[nodeId].Address
+}
+
+func (c *Cluster) Hostname(nodeId int) string {
+	return c.cfg.Nodes[nodeId].Name
+}
+
+func (c *Cluster) exec(ctx context.Context, nodeId int, command, version string) ([]byte, error) {
+	node := c.cfg.Nodes[nodeId]
+	out, err := c.run(ctx, node.SSH, render(command, node, version))
+	if err != nil {
+		return out, fmt.Errorf("%s: %w", node.Name, err)
+	}
+	return out, nil
+}
+
+// Reset stops every node and removes its data, so that the journey starts
+// from an empty cluster
+func (c *Cluster) Reset(ctx context.Context) error {
+	for i := range c.cfg.Nodes {
+		// a node that does not run yet fails to stop
+		if _, err := c.exec(ctx, i, c.cfg.Commands.Stop, ""); err != nil {
+			c.logger.Debug("cannot stop node before wiping it", "node", c.Hostname(i), "err", err)
+		}
+		if _, err := c.exec(ctx, i, c.cfg.Commands.Wipe, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StartAllNodes launches every node and then waits for all of them, as a
+// node may not become ready before enough of its peers are up
+func (c *Cluster) StartAllNodes(ctx context.Context, version string) error {
+	for i := range c.cfg.Nodes {
+		if _, err := c.exec(ctx, i, c.cfg.Commands.Start, version); err != nil {
+			return err
+		}
+		c.NodeVersions[i] = version
+	}
+
+	for i := range c.cfg.Nodes {
+		if err := c.waitReady(ctx, i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RollingUpdate restarts the nodes one by one on the version, every node
+// has to see the whole cluster healthy before the next one goes down
+func (c *Cluster) RollingUpdate(ctx context.Context, version string) error {
+	c.logger.Info("starting rolling update", "version", version)
+	for i := range c.cfg.Nodes {
+		if err := c.StopNode(ctx, i); err != nil {
+			return err
+		}
+
+		if err := c.StartNode(ctx, i, version); err != nil {
+			c.logger.Error("node did not start", "node", c.Hostname(i), "version", version, "err", err)
+			return err
+		}
+
+		if err := c.waitHealthy(ctx, i); err != nil {
+			return err
+		}
+	}
+
+	c.logger.Info("completed rolling update", "version", version)
+	return nil
+}
+
+// StartNode starts a stopped node and waits until it is ready
+func (c *Cluster) StartNode(ctx context.Context, nodeId int, version string) error {
+	if _, err := c.exec(ctx, nodeId, c.cfg.Commands.Start, version); err != nil {
+		return err
+	}
+	c.NodeVersions[nodeId] = version
+	return c.waitReady(ctx, nodeId)
+}
+
+func (c *Cluster) StopNode(ctx context.Context, nodeId int) error {
+	_, err := c.exec(ctx, nodeId, c.cfg.Commands.Stop, c.NodeVersions[nodeId])
+	return err
+}
+
+// KillNode stops a node without giving it a chance to shut down
+func (c *Cluster) KillNode(ctx context.Context, nodeId int) error {
+	_, err := c.exec(ctx, nodeId, c.cfg.Commands.Kill, c.NodeVersions[nodeId])
+	return err
+}
+
+// SaveLogs writes the logs of every node to dir, a node whose logs cannot
+// be read is skipped
+func (c *Cluster) SaveLogs(ctx context.Context, dir string) error {
+	if c.cfg.Commands.Logs == "" {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0o777); err != nil {
+		return err
+	}
+
+	for i := range c.cfg.Nodes {
+		out, err := c.exec(ctx, i, c.cfg.Commands.Logs, c.NodeVersions[i])
+		if err != nil {
+			c.logger.Warn("cannot read logs", "node", c.Hostname(i), "err", err)
+			continue
+		}
+		if err := os.WriteFile(path.Join(dir, c.Hostname(i)+".log"), out, 0o666); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Cluster) waitReady(ctx context.Context, nodeId int) error {
+	return c.poll(ctx, nodeId, func() error {
+		_, err := c.get(ctx, nodeId, "/v1/.well-known/ready")
+		return err
+	})
+}
+
+// waitHealthy waits until the node reports every node as healthy
+func (c *Cluster) waitHealthy(ctx context.Context, nodeId int) error {
+	return c.poll(ctx, nodeId, func() error {
+		raw, err := c.get(ctx, nodeId, "/v1/nodes")
+		if err != nil {
+			return err
+		}
+
+		var nodes struct {
+			Nodes []struct {
+				Status string `json:"status"`
+			} `json:"nodes"`
+		}
+		if err := json.Unmarshal(raw, &nodes); err != nil {
+			return err
+		}
+		healthy := 0
+		for _, node := range nodes.Nodes {
+			if node.Status == "HEALTHY" {
+				healthy++
+			}
+		}
+		if healthy != c.NodeCount {
+			return fmt.Errorf("%d of %d nodes healthy", healthy, c.NodeCount)
+		}
+		return nil
+	})
+}
+
+func (c *Cluster) poll(ctx context.Context, nodeId int, check func() error) error {
+	deadline := time.Now().Add(c.cfg.ReadyTimeout)
+	for {
+		err := check()
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s not ready within %s: %w", c.Hostname(nodeId), c.cfg.ReadyTimeout, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (c *Cluster) get(ctx context.Context, nodeId int, endpoint string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("http://%s%s", c.Host(nodeId), endpoint),
+		nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	raw, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, fmt.Errorf("GET %s: status %d: %s", endpoint, res.StatusCode, raw)
+	}
+	return raw, nil
+}
@@ -0,0 +1,90 @@
+package remote
+
+import (
+	"context"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/exp/slog"
+)
+
+func TestLoadConfig(t *testing.T) {
+	file := path.Join(t.TempDir(), "remote.yaml")
+	os.WriteFile(file, []byte(`
+preset: systemd
+commands:
+  logs: tail -n 1000 /var/log/weaviate.log
+nodes:
+  - ssh: ubuntu@10.0.0.10
+    address: 10.0.0.10:8080
+    data: /var/lib/weaviate
+`), 0o644)
+
+	cfg, err := LoadConfig(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Commands.Start != presets["systemd"].Start || cfg.Commands.Logs != "tail -n 1000 /var/log/weaviate.log" {
+		t.Errorf("wanted the preset with the logs overridden, got %+v", cfg.Commands)
+	}
+	if cfg.Nodes[0].Name != "weaviate-0" || cfg.ReadyTimeout != 5*time.Minute {
+		t.Errorf("wanted the defaults, got %+v", cfg)
+	}
+
+	tests := []struct {
+		name   string
+		config string
+	}{
+		{name: "unknown key", config: "preset: systemd\nnode: []\n"},
+		{name: "unknown preset", config: "preset: k8s\nnodes: [{ssh: a, address: a:8080, data: /d}]\n"},
+		{name: "no commands", config: "nodes: [{ssh: a, address: a:8080, data: /d}]\n"},
+		{name: "no data", config: "preset: docker\nnodes: [{ssh: a, address: a:8080}]\n"},
+	}
+	for _, tt := range tests {
+		os.WriteFile(file, []byte(tt.config), 0o644)
+		if _, err := LoadConfig(file); err == nil {
+			t.Errorf("%s: wanted an error", tt.name)
+		}
+	}
+}
+
+func TestCommands(t *testing.T) {
+	cfg := Config{
+		Preset: "docker",
+		Nodes: []Node{
+			{SSH: "a", Address: "a:8080", Data: "/data/a"},
+			{SSH: "b", Address: "b:8080", Data: "/data/b"},
+		},
+	}
+	if err := cfg.complete(); err != nil {
+		t.Fatal(err)
+	}
+
+	var ran []string
+	c := New(cfg, func(ctx context.Context, dest, command string) ([]byte, error) {
+		ran = append(ran, dest+": "+command)
+		return nil, nil
+	}, slog.Default())
+	c.NodeVersions[1] = "1.25.0"
+
+	if err := c.Reset(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.KillNode(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"a: docker stop weaviate-0",
+		"a: docker rm -f weaviate-0 >/dev/null 2>&1; sudo rm -rf /data/a",
+		"b: docker stop weaviate-1",
+		"b: docker rm -f weaviate-1 >/dev/null 2>&1; sudo rm -rf /data/b",
+		"b: docker kill weaviate-1",
+	}
+	if !reflect.DeepEqual(ran, want) {
+		t.Errorf("wanted %q, got %q", want, ran)
+	}
+}
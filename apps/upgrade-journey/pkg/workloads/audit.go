@@ -0,0 +1,290 @@
+package workloads
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+type LedgerOp string
+
+const (
+	LedgerCreate LedgerOp = "create"
+	LedgerUpdate LedgerOp = "update"
+	LedgerDelete LedgerOp = "delete"
+)
+
+// every ledgerDeleteEvery-th mutation deletes the entry, all others update
+// it
+const ledgerDeleteEvery = 4
+
+// LedgerClockSkew is how far the timestamps Weaviate stores may be off the
+// clock of the harness. The default fits nodes on the same host.
+var LedgerClockSkew = time.Second
+
+// LedgerEvent is a single write in the history of an entry. Started is
+// when the first attempt was sent, Finished when the last one returned, the
+// server applied the write somewhere in between.
+type LedgerEvent struct {
+	Op       LedgerOp
+	Status   WriteStatus
+	Host     string
+	Hop      int
+	Revision int
+	Started  time.Time
+	Finished time.Time
+}
+
+// Mutate updates or deletes n entries whose creation was acknowledged,
+// through host like Write. An entry that is deleted, or whose deletion may
+// have happened, is never touched again.
+func (l *Ledger) Mutate(ctx context.Context, host string, hop, n int) error {
+	l.Lock()
+	var candidates []string
+	for _, id := range l.order {
+		if l.entries[id] == WriteAcknowledged && !l.deleteAttempted(id) {
+			candidates = append(candidates, id)
+		}
+	}
+	l.Unlock()
+
+	rand.Shuffle(len(candidates), func(a, b int) { candidates[a], candidates[b] = candidates[b], candidates[a] })
+	if len(candidates) > n {
+		candidates = candidates[:n]
+	}
+
+	for _, id := range candidates {
+		l.Lock()
+		l.mutations++
+		del := l.mutations%ledgerDeleteEvery == 0
+		revision := l.lastRevision(id) + 1
+		l.Unlock()
+
+		event := LedgerEvent{Op: LedgerUpdate, Host: host, Hop: hop, Revision: revision, Started: time.Now()}
+		if del {
+			event.Op = LedgerDelete
+			event.Status = l.deleteOne(ctx, host, id)
+		} else {
+			event.Status = l.updateOne(ctx, host, id, revision)
+		}
+		event.Finished = time.Now()
+
+		l.Lock()
+		l.history[id] = append(l.history[id], event)
+		l.Unlock()
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if event.Status == WriteUnacknowledged && l.windows != nil {
+			if _, ok := l.windows.During(event.Started, event.Finished); !ok {
+				return fmt.Errorf("%s of %s unacknowledged outside of any fault window", event.Op, id)
+			}
+		}
+	}
+	return nil
+}
+
+// HistoryOfHops returns a copy of the whole history of every entry with a
+// write on a hop from from to to, the entries a failure of these hops may
+// be about
+func (l *Ledger) HistoryOfHops(from, to int) map[string][]LedgerEvent {
+	l.Lock()
+	defer l.Unlock()
+
+	history := map[string][]LedgerEvent{}
+	for id, events := range l.history {
+		for _, event := range events {
+			if event.Hop >= from && event.Hop <= to {
+				history[id] = append([]LedgerEvent(nil), events...)
+				break
+			}
+		}
+	}
+	return history
+}
+
+// deleteAttempted reports whether a deletion of the entry was sent. The
+// caller holds the lock.
+func (l *Ledger) deleteAttempted(id string) bool {
+	_, ok := l.deletion(id)
+	return ok
+}
+
+// lastRevision is the highest revision ever sent for the entry. The caller
+// holds the lock.
+func (l *Ledger) lastRevision(id string) int {
+	revision := 0
+	for _, event := range l.history[id] {
+		if event.Revision > revision {
+			revision = event.Revision
+		}
+	}
+	return revision
+}
+
+func (l *Ledger) updateOne(ctx context.Context, host, id string, revision int) WriteStatus {
+	payload := map[string]interface{}{
+		"class":      LedgerClass,
+		"properties": map[string]interface{}{"revision": revision},
+	}
+
+	for attempt := 0; attempt <= ledgerWriteRetries; attempt++ {
+		// setting the revision is idempotent, a retry is always safe
+		status, _, err := l.send(ctx, http.MethodPatch, host, fmt.Sprintf("/v1/objects/%s/%s", LedgerClass, id),
+			payload)
+		if err == nil && status == http.StatusNoContent {
+			return WriteAcknowledged
+		}
+
+		l.logger.Debug("ledger update failed", "id", id, "attempt", attempt, "status", status, "err", err)
+	}
+	return WriteUnacknowledged
+}
+
+func (l *Ledger) deleteOne(ctx context.Context, host, id string) WriteStatus {
+	for attempt := 0; attempt <= ledgerWriteRetries; attempt++ {
+		status, _, err := l.send(ctx, http.MethodDelete, host, fmt.Sprintf("/v1/objects/%s/%s", LedgerClass, id),
+			nil)
+		switch {
+		case err == nil && status == http.StatusNoContent:
+			return WriteAcknowledged
+		case err == nil && status == http.StatusNotFound && attempt > 0:
+			// an earlier, unacknowledged attempt went through
+			return WriteAcknowledged
+		}
+
+		l.logger.Debug("ledger delete failed", "id", id, "attempt", attempt, "status", status, "err", err)
+	}
+	return WriteUnacknowledged
+}
+
+// deletion returns the deletion of the entry, if one was sent. The entry
+// must be gone if it was acknowledged, and may be gone otherwise. The caller
+// holds the lock.
+func (l *Ledger) deletion(id string) (LedgerEvent, bool) {
+	for _, event := range l.history[id] {
+		if event.Op == LedgerDelete {
+			return event, true
+		}
+	}
+	return LedgerEvent{}, false
+}
+
+// auditedEntry is what Weaviate stores about an entry
+type auditedEntry struct {
+	revision int
+	created  int64
+	updated  int64
+}
+
+func storedLedgerAudit(ctx context.Context, host string) (map[string]auditedEntry, error) {
+	raw, err := get(ctx, host, fmt.Sprintf("/v1/objects?class=%s&limit=100000", LedgerClass))
+	if err != nil {
+		return nil, err
+	}
+
+	var res struct {
+		Objects []struct {
+			ID                 string `json:"id"`
+			CreationTimeUnix   int64  `json:"creationTimeUnix"`
+			LastUpdateTimeUnix int64  `json:"lastUpdateTimeUnix"`
+			Properties         struct {
+				Revision int `json:"revision"`
+			} `json:"properties"`
+		} `json:"objects"`
+	}
+	if err := json.NewDecoder(bytes.NewReader(raw)).Decode(&res); err != nil {
+		return nil, err
+	}
+
+	entries := make(map[string]auditedEntry, len(res.Objects))
+	for _, obj := range res.Objects {
+		entries[obj.ID] = auditedEntry{
+			revision: obj.Properties.Revision,
+			created:  obj.CreationTimeUnix,
+			updated:  obj.LastUpdateTimeUnix,
+		}
+	}
+	return entries, nil
+}
+
+// within reports whether a timestamp in milliseconds falls into the time
+// the event took, give or take the clock skew
+func (e LedgerEvent) within(unixMilli int64) bool {
+	at := time.UnixMilli(unixMilli)
+	return !at.Before(e.Started.Add(-LedgerClockSkew)) && !at.After(e.Finished.Add(LedgerClockSkew))
+}
+
+// auditEntry cross-checks the timestamps and the revision Weaviate stores
+// for an entry with its history. firstCreated is the creation time an
+// earlier audit saw, 0 on the first one.
+func auditEntry(history []LedgerEvent, stored auditedEntry, firstCreated int64) []string {
+	var broken []string
+	if len(history) == 0 {
+		return nil
+	}
+
+	create := history[0]
+	if !create.within(stored.created) {
+		broken = append(broken, fmt.Sprintf("creationTimeUnix %d is outside of its creation on hop %d via %s "+
+			"(%d to %d)", stored.created, create.Hop, create.Host, create.Started.UnixMilli(),
+			create.Finished.UnixMilli()))
+	}
+	if firstCreated != 0 && stored.created != firstCreated {
+		broken = append(broken, fmt.Sprintf("creationTimeUnix changed from %d to %d", firstCreated, stored.created))
+	}
+	if stored.updated < stored.created {
+		broken = append(broken, fmt.Sprintf("lastUpdateTimeUnix %d is before creationTimeUnix %d", stored.updated,
+			stored.created))
+	}
+
+	// the last write that must have happened, and every later one that may
+	// have, could be the last one applied
+	candidates := []LedgerEvent{create}
+	for _, event := range history[1:] {
+		if event.Op != LedgerUpdate {
+			continue
+		}
+		if event.Status == WriteAcknowledged {
+			candidates = candidates[:0]
+		}
+		candidates = append(candidates, event)
+	}
+
+	for _, event := range candidates {
+		if event.Revision == stored.revision && event.within(stored.updated) {
+			return broken
+		}
+	}
+
+	last := candidates[len(candidates)-1]
+	return append(broken, fmt.Sprintf("revision %d updated at %d does not match its history, last %s on hop %d "+
+		"via %s set revision %d between %d and %d", stored.revision, stored.updated, last.Op, last.Hop, last.Host,
+		last.Revision, last.Started.UnixMilli(), last.Finished.UnixMilli()))
+}
+
+// auditLedger checks every stored entry against its history and returns
+// the broken entries in write order. The caller holds the lock.
+func (l *Ledger) auditLedger(stored map[string]auditedEntry) []string {
+	var broken []string
+	for _, id := range l.order {
+		entry, ok := stored[id]
+		if !ok {
+			continue
+		}
+
+		for _, problem := range auditEntry(l.history[id], entry, l.firstCreated[id]) {
+			broken = append(broken, fmt.Sprintf("%s: %s", id, problem))
+		}
+		if l.firstCreated[id] == 0 {
+			l.firstCreated[id] = entry.created
+		}
+	}
+	return broken
+}
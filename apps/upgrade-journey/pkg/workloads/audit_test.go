@@ -0,0 +1,107 @@
+package workloads
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAuditEntry(t *testing.T) {
+	start := time.UnixMilli(1_700_000_000_000)
+	event := func(op LedgerOp, status WriteStatus, revision int, at time.Duration) LedgerEvent {
+		return LedgerEvent{
+			Op: op, Status: status, Host: "localhost:8080", Revision: revision,
+			Started: start.Add(at), Finished: start.Add(at + 100*time.Millisecond),
+		}
+	}
+	create := event(LedgerCreate, WriteAcknowledged, 0, 0)
+	at := func(d time.Duration) int64 { return start.Add(d).UnixMilli() }
+
+	tests := []struct {
+		name         string
+		history      []LedgerEvent
+		stored       auditedEntry
+		firstCreated int64
+		wantBroken   int
+	}{
+		{
+			name:    "never updated",
+			history: []LedgerEvent{create},
+			stored:  auditedEntry{created: at(50 * time.Millisecond), updated: at(50 * time.Millisecond)},
+		},
+		{
+			name:    "acknowledged update",
+			history: []LedgerEvent{create, event(LedgerUpdate, WriteAcknowledged, 1, time.Minute)},
+			stored:  auditedEntry{revision: 1, created: at(0), updated: at(time.Minute)},
+		},
+		{
+			name:       "acknowledged update lost",
+			history:    []LedgerEvent{create, event(LedgerUpdate, WriteAcknowledged, 1, time.Minute)},
+			stored:     auditedEntry{created: at(0), updated: at(0)},
+			wantBroken: 1,
+		},
+		{
+			name: "unacknowledged update applied",
+			history: []LedgerEvent{
+				create, event(LedgerUpdate, WriteAcknowledged, 1, time.Minute),
+				event(LedgerUpdate, WriteUnacknowledged, 2, 2*time.Minute),
+			},
+			stored: auditedEntry{revision: 2, created: at(0), updated: at(2 * time.Minute)},
+		},
+		{
+			name: "unacknowledged update not applied",
+			history: []LedgerEvent{
+				create, event(LedgerUpdate, WriteAcknowledged, 1, time.Minute),
+				event(LedgerUpdate, WriteUnacknowledged, 2, 2*time.Minute),
+			},
+			stored: auditedEntry{revision: 1, created: at(0), updated: at(time.Minute)},
+		},
+		{
+			name:       "update time outside of the update",
+			history:    []LedgerEvent{create, event(LedgerUpdate, WriteAcknowledged, 1, time.Minute)},
+			stored:     auditedEntry{revision: 1, created: at(0), updated: at(3 * time.Minute)},
+			wantBroken: 1,
+		},
+		{
+			name:       "created outside of the creation",
+			history:    []LedgerEvent{create},
+			stored:     auditedEntry{created: at(time.Hour), updated: at(time.Hour)},
+			wantBroken: 2,
+		},
+		{
+			name:         "creation time changed",
+			history:      []LedgerEvent{create},
+			stored:       auditedEntry{created: at(500 * time.Millisecond), updated: at(500 * time.Millisecond)},
+			firstCreated: at(0),
+			wantBroken:   1,
+		},
+		{
+			name:       "updated before created",
+			history:    []LedgerEvent{create},
+			stored:     auditedEntry{created: at(500 * time.Millisecond), updated: at(0)},
+			wantBroken: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			broken := auditEntry(tt.history, tt.stored, tt.firstCreated)
+			if len(broken) != tt.wantBroken {
+				t.Errorf("wanted %d problems, got %v", tt.wantBroken, broken)
+			}
+		})
+	}
+}
+
+func TestHistoryOfHops(t *testing.T) {
+	l := NewLedger(LedgerWriteTimeout, nil)
+	l.history = map[string][]LedgerEvent{
+		"old":     {{Op: LedgerCreate, Hop: 0}},
+		"updated": {{Op: LedgerCreate, Hop: 0}, {Op: LedgerUpdate, Hop: 2}},
+		"new":     {{Op: LedgerCreate, Hop: 3}},
+	}
+
+	got := l.HistoryOfHops(1, 2)
+	if len(got) != 1 || len(got["updated"]) != 2 {
+		t.Errorf("wanted the whole history of the updated entry only, got %v", got)
+	}
+}
@@ -0,0 +1,62 @@
+package workloads
+
+import (
+	"context"
+	"fmt"
+)
+
+// ExpectedCount returns how many entries the ledger class holds at least
+// and at most. An ambiguous creation or deletion may or may not have
+// happened, so it only widens the range.
+func (l *Ledger) ExpectedCount() (min, max int) {
+	l.Lock()
+	defer l.Unlock()
+
+	for id, status := range l.entries {
+		deleted, mayBeDeleted := l.deletion(id)
+		switch {
+		case mayBeDeleted && deleted.Status == WriteAcknowledged:
+		case status == WriteAcknowledged && !mayBeDeleted:
+			min++
+			max++
+		default:
+			max++
+		}
+	}
+	return min, max
+}
+
+// CountDrift is how far count is outside of the range the ledger expects,
+// 0 if it is within
+func (l *Ledger) CountDrift(count int) int {
+	min, max := l.ExpectedCount()
+	switch {
+	case count < min:
+		return min - count
+	case count > max:
+		return count - max
+	default:
+		return 0
+	}
+}
+
+// LedgerAggregateCount returns the count of the ledger class as Aggregate
+// reports it, which unlike listing the objects comes from the counters of
+// the shards
+func LedgerAggregateCount(ctx context.Context, host string) (int, error) {
+	var res struct {
+		Aggregate map[string][]struct {
+			Meta struct {
+				Count int `json:"count"`
+			} `json:"meta"`
+		} `json:"Aggregate"`
+	}
+	if err := graphQL(ctx, host, fmt.Sprintf(`{Aggregate{%s{meta{count}}}}`, LedgerClass), &res); err != nil {
+		return 0, fmt.Errorf("aggregate %s: %w", LedgerClass, err)
+	}
+
+	if groups := res.Aggregate[LedgerClass]; len(groups) > 0 {
+		return groups[0].Meta.Count, nil
+	}
+	return 0, nil
+}
@@ -0,0 +1,31 @@
+package workloads
+
+import (
+	"testing"
+
+	"golang.org/x/exp/slog"
+)
+
+func TestLedgerExpectedCount(t *testing.T) {
+	l := NewLedger(LedgerWriteTimeout, slog.Default())
+	l.entries = map[string]WriteStatus{
+		"kept":          WriteAcknowledged,
+		"ambiguous":     WriteUnacknowledged,
+		"deleted":       WriteAcknowledged,
+		"maybe deleted": WriteAcknowledged,
+	}
+	l.history = map[string][]LedgerEvent{
+		"deleted":       {{Op: LedgerCreate, Status: WriteAcknowledged}, {Op: LedgerDelete, Status: WriteAcknowledged}},
+		"maybe deleted": {{Op: LedgerCreate, Status: WriteAcknowledged}, {Op: LedgerDelete, Status: WriteUnacknowledged}},
+	}
+
+	if min, max := l.ExpectedCount(); min != 1 || max != 3 {
+		t.Errorf("wanted 1 to 3 entries, got %d to %d", min, max)
+	}
+
+	for count, want := range map[int]int{0: 1, 1: 0, 3: 0, 5: 2} {
+		if got := l.CountDrift(count); got != want {
+			t.Errorf("count %d: wanted a drift of %d, got %d", count, want, got)
+		}
+	}
+}
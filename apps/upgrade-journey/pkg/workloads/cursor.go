@@ -0,0 +1,126 @@
+package workloads
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+)
+
+// cursorPageSize is small, so that the pass takes many pages and the
+// concurrent writes land between them
+const cursorPageSize = 10
+
+// CursorStats summarize a pass through the ledger with the cursor API
+type CursorStats struct {
+	Pages int
+	Seen  int
+
+	// Settled are the entries that existed before the pass and every one
+	// of which it had to return
+	Settled int
+}
+
+// VerifyCursor pages through the ledger with the cursor API on readHost
+// while new entries are written through writeHost. Every entry whose
+// creation was acknowledged before the pass, and that was never deleted,
+// has to be returned exactly once, in id order. Entries written during the
+// pass may or may not be returned.
+func (l *Ledger) VerifyCursor(ctx context.Context, readHost, writeHost string, hop, writes int) (CursorStats, error) {
+	settled := l.settled()
+
+	written := make(chan error, 1)
+	go func() {
+		written <- l.Write(ctx, writeHost, hop, writes)
+	}()
+
+	seen, pages, err := cursorPass(ctx, readHost)
+	if writeErr := <-written; writeErr != nil {
+		return CursorStats{}, fmt.Errorf("write during cursor pass: %w", writeErr)
+	}
+	if err != nil {
+		return CursorStats{}, err
+	}
+
+	stats := CursorStats{Pages: pages, Seen: len(seen), Settled: len(settled)}
+	return stats, checkCursorPass(seen, settled, l.Entries())
+}
+
+// settled returns the entries whose creation was acknowledged and whose
+// deletion was never attempted
+func (l *Ledger) settled() map[string]bool {
+	l.Lock()
+	defer l.Unlock()
+
+	settled := map[string]bool{}
+	for id, status := range l.entries {
+		if status == WriteAcknowledged && !l.deleteAttempted(id) {
+			settled[id] = true
+		}
+	}
+	return settled
+}
+
+// cursorPass returns the ids of every page until the first empty one
+func cursorPass(ctx context.Context, host string) ([]string, int, error) {
+	var seen []string
+	after := ""
+	for pages := 1; ; pages++ {
+		endpoint := fmt.Sprintf("/v1/objects?class=%s&limit=%d", LedgerClass, cursorPageSize)
+		if after != "" {
+			endpoint += "&after=" + url.QueryEscape(after)
+		}
+
+		raw, err := get(ctx, host, endpoint)
+		if err != nil {
+			return nil, pages, fmt.Errorf("cursor page %d after %q: %w", pages, after, err)
+		}
+
+		var res struct {
+			Objects []struct {
+				ID string `json:"id"`
+			} `json:"objects"`
+		}
+		if err := json.Unmarshal(raw, &res); err != nil {
+			return nil, pages, err
+		}
+
+		if len(res.Objects) == 0 {
+			return seen, pages, nil
+		}
+		for _, obj := range res.Objects {
+			seen = append(seen, obj.ID)
+		}
+		after = res.Objects[len(res.Objects)-1].ID
+	}
+}
+
+// checkCursorPass returns an error for the first anomaly of a pass: ids out
+// of order or returned twice, ids that were never written, or settled
+// entries that were skipped
+func checkCursorPass(seen []string, settled map[string]bool, written map[string]WriteStatus) error {
+	returned := make(map[string]bool, len(seen))
+	for i, id := range seen {
+		if i > 0 && id <= seen[i-1] {
+			return fmt.Errorf("cursor returned %s after %s, out of id order", id, seen[i-1])
+		}
+		if _, ok := written[id]; !ok {
+			return fmt.Errorf("cursor returned %s, which was never written", id)
+		}
+		returned[id] = true
+	}
+
+	var skipped []string
+	for id := range settled {
+		if !returned[id] {
+			skipped = append(skipped, id)
+		}
+	}
+	if len(skipped) > 0 {
+		sort.Strings(skipped)
+		return fmt.Errorf("cursor skipped %d of %d entries that existed before the pass, e.g. %s", len(skipped),
+			len(settled), skipped[0])
+	}
+	return nil
+}
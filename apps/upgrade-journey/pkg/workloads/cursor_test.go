@@ -0,0 +1,32 @@
+package workloads
+
+import (
+	"testing"
+)
+
+func TestCheckCursorPass(t *testing.T) {
+	written := map[string]WriteStatus{
+		"a": WriteAcknowledged, "b": WriteAcknowledged, "c": WriteUnacknowledged, "d": WriteAcknowledged,
+	}
+	settled := map[string]bool{"a": true, "b": true}
+
+	tests := []struct {
+		name    string
+		seen    []string
+		wantErr bool
+	}{
+		{name: "all settled", seen: []string{"a", "b"}},
+		{name: "written during the pass", seen: []string{"a", "b", "c", "d"}},
+		{name: "skipped", seen: []string{"a", "d"}, wantErr: true},
+		{name: "out of order", seen: []string{"b", "a"}, wantErr: true},
+		{name: "returned twice", seen: []string{"a", "a", "b"}, wantErr: true},
+		{name: "never written", seen: []string{"a", "b", "x"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkCursorPass(tt.seen, settled, written); (err != nil) != tt.wantErr {
+				t.Errorf("wanted error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
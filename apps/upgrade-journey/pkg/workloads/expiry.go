@@ -0,0 +1,152 @@
+package workloads
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// None of the versions in the journey expire objects on their own, so
+// expiration is done the way users do it: objects carry an expiry date and
+// a scheduled job deletes everything past it with a filtered batch delete.
+// The workload checks that the sweep keeps deleting exactly the expired
+// objects across restarts and upgrades. Time is logical, one day per hop,
+// so that the result does not depend on how long a hop takes.
+const (
+	ExpiringClass = "Expiring"
+
+	// every hop imports one object expiring on each of the following hops
+	expiringLifetimeHops = 3
+)
+
+var expiryEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+type Expiry struct {
+	sync.Mutex
+
+	// expiresAt is the hop on which an object expires, by id
+	expiresAt map[string]int
+}
+
+func NewExpiry() *Expiry {
+	return &Expiry{expiresAt: map[string]int{}}
+}
+
+func hopTime(hop int) string {
+	return expiryEpoch.AddDate(0, 0, hop).Format(time.RFC3339)
+}
+
+func (w *Expiry) CreateClass(ctx context.Context, host string) error {
+	_, err := send(ctx, http.MethodPost, host, "/v1/schema", map[string]interface{}{
+		"class":      ExpiringClass,
+		"vectorizer": "none",
+		"properties": []map[string]interface{}{
+			{"name": "hop", "dataType": []string{"int"}},
+			{"name": "expires_at", "dataType": []string{"date"}},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("create class %s: %w", ExpiringClass, err)
+	}
+
+	return nil
+}
+
+// ImportAndSweep imports the objects of the hop and then runs the sweep
+// that deletes everything that expired up to and including this hop
+func (w *Expiry) ImportAndSweep(ctx context.Context, host string, hop int) error {
+	w.Lock()
+	defer w.Unlock()
+
+	for lifetime := 1; lifetime <= expiringLifetimeHops; lifetime++ {
+		id := uuid.New().String()
+		if _, err := send(ctx, http.MethodPost, host, "/v1/objects", map[string]interface{}{
+			"class": ExpiringClass,
+			"id":    id,
+			"properties": map[string]interface{}{
+				"hop":        hop,
+				"expires_at": hopTime(hop + lifetime),
+			},
+		}); err != nil {
+			return fmt.Errorf("import expiring object: %w", err)
+		}
+		w.expiresAt[id] = hop + lifetime
+	}
+
+	raw, err := send(ctx, http.MethodDelete, host, "/v1/batch/objects", map[string]interface{}{
+		"match": map[string]interface{}{
+			"class": ExpiringClass,
+			"where": map[string]interface{}{
+				"path":      []string{"expires_at"},
+				"operator":  "LessThanEqual",
+				"valueDate": hopTime(hop),
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("sweep expired objects: %w", err)
+	}
+
+	var res struct {
+		Results struct {
+			Matches int `json:"matches"`
+			Failed  int `json:"failed"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return err
+	}
+	if res.Results.Failed > 0 {
+		return fmt.Errorf("sweep expired objects: %d of %d deletes failed",
+			res.Results.Failed, res.Results.Matches)
+	}
+
+	for id, expiresAt := range w.expiresAt {
+		if expiresAt <= hop {
+			delete(w.expiresAt, id)
+		}
+	}
+
+	return nil
+}
+
+// Verify checks that exactly the objects that did not expire yet exist
+func (w *Expiry) Verify(ctx context.Context, host string) error {
+	raw, err := get(ctx, host, fmt.Sprintf("/v1/objects?class=%s&limit=10000", ExpiringClass))
+	if err != nil {
+		return err
+	}
+
+	var res struct {
+		Objects []struct {
+			ID         string `json:"id"`
+			Properties struct {
+				ExpiresAt string `json:"expires_at"`
+			} `json:"properties"`
+		} `json:"objects"`
+	}
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return err
+	}
+
+	w.Lock()
+	defer w.Unlock()
+
+	for _, obj := range res.Objects {
+		if _, ok := w.expiresAt[obj.ID]; !ok {
+			return fmt.Errorf("object %s expiring at %s should have been deleted",
+				obj.ID, obj.Properties.ExpiresAt)
+		}
+	}
+
+	if len(res.Objects) != len(w.expiresAt) {
+		return fmt.Errorf("wanted %d unexpired objects, got %d", len(w.expiresAt), len(res.Objects))
+	}
+
+	return nil
+}
@@ -0,0 +1,207 @@
+package workloads
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+const (
+	ledgerDims = 16
+
+	// neighbors every filtered search asks for
+	filteredLimit = 5
+
+	// searches per filter, with query vectors that were never written
+	filteredQueries = 3
+
+	// share of the true neighbors a filtered search must return. Filtered
+	// HNSW is approximate for very restrictive filters, but returning an
+	// object that does not match the filter is always wrong.
+	filteredMinRecall = 0.8
+)
+
+// ledgerVector is the vector of an entry, derived from its seq so that the
+// ledger does not need to keep it
+func ledgerVector(seq int) []float32 {
+	rng := rand.New(rand.NewSource(int64(seq)))
+	vector := make([]float32, ledgerDims)
+	for i := range vector {
+		vector[i] = rng.Float32()*2 - 1
+	}
+	return vector
+}
+
+// ledgerPoint is a stored entry of the ledger
+type ledgerPoint struct {
+	id       string
+	seq, hop int
+}
+
+// ledgerFilter is a where filter together with the entries it matches
+type ledgerFilter struct {
+	name  string
+	where string
+	match func(p ledgerPoint) bool
+}
+
+// storedPoints returns the stored entries in write order. The caller holds
+// the lock.
+func (l *Ledger) storedPoints(stored map[string]int) []ledgerPoint {
+	points := make([]ledgerPoint, 0, len(stored))
+	for seq, id := range l.order {
+		if hop, ok := stored[id]; ok {
+			points = append(points, ledgerPoint{id: id, seq: seq, hop: hop})
+		}
+	}
+	return points
+}
+
+// ledgerFilters restricts the search to the entries of one hop and to a
+// narrow range of writes, both select only a small share of the ledger
+func ledgerFilters(points []ledgerPoint) []ledgerFilter {
+	if len(points) == 0 {
+		return nil
+	}
+
+	hop := points[len(points)-1].hop
+	from := points[len(points)/2].seq
+	to := from + 10
+
+	return []ledgerFilter{
+		{
+			name:  fmt.Sprintf("hop == %d", hop),
+			where: fmt.Sprintf(`{operator:Equal,path:["hop"],valueInt:%d}`, hop),
+			match: func(p ledgerPoint) bool { return p.hop == hop },
+		},
+		{
+			name: fmt.Sprintf("%d <= seq < %d", from, to),
+			where: fmt.Sprintf(`{operator:And,operands:[`+
+				`{operator:GreaterThanEqual,path:["seq"],valueInt:%d},`+
+				`{operator:LessThan,path:["seq"],valueInt:%d}]}`, from, to),
+			match: func(p ledgerPoint) bool { return p.seq >= from && p.seq < to },
+		},
+	}
+}
+
+func cosineDistance(a, b []float32) float64 {
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 1
+	}
+	return 1 - dot/math.Sqrt(normA*normB)
+}
+
+// filteredTruth returns the ids of the k entries closest to query among the
+// ones matching the filter, by brute force
+func filteredTruth(points []ledgerPoint, query []float32, match func(p ledgerPoint) bool, k int) []string {
+	type scored struct {
+		id       string
+		distance float64
+	}
+
+	var matching []scored
+	for _, p := range points {
+		if match(p) {
+			matching = append(matching, scored{id: p.id, distance: cosineDistance(query, ledgerVector(p.seq))})
+		}
+	}
+	sort.Slice(matching, func(a, b int) bool { return matching[a].distance < matching[b].distance })
+
+	if len(matching) > k {
+		matching = matching[:k]
+	}
+	ids := make([]string, len(matching))
+	for i, s := range matching {
+		ids[i] = s.id
+	}
+	return ids
+}
+
+// filteredRecall is the share of truth found in got
+func filteredRecall(truth, got []string) float64 {
+	if len(truth) == 0 {
+		return 1
+	}
+
+	found := map[string]bool{}
+	for _, id := range got {
+		found[id] = true
+	}
+	hits := 0
+	for _, id := range truth {
+		if found[id] {
+			hits++
+		}
+	}
+	return float64(hits) / float64(len(truth))
+}
+
+// verifyLedgerFilteredSearch runs nearVector searches with restrictive
+// filters and compares them against the brute-force neighbors among the
+// stored entries. It returns the lowest recall of the searches.
+func verifyLedgerFilteredSearch(ctx context.Context, host string, points []ledgerPoint) (float64, error) {
+	byID := make(map[string]ledgerPoint, len(points))
+	for _, p := range points {
+		byID[p.id] = p
+	}
+
+	lowest := 1.0
+	for _, filter := range ledgerFilters(points) {
+		for q := 0; q < filteredQueries; q++ {
+			// negative seeds never collide with the seq of an entry
+			query := ledgerVector(-1 - q)
+			literal := make([]string, len(query))
+			for i, v := range query {
+				literal[i] = fmt.Sprintf("%g", v)
+			}
+
+			var res struct {
+				Get map[string][]struct {
+					Additional struct {
+						ID string `json:"id"`
+					} `json:"_additional"`
+				} `json:"Get"`
+			}
+			if err := graphQL(ctx, host, fmt.Sprintf(
+				`{Get{%s(nearVector:{vector:[%s]},where:%s,limit:%d){_additional{id}}}}`,
+				LedgerClass, strings.Join(literal, ","), filter.where, filteredLimit), &res); err != nil {
+				return 0, fmt.Errorf("filtered search %s: %w", filter.name, err)
+			}
+
+			var got []string
+			for _, hit := range res.Get[LedgerClass] {
+				p, ok := byID[hit.Additional.ID]
+				if !ok || !filter.match(p) {
+					return 0, fmt.Errorf("filtered search %s returned %s, which does not match the filter",
+						filter.name, hit.Additional.ID)
+				}
+				got = append(got, hit.Additional.ID)
+			}
+
+			truth := filteredTruth(points, query, filter.match, filteredLimit)
+			if len(got) < len(truth) {
+				return 0, fmt.Errorf("filtered search %s returned %d objects, %d match the filter",
+					filter.name, len(got), len(truth))
+			}
+			recall := filteredRecall(truth, got)
+			if recall < filteredMinRecall {
+				return 0, fmt.Errorf("filtered search %s has a recall of %.2f, wanted %v, got %v",
+					filter.name, recall, truth, got)
+			}
+			if recall < lowest {
+				lowest = recall
+			}
+		}
+	}
+
+	return lowest, nil
+}
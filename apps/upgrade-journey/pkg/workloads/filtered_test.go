@@ -0,0 +1,72 @@
+package workloads
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFilteredTruth(t *testing.T) {
+	var points []ledgerPoint
+	for seq := 0; seq < 20; seq++ {
+		points = append(points, ledgerPoint{id: string(rune('a' + seq)), seq: seq, hop: seq / 10})
+	}
+
+	// the vector of an entry is its own nearest neighbor
+	query := ledgerVector(13)
+	got := filteredTruth(points, query, func(p ledgerPoint) bool { return p.hop == 1 }, 3)
+	if len(got) != 3 || got[0] != "n" {
+		t.Errorf("wanted 3 neighbors starting with n, got %v", got)
+	}
+	for _, id := range got {
+		if id < "k" {
+			t.Errorf("%s does not match the filter", id)
+		}
+	}
+
+	if got := filteredTruth(points, query, func(p ledgerPoint) bool { return p.seq == 2 }, 3); !reflect.DeepEqual(got, []string{"c"}) {
+		t.Errorf("wanted only c, got %v", got)
+	}
+}
+
+func TestFilteredRecall(t *testing.T) {
+	tests := []struct {
+		name  string
+		truth []string
+		got   []string
+		want  float64
+	}{
+		{name: "all", truth: []string{"a", "b"}, got: []string{"b", "a"}, want: 1},
+		{name: "half", truth: []string{"a", "b"}, got: []string{"a", "c"}, want: 0.5},
+		{name: "nothing matches", got: []string{"a"}, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := filteredRecall(tt.truth, tt.got); got != tt.want {
+				t.Errorf("wanted %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLedgerFilters(t *testing.T) {
+	points := []ledgerPoint{{id: "a", seq: 0, hop: 0}, {id: "b", seq: 1, hop: 0}, {id: "c", seq: 25, hop: 1}}
+
+	filters := ledgerFilters(points)
+	if len(filters) != 2 {
+		t.Fatalf("wanted 2 filters, got %d", len(filters))
+	}
+
+	var matched [][]string
+	for _, f := range filters {
+		var ids []string
+		for _, p := range points {
+			if f.match(p) {
+				ids = append(ids, p.id)
+			}
+		}
+		matched = append(matched, ids)
+	}
+	if want := [][]string{{"c"}, {"b"}}; !reflect.DeepEqual(matched, want) {
+		t.Errorf("wanted %v, got %v", want, matched)
+	}
+}
@@ -0,0 +1,78 @@
+package workloads
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// ParseGroups reads the count of every group from the data section of a
+// grouped Aggregate response
+func ParseGroups(raw []byte, class string) (map[string]int, error) {
+	var res struct {
+		Aggregate map[string][]struct {
+			GroupedBy struct {
+				Value interface{} `json:"value"`
+			} `json:"groupedBy"`
+			Meta struct {
+				Count int `json:"count"`
+			} `json:"meta"`
+		} `json:"Aggregate"`
+	}
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return nil, err
+	}
+
+	groups := map[string]int{}
+	for _, group := range res.Aggregate[class] {
+		value := fmt.Sprint(group.GroupedBy.Value)
+		if _, ok := groups[value]; ok {
+			return nil, fmt.Errorf("group %s returned twice", value)
+		}
+		groups[value] = group.Meta.Count
+	}
+	return groups, nil
+}
+
+// DiffGroups returns an error for the first group whose count differs
+func DiffGroups(want, got map[string]int) error {
+	for value, count := range want {
+		if got[value] != count {
+			return fmt.Errorf("group %s: wanted %d, got %d", value, count, got[value])
+		}
+	}
+
+	for value, count := range got {
+		if _, ok := want[value]; !ok {
+			return fmt.Errorf("unexpected group %s with %d objects", value, count)
+		}
+	}
+
+	return nil
+}
+
+// verifyLedgerGroups aggregates the ledger entries by hop and compares the
+// counts with the stored entries
+func verifyLedgerGroups(ctx context.Context, host string, stored map[string]int) error {
+	want := map[string]int{}
+	for _, hop := range stored {
+		want[strconv.Itoa(hop)]++
+	}
+
+	var res json.RawMessage
+	if err := graphQL(ctx, host, fmt.Sprintf(`{Aggregate{%s(groupBy:["hop"],limit:10000)`+
+		`{groupedBy{value} meta{count}}}}`, LedgerClass), &res); err != nil {
+		return fmt.Errorf("ledger groupBy: %w", err)
+	}
+
+	got, err := ParseGroups(res, LedgerClass)
+	if err != nil {
+		return fmt.Errorf("ledger groupBy: %w", err)
+	}
+
+	if err := DiffGroups(want, got); err != nil {
+		return fmt.Errorf("ledger groupBy: %w", err)
+	}
+	return nil
+}
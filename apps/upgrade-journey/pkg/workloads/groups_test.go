@@ -0,0 +1,49 @@
+package workloads
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseGroups(t *testing.T) {
+	raw := []byte(`{"Aggregate":{"Collection":[` +
+		`{"groupedBy":{"value":"24"},"meta":{"count":2}},` +
+		`{"groupedBy":{"value":"25"},"meta":{"count":1}}]}}`)
+
+	got, err := ParseGroups(raw, "Collection")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]int{"24": 2, "25": 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("wanted %v, got %v", want, got)
+	}
+
+	duplicate := []byte(`{"Aggregate":{"Collection":[` +
+		`{"groupedBy":{"value":"24"},"meta":{"count":2}},` +
+		`{"groupedBy":{"value":"24"},"meta":{"count":1}}]}}`)
+	if _, err := ParseGroups(duplicate, "Collection"); err == nil {
+		t.Error("wanted an error for a duplicate group")
+	}
+}
+
+func TestDiffGroups(t *testing.T) {
+	tests := []struct {
+		name    string
+		got     map[string]int
+		wantErr bool
+	}{
+		{name: "equal", got: map[string]int{"1": 2, "2": 1}},
+		{name: "wrong count", got: map[string]int{"1": 1, "2": 1}, wantErr: true},
+		{name: "missing group", got: map[string]int{"1": 2}, wantErr: true},
+		{name: "extra group", got: map[string]int{"1": 2, "2": 1, "3": 1}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := DiffGroups(map[string]int{"1": 2, "2": 1}, test.got)
+			if (err != nil) != test.wantErr {
+				t.Errorf("wanted error %v, got %v", test.wantErr, err)
+			}
+		})
+	}
+}
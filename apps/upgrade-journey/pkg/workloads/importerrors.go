@@ -0,0 +1,142 @@
+package workloads
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrorClass is the kind of an import error. Each class says something
+// different about the cluster: a refused connection is a node that is down,
+// a 503 a node that is up but not ready, a timeout a node that is too slow
+// or paused, and a 422 a request the server considers invalid, which no
+// fault should ever cause.
+type ErrorClass string
+
+const (
+	ErrorRefused       ErrorClass = "refused"
+	ErrorTimeout       ErrorClass = "timeout"
+	ErrorUnavailable   ErrorClass = "unavailable"
+	ErrorUnprocessable ErrorClass = "unprocessable"
+
+	// ErrorObject is an object a batch rejected in an otherwise successful
+	// response
+	ErrorObject ErrorClass = "object"
+	ErrorOther  ErrorClass = "other"
+)
+
+var errorClasses = []ErrorClass{
+	ErrorRefused, ErrorTimeout, ErrorUnavailable, ErrorUnprocessable, ErrorObject, ErrorOther,
+}
+
+// ClassifyError returns the class of an error returned by a request
+func ClassifyError(err error) ErrorClass {
+	var statusErr *StatusError
+	var netErr net.Error
+	var objectsErr batchObjectsError
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ErrorRefused
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorTimeout
+	case errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusServiceUnavailable:
+		return ErrorUnavailable
+	case errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusGatewayTimeout:
+		return ErrorTimeout
+	case errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusUnprocessableEntity:
+		return ErrorUnprocessable
+	case errors.As(err, &objectsErr):
+		return ErrorObject
+	default:
+		return ErrorOther
+	}
+}
+
+// ambiguous tells whether a request that failed with the class may still
+// have been applied
+func (c ErrorClass) ambiguous() bool {
+	switch c {
+	case ErrorTimeout, ErrorUnavailable, ErrorOther:
+		return true
+	default:
+		return false
+	}
+}
+
+// FaultWindows tells which fault, if any, was active at some point between
+// two times. faults.Windows implements it.
+type FaultWindows interface {
+	During(from, to time.Time) (string, bool)
+}
+
+// ErrorBudget is the number of errors of every class a scenario tolerates.
+// A class that is not part of the budget is not tolerated at all.
+type ErrorBudget map[ErrorClass]int
+
+// ParseErrorBudget parses class=count pairs, for example
+// "timeout=20,unavailable=5"
+func ParseErrorBudget(spec string) (ErrorBudget, error) {
+	budget := ErrorBudget{}
+	for _, kv := range strings.Split(spec, ",") {
+		if strings.TrimSpace(kv) == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(strings.TrimSpace(kv), "=")
+		if !ok {
+			return nil, fmt.Errorf("error budget: invalid entry %q", kv)
+		}
+
+		class := ErrorClass(key)
+		if !class.known() {
+			return nil, fmt.Errorf("error budget: unknown class %q, available: %s", key, classNames())
+		}
+
+		count, err := strconv.Atoi(value)
+		if err != nil || count < 0 {
+			return nil, fmt.Errorf("error budget: %s must be a count of at least 0", key)
+		}
+		budget[class] = count
+	}
+	return budget, nil
+}
+
+func (c ErrorClass) known() bool {
+	for _, class := range errorClasses {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+func classNames() string {
+	names := make([]string, len(errorClasses))
+	for i, class := range errorClasses {
+		names[i] = string(class)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// ErrorCounts counts the import errors by class against a budget. The
+// caller synchronizes access.
+type ErrorCounts map[ErrorClass]int
+
+// record counts an error and returns an error once its class is over the
+// budget
+func (c ErrorCounts) record(budget ErrorBudget, class ErrorClass, err error) error {
+	c[class]++
+	if c[class] > budget[class] {
+		return fmt.Errorf("%s errors over the budget of %d: %w", class, budget[class], err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package workloads
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"syscall"
+	"testing"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want ErrorClass
+	}{
+		{err: fmt.Errorf("dial: %w", syscall.ECONNREFUSED), want: ErrorRefused},
+		{err: fmt.Errorf("post: %w", context.DeadlineExceeded), want: ErrorTimeout},
+		{err: &StatusError{StatusCode: http.StatusServiceUnavailable}, want: ErrorUnavailable},
+		{err: &StatusError{StatusCode: http.StatusGatewayTimeout}, want: ErrorTimeout},
+		{err: &StatusError{StatusCode: http.StatusUnprocessableEntity}, want: ErrorUnprocessable},
+		{err: &StatusError{StatusCode: http.StatusInternalServerError}, want: ErrorOther},
+		{err: batchObjectsError{failed: 1, total: 2}, want: ErrorObject},
+		{err: errors.New("boom"), want: ErrorOther},
+	}
+
+	for _, tt := range tests {
+		if got := ClassifyError(tt.err); got != tt.want {
+			t.Errorf("ClassifyError(%v) = %s, want %s", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrorBudget(t *testing.T) {
+	budget, err := ParseErrorBudget("timeout=20, unavailable=5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if budget[ErrorTimeout] != 20 || budget[ErrorUnavailable] != 5 || budget[ErrorUnprocessable] != 0 {
+		t.Errorf("unexpected budget %v", budget)
+	}
+
+	for _, spec := range []string{"timeout", "timeout=-1", "teapot=1"} {
+		if _, err := ParseErrorBudget(spec); err == nil {
+			t.Errorf("ParseErrorBudget(%q) expected an error", spec)
+		}
+	}
+}
+
+func TestErrorCountsRecord(t *testing.T) {
+	counts := ErrorCounts{}
+	budget := ErrorBudget{ErrorTimeout: 1}
+	boom := errors.New("boom")
+
+	if err := counts.record(budget, ErrorTimeout, boom); err != nil {
+		t.Errorf("first timeout is within the budget, got %v", err)
+	}
+	if err := counts.record(budget, ErrorTimeout, boom); err == nil {
+		t.Error("second timeout is over the budget")
+	}
+	if err := counts.record(budget, ErrorUnprocessable, boom); !errors.Is(err, boom) {
+		t.Errorf("wanted the original error wrapped, got %v", err)
+	}
+}
@@ -0,0 +1,332 @@
+package workloads
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/exp/slog"
+)
+
+type WriteStatus string
+
+const (
+	// WriteAcknowledged means the server confirmed the write, the object
+	// must exist from then on
+	WriteAcknowledged WriteStatus = "acknowledged"
+
+	// WriteUnacknowledged means every attempt ended in an ambiguous failure,
+	// such as a 502 or a timeout. The write may or may not have happened,
+	// both outcomes are correct.
+	WriteUnacknowledged WriteStatus = "unacknowledged"
+)
+
+const (
+	LedgerClass        = "LedgerEntry"
+	LedgerWriteTimeout = 2 * time.Second
+
+	ledgerWriteRetries = 3
+)
+
+// Ledger keeps track of every write of the ledger workload and whether it
+// was acknowledged. Writes use client-side ids, so a retry after an
+// ambiguous failure either creates the object or finds out that the earlier
+// attempt already did.
+type Ledger struct {
+	sync.Mutex
+	entries map[string]WriteStatus
+	client  *http.Client
+	logger  *slog.Logger
+
+	// order holds the ids in the order they were written, the position of
+	// an id is its seq property
+	order []string
+
+	windows FaultWindows
+
+	// history holds every write of an entry in the order they were sent,
+	// starting with its creation
+	history map[string][]LedgerEvent
+
+	// firstCreated is the creationTimeUnix the first audit saw for an
+	// entry, it must never change afterwards
+	firstCreated map[string]int64
+
+	mutations int
+}
+
+// NewLedger returns an empty ledger whose writes time out after
+// writeTimeout, a timed out write counts as ambiguous. Failed attempts are
+// logged at debug level.
+func NewLedger(writeTimeout time.Duration, logger *slog.Logger) *Ledger {
+	return &Ledger{
+		entries:      map[string]WriteStatus{},
+		client:       &http.Client{Timeout: writeTimeout},
+		logger:       logger,
+		history:      map[string][]LedgerEvent{},
+		firstCreated: map[string]int64{},
+	}
+}
+
+// WithFaultWindows makes an unacknowledged write fail the workload unless it
+// overlaps a fault window: without a fault, every write has to succeed
+func (l *Ledger) WithFaultWindows(windows FaultWindows) *Ledger {
+	l.windows = windows
+	return l
+}
+
+// Entries returns a copy of the status of every write by id
+func (l *Ledger) Entries() map[string]WriteStatus {
+	l.Lock()
+	defer l.Unlock()
+
+	entries := make(map[string]WriteStatus, len(l.entries))
+	for id, status := range l.entries {
+		entries[id] = status
+	}
+	return entries
+}
+
+func (l *Ledger) CreateClass(ctx context.Context, host string) error {
+	status, body, err := l.send(ctx, http.MethodPost, host, "/v1/schema", map[string]interface{}{
+		"class":      LedgerClass,
+		"vectorizer": "none",
+		// filtered searches go through the HNSW graph however few entries
+		// match, instead of falling back to a flat search
+		"vectorIndexConfig": map[string]interface{}{"flatSearchCutoff": 0},
+		// filters on _creationTimeUnix and _lastUpdateTimeUnix need the
+		// timestamp index
+		"invertedIndexConfig": map[string]interface{}{"indexTimestamps": true},
+		"properties": []map[string]interface{}{
+			{"name": "hop", "dataType": []string{"int"}},
+			{"name": "seq", "dataType": []string{"int"}},
+			{"name": "written_at", "dataType": []string{"date"}},
+			{"name": "revision", "dataType": []string{"int"}},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("create class %s: status %d: %s", LedgerClass, status, body)
+	}
+
+	return nil
+}
+
+// Write creates n objects through host, which may be a proxy that injects
+// faults
+func (l *Ledger) Write(ctx context.Context, host string, hop, n int) error {
+	for i := 0; i < n; i++ {
+		id := uuid.New().String()
+
+		l.Lock()
+		seq := len(l.order)
+		l.order = append(l.order, id)
+		l.Unlock()
+
+		before := time.Now()
+		status := l.writeOne(ctx, host, hop, seq, id)
+
+		l.Lock()
+		l.entries[id] = status
+		l.history[id] = append(l.history[id], LedgerEvent{
+			Op: LedgerCreate, Status: status, Host: host, Hop: hop, Started: before, Finished: time.Now(),
+		})
+		l.Unlock()
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if status == WriteUnacknowledged && l.windows != nil {
+			if _, ok := l.windows.During(before, time.Now()); !ok {
+				return fmt.Errorf("write %s unacknowledged outside of any fault window", id)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (l *Ledger) writeOne(ctx context.Context, host string, hop, seq int, id string) WriteStatus {
+	payload := map[string]interface{}{
+		"class":  LedgerClass,
+		"id":     id,
+		"vector": ledgerVector(seq),
+		"properties": map[string]interface{}{
+			"hop":        hop,
+			"seq":        seq,
+			"written_at": ledgerWrittenAt(seq),
+		},
+	}
+
+	for attempt := 0; attempt <= ledgerWriteRetries; attempt++ {
+		status, body, err := l.send(ctx, http.MethodPost, host, "/v1/objects", payload)
+		switch {
+		case err == nil && status == http.StatusOK:
+			return WriteAcknowledged
+		case err == nil && status == http.StatusUnprocessableEntity && bytes.Contains(body, []byte("already exists")):
+			// an earlier, unacknowledged attempt went through
+			return WriteAcknowledged
+		}
+
+		l.logger.Debug("ledger write failed", "id", id, "attempt", attempt, "status", status, "err", err)
+	}
+
+	return WriteUnacknowledged
+}
+
+func (l *Ledger) send(ctx context.Context, method, host, endpoint string, payload interface{}) (int, []byte, error) {
+	var body io.Reader
+	if payload != nil {
+		raw, err := json.Marshal(payload)
+		if err != nil {
+			return 0, nil, err
+		}
+		body = bytes.NewReader(raw)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("http://%s%s", host, endpoint), body)
+	if err != nil {
+		return 0, nil, err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := l.client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer res.Body.Close()
+
+	raw, err := io.ReadAll(res.Body)
+	return res.StatusCode, raw, err
+}
+
+// LedgerStats summarize a successful verification
+type LedgerStats struct {
+	Writes int
+	Stored int
+
+	// UnacknowledgedButStored are ambiguous writes that turned out to have
+	// happened
+	UnacknowledgedButStored int
+
+	// Updates and Deletes count the mutations of entries, acknowledged or
+	// not
+	Updates int
+	Deletes int
+
+	// FilteredRecall is the lowest recall of the filtered vector searches
+	FilteredRecall float64
+}
+
+// Verify checks the stored ledger objects against the ledger: every
+// acknowledged write must exist unless its deletion was acknowledged, and
+// nothing may exist that was never written. The timestamps and the revision
+// of every entry have to match its history, and filters on the timestamps
+// have to find exactly the entries they match. Sorted pages, grouped
+// aggregations and filtered vector searches of the entries have to match
+// as well. host should be a node, never a proxy that injects faults.
+func (l *Ledger) Verify(ctx context.Context, host string) (LedgerStats, error) {
+	stored, err := storedLedgerIds(ctx, host)
+	if err != nil {
+		return LedgerStats{}, err
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	stats := LedgerStats{Writes: len(l.entries), Stored: len(stored)}
+	for id, status := range l.entries {
+		_, ok := stored[id]
+		deleted, mayBeDeleted := l.deletion(id)
+		switch {
+		case mayBeDeleted && deleted.Status == WriteAcknowledged && ok:
+			return stats, fmt.Errorf("acknowledged delete of %s on hop %d via %s did not happen", id, deleted.Hop,
+				deleted.Host)
+		case status == WriteAcknowledged && !ok && !mayBeDeleted:
+			return stats, fmt.Errorf("acknowledged write %s is missing", id)
+		case status == WriteUnacknowledged && ok:
+			stats.UnacknowledgedButStored++
+		}
+
+		for _, event := range l.history[id] {
+			switch event.Op {
+			case LedgerUpdate:
+				stats.Updates++
+			case LedgerDelete:
+				stats.Deletes++
+			}
+		}
+	}
+
+	for id := range stored {
+		if _, ok := l.entries[id]; !ok {
+			return stats, fmt.Errorf("object %s exists, but was never written", id)
+		}
+	}
+
+	audited, err := storedLedgerAudit(ctx, host)
+	if err != nil {
+		return stats, err
+	}
+	if broken := l.auditLedger(audited); len(broken) > 0 {
+		return stats, fmt.Errorf("%d ledger entries do not match their history:\n%s", len(broken),
+			strings.Join(broken, "\n"))
+	}
+
+	if err := verifyLedgerTimestampFilters(ctx, host, audited); err != nil {
+		return stats, err
+	}
+
+	if err := verifyLedgerPagination(ctx, host, l.storedInOrder(stored)); err != nil {
+		return stats, err
+	}
+
+	if err := verifyLedgerGroups(ctx, host, stored); err != nil {
+		return stats, err
+	}
+
+	stats.FilteredRecall, err = verifyLedgerFilteredSearch(ctx, host, l.storedPoints(stored))
+	if err != nil {
+		return stats, err
+	}
+
+	return stats, nil
+}
+
+// storedLedgerIds returns the hop of every stored ledger entry by id
+func storedLedgerIds(ctx context.Context, host string) (map[string]int, error) {
+	raw, err := get(ctx, host, fmt.Sprintf("/v1/objects?class=%s&limit=100000", LedgerClass))
+	if err != nil {
+		return nil, err
+	}
+
+	var res struct {
+		Objects []struct {
+			ID         string `json:"id"`
+			Properties struct {
+				Hop int `json:"hop"`
+			} `json:"properties"`
+		} `json:"objects"`
+	}
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]int, len(res.Objects))
+	for _, obj := range res.Objects {
+		ids[obj.ID] = obj.Properties.Hop
+	}
+	return ids, nil
+}
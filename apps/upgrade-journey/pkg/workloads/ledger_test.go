@@ -0,0 +1,81 @@
+package workloads
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/exp/slog"
+)
+
+// fakeObjectStore accepts object creations the way Weaviate does: a second
+// creation with the same id is rejected
+type fakeObjectStore struct {
+	sync.Mutex
+	ids map[string]bool
+}
+
+func (s *fakeObjectStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var obj struct {
+		ID string `json:"id"`
+	}
+	json.NewDecoder(r.Body).Decode(&obj)
+
+	s.Lock()
+	defer s.Unlock()
+	if s.ids[obj.ID] {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(`{"error":[{"message":"id '` + obj.ID + `' already exists"}]}`))
+		return
+	}
+	s.ids[obj.ID] = true
+	w.Write([]byte(`{}`))
+}
+
+func TestWriteOneFindsEarlierAttempt(t *testing.T) {
+	// a retry finds out that an ambiguous first attempt went through
+	store := &fakeObjectStore{ids: map[string]bool{}}
+	server := httptest.NewServer(store)
+	defer server.Close()
+
+	l := NewLedger(LedgerWriteTimeout, slog.New(slog.NewTextHandler(os.Stderr, nil)))
+	store.ids["5b6f2f4e-6a43-4d2e-9f5e-3c1f1d4e2a10"] = true
+	if status := l.writeOne(context.Background(), strings.TrimPrefix(server.URL, "http://"), 0, 0,
+		"5b6f2f4e-6a43-4d2e-9f5e-3c1f1d4e2a10"); status != WriteAcknowledged {
+		t.Errorf("status = %s, want %s", status, WriteAcknowledged)
+	}
+}
+
+// fixedWindows reports a fault for every time range while active is set
+type fixedWindows struct {
+	active bool
+}
+
+func (w fixedWindows) During(from, to time.Time) (string, bool) {
+	return "kill weaviate-1", w.active
+}
+
+func TestWriteCorrelatesWithFaultWindows(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	l := NewLedger(LedgerWriteTimeout, logger).WithFaultWindows(fixedWindows{active: true})
+	if err := l.Write(context.Background(), host, 0, 1); err != nil {
+		t.Errorf("unacknowledged write during a fault: %v", err)
+	}
+
+	l = NewLedger(LedgerWriteTimeout, logger).WithFaultWindows(fixedWindows{})
+	if err := l.Write(context.Background(), host, 0, 1); err == nil {
+		t.Error("wanted an error for an unacknowledged write outside of any fault window")
+	}
+}
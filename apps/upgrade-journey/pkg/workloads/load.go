@@ -0,0 +1,384 @@
+package workloads
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/slog"
+)
+
+const (
+	LoadClass = "Load"
+
+	loadMinBatchSize = 10
+	loadBatchStep    = 10
+)
+
+// LoadOptions configure the load workload. The zero value of every field
+// picks a default that adapts to the machine the scenario runs on.
+type LoadOptions struct {
+	// Workers is the number of batches in flight, 0 uses GOMAXPROCS
+	Workers int
+
+	// BatchSize is the size of the first batch, 0 starts at 100
+	BatchSize int
+
+	// MaxBatchSize caps the tuned batch size, 0 uses 1000
+	MaxBatchSize int
+
+	// TargetLatency is the batch latency the batch size is tuned to, 0
+	// uses one second
+	TargetLatency time.Duration
+
+	// Dimensions of the random vectors, 0 uses 32
+	Dimensions int
+
+	// ErrorBudget are the failed batches tolerated per error class, a
+	// tolerated batch is logged and skipped
+	ErrorBudget ErrorBudget
+
+	// FaultWindows, if set, restricts the error budget to batches that
+	// overlap a fault window. A batch that fails while no fault is active
+	// fails the import regardless of the budget.
+	FaultWindows FaultWindows
+}
+
+func (o LoadOptions) withDefaults() LoadOptions {
+	if o.Workers <= 0 {
+		o.Workers = runtime.GOMAXPROCS(0)
+	}
+	if o.MaxBatchSize <= 0 {
+		o.MaxBatchSize = 1000
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = 100
+	}
+	if o.BatchSize > o.MaxBatchSize {
+		o.BatchSize = o.MaxBatchSize
+	}
+	if o.TargetLatency <= 0 {
+		o.TargetLatency = time.Second
+	}
+	if o.Dimensions <= 0 {
+		o.Dimensions = 32
+	}
+	return o
+}
+
+// batchSizer tunes the batch size the way TCP tunes its window: every batch
+// that is faster than the target grows the size by a constant step, a
+// slower or failed one halves it. The size settles just below what the
+// cluster handles within the target, on a small CI runner as well as on a
+// large machine.
+type batchSizer struct {
+	sync.Mutex
+	size, max int
+	target    time.Duration
+}
+
+func (s *batchSizer) next() int {
+	s.Lock()
+	defer s.Unlock()
+	return s.size
+}
+
+func (s *batchSizer) observe(latency time.Duration, failed bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	if failed || latency > s.target {
+		s.size /= 2
+		if s.size < loadMinBatchSize {
+			s.size = loadMinBatchSize
+		}
+		return
+	}
+
+	s.size += loadBatchStep
+	if s.size > s.max {
+		s.size = s.max
+	}
+}
+
+// Load imports objects with random vectors through the batch endpoint with
+// several workers in parallel, to put the cluster under the pressure of a
+// real import on every hop
+type Load struct {
+	sync.Mutex
+	opts     LoadOptions
+	sizer    *batchSizer
+	logger   *slog.Logger
+	imported int
+
+	// ambiguous counts the objects of failed batches that may have been
+	// written anyway
+	ambiguous int
+	errors    ErrorCounts
+}
+
+func NewLoad(opts LoadOptions, logger *slog.Logger) *Load {
+	opts = opts.withDefaults()
+	return &Load{
+		opts: opts,
+		sizer: &batchSizer{
+			size:   opts.BatchSize,
+			max:    opts.MaxBatchSize,
+			target: opts.TargetLatency,
+		},
+		logger: logger,
+		errors: ErrorCounts{},
+	}
+}
+
+func (l *Load) CreateClass(ctx context.Context, host string) error {
+	_, err := send(ctx, http.MethodPost, host, "/v1/schema", map[string]interface{}{
+		"class":      LoadClass,
+		"vectorizer": "none",
+		"properties": []map[string]interface{}{
+			{"name": "hop", "dataType": []string{"int"}},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("create class %s: %w", LoadClass, err)
+	}
+
+	return nil
+}
+
+// Import imports n objects through host. Failed batches are counted by
+// their error class, the first one over the error budget stops all workers
+// and is returned.
+func (l *Load) Import(parent context.Context, host string, hop, n int) error {
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	var (
+		lock      sync.Mutex
+		remaining = n
+		firstErr  error
+	)
+
+	// take reserves the objects of the next batch, 0 means there are none
+	// left
+	take := func() int {
+		lock.Lock()
+		defer lock.Unlock()
+
+		size := l.sizer.next()
+		if size > remaining {
+			size = remaining
+		}
+		remaining -= size
+		return size
+	}
+
+	fail := func(err error) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+	}
+
+	start := time.Now()
+	wg := sync.WaitGroup{}
+	for w := 0; w < l.opts.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for size := take(); size > 0 && ctx.Err() == nil; size = take() {
+				before := time.Now()
+				err := l.sendBatch(ctx, host, hop, size)
+				after := time.Now()
+				l.sizer.observe(after.Sub(before), err != nil)
+				if err != nil && ctx.Err() != nil {
+					// stopped by an earlier failure
+					return
+				}
+
+				if err := l.record(hop, size, before, after, err); err != nil {
+					fail(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return fmt.Errorf("load: %w", firstErr)
+	}
+	if err := parent.Err(); err != nil {
+		return err
+	}
+
+	l.logger.Info("load imported", "hop", hop, "objects", n, "workers", l.opts.Workers,
+		"batch_size", l.sizer.next(), "took", time.Since(start), "errors", l.Errors())
+	return nil
+}
+
+// record books a batch that was sent between from and to. A failed batch is
+// correlated with the fault windows and checked against the error budget,
+// its objects count as imported if the server rejected only some of them
+// and as ambiguous if it may have been applied.
+func (l *Load) record(hop, size int, from, to time.Time, err error) error {
+	l.Lock()
+	defer l.Unlock()
+
+	if err == nil {
+		l.imported += size
+		return nil
+	}
+
+	class := ClassifyError(err)
+	fault := ""
+	if l.opts.FaultWindows != nil {
+		var ok bool
+		if fault, ok = l.opts.FaultWindows.During(from, to); !ok {
+			l.errors[class]++
+			return fmt.Errorf("%s error outside of any fault window: %w", class, err)
+		}
+	}
+
+	if err := l.errors.record(l.opts.ErrorBudget, class, err); err != nil {
+		return err
+	}
+	l.logger.Warn("load batch failed within the error budget", "hop", hop, "class", class,
+		"fault", fault, "err", err)
+
+	var objectsErr batchObjectsError
+	switch {
+	case errors.As(err, &objectsErr):
+		l.imported += size - objectsErr.failed
+	case class.ambiguous():
+		l.ambiguous += size
+	}
+	return nil
+}
+
+// Errors returns the failed batches by error class
+func (l *Load) Errors() map[ErrorClass]int {
+	l.Lock()
+	defer l.Unlock()
+
+	counts := make(map[ErrorClass]int, len(l.errors))
+	for class, count := range l.errors {
+		counts[class] = count
+	}
+	return counts
+}
+
+func (l *Load) sendBatch(ctx context.Context, host string, hop, size int) error {
+	objects := make([]map[string]interface{}, size)
+	for i := range objects {
+		vec := make([]float32, l.opts.Dimensions)
+		for j := range vec {
+			vec[j] = rand.Float32()
+		}
+
+		objects[i] = map[string]interface{}{
+			"class":      LoadClass,
+			"vector":     vec,
+			"properties": map[string]interface{}{"hop": hop},
+		}
+	}
+
+	raw, err := send(ctx, http.MethodPost, host, "/v1/batch/objects",
+		map[string]interface{}{"objects": objects})
+	if err != nil {
+		return err
+	}
+
+	return batchErrors(raw)
+}
+
+// batchObjectsError is returned for a batch in which some objects failed
+type batchObjectsError struct {
+	failed, total int
+	first         string
+}
+
+func (e batchObjectsError) Error() string {
+	return fmt.Sprintf("%d of %d objects failed: %s", e.failed, e.total, e.first)
+}
+
+// batchErrors returns the failed objects of a batch response
+func batchErrors(raw []byte) error {
+	var res []struct {
+		Result struct {
+			Errors *struct {
+				Error []struct {
+					Message string `json:"message"`
+				} `json:"error"`
+			} `json:"errors"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return err
+	}
+
+	failed := 0
+	var first string
+	for _, obj := range res {
+		if obj.Result.Errors == nil || len(obj.Result.Errors.Error) == 0 {
+			continue
+		}
+		if failed == 0 {
+			first = obj.Result.Errors.Error[0].Message
+		}
+		failed++
+	}
+
+	if failed > 0 {
+		return batchObjectsError{failed: failed, total: len(res), first: first}
+	}
+	return nil
+}
+
+// Imported is the number of objects that were acknowledged so far
+func (l *Load) Imported() int {
+	l.Lock()
+	defer l.Unlock()
+	return l.imported
+}
+
+// Verify checks that the class holds the imported objects, plus at most
+// the objects of ambiguous failed batches
+func (l *Load) Verify(ctx context.Context, host string) error {
+	var res struct {
+		Aggregate map[string][]struct {
+			Meta struct {
+				Count int `json:"count"`
+			} `json:"meta"`
+		} `json:"Aggregate"`
+	}
+	if err := graphQL(ctx, host, fmt.Sprintf(`{Aggregate{%s{meta{count}}}}`, LoadClass), &res); err != nil {
+		return err
+	}
+
+	count := 0
+	if groups := res.Aggregate[LoadClass]; len(groups) > 0 {
+		count = groups[0].Meta.Count
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	if l.ambiguous == 0 && count != l.imported {
+		return fmt.Errorf("wanted %d loaded objects, got %d", l.imported, count)
+	}
+	if count < l.imported || count > l.imported+l.ambiguous {
+		return fmt.Errorf("wanted between %d and %d loaded objects, got %d",
+			l.imported, l.imported+l.ambiguous, count)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package workloads
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestBatchSizer(t *testing.T) {
+	s := &batchSizer{size: 100, max: 120, target: time.Second}
+
+	s.observe(100*time.Millisecond, false)
+	if got := s.next(); got != 110 {
+		t.Errorf("after a fast batch: wanted 110, got %d", got)
+	}
+
+	s.observe(100*time.Millisecond, false)
+	s.observe(100*time.Millisecond, false)
+	if got := s.next(); got != 120 {
+		t.Errorf("at the maximum: wanted 120, got %d", got)
+	}
+
+	s.observe(2*time.Second, false)
+	if got := s.next(); got != 60 {
+		t.Errorf("after a slow batch: wanted 60, got %d", got)
+	}
+
+	s.observe(time.Millisecond, true)
+	if got := s.next(); got != 30 {
+		t.Errorf("after a failed batch: wanted 30, got %d", got)
+	}
+
+	for i := 0; i < 10; i++ {
+		s.observe(2*time.Second, false)
+	}
+	if got := s.next(); got != loadMinBatchSize {
+		t.Errorf("at the minimum: wanted %d, got %d", loadMinBatchSize, got)
+	}
+}
+
+func TestLoadOptionsDefaults(t *testing.T) {
+	opts := LoadOptions{BatchSize: 5000}.withDefaults()
+	if opts.Workers != runtime.GOMAXPROCS(0) {
+		t.Errorf("wanted %d workers, got %d", runtime.GOMAXPROCS(0), opts.Workers)
+	}
+	if opts.BatchSize != opts.MaxBatchSize {
+		t.Errorf("wanted the batch size capped at %d, got %d", opts.MaxBatchSize, opts.BatchSize)
+	}
+}
+
+func TestBatchErrors(t *testing.T) {
+	if err := batchErrors([]byte(`[{"result":{}},{"result":{}}]`)); err != nil {
+		t.Errorf("wanted no error, got %v", err)
+	}
+
+	raw := []byte(`[{"result":{}},{"result":{"errors":{"error":[{"message":"no space left"}]}}}]`)
+	if err := batchErrors(raw); err == nil || err.Error() != "1 of 2 objects failed: no space left" {
+		t.Errorf("wanted the failed object, got %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package workloads
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ledgerPage is one limit/offset window of a sorted query
+type ledgerPage struct {
+	offset, limit int
+}
+
+// ledgerSort is a sort clause together with whether it returns the ledger
+// in write order or in reverse
+type ledgerSort struct {
+	path    string
+	order   string
+	reverse bool
+}
+
+var ledgerSorts = []ledgerSort{
+	{path: "seq", order: "asc"},
+	{path: "written_at", order: "desc", reverse: true},
+}
+
+// ledgerWrittenAt is the logical write time of an entry, one minute per
+// write, so that sorting by it has no ties
+func ledgerWrittenAt(seq int) string {
+	return expiryEpoch.Add(time.Duration(seq) * time.Minute).Format(time.RFC3339)
+}
+
+// storedInOrder returns the stored ids in write order. Unacknowledged writes
+// that never made it are skipped. The caller holds the lock.
+func (l *Ledger) storedInOrder(stored map[string]int) []string {
+	ordered := make([]string, 0, len(stored))
+	for _, id := range l.order {
+		if _, ok := stored[id]; ok {
+			ordered = append(ordered, id)
+		}
+	}
+	return ordered
+}
+
+// ledgerPages covers the first page, pages in the middle, the last,
+// partially filled page and a page past the end
+func ledgerPages(total int) []ledgerPage {
+	pages := []ledgerPage{{offset: 0, limit: 1}, {offset: 0, limit: 10}, {offset: 3, limit: 7}}
+	if total > 5 {
+		pages = append(pages, ledgerPage{offset: total - 5, limit: 10})
+	}
+	return append(pages, ledgerPage{offset: total, limit: 10})
+}
+
+// expectedPage is the part of ordered a page has to return
+func expectedPage(ordered []string, p ledgerPage) []string {
+	if p.offset >= len(ordered) {
+		return nil
+	}
+
+	end := p.offset + p.limit
+	if end > len(ordered) {
+		end = len(ordered)
+	}
+	return ordered[p.offset:end]
+}
+
+// verifyLedgerPagination sorts the ledger by an int and a date property and
+// checks every page against the write order
+func verifyLedgerPagination(ctx context.Context, host string, ordered []string) error {
+	reversed := make([]string, len(ordered))
+	for i, id := range ordered {
+		reversed[len(ordered)-1-i] = id
+	}
+
+	for _, sort := range ledgerSorts {
+		expected := ordered
+		if sort.reverse {
+			expected = reversed
+		}
+
+		for _, page := range ledgerPages(len(ordered)) {
+			query := fmt.Sprintf(`{Get{%s(sort:[{path:["%s"],order:%s}],limit:%d,offset:%d){_additional{id}}}}`,
+				LedgerClass, sort.path, sort.order, page.limit, page.offset)
+
+			var res struct {
+				Get map[string][]struct {
+					Additional struct {
+						ID string `json:"id"`
+					} `json:"_additional"`
+				} `json:"Get"`
+			}
+			if err := graphQL(ctx, host, query, &res); err != nil {
+				return fmt.Errorf("sort by %s: %w", sort.path, err)
+			}
+
+			want := expectedPage(expected, page)
+			got := res.Get[LedgerClass]
+			if len(got) != len(want) {
+				return fmt.Errorf("sort by %s %s, offset %d, limit %d: wanted %d objects, got %d",
+					sort.path, sort.order, page.offset, page.limit, len(want), len(got))
+			}
+			for i := range want {
+				if got[i].Additional.ID != want[i] {
+					return fmt.Errorf("sort by %s %s, offset %d, limit %d: position %d: wanted %s, got %s",
+						sort.path, sort.order, page.offset, page.limit, page.offset+i, want[i], got[i].Additional.ID)
+				}
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package workloads
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStoredInOrder(t *testing.T) {
+	l := &Ledger{order: []string{"a", "b", "c", "d"}}
+	stored := map[string]int{"d": 1, "a": 0, "c": 1}
+
+	if got, want := l.storedInOrder(stored), []string{"a", "c", "d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("wanted %v, got %v", want, got)
+	}
+}
+
+func TestExpectedPage(t *testing.T) {
+	ordered := []string{"a", "b", "c", "d", "e"}
+
+	tests := []struct {
+		page ledgerPage
+		want []string
+	}{
+		{page: ledgerPage{offset: 0, limit: 1}, want: []string{"a"}},
+		{page: ledgerPage{offset: 1, limit: 2}, want: []string{"b", "c"}},
+		{page: ledgerPage{offset: 3, limit: 10}, want: []string{"d", "e"}},
+		{page: ledgerPage{offset: 5, limit: 10}, want: nil},
+		{page: ledgerPage{offset: 7, limit: 1}, want: nil},
+	}
+
+	for _, test := range tests {
+		if got := expectedPage(ordered, test.page); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%+v: wanted %v, got %v", test.page, test.want, got)
+		}
+	}
+}
+
+func TestLedgerPagesEndPastTheLastObject(t *testing.T) {
+	for _, total := range []int{0, 3, 40} {
+		pages := ledgerPages(total)
+		if last := pages[len(pages)-1]; last.offset < total {
+			t.Errorf("total %d: last page %+v does not start past the end", total, last)
+		}
+	}
+}
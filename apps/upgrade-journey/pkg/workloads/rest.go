@@ -0,0 +1,115 @@
+// Package workloads contains workloads that write to a Weaviate cluster on
+// every hop of a scenario and can verify afterwards that nothing they wrote
+// got lost or came back. They only need the address of a node, so they
+// work against any cluster.
+package workloads
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// RequestTimeout bounds every single request of the workloads, so that a
+// hung node fails the request instead of the whole phase. 0 disables it.
+var RequestTimeout time.Duration
+
+func get(ctx context.Context, host, endpoint string) ([]byte, error) {
+	return request(ctx, http.MethodGet, host, endpoint, nil)
+}
+
+func send(ctx context.Context, method, host, endpoint string, payload interface{}) ([]byte, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return request(ctx, method, host, endpoint, raw)
+}
+
+func request(ctx context.Context, method, host, endpoint string, payload []byte) ([]byte, error) {
+	reqCtx, cancel := context.WithCancel(ctx)
+	if RequestTimeout > 0 {
+		reqCtx, cancel = context.WithTimeout(ctx, RequestTimeout)
+	}
+	defer cancel()
+
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(reqCtx, method, fmt.Sprintf("http://%s%s", host, endpoint), body)
+	if err != nil {
+		return nil, err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := do(req)
+	if err != nil && ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+		return nil, fmt.Errorf("%s %s on %s timed out after %s: %w", method, endpoint, host, RequestTimeout, err)
+	}
+	return res, err
+}
+
+func do(req *http.Request) ([]byte, error) {
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, &StatusError{Method: req.Method, Path: req.URL.Path, StatusCode: res.StatusCode, Body: body}
+	}
+
+	return body, nil
+}
+
+// StatusError is returned for every response that is not a 200
+type StatusError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Body       []byte
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s %s: status %d: %s", e.Method, e.Path, e.StatusCode, e.Body)
+}
+
+// graphQL sends the query and decodes the data section of the response into
+// target. Any GraphQL error is returned as an error.
+func graphQL(ctx context.Context, host, query string, target interface{}) error {
+	body, err := send(ctx, http.MethodPost, host, "/v1/graphql", map[string]string{"query": query})
+	if err != nil {
+		return err
+	}
+
+	var res struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return err
+	}
+
+	if len(res.Errors) > 0 {
+		return fmt.Errorf("graphql: %s", res.Errors[0].Message)
+	}
+
+	return json.Unmarshal(res.Data, target)
+}
@@ -0,0 +1,251 @@
+package workloads
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// The revector workload replaces the vectors of the same objects over and
+// over. HNSW does not update a vector in place: the old node is tombstoned
+// and the new vector is inserted, so a search may still find an object by a
+// vector it no longer has, or not find it by its current one.
+const (
+	RevectoredClass = "Revectored"
+
+	revectorDims = 16
+
+	// vectors closer than this are considered the same
+	revectorEpsilon = 1e-4
+)
+
+type Revector struct {
+	sync.Mutex
+
+	// current holds the possible vectors of every object. A failed update
+	// may or may not have been applied, so it keeps both vectors until the
+	// next update succeeds.
+	current map[string][][]float32
+
+	// replaced holds a vector every object had before, which no search
+	// must find the object by anymore
+	replaced map[string][]float32
+}
+
+func NewRevector() *Revector {
+	return &Revector{current: map[string][][]float32{}, replaced: map[string][]float32{}}
+}
+
+func randomUnitVector() []float32 {
+	vector := make([]float32, revectorDims)
+	norm := 0.0
+	for i := range vector {
+		vector[i] = rand.Float32()*2 - 1
+		norm += float64(vector[i] * vector[i])
+	}
+	for i := range vector {
+		vector[i] /= float32(math.Sqrt(norm))
+	}
+	return vector
+}
+
+// CreateClass creates the class with objects objects
+func (w *Revector) CreateClass(ctx context.Context, host string, objects int) error {
+	if _, err := send(ctx, http.MethodPost, host, "/v1/schema", map[string]interface{}{
+		"class":      RevectoredClass,
+		"vectorizer": "none",
+		"properties": []map[string]interface{}{
+			{"name": "updates", "dataType": []string{"int"}},
+		},
+	}); err != nil {
+		return fmt.Errorf("create class %s: %w", RevectoredClass, err)
+	}
+
+	w.Lock()
+	defer w.Unlock()
+
+	for i := 0; i < objects; i++ {
+		id, vector := uuid.New().String(), randomUnitVector()
+		if _, err := send(ctx, http.MethodPost, host, "/v1/objects", map[string]interface{}{
+			"class":      RevectoredClass,
+			"id":         id,
+			"vector":     vector,
+			"properties": map[string]interface{}{"updates": 0},
+		}); err != nil {
+			return fmt.Errorf("import %s object: %w", RevectoredClass, err)
+		}
+		w.current[id] = [][]float32{vector}
+	}
+
+	return nil
+}
+
+// Update gives every object a new vector, rounds times in a row. Failed
+// updates are expected while a fault is active and are returned together
+// with the number of successful ones.
+func (w *Revector) Update(ctx context.Context, host string, rounds int) (updated, failed int, err error) {
+	w.Lock()
+	defer w.Unlock()
+
+	for round := 0; round < rounds; round++ {
+		for id, candidates := range w.current {
+			vector := randomUnitVector()
+			_, err := send(ctx, http.MethodPut, host, fmt.Sprintf("/v1/objects/%s/%s", RevectoredClass, id),
+				map[string]interface{}{
+					"class":      RevectoredClass,
+					"id":         id,
+					"vector":     vector,
+					"properties": map[string]interface{}{"updates": round + 1},
+				})
+			if ctx.Err() != nil {
+				return updated, failed, ctx.Err()
+			}
+			if err != nil {
+				failed++
+				w.current[id] = append(candidates, vector)
+				continue
+			}
+
+			updated++
+			if len(candidates) == 1 {
+				w.replaced[id] = candidates[0]
+			} else {
+				// any of the candidates might have been applied
+				delete(w.replaced, id)
+			}
+			w.current[id] = [][]float32{vector}
+		}
+	}
+
+	return updated, failed, nil
+}
+
+// Verify checks that every object is found by its current vector and not
+// by a vector it had before
+func (w *Revector) Verify(ctx context.Context, host string) error {
+	w.Lock()
+	defer w.Unlock()
+
+	var wrong []string
+	for id, candidates := range w.current {
+		stored, err := w.storedVector(ctx, host, id)
+		if err != nil {
+			return err
+		}
+		if !matchesAny(stored, candidates) {
+			wrong = append(wrong, fmt.Sprintf("%s: stored vector is none of the written ones", id))
+			continue
+		}
+
+		nearest, distance, err := w.nearest(ctx, host, stored)
+		if err != nil {
+			return err
+		}
+		if nearest != id || distance > revectorEpsilon {
+			wrong = append(wrong, fmt.Sprintf("%s: its current vector finds %s at distance %g", id,
+				nearest, distance))
+		}
+
+		if old, ok := w.replaced[id]; ok {
+			nearest, distance, err := w.nearest(ctx, host, old)
+			if err != nil {
+				return err
+			}
+			if nearest == id && distance <= revectorEpsilon {
+				wrong = append(wrong, fmt.Sprintf("%s: still found by a replaced vector", id))
+			}
+		}
+	}
+
+	if len(wrong) > 0 {
+		return fmt.Errorf("%d of %d revectored objects are wrong:\n%s", len(wrong), len(w.current),
+			strings.Join(wrong, "\n"))
+	}
+	return nil
+}
+
+func (w *Revector) storedVector(ctx context.Context, host, id string) ([]float32, error) {
+	raw, err := get(ctx, host, fmt.Sprintf("/v1/objects/%s/%s?include=vector", RevectoredClass, id))
+	if err != nil {
+		return nil, fmt.Errorf("get %s object %s: %w", RevectoredClass, id, err)
+	}
+
+	var obj struct {
+		Vector []float32 `json:"vector"`
+	}
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return nil, err
+	}
+	return obj.Vector, nil
+}
+
+func (w *Revector) nearest(ctx context.Context, host string, vector []float32) (string, float64, error) {
+	query := fmt.Sprintf(`{Get{%s(nearVector:{vector:%s},limit:1){_additional{id distance}}}}`,
+		RevectoredClass, vectorLiteral(vector))
+	raw, err := send(ctx, http.MethodPost, host, "/v1/graphql", map[string]interface{}{"query": query})
+	if err != nil {
+		return "", 0, fmt.Errorf("search %s: %w", RevectoredClass, err)
+	}
+
+	var res struct {
+		Data struct {
+			Get map[string][]struct {
+				Additional struct {
+					ID       string  `json:"id"`
+					Distance float64 `json:"distance"`
+				} `json:"_additional"`
+			} `json:"Get"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return "", 0, err
+	}
+	if len(res.Errors) > 0 {
+		return "", 0, fmt.Errorf("search %s: %s", RevectoredClass, res.Errors[0].Message)
+	}
+
+	hits := res.Data.Get[RevectoredClass]
+	if len(hits) == 0 {
+		return "", math.Inf(1), nil
+	}
+	return hits[0].Additional.ID, hits[0].Additional.Distance, nil
+}
+
+func vectorLiteral(vector []float32) string {
+	parts := make([]string, len(vector))
+	for i, v := range vector {
+		parts[i] = fmt.Sprintf("%g", v)
+	}
+	return "[" + strings.Join(parts, ",") + "]"
+}
+
+func matchesAny(vector []float32, candidates [][]float32) bool {
+	for _, candidate := range candidates {
+		if sameVector(vector, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+func sameVector(a, b []float32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.Abs(float64(a[i]-b[i])) > revectorEpsilon {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,51 @@
+package workloads
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMatchesAny(t *testing.T) {
+	tests := []struct {
+		name       string
+		vector     []float32
+		candidates [][]float32
+		want       bool
+	}{
+		{name: "equal", vector: []float32{0.1, 0.2}, candidates: [][]float32{{0.1, 0.2}}, want: true},
+		{name: "rounded", vector: []float32{0.10001, 0.2}, candidates: [][]float32{{0.1, 0.2}}, want: true},
+		{name: "second candidate", vector: []float32{0.3, 0.4},
+			candidates: [][]float32{{0.1, 0.2}, {0.3, 0.4}}, want: true},
+		{name: "different", vector: []float32{0.1, 0.3}, candidates: [][]float32{{0.1, 0.2}}},
+		{name: "other length", vector: []float32{0.1}, candidates: [][]float32{{0.1, 0.2}}},
+		{name: "no candidates", vector: []float32{0.1, 0.2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchesAny(tt.vector, tt.candidates); got != tt.want {
+				t.Errorf("wanted %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRandomUnitVector(t *testing.T) {
+	vector := randomUnitVector()
+	if len(vector) != revectorDims {
+		t.Fatalf("wanted %d dimensions, got %d", revectorDims, len(vector))
+	}
+
+	norm := 0.0
+	for _, v := range vector {
+		norm += float64(v * v)
+	}
+	if math.Abs(norm-1) > 1e-5 {
+		t.Errorf("wanted a unit vector, got a norm of %g", math.Sqrt(norm))
+	}
+}
+
+func TestVectorLiteral(t *testing.T) {
+	if got, want := vectorLiteral([]float32{0.5, -1, 0.25}), "[0.5,-1,0.25]"; got != want {
+		t.Errorf("wanted %s, got %s", want, got)
+	}
+}
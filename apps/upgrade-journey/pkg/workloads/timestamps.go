@@ -0,0 +1,115 @@
+package workloads
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// timestampFilter is a where filter on the internal timestamps together with
+// the entries it matches
+type timestampFilter struct {
+	name  string
+	where string
+	match func(e auditedEntry) bool
+}
+
+// timestampWhere filters on an internal timestamp property. The value is
+// the timestamp in milliseconds as a string, valueString is accepted by
+// every version of the journey.
+func timestampWhere(operator, path string, unixMilli int64) string {
+	return fmt.Sprintf(`{operator:%s,path:["%s"],valueString:"%d"}`, operator, path, unixMilli)
+}
+
+// timestampFilters split the stored entries at the median of their
+// creation and update times, and look up the creation time of a single
+// entry
+func timestampFilters(stored map[string]auditedEntry) []timestampFilter {
+	if len(stored) == 0 {
+		return nil
+	}
+
+	var created, updated []int64
+	for _, e := range stored {
+		created = append(created, e.created)
+		updated = append(updated, e.updated)
+	}
+	sort.Slice(created, func(a, b int) bool { return created[a] < created[b] })
+	sort.Slice(updated, func(a, b int) bool { return updated[a] < updated[b] })
+
+	createdFrom := created[len(created)/2]
+	updatedBefore := updated[len(updated)/2]
+	createdAt := created[0]
+
+	return []timestampFilter{
+		{
+			name:  fmt.Sprintf("_creationTimeUnix >= %d", createdFrom),
+			where: timestampWhere("GreaterThanEqual", "_creationTimeUnix", createdFrom),
+			match: func(e auditedEntry) bool { return e.created >= createdFrom },
+		},
+		{
+			name:  fmt.Sprintf("_lastUpdateTimeUnix < %d", updatedBefore),
+			where: timestampWhere("LessThan", "_lastUpdateTimeUnix", updatedBefore),
+			match: func(e auditedEntry) bool { return e.updated < updatedBefore },
+		},
+		{
+			name:  fmt.Sprintf("_creationTimeUnix == %d", createdAt),
+			where: timestampWhere("Equal", "_creationTimeUnix", createdAt),
+			match: func(e auditedEntry) bool { return e.created == createdAt },
+		},
+	}
+}
+
+// diffFiltered returns an error for the first entry that a filter should
+// have returned but did not, or returned but should not have
+func diffFiltered(filter timestampFilter, stored map[string]auditedEntry, got []string) error {
+	returned := map[string]bool{}
+	for _, id := range got {
+		entry, ok := stored[id]
+		if !ok || !filter.match(entry) {
+			return fmt.Errorf("filter %s returned %s, which does not match the filter", filter.name, id)
+		}
+		returned[id] = true
+	}
+
+	var missing []string
+	for id, entry := range stored {
+		if filter.match(entry) && !returned[id] {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("filter %s is missing %d of %d matching entries, e.g. %s", filter.name, len(missing),
+			len(missing)+len(returned), missing[0])
+	}
+	return nil
+}
+
+// verifyLedgerTimestampFilters filters the ledger by its creation and update
+// times, which goes through the timestamp index, and compares the result
+// with the timestamps stored in the objects themselves
+func verifyLedgerTimestampFilters(ctx context.Context, host string, stored map[string]auditedEntry) error {
+	for _, filter := range timestampFilters(stored) {
+		var res struct {
+			Get map[string][]struct {
+				Additional struct {
+					ID string `json:"id"`
+				} `json:"_additional"`
+			} `json:"Get"`
+		}
+		if err := graphQL(ctx, host, fmt.Sprintf(`{Get{%s(where:%s,limit:10000){_additional{id}}}}`,
+			LedgerClass, filter.where), &res); err != nil {
+			return fmt.Errorf("filter %s: %w", filter.name, err)
+		}
+
+		var got []string
+		for _, hit := range res.Get[LedgerClass] {
+			got = append(got, hit.Additional.ID)
+		}
+		if err := diffFiltered(filter, stored, got); err != nil {
+			return err
+		}
+	}
+	return nil
+}
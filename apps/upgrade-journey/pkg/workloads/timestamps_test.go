@@ -0,0 +1,62 @@
+package workloads
+
+import (
+	"testing"
+)
+
+func TestTimestampFilters(t *testing.T) {
+	stored := map[string]auditedEntry{
+		"a": {created: 100, updated: 100},
+		"b": {created: 200, updated: 500},
+		"c": {created: 300, updated: 300},
+		"d": {created: 400, updated: 400},
+	}
+
+	filters := timestampFilters(stored)
+	if len(filters) != 3 {
+		t.Fatalf("wanted 3 filters, got %d", len(filters))
+	}
+
+	want := []int{2, 2, 1}
+	for i, f := range filters {
+		matched := 0
+		for _, e := range stored {
+			if f.match(e) {
+				matched++
+			}
+		}
+		if matched != want[i] {
+			t.Errorf("%s: wanted %d matches, got %d", f.name, want[i], matched)
+		}
+	}
+
+	if filters := timestampFilters(nil); filters != nil {
+		t.Errorf("wanted no filters without entries, got %d", len(filters))
+	}
+}
+
+func TestDiffFiltered(t *testing.T) {
+	stored := map[string]auditedEntry{"a": {created: 100}, "b": {created: 200}, "c": {created: 300}}
+	filter := timestampFilter{
+		name:  "_creationTimeUnix >= 200",
+		match: func(e auditedEntry) bool { return e.created >= 200 },
+	}
+
+	tests := []struct {
+		name    string
+		got     []string
+		wantErr bool
+	}{
+		{name: "exact", got: []string{"c", "b"}},
+		{name: "missing", got: []string{"b"}, wantErr: true},
+		{name: "not matching", got: []string{"a", "b", "c"}, wantErr: true},
+		{name: "unknown", got: []string{"b", "c", "x"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := diffFiltered(filter, stored, tt.got); (err != nil) != tt.wantErr {
+				t.Errorf("wanted error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package workloads
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// The tokenization workload keeps a class per tokenization of a text
+// property, filled with strings that the tokenizations split differently:
+// case, punctuation, whitespace and substrings. Which strings a filter or a
+// BM25 search matches is taken from the version that created the class and
+// must not change on any later version.
+//
+// gse and kagome need a setting on the nodes and are not covered.
+
+// tokenizationSamples are written once per hop to every class
+var tokenizationSamples = []string{
+	"Hello World",
+	"hello world",
+	"HELLO-WORLD",
+	"hello_world",
+	"hello.world@example.com",
+	"New York City",
+	"new-york",
+	"  padded   text  ",
+	"C++ and C#",
+	"2023-01-01T10:00",
+	"snake_case camelCase",
+}
+
+// tokenizationFilters are the values of Equal filters on the text
+var tokenizationFilters = []string{
+	"hello",
+	"Hello",
+	"HELLO-WORLD",
+	"hello world",
+	"Hello World",
+	"world",
+	"york",
+	"new-york",
+	"hello.world@example.com",
+	"example",
+	"C++",
+	"c",
+	"camelcase",
+	"ell",
+	"padded",
+}
+
+// tokenizationSearches are the queries of BM25 searches on the text
+var tokenizationSearches = []string{
+	"hello",
+	"york city",
+	"HELLO-WORLD",
+	"example.com",
+	"camel",
+	"orl",
+}
+
+// TokenizationClass is the class of a tokenization
+func TokenizationClass(tokenization string) string {
+	return "Tokenization" + strings.ToUpper(tokenization[:1]) + tokenization[1:]
+}
+
+type Tokenization struct {
+	sync.Mutex
+
+	// classes are the tokenizations that have a class, by the version that
+	// created it
+	classes map[string]string
+
+	// baseline holds the samples every query matched on the first
+	// verification, by class and query
+	baseline map[string]map[string][]int
+}
+
+func NewTokenization() *Tokenization {
+	return &Tokenization{classes: map[string]string{}, baseline: map[string]map[string][]int{}}
+}
+
+// CreateClasses creates a class for every tokenization that does not have
+// one yet. The version decides which tokenizations it supports, so classes
+// of newer tokenizations are only created on the hop that introduces them.
+func (w *Tokenization) CreateClasses(ctx context.Context, host, version string, tokenizations []string) error {
+	w.Lock()
+	defer w.Unlock()
+
+	for _, tokenization := range tokenizations {
+		if _, ok := w.classes[tokenization]; ok {
+			continue
+		}
+
+		class := TokenizationClass(tokenization)
+		if _, err := send(ctx, http.MethodPost, host, "/v1/schema", map[string]interface{}{
+			"class":      class,
+			"vectorizer": "none",
+			"properties": []map[string]interface{}{
+				{"name": "text", "dataType": []string{"text"}, "tokenization": tokenization},
+				{"name": "sample", "dataType": []string{"int"}},
+			},
+		}); err != nil {
+			return fmt.Errorf("create class %s: %w", class, err)
+		}
+		w.classes[tokenization] = version
+	}
+
+	return nil
+}
+
+// Import writes every sample to every class once for the hop
+func (w *Tokenization) Import(ctx context.Context, host string, hop int) error {
+	w.Lock()
+	defer w.Unlock()
+
+	for _, tokenization := range sortedKeys(w.classes) {
+		class := TokenizationClass(tokenization)
+		for i, sample := range tokenizationSamples {
+			if _, err := send(ctx, http.MethodPost, host, "/v1/objects", map[string]interface{}{
+				"class":      class,
+				"id":         uuid.New().String(),
+				"vector":     []float32{float32(i), float32(hop), 1},
+				"properties": map[string]interface{}{"text": sample, "sample": i},
+			}); err != nil {
+				return fmt.Errorf("import %s sample: %w", class, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Verify runs every filter, and every BM25 search if bm25 is set, on every
+// class and compares the samples they match with the first verification
+// of the class. Queries that had no baseline yet, such as the searches of
+// a version that introduced BM25, become part of it.
+func (w *Tokenization) Verify(ctx context.Context, host string, bm25 bool) error {
+	w.Lock()
+	defer w.Unlock()
+
+	var diff []string
+	for _, tokenization := range sortedKeys(w.classes) {
+		class := TokenizationClass(tokenization)
+		matches, err := tokenizationMatches(ctx, host, class, bm25)
+		if err != nil {
+			return err
+		}
+
+		diff = append(diff, w.compare(class, w.classes[tokenization], matches)...)
+	}
+
+	if len(diff) > 0 {
+		return fmt.Errorf("tokenization changed: %s", strings.Join(diff, "; "))
+	}
+	return nil
+}
+
+// compare describes every query on the class that matches other samples
+// than in the baseline and adds the queries the baseline does not have yet.
+// The caller holds the lock.
+func (w *Tokenization) compare(class, version string, matches map[string][]int) []string {
+	baseline, ok := w.baseline[class]
+	if !ok {
+		baseline = map[string][]int{}
+		w.baseline[class] = baseline
+	}
+
+	var diff []string
+	for _, query := range sortedKeys(matches) {
+		want, ok := baseline[query]
+		if !ok {
+			baseline[query] = matches[query]
+			continue
+		}
+		if !equalInts(want, matches[query]) {
+			diff = append(diff, fmt.Sprintf("%s %s matched samples %v on %s, now %v", class, query, want,
+				version, matches[query]))
+		}
+	}
+	return diff
+}
+
+// tokenizationMatches returns the samples every query matches in a class
+func tokenizationMatches(ctx context.Context, host, class string, bm25 bool) (map[string][]int, error) {
+	queries := map[string]string{}
+	for _, value := range tokenizationFilters {
+		queries[fmt.Sprintf("Equal %q", value)] = fmt.Sprintf(`where:{path:["text"],operator:Equal,valueText:%q}`,
+			value)
+	}
+	if bm25 {
+		for _, query := range tokenizationSearches {
+			queries[fmt.Sprintf("bm25 %q", query)] = fmt.Sprintf(`bm25:{query:%q,properties:["text"]}`, query)
+		}
+	}
+
+	matches := map[string][]int{}
+	for name, args := range queries {
+		var res struct {
+			Get map[string][]struct {
+				Sample int `json:"sample"`
+			} `json:"Get"`
+		}
+		if err := graphQL(ctx, host, fmt.Sprintf(`{Get{%s(%s,limit:10000){sample}}}`, class, args),
+			&res); err != nil {
+			return nil, fmt.Errorf("%s %s: %w", class, name, err)
+		}
+
+		seen := map[int]bool{}
+		samples := []int{}
+		for _, hit := range res.Get[class] {
+			if !seen[hit.Sample] {
+				seen[hit.Sample] = true
+				samples = append(samples, hit.Sample)
+			}
+		}
+		sort.Ints(samples)
+		matches[name] = samples
+	}
+	return matches, nil
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,36 @@
+package workloads
+
+import "testing"
+
+func TestTokenizationClass(t *testing.T) {
+	if got, want := TokenizationClass("whitespace"), "TokenizationWhitespace"; got != want {
+		t.Errorf("wanted %s, got %s", want, got)
+	}
+}
+
+func TestTokenizationCompare(t *testing.T) {
+	w := NewTokenization()
+	class := TokenizationClass("word")
+
+	if diff := w.compare(class, "1.18.0", map[string][]int{`Equal "hello"`: {0, 1, 2}}); len(diff) > 0 {
+		t.Fatalf("wanted the first verification to become the baseline, got %v", diff)
+	}
+
+	tests := []struct {
+		name     string
+		matches  map[string][]int
+		wantDiff int
+	}{
+		{name: "same", matches: map[string][]int{`Equal "hello"`: {0, 1, 2}}},
+		{name: "changed", matches: map[string][]int{`Equal "hello"`: {0, 1}}, wantDiff: 1},
+		{name: "new query", matches: map[string][]int{`Equal "hello"`: {0, 1, 2}, `bm25 "hello"`: {0}}},
+		{name: "new query is baseline", matches: map[string][]int{`bm25 "hello"`: {1}}, wantDiff: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := w.compare(class, "1.18.0", tt.matches); len(diff) != tt.wantDiff {
+				t.Errorf("wanted %d differences, got %v", tt.wantDiff, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package workloads
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// The unicode workload writes texts that tokenizers and filters have to get
+// right byte by byte: multilingual text, emoji sequences and strings that
+// look the same in a different Unicode normalization form. A node must
+// store every text exactly as written, and which texts a filter matches
+// must not change between versions, whatever the first version decided.
+const UnicodeClass = "UnicodeText"
+
+// unicodeNamespace derives the id of an object from its text, so that the
+// NFC and the NFD form of a text end up as two objects
+var unicodeNamespace = uuid.MustParse("8f4a5d3e-2b1c-4e7f-9a6b-0c3d2e1f4a5b")
+
+// unicodeSample is a text of the workload. Samples that only differ in
+// their normalization form are different samples, a node must neither
+// merge nor normalize them.
+type unicodeSample struct {
+	name string
+	text string
+}
+
+var unicodeSamples = []unicodeSample{
+	{name: "latin nfc", text: "Café Müller straße"},
+	{name: "latin nfd", text: "Cafe\u0301 Mu\u0308ller straße"},
+	{name: "turkish", text: "İstanbul ılık ışık"},
+	{name: "ligature", text: "ﬁle ﬂow"},
+	{name: "fullwidth", text: "ｆｕｌｌ ｗｉｄｔｈ"},
+	{name: "japanese", text: "東京 タワー"},
+	{name: "chinese", text: "北京 欢迎你"},
+	{name: "arabic", text: "مرحبا بالعالم"},
+	{name: "hindi", text: "नमस्ते दुनिया"},
+	{name: "emoji", text: "rocket \U0001f680 launch"},
+	{name: "emoji zwj", text: "family \U0001f469\u200d\U0001f469\u200d\U0001f467 together"},
+	{name: "emoji skin tone", text: "wave \U0001f44b\U0001f3fd hello"},
+	{name: "flags", text: "flag \U0001f1e9\U0001f1ea \U0001f1ef\U0001f1f5"},
+	{name: "zero width", text: "zero\u200bwidth space"},
+}
+
+// unicodeQuery is a where filter on the text of the samples
+type unicodeQuery struct {
+	name  string
+	where string
+}
+
+func unicodeEqual(token string) unicodeQuery {
+	return unicodeQuery{
+		name:  fmt.Sprintf("Equal %+q", token),
+		where: fmt.Sprintf(`{path:["text"],operator:Equal,valueText:%q}`, token),
+	}
+}
+
+func unicodeLike(pattern string) unicodeQuery {
+	return unicodeQuery{
+		name:  fmt.Sprintf("Like %+q", pattern),
+		where: fmt.Sprintf(`{path:["text"],operator:Like,valueText:%q}`, pattern),
+	}
+}
+
+var unicodeQueries = []unicodeQuery{
+	unicodeEqual("café"),
+	unicodeEqual("cafe\u0301"),
+	unicodeEqual("cafe"),
+	unicodeEqual("straße"),
+	unicodeEqual("strasse"),
+	unicodeEqual("İstanbul"),
+	unicodeEqual("istanbul"),
+	unicodeEqual("ﬁle"),
+	unicodeEqual("file"),
+	unicodeEqual("ｆｕｌｌ"),
+	unicodeEqual("full"),
+	unicodeEqual("東京"),
+	unicodeEqual("مرحبا"),
+	unicodeEqual("नमस्ते"),
+	unicodeEqual("\U0001f680"),
+	unicodeEqual("\U0001f44b\U0001f3fd"),
+	unicodeEqual("zero"),
+	unicodeLike("caf*"),
+	unicodeLike("m*ller"),
+}
+
+type Unicode struct {
+	sync.Mutex
+
+	// written holds the text of every object by id
+	written map[string]string
+
+	// baseline holds the samples every query matched on the first
+	// verification
+	baseline map[string][]string
+}
+
+func NewUnicode() *Unicode {
+	return &Unicode{written: map[string]string{}}
+}
+
+func (w *Unicode) CreateClass(ctx context.Context, host string) error {
+	_, err := send(ctx, http.MethodPost, host, "/v1/schema", map[string]interface{}{
+		"class":      UnicodeClass,
+		"vectorizer": "none",
+		"properties": []map[string]interface{}{
+			{"name": "text", "dataType": []string{"text"}},
+			{"name": "sample", "dataType": []string{"text"}},
+			{"name": "hop", "dataType": []string{"int"}},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("create class %s: %w", UnicodeClass, err)
+	}
+
+	return nil
+}
+
+// unicodeID is the id of a sample written on a hop
+func unicodeID(hop int, text string) string {
+	return uuid.NewSHA1(unicodeNamespace, []byte(fmt.Sprintf("%d/%s", hop, text))).String()
+}
+
+// Import writes every sample once for the hop
+func (w *Unicode) Import(ctx context.Context, host string, hop int) error {
+	w.Lock()
+	defer w.Unlock()
+
+	for i, sample := range unicodeSamples {
+		id := unicodeID(hop, sample.text)
+		if _, err := send(ctx, http.MethodPost, host, "/v1/objects", map[string]interface{}{
+			"class":  UnicodeClass,
+			"id":     id,
+			"vector": []float32{float32(i), float32(hop), 1},
+			"properties": map[string]interface{}{
+				"text":   sample.text,
+				"sample": sample.name,
+				"hop":    hop,
+			},
+		}); err != nil {
+			return fmt.Errorf("import %s sample: %w", sample.name, err)
+		}
+		w.written[id] = sample.text
+	}
+
+	return nil
+}
+
+// Verify checks that every text is stored exactly as written and that
+// every query matches the same samples as on the first verification
+func (w *Unicode) Verify(ctx context.Context, host string) error {
+	w.Lock()
+	defer w.Unlock()
+
+	for id, want := range w.written {
+		raw, err := get(ctx, host, fmt.Sprintf("/v1/objects/%s/%s", UnicodeClass, id))
+		if err != nil {
+			return err
+		}
+
+		var obj struct {
+			Properties struct {
+				Text string `json:"text"`
+			} `json:"properties"`
+		}
+		if err := json.Unmarshal(raw, &obj); err != nil {
+			return err
+		}
+		if obj.Properties.Text != want {
+			return fmt.Errorf("object %s: wanted %+q, got %+q", id, want, obj.Properties.Text)
+		}
+	}
+
+	matches := map[string][]string{}
+	for _, query := range unicodeQueries {
+		var res struct {
+			Get map[string][]struct {
+				Sample string `json:"sample"`
+			} `json:"Get"`
+		}
+		if err := graphQL(ctx, host, fmt.Sprintf(`{Get{%s(where:%s,limit:10000){sample}}}`, UnicodeClass,
+			query.where), &res); err != nil {
+			return fmt.Errorf("%s: %w", query.name, err)
+		}
+
+		var samples []string
+		for _, hit := range res.Get[UnicodeClass] {
+			samples = append(samples, hit.Sample)
+		}
+		matches[query.name] = distinctSorted(samples)
+	}
+
+	if w.baseline == nil {
+		w.baseline = matches
+		return nil
+	}
+
+	if diff := diffMatches(w.baseline, matches); len(diff) > 0 {
+		return fmt.Errorf("filters on unicode text match different samples than before: %s",
+			strings.Join(diff, "; "))
+	}
+	return nil
+}
+
+func distinctSorted(values []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// diffMatches describes every query that matches other samples than it did
+// before, in the order of the queries
+func diffMatches(want, got map[string][]string) []string {
+	var diff []string
+	for _, query := range unicodeQueries {
+		before, now := want[query.name], got[query.name]
+		if strings.Join(before, "\x00") != strings.Join(now, "\x00") {
+			diff = append(diff, fmt.Sprintf("%s matched %v, now %v", query.name, before, now))
+		}
+	}
+	return diff
+}
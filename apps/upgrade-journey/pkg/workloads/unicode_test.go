@@ -0,0 +1,55 @@
+package workloads
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnicodeID(t *testing.T) {
+	nfc, nfd := unicodeSamples[0].text, unicodeSamples[1].text
+	if nfc == nfd {
+		t.Fatal("wanted the NFC and the NFD sample to differ in their bytes")
+	}
+	if unicodeID(0, nfc) == unicodeID(0, nfd) {
+		t.Error("wanted different ids for the NFC and the NFD form of a text")
+	}
+	if unicodeID(0, nfc) != unicodeID(0, nfc) {
+		t.Error("wanted the same id for the same text on the same hop")
+	}
+	if unicodeID(0, nfc) == unicodeID(1, nfc) {
+		t.Error("wanted different ids for the same text on different hops")
+	}
+}
+
+func TestDistinctSorted(t *testing.T) {
+	got := distinctSorted([]string{"latin nfd", "emoji", "latin nfd", "emoji", "arabic"})
+	if want := []string{"arabic", "emoji", "latin nfd"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("wanted %v, got %v", want, got)
+	}
+}
+
+func TestDiffMatches(t *testing.T) {
+	first, second := unicodeQueries[0].name, unicodeQueries[1].name
+	before := map[string][]string{first: {"latin nfc"}, second: {"latin nfd"}}
+
+	tests := []struct {
+		name     string
+		got      map[string][]string
+		wantDiff int
+	}{
+		{name: "same", got: map[string][]string{first: {"latin nfc"}, second: {"latin nfd"}}},
+		{
+			name:     "normalized",
+			got:      map[string][]string{first: {"latin nfc", "latin nfd"}, second: {"latin nfc", "latin nfd"}},
+			wantDiff: 2,
+		},
+		{name: "lost", got: map[string][]string{first: {"latin nfc"}}, wantDiff: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := diffMatches(before, tt.got); len(diff) != tt.wantDiff {
+				t.Errorf("wanted %d differences, got %v", tt.wantDiff, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// rough durations used to estimate how long a journey takes. They are based
+// on runs on the default CI runners and only need to be in the right ballpark.
+const (
+	estimatedNodeStart         = 20 * time.Second
+	estimatedImport            = 2 * time.Second
+	estimatedInProcessVerify   = 5 * time.Second
+	estimatedContainerVerify   = 60 * time.Second
+	estimatedMetadataSnapshots = 2 * time.Second
+	estimatedImagePull         = 60 * time.Second
+	estimatedCheck             = 5 * time.Second
+)
+
+type planStep struct {
+	version   string
+	actions   []string
+	estimated time.Duration
+}
+
+// buildPlan describes what a run with the current configuration would do.
+// It must never touch Docker, so that it can be used to validate a config
+// and to review what a CI job does.
+func buildPlan(verifiers []verifier, faultSpecs []string, faultHold, maxDuration time.Duration) string {
+	steps := planSteps(verifiers, faultSpecs, faultHold)
+
+	var sb strings.Builder
+	var total time.Duration
+	fmt.Fprintf(&sb, "upgrade journey across %d versions on %d nodes\n\n", len(versions), nodeCount)
+	for i, step := range steps {
+		fmt.Fprintf(&sb, "hop %d: %s (~%s)\n", i, step.version, step.estimated)
+		for _, action := range step.actions {
+			fmt.Fprintf(&sb, "  - %s\n", action)
+		}
+		total += step.estimated
+	}
+	for _, state := range scenarioStates {
+		nodes := "all nodes"
+		if state.Nodes > 0 {
+			nodes = fmt.Sprintf("%d nodes", state.Nodes)
+		}
+		fmt.Fprintf(&sb, "state: converge to %s on %s with classes %v, then verify\n", state.Version, nodes,
+			state.Classes)
+	}
+	for _, line := range hookSummary() {
+		fmt.Fprintf(&sb, "%s\n", line)
+	}
+	for _, line := range debugSummary() {
+		fmt.Fprintf(&sb, "%s\n", line)
+	}
+
+	fmt.Fprintf(&sb, "\nestimated duration: ~%s\n", total.Round(time.Minute))
+	if maxDuration > 0 {
+		fmt.Fprintf(&sb, "max duration: %s\n", maxDuration)
+		if total > maxDuration {
+			fmt.Fprintf(&sb, "warning: the estimate exceeds the max duration\n")
+		}
+	}
+
+	return sb.String()
+}
+
+// planSteps estimates every hop of the journey. The budget sizes the hops
+// by these estimates, so they include everything a hop runs. The states of
+// the scenario converge after the last hop and count towards it.
+func planSteps(verifiers []verifier, faultSpecs []string, faultHold time.Duration) []planStep {
+	var verify time.Duration
+	for _, v := range verifiers {
+		if _, ok := v.(*containerVerifier); ok {
+			verify += estimatedContainerVerify
+		} else {
+			verify += estimatedInProcessVerify
+		}
+	}
+
+	var steps []planStep
+	for i, version := range versions {
+		step := planStep{version: version}
+
+		switch {
+		case i == 0:
+			step.actions = append(step.actions, fmt.Sprintf("start %d nodes", nodeCount))
+			step.estimated += estimatedImagePull + time.Duration(nodeCount)*estimatedNodeStart
+		case isRaftMigrationHop(versions[i-1], version):
+			step.actions = append(step.actions, fmt.Sprintf(
+				"migrate schema to raft with weaviate-%d down, then bring it back", nodeCount-1))
+			step.estimated += time.Duration(nodeCount+1) * estimatedNodeStart
+		case nodeCount >= largeClusterNodes && isRaftVersion(versions[i-1]):
+			step.actions = append(step.actions, "rolling update of all nodes, raft leader last")
+			step.estimated += time.Duration(nodeCount) * estimatedNodeStart
+		default:
+			step.actions = append(step.actions, "rolling update of all nodes")
+			step.estimated += time.Duration(nodeCount) * estimatedNodeStart
+		}
+
+		if i == 0 {
+			step.actions = append(step.actions, "create schema and record config baseline")
+		}
+
+		step.actions = append(step.actions, "import 1 Collection object referencing 1 RefTarget object")
+		step.estimated += estimatedImport
+
+		for _, v := range verifiers {
+			step.actions = append(step.actions, fmt.Sprintf("verify with %s", v.name()))
+		}
+		step.estimated += verify
+
+		step.actions = append(step.actions, "snapshot cluster metadata and check config drift")
+		step.estimated += estimatedMetadataSnapshots
+
+		for _, check := range hopChecks(version) {
+			step.actions = append(step.actions, check.action)
+			step.estimated += check.estimated
+		}
+
+		for _, spec := range faultSpecs {
+			f, _ := newFault(spec)
+			if driftWindow > 0 {
+				step.actions = append(step.actions, fmt.Sprintf(
+					"fault: %s, then wait up to %s for the ledger count to converge and verify", f.Describe(),
+					driftWindow))
+			} else {
+				step.actions = append(step.actions, fmt.Sprintf("fault: %s, then verify", f.Describe()))
+			}
+			step.estimated += faultHold + estimatedNodeStart + verify
+		}
+
+		steps = append(steps, step)
+	}
+
+	if len(steps) > 0 {
+		last := &steps[len(steps)-1]
+		last.estimated += time.Duration(len(scenarioStates)) * (time.Duration(nodeCount)*estimatedNodeStart + verify)
+	}
+	return steps
+}
+
+// planEstimates are the estimates of the hops of the journey
+func planEstimates(verifiers []verifier, faultSpecs []string, faultHold time.Duration) []time.Duration {
+	var out []time.Duration
+	for _, step := range planSteps(verifiers, faultSpecs, faultHold) {
+		out = append(out, step.estimated)
+	}
+	return out
+}
+
+// hopCheck is a check that the flags add to a hop after its verification
+type hopCheck struct {
+	action    string
+	estimated time.Duration
+}
+
+// hopChecks are the checks of a hop on a version, in the order do runs them.
+// Checks that stop or restart nodes are estimated like starting them.
+func hopChecks(version string) []hopCheck {
+	var out []hopCheck
+	add := func(on bool, action string, estimated time.Duration) {
+		if on {
+			out = append(out, hopCheck{action: action, estimated: estimated})
+		}
+	}
+
+	add(loadMemoryFactor > 0, "check the vector memory of the nodes", estimatedCheck)
+	add(withEgressCheck, "check that no container called a host outside of the cluster", estimatedCheck)
+	add(manyClasses > 0, fmt.Sprintf("check the schema of %d classes", manyClasses), estimatedCheck)
+	add(manyTenants > 0, fmt.Sprintf("check %d tenants", manyTenants), estimatedCheck)
+	add(withRestoreUnderLoad, "restore a backup under load", estimatedNodeStart)
+	add(withReadRepair, "check read repair of a node that was down", estimatedNodeStart)
+	add(withDeleteWhileDown, "delete while a node is down", estimatedNodeStart)
+	add(withConflictingUpdates, "check conflicting updates", estimatedCheck)
+	add(withBatchLimits, "check batch limits", estimatedCheck)
+	add(withConnectionFlood, "flood a node with connections", estimatedNodeStart)
+	add(withAsyncIndexingChaos, "restart a node while it indexes asynchronously", estimatedNodeStart)
+	add(withVectorizerSwap, "swap the vectorizer module of all nodes",
+		time.Duration(nodeCount)*estimatedNodeStart)
+	add(withIndexTuning, "check the tuned index", estimatedCheck)
+	add(withRaftWipe && isRaftVersion(version),
+		fmt.Sprintf("fault: wipe raft state of weaviate-%d and restart it", nodeCount-1), estimatedNodeStart)
+	add(withZoneOutage, "fault: take down a zone and bring it back", 2*estimatedNodeStart)
+	add(isRaftVersion(version) && plannedVoters() >= largeClusterNodes,
+		"fault: take down raft voters up to the quorum, then one more, and check which schema writes succeed",
+		2*estimatedNodeStart)
+	return out
+}
+
+// plannedVoters is the number of raft voters of the configured topology
+func plannedVoters() int {
+	if clusterTopology.Voters == 0 {
+		return nodeCount
+	}
+	return clusterTopology.Voters
+}
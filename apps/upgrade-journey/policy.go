@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate"
+	"github.com/weaviate/weaviate/entities/models"
+)
+
+// The supported upgrade path: any patch jump within a minor, but at most one
+// minor at a time. Skipping a minor is not supported, a node should refuse
+// to start on data written by a version more than one minor behind.
+const (
+	pathPolicyAll   = "all"
+	pathPolicyMinor = "minor"
+)
+
+// upgradeAllowed is true if upgrading from one version to the other follows
+// the supported upgrade path. Non-semver versions, such as preview images,
+// can't be judged and are always allowed.
+func upgradeAllowed(from, to string) bool {
+	fromV, ok := parseVersion(from)
+	if !ok {
+		return true
+	}
+
+	toV, ok := parseVersion(to)
+	if !ok {
+		return true
+	}
+
+	if fromV.major() != toV.major() {
+		return false
+	}
+
+	return toV.minor()-fromV.minor() <= 1
+}
+
+// applyPathPolicy expands the journey according to the policy. "all" visits
+// every release, "minor" takes the shortest supported path, which is the
+// first version, the latest patch of every minor in between, and the target.
+func applyPathPolicy(versions []string, policy string) ([]string, error) {
+	switch policy {
+	case pathPolicyAll:
+		return versions, nil
+	case pathPolicyMinor:
+	default:
+		return nil, fmt.Errorf("invalid --path-policy %q", policy)
+	}
+
+	if len(versions) <= 2 {
+		return versions, nil
+	}
+
+	out := []string{versions[0]}
+	for i := 1; i < len(versions)-1; i++ {
+		cur, curOk := parseVersion(versions[i])
+		next, nextOk := parseVersion(versions[i+1])
+		if curOk && nextOk && next.sameMinor(cur) {
+			// a newer patch of the same minor follows
+			continue
+		}
+
+		out = append(out, versions[i])
+	}
+	out = append(out, versions[len(versions)-1])
+
+	for i := 1; i < len(out); i++ {
+		if !upgradeAllowed(out[i-1], out[i]) {
+			return nil, fmt.Errorf("no supported upgrade path from %s to %s", out[i-1], out[i])
+		}
+	}
+
+	return out, nil
+}
+
+// firstForbiddenJump returns the first pair of versions in the journey that
+// skips a minor, if there is one
+func firstForbiddenJump(versions []string) (string, string, bool) {
+	for i := range versions {
+		for j := i + 1; j < len(versions); j++ {
+			if !upgradeAllowed(versions[i], versions[j]) {
+				return versions[i], versions[j], true
+			}
+		}
+	}
+
+	return "", "", false
+}
+
+// checkForbiddenJump upgrades a single node straight from one version to a
+// version that is not on the supported path. The new version has to refuse
+// to start with a non-zero exit code, rather than start on data it does not
+// support, and it must leave the data intact, so that the old version can
+// still start on it.
+func checkForbiddenJump(ctx context.Context, rootDir, from, to string) error {
+	dataPath := path.Join(rootDir, "data", "forbidden-jump")
+	if err := os.MkdirAll(dataPath, 0o777); err != nil {
+		return err
+	}
+
+	log := logger.With("from", from, "to", to)
+	log.Info("checking that a forbidden upgrade is refused")
+
+	if err := withSingleNode(ctx, from, dataPath, func(client *weaviate.Client) error {
+		reqCtx, cancel := requestContext(ctx)
+		defer cancel()
+		err := client.Schema().ClassCreator().
+			WithClass(&models.Class{Class: "ForbiddenJump", Vectorizer: "none"}).
+			Do(reqCtx)
+		return requestError(ctx, "create class ForbiddenJump", err)
+	}); err != nil {
+		return fmt.Errorf("prepare data with %s: %w", from, err)
+	}
+
+	container, err := startSingleNode(ctx, to, dataPath, wait.ForExit().WithExitTimeout(2*time.Minute))
+	if container != nil {
+		defer container.Terminate(ctx)
+	}
+	if err != nil {
+		return fmt.Errorf("%s neither refused nor completed startup: %w", to, err)
+	}
+
+	state, err := container.State(ctx)
+	if err != nil {
+		return err
+	}
+	if state.ExitCode == 0 {
+		return fmt.Errorf("%s did not refuse the forbidden upgrade from %s", to, from)
+	}
+	log.Info("forbidden upgrade was refused", "exit_code", state.ExitCode)
+
+	if err := withSingleNode(ctx, from, dataPath, func(client *weaviate.Client) error {
+		reqCtx, cancel := requestContext(ctx)
+		defer cancel()
+		if _, err := client.Schema().ClassGetter().WithClassName("ForbiddenJump").Do(reqCtx); err != nil {
+			return fmt.Errorf("class ForbiddenJump: %w", requestError(ctx, "get class ForbiddenJump", err))
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("restart %s after refused upgrade: %w", from, err)
+	}
+
+	return nil
+}
+
+// withSingleNode starts a standalone node on the given data path, runs fn
+// against it and stops it again
+func withSingleNode(ctx context.Context, version, dataPath string, fn func(client *weaviate.Client) error) error {
+	container, err := startSingleNode(ctx, version, dataPath, wait.
+		ForHTTP("/v1/.well-known/ready").
+		WithPort(nat.Port("8080")).
+		WithStatusCodeMatcher(func(status int) bool {
+			return status >= 200 && status <= 299
+		}).
+		WithStartupTimeout(30*time.Second))
+	if container != nil {
+		defer container.Terminate(ctx)
+	}
+	if err != nil {
+		return err
+	}
+
+	httpUri, err := container.PortEndpoint(ctx, nat.Port("8080/tcp"), "")
+	if err != nil {
+		return err
+	}
+
+	return fn(weaviate.New(weaviate.Config{Host: httpUri, Scheme: "http"}))
+}
+
+// startSingleNode is kept apart from the cluster, so that it can neither
+// join it nor clash with its ports
+func startSingleNode(ctx context.Context, version, dataPath string,
+	waitFor wait.Strategy,
+) (testcontainers.Container, error) {
+	return testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		Logger: testcontainersLogger{},
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:         images.image(version),
+			ImagePlatform: images.platform,
+			Cmd:           []string{"--host", "0.0.0.0", "--port", "8080", "--scheme", "http"},
+			ExposedPorts:  []string{"8080/tcp"},
+			Env: map[string]string{
+				"AUTHENTICATION_ANONYMOUS_ACCESS_ENABLED": "true",
+				"PERSISTENCE_DATA_PATH":                   "/var/lib/weaviate",
+				"DEFAULT_VECTORIZER_MODULE":               "none",
+				"CLUSTER_HOSTNAME":                        "forbidden-jump",
+			},
+			Mounts:     testcontainers.Mounts(testcontainers.BindMount(dataPath, "/var/lib/weaviate")),
+			Labels:     runLabels(),
+			WaitingFor: waitFor,
+		},
+		Started: true,
+	})
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_upgradeAllowed(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{from: "1.22.0", to: "1.22.8", want: true},
+		{from: "1.22.8", to: "1.23.0", want: true},
+		{from: "1.22.0", to: "1.23.9", want: true},
+		{from: "1.22.8", to: "1.24.0", want: false},
+		{from: "1.25.3", to: "2.0.0", want: false},
+		{from: "1.25.3", to: "preview-abc", want: true},
+	}
+	for _, tt := range tests {
+		if got := upgradeAllowed(tt.from, tt.to); got != tt.want {
+			t.Errorf("upgradeAllowed(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func Test_applyPathPolicy(t *testing.T) {
+	versions := []string{
+		"1.20.3", "1.20.4", "1.21.0", "1.21.1", "1.21.2", "1.22.0", "1.22.1", "1.23.0", "preview-abc",
+	}
+
+	got, err := applyPathPolicy(versions, pathPolicyAll)
+	if err != nil || !reflect.DeepEqual(got, versions) {
+		t.Errorf("applyPathPolicy(all) = %v, %v", got, err)
+	}
+
+	got, err = applyPathPolicy(versions, pathPolicyMinor)
+	want := []string{"1.20.3", "1.20.4", "1.21.2", "1.22.1", "1.23.0", "preview-abc"}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("applyPathPolicy(minor) = %v, %v, want %v", got, err, want)
+	}
+
+	got, err = applyPathPolicy([]string{"1.25.0", "1.25.1", "latest", "nightly"}, pathPolicyMinor)
+	want2 := []string{"1.25.0", "1.25.1", "latest", "nightly"}
+	if err != nil || !reflect.DeepEqual(got, want2) {
+		t.Errorf("applyPathPolicy(minor) = %v, %v, want %v", got, err, want2)
+	}
+
+	if _, err := applyPathPolicy([]string{"1.20.3", "1.22.0", "1.22.1"}, pathPolicyMinor); err == nil {
+		t.Errorf("expected an error for a path that skips a minor")
+	}
+
+	from, to, ok := firstForbiddenJump(want)
+	if !ok || from != "1.20.3" || to != "1.22.1" {
+		t.Errorf("firstForbiddenJump() = %s, %s, %v", from, to, ok)
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/testcontainers/testcontainers-go"
+	wcluster "github.com/weaviate/weaviate-chaos-engineering/apps/upgrade-journey/pkg/cluster"
+)
+
+// With --preload-images=DIR every image of the run is in place before the
+// first node starts, so that a hop measures the upgrade and not the
+// registry, and the journey follows an offline upgrade procedure. An
+// archive that DIR already holds, e.g. one copied into an air-gapped
+// environment, is loaded as is. Otherwise the images are pulled and saved
+// to the archive first, which is loaded either way.
+//
+// Once the archive is loaded the registry is blocked for the rest of the
+// run: an image of the run that is not in the archive fails the run before
+// the first node starts instead of being pulled. Docker only pulls an image
+// that does not exist locally, so none is pulled after that. Together with
+// --check-egress nothing of the cluster reaches outside of it either.
+const (
+	preloadArchive  = "images.tar"
+	preloadManifest = "images.txt"
+)
+
+var (
+	// preloadDir is set by --preload-images
+	preloadDir string
+
+	// registryBlocked is set once the images were preloaded
+	registryBlocked bool
+)
+
+// harnessImages are the images of the containers the harness starts next
+// to the nodes, e.g. to wipe data or to shape traffic, and the reaper that
+// testcontainers starts to remove them
+var harnessImages = []string{"alpine:3", egressImage, testcontainers.ReaperDefaultImage}
+
+// validatePreload rejects the client verifiers that install their client
+// when they start, go get and pip install need the registries of the
+// languages that a preloaded run has no access to
+func validatePreload(verifiers []verifier) error {
+	if preloadDir == "" {
+		return nil
+	}
+	for _, v := range verifiers {
+		if cv, ok := v.(*containerVerifier); ok {
+			return fmt.Errorf("--preload-images can't run the %s:%s entry of --client-matrix, it installs the client when it starts",
+				cv.language, cv.version)
+		}
+	}
+	return nil
+}
+
+// preloadImages are the images a run starts: the nodes on every version,
+// the harness containers and the sidecars of the flags. validatePreload
+// keeps the client verifiers that run in containers out of the run.
+func preloadImages(versions []string) []string {
+	set := map[string]bool{}
+	for _, version := range versions {
+		set[images.image(version)] = true
+	}
+	for _, image := range harnessImages {
+		set[image] = true
+	}
+	if useToxiproxy {
+		set[toxiproxyImage] = true
+	}
+	if vectorizerModule != "" {
+		set[contextionaryImage] = true
+	}
+	if withGenerativeMock {
+		set[generativeMockImage] = true
+	}
+	return sortedMapKeys(set)
+}
+
+// preload loads the archive of DIR, after pulling and saving the images to
+// it if there is none yet, and blocks the registry
+func preload(ctx context.Context, dir string, imageList []string) error {
+	start := time.Now()
+	archive := path.Join(dir, preloadArchive)
+
+	err := wcluster.WithDockerClient(func(cli *client.Client) error {
+		if _, err := os.Stat(archive); err != nil {
+			if err := pullAndSave(ctx, cli, dir, imageList); err != nil {
+				return err
+			}
+		}
+
+		f, err := os.Open(archive)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		res, err := cli.ImageLoad(ctx, f, true)
+		if err != nil {
+			return fmt.Errorf("load %s: %w", archive, err)
+		}
+		defer res.Body.Close()
+		if _, err := io.Copy(io.Discard, res.Body); err != nil {
+			return fmt.Errorf("load %s: %w", archive, err)
+		}
+
+		for _, image := range imageList {
+			if _, _, err := cli.ImageInspectWithRaw(ctx, image); err != nil {
+				return fmt.Errorf("image %s is not in %s and the registry is blocked", image, archive)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("preload images: %w", err)
+	}
+
+	registryBlocked = true
+	logger.Info("preloaded images, the registry is blocked", "archive", archive, "images", len(imageList),
+		"took", time.Since(start).Round(time.Second))
+	return nil
+}
+
+// pullAndSave pulls the images for the platform of the run and saves them
+// to the archive of DIR, with a manifest that lists them
+func pullAndSave(ctx context.Context, cli *client.Client, dir string, imageList []string) error {
+	for _, image := range imageList {
+		logger.Info("pulling image", "image", image, "platform", images.platform)
+		res, err := cli.ImagePull(ctx, image, types.ImagePullOptions{
+			Platform:     images.platform,
+			RegistryAuth: registryAuth(ctx, image),
+		})
+		if err != nil {
+			return fmt.Errorf("pull %s: %w", image, err)
+		}
+		_, err = io.Copy(io.Discard, res)
+		res.Close()
+		if err != nil {
+			return fmt.Errorf("pull %s: %w", image, err)
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	saved, err := cli.ImageSave(ctx, imageList)
+	if err != nil {
+		return fmt.Errorf("save images: %w", err)
+	}
+	defer saved.Close()
+
+	// the archive is written under another name first, a run that stops
+	// while saving must not leave an archive that lacks images
+	archive := path.Join(dir, preloadArchive)
+	f, err := os.Create(archive + ".partial")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, saved); err != nil {
+		f.Close()
+		return fmt.Errorf("save images: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(archive+".partial", archive); err != nil {
+		return err
+	}
+
+	manifest := strings.Join(imageList, "\n") + "\n"
+	return os.WriteFile(path.Join(dir, preloadManifest), []byte(manifest), 0o644)
+}
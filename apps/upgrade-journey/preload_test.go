@@ -0,0 +1,67 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_preloadImages(t *testing.T) {
+	defer func(toxiproxy bool, module string) {
+		useToxiproxy, vectorizerModule = toxiproxy, module
+	}(useToxiproxy, vectorizerModule)
+	useToxiproxy, vectorizerModule = true, ""
+
+	got := preloadImages([]string{"1.24.0", "1.25.0", "1.24.0"})
+	want := []string{
+		"alpine:3",
+		"docker.io/testcontainers/ryuk:0.5.1",
+		"ghcr.io/shopify/toxiproxy:2.5.0",
+		"nicolaka/netshoot",
+		"semitechnologies/weaviate:1.24.0",
+		"semitechnologies/weaviate:1.25.0",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wanted %v, got %v", want, got)
+	}
+}
+
+func Test_validatePreload(t *testing.T) {
+	defer func(dir string) { preloadDir = dir }(preloadDir)
+
+	tests := []struct {
+		name      string
+		dir       string
+		verifiers []verifier
+		wantErr   bool
+	}{
+		{
+			name:      "in-process client",
+			dir:       "/images",
+			verifiers: []verifier{&goVerifier{}},
+		},
+		{
+			name:      "python client",
+			dir:       "/images",
+			verifiers: []verifier{&goVerifier{}, &containerVerifier{language: "python", version: "4.5.0"}},
+			wantErr:   true,
+		},
+		{
+			name:      "go client of a version",
+			dir:       "/images",
+			verifiers: []verifier{&containerVerifier{language: "go", version: "v4.15.0"}},
+			wantErr:   true,
+		},
+		{
+			name:      "without preloading",
+			verifiers: []verifier{&containerVerifier{language: "python", version: "4.5.0"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			preloadDir = tt.dir
+			if err := validatePreload(tt.verifiers); (err != nil) != tt.wantErr {
+				t.Errorf("wanted error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
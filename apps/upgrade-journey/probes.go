@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// probeImports records the hops that wrote the objects of a probe class.
+// The import phase creates the class and writes the objects of every hop,
+// the verifiers only read them, so that a retried verification does not
+// write the objects of a hop twice.
+type probeImports struct {
+	created bool
+	hops    []int
+}
+
+// importHop creates the class on the first hop and writes the objects of a
+// hop once, a hop that wrote its objects before is left alone
+func (p *probeImports) importHop(ctx context.Context, hop int,
+	create func(ctx context.Context) error, write func(ctx context.Context, hop int) error,
+) error {
+	if !p.created {
+		if err := create(ctx); err != nil {
+			return err
+		}
+		p.created = true
+	}
+
+	if n := len(p.hops); n > 0 && p.hops[n-1] >= hop {
+		return nil
+	}
+	if err := write(ctx, hop); err != nil {
+		return err
+	}
+	p.hops = append(p.hops, hop)
+	return nil
+}
+
+// once writes the objects of a probe on its first hop only, for probes that
+// compare the same objects across hops
+func (p *probeImports) once(write func(ctx context.Context) error) func(ctx context.Context, hop int) error {
+	return func(ctx context.Context, hop int) error {
+		if len(p.hops) > 0 {
+			return nil
+		}
+		return write(ctx)
+	}
+}
+
+// rounds is the number of hops that wrote their objects
+func (p *probeImports) rounds() int {
+	return len(p.hops)
+}
+
+// probeID is the id of an object of a probe class. It only depends on the
+// class, the hop and the object, so that writing it again replaces it.
+func probeID(class string, hop, i int) string {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(fmt.Sprintf("%s/%d/%d", class, hop, i))).String()
+}
+
+// importProbes creates the probe classes the version has the features for
+// and writes the objects of the hop to them
+func importProbes(ctx context.Context, hop int) error {
+	version := versions[hop]
+
+	if err := contractProbe.importHop(ctx, hop, createContractClass, contractProbe.once(importContractObject)); err != nil {
+		return err
+	}
+
+	if err := arrayProbe.importHop(ctx, hop, createArraysClass, importArrays); err != nil {
+		return err
+	}
+
+	if supports(version, featureHybrid) {
+		create := func(ctx context.Context) error {
+			return createHybridClass(ctx, version)
+		}
+		if err := hybridProbe.importHop(ctx, hop, create, hybridProbe.once(importHybridTexts)); err != nil {
+			return err
+		}
+
+		err := bm25ConfigProbe.importHop(ctx, hop, createBM25ConfigClass, bm25ConfigProbe.once(importBM25ConfigDocs))
+		if err != nil {
+			return err
+		}
+	}
+
+	if withGenerativeMock && supports(version, featureGenerativeURL) {
+		err := generativeProbe.importHop(ctx, hop, createGenerativeClass, generativeProbe.once(importGenerativeTexts))
+		if err != nil {
+			return err
+		}
+	}
+
+	if supports(version, featureNullState) {
+		if err := nullValuesProbe.importHop(ctx, hop, createNullValuesClass, importNullValues); err != nil {
+			return err
+		}
+	}
+
+	if supports(version, featureNestedObjects) {
+		if err := nestedProbe.importHop(ctx, hop, createNestedClass, importNested); err != nil {
+			return err
+		}
+	}
+
+	if supports(version, featureUUIDType) {
+		if err := uuidProbe.importHop(ctx, hop, createUUIDClass, importUUIDs); err != nil {
+			return err
+		}
+	}
+
+	if supports(version, featureMultiVector) {
+		if err := multiVectorProbe.importHop(ctx, hop, createMultiVectorClass, importMultiVectors); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func Test_probeImports(t *testing.T) {
+	var p probeImports
+	creates, writes := 0, []int{}
+	create := func(ctx context.Context) error {
+		creates++
+		return nil
+	}
+	write := func(ctx context.Context, hop int) error {
+		writes = append(writes, hop)
+		return nil
+	}
+
+	// the import phase of hop 1 runs twice, e.g. after it was retried
+	for _, hop := range []int{0, 1, 1, 3} {
+		if err := p.importHop(context.Background(), hop, create, write); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if creates != 1 {
+		t.Errorf("wanted the class created once, got %d", creates)
+	}
+	if want := []int{0, 1, 3}; !reflect.DeepEqual(writes, want) || !reflect.DeepEqual(p.hops, want) {
+		t.Errorf("wanted hops %v, got writes %v and hops %v", want, writes, p.hops)
+	}
+	if p.rounds() != 3 {
+		t.Errorf("wanted 3 rounds, got %d", p.rounds())
+	}
+}
+
+func Test_probeID(t *testing.T) {
+	if probeID(nestedClass, 1, 2) != probeID(nestedClass, 1, 2) {
+		t.Errorf("wanted the same id for the same object")
+	}
+	ids := map[string]bool{
+		probeID(nestedClass, 1, 2):      true,
+		probeID(nestedClass, 2, 1):      true,
+		probeID(nestedClass, 12, 0):     true,
+		probeID(multiVectorClass, 1, 2): true,
+	}
+	if len(ids) != 4 {
+		t.Errorf("wanted 4 distinct ids, got %d", len(ids))
+	}
+}
+
+func Test_probeImports_once(t *testing.T) {
+	var p probeImports
+	writes := 0
+	create := func(ctx context.Context) error { return nil }
+	write := p.once(func(ctx context.Context) error {
+		writes++
+		return nil
+	})
+
+	for hop := 2; hop < 5; hop++ {
+		if err := p.importHop(context.Background(), hop, create, write); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if writes != 1 {
+		t.Errorf("wanted the objects written once, got %d", writes)
+	}
+}
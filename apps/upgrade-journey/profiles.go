@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"time"
+)
+
+// profileInterval is set by --profile-interval, 0 disables profiling
+var profileInterval time.Duration
+
+// profileKinds are the pprof profiles captured from every node
+var profileKinds = []string{"heap", "goroutine"}
+
+func profilesDir() string {
+	return path.Join(artifactsDir, "profiles")
+}
+
+// profileFileName names a profile after the node and the point of the run it
+// was taken at, so that the profiles of a node sort chronologically
+func profileFileName(node, kind string, at time.Time, label string) string {
+	return fmt.Sprintf("%s/%s-%s-%s.pb.gz", node, at.UTC().Format("20060102T150405"), label, kind)
+}
+
+// startProfiling captures the profiles of every node on every tick until the
+// returned function is called. Nodes that are down at the time of a tick are
+// skipped.
+func (c *cluster) startProfiling(ctx context.Context) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(profileInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				c.captureProfiles(ctx, "periodic")
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// captureProfiles stores the profiles of every running node under label.
+// It is best effort like sampleResources, a failed capture is logged but
+// never fails the run.
+func (c *cluster) captureProfiles(ctx context.Context, label string) {
+	at := time.Now()
+	for i, container := range c.Containers {
+		if container == nil {
+			continue
+		}
+
+		for _, kind := range profileKinds {
+			name := profileFileName(c.Hostname(i), kind, at, label)
+			if err := c.captureProfile(ctx, i, kind, path.Join(profilesDir(), name)); err != nil {
+				nodeLogger(c, i).Debug("cannot capture profile", "kind", kind, "err", err)
+			}
+		}
+	}
+}
+
+func (c *cluster) captureProfile(ctx context.Context, nodeId int, kind, dest string) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	url := fmt.Sprintf("http://%s/debug/pprof/%s", c.ProfilingHost(nodeId), kind)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d", url, res.StatusCode)
+	}
+
+	if err := os.MkdirAll(path.Dir(dest), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(f, res.Body)
+	return err
+}
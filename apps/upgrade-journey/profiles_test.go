@@ -0,0 +1,14 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProfileFileName(t *testing.T) {
+	at := time.Date(2024, 5, 17, 9, 3, 7, 0, time.FixedZone("CEST", 2*60*60))
+	want := "weaviate-1/20240517T070307-hop02-heap.pb.gz"
+	if got := profileFileName("weaviate-1", "heap", at, "hop02"); got != want {
+		t.Errorf("wanted %s, got %s", want, got)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/weaviate/weaviate-chaos-engineering/apps/upgrade-journey/pkg/faults"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate"
+)
+
+// isRaftVersion is true for any version that stores the schema in RAFT.
+// Before that the schema was replicated with a two-phase commit.
+func isRaftVersion(version string) bool {
+	return supports(version, featureRaft)
+}
+
+// isRaftMigrationHop is true for the single hop in the journey where the
+// cluster moves from the pre-RAFT schema to the RAFT-based schema
+func isRaftMigrationHop(prev, cur string) bool {
+	return !isRaftVersion(prev) && isRaftVersion(cur)
+}
+
+// withRaftWipe is set by --raft-wipe
+var withRaftWipe bool
+
+// raftSnapshotChaos deletes the entire RAFT state (log, snapshots and
+// metadata) of a single node and restarts it. The node has to re-bootstrap
+// the schema from its peers. Afterwards its schema must be identical to the
+// schema of a node that was not touched, and all objects must be reachable
+// through it.
+func raftSnapshotChaos(ctx context.Context, c *cluster, posOfMaxVersion int) error {
+	version := versions[posOfMaxVersion]
+	nodeId := c.NodeCount - 1
+
+	if err := applyFault(ctx, c, posOfMaxVersion, &faults.Wipe[*cluster]{Node: nodeId, Path: "raft"}, 0, nil); err != nil {
+		return err
+	}
+
+	if err := waitForSchemaConvergence(ctx, c.nodeClient(0), c.nodeClient(nodeId),
+		30*time.Second); err != nil {
+		return fmt.Errorf("%s after raft wipe: %w", c.Hostname(nodeId), err)
+	}
+
+	if err := verify(ctx, c.nodeClient(nodeId), posOfMaxVersion); err != nil {
+		return fmt.Errorf("%s after raft wipe: %w", c.Hostname(nodeId), err)
+	}
+
+	nodeLogger(c, nodeId).Info("node re-bootstrapped its schema from peers", "version", version)
+	return nil
+}
+
+// waitForSchemaConvergence polls both nodes until they report the same
+// schema or the timeout is hit
+func waitForSchemaConvergence(ctx context.Context, reference, candidate *weaviate.Client,
+	timeout time.Duration,
+) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		err := compareSchemas(ctx, reference, candidate)
+		if err == nil {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("schema did not converge within %s: %w", timeout, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("schema did not converge: %w", ctx.Err())
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func compareSchemas(ctx context.Context, reference, candidate *weaviate.Client) error {
+	want, err := classesByName(ctx, reference)
+	if err != nil {
+		return fmt.Errorf("reference schema: %w", err)
+	}
+
+	got, err := classesByName(ctx, candidate)
+	if err != nil {
+		return fmt.Errorf("candidate schema: %w", err)
+	}
+
+	if len(want) != len(got) {
+		return fmt.Errorf("wanted %d classes, got %d", len(want), len(got))
+	}
+
+	for name, wantClass := range want {
+		gotClass, ok := got[name]
+		if !ok {
+			return fmt.Errorf("class %s is missing", name)
+		}
+
+		if wantClass != gotClass {
+			return fmt.Errorf("class %s differs: wanted %s, got %s", name, wantClass, gotClass)
+		}
+	}
+
+	return nil
+}
+
+// classesByName returns the JSON representation of every class keyed by the
+// class name. Properties are sorted, so that the order in which a node
+// happens to return them does not matter.
+func classesByName(ctx context.Context, client *weaviate.Client) (map[string]string, error) {
+	reqCtx, cancel := requestContext(ctx)
+	dump, err := client.Schema().Getter().Do(reqCtx)
+	cancel()
+	if err != nil {
+		return nil, requestError(ctx, "get schema", err)
+	}
+
+	out := map[string]string{}
+	for _, class := range dump.Classes {
+		sort.Slice(class.Properties, func(a, b int) bool {
+			return class.Properties[a].Name < class.Properties[b].Name
+		})
+
+		asJSON, err := json.Marshal(class)
+		if err != nil {
+			return nil, err
+		}
+
+		out[class.Class] = string(asJSON)
+	}
+
+	return out, nil
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+)
+
+// rawVerifier performs the same checks as verify, but with hand-built
+// payloads sent over plain HTTP. It is used with --transport=raw, so that a
+// bug in the client library can neither mask a server bug nor produce a
+// false positive.
+type rawVerifier struct {
+	host string
+}
+
+func (v *rawVerifier) name() string {
+	return "raw"
+}
+
+func (v *rawVerifier) verify(ctx context.Context, posOfMaxVersion int) error {
+	for i := 0; i <= posOfMaxVersion; i++ {
+		if err := v.findObjectUsingVersionString(ctx, versions[i]); err != nil {
+			return fmt.Errorf("string filter: %w", err)
+		}
+
+		if err := v.findObjectUsingVersionInts(ctx, versions[i]); err != nil {
+			return fmt.Errorf("and-ed int filter: %w", err)
+		}
+	}
+
+	if err := v.aggregateObjects(ctx); err != nil {
+		return err
+	}
+
+	if err := v.groupedAggregation(ctx, posOfMaxVersion); err != nil {
+		return err
+	}
+
+	if err := v.listObjects(ctx); err != nil {
+		return err
+	}
+
+	if err := v.unfilteredVectorSearch(ctx, posOfMaxVersion); err != nil {
+		return fmt.Errorf("unfiltered vec: %w", err)
+	}
+
+	for i := 0; i <= posOfMaxVersion; i++ {
+		if err := v.filteredVectorSearch(ctx, versions[i]); err != nil {
+			return fmt.Errorf("filtered vec: %w", err)
+		}
+	}
+
+	for i := 0; i <= posOfMaxVersion; i++ {
+		if err := v.crossClassChain(ctx, versions[i]); err != nil {
+			return fmt.Errorf("cross-class chain for %s: %w", versions[i], err)
+		}
+	}
+
+	return nil
+}
+
+func (v *rawVerifier) findObjectUsingVersionString(ctx context.Context, version string) error {
+	where := fmt.Sprintf(`{path:["version"],operator:Equal,valueString:%q}`, version)
+	return v.findSingleObject(ctx, version, where)
+}
+
+func (v *rawVerifier) findObjectUsingVersionInts(ctx context.Context, version string) error {
+	parsed, ok := parseVersion(version)
+	if !ok {
+		return nil
+	}
+
+	where := fmt.Sprintf(`{operator:And,operands:[`+
+		`{path:["major_version"],operator:Equal,valueInt:%d},`+
+		`{path:["minor_version"],operator:Equal,valueInt:%d},`+
+		`{path:["patch_version"],operator:Equal,valueInt:%d}]}`,
+		parsed.major(), parsed.minor(), parsed.patch())
+	return v.findSingleObject(ctx, version, where)
+}
+
+func (v *rawVerifier) findSingleObject(ctx context.Context, version, where string) error {
+	query := fmt.Sprintf(`{Get{Collection(where:%s){version ref_prop{... on RefTarget{version}}}}}`, where)
+
+	var res struct {
+		Get struct {
+			Collection []struct {
+				Version string `json:"version"`
+				RefProp []struct {
+					Version string `json:"version"`
+				} `json:"ref_prop"`
+			} `json:"Collection"`
+		} `json:"Get"`
+	}
+	if err := v.graphQL(ctx, query, &res); err != nil {
+		return err
+	}
+
+	if len(res.Get.Collection) != 1 {
+		return fmt.Errorf("wanted 1 object for %s, got %d", version, len(res.Get.Collection))
+	}
+
+	obj := res.Get.Collection[0]
+	if obj.Version != version {
+		return fmt.Errorf("root obj: wanted %s got %s", version, obj.Version)
+	}
+
+	if len(obj.RefProp) != 1 || obj.RefProp[0].Version != version {
+		return fmt.Errorf("ref object: wanted %s got %v", version, obj.RefProp)
+	}
+
+	return nil
+}
+
+func (v *rawVerifier) aggregateObjects(ctx context.Context) error {
+	var res struct {
+		Aggregate struct {
+			Collection []struct {
+				Meta struct {
+					Count int `json:"count"`
+				} `json:"meta"`
+			} `json:"Collection"`
+		} `json:"Aggregate"`
+	}
+	if err := v.graphQL(ctx, `{Aggregate{Collection{meta{count}}}}`, &res); err != nil {
+		return err
+	}
+
+	if len(res.Aggregate.Collection) != 1 {
+		return fmt.Errorf("aggregation: no result")
+	}
+
+	if actual := res.Aggregate.Collection[0].Meta.Count; actual != objectsCreated {
+		return fmt.Errorf("aggregation: wanted %d, got %d", objectsCreated, actual)
+	}
+
+	return nil
+}
+
+// listObjects uses the REST API rather than GraphQL to make sure that both
+// APIs return the same objects
+func (v *rawVerifier) listObjects(ctx context.Context) error {
+	body, err := v.do(ctx, http.MethodGet, "/v1/objects?class=Collection&limit=10000", nil)
+	if err != nil {
+		return err
+	}
+
+	var res struct {
+		Objects []json.RawMessage `json:"objects"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return err
+	}
+
+	if len(res.Objects) != objectsCreated {
+		return fmt.Errorf("rest list: wanted %d, got %d", objectsCreated, len(res.Objects))
+	}
+
+	return nil
+}
+
+func (v *rawVerifier) unfilteredVectorSearch(ctx context.Context, posOfMaxVersion int) error {
+	query := fmt.Sprintf(`{Get{Collection(nearVector:{vector:%s},limit:10000){version}}}`,
+		rawVector())
+
+	var res struct {
+		Get struct {
+			Collection []json.RawMessage `json:"Collection"`
+		} `json:"Get"`
+	}
+	if err := v.graphQL(ctx, query, &res); err != nil {
+		return err
+	}
+
+	if len(res.Get.Collection) != posOfMaxVersion+1 {
+		return fmt.Errorf("not all objects returned in vector search")
+	}
+
+	return nil
+}
+
+func (v *rawVerifier) filteredVectorSearch(ctx context.Context, version string) error {
+	query := fmt.Sprintf(`{Get{Collection(nearVector:{vector:%s},`+
+		`where:{path:["version"],operator:Equal,valueString:%q}){version}}}`,
+		rawVector(), version)
+
+	var res struct {
+		Get struct {
+			Collection []struct {
+				Version string `json:"version"`
+			} `json:"Collection"`
+		} `json:"Get"`
+	}
+	if err := v.graphQL(ctx, query, &res); err != nil {
+		return err
+	}
+
+	if len(res.Get.Collection) == 0 || res.Get.Collection[0].Version != version {
+		return fmt.Errorf("wanted %s got %v", version, res.Get.Collection)
+	}
+
+	return nil
+}
+
+// graphQL sends the query and decodes the data section of the response into
+// target. Any GraphQL error is returned as an error.
+func (v *rawVerifier) graphQL(ctx context.Context, query string, target interface{}) error {
+	payload, err := json.Marshal(map[string]string{"query": query})
+	if err != nil {
+		return err
+	}
+
+	body, err := v.do(ctx, http.MethodPost, "/v1/graphql", payload)
+	if err != nil {
+		return err
+	}
+
+	var res struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return err
+	}
+
+	if len(res.Errors) > 0 {
+		return fmt.Errorf("graphql: %s", res.Errors[0].Message)
+	}
+
+	return json.Unmarshal(res.Data, target)
+}
+
+func (v *rawVerifier) do(ctx context.Context, method, endpoint string, payload []byte) ([]byte, error) {
+	reqCtx, cancel := requestContext(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, method,
+		fmt.Sprintf("http://%s%s", v.host, endpoint), bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	op := fmt.Sprintf("%s %s on %s", method, endpoint, v.host)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, requestError(ctx, op, err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, requestError(ctx, op, err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s %s: status %d: %s", method, endpoint, res.StatusCode, body)
+	}
+
+	return body, nil
+}
+
+func rawVector() string {
+	parts := make([]string, 32)
+	for i := range parts {
+		parts[i] = fmt.Sprintf("%f", rand.Float32())
+	}
+	return "[" + strings.Join(parts, ",") + "]"
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// withReadRepair is set by --read-repair
+var withReadRepair bool
+
+const (
+	// objects of the replicated class written while a replica is down
+	readRepairObjects = 20
+
+	// how long the repaired node may take to serve every missed object
+	readRepairTimeout = time.Minute
+)
+
+// readRepair takes the last node down, writes objects of the replicated
+// class at QUORUM, so that the node misses them, and brings it back.
+// Reading every missed object at ALL has to repair the node: afterwards it
+// serves all of them from its own replica, with the timestamps of the
+// other replicas. Weaviate has no endpoint that repairs a shard on demand,
+// the reads are the only trigger. The time from the first read to the last
+// repaired object is reported per hop.
+func (c *cluster) readRepair(ctx context.Context, posOfVersion int) error {
+	// a write at QUORUM with a replica down needs at least three of them
+	if replicatedIds == nil || c.NodeCount < 3 {
+		hopLogger(posOfVersion).Info("skipping read repair, it needs a replicated class on at least 3 nodes")
+		return nil
+	}
+
+	down := c.NodeCount - 1
+	if err := c.StopNode(ctx, down); err != nil {
+		return err
+	}
+
+	var missed []string
+	for i := 0; i < readRepairObjects; i++ {
+		id, err := createReplicated(ctx, versions[posOfVersion], "QUORUM")
+		if err != nil {
+			return fmt.Errorf("while %s is down: %w", c.Hostname(down), err)
+		}
+		missed = append(missed, id)
+	}
+
+	if err := c.StartStoppedNode(ctx, down, c.NodeVersions[down]); err != nil {
+		return fmt.Errorf("restart %s: %w", c.Hostname(down), err)
+	}
+	if err := c.WaitForHealthyNodes(ctx, 0, time.Minute); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	for _, id := range missed {
+		if _, err := getRaw(ctx, 0, fmt.Sprintf("/v1/objects/%s/%s?consistency_level=ALL",
+			replicatedClass, id)); err != nil {
+			return fmt.Errorf("read %s at ALL: %w", id, err)
+		}
+	}
+
+	// the copies on the nodes that were up are the reference
+	if err := preservedTimestamps.checkIds(ctx, 0, replicatedClass, missed, ""); err != nil {
+		return err
+	}
+
+	node := "?node_name=" + c.Hostname(down)
+	deadline := time.Now().Add(readRepairTimeout)
+	for {
+		err := preservedTimestamps.checkIds(ctx, 0, replicatedClass, missed, node)
+		if err == nil {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s not repaired within %s: %w", c.Hostname(down), readRepairTimeout, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+
+	took := time.Since(start)
+	runReport.recordLatency(posOfVersion, "read repair", took)
+	hopLogger(posOfVersion).Info("read repair converged", "node", c.Hostname(down), "objects", len(missed),
+		"took", took.Round(time.Millisecond))
+	return nil
+}
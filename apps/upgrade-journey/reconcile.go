@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// desiredState is a state the cluster is driven into after the journey.
+// Scenarios declare a list of them in the scenario file, and the harness
+// converges to one after the other instead of scripting every step:
+//
+//	states:
+//	  - version: 1.24.4
+//	    nodes: 2
+//	    classes: [Extra]
+//	  - version: 1.25.0
+//
+// Nodes is the number of running nodes, counted from weaviate-0, and
+// defaults to all of them. Classes are created when they are missing, and
+// classes an earlier state created are removed when a state no longer
+// lists them. Every state is verified once the cluster converged.
+type desiredState struct {
+	Version string   `yaml:"version"`
+	Nodes   int      `yaml:"nodes"`
+	Classes []string `yaml:"classes"`
+}
+
+// scenarioStates is set from --scenario-file
+var scenarioStates []desiredState
+
+func (s desiredState) validate(versions []string, nodeCount int) error {
+	if s.Nodes < 0 || s.Nodes > nodeCount {
+		return fmt.Errorf("state %s: nodes must be between 0, for all of them, and %d", s.Version, nodeCount)
+	}
+	if versionPos(versions, s.Version) < 0 {
+		return fmt.Errorf("state %s: version is not part of the journey %v", s.Version, versions)
+	}
+	return nil
+}
+
+func versionPos(versions []string, version string) int {
+	for i, v := range versions {
+		if v == version {
+			return i
+		}
+	}
+	return -1
+}
+
+// reconcileTimeout bounds how long the cluster may take to converge to a
+// single state
+const reconcileTimeout = 10 * time.Minute
+
+type nodeState struct {
+	running bool
+	version string
+}
+
+// observedState is what the cluster looks like right now
+type observedState struct {
+	nodes   []nodeState
+	classes map[string]bool
+}
+
+type reconcileKind string
+
+const (
+	reconcileStart       reconcileKind = "start"
+	reconcileStop        reconcileKind = "stop"
+	reconcileUpgrade     reconcileKind = "upgrade"
+	reconcileCreateClass reconcileKind = "create class"
+	reconcileDeleteClass reconcileKind = "delete class"
+)
+
+// reconcileAction is a single step towards a desired state
+type reconcileAction struct {
+	kind    reconcileKind
+	node    int
+	version string
+	class   string
+}
+
+func (a reconcileAction) String() string {
+	switch a.kind {
+	case reconcileCreateClass, reconcileDeleteClass:
+		return fmt.Sprintf("%s %s", a.kind, a.class)
+	case reconcileStop:
+		return fmt.Sprintf("%s weaviate-%d", a.kind, a.node)
+	default:
+		return fmt.Sprintf("%s weaviate-%d on %s", a.kind, a.node, a.version)
+	}
+}
+
+// planReconcile returns the steps from the observed to the desired state.
+// Nodes come first, and one at a time, like in a rolling update; classes
+// only once every node that has to run does. owned are the classes that
+// earlier states created, only those are ever removed.
+func planReconcile(want desiredState, got observedState, owned map[string]bool) []reconcileAction {
+	running := want.Nodes
+	if running == 0 {
+		running = len(got.nodes)
+	}
+
+	var actions []reconcileAction
+	for i, node := range got.nodes {
+		switch {
+		case i >= running && node.running:
+			actions = append(actions, reconcileAction{kind: reconcileStop, node: i})
+		case i >= running:
+		case !node.running:
+			actions = append(actions, reconcileAction{kind: reconcileStart, node: i, version: want.Version})
+		case node.version != want.Version:
+			actions = append(actions, reconcileAction{kind: reconcileUpgrade, node: i, version: want.Version})
+		}
+	}
+	if len(actions) > 0 {
+		return actions
+	}
+
+	wanted := map[string]bool{}
+	for _, class := range want.Classes {
+		wanted[class] = true
+		if !got.classes[class] {
+			actions = append(actions, reconcileAction{kind: reconcileCreateClass, class: class})
+		}
+	}
+	var removed []string
+	for class := range owned {
+		if !wanted[class] && got.classes[class] {
+			removed = append(removed, class)
+		}
+	}
+	sort.Strings(removed)
+	for _, class := range removed {
+		actions = append(actions, reconcileAction{kind: reconcileDeleteClass, class: class})
+	}
+	return actions
+}
+
+// reconciler drives the cluster into the desired states one after the
+// other
+type reconciler struct {
+	owned map[string]bool
+}
+
+var stateReconciler = &reconciler{owned: map[string]bool{}}
+
+// converge observes the cluster and applies the first planned step until
+// nothing is left to do. The cluster is observed again after every step,
+// so that a node that went away in the meantime is brought back as well.
+func (r *reconciler) converge(ctx context.Context, c *cluster, want desiredState) error {
+	deadline := time.Now().Add(reconcileTimeout)
+	for {
+		got, err := observeCluster(ctx, c)
+		if err != nil {
+			return err
+		}
+
+		actions := planReconcile(want, got, r.owned)
+		if len(actions) == 0 {
+			logger.Info("cluster converged", "version", want.Version, "nodes", want.Nodes, "classes", want.Classes)
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("cluster did not converge to %s within %s, left: %v", want.Version,
+				reconcileTimeout, actions)
+		}
+
+		logger.Info("reconciling", "action", actions[0].String(), "pending", len(actions)-1)
+		if err := r.apply(ctx, c, actions[0]); err != nil {
+			return fmt.Errorf("%s: %w", actions[0], err)
+		}
+	}
+}
+
+func (r *reconciler) apply(ctx context.Context, c *cluster, a reconcileAction) error {
+	switch a.kind {
+	case reconcileStart:
+		return c.StartStoppedNode(ctx, a.node, a.version)
+	case reconcileStop:
+		return c.StopNode(ctx, a.node)
+	case reconcileUpgrade:
+		if err := c.StopNode(ctx, a.node); err != nil {
+			return err
+		}
+		return c.StartStoppedNode(ctx, a.node, a.version)
+	case reconcileCreateClass:
+		if _, err := postRaw(ctx, 0, "/v1/schema", map[string]interface{}{
+			"class":      a.class,
+			"vectorizer": "none",
+		}); err != nil {
+			return err
+		}
+		r.owned[a.class] = true
+		return nil
+	case reconcileDeleteClass:
+		if _, err := sendRaw(ctx, http.MethodDelete, 0, "/v1/schema/"+a.class, nil); err != nil {
+			return err
+		}
+		delete(r.owned, a.class)
+		return nil
+	default:
+		return fmt.Errorf("unknown action %q", a.kind)
+	}
+}
+
+// observeCluster reads which nodes are live and the classes of the schema.
+// The version of a node is the one it was last started with.
+func observeCluster(ctx context.Context, c *cluster) (observedState, error) {
+	got := observedState{nodes: make([]nodeState, c.NodeCount), classes: map[string]bool{}}
+	for i := range got.nodes {
+		_, err := getRaw(ctx, i, "/v1/.well-known/live")
+		got.nodes[i] = nodeState{running: err == nil, version: c.NodeVersions[i]}
+	}
+
+	if !got.nodes[0].running {
+		return got, nil
+	}
+	body, err := getRaw(ctx, 0, "/v1/schema")
+	if err != nil {
+		return got, fmt.Errorf("observe schema: %w", err)
+	}
+	var schema struct {
+		Classes []struct {
+			Class string `json:"class"`
+		} `json:"classes"`
+	}
+	if err := json.Unmarshal(body, &schema); err != nil {
+		return got, err
+	}
+	for _, class := range schema.Classes {
+		got.classes[class.Class] = true
+	}
+	return got, nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlanReconcile(t *testing.T) {
+	running := func(version string) nodeState { return nodeState{running: true, version: version} }
+	stopped := nodeState{version: "1.24.0"}
+
+	tests := []struct {
+		name  string
+		want  desiredState
+		got   observedState
+		owned map[string]bool
+		plan  []string
+	}{
+		{
+			name: "converged",
+			want: desiredState{Version: "1.25.0", Classes: []string{"Extra"}},
+			got: observedState{
+				nodes:   []nodeState{running("1.25.0"), running("1.25.0")},
+				classes: map[string]bool{"Extra": true, "Collection": true},
+			},
+		},
+		{
+			name: "nodes before classes",
+			want: desiredState{Version: "1.25.0", Nodes: 2, Classes: []string{"Extra"}},
+			got: observedState{
+				nodes: []nodeState{running("1.24.0"), stopped, running("1.24.0")},
+			},
+			plan: []string{
+				"upgrade weaviate-0 on 1.25.0",
+				"start weaviate-1 on 1.25.0",
+				"stop weaviate-2",
+			},
+		},
+		{
+			name: "only owned classes are removed",
+			want: desiredState{Version: "1.25.0", Nodes: 1, Classes: []string{"New"}},
+			got: observedState{
+				nodes:   []nodeState{running("1.25.0"), stopped},
+				classes: map[string]bool{"Old": true, "Collection": true},
+			},
+			owned: map[string]bool{"Old": true},
+			plan:  []string{"create class New", "delete class Old"},
+		},
+	}
+
+	for _, tt := range tests {
+		var plan []string
+		for _, action := range planReconcile(tt.want, tt.got, tt.owned) {
+			plan = append(plan, action.String())
+		}
+		if !reflect.DeepEqual(plan, tt.plan) {
+			t.Errorf("%s: wanted %q, got %q", tt.name, tt.plan, plan)
+		}
+	}
+}
+
+func TestDesiredStateValidate(t *testing.T) {
+	versions := []string{"1.24.0", "1.25.0"}
+	tests := []struct {
+		state   desiredState
+		wantErr bool
+	}{
+		{state: desiredState{Version: "1.25.0", Nodes: 3}},
+		{state: desiredState{Version: "1.25.0", Nodes: 4}, wantErr: true},
+		{state: desiredState{Version: "1.23.0"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		if err := tt.state.validate(versions, 3); (err != nil) != tt.wantErr {
+			t.Errorf("%+v: unexpected error %v", tt.state, err)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/weaviate/weaviate-chaos-engineering/apps/upgrade-journey/pkg/faults"
+	"github.com/weaviate/weaviate-chaos-engineering/apps/upgrade-journey/pkg/remote"
+)
+
+// With --remote-cluster the nodes run on remote hosts, e.g. VMs or bare
+// metal, and are started, upgraded and faulted over SSH instead of in
+// Docker. The journey wipes the data of every node before the first hop.
+var (
+	// remoteClusterFile is set by --remote-cluster
+	remoteClusterFile string
+
+	remoteNodes *remote.Cluster
+)
+
+// remoteFlags are the flags that work with --remote-cluster, every other
+// flag needs the Docker cluster and is rejected
+func remoteFlags() map[string]bool {
+	allowed := map[string]bool{"remote-cluster": true, "path-policy": true}
+	for name := range externalFlags {
+		if name != "external-url" {
+			allowed[name] = true
+		}
+	}
+	return allowed
+}
+
+// remoteFaultRegistry holds the faults that can be applied over SSH, as well
+// as the client-side faults
+func remoteFaultRegistry() faults.Registry[*cluster] {
+	return faults.Registry[*cluster]{
+		"kill": func(p faults.Params) (faultInjector, error) {
+			node, err := p.Node(nodeCount)
+			return &remoteKill{node: node}, err
+		},
+		"stop": func(p faults.Params) (faultInjector, error) {
+			node, err := p.Node(nodeCount)
+			return &remoteStop{node: node}, err
+		},
+		"http": newHTTPFault,
+	}
+}
+
+// remoteKill kills a remote node and starts it again on the same data
+type remoteKill struct {
+	node int
+}
+
+func (f *remoteKill) Describe() string {
+	return fmt.Sprintf("kill %s", remoteNodes.Hostname(f.node))
+}
+
+func (f *remoteKill) Inject(ctx context.Context, c *cluster) error {
+	return remoteNodes.KillNode(ctx, f.node)
+}
+
+func (f *remoteKill) Heal(ctx context.Context, c *cluster) error {
+	return remoteNodes.StartNode(ctx, f.node, remoteNodes.NodeVersions[f.node])
+}
+
+// remoteStop shuts a remote node down gracefully for the hold duration
+type remoteStop struct {
+	node int
+}
+
+func (f *remoteStop) Describe() string {
+	return fmt.Sprintf("stop %s", remoteNodes.Hostname(f.node))
+}
+
+func (f *remoteStop) Inject(ctx context.Context, c *cluster) error {
+	return remoteNodes.StopNode(ctx, f.node)
+}
+
+func (f *remoteStop) Heal(ctx context.Context, c *cluster) error {
+	return remoteNodes.StartNode(ctx, f.node, remoteNodes.NodeVersions[f.node])
+}
+
+// doRemote runs the journey on the remote nodes. It runs the workloads and
+// verifiers that need no Docker, and saves the logs of the nodes at the end.
+func doRemote(ctx context.Context, pool *clientPool, importRouting routing, loadObjects int,
+	verifiers []verifier, faults faultSchedule, b *budget,
+) (err error) {
+	ctx, stopSelfCheck := harnessHealth.watch(ctx)
+	defer func() {
+		stopSelfCheck()
+		if cause := harnessHealth.cause(); cause != nil {
+			err = cause
+		}
+	}()
+
+	defer func() {
+		if err := remoteNodes.SaveLogs(context.Background(), path.Join(artifactsDir, "logs")); err != nil {
+			logger.Error("cannot save logs", "err", err)
+		}
+	}()
+
+	defer func() {
+		if err != nil {
+			saveCountComparison()
+		}
+	}()
+
+	if err := remoteNodes.Reset(ctx); err != nil {
+		return err
+	}
+
+	if err := clientFaultProxy.start(nodeHost(0)); err != nil {
+		return err
+	}
+
+	for i, version := range versions {
+		startPhase := phaseUpgrade
+		if i == 0 {
+			startPhase = phaseStart
+		}
+
+		if err := b.run(ctx, i, startPhase, func(ctx context.Context) error {
+			if i == 0 {
+				return remoteNodes.StartAllNodes(ctx, version)
+			}
+			return remoteNodes.RollingUpdate(ctx, version)
+		}); err != nil {
+			return err
+		}
+
+		if err := b.run(ctx, i, phaseImport, func(ctx context.Context) error {
+			if i == 0 {
+				if err := createWorkloadClasses(ctx, pool); err != nil {
+					return err
+				}
+
+				if err := checkIntendedSchema(ctx, i); err != nil {
+					return err
+				}
+
+				if err := recordClassConfigBaseline(ctx, "RefTarget", "Collection"); err != nil {
+					return err
+				}
+			}
+			return importWorkloads(ctx, pool, importRouting, i, loadObjects)
+		}); err != nil {
+			return err
+		}
+
+		if err := b.run(ctx, i, phaseVerify, func(ctx context.Context) error {
+			return verifyHop(ctx, verifiers, i)
+		}); err != nil {
+			return err
+		}
+
+		// the remote faults act on remoteNodes, not on a Docker cluster
+		if err := applyHopFaults(ctx, nil, verifiers, faults, b, i, loadObjects); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
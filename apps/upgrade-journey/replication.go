@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	replicatedClass  = "Replicated"
+	replicatedPerHop = 20
+	resyncTimeout    = 2 * time.Minute
+)
+
+// replicatedIds are the ids of all objects of the replicated class, every
+// node holds a copy of each of them
+var replicatedIds []string
+
+// importReplicated creates the replicated class on the first hop that
+// supports replication and imports objects into it on every hop from then
+// on
+func importReplicated(ctx context.Context, c *cluster, version string) error {
+	if !supports(version, featureReplication) {
+		return nil
+	}
+
+	if replicatedIds == nil {
+		if _, err := postRaw(ctx, 0, "/v1/schema", map[string]interface{}{
+			"class":             replicatedClass,
+			"vectorizer":        "none",
+			"replicationConfig": map[string]interface{}{"factor": c.NodeCount},
+			"properties": []map[string]interface{}{
+				{"name": "version", "dataType": []string{"text"}},
+			},
+		}); err != nil {
+			return fmt.Errorf("create class %s: %w", replicatedClass, err)
+		}
+		replicatedIds = []string{}
+	}
+
+	for i := 0; i < replicatedPerHop; i++ {
+		if _, err := createReplicated(ctx, version, "ALL"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// createReplicated writes a new object of the replicated class at the given
+// consistency level and returns its id
+func createReplicated(ctx context.Context, version, consistency string) (string, error) {
+	id := uuid.New().String()
+	vec := make([]float32, 4)
+	for i := range vec {
+		vec[i] = rand.Float32()
+	}
+
+	if _, err := postRaw(ctx, 0, "/v1/objects?consistency_level="+consistency, map[string]interface{}{
+		"class":      replicatedClass,
+		"id":         id,
+		"vector":     vec,
+		"properties": map[string]interface{}{"version": version},
+	}); err != nil {
+		return "", fmt.Errorf("import replicated object: %w", err)
+	}
+	replicatedIds = append(replicatedIds, id)
+	return id, nil
+}
+
+// replaceFault simulates a disk replacement: the node loses its entire data
+// directory and comes back empty. Heal restarts it and waits until it holds
+// a full copy of the replicated class again, the time this takes is part of
+// the fault timeline.
+type replaceFault struct {
+	node    int
+	skipped bool
+}
+
+func (f *replaceFault) Describe() string {
+	return fmt.Sprintf("replace weaviate-%d with an empty data directory", f.node)
+}
+
+func (f *replaceFault) Inject(ctx context.Context, c *cluster) error {
+	if replicatedIds == nil {
+		// without a replicated class there is nothing to recover from
+		logger.Info("skipping node replacement, replication is not supported yet",
+			"version", c.NodeVersions[f.node])
+		f.skipped = true
+		return nil
+	}
+
+	if err := c.StopNode(ctx, f.node); err != nil {
+		return err
+	}
+
+	return c.WipeNodeData(ctx, f.node)
+}
+
+func (f *replaceFault) Heal(ctx context.Context, c *cluster) error {
+	if f.skipped {
+		return nil
+	}
+
+	if err := c.StartStoppedNode(ctx, f.node, c.NodeVersions[f.node]); err != nil {
+		return fmt.Errorf("start replaced node: %w", err)
+	}
+
+	start := time.Now()
+	err := resyncReplicas(ctx, c, f.node)
+	runReport.recordFault(runProgress.currentHop(), fmt.Sprintf("resync weaviate-%d from replicas", f.node), start, err)
+	return err
+}
+
+// resyncReplicas reads every replicated object with consistency ALL, which
+// repairs the missing replicas on the replaced node, and waits until the
+// node reports all of them in its own shards with their original timestamps
+func resyncReplicas(ctx context.Context, c *cluster, nodeId int) error {
+	if err := c.WaitForHealthyNodes(ctx, 0, time.Minute); err != nil {
+		return err
+	}
+
+	for _, id := range replicatedIds {
+		if _, err := getRaw(ctx, 0, fmt.Sprintf("/v1/objects/%s/%s?consistency_level=ALL",
+			replicatedClass, id)); err != nil {
+			return fmt.Errorf("repair %s: %w", id, err)
+		}
+	}
+
+	deadline := time.Now().Add(resyncTimeout)
+	for {
+		count, err := localObjectCount(ctx, c, nodeId, replicatedClass)
+		if err == nil && count == len(replicatedIds) {
+			// the repair copies the objects, their timestamps have to come
+			// along unchanged
+			return preservedTimestamps.checkIds(ctx, 0, replicatedClass, replicatedIds,
+				"?node_name="+c.Hostname(nodeId))
+		}
+
+		if time.Now().After(deadline) {
+			if err != nil {
+				return fmt.Errorf("resync within %s: %w", resyncTimeout, err)
+			}
+			return fmt.Errorf("%s holds %d of %d replicated objects after %s",
+				c.Hostname(nodeId), count, len(replicatedIds), resyncTimeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// localObjectCount is the number of objects of a class in the shards of a
+// single node
+func localObjectCount(ctx context.Context, c *cluster, nodeId int, class string) (int, error) {
+	raw, err := getRaw(ctx, nodeId, "/v1/nodes?output=verbose")
+	if err != nil {
+		return 0, err
+	}
+
+	var res struct {
+		Nodes []struct {
+			Name   string `json:"name"`
+			Shards []struct {
+				Class       string `json:"class"`
+				ObjectCount int    `json:"objectCount"`
+			} `json:"shards"`
+		} `json:"nodes"`
+	}
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, node := range res.Nodes {
+		if node.Name != c.Hostname(nodeId) {
+			continue
+		}
+		for _, shard := range node.Shards {
+			if shard.Class == class {
+				count += shard.ObjectCount
+			}
+		}
+	}
+	return count, nil
+}
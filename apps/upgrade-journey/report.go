@@ -0,0 +1,490 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type phaseTiming struct {
+	Hop     int
+	Version string
+	Phase   phase
+	Start   time.Time
+	Took    time.Duration
+	Err     string
+}
+
+type verifyLatency struct {
+	Hop      int
+	Verifier string
+	Took     time.Duration
+}
+
+type nodeResources struct {
+	Hop         int
+	Node        string
+	MemoryBytes uint64
+	DiskBytes   uint64
+}
+
+type nodeStartup struct {
+	Version string
+	Node    string
+	Took    time.Duration
+}
+
+// hopTiming is a duration measured on a hop, such as loading the schema
+// from a node or activating a tenant
+type hopTiming struct {
+	Hop  int
+	Name string
+	Took time.Duration
+}
+
+// recallSample is the recall of a vector search check on a hop
+type recallSample struct {
+	Hop    int
+	Check  string
+	Recall float64
+}
+
+// the checks that record a recall
+const (
+	recallAsyncIndexing  = "async_indexing"
+	recallFilteredSearch = "filtered_search"
+)
+
+type stepOutcome struct {
+	Hop      int
+	Step     string
+	Outcome  outcome
+	Attempts int
+	Err      string
+}
+
+type faultEvent struct {
+	Hop         int
+	Description string
+	Start       time.Time
+	Took        time.Duration
+	Err         string
+}
+
+// report collects everything that ends up in the HTML report. Recording is
+// cheap and never fails, so it can be done unconditionally.
+type report struct {
+	sync.Mutex
+	started     time.Time
+	phases      []phaseTiming
+	latencies   []verifyLatency
+	resources   []nodeResources
+	faults      []faultEvent
+	steps       []stepOutcome
+	startups    []nodeStartup
+	schemas     []hopTiming
+	activations []hopTiming
+	recalls     []recallSample
+
+	// convergence holds the ledger count drift sampled after every healed
+	// fault, by hop and fault
+	convergence map[string][]float64
+}
+
+var runReport = &report{started: time.Now()}
+
+func (r *report) recordPhase(hop int, p phase, start time.Time, err error) {
+	r.Lock()
+	defer r.Unlock()
+	r.phases = append(r.phases, phaseTiming{
+		Hop: hop, Version: versions[hop], Phase: p, Start: start,
+		Took: time.Since(start), Err: errString(err),
+	})
+}
+
+func (r *report) recordLatency(hop int, verifier string, took time.Duration) {
+	r.Lock()
+	defer r.Unlock()
+	r.latencies = append(r.latencies, verifyLatency{Hop: hop, Verifier: verifier, Took: took})
+}
+
+func (r *report) recordResources(hop int, node string, memory, disk uint64) {
+	r.Lock()
+	defer r.Unlock()
+	r.resources = append(r.resources, nodeResources{
+		Hop: hop, Node: node, MemoryBytes: memory, DiskBytes: disk,
+	})
+}
+
+func (r *report) recordStartup(node, version string, took time.Duration) {
+	r.Lock()
+	defer r.Unlock()
+	r.startups = append(r.startups, nodeStartup{Version: version, Node: node, Took: took})
+}
+
+func (r *report) recordSchemaLoad(hop int, node string, took time.Duration) {
+	r.Lock()
+	defer r.Unlock()
+	r.schemas = append(r.schemas, hopTiming{Hop: hop, Name: node, Took: took})
+}
+
+func (r *report) recordActivation(hop int, tenant string, took time.Duration) {
+	r.Lock()
+	defer r.Unlock()
+	r.activations = append(r.activations, hopTiming{Hop: hop, Name: tenant, Took: took})
+}
+
+func (r *report) recordRecall(hop int, check string, recall float64) {
+	r.Lock()
+	defer r.Unlock()
+	r.recalls = append(r.recalls, recallSample{Hop: hop, Check: check, Recall: recall})
+}
+
+// nodeMemory returns the memory of every node sampled after a hop
+func (r *report) nodeMemory(hop int) map[string]uint64 {
+	r.Lock()
+	defer r.Unlock()
+	memory := map[string]uint64{}
+	for _, res := range r.resources {
+		if res.Hop == hop {
+			memory[res.Node] = res.MemoryBytes
+		}
+	}
+	return memory
+}
+
+func (r *report) recordFault(hop int, description string, start time.Time, err error) {
+	r.Lock()
+	defer r.Unlock()
+	r.faults = append(r.faults, faultEvent{
+		Hop: hop, Description: description, Start: start,
+		Took: time.Since(start), Err: errString(err),
+	})
+}
+
+func (r *report) recordConvergence(hop int, fault string, drift []float64) {
+	r.Lock()
+	defer r.Unlock()
+	if r.convergence == nil {
+		r.convergence = map[string][]float64{}
+	}
+	r.convergence[fmt.Sprintf("%d %s", hop, fault)] = drift
+}
+
+func (r *report) recordStep(hop int, step string, o outcome, attempts int, err error) {
+	r.Lock()
+	defer r.Unlock()
+	r.steps = append(r.steps, stepOutcome{
+		Hop: hop, Step: step, Outcome: o, Attempts: attempts, Err: errString(err),
+	})
+}
+
+// flakySteps are kept apart from failures, so that transient hiccups don't
+// drown out real findings
+func (r *report) flakySteps() []stepOutcome {
+	var out []stepOutcome
+	for _, s := range r.steps {
+		if s.Outcome == outcomeFlaky {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// writeHTML renders a single self-contained file without any external
+// scripts or styles, so that it can be attached to CI artifacts and opened
+// anywhere
+func (r *report) writeHTML(dir string, runErr error) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if err := os.MkdirAll(dir, 0o777); err != nil {
+		return err
+	}
+
+	var phaseBars, latencyBars, faultBars, startupBars []bar
+	for _, p := range r.phases {
+		phaseBars = append(phaseBars, bar{
+			label: fmt.Sprintf("%d %s %s", p.Hop, p.Version, p.Phase),
+			start: p.Start.Sub(r.started).Seconds(), value: p.Took.Seconds(), failed: p.Err != "",
+		})
+	}
+	for _, l := range r.latencies {
+		latencyBars = append(latencyBars, bar{
+			label: fmt.Sprintf("%d %s %s", l.Hop, versions[l.Hop], l.Verifier),
+			value: float64(l.Took.Milliseconds()),
+		})
+	}
+	for _, f := range r.faults {
+		faultBars = append(faultBars, bar{
+			label: fmt.Sprintf("%d %s", f.Hop, f.Description),
+			start: f.Start.Sub(r.started).Seconds(), value: f.Took.Seconds(), failed: f.Err != "",
+		})
+	}
+
+	for _, s := range r.startups {
+		startupBars = append(startupBars, bar{
+			label: fmt.Sprintf("%s %s", s.Version, s.Node), value: s.Took.Seconds(),
+		})
+	}
+	schemaBars, activationBars := hopTimingBars(r.schemas), hopTimingBars(r.activations)
+
+	memory := map[string][]float64{}
+	disk := map[string][]float64{}
+	for _, res := range r.resources {
+		memory[res.Node] = append(memory[res.Node], float64(res.MemoryBytes)/(1<<20))
+		disk[res.Node] = append(disk[res.Node], float64(res.DiskBytes)/(1<<20))
+	}
+
+	status := "passed"
+	if runErr != nil {
+		status = "failed: " + runErr.Error()
+	}
+
+	f, err := os.Create(path.Join(dir, "report.html"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return reportTemplate.Execute(f, map[string]interface{}{
+		"Versions": strings.Join(versions, " → "),
+		"Status":   status,
+		"Failed":   runErr != nil,
+		"RunID":    runID,
+		"Started":  r.started.Format(time.RFC3339),
+		"Took":     time.Since(r.started).Round(time.Second),
+		"Phases":   timeline(phaseBars, "s"),
+		"Latency":  barChart(latencyBars, "ms"),
+		"Startup":  barChart(startupBars, "s"),
+		"Schema":   schemaBars != nil,
+		"Load":     barChart(schemaBars, "ms"),
+		"Tenants":  activationBars != nil,
+		"Activate": barChart(activationBars, "ms"),
+		"Memory":   lineChart(memory, "MiB"),
+		"Disk":     lineChart(disk, "MiB"),
+		"Faults":   timeline(faultBars, "s"),
+		"Events":   r.faults,
+		"Drift":    r.convergence != nil,
+		"Converge": lineChart(r.convergence, ""),
+		"Flaky":    r.flakySteps(),
+	})
+}
+
+func hopTimingBars(timings []hopTiming) []bar {
+	var bars []bar
+	for _, t := range timings {
+		bars = append(bars, bar{
+			label: fmt.Sprintf("%d %s %s", t.Hop, versions[t.Hop], t.Name),
+			value: float64(t.Took.Milliseconds()),
+		})
+	}
+	return bars
+}
+
+type bar struct {
+	label  string
+	start  float64
+	value  float64
+	failed bool
+}
+
+const (
+	chartWidth  = 900
+	labelWidth  = 260
+	barHeight   = 18
+	chartHeight = 240
+)
+
+var seriesColors = []string{"#1f77b4", "#ff7f0e", "#2ca02c", "#9467bd", "#8c564b"}
+
+func barColor(b bar) string {
+	if b.failed {
+		return "#d62728"
+	}
+	return seriesColors[0]
+}
+
+// timeline draws every bar at its offset from the start of the run
+func timeline(bars []bar, unit string) template.HTML {
+	if len(bars) == 0 {
+		return "<p>none</p>"
+	}
+
+	max := 0.0
+	for _, b := range bars {
+		if b.start+b.value > max {
+			max = b.start + b.value
+		}
+	}
+
+	return drawBars(bars, max, unit, true)
+}
+
+// barChart draws every bar from zero
+func barChart(bars []bar, unit string) template.HTML {
+	if len(bars) == 0 {
+		return "<p>none</p>"
+	}
+
+	max := 0.0
+	for _, b := range bars {
+		if b.value > max {
+			max = b.value
+		}
+	}
+
+	return drawBars(bars, max, unit, false)
+}
+
+func drawBars(bars []bar, max float64, unit string, offset bool) template.HTML {
+	if max == 0 {
+		max = 1
+	}
+	scale := float64(chartWidth-labelWidth-80) / max
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg width="%d" height="%d" xmlns="http://www.w3.org/2000/svg">`,
+		chartWidth, len(bars)*(barHeight+4)+4)
+	for i, b := range bars {
+		y := i*(barHeight+4) + 2
+		x := float64(labelWidth)
+		if offset {
+			x += b.start * scale
+		}
+		fmt.Fprintf(&sb, `<text x="0" y="%d" font-size="12">%s</text>`, y+13, template.HTMLEscapeString(b.label))
+		fmt.Fprintf(&sb, `<rect x="%.1f" y="%d" width="%.1f" height="%d" fill="%s"/>`,
+			x, y, b.value*scale+1, barHeight, barColor(b))
+		fmt.Fprintf(&sb, `<text x="%.1f" y="%d" font-size="11">%.1f%s</text>`,
+			x+b.value*scale+4, y+13, b.value, unit)
+	}
+	sb.WriteString(`</svg>`)
+
+	return template.HTML(sb.String())
+}
+
+// lineChart draws one line per series with one point per hop
+func lineChart(series map[string][]float64, unit string) template.HTML {
+	if len(series) == 0 {
+		return "<p>none</p>"
+	}
+
+	names := make([]string, 0, len(series))
+	max, points := 0.0, 0
+	for name, values := range series {
+		names = append(names, name)
+		for _, v := range values {
+			if v > max {
+				max = v
+			}
+		}
+		if len(values) > points {
+			points = len(values)
+		}
+	}
+	sort.Strings(names)
+	if max == 0 {
+		max = 1
+	}
+
+	plotWidth := float64(chartWidth - 120)
+	step := plotWidth
+	if points > 1 {
+		step = plotWidth / float64(points-1)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg width="%d" height="%d" xmlns="http://www.w3.org/2000/svg">`,
+		chartWidth, chartHeight+40)
+	fmt.Fprintf(&sb, `<text x="0" y="12" font-size="11">%.0f%s</text>`, max, unit)
+	fmt.Fprintf(&sb, `<line x1="60" y1="%d" x2="%.0f" y2="%d" stroke="#999"/>`,
+		chartHeight+10, 60+plotWidth, chartHeight+10)
+	for i, name := range names {
+		color := seriesColors[i%len(seriesColors)]
+		coords := make([]string, len(series[name]))
+		for hop, v := range series[name] {
+			coords[hop] = fmt.Sprintf("%.1f,%.1f", 60+float64(hop)*step,
+				float64(chartHeight+10)-v/max*float64(chartHeight))
+		}
+		fmt.Fprintf(&sb, `<polyline fill="none" stroke="%s" stroke-width="2" points="%s"/>`,
+			color, strings.Join(coords, " "))
+		fmt.Fprintf(&sb, `<text x="%d" y="%d" font-size="12" fill="%s">%s</text>`,
+			60+i*120, chartHeight+32, color, template.HTMLEscapeString(name))
+	}
+	sb.WriteString(`</svg>`)
+
+	return template.HTML(sb.String())
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Upgrade journey report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+.failed { color: #d62728; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+</style>
+</head>
+<body>
+<h1>Upgrade journey</h1>
+<p>{{.Versions}}</p>
+<p>run {{.RunID}}, started {{.Started}}, took {{.Took}}, <span{{if .Failed}} class="failed"{{end}}>{{.Status}}</span></p>
+<h2>Phases</h2>
+{{.Phases}}
+<h2>Verification latency per hop</h2>
+{{.Latency}}
+<h2>Startup time per node</h2>
+{{.Startup}}
+{{if .Schema}}
+<h2>Schema load time per node after every hop</h2>
+{{.Load}}
+{{end}}
+{{if .Tenants}}
+<h2>Tenant activation latency after every hop</h2>
+{{.Activate}}
+{{end}}
+<h2>Memory per node after every hop</h2>
+{{.Memory}}
+<h2>Disk usage per node after every hop</h2>
+{{.Disk}}
+<h2>Fault timeline</h2>
+{{.Faults}}
+{{if .Events}}
+<table>
+<tr><th>hop</th><th>fault</th><th>took</th><th>error</th></tr>
+{{range .Events}}<tr><td>{{.Hop}}</td><td>{{.Description}}</td><td>{{.Took}}</td><td>{{.Err}}</td></tr>
+{{end}}
+</table>
+{{end}}
+{{if .Drift}}
+<h2>Ledger count drift after every fault, sampled every second</h2>
+{{.Converge}}
+{{end}}
+<h2>Flaky steps</h2>
+{{if .Flaky}}
+<table>
+<tr><th>hop</th><th>step</th><th>attempts</th><th>first error</th></tr>
+{{range .Flaky}}<tr><td>{{.Hop}}</td><td>{{.Step}}</td><td>{{.Attempts}}</td><td>{{.Err}}</td></tr>
+{{end}}
+</table>
+{{else}}
+<p>none</p>
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_reportWriteHTML(t *testing.T) {
+	versions = []string{"1.24.0", "1.25.0"}
+	defer func() { versions = nil }()
+
+	r := &report{started: time.Now()}
+	for hop := range versions {
+		r.recordPhase(hop, phaseUpgrade, time.Now(), nil)
+		r.recordLatency(hop, "go", 120*time.Millisecond)
+		r.recordResources(hop, "weaviate-0", 200<<20, 10<<20)
+	}
+	r.recordFault(1, "wipe raft of weaviate-2", time.Now(), errors.New("<boom>"))
+
+	dir := t.TempDir()
+	if err := r.writeHTML(dir, errors.New("hop 1 failed")); err != nil {
+		t.Fatal(err)
+	}
+
+	html, err := os.ReadFile(path.Join(dir, "report.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"<svg", "<polyline", "failed: hop 1 failed", "&lt;boom&gt;", "1.24.0 → 1.25.0"} {
+		if !strings.Contains(string(html), want) {
+			t.Errorf("report does not contain %q", want)
+		}
+	}
+}
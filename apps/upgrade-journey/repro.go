@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// A failed hop leaves a repro directory in the artifacts, so that the hop
+// can be reproduced without the journey that led to it:
+//
+//	repro.sh            runs the harness as a two-hop journey into the
+//	                    failed hop, with the seed and flags of the run
+//	docker-compose.yml  the nodes with the environment of the run, on the
+//	                    version before the hop, to poke at by hand
+//	scenario.yaml       the alerts, hooks and the states and debug windows
+//	                    of the two versions of the scenario file, if there
+//	                    was one
+//	ledger.json         the history of every ledger entry written on the
+//	                    two hops
+var (
+	// runSeed is set by --seed, or picked at random
+	runSeed int64
+
+	// scenarioSource is the scenario file of the run, if there was one
+	scenarioSource *scenarioConfig
+)
+
+// reproDroppedFlags are the flags of the failed run that repro.sh does not
+// pass on, in addition to bisectDroppedFlags, as it sets them itself
+var reproDroppedFlags = map[string]bool{
+	"seed":          true,
+	"scenario-file": true,
+}
+
+// writeRepro writes the repro directory of a failed hop. It is best effort:
+// what cannot be written is logged, but never fails the run.
+func writeRepro(c *cluster, hop int, runErr error) {
+	dir := path.Join(artifactsDir, "repro")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		logger.Warn("cannot write repro", "err", err)
+		return
+	}
+
+	from, to := reproVersions(hop)
+	files := map[string]func() ([]byte, error){
+		"repro.sh": func() ([]byte, error) {
+			return []byte(reproScript(c.RootDir, os.Args[1:], from, to, hop, runErr)), nil
+		},
+		"docker-compose.yml": func() ([]byte, error) {
+			return reproCompose(c, from)
+		},
+		"ledger.json": func() ([]byte, error) {
+			return json.MarshalIndent(writeLedger.HistoryOfHops(hop-1, hop), "", "  ")
+		},
+	}
+	if scenarioSource != nil {
+		files["scenario.yaml"] = func() ([]byte, error) {
+			return yaml.Marshal(scenarioSlice(*scenarioSource, from, to))
+		}
+	}
+
+	for _, name := range sortedMapKeys(files) {
+		content, err := files[name]()
+		if err == nil {
+			mode := os.FileMode(0o644)
+			if strings.HasSuffix(name, ".sh") {
+				mode = 0o755
+			}
+			err = os.WriteFile(path.Join(dir, name), content, mode)
+		}
+		if err != nil {
+			logger.Warn("cannot write repro", "file", name, "err", err)
+		}
+	}
+	logger.Info("wrote repro of the failed hop", "dir", dir, "from", from, "to", to, "seed", runSeed)
+}
+
+// reproVersions are the versions of the two-hop journey into a hop. A
+// failure on the first version has no version before it.
+func reproVersions(hop int) (string, string) {
+	if hop == 0 {
+		return versions[0], versions[0]
+	}
+	return versions[hop-1], versions[hop]
+}
+
+// reproScript is a script that reruns the failed hop with the seed of the
+// run and the flags that are not about the versions
+func reproScript(rootDir string, args []string, from, to string, hop int, runErr error) string {
+	journey := from
+	if to != from {
+		journey += "," + to
+	}
+
+	args = stripFlags(stripFlags(args, bisectDroppedFlags), reproDroppedFlags)
+	args = append(args, "--versions="+journey, fmt.Sprintf("--seed=%d", runSeed))
+	if scenarioSource != nil {
+		args = append(args, `--scenario-file="$repro/scenario.yaml"`)
+	}
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = arg
+		if !strings.HasPrefix(arg, "--scenario-file=") {
+			quoted[i] = shellQuote(arg)
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "#!/bin/bash\n")
+	fmt.Fprintf(&b, "# Reproduces hop %d of run %s, from %s to %s, which failed with:\n", hop, runID, from, to)
+	for _, line := range strings.Split(errString(runErr), "\n") {
+		fmt.Fprintf(&b, "#   %s\n", line)
+	}
+	fmt.Fprintf(&b, "#\n# docker-compose.yml next to this script starts the nodes on %s instead.\n\n", from)
+	fmt.Fprintf(&b, "set -eou pipefail\n\n")
+	fmt.Fprintf(&b, "repro=\"$(cd \"$(dirname \"$0\")\" && pwd)\"\n")
+	fmt.Fprintf(&b, "cd \"${UPGRADE_JOURNEY_DIR:-%s}\"\n", rootDir)
+	fmt.Fprintf(&b, "rm -rf data\n")
+	fmt.Fprintf(&b, "ARTIFACTS_DIR=\"$repro/artifacts\" go run . %s\n", strings.Join(quoted, " "))
+	return b.String()
+}
+
+// reproCompose is a compose file of the nodes on a version, with the
+// environment the run started them with
+func reproCompose(c *cluster, version string) ([]byte, error) {
+	services := map[string]interface{}{}
+	for i := 0; i < c.NodeCount; i++ {
+		services[c.Hostname(i)] = map[string]interface{}{
+			"image":       fmt.Sprintf("${WEAVIATE_IMAGE:-%s}", images.image(version)),
+			"hostname":    c.Hostname(i),
+			"ports":       []string{fmt.Sprintf("%d:8080", 8080+i)},
+			"environment": c.NodeEnv(i, version),
+			"volumes":     []string{fmt.Sprintf("./data/%s:/var/lib/weaviate", c.Hostname(i))},
+		}
+	}
+	return yaml.Marshal(map[string]interface{}{"services": services})
+}
+
+// scenarioSlice is the part of a scenario file that applies to a two-hop
+// journey: all of the alerts and hooks, but only the states of its versions
+// and the debug windows that overlap them, cut to the two versions
+func scenarioSlice(config scenarioConfig, from, to string) scenarioConfig {
+	slice := scenarioConfig{Alerts: config.Alerts, Hooks: config.Hooks}
+	for _, state := range config.States {
+		if state.Version == from || state.Version == to {
+			slice.States = append(slice.States, state)
+		}
+	}
+	for _, w := range config.Debug {
+		if (w.Until != "" && versionLess(w.Until, from)) || (w.From != "" && versionLess(to, w.From)) {
+			continue
+		}
+		if w.From == "" || versionLess(w.From, from) {
+			w.From = from
+		}
+		if w.Until == "" || versionLess(to, w.Until) {
+			w.Until = to
+		}
+		slice.Debug = append(slice.Debug, w)
+	}
+	return slice
+}
+
+func shellQuote(arg string) string {
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func Test_reproScript(t *testing.T) {
+	runSeed = 42
+	script := reproScript("/src/upgrade-journey", []string{
+		"--latest-releases=5", "--seed", "7", "--faults", "kill:node=1; stop:node=2", "--nodes=3",
+	}, "1.24.0", "1.24.1", 2, errors.New("hop failed"))
+
+	for _, want := range []string{
+		"# Reproduces hop 2",
+		"#   hop failed",
+		`cd "${UPGRADE_JOURNEY_DIR:-/src/upgrade-journey}"`,
+		`go run . '--faults' 'kill:node=1; stop:node=2' '--nodes=3' '--versions=1.24.0,1.24.1' '--seed=42'` + "\n",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("wanted %q in\n%s", want, script)
+		}
+	}
+}
+
+func Test_scenarioSlice(t *testing.T) {
+	config := scenarioConfig{
+		Alerts: alertRules{MaxHeapMB: 512},
+		States: []desiredState{{Version: "1.23.0"}, {Version: "1.24.0"}, {Version: "1.24.1"}},
+	}
+	got := scenarioSlice(config, "1.24.0", "1.24.1")
+	if got.Alerts.MaxHeapMB != 512 || len(got.States) != 2 || got.States[0].Version != "1.24.0" {
+		t.Errorf("wanted the alerts and the states of 1.24.0 and 1.24.1, got %+v", got)
+	}
+}
+
+func Test_scenarioSlice_debug(t *testing.T) {
+	config := scenarioConfig{Debug: []debugWindow{
+		{From: "1.22.0", Until: "1.23.0", LogLevel: "debug"},
+		{From: "1.23.0", LogLevel: "trace"},
+		{Until: "1.24.0", Pprof: true},
+	}}
+	got := scenarioSlice(config, "1.24.0", "1.24.1")
+	if len(got.Debug) != 2 {
+		t.Fatalf("wanted the two windows that overlap the slice, got %+v", got.Debug)
+	}
+	for i, want := range [][2]string{{"1.24.0", "1.24.1"}, {"1.24.0", "1.24.0"}} {
+		if got.Debug[i].From != want[0] || got.Debug[i].Until != want[1] {
+			t.Errorf("wanted window %d cut to %s..%s, got %s", i, want[0], want[1], got.Debug[i].name())
+		}
+	}
+}
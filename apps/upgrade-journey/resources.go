@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/fs"
+	"path/filepath"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	wcluster "github.com/weaviate/weaviate-chaos-engineering/apps/upgrade-journey/pkg/cluster"
+)
+
+// sampleResources records the memory usage and the size of the data
+// directory of every node. It is best effort: a failed sample is logged, but
+// never fails the run.
+func (c *cluster) sampleResources(ctx context.Context, posOfVersion int) {
+	err := wcluster.WithDockerClient(func(cli *client.Client) error {
+		c.sampleNodes(ctx, cli, posOfVersion)
+		return nil
+	})
+	if err != nil {
+		logger.Warn("cannot sample resources", "err", err)
+	}
+}
+
+func (c *cluster) sampleNodes(ctx context.Context, cli *client.Client, posOfVersion int) {
+	for i, container := range c.Containers {
+		if container == nil {
+			continue
+		}
+
+		var memory uint64
+		stats, err := cli.ContainerStatsOneShot(ctx, container.GetContainerID())
+		if err == nil {
+			var parsed types.StatsJSON
+			err = json.NewDecoder(stats.Body).Decode(&parsed)
+			stats.Body.Close()
+			memory = parsed.MemoryStats.Usage
+		}
+		if err != nil {
+			nodeLogger(c, i).Warn("cannot read memory usage", "err", err)
+		}
+
+		disk, err := dirSize(c.VolumePath(i))
+		if err != nil {
+			nodeLogger(c, i).Warn("cannot read disk usage", "err", err)
+		}
+
+		runReport.recordResources(posOfVersion, c.Hostname(i), memory, disk)
+	}
+}
+
+func dirSize(dir string) (uint64, error) {
+	var size uint64
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += uint64(info.Size())
+		}
+
+		return nil
+	})
+
+	return size, err
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The restore scenario restores a large class from a backup of the first
+// version on every hop, while the other classes serve queries and one node
+// restarts in the middle of the restore.
+var (
+	// withRestoreUnderLoad is set by --restore-under-load
+	withRestoreUnderLoad bool
+
+	// restoreObjects is set by --restore-objects
+	restoreObjects int
+
+	// maxRestoreQueryLatency is set by --max-restore-query-latency
+	maxRestoreQueryLatency time.Duration
+)
+
+const (
+	restoreClass    = "RestoreSource"
+	restoreBackupID = "restore-source"
+	restoreBatch    = 1000
+	restoreDims     = 32
+)
+
+// createRestoreSource imports the class that is restored on every hop and
+// takes its backup on the first version
+func createRestoreSource(ctx context.Context) error {
+	if _, err := postRaw(ctx, 0, "/v1/schema", map[string]interface{}{
+		"class":      restoreClass,
+		"vectorizer": "none",
+		"properties": []map[string]interface{}{
+			{"name": "index", "dataType": []string{"int"}},
+		},
+	}); err != nil {
+		return fmt.Errorf("create class %s: %w", restoreClass, err)
+	}
+
+	for from := 0; from < restoreObjects; from += restoreBatch {
+		var objects []map[string]interface{}
+		for i := from; i < from+restoreBatch && i < restoreObjects; i++ {
+			vector := make([]float32, restoreDims)
+			for d := range vector {
+				vector[d] = rand.Float32()
+			}
+			objects = append(objects, map[string]interface{}{
+				"class":      restoreClass,
+				"id":         uuid.New().String(),
+				"vector":     vector,
+				"properties": map[string]interface{}{"index": i},
+			})
+		}
+
+		if _, err := postRaw(ctx, 0, "/v1/batch/objects", map[string]interface{}{"objects": objects}); err != nil {
+			return fmt.Errorf("import %s: %w", restoreClass, err)
+		}
+	}
+
+	// the timestamps of a sample are recorded before the backup and
+	// compared after every restore
+	raw, err := getRaw(ctx, 0, fmt.Sprintf("/v1/objects?class=%s&limit=%d", restoreClass, restoreTimestampSample))
+	if err != nil {
+		return err
+	}
+	sample, err := parseObjectTimestamps(raw)
+	if err != nil {
+		return err
+	}
+	preservedTimestamps.compare(restoreClass, sample)
+
+	if err := startBackup(ctx, restoreBackupID, []string{restoreClass}); err != nil {
+		return err
+	}
+	status, err := awaitBackup(ctx, restoreBackupID)
+	if err != nil {
+		return err
+	}
+	if status.Status != "SUCCESS" {
+		return fmt.Errorf("backup %s: %s: %s", restoreBackupID, status.Status, status.Error)
+	}
+	return nil
+}
+
+// restoreUnderLoad drops the class and restores it from its backup while
+// queries run against the journey classes. The last node restarts as soon
+// as the restore started. The restore must succeed, or fail with a reason
+// and succeed when it is retried without a restart. The restored objects
+// keep the timestamps they had when the backup was taken.
+func (c *cluster) restoreUnderLoad(ctx context.Context, posOfVersion int) error {
+	if err := dropRestoreClass(ctx); err != nil {
+		return err
+	}
+
+	load := startQueryLoad(ctx)
+	if err := startRestore(ctx); err != nil {
+		load.stop()
+		return err
+	}
+
+	if c.NodeCount > 1 {
+		restarted := c.NodeCount - 1
+		load.restarting.Store(true)
+		err := c.RestartNode(ctx, restarted, c.NodeVersions[restarted])
+		load.restarting.Store(false)
+		if err != nil {
+			load.stop()
+			return fmt.Errorf("restart %s during the restore: %w", c.Hostname(restarted), err)
+		}
+	}
+
+	status, err := awaitRestore(ctx, restoreBackupID)
+	if err != nil {
+		load.stop()
+		return err
+	}
+	latencies, failed := load.stop()
+
+	switch {
+	case status.Status == "SUCCESS":
+	case status.clean():
+		hopLogger(posOfVersion).Info("restore failed after a restart, retrying", "reason", status.Error)
+		if err := retryRestore(ctx); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("restore of %s failed without a reason", restoreBackupID)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d queries on the journey classes failed while no node was restarting", failed)
+	}
+	p99 := percentile(latencies, 0.99)
+	runReport.recordLatency(posOfVersion, "queries during restore (p99)", p99)
+	hopLogger(posOfVersion).Info("restored under load", "queries", len(latencies),
+		"p99", p99.Round(time.Millisecond))
+	if maxRestoreQueryLatency > 0 && p99 > maxRestoreQueryLatency {
+		return fmt.Errorf("p99 query latency during the restore was %s, above %s", p99.Round(time.Millisecond),
+			maxRestoreQueryLatency)
+	}
+
+	if err := checkRestoredCount(ctx); err != nil {
+		return err
+	}
+	return preservedTimestamps.checkIds(ctx, 0, restoreClass,
+		preservedTimestamps.ids(restoreClass, restoreTimestampSample), "")
+}
+
+func retryRestore(ctx context.Context) error {
+	if err := dropRestoreClass(ctx); err != nil {
+		return err
+	}
+	if err := startRestore(ctx); err != nil {
+		return err
+	}
+	status, err := awaitRestore(ctx, restoreBackupID)
+	if err != nil {
+		return err
+	}
+	if status.Status != "SUCCESS" {
+		return fmt.Errorf("retried restore of %s: %s: %s", restoreBackupID, status.Status, status.Error)
+	}
+	return nil
+}
+
+func startRestore(ctx context.Context) error {
+	if _, err := postRaw(ctx, 0, fmt.Sprintf("/v1/backups/%s/%s/restore", backupBackend, restoreBackupID),
+		map[string]interface{}{"include": []string{restoreClass}}); err != nil {
+		return fmt.Errorf("start restore of %s: %w", restoreBackupID, err)
+	}
+	return nil
+}
+
+// dropRestoreClass deletes the class if it exists, a restore fails for an
+// existing class
+func dropRestoreClass(ctx context.Context) error {
+	status, body, err := doRaw(ctx, http.MethodDelete, 0, "/v1/schema/"+restoreClass, nil)
+	if err != nil {
+		return err
+	}
+	if (status < 200 || status > 299) && status != http.StatusNotFound {
+		return fmt.Errorf("delete class %s: status %d: %s", restoreClass, status, body)
+	}
+	return nil
+}
+
+func checkRestoredCount(ctx context.Context) error {
+	body, err := postRaw(ctx, 0, "/v1/graphql", map[string]interface{}{
+		"query": fmt.Sprintf("{Aggregate{%s{meta{count}}}}", restoreClass),
+	})
+	if err != nil {
+		return err
+	}
+
+	var res struct {
+		Data struct {
+			Aggregate map[string][]struct {
+				Meta struct {
+					Count int `json:"count"`
+				} `json:"meta"`
+			} `json:"Aggregate"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return err
+	}
+
+	groups := res.Data.Aggregate[restoreClass]
+	if len(groups) != 1 || groups[0].Meta.Count != restoreObjects {
+		return fmt.Errorf("restored %s has %v objects, want %d", restoreClass, groups, restoreObjects)
+	}
+	return nil
+}
+
+// queryLoad lists objects of the journey classes until it is stopped.
+// Failures while a node restarts are expected and not counted.
+type queryLoad struct {
+	restarting atomic.Bool
+
+	mu        sync.Mutex
+	latencies []time.Duration
+	failed    int
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func startQueryLoad(ctx context.Context) *queryLoad {
+	ctx, cancel := context.WithCancel(ctx)
+	l := &queryLoad{cancel: cancel, done: make(chan struct{})}
+
+	go func() {
+		defer close(l.done)
+		classes := journeyClassNames()
+		for i := 0; ctx.Err() == nil; i++ {
+			endpoint := fmt.Sprintf("/v1/objects?class=%s&limit=10", classes[i%len(classes)])
+			start := time.Now()
+			_, err := getRaw(ctx, 0, endpoint)
+			took := time.Since(start)
+			if ctx.Err() != nil {
+				return
+			}
+
+			l.mu.Lock()
+			if err == nil {
+				l.latencies = append(l.latencies, took)
+			} else if !l.restarting.Load() {
+				l.failed++
+				logger.Debug("query failed during the restore", "endpoint", endpoint, "err", err)
+			}
+			l.mu.Unlock()
+		}
+	}()
+
+	return l
+}
+
+func (l *queryLoad) stop() ([]time.Duration, int) {
+	l.cancel()
+	<-l.done
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.latencies, l.failed
+}
+
+// percentile returns the nearest-rank percentile p of latencies
+func percentile(latencies []time.Duration, p float64) time.Duration {
+	if len(latencies) == 0 {
+		return 0
+	}
+
+	sorted := append([]time.Duration{}, latencies...)
+	sort.Slice(sorted, func(a, b int) bool { return sorted[a] < sorted[b] })
+	rank := int(math.Ceil(p * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
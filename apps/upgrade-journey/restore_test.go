@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_percentile(t *testing.T) {
+	var latencies []time.Duration
+	for i := 100; i >= 1; i-- {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+
+	tests := []struct {
+		p    float64
+		want time.Duration
+	}{
+		{p: 0.5, want: 50 * time.Millisecond},
+		{p: 0.99, want: 99 * time.Millisecond},
+		{p: 1, want: 100 * time.Millisecond},
+		{p: 0, want: time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := percentile(latencies, tt.p); got != tt.want {
+			t.Errorf("percentile(%g) = %s, want %s", tt.p, got, tt.want)
+		}
+	}
+
+	if got := percentile(nil, 0.99); got != 0 {
+		t.Errorf("percentile of no latencies = %s, want 0", got)
+	}
+}
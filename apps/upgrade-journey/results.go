@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+)
+
+// runSummary is what is kept of a run in the results store, so that runs of
+// the same scenario can be compared over time
+type runSummary struct {
+	Scenario        string             `json:"scenario"`
+	RunID           string             `json:"run_id"`
+	Started         time.Time          `json:"started"`
+	Versions        []string           `json:"versions"`
+	Passed          bool               `json:"passed"`
+	Error           string             `json:"error,omitempty"`
+	DurationSeconds float64            `json:"duration_seconds"`
+	PhaseSeconds    map[string]float64 `json:"phase_seconds"`
+	VerifyLatencyMs float64            `json:"verify_latency_ms"`
+	FailedPhases    int                `json:"failed_phases"`
+	FailedFaults    int                `json:"failed_faults"`
+	FlakySteps      int                `json:"flaky_steps"`
+	MaxMemoryMiB    float64            `json:"max_memory_mib"`
+	MaxDiskMiB      float64            `json:"max_disk_mib"`
+
+	// Recall is the lowest recall of every check that measured one, see
+	// recordRecall
+	Recall map[string]float64 `json:"recall,omitempty"`
+}
+
+func (r *report) summary(scenario string, runErr error) runSummary {
+	r.Lock()
+	defer r.Unlock()
+
+	s := runSummary{
+		Scenario:        scenario,
+		Started:         r.started,
+		Versions:        versions,
+		Passed:          runErr == nil,
+		Error:           errString(runErr),
+		DurationSeconds: time.Since(r.started).Seconds(),
+		PhaseSeconds:    map[string]float64{},
+	}
+
+	for _, p := range r.phases {
+		s.PhaseSeconds[string(p.Phase)] += p.Took.Seconds()
+		if p.Err != "" {
+			s.FailedPhases++
+		}
+	}
+
+	for _, l := range r.latencies {
+		s.VerifyLatencyMs += float64(l.Took.Milliseconds()) / float64(len(r.latencies))
+	}
+
+	s.FlakySteps = len(r.flakySteps())
+
+	for _, f := range r.faults {
+		if f.Err != "" {
+			s.FailedFaults++
+		}
+	}
+
+	for _, res := range r.resources {
+		if mem := float64(res.MemoryBytes) / (1 << 20); mem > s.MaxMemoryMiB {
+			s.MaxMemoryMiB = mem
+		}
+		if disk := float64(res.DiskBytes) / (1 << 20); disk > s.MaxDiskMiB {
+			s.MaxDiskMiB = disk
+		}
+	}
+
+	for _, sample := range r.recalls {
+		if s.Recall == nil {
+			s.Recall = map[string]float64{}
+		}
+		if lowest, ok := s.Recall[sample.Check]; !ok || sample.Recall < lowest {
+			s.Recall[sample.Check] = sample.Recall
+		}
+	}
+
+	return s
+}
+
+// resultsStore keeps the summaries of all runs as a single JSON array. It is
+// either a local file or an object on S3, which is accessed through the aws
+// CLI so that the harness does not need to carry an SDK.
+type resultsStore struct {
+	location string
+}
+
+func (s resultsStore) isS3() bool {
+	return strings.HasPrefix(s.location, "s3://")
+}
+
+func (s resultsStore) load(ctx context.Context) ([]runSummary, error) {
+	var raw []byte
+	var err error
+	if s.isS3() {
+		raw, err = exec.CommandContext(ctx, "aws", "s3", "cp", s.location, "-").Output()
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && strings.Contains(string(exitErr.Stderr), "Not Found") {
+			return nil, nil
+		}
+	} else {
+		raw, err = os.ReadFile(s.location)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load results from %s: %w", s.location, err)
+	}
+
+	var runs []runSummary
+	if err := json.Unmarshal(raw, &runs); err != nil {
+		return nil, fmt.Errorf("parse results from %s: %w", s.location, err)
+	}
+
+	return runs, nil
+}
+
+func (s resultsStore) append(ctx context.Context, run runSummary) error {
+	runs, err := s.load(ctx)
+	if err != nil {
+		return err
+	}
+
+	raw, err := json.MarshalIndent(append(runs, run), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if s.isS3() {
+		cmd := exec.CommandContext(ctx, "aws", "s3", "cp", "-", s.location)
+		cmd.Stdin = strings.NewReader(string(raw))
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("store results to %s: %w: %s", s.location, err, out)
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(path.Dir(s.location), 0o777); err != nil {
+		return err
+	}
+
+	return os.WriteFile(s.location, raw, 0o666)
+}
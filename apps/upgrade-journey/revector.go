@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+
+	"github.com/weaviate/weaviate-chaos-engineering/apps/upgrade-journey/pkg/workloads"
+)
+
+// revectorObjects is set by --revector-objects
+var revectorObjects int
+
+// revectoring is nil unless --revector-objects is set
+var revectoring *workloads.Revector
+
+// every object gets this many new vectors per hop and per fault
+const revectorRounds = 3
+
+// updateVectors replaces the vectors of the revectored objects, failed
+// updates are logged, they are expected while a fault is active
+func updateVectors(ctx context.Context, posOfVersion int) error {
+	if revectoring == nil {
+		return nil
+	}
+
+	updated, failed, err := revectoring.Update(ctx, nodeHost(0), revectorRounds)
+	if err != nil {
+		return err
+	}
+	hopLogger(posOfVersion).Debug("updated vectors", "updated", updated, "failed", failed)
+	return nil
+}
+
+// revectorVerifier checks that every revectored object is found by its
+// latest vector and no longer by the one it had before
+type revectorVerifier struct{}
+
+func (v *revectorVerifier) name() string {
+	return "revector"
+}
+
+func (v *revectorVerifier) verify(ctx context.Context, posOfMaxVersion int) error {
+	return revectoring.Verify(ctx, nodeHost(0))
+}
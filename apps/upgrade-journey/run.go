@@ -2,84 +2,1014 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
-	"log"
 	"math/rand"
 	"os"
+	"path"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	wcluster "github.com/weaviate/weaviate-chaos-engineering/apps/upgrade-journey/pkg/cluster"
+	"github.com/weaviate/weaviate-chaos-engineering/apps/upgrade-journey/pkg/remote"
+	"github.com/weaviate/weaviate-chaos-engineering/apps/upgrade-journey/pkg/workloads"
 	"github.com/weaviate/weaviate-go-client/v4/weaviate"
 	"github.com/weaviate/weaviate-go-client/v4/weaviate/filters"
 	"github.com/weaviate/weaviate-go-client/v4/weaviate/graphql"
 	"github.com/weaviate/weaviate/entities/models"
 )
 
+const (
+	// ledgerWritesPerHop is the number of ledger writes on every hop, as
+	// well as while every fault is active
+	ledgerWritesPerHop = 20
+
+	// ledgerMutationsPerHop is the number of ledger entries that are updated
+	// or deleted right after the writes
+	ledgerMutationsPerHop = 5
+)
+
 var (
+	// nodeCount is set by --nodes
+	nodeCount = 3
+
+	// runID identifies the run in its Docker objects, logs and results,
+	// scenarioName is set by --scenario
+	runID        = wcluster.NewRunID()
+	scenarioName = "default"
+
 	versions       []string
 	objectsCreated = 0
+	artifactsDir   string
 )
 
 func main() {
-	ctx := context.Background()
-	targetW, ok := os.LookupEnv("WEAVIATE_VERSION")
-	if !ok {
-		log.Fatal("missing WEAVIATE_VERSION")
+	if len(os.Args) > 1 && os.Args[1] == "compare" {
+		runCompare(os.Args[2:])
+		return
 	}
-
-	minimumW, ok := os.LookupEnv("MINIMUM_WEAVIATE_VERSION")
-	if !ok {
-		log.Fatal("missing MINIMUM_WEAVIATE_VERSION")
+	if len(os.Args) > 1 && os.Args[1] == "cleanup" {
+		runCleanup(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "counts" {
+		runCounts(os.Args[2:])
+		return
 	}
 
+	clientMatrix := flag.String("client-matrix", "go",
+		"comma-separated clients that verify every hop: go, go:<version>, python:<version>")
+	flag.StringVar(&goClientImage, "go-client-image", "golang:1.22",
+		"image the go:<version> clients of --client-matrix are built in; clients that require a newer "+
+			"go download it")
+	transport := flag.String("transport", "client",
+		"how the in-process verification talks to Weaviate: client (Go client) or raw (plain HTTP)")
+	maxDuration := flag.Duration("max-duration", 0,
+		"deadline for the entire run, split across the hops by the work the plan estimates for them "+
+			"(0 disables it)")
+	logFormat := flag.String("log-format", "text", "log format: text or json")
+	showProgress := flag.Bool("progress", false,
+		"display the current hop, objects imported and elapsed time on stderr")
+	planOnly := flag.Bool("plan", false,
+		"print the resolved journey without starting any containers")
+	flag.StringVar(&images.repo, "image-repo", images.repo,
+		"image repository, e.g. to use a mirror or a private registry")
+	flag.StringVar(&images.tagTemplate, "image-tag-template", images.tagTemplate,
+		"template for the image tag of a version, {version} is replaced with the version and {arch} "+
+			"with the architecture of --image-platform")
+	flag.StringVar(&images.platform, "image-platform", "",
+		"platform of the images, e.g. linux/amd64 to run emulated on ARM; defaults to the platform of the Docker host")
+	flag.StringVar(&images.finalImage, "final-image", "",
+		"complete image reference for the last hop, e.g. a local or PR build such as weaviate:pr-1234")
+	latestReleases := flag.Int("latest-releases", 0,
+		"journey through the last N releases instead of MINIMUM_WEAVIATE_VERSION to WEAVIATE_VERSION")
+	channels := flag.String("channels", "",
+		"comma-separated image tags that follow the releases with --latest-releases, e.g. latest,nightly")
+	flag.StringVar(&pinnedVersions, "versions", "",
+		"comma-separated versions of the journey, instead of MINIMUM_WEAVIATE_VERSION to WEAVIATE_VERSION")
+	flag.BoolVar(&withBisect, "bisect", false,
+		"after a failed hop, bisect the releases between the previous version and the one of the hop with "+
+			"two-hop journeys on fresh clusters to find the first release that fails")
+	pathPolicy := flag.String("path-policy", pathPolicyAll,
+		"which releases to visit: all, or minor for one minor at a time with the latest patch of each")
+	checkForbidden := flag.Bool("check-forbidden-jump", false,
+		"before the journey, check that a jump across a minor is refused and leaves the data intact")
+	store := flag.String("results-store", "",
+		"local JSON file or s3://bucket/key the summary of the run is appended to")
+	flag.StringVar(&scenarioName, "scenario", scenarioName, "name under which the run is stored for comparisons")
+	flag.IntVar(&verifyRetries.retries, "verify-retries", 0,
+		"how often a failed verification is retried, a verification that passes on a retry is reported as flaky")
+	faultList := flag.String("faults", "",
+		"semicolon-separated faults injected and healed on every hop, e.g. kill:node=1;latency:node=2,delay=500ms")
+	verifyUnderFault := flag.Bool("verify-under-fault", false,
+		"also verify while every fault is active, e.g. to check query routing around a slow node")
+	faultsDuringUpgrade := flag.Bool("faults-during-upgrade", false,
+		"keep the faults active during the rolling update of every hop instead of injecting them afterwards; "+
+			"only faults that survive a restart, such as cpu, are meaningful")
+	faultHold := flag.Duration("fault-hold", 10*time.Second, "how long every fault is kept before it is healed")
+	flag.BoolVar(&useToxiproxy, "toxiproxy", false,
+		"route all traffic to the nodes through Toxiproxy, which enables the toxic fault")
+	flag.IntVar(&portOffset, "port-offset", 0,
+		"added to every port the nodes and Toxiproxy expose on the host, so that several journeys can run on "+
+			"the same host")
+	verifyRoute := flag.String("verify-routing", "node:0",
+		"node the in-process Go verification talks to: node:N, any (first healthy node) or round-robin")
+	importRoute := flag.String("import-routing", "node:0",
+		"node the versioned objects are imported through: node:N, any or round-robin")
+	flag.StringVar(&vectorizerModule, "vectorizer", "",
+		"vectorizer module of the nodes; contextionary enables the vector drift and text search checks")
+	flag.Float64Var(&vectorDriftTolerance, "vector-drift-tolerance", 1e-4,
+		"largest accepted cosine distance between the vectors of identical text")
+	loadObjects := flag.Int("load-objects", 0,
+		"objects bulk imported on every hop by parallel workers (0 disables the load)")
+	loadWorkers := flag.Int("load-workers", 0,
+		"batches of the load in flight, 0 uses GOMAXPROCS")
+	loadBatchSize := flag.Int("load-batch-size", 0,
+		"size of the first batch of the load, later batches are tuned to --load-target-latency (0 starts at 100)")
+	flag.IntVar(&loadDimensions, "load-dimensions", 32,
+		"dimensions of the vectors of the load, e.g. 1536 or 3072 to approximate OpenAI-sized embeddings")
+	flag.Float64Var(&loadMemoryFactor, "load-memory-factor", 0,
+		"factor of the raw size of the vectors imported since the first hop by which the memory of a node "+
+			"may grow, 0 disables the check")
+	loadTargetLatency := flag.Duration("load-target-latency", time.Second,
+		"batch latency the load tunes its batch size to, halving it after slower batches")
+	importErrorBudget := flag.String("import-error-budget", "",
+		"failed load batches tolerated per error class while a fault is active, e.g. timeout=20,unavailable=5; "+
+			"classes: refused, timeout, unavailable, unprocessable, object, other (default none). "+
+			"Errors outside of a fault always fail the run")
+	raftVoters := flag.Int("raft-voters", 0,
+		"number of raft voters, nodes 0 to N-1 vote and the others join as non-voters (0 makes every node a voter)")
+	zones := flag.String("zones", "",
+		"comma-separated zone of every node, e.g. a,a,b; lets faults target a zone with down:nodes=zone:a")
+	nodeEnv := flag.String("node-env", "",
+		"semicolon-separated environment overrides of single nodes, e.g. 2:LOG_LEVEL=debug;2:GOMEMLIMIT=1GiB")
+	flag.IntVar(&nodeCount, "nodes", nodeCount,
+		"number of nodes; from 5 nodes on, every node gets an even share of the machine and raft versions "+
+			"check the quorum and restart the leader last")
+	flag.Float64Var(&nodeCPUs, "node-cpus", 0,
+		"CPU limit of every node, 0 shares the CPUs evenly from 5 nodes on and is unlimited below")
+	flag.IntVar(&nodeMemoryMB, "node-memory-mb", 0,
+		"memory limit of every node in MiB, 0 shares the memory evenly from 5 nodes on and is unlimited below")
+	flag.DurationVar(&profileInterval, "profile-interval", 0,
+		"capture heap and goroutine profiles of every node at this interval and after every hop "+
+			"into artifacts/profiles, 0 disables profiling")
+	flag.StringVar(&preloadDir, "preload-images", "",
+		"directory of an archive of every image of the run, which is pulled and saved there if it does not "+
+			"exist, loaded before the first node starts and the only source of images for the rest of the run, "+
+			"--client-matrix may only hold go")
+	flag.BoolVar(&withEgressCheck, "check-egress", false,
+		"disable the telemetry of the nodes and fail every hop on which a container of the cluster "+
+			"opened a connection to a host outside of it, as in an air-gapped installation")
+	flag.BoolVar(&withMetrics, "metrics", false,
+		"scrape the Prometheus metrics of every node after every hop and fail on error counters that grow "+
+			"outside of a fault, on growing goroutines and on tombstones that are not cleaned up")
+	flag.DurationVar(&tombstoneCleanupTimeout, "tombstone-cleanup-timeout", 2*time.Minute,
+		"how long --metrics waits for the tombstones of deleted vectors to be cleaned up")
+	flag.IntVar(&journeyShards, "shards", 0,
+		"shards of the journey classes, spread over the nodes; 0 uses the server default of one shard per node")
+	flag.IntVar(&manyClasses, "many-classes", 0,
+		"create this many classes before the first hop and check after every hop that every node loads all "+
+			"of them in time and without its memory growing, 0 disables the large schema")
+	flag.IntVar(&manyClassesShards, "many-classes-shards", 1, "shards of every class of --many-classes")
+	flag.DurationVar(&maxSchemaLoadTime, "max-schema-load-time", 30*time.Second,
+		"how long loading the schema of --many-classes from a node may take, 0 disables the limit")
+	flag.Float64Var(&manyClassesMemoryGrowth, "many-classes-memory-growth", 2,
+		"factor by which the memory of a node may grow over its memory after the first hop with "+
+			"--many-classes, 0 disables the limit")
+	flag.IntVar(&manyTenants, "many-tenants", 0,
+		"create a class with this many tenants before the first hop and activate a few of the inactive ones "+
+			"after every hop, 0 disables the many tenants scenario")
+	flag.IntVar(&activeTenants, "active-tenants", 100, "tenants of --many-tenants that are active")
+	flag.DurationVar(&maxActivationLatency, "max-activation-latency", 10*time.Second,
+		"how long activating a tenant of --many-tenants may take until it accepts writes, 0 disables the limit")
+	flag.BoolVar(&withUpgradeBackup, "backup-during-upgrade", false,
+		"start a backup right before every rolling update and check that it neither blocks the update nor "+
+			"keeps its lock, it must succeed or fail with a reason")
+	flag.DurationVar(&backupTimeout, "backup-timeout", 10*time.Minute,
+		"how long the rolling update and the backup of --backup-during-upgrade may take each")
+	flag.BoolVar(&withRestoreUnderLoad, "restore-under-load", false,
+		"on every hop, restore a class from a backup of the first version while the journey classes serve "+
+			"queries and the last node restarts")
+	flag.IntVar(&restoreObjects, "restore-objects", 50000, "objects of the class --restore-under-load restores")
+	flag.DurationVar(&maxRestoreQueryLatency, "max-restore-query-latency", 2*time.Second,
+		"p99 latency of the queries during --restore-under-load, 0 disables the limit")
+	flag.BoolVar(&withReadRepair, "read-repair", false,
+		"on every hop with replication and at least 3 nodes, write to the replicated class while the last node "+
+			"is down and check that reads at ALL repair it")
+	flag.BoolVar(&withRaftWipe, "raft-wipe", false,
+		"on every hop with raft, delete the whole raft state of the last node, restart it and check that it "+
+			"re-bootstraps the schema from its peers")
+	flag.BoolVar(&withDeleteWhileDown, "delete-while-down", false,
+		"on every hop with replication and at least 3 nodes, delete objects while the last node is down and "+
+			"check that they never come back, neither after the node returned nor after later upgrades")
+	flag.BoolVar(&withConflictingUpdates, "conflicting-updates", false,
+		"on every hop with replication and at least 3 nodes, update the same objects on both sides of a split "+
+			"that isolates the last node and check that every replica converges on the later update")
+	flag.BoolVar(&withBatchLimits, "batch-limits", false,
+		"on every hop, send batches near and above the size limits of a node and check that none is half "+
+			"written and that every version accepts or rejects them like the first one")
+	flag.BoolVar(&withConnectionFlood, "connection-flood", false,
+		"on every hop, flood the last node with requests that give up after --flood-timeout and check that it "+
+			"recovers while the other nodes keep answering")
+	flag.IntVar(&floodRequests, "flood-requests", 5000, "requests of --connection-flood")
+	flag.IntVar(&floodConnections, "flood-connections", 1000,
+		"connections --connection-flood keeps open at a time, below --max-harness-fds")
+	flag.DurationVar(&floodTimeout, "flood-timeout", 10*time.Millisecond,
+		"deadline of every request of --connection-flood")
+	flag.BoolVar(&withIndexTuning, "tune-vector-index", false,
+		"change the mutable HNSW settings of a class after every hop and check that every node still "+
+			"reports them after the next upgrade")
+	flag.BoolVar(&withAsyncIndexingChaos, "async-indexing-chaos", false,
+		"turn on async indexing on the versions that have it, kill the node with the longest indexing queue "+
+			"after an import on every hop and check that the queue resumes and drains")
+	flag.IntVar(&asyncObjects, "async-indexing-objects", 50000,
+		"objects imported on every hop of --async-indexing-chaos to build up the indexing queue")
+	flag.DurationVar(&asyncDrainTimeout, "async-indexing-drain-timeout", 10*time.Minute,
+		"how long the indexing queues of --async-indexing-chaos may take to drain, after the kill and "+
+			"before every verification")
+	flag.BoolVar(&withGenerativeMock, "generative-mock", false,
+		"run the generative-openai module against a mock of the OpenAI API and check a search followed by a "+
+			"generation on every hop")
+	flag.BoolVar(&withVectorizerSwap, "vectorizer-swap", false,
+		"change the vectorizer settings of a class on every hop and check that they are validated and that "+
+			"the existing vectors stay searchable, needs --vectorizer=contextionary")
+	flag.BoolVar(&withUnicodeTexts, "unicode-texts", false,
+		"write multilingual, emoji and normalization-sensitive texts on every hop and check that filters on "+
+			"them match the same objects on every version")
+	flag.BoolVar(&withTokenizationMatrix, "tokenization-matrix", false,
+		"keep a class per tokenization of a text property and check that filters and BM25 searches on every "+
+			"hop match the same strings as on the version that created it")
+	flag.IntVar(&revectorObjects, "revector-objects", 0,
+		"objects whose vectors are replaced again and again on every hop and while faults are active, "+
+			"0 disables the re-vectorization workload")
+	flag.Int64Var(&runSeed, "seed", 0,
+		"seed of the random choices of the harness, such as faulted nodes and vectors; 0 picks one, "+
+			"a failed run writes its seed to the repro in the artifacts")
+	scenarioFile := flag.String("scenario-file", "",
+		"YAML file with alert rules, e.g. max heap and startup time, that stop the run as soon as one is broken")
+	flag.BoolVar(&withZoneOutage, "zone-outage", false,
+		"on every hop, take down every zone of --zones in turn and check which consistency levels "+
+			"the replicated class still serves")
+	flag.DurationVar(&requestTimeout, "request-timeout", time.Minute,
+		"deadline of every single request to a node, 0 leaves requests to the deadline of their phase")
+	flag.DurationVar(&workloads.LedgerClockSkew, "ledger-clock-skew", workloads.LedgerClockSkew,
+		"how far the object timestamps of the nodes may be off the clock of the harness in the ledger audit")
+	flag.DurationVar(&driftWindow, "drift-window", 0,
+		"how long the Aggregate count of the ledger may drift from what the ledger expects after a fault "+
+			"was healed, 0 disables the check")
+	flag.DurationVar(&harnessCheckInterval, "harness-check-interval", 30*time.Second,
+		"how often the harness checks its own goroutines, heap and open files, 0 disables the checks")
+	flag.IntVar(&harnessLimits.goroutines, "max-harness-goroutines", 10000,
+		"goroutines of the harness itself that stop the run as a leak, 0 disables the limit")
+	flag.Float64Var(&harnessLimits.heapMB, "max-harness-heap-mb", 4096,
+		"heap of the harness itself that stops the run as a leak, 0 disables the limit")
+	flag.IntVar(&harnessLimits.fds, "max-harness-fds", 4096,
+		"open files of the harness itself that stop the run as a leak, 0 disables the limit")
+	flag.StringVar(&externalURL, "external-url", "",
+		"run the workloads and verifiers against an externally managed cluster instead of starting one, "+
+			"with the API key in WEAVIATE_API_KEY; only http faults are supported")
+	flag.StringVar(&remoteClusterFile, "remote-cluster", "",
+		"YAML file with nodes on remote hosts that are controlled over SSH instead of Docker; their data is "+
+			"wiped before the first hop")
+	flag.IntVar(&mixedVersionWrites, "mixed-version-writes", 0,
+		"objects written to every node after each restart of a rolling update, tagged with the version of "+
+			"the node, and verified once all nodes run the new version; 0 disables the workload")
+	flag.Parse()
+
 	var err error
-	versions, err = buildVersionList(ctx, minimumW, targetW)
+	logger, err = newLogger(*logFormat, os.Stdout)
 	if err != nil {
-		log.Fatal(err)
+		fatal("invalid flags", "err", err)
+	}
+	if nodeCount < 1 {
+		fatal("invalid flags", "err", fmt.Errorf("--nodes must be at least 1, got %d", nodeCount))
+	}
+	if portOffset < 0 {
+		fatal("invalid flags", "err", fmt.Errorf("--port-offset must not be negative, got %d", portOffset))
+	}
+	if activeTenants > manyTenants && manyTenants > 0 {
+		fatal("invalid flags", "err", fmt.Errorf("--active-tenants %d exceeds --many-tenants %d",
+			activeTenants, manyTenants))
+	}
+	clusterTopology, err = parseTopology(*raftVoters, *zones, *nodeEnv)
+	if err == nil {
+		err = clusterTopology.Validate(nodeCount)
+	}
+	if err == nil && withZoneOutage && len(clusterTopology.Zones) == 0 {
+		err = fmt.Errorf("--zone-outage needs --zones")
+	}
+	if err == nil && withConnectionFlood {
+		err = validateFlood()
+	}
+	if err == nil {
+		err = validateVectorScale(*loadObjects)
+	}
+	if err != nil {
+		fatal("invalid flags", "err", err)
+	}
+	if *scenarioFile != "" {
+		config, err := loadScenarioConfig(*scenarioFile)
+		if err != nil {
+			fatal("invalid flags", "err", err)
+		}
+		scenarioAlerts.rules = config.Alerts
+		scenarioStates = config.States
+		scenarioHooks = config.Hooks
+		scenarioDebug = config.Debug
+		scenarioSource = &config
+	}
+	if externalURL != "" {
+		var set []string
+		flag.Visit(func(f *flag.Flag) { set = append(set, f.Name) })
+		if conflicts := flagConflicts(set, externalFlags); len(conflicts) > 0 {
+			fatal("invalid flags", "err", fmt.Errorf("--external-url does not support %s",
+				strings.Join(conflicts, ", ")))
+		}
+		if *clientMatrix != "go" {
+			fatal("invalid flags", "err", fmt.Errorf("--external-url only supports --client-matrix=go"))
+		}
+		externalTarget, err = parseExternalURL(externalURL)
+		if err != nil {
+			fatal("invalid flags", "err", err)
+		}
+		nodeCount = 1
+	}
+	if remoteClusterFile != "" {
+		var set []string
+		flag.Visit(func(f *flag.Flag) { set = append(set, f.Name) })
+		if conflicts := flagConflicts(set, remoteFlags()); len(conflicts) > 0 {
+			fatal("invalid flags", "err", fmt.Errorf("--remote-cluster does not support %s",
+				strings.Join(conflicts, ", ")))
+		}
+		if *clientMatrix != "go" {
+			fatal("invalid flags", "err", fmt.Errorf("--remote-cluster only supports --client-matrix=go"))
+		}
+		config, err := remote.LoadConfig(remoteClusterFile)
+		if err != nil {
+			fatal("invalid flags", "err", err)
+		}
+		remoteNodes = remote.New(config, remote.SSH(config.SSHArgs), logger)
+		nodeCount = remoteNodes.NodeCount
+	}
+	if runSeed == 0 {
+		runSeed = time.Now().UnixNano()
+	}
+	workloads.RequestTimeout = requestTimeout
+	writeLedger = workloads.NewLedger(workloads.LedgerWriteTimeout, logger).WithFaultWindows(faultWindows)
+
+	ctx := interruptible(context.Background())
+
+	rootDir, err := os.Getwd()
+	if err != nil {
+		fatal("cannot determine working directory", "err", err)
+	}
+
+	artifactsDir = path.Join(rootDir, "artifacts")
+	if dir, ok := os.LookupEnv("ARTIFACTS_DIR"); ok {
+		artifactsDir = dir
+	}
+
+	if externalTarget != nil {
+		version, err := setupExternal(ctx, os.Getenv("WEAVIATE_API_KEY"))
+		if err != nil {
+			fatal("cannot reach external cluster", "err", err)
+		}
+		versions = []string{version}
+		logger.Info("identified external cluster", "run_id", runID, "url", externalTarget.String(),
+			"version", version)
+	} else {
+		versions = journeyVersions(ctx, *planOnly || remoteNodes != nil, *latestReleases, *channels, *pathPolicy)
+	}
+
+	for _, state := range scenarioStates {
+		if externalTarget != nil || remoteNodes != nil {
+			fatal("invalid flags", "err", fmt.Errorf("states of --scenario-file need the Docker cluster"))
+		}
+		if err := state.validate(versions, nodeCount); err != nil {
+			fatal("invalid flags", "err", err)
+		}
+	}
+	for i := 1; i < len(versions) && externalTarget == nil && remoteNodes == nil; i++ {
+		if !isRaftMigrationHop(versions[i-1], versions[i]) {
+			continue
+		}
+		if err := validateRaftMigration(clusterTopology, nodeCount); err != nil {
+			fatal("invalid flags", "err", err)
+		}
+	}
+	for _, window := range scenarioDebug {
+		if externalTarget != nil || remoteNodes != nil {
+			fatal("invalid flags", "err", fmt.Errorf("debug windows of --scenario-file need the Docker cluster"))
+		}
+		if err := window.validateJourney(versions, nodeCount); err != nil {
+			fatal("invalid flags", "err", err)
+		}
+	}
+
+	verifyRouting, err := parseRouting(*verifyRoute)
+	if err != nil {
+		fatal("invalid flags", "err", err)
+	}
+
+	importRouting, err := parseRouting(*importRoute)
+	if err != nil {
+		fatal("invalid flags", "err", err)
+	}
+
+	pool := newClientPool(nodeCount)
+
+	var inProcess verifier
+	switch *transport {
+	case "client":
+		inProcess = &goVerifier{pool: pool, routing: verifyRouting}
+	case "raw":
+		inProcess = &rawVerifier{host: nodeHost(0)}
+	default:
+		fatal("invalid flags", "transport", *transport)
+	}
+
+	verifiers, err := parseClientMatrix(*clientMatrix, rootDir, inProcess)
+	if err != nil {
+		fatal("invalid flags", "err", err)
+	}
+	if err := validatePreload(verifiers); err != nil {
+		fatal("invalid flags", "err", err)
+	}
+	verifiers = append(verifiers, &ledgerVerifier{}, &cursorVerifier{}, &expiryVerifier{},
+		&contractVerifier{}, &hybridVerifier{}, &bm25ConfigVerifier{}, &nullValuesVerifier{},
+		&nestedVerifier{}, &arraysVerifier{}, &uuidVerifier{},
+		&multiVectorVerifier{}, &timestampVerifier{})
+
+	if *loadObjects > 0 {
+		errorBudget, err := workloads.ParseErrorBudget(*importErrorBudget)
+		if err != nil {
+			fatal("invalid flags", "err", err)
+		}
+
+		bulkLoad = workloads.NewLoad(workloads.LoadOptions{
+			Workers:       *loadWorkers,
+			BatchSize:     *loadBatchSize,
+			TargetLatency: *loadTargetLatency,
+			Dimensions:    loadDimensions,
+			ErrorBudget:   errorBudget,
+			FaultWindows:  faultWindows,
+		}, logger)
+		verifiers = append(verifiers, &loadVerifier{})
+	}
+
+	if withIndexTuning {
+		expectedSchemaMutations = append(expectedSchemaMutations, tunedSchemaMutations()...)
+	}
+
+	if withGenerativeMock {
+		verifiers = append(verifiers, &generativeVerifier{})
+	}
+
+	if mixedVersionWrites > 0 {
+		verifiers = append(verifiers, &mixedVersionVerifier{})
+	}
+
+	if revectorObjects > 0 {
+		revectoring = workloads.NewRevector()
+		verifiers = append(verifiers, &revectorVerifier{})
+	}
+
+	if withUnicodeTexts {
+		unicodeTexts = workloads.NewUnicode()
+		verifiers = append(verifiers, &unicodeVerifier{})
+	}
+
+	if withTokenizationMatrix {
+		tokenizations = workloads.NewTokenization()
+		verifiers = append(verifiers, &tokenizationVerifier{})
+	}
+
+	switch vectorizerModule {
+	case "":
+	case "contextionary":
+		verifiers = append(verifiers, &relevanceVerifier{}, &textSearchVerifier{})
+	default:
+		fatal("invalid flags", "vectorizer", vectorizerModule)
+	}
+
+	if withVectorizerSwap {
+		if vectorizerModule != "contextionary" {
+			fatal("invalid flags", "err", fmt.Errorf("--vectorizer-swap needs --vectorizer=contextionary"))
+		}
+		expectedSchemaMutations = append(expectedSchemaMutations,
+			fmt.Sprintf("classes.[%s].moduleConfig.text2vec-contextionary.%s", moduleSwapClass, moduleSwapSetting))
+	}
+
+	var faultSpecs []string
+	for _, spec := range strings.Split(*faultList, ";") {
+		if strings.TrimSpace(spec) == "" {
+			continue
+		}
+		if _, err := newFault(spec); err != nil {
+			fatal("invalid flags", "err", err)
+		}
+		faultSpecs = append(faultSpecs, spec)
+	}
+	if conflicts := externalFaultConflicts(faultSpecs); externalTarget != nil && len(conflicts) > 0 {
+		fatal("invalid flags", "err", fmt.Errorf("--external-url only supports http faults, got %s",
+			strings.Join(conflicts, "; ")))
+	}
+
+	forbiddenFrom, forbiddenTo, hasForbidden := firstForbiddenJump(versions)
+	if *checkForbidden && !hasForbidden {
+		logger.Warn("no forbidden jump between the versions of the journey, skipping the check")
+	}
+
+	if *planOnly {
+		fmt.Print(buildPlan(verifiers, faultSpecs, *faultHold, *maxDuration))
+		if *checkForbidden && hasForbidden {
+			fmt.Printf("before the journey: check that %s refuses data of %s\n", forbiddenTo, forbiddenFrom)
+		}
+		return
+	}
+
+	if externalTarget == nil && remoteNodes == nil {
+		if preloadDir != "" {
+			if err := preload(ctx, preloadDir, preloadImages(versions)); err != nil {
+				fatal("missing images", "err", err)
+			}
+		}
+		if err := images.checkImages(ctx, versions); err != nil {
+			fatal("missing images", "err", err)
+		}
+		logger.Info("verified images", "platform", images.platform)
 	}
 
-	log.Printf("configured minimum version is %s", minimumW)
-	log.Printf("configured target version is %s", targetW)
-	log.Printf("identified the following versions: %v", versions)
+	if *showProgress {
+		stop := make(chan struct{})
+		defer close(stop)
+		go displayProgress(os.Stderr, stop)
+	}
 
-	cfg := weaviate.Config{
-		Host:   "localhost:8080",
-		Scheme: "http",
+	if *checkForbidden && hasForbidden {
+		if err := checkForbiddenJump(ctx, rootDir, forbiddenFrom, forbiddenTo); err != nil {
+			fatal("forbidden upgrade check failed", "err", err)
+		}
 	}
-	client := weaviate.New(cfg)
 
-	err = do(ctx, client)
+	faults := faultSchedule{
+		specs:         faultSpecs,
+		hold:          *faultHold,
+		verifyDuring:  *verifyUnderFault,
+		duringUpgrade: *faultsDuringUpgrade,
+	}
+	run := do
+	if externalTarget != nil {
+		run = doExternal
+	} else if remoteNodes != nil {
+		run = doRemote
+	}
+	err = run(ctx, pool, importRouting, *loadObjects, verifiers, faults, newBudget(*maxDuration, planEstimates(verifiers, faultSpecs, *faultHold)))
+	if err != nil && ctx.Err() != nil {
+		err = fmt.Errorf("interrupted: %w", err)
+	}
+	if err := runReport.writeHTML(artifactsDir, err); err != nil {
+		logger.Error("cannot write report", "err", err)
+	}
+	if *store != "" {
+		// the run's context is cancelled if the run was interrupted
+		summary := runReport.summary(scenarioName, err)
+		if err := (resultsStore{location: *store}).append(context.Background(), summary); err != nil {
+			logger.Error("cannot store results", "err", err)
+		}
+	}
+	if err != nil && withBisect && ctx.Err() == nil && externalTarget == nil && remoteNodes == nil {
+		result, bisectErr := bisect(ctx, rootDir, runProgress.currentHop())
+		reportBisect(result, bisectErr)
+	}
 	if err != nil {
-		log.Fatal(err)
+		fatal("upgrade journey failed", "err", err)
 	}
+
+	logger.Info("upgrade journey completed", "versions", len(versions))
 }
 
-func do(ctx context.Context, client *weaviate.Client) error {
-	rand.Seed(time.Now().UnixNano())
+func do(ctx context.Context, pool *clientPool, importRouting routing, loadObjects int, verifiers []verifier,
+	faults faultSchedule, b *budget,
+) (err error) {
+	rand.Seed(runSeed)
 
-	c := newCluster(3)
+	c := newCluster(ctx, nodeCount)
+	defer c.tearDown()
+	defer func() {
+		if err != nil {
+			writeRepro(c, runProgress.currentHop(), err)
+		}
+	}()
+	defer func() {
+		if err != nil {
+			saveCountComparison()
+		}
+	}()
+
+	// a broken alert rule cancels the context, the failure it causes in the
+	// current phase is replaced with the rule
+	ctx, stopAlerts := scenarioAlerts.watch(ctx, c)
+	defer func() {
+		stopAlerts()
+		if cause := scenarioAlerts.cause(); cause != nil {
+			err = cause
+		}
+	}()
+
+	// a leak in the harness itself stops the run the same way
+	ctx, stopSelfCheck := harnessHealth.watch(ctx)
+	defer func() {
+		stopSelfCheck()
+		if cause := harnessHealth.cause(); cause != nil {
+			err = cause
+		}
+	}()
 
-	if err := c.startNetwork(ctx); err != nil {
+	if err := c.StartNetwork(ctx); err != nil {
 		return err
 	}
 
-	for i, version := range versions {
+	if withEgressCheck {
+		if err := c.startEgressCapture(ctx); err != nil {
+			return err
+		}
+	}
+
+	if profileInterval > 0 {
+		defer c.startProfiling(ctx)()
+	}
 
-		if err := startOrUpgrade(ctx, c, i, version); err != nil {
+	if useToxiproxy {
+		if err := c.startToxiproxy(ctx); err != nil {
 			return err
 		}
+	}
 
+	if vectorizerModule != "" {
+		if err := c.startContextionary(ctx); err != nil {
+			return err
+		}
+	}
+
+	if withGenerativeMock {
+		if err := c.startGenerativeMock(ctx); err != nil {
+			return err
+		}
+	}
+
+	if err := clientFaultProxy.start(nodeHost(0)); err != nil {
+		return err
+	}
+
+	for i, version := range versions {
+		hopLogger(i).Debug("supported features", "features", supportedFeatures(version))
+		startPhase := phaseUpgrade
 		if i == 0 {
-			if err := createSchema(ctx, client); err != nil {
+			startPhase = phaseStart
+		}
+
+		if err := b.run(ctx, i, startPhase, func(ctx context.Context) error {
+			upgrade := func(ctx context.Context) error {
+				return startOrUpgrade(ctx, c, i, version)
+			}
+			if i > 0 && withUpgradeBackup {
+				plain := upgrade
+				upgrade = func(ctx context.Context) error {
+					return upgradeDuringBackup(ctx, i, plain)
+				}
+			}
+
+			if i == 0 || !faults.duringUpgrade {
+				return upgrade(ctx)
+			}
+
+			return withFaults(ctx, c, i, faults.create(), upgrade)
+		}); err != nil {
+			return err
+		}
+
+		if withMetrics {
+			if err := metrics.recordBaseline(ctx, c); err != nil {
 				return err
 			}
 		}
 
-		if err := importForVersion(ctx, client, version); err != nil {
+		if err := b.run(ctx, i, phaseImport, func(ctx context.Context) error {
+			if i == 0 {
+				if manyClasses > 0 {
+					if err := createManyClasses(ctx); err != nil {
+						return err
+					}
+				}
+
+				if manyTenants > 0 {
+					if err := createManyTenants(ctx); err != nil {
+						return err
+					}
+				}
+
+				if withRestoreUnderLoad {
+					if err := createRestoreSource(ctx); err != nil {
+						return err
+					}
+				}
+
+				if withIndexTuning {
+					if err := createTunedClass(ctx); err != nil {
+						return err
+					}
+				}
+
+				if err := createSchema(ctx, pool.node(0)); err != nil {
+					return err
+				}
+
+				if err := checkIntendedSchema(ctx, i); err != nil {
+					return err
+				}
+
+				if err := recordClassConfigBaseline(ctx, "RefTarget", "Collection"); err != nil {
+					return err
+				}
+
+				if err := writeLedger.CreateClass(ctx, nodeHost(0)); err != nil {
+					return err
+				}
+
+				if err := expiring.CreateClass(ctx, nodeHost(0)); err != nil {
+					return err
+				}
+
+				if vectorizerModule != "" {
+					if err := relevance.createClass(ctx); err != nil {
+						return err
+					}
+				}
+
+				if bulkLoad != nil {
+					if err := bulkLoad.CreateClass(ctx, nodeHost(0)); err != nil {
+						return err
+					}
+				}
+
+				if revectoring != nil {
+					if err := revectoring.CreateClass(ctx, nodeHost(0), revectorObjects); err != nil {
+						return err
+					}
+				}
+
+				if unicodeTexts != nil {
+					if err := unicodeTexts.CreateClass(ctx, nodeHost(0)); err != nil {
+						return err
+					}
+				}
+
+				if mixedVersionWrites > 0 {
+					if err := mixedWrites.createClass(ctx); err != nil {
+						return err
+					}
+				}
+			}
+
+			if vectorizerModule != "" {
+				if err := relevance.importTexts(ctx); err != nil {
+					return err
+				}
+			}
+
+			if err := expiring.ImportAndSweep(ctx, nodeHost(0), i); err != nil {
+				return err
+			}
+
+			if err := writeLedger.Write(ctx, clientFaultProxy.host(), i, ledgerWritesPerHop); err != nil {
+				return err
+			}
+
+			if err := writeLedger.Mutate(ctx, clientFaultProxy.host(), i, ledgerMutationsPerHop); err != nil {
+				return err
+			}
+
+			if err := updateVectors(ctx, i); err != nil {
+				return err
+			}
+
+			if unicodeTexts != nil {
+				if err := unicodeTexts.Import(ctx, nodeHost(0), i); err != nil {
+					return err
+				}
+			}
+
+			if err := importTokenizations(ctx, i); err != nil {
+				return err
+			}
+
+			if err := importProbes(ctx, i); err != nil {
+				return err
+			}
+
+			if err := importReplicated(ctx, c, version); err != nil {
+				return err
+			}
+
+			pool.refresh(ctx)
+			client, nodeId, err := pool.pick(importRouting)
+			if err != nil {
+				return err
+			}
+
+			hopLogger(i).Debug("importing", "node", nodeId, "routing", importRouting)
+			if bulkLoad != nil {
+				if err := bulkLoad.Import(ctx, nodeHost(nodeId), i, loadObjects); err != nil {
+					return err
+				}
+			}
+
+			return importForVersion(ctx, client, version)
+		}); err != nil {
 			return err
 		}
 
-		if err := verify(ctx, client, i); err != nil {
+		if err := b.run(ctx, i, phaseVerify, func(ctx context.Context) error {
+			// searches only find what was indexed, so with async indexing
+			// the verifiers wait for the queues instead of for a fixed time
+			if awaitIndexing(version) {
+				if _, err := c.awaitIndexed(ctx, i, "", asyncDrainTimeout); err != nil {
+					return err
+				}
+			}
+
+			if err := verifyHop(ctx, verifiers, i); err != nil {
+				return err
+			}
+
+			if err := c.verifyDiskFormat(ctx, i); err != nil {
+				return err
+			}
+
+			if withMetrics {
+				return metrics.check(ctx, c, i)
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		c.sampleResources(ctx, i)
+		if profileInterval > 0 {
+			c.captureProfiles(ctx, fmt.Sprintf("hop%02d", i))
+		}
+		if debugProfiling() {
+			c.captureDebug(ctx, i)
+		}
+
+		if loadMemoryFactor > 0 {
+			if err := b.run(ctx, i, phaseVerify, func(ctx context.Context) error {
+				return c.checkVectorMemory(i)
+			}); err != nil {
+				return err
+			}
+		}
+
+		if withEgressCheck {
+			if err := b.run(ctx, i, phaseVerify, func(ctx context.Context) error {
+				return c.checkEgress(ctx, i)
+			}); err != nil {
+				return err
+			}
+		}
+
+		if manyClasses > 0 {
+			if err := b.run(ctx, i, phaseVerify, func(ctx context.Context) error {
+				return c.checkLargeSchema(ctx, i)
+			}); err != nil {
+				return err
+			}
+		}
+
+		if manyTenants > 0 {
+			if err := b.run(ctx, i, phaseVerify, func(ctx context.Context) error {
+				return c.checkManyTenants(ctx, i)
+			}); err != nil {
+				return err
+			}
+		}
+
+		if withRestoreUnderLoad {
+			if err := b.run(ctx, i, phaseVerify, func(ctx context.Context) error {
+				return c.restoreUnderLoad(ctx, i)
+			}); err != nil {
+				return err
+			}
+		}
+
+		if withReadRepair {
+			if err := b.run(ctx, i, phaseVerify, func(ctx context.Context) error {
+				return c.readRepair(ctx, i)
+			}); err != nil {
+				return err
+			}
+		}
+
+		if withDeleteWhileDown {
+			if err := b.run(ctx, i, phaseVerify, func(ctx context.Context) error {
+				return c.deleteWhileDown(ctx, i)
+			}); err != nil {
+				return err
+			}
+		}
+
+		if withConflictingUpdates {
+			if err := b.run(ctx, i, phaseVerify, func(ctx context.Context) error {
+				return c.conflictingUpdates(ctx, i)
+			}); err != nil {
+				return err
+			}
+		}
+
+		if withBatchLimits {
+			if err := b.run(ctx, i, phaseVerify, func(ctx context.Context) error {
+				return batchLimits(ctx, i)
+			}); err != nil {
+				return err
+			}
+		}
+
+		if withConnectionFlood {
+			if err := b.run(ctx, i, phaseVerify, func(ctx context.Context) error {
+				return c.connectionFlood(ctx, i)
+			}); err != nil {
+				return err
+			}
+		}
+
+		if withAsyncIndexingChaos {
+			if err := b.run(ctx, i, phaseVerify, func(ctx context.Context) error {
+				return c.asyncIndexingChaos(ctx, i)
+			}); err != nil {
+				return err
+			}
+		}
+
+		if withVectorizerSwap {
+			if err := b.run(ctx, i, phaseVerify, func(ctx context.Context) error {
+				return moduleSwap.swap(ctx, c, i)
+			}); err != nil {
+				return err
+			}
+		}
+
+		if withIndexTuning {
+			if err := b.run(ctx, i, phaseVerify, func(ctx context.Context) error {
+				return c.checkIndexTuning(ctx, i)
+			}); err != nil {
+				return err
+			}
+		}
+
+		if withRaftWipe && isRaftVersion(version) {
+			if err := b.run(ctx, i, phaseUpgrade, func(ctx context.Context) error {
+				return raftSnapshotChaos(ctx, c, i)
+			}); err != nil {
+				return err
+			}
+		}
+
+		if withZoneOutage {
+			if err := b.run(ctx, i, phaseUpgrade, func(ctx context.Context) error {
+				return zoneOutage(ctx, c, i)
+			}); err != nil {
+				return err
+			}
+		}
+
+		if isRaftVersion(version) && len(c.Voters()) >= largeClusterNodes {
+			if err := b.run(ctx, i, phaseUpgrade, func(ctx context.Context) error {
+				return raftQuorumCheck(ctx, c, i)
+			}); err != nil {
+				return err
+			}
+		}
+
+		if !faults.duringUpgrade {
+			if err := applyHopFaults(ctx, c, verifiers, faults, b, i, loadObjects); err != nil {
+				return err
+			}
+		}
+	}
+
+	last := len(versions) - 1
+	for _, state := range scenarioStates {
+		if err := b.run(ctx, last, phaseUpgrade, func(ctx context.Context) error {
+			return stateReconciler.converge(ctx, c, state)
+		}); err != nil {
+			return err
+		}
+
+		if err := b.run(ctx, last, phaseVerify, func(ctx context.Context) error {
+			return runVerifiers(ctx, verifiers, versionPos(versions, state.Version))
+		}); err != nil {
 			return err
 		}
 	}
@@ -87,6 +1017,202 @@ func do(ctx context.Context, client *weaviate.Client) error {
 	return nil
 }
 
+// journeyVersions resolves the versions the journey visits, from
+// MINIMUM_WEAVIATE_VERSION to WEAVIATE_VERSION or the latest releases.
+// withoutDocker resolves them from the tags alone.
+func journeyVersions(ctx context.Context, withoutDocker bool, latestReleases int, channels,
+	pathPolicy string,
+) []string {
+	if pinnedVersions != "" {
+		return pinnedJourneyVersions(ctx, withoutDocker)
+	}
+
+	targetW, ok := os.LookupEnv("WEAVIATE_VERSION")
+	if !ok && images.finalImage != "" {
+		targetW = tagOf(images.finalImage)
+	} else if !ok && latestReleases == 0 {
+		fatal("missing WEAVIATE_VERSION")
+	}
+	images.finalVersion = targetW
+
+	minimumW, ok := os.LookupEnv("MINIMUM_WEAVIATE_VERSION")
+	if !ok && latestReleases == 0 {
+		fatal("missing MINIMUM_WEAVIATE_VERSION")
+	}
+
+	resolveTarget := targetResolver(getTargetVersion)
+	if withoutDocker {
+		resolveTarget = resolveTargetWithoutDocker
+	} else if err := images.resolvePlatform(ctx); err != nil {
+		fatal("cannot determine image platform", "err", err)
+	}
+
+	var versions []string
+	var err error
+	if latestReleases > 0 {
+		versions, err = buildLatestVersionList(latestReleases, splitList(channels), targetW)
+	} else {
+		versions, err = buildVersionList(ctx, minimumW, targetW, resolveTarget)
+	}
+	if err != nil {
+		fatal("cannot build version list", "err", err)
+	}
+
+	if err := checkJourneyOrder(versions); err != nil {
+		fatal("invalid version list", "err", err)
+	}
+
+	if manyTenants > 0 && !supports(versions[0], featureTenantActivity) {
+		fatal("invalid flags", "err", fmt.Errorf("--many-tenants needs %s from the first version on, %s is older",
+			featureTenantActivity, versions[0]))
+	}
+
+	versions, err = applyPathPolicy(versions, pathPolicy)
+	if err != nil {
+		fatal("cannot apply upgrade path policy", "err", err)
+	}
+
+	logger.Info("identified versions", "run_id", runID, "minimum", minimumW, "target", targetW,
+		"versions", versions)
+
+	return versions
+}
+
+// pinnedJourneyVersions returns the versions of --versions as they are. The
+// last one is the target, which --final-image can replace.
+func pinnedJourneyVersions(ctx context.Context, withoutDocker bool) []string {
+	versions := splitList(pinnedVersions)
+	if len(versions) == 0 {
+		fatal("invalid flags", "err", fmt.Errorf("--versions lists no version"))
+	}
+	if err := checkJourneyOrder(versions); err != nil {
+		fatal("invalid version list", "err", err)
+	}
+	images.finalVersion = versions[len(versions)-1]
+
+	if !withoutDocker {
+		if err := images.resolvePlatform(ctx); err != nil {
+			fatal("cannot determine image platform", "err", err)
+		}
+	}
+
+	logger.Info("identified versions", "run_id", runID, "versions", versions)
+	return versions
+}
+
+// splitList splits a comma-separated flag value, ignoring empty entries
+func splitList(value string) []string {
+	var out []string
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			out = append(out, entry)
+		}
+	}
+
+	return out
+}
+
+// applyHopFaults applies every fault of the schedule one after another and
+// verifies the cluster after every one of them was healed. While a fault is
+// active, the ledger, load and revector workloads keep writing.
+func applyHopFaults(ctx context.Context, c *cluster, verifiers []verifier,
+	faults faultSchedule, b *budget, i, loadObjects int,
+) error {
+	for _, f := range faults.create() {
+		if err := b.run(ctx, i, phaseUpgrade, func(ctx context.Context) error {
+			return applyFault(ctx, c, i, f, faults.hold, func(ctx context.Context) error {
+				if err := writeLedger.Write(ctx, clientFaultProxy.host(), i, ledgerWritesPerHop); err != nil {
+					return err
+				}
+
+				if err := writeLedger.Mutate(ctx, clientFaultProxy.host(), i, ledgerMutationsPerHop); err != nil {
+					return err
+				}
+
+				if bulkLoad != nil {
+					if err := bulkLoad.Import(ctx, nodeHost(0), i, loadObjects); err != nil {
+						return err
+					}
+				}
+
+				if err := updateVectors(ctx, i); err != nil {
+					return err
+				}
+
+				if faults.verifyDuring {
+					return runVerifiers(ctx, verifiers, i)
+				}
+				return nil
+			})
+		}); err != nil {
+			return err
+		}
+
+		if err := b.run(ctx, i, phaseVerify, func(ctx context.Context) error {
+			if driftWindow > 0 {
+				if err := awaitLedgerConvergence(ctx, i, f.Describe()); err != nil {
+					return err
+				}
+			}
+
+			if err := runVerifiers(ctx, verifiers, i); err != nil {
+				return fmt.Errorf("after %s: %w", f.Describe(), err)
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// verifyHop runs every verifier as well as the metadata checks after the
+// cluster reached a new version
+func verifyHop(ctx context.Context, verifiers []verifier, i int) error {
+	if err := runVerifiers(ctx, verifiers, i); err != nil {
+		return err
+	}
+
+	if err := snapshotClusterMetadata(ctx, i); err != nil {
+		return err
+	}
+
+	if err := checkIntendedSchema(ctx, i); err != nil {
+		return err
+	}
+
+	if err := checkShardPlacement(ctx, i); err != nil {
+		return err
+	}
+
+	return detectConfigDrift(ctx, i)
+}
+
+func runVerifiers(ctx context.Context, verifiers []verifier, i int) error {
+	for _, v := range verifiers {
+		var took time.Duration
+		result, attempts, err := verifyRetries.run(ctx, func(ctx context.Context) error {
+			start := time.Now()
+			err := v.verify(ctx, i)
+			took = time.Since(start)
+			return err
+		})
+		runReport.recordStep(i, "verify with "+v.name(), result, attempts, err)
+
+		switch result {
+		case outcomeFail:
+			return fmt.Errorf("verify %s with %s client: %w", versions[i], v.name(), err)
+		case outcomeFlaky:
+			hopLogger(i).Warn("verification is flaky", "client", v.name(),
+				"attempts", attempts, "first_err", err)
+		}
+		runReport.recordLatency(i, v.name(), took)
+	}
+
+	return nil
+}
+
 func verify(ctx context.Context, client *weaviate.Client, i int) error {
 	if err := findEachImportedObject(ctx, client, i); err != nil {
 		return err
@@ -96,22 +1222,32 @@ func verify(ctx context.Context, client *weaviate.Client, i int) error {
 		return err
 	}
 
+	if err := groupedAggregation(ctx, client, i); err != nil {
+		return err
+	}
+
 	if err := vectorSearch(ctx, client, i); err != nil {
 		return err
 	}
 
+	if err := crossClassChain(ctx, client, i); err != nil {
+		return err
+	}
+
 	return nil
 }
 
 func aggregateObjects(ctx context.Context, client *weaviate.Client,
 	count int,
 ) error {
+	reqCtx, cancel := requestContext(ctx)
 	result, err := client.GraphQL().Aggregate().
 		WithClassName("Collection").
 		WithFields(graphql.Field{Name: "meta", Fields: []graphql.Field{{Name: "count"}}}).
-		Do(ctx)
+		Do(reqCtx)
+	cancel()
 	if err != nil {
-		return err
+		return requestError(ctx, "aggregate Collection", err)
 	}
 
 	if len(result.Errors) > 0 {
@@ -159,13 +1295,15 @@ func findObjectUsingVersionString(ctx context.Context, client *weaviate.Client,
 		WithOperator(filters.Equal).
 		WithValueString(version)
 
+	reqCtx, cancel := requestContext(ctx)
 	result, err := client.GraphQL().Get().
 		WithClassName("Collection").
 		WithFields(fields...).
 		WithWhere(where).
-		Do(ctx)
+		Do(reqCtx)
+	cancel()
 	if err != nil {
-		return err
+		return requestError(ctx, "get Collection by version", err)
 	}
 	if len(result.Errors) > 0 {
 		return fmt.Errorf("%v", result.Errors)
@@ -188,9 +1326,9 @@ func findObjectUsingVersionString(ctx context.Context, client *weaviate.Client,
 func findObjectUsingVersionInts(ctx context.Context, client *weaviate.Client,
 	version string,
 ) error {
-	parsed, ok := maybeParseSingleSemverWithoutLeadingV(version)
+	parsed, ok := parseVersion(version)
 	if !ok {
-		log.Printf("skipping int version test because %q is not a valid semver", version)
+		logger.Info("skipping int version test, not a valid semver", "version", version)
 		return nil
 	}
 	fields := []graphql.Field{
@@ -218,13 +1356,15 @@ func findObjectUsingVersionInts(ctx context.Context, client *weaviate.Client,
 		},
 		)
 
+	reqCtx, cancel := requestContext(ctx)
 	result, err := client.GraphQL().Get().
 		WithClassName("Collection").
 		WithFields(fields...).
 		WithWhere(where).
-		Do(ctx)
+		Do(reqCtx)
+	cancel()
 	if err != nil {
-		return err
+		return requestError(ctx, "get Collection by version ints", err)
 	}
 	if len(result.Errors) > 0 {
 		return fmt.Errorf("%v", result.Errors[0])
@@ -282,14 +1422,16 @@ func unfilteredVectorSearch(ctx context.Context, client *weaviate.Client,
 	nearVector := client.GraphQL().NearVectorArgBuilder().
 		WithVector(searchVec)
 
+	reqCtx, cancel := requestContext(ctx)
 	result, err := client.GraphQL().Get().
 		WithClassName("Collection").
 		WithFields(fields...).
 		WithNearVector(nearVector).
 		WithLimit(10000).
-		Do(ctx)
+		Do(reqCtx)
+	cancel()
 	if err != nil {
-		return err
+		return requestError(ctx, "unfiltered vector search", err)
 	}
 	if len(result.Errors) > 0 {
 		return fmt.Errorf("%v", result.Errors)
@@ -329,14 +1471,16 @@ func filteredVectorSearch(ctx context.Context, client *weaviate.Client,
 		nearVector := client.GraphQL().NearVectorArgBuilder().
 			WithVector(searchVec)
 
+		reqCtx, cancel := requestContext(ctx)
 		result, err := client.GraphQL().Get().
 			WithClassName("Collection").
 			WithFields(fields...).
 			WithWhere(where).
 			WithNearVector(nearVector).
-			Do(ctx)
+			Do(reqCtx)
+		cancel()
 		if err != nil {
-			return err
+			return requestError(ctx, "filtered vector search", err)
 		}
 		if len(result.Errors) > 0 {
 			return fmt.Errorf("%v", result.Errors)
@@ -352,9 +1496,12 @@ func filteredVectorSearch(ctx context.Context, client *weaviate.Client,
 	return nil
 }
 
-func createSchema(ctx context.Context, client *weaviate.Client) error {
+// journeyClasses are the classes of the journey as the client intends them,
+// checkIntendedSchema makes sure the server keeps them that way
+func journeyClasses() []*models.Class {
 	refTarget := &models.Class{
-		Class: "RefTarget",
+		Class:          "RefTarget",
+		ShardingConfig: shardingConfig(),
 		Properties: []*models.Property{
 			{
 				DataType: []string{"string"},
@@ -367,13 +1514,9 @@ func createSchema(ctx context.Context, client *weaviate.Client) error {
 		},
 	}
 
-	err := client.Schema().ClassCreator().WithClass(refTarget).Do(context.Background())
-	if err != nil {
-		return err
-	}
-
 	classObj := &models.Class{
-		Class: "Collection",
+		Class:          "Collection",
+		ShardingConfig: shardingConfig(),
 		Properties: []*models.Property{
 			{
 				DataType: []string{"string"},
@@ -402,9 +1545,17 @@ func createSchema(ctx context.Context, client *weaviate.Client) error {
 		},
 	}
 
-	err = client.Schema().ClassCreator().WithClass(classObj).Do(context.Background())
-	if err != nil {
-		return err
+	return []*models.Class{refTarget, classObj}
+}
+
+func createSchema(ctx context.Context, client *weaviate.Client) error {
+	for _, class := range journeyClasses() {
+		reqCtx, cancel := requestContext(ctx)
+		err := client.Schema().ClassCreator().WithClass(class).Do(reqCtx)
+		cancel()
+		if err != nil {
+			return requestError(ctx, "create class "+class.Class, err)
+		}
 	}
 
 	return nil
@@ -423,6 +1574,7 @@ func importForVersion(ctx context.Context, client *weaviate.Client,
 	}
 
 	objectsCreated++
+	runProgress.setObjects(objectsCreated)
 
 	return nil
 }
@@ -435,13 +1587,16 @@ func importTargetObject(ctx context.Context, client *weaviate.Client,
 		"object_count": objectsCreated,
 	}
 
+	reqCtx, cancel := requestContext(ctx)
+	defer cancel()
+
 	_, err := client.Data().Creator().
 		WithClassName("RefTarget").
 		WithID(id).
 		WithProperties(props).
-		Do(context.Background())
+		Do(reqCtx)
 	if err != nil {
-		return err
+		return requestError(ctx, "create RefTarget object", err)
 	}
 
 	return nil
@@ -451,7 +1606,7 @@ func importSourceObject(ctx context.Context, client *weaviate.Client,
 	version, targetID string,
 ) error {
 	var major, minor, patch int64
-	semver, ok := maybeParseSingleSemverWithoutLeadingVForImport(version)
+	semver, ok := parseVersionForImport(version)
 	if ok {
 		major, minor, patch = semver.major(), semver.minor(), semver.patch()
 	}
@@ -469,13 +1624,16 @@ func importSourceObject(ctx context.Context, client *weaviate.Client,
 		vec[i] = rand.Float32()
 	}
 
+	reqCtx, cancel := requestContext(ctx)
+	defer cancel()
+
 	_, err := client.Data().Creator().
 		WithClassName("Collection").
 		WithVector(vec).
 		WithProperties(props).
-		Do(context.Background())
+		Do(reqCtx)
 	if err != nil {
-		return err
+		return requestError(ctx, "create Collection object", err)
 	}
 
 	return nil
@@ -483,8 +1641,12 @@ func importSourceObject(ctx context.Context, client *weaviate.Client,
 
 func startOrUpgrade(ctx context.Context, c *cluster, i int, version string) error {
 	if i == 0 {
-		return c.startAllNodes(ctx, version)
+		return c.StartAllNodes(ctx, version)
+	}
+
+	if isRaftMigrationHop(versions[i-1], version) {
+		return migrateToRaft(ctx, c, i)
 	}
 
-	return c.rollingUpdate(ctx, version)
+	return c.RollingUpdate(ctx, version)
 }
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// scenarioConfig is the YAML file passed with --scenario-file. It declares
+// what a scenario expects of a healthy cluster, in addition to what the
+// flags configure.
+type scenarioConfig struct {
+	Alerts alertRules     `yaml:"alerts"`
+	States []desiredState `yaml:"states"`
+	Hooks  []stepHook     `yaml:"hooks"`
+	Debug  []debugWindow  `yaml:"debug"`
+}
+
+// loadScenarioConfig reads a scenario file. Unknown keys are rejected, so
+// that a typo does not silently disable a rule.
+func loadScenarioConfig(file string) (scenarioConfig, error) {
+	var config scenarioConfig
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return config, err
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(raw))
+	dec.KnownFields(true)
+	if err := dec.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		return config, fmt.Errorf("scenario file %s: %w", file, err)
+	}
+
+	if err := config.Alerts.validate(); err != nil {
+		return config, fmt.Errorf("scenario file %s: %w", file, err)
+	}
+	for _, hook := range config.Hooks {
+		if err := hook.validate(); err != nil {
+			return config, fmt.Errorf("scenario file %s: %w", file, err)
+		}
+	}
+	for _, window := range config.Debug {
+		if err := window.validate(); err != nil {
+			return config, fmt.Errorf("scenario file %s: %w", file, err)
+		}
+	}
+	return config, nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"runtime"
+	"runtime/pprof"
+	"sync"
+	"time"
+)
+
+// The harness checks its own health while it runs. Long soak runs have been
+// killed because the harness itself leaked goroutines, memory or file
+// descriptors, e.g. through response bodies that were never closed, which
+// looked like a failure of the cluster. Once a limit is crossed the run
+// stops with the diagnostics of the harness instead.
+var (
+	// harnessCheckInterval is set by --harness-check-interval, 0 disables
+	// the checks
+	harnessCheckInterval time.Duration
+
+	harnessLimits selfLimits
+)
+
+// selfLimits are set by --max-harness-goroutines, --max-harness-heap-mb and
+// --max-harness-fds, 0 disables a limit
+type selfLimits struct {
+	goroutines int
+	heapMB     float64
+	fds        int
+}
+
+// selfSample is the state of the harness process at one point in time
+type selfSample struct {
+	goroutines int
+	heapMB     float64
+
+	// fds is -1 where open descriptors cannot be counted
+	fds int
+}
+
+func (s selfSample) String() string {
+	fds := "unknown"
+	if s.fds >= 0 {
+		fds = fmt.Sprint(s.fds)
+	}
+	return fmt.Sprintf("%d goroutines, %.0f MB heap, %s open files", s.goroutines, s.heapMB, fds)
+}
+
+func sampleSelf() selfSample {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	return selfSample{
+		goroutines: runtime.NumGoroutine(),
+		heapMB:     float64(mem.HeapInuse) / (1 << 20),
+		fds:        openFiles(),
+	}
+}
+
+// openFiles counts the open descriptors of the process, -1 on systems
+// without /proc
+func openFiles() int {
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return -1
+	}
+	return len(entries)
+}
+
+// violations returns the limits the sample crosses, with the growth since
+// the start of the run
+func (l selfLimits) violations(start, cur selfSample) []string {
+	var broken []string
+	if l.goroutines > 0 && cur.goroutines > l.goroutines {
+		broken = append(broken, fmt.Sprintf("%d goroutines, above %d, %d at the start", cur.goroutines,
+			l.goroutines, start.goroutines))
+	}
+	if l.heapMB > 0 && cur.heapMB > l.heapMB {
+		broken = append(broken, fmt.Sprintf("%.0f MB heap, above %.0f, %.0f at the start", cur.heapMB,
+			l.heapMB, start.heapMB))
+	}
+	if l.fds > 0 && cur.fds > l.fds {
+		broken = append(broken, fmt.Sprintf("%d open files, above %d, %d at the start", cur.fds, l.fds,
+			start.fds))
+	}
+	return broken
+}
+
+// errHarnessUnhealthy is the cause of a run that was stopped because the
+// harness crossed one of its own limits
+var errHarnessUnhealthy = errors.New("harness unhealthy")
+
+// selfMonitor stops the run through its context once the harness crosses a
+// limit, like alertMonitor does for the nodes
+type selfMonitor struct {
+	sync.Mutex
+	err error
+}
+
+var harnessHealth = &selfMonitor{}
+
+// watch samples the harness on every --harness-check-interval until the
+// returned function is called
+func (m *selfMonitor) watch(ctx context.Context) (context.Context, func()) {
+	if harnessCheckInterval <= 0 {
+		return ctx, func() {}
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	start := sampleSelf()
+	logger.Debug("harness health", "sample", start.String())
+
+	done := make(chan struct{})
+	stop := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(harnessCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				cur := sampleSelf()
+				logger.Debug("harness health", "sample", cur.String())
+				if broken := harnessLimits.violations(start, cur); len(broken) > 0 {
+					m.trip(cancel, broken)
+					return
+				}
+			}
+		}
+	}()
+
+	return ctx, func() {
+		close(stop)
+		<-done
+		cancel(nil)
+	}
+}
+
+// trip stores the goroutines of the harness next to the other artifacts
+// and stops the run
+func (m *selfMonitor) trip(cancel context.CancelCauseFunc, broken []string) {
+	m.Lock()
+	defer m.Unlock()
+
+	dump := path.Join(artifactsDir, "harness-goroutines.txt")
+	if err := writeGoroutines(dump); err != nil {
+		logger.Warn("cannot dump the goroutines of the harness", "err", err)
+		dump = ""
+	}
+
+	m.err = fmt.Errorf("%w: %v", errHarnessUnhealthy, broken)
+	logger.Error("harness crossed its own limits, stopping the run", "err", m.err, "goroutines", dump)
+	cancel(m.err)
+}
+
+func writeGoroutines(dest string) error {
+	if err := os.MkdirAll(path.Dir(dest), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// debug level 2 prints every goroutine with its full stack, like a
+	// panic does
+	return pprof.Lookup("goroutine").WriteTo(f, 2)
+}
+
+// cause returns the limit that stopped the run, if any
+func (m *selfMonitor) cause() error {
+	m.Lock()
+	defer m.Unlock()
+	return m.err
+}
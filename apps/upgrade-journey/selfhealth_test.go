@@ -0,0 +1,45 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSelfLimitViolations(t *testing.T) {
+	start := selfSample{goroutines: 50, heapMB: 100, fds: 20}
+	tests := []struct {
+		name   string
+		limits selfLimits
+		cur    selfSample
+		want   []string
+	}{
+		{
+			name:   "within limits",
+			limits: selfLimits{goroutines: 1000, heapMB: 512, fds: 100},
+			cur:    selfSample{goroutines: 900, heapMB: 500, fds: 100},
+		},
+		{
+			name:   "goroutine leak",
+			limits: selfLimits{goroutines: 1000, heapMB: 512, fds: 100},
+			cur:    selfSample{goroutines: 1500, heapMB: 500, fds: 30},
+			want:   []string{"1500 goroutines, above 1000, 50 at the start"},
+		},
+		{
+			name:   "heap and files",
+			limits: selfLimits{heapMB: 512, fds: 100},
+			cur:    selfSample{goroutines: 5000, heapMB: 600, fds: 120},
+			want:   []string{"600 MB heap, above 512, 100 at the start", "120 open files, above 100, 20 at the start"},
+		},
+		{
+			name:   "files cannot be counted",
+			limits: selfLimits{fds: 100},
+			cur:    selfSample{fds: -1},
+		},
+	}
+
+	for _, tt := range tests {
+		if got := tt.limits.violations(start, tt.cur); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: wanted %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
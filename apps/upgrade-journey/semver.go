@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+
+	hashicorpversion "github.com/hashicorp/go-version"
+)
+
+// semver is a parsed release version. Everything that orders or compares
+// versions goes through it, never through the position of a version in a
+// list or through string comparison.
+type semver struct {
+	version *hashicorpversion.Version
+}
+
+type semverList []semver
+
+func (self semver) compare(other semver) int {
+	return self.version.Compare(other.version)
+}
+
+func (self semver) less(other semver) bool {
+	return self.compare(other) < 0
+}
+
+func (self semver) largerOrEqual(other semver) bool {
+	return self.compare(other) >= 0
+}
+
+func (self semver) major() int64 {
+	return self.version.Segments64()[0]
+}
+
+func (self semver) minor() int64 {
+	return self.version.Segments64()[1]
+}
+
+func (self semver) patch() int64 {
+	return self.version.Segments64()[2]
+}
+
+func (self semver) sameMinor(other semver) bool {
+	return self.major() == other.major() && self.minor() == other.minor()
+}
+
+func (self semver) String() string {
+	return self.version.String()
+}
+
+func mustParseVersion(input string) semver {
+	v, ok := parseVersion(input)
+	if !ok {
+		panic("not an acceptable semver")
+	}
+
+	return v
+}
+
+func parseVersionForImport(input string) (semver, bool) {
+	ver, ok := parseVersion(input)
+	if !ok {
+		// let's return a dummy version bc here we got a preview image
+		ver, err := hashicorpversion.NewSemver("0.0.0")
+		if err != nil {
+			panic("cannot parse 0.0.0 dummy version")
+		}
+		return semver{version: ver}, true
+	}
+	return ver, true
+}
+
+func parseVersion(input string) (semver, bool) {
+	ver, err := hashicorpversion.NewSemver(input)
+	if err != nil {
+		return semver{version: nil}, false
+	}
+
+	return semver{
+		version: ver,
+	}, true
+}
+
+// versionLess orders releases by semver. Versions that are not a semver,
+// such as preview images and channels, sort after every release and keep
+// their relative order.
+func versionLess(a, b string) bool {
+	va, aok := parseVersion(a)
+	vb, bok := parseVersion(b)
+	switch {
+	case aok && bok:
+		return va.less(vb)
+	case aok:
+		return true
+	default:
+		return false
+	}
+}
+
+// checkJourneyOrder fails if the journey would ever go back to an older
+// release, which would make it a downgrade test by accident
+func checkJourneyOrder(versions []string) error {
+	for i := 1; i < len(versions); i++ {
+		if versionLess(versions[i], versions[i-1]) {
+			return fmt.Errorf("version %s follows %s, the journey must not downgrade",
+				versions[i], versions[i-1])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import "testing"
+
+func Test_versionLess(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{a: "1.9.0", b: "1.10.0", want: true},
+		{a: "1.10.0", b: "1.9.0", want: false},
+		{a: "1.22.10", b: "1.22.9", want: false},
+		{a: "1.25.0", b: "v1.25.1", want: true},
+		{a: "1.25.0", b: "preview-abc", want: true},
+		{a: "preview-abc", b: "1.25.0", want: false},
+		{a: "preview-abc", b: "stable", want: false},
+	}
+	for _, tt := range tests {
+		if got := versionLess(tt.a, tt.b); got != tt.want {
+			t.Errorf("versionLess(%s, %s) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func Test_checkJourneyOrder(t *testing.T) {
+	tests := []struct {
+		versions []string
+		wantErr  bool
+	}{
+		{versions: []string{"1.9.0", "1.10.0", "1.10.2", "preview-abc"}},
+		{versions: []string{"1.22.9", "1.22.10", "stable", "preview-abc"}},
+		{versions: []string{"1.10.0", "1.9.0"}, wantErr: true},
+		{versions: []string{"1.24.0", "preview-abc", "1.25.0"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := checkJourneyOrder(tt.versions); (err != nil) != tt.wantErr {
+			t.Errorf("checkJourneyOrder(%v) = %v, wantErr %v", tt.versions, err, tt.wantErr)
+		}
+	}
+}
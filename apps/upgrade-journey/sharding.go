@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// journeyShards is set by --shards, 0 leaves the shard count of the journey
+// classes to the server, which creates one shard per node
+var journeyShards int
+
+// shardingConfig returns the sharding config of the journey classes, nil
+// for the server default. It is an interface, so that nil is left out of
+// the class instead of being sent as null.
+func shardingConfig() interface{} {
+	if journeyShards == 0 {
+		return nil
+	}
+	return map[string]interface{}{"desiredCount": journeyShards}
+}
+
+type nodeShards struct {
+	Nodes []struct {
+		Name   string `json:"name"`
+		Shards []struct {
+			Name  string `json:"name"`
+			Class string `json:"class"`
+		} `json:"shards"`
+	} `json:"nodes"`
+}
+
+// shardPlacement returns the nodes that hold each shard of a class
+func shardPlacement(body []byte, className string) (map[string][]string, error) {
+	var parsed nodeShards
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return nil, err
+	}
+
+	placement := map[string][]string{}
+	for _, node := range parsed.Nodes {
+		for _, shard := range node.Shards {
+			if shard.Class == className {
+				placement[shard.Name] = append(placement[shard.Name], node.Name)
+			}
+		}
+	}
+	return placement, nil
+}
+
+// checkShardPlacement checks after every hop, and so after every kill,
+// upgrade and repair, that the classes of the journey still have all of
+// their shards and that the shards are spread over as many nodes as there
+// are shards
+func checkShardPlacement(ctx context.Context, posOfVersion int) error {
+	if journeyShards == 0 {
+		return nil
+	}
+
+	body, err := getRaw(ctx, 0, "/v1/nodes?output=verbose")
+	if err != nil {
+		return fmt.Errorf("shard placement: %w", err)
+	}
+
+	for _, class := range journeyClasses() {
+		placement, err := shardPlacement(body, class.Class)
+		if err != nil {
+			return fmt.Errorf("shard placement: %w", err)
+		}
+
+		if err := checkSpread(placement, journeyShards, nodeCount); err != nil {
+			return fmt.Errorf("class %s on %s: %w", class.Class, versions[posOfVersion], err)
+		}
+		hopLogger(posOfVersion).Debug("shard placement", "class", class.Class, "placement", placement)
+	}
+	return nil
+}
+
+// checkSpread fails if shards are missing or if they share a node while
+// another node holds none of them
+func checkSpread(placement map[string][]string, shards, nodes int) error {
+	if len(placement) != shards {
+		return fmt.Errorf("has %d shards, want %d", len(placement), shards)
+	}
+
+	used := map[string]bool{}
+	for _, holders := range placement {
+		for _, node := range holders {
+			used[node] = true
+		}
+	}
+
+	want := shards
+	if nodes < want {
+		want = nodes
+	}
+	if len(used) < want {
+		return fmt.Errorf("shards are on %d nodes, want %d: %v", len(used), want, placement)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_shardPlacement(t *testing.T) {
+	body := []byte(`{"nodes":[
+		{"name":"weaviate-0","shards":[{"name":"s1","class":"Collection"},{"name":"r1","class":"RefTarget"}]},
+		{"name":"weaviate-1","shards":[{"name":"s2","class":"Collection"},{"name":"s1","class":"Collection"}]}
+	]}`)
+
+	got, err := shardPlacement(body, "Collection")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]string{"s1": {"weaviate-0", "weaviate-1"}, "s2": {"weaviate-1"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("shardPlacement() = %v, want %v", got, want)
+	}
+}
+
+func Test_checkSpread(t *testing.T) {
+	tests := []struct {
+		name      string
+		placement map[string][]string
+		shards    int
+		nodes     int
+		wantErr   bool
+	}{
+		{
+			name:      "one shard per node",
+			placement: map[string][]string{"a": {"n0"}, "b": {"n1"}, "c": {"n2"}},
+			shards:    3, nodes: 3,
+		},
+		{
+			name:      "more shards than nodes",
+			placement: map[string][]string{"a": {"n0"}, "b": {"n1"}, "c": {"n2"}, "d": {"n0"}, "e": {"n1"}, "f": {"n2"}},
+			shards:    6, nodes: 3,
+		},
+		{
+			name:      "missing shard",
+			placement: map[string][]string{"a": {"n0"}, "b": {"n1"}},
+			shards:    3, nodes: 3, wantErr: true,
+		},
+		{
+			name:      "shards on a single node",
+			placement: map[string][]string{"a": {"n0"}, "b": {"n0"}, "c": {"n0"}},
+			shards:    3, nodes: 3, wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		if err := checkSpread(tt.placement, tt.shards, tt.nodes); (err != nil) != tt.wantErr {
+			t.Errorf("%s: checkSpread() = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
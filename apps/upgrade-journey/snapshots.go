@@ -0,0 +1,469 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// expectedSchemaMutations lists flattened schema paths whose values are
+// allowed to change from one hop to the next. A trailing "*" matches any
+// suffix. Anything else that changes or disappears fails the run.
+var expectedSchemaMutations = []string{}
+
+// snapshotClusterMetadata dumps the schema, nodes and (for RAFT versions)
+// cluster statistics as returned by the server after a hop. The responses
+// are stored exactly as received, so that fields the client would not know
+// about are preserved. The schema and the status of the nodes and shards are
+// then compared against the snapshots of the previous hop.
+func snapshotClusterMetadata(ctx context.Context, posOfVersion int) error {
+	version := versions[posOfVersion]
+	dir := hopArtifactsDir(posOfVersion)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	endpoints := map[string]string{
+		"schema.json": "/v1/schema",
+		"nodes.json":  "/v1/nodes?output=verbose",
+	}
+	if isRaftVersion(version) {
+		endpoints["statistics.json"] = "/v1/cluster/statistics"
+	}
+
+	for file, endpoint := range endpoints {
+		body, err := getRaw(ctx, 0, endpoint)
+		if err != nil {
+			return fmt.Errorf("snapshot %s: %w", endpoint, err)
+		}
+
+		if err := os.WriteFile(path.Join(dir, file), body, 0o644); err != nil {
+			return err
+		}
+	}
+
+	if posOfVersion == 0 {
+		return nil
+	}
+
+	prevSchema, err := os.ReadFile(path.Join(hopArtifactsDir(posOfVersion-1), "schema.json"))
+	if err != nil {
+		return err
+	}
+
+	curSchema, err := os.ReadFile(path.Join(dir, "schema.json"))
+	if err != nil {
+		return err
+	}
+
+	diff, err := diffSnapshots(prevSchema, curSchema)
+	if err != nil {
+		return fmt.Errorf("diff schema snapshots: %w", err)
+	}
+
+	if err := os.WriteFile(path.Join(dir, "schema.diff.txt"), []byte(diff.String()), 0o644); err != nil {
+		return err
+	}
+
+	for _, added := range diff.added {
+		hopLogger(posOfVersion).Info("schema has a new field", "field", added)
+	}
+
+	if unexpected := diff.unexpected(expectedSchemaMutations); len(unexpected) > 0 {
+		return fmt.Errorf("schema changed unexpectedly from %s to %s:\n%s",
+			versions[posOfVersion-1], version, strings.Join(unexpected, "\n"))
+	}
+
+	return diffStatusSnapshots(posOfVersion)
+}
+
+// diffStatusSnapshots compares the status of the nodes, their shards and,
+// if both hops run RAFT, their raft members with the previous hop
+func diffStatusSnapshots(posOfVersion int) error {
+	prev, err := readStatusSnapshot(hopArtifactsDir(posOfVersion - 1))
+	if err != nil {
+		return err
+	}
+	cur, err := readStatusSnapshot(hopArtifactsDir(posOfVersion))
+	if err != nil {
+		return err
+	}
+
+	diff, unexpected := diffStatus(prev, cur)
+	if err := os.WriteFile(path.Join(hopArtifactsDir(posOfVersion), "status.diff.txt"),
+		[]byte(strings.Join(diff, "\n")), 0o644); err != nil {
+		return err
+	}
+
+	if len(unexpected) > 0 {
+		return fmt.Errorf("status changed unexpectedly from %s to %s:\n%s",
+			versions[posOfVersion-1], versions[posOfVersion], strings.Join(unexpected, "\n"))
+	}
+	return nil
+}
+
+// readStatusSnapshot reads the status of a hop from its nodes.json and, if
+// the hop ran RAFT, its statistics.json
+func readStatusSnapshot(dir string) (map[string]string, error) {
+	nodes, err := os.ReadFile(path.Join(dir, "nodes.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	statistics, err := os.ReadFile(path.Join(dir, "statistics.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		statistics = nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return statusSnapshot(nodes, statistics)
+}
+
+// statusSnapshot maps every node, shard and raft member to its status.
+// statistics is nil for versions without RAFT.
+func statusSnapshot(nodes, statistics []byte) (map[string]string, error) {
+	var parsed struct {
+		Nodes []struct {
+			Name   string `json:"name"`
+			Status string `json:"status"`
+			Shards []struct {
+				Name   string `json:"name"`
+				Class  string `json:"class"`
+				Status string `json:"vectorIndexingStatus"`
+			} `json:"shards"`
+		} `json:"nodes"`
+	}
+	if err := json.Unmarshal(nodes, &parsed); err != nil {
+		return nil, fmt.Errorf("parse nodes: %w", err)
+	}
+
+	out := map[string]string{}
+	for _, node := range parsed.Nodes {
+		out["node "+node.Name] = node.Status
+		for _, shard := range node.Shards {
+			out[fmt.Sprintf("shard %s/%s/%s", node.Name, shard.Class, shard.Name)] = shard.Status
+		}
+	}
+
+	if statistics == nil {
+		return out, nil
+	}
+
+	var stats clusterStatistics
+	if err := json.Unmarshal(statistics, &stats); err != nil {
+		return nil, fmt.Errorf("parse statistics: %w", err)
+	}
+	for _, stat := range stats.Statistics {
+		out["raft "+stat.Name] = fmt.Sprintf("%s ready=%t", stat.Status, stat.Ready)
+	}
+	out["raft synchronized"] = fmt.Sprint(stats.Synchronized)
+
+	return out, nil
+}
+
+// healthyStatus are the values of a status snapshot that a node, shard or
+// raft member has once it serves requests
+var healthyStatus = map[string]bool{
+	"HEALTHY":            true,
+	"READY":              true,
+	"INDEXING":           true,
+	"HEALTHY ready=true": true,
+	"true":               true,
+}
+
+// diffStatus lists every status that changed, appeared or disappeared from
+// one hop to the next. A status that was healthy and no longer is, or that
+// disappeared, is unexpected. Versions that don't report a status, such as
+// the indexing status of a shard before 1.22, report it empty, which is
+// never unexpected. Raft members are only compared if both hops run RAFT,
+// as the members appear with the migration.
+func diffStatus(prev, cur map[string]string) (diff, unexpected []string) {
+	raft := prev["raft synchronized"] != "" && cur["raft synchronized"] != ""
+
+	keys := map[string]interface{}{}
+	for key := range prev {
+		keys[key] = nil
+	}
+	for key := range cur {
+		keys[key] = nil
+	}
+
+	for _, key := range sortedKeys(keys) {
+		if strings.HasPrefix(key, "raft ") && !raft {
+			continue
+		}
+
+		was, inPrev := prev[key]
+		is, inCur := cur[key]
+		switch {
+		case !inPrev:
+			diff = append(diff, fmt.Sprintf("+ %s %s", key, is))
+		case !inCur:
+			line := fmt.Sprintf("- %s %s", key, was)
+			diff = append(diff, line)
+			unexpected = append(unexpected, line)
+		case was != is:
+			line := fmt.Sprintf("~ %s %s -> %s", key, was, is)
+			diff = append(diff, line)
+			if healthyStatus[was] && is != "" && !healthyStatus[is] {
+				unexpected = append(unexpected, line)
+			}
+		}
+	}
+	return diff, unexpected
+}
+
+func hopArtifactsDir(posOfVersion int) string {
+	return path.Join(artifactsDir, "hops", fmt.Sprintf("%02d-%s", posOfVersion, versions[posOfVersion]))
+}
+
+type snapshotDiff struct {
+	added   []string
+	removed []string
+	changed []string
+
+	// migrated are the changes of properties that the server moved to the
+	// successor of a deprecated data type, see deprecatedDataTypes
+	migrated []string
+}
+
+func (d snapshotDiff) String() string {
+	var sb strings.Builder
+	for _, line := range d.added {
+		fmt.Fprintf(&sb, "+ %s\n", line)
+	}
+	for _, line := range d.removed {
+		fmt.Fprintf(&sb, "- %s\n", line)
+	}
+	for _, line := range d.changed {
+		fmt.Fprintf(&sb, "~ %s\n", line)
+	}
+	for _, line := range d.migrated {
+		fmt.Fprintf(&sb, "~ %s (migrated data type)\n", line)
+	}
+	return sb.String()
+}
+
+// unexpected returns all removed and changed paths that are not covered by
+// the allow list. New fields are never considered unexpected, as new
+// versions regularly introduce new config options, and neither are the
+// changes of a migrated data type.
+func (d snapshotDiff) unexpected(allowed []string) []string {
+	var out []string
+	for _, line := range append(append([]string{}, d.removed...), d.changed...) {
+		if !matchesAnyPath(strings.SplitN(line, " ", 2)[0], allowed) {
+			out = append(out, line)
+		}
+	}
+	return out
+}
+
+func matchesAnyPath(p string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if strings.HasSuffix(pattern, "*") {
+			if strings.HasPrefix(p, strings.TrimSuffix(pattern, "*")) {
+				return true
+			}
+			continue
+		}
+		if p == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+func diffSnapshots(prev, cur []byte) (snapshotDiff, error) {
+	var diff snapshotDiff
+
+	var prevParsed, curParsed interface{}
+	if err := json.Unmarshal(prev, &prevParsed); err != nil {
+		return diff, err
+	}
+	if err := json.Unmarshal(cur, &curParsed); err != nil {
+		return diff, err
+	}
+
+	prevFlat, curFlat := map[string]interface{}{}, map[string]interface{}{}
+	flatten("", prevParsed, prevFlat)
+	flatten("", curParsed, curFlat)
+
+	// a property whose data type was migrated changes its tokenization with
+	// it, e.g. string with word tokenization becomes text with whitespace
+	migrated := map[string]bool{}
+	for p, prevValue := range prevFlat {
+		if curValue, ok := curFlat[p]; ok && replacedDataType(p, prevValue, curValue) {
+			migrated[p[:strings.LastIndex(p, ".dataType.")]] = true
+		}
+	}
+
+	for _, p := range sortedKeys(curFlat) {
+		prevValue, ok := prevFlat[p]
+		if !ok {
+			diff.added = append(diff.added, fmt.Sprintf("%s %v", p, curFlat[p]))
+			continue
+		}
+		if reflect.DeepEqual(prevValue, curFlat[p]) {
+			continue
+		}
+
+		line := fmt.Sprintf("%s %v -> %v", p, prevValue, curFlat[p])
+		if replacedDataType(p, prevValue, curFlat[p]) ||
+			(strings.HasSuffix(p, ".tokenization") && migrated[strings.TrimSuffix(p, ".tokenization")]) {
+			diff.migrated = append(diff.migrated, line)
+		} else {
+			diff.changed = append(diff.changed, line)
+		}
+	}
+
+	for _, p := range sortedKeys(prevFlat) {
+		if _, ok := curFlat[p]; !ok {
+			diff.removed = append(diff.removed, fmt.Sprintf("%s %v", p, prevFlat[p]))
+		}
+	}
+
+	return diff, nil
+}
+
+// flatten turns nested JSON into a map of dot-separated paths to leaf
+// values. Array elements that are objects with a "class" or "name" field are
+// keyed by that field instead of their position, so that classes and
+// properties can be returned in any order.
+func flatten(prefix string, in interface{}, out map[string]interface{}) {
+	switch typed := in.(type) {
+	case map[string]interface{}:
+		for key, value := range typed {
+			flatten(joinPath(prefix, key), value, out)
+		}
+	case []interface{}:
+		if len(typed) == 0 {
+			out[prefix] = "[]"
+			return
+		}
+		for i, value := range typed {
+			flatten(joinPath(prefix, arrayKey(i, value)), value, out)
+		}
+	default:
+		out[prefix] = typed
+	}
+}
+
+func arrayKey(pos int, value interface{}) string {
+	if obj, ok := value.(map[string]interface{}); ok {
+		for _, field := range []string{"class", "name"} {
+			if name, ok := obj[field].(string); ok {
+				return fmt.Sprintf("[%s]", name)
+			}
+		}
+	}
+
+	return fmt.Sprintf("[%d]", pos)
+}
+
+func joinPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+func sortedKeys(in map[string]interface{}) []string {
+	keys := make([]string, 0, len(in))
+	for key := range in {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// getRaw returns the unmodified response body of a GET request against a
+// single node
+func getRaw(ctx context.Context, nodeId int, endpoint string) ([]byte, error) {
+	reqCtx, cancel := requestContext(ctx)
+	defer cancel()
+
+	url := fmt.Sprintf("http://%s%s", nodeHost(nodeId), endpoint)
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	op := fmt.Sprintf("GET %s on node %d", endpoint, nodeId)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, requestError(ctx, op, err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, requestError(ctx, op, err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: status %d: %s", endpoint, res.StatusCode, body)
+	}
+
+	return body, nil
+}
+
+// postRaw sends payload as JSON and returns the body of a 2xx response
+func postRaw(ctx context.Context, nodeId int, endpoint string, payload interface{}) ([]byte, error) {
+	return sendRaw(ctx, http.MethodPost, nodeId, endpoint, payload)
+}
+
+func sendRaw(ctx context.Context, method string, nodeId int, endpoint string, payload interface{}) ([]byte, error) {
+	status, body, err := doRaw(ctx, method, nodeId, endpoint, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if status < 200 || status > 299 {
+		return nil, fmt.Errorf("%s %s: status %d: %s", method, endpoint, status, body)
+	}
+
+	return body, nil
+}
+
+// doRaw sends payload as JSON and returns the status and body of any
+// response, for requests that are expected to fail
+func doRaw(ctx context.Context, method string, nodeId int, endpoint string, payload interface{}) (int, []byte, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	reqCtx, cancel := requestContext(ctx)
+	defer cancel()
+
+	url := fmt.Sprintf("http://%s%s", nodeHost(nodeId), endpoint)
+	req, err := http.NewRequestWithContext(reqCtx, method, url, bytes.NewReader(raw))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	op := fmt.Sprintf("%s %s on node %d", method, endpoint, nodeId)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, nil, requestError(ctx, op, err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return 0, nil, requestError(ctx, op, err)
+	}
+
+	return res.StatusCode, body, nil
+}
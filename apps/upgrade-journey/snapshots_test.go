@@ -0,0 +1,193 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_diffSnapshots(t *testing.T) {
+	prev := []byte(`{"classes":[
+		{"class":"A","vectorIndexConfig":{"ef":-1},"properties":[{"name":"p1","dataType":["string"]},{"name":"p2","dataType":["int"]}]},
+		{"class":"B","vectorIndexConfig":{"ef":-1}}
+	]}`)
+
+	tests := []struct {
+		name           string
+		prev           []byte
+		cur            []byte
+		allowed        []string
+		wantAdded      []string
+		wantUnexpected []string
+		wantMigrated   []string
+	}{
+		{
+			name: "reordered classes and properties are identical",
+			cur: []byte(`{"classes":[
+				{"class":"B","vectorIndexConfig":{"ef":-1}},
+				{"class":"A","vectorIndexConfig":{"ef":-1},"properties":[{"name":"p2","dataType":["int"]},{"name":"p1","dataType":["string"]}]}
+			]}`),
+		},
+		{
+			name: "new fields are not unexpected",
+			cur: []byte(`{"classes":[
+				{"class":"A","vectorIndexConfig":{"ef":-1,"pq":{"enabled":false}},"properties":[{"name":"p1","dataType":["string"]},{"name":"p2","dataType":["int"]}]},
+				{"class":"B","vectorIndexConfig":{"ef":-1}}
+			]}`),
+			wantAdded: []string{"classes.[A].vectorIndexConfig.pq.enabled false"},
+		},
+		{
+			name: "changed defaults and removed properties are unexpected",
+			cur: []byte(`{"classes":[
+				{"class":"A","vectorIndexConfig":{"ef":100},"properties":[{"name":"p1","dataType":["string"]}]},
+				{"class":"B","vectorIndexConfig":{"ef":-1}}
+			]}`),
+			wantUnexpected: []string{
+				"classes.[A].properties.[p2].dataType.[0] int",
+				"classes.[A].properties.[p2].name p2",
+				"classes.[A].vectorIndexConfig.ef -1 -> 100",
+			},
+		},
+		{
+			name: "string migrated to text with its tokenization is expected",
+			cur: []byte(`{"classes":[
+				{"class":"A","vectorIndexConfig":{"ef":-1},"properties":[{"name":"p1","dataType":["text"],"tokenization":"whitespace"},{"name":"p2","dataType":["int"]}]},
+				{"class":"B","vectorIndexConfig":{"ef":-1}}
+			]}`),
+			wantAdded: []string{"classes.[A].properties.[p1].tokenization whitespace"},
+			wantMigrated: []string{
+				"classes.[A].properties.[p1].dataType.[0] string -> text",
+			},
+		},
+		{
+			name: "tokenization of a migrated property may change",
+			prev: []byte(`{"classes":[
+				{"class":"A","properties":[{"name":"p1","dataType":["string"],"tokenization":"word"},{"name":"p2","dataType":["int"],"tokenization":"word"}]}
+			]}`),
+			cur: []byte(`{"classes":[
+				{"class":"A","properties":[{"name":"p1","dataType":["text"],"tokenization":"whitespace"},{"name":"p2","dataType":["int"],"tokenization":"field"}]}
+			]}`),
+			wantUnexpected: []string{"classes.[A].properties.[p2].tokenization word -> field"},
+			wantMigrated: []string{
+				"classes.[A].properties.[p1].dataType.[0] string -> text",
+				"classes.[A].properties.[p1].tokenization word -> whitespace",
+			},
+		},
+		{
+			name: "allowed mutations are ignored",
+			cur: []byte(`{"classes":[
+				{"class":"A","vectorIndexConfig":{"ef":100},"properties":[{"name":"p1","dataType":["string"]},{"name":"p2","dataType":["int"]}]},
+				{"class":"B","vectorIndexConfig":{"ef":-1}}
+			]}`),
+			allowed: []string{"classes.[A].vectorIndexConfig.*"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from := prev
+			if tt.prev != nil {
+				from = tt.prev
+			}
+			diff, err := diffSnapshots(from, tt.cur)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(diff.added, tt.wantAdded) {
+				t.Errorf("added = %v, want %v", diff.added, tt.wantAdded)
+			}
+
+			if got := diff.unexpected(tt.allowed); !reflect.DeepEqual(got, tt.wantUnexpected) {
+				t.Errorf("unexpected = %v, want %v", got, tt.wantUnexpected)
+			}
+
+			if !reflect.DeepEqual(diff.migrated, tt.wantMigrated) {
+				t.Errorf("migrated = %v, want %v", diff.migrated, tt.wantMigrated)
+			}
+		})
+	}
+}
+
+func Test_diffStatus(t *testing.T) {
+	nodes := []byte(`{"nodes":[
+		{"name":"weaviate-0","status":"HEALTHY","shards":[{"name":"s0","class":"A","vectorIndexingStatus":"READY"}]},
+		{"name":"weaviate-1","status":"HEALTHY","shards":[{"name":"s1","class":"A","vectorIndexingStatus":"READY"}]}
+	]}`)
+	statistics := []byte(`{"statistics":[{"name":"weaviate-0","status":"HEALTHY","ready":true},` +
+		`{"name":"weaviate-1","status":"HEALTHY","ready":true}],"synchronized":true}`)
+
+	tests := []struct {
+		name           string
+		cur            []byte
+		curStatistics  []byte
+		prevStatistics []byte
+		wantDiff       []string
+		wantUnexpected []string
+	}{
+		{
+			name: "unchanged status",
+			cur:  nodes,
+		},
+		{
+			name: "readonly shard and unhealthy node are unexpected",
+			cur: []byte(`{"nodes":[
+				{"name":"weaviate-0","status":"HEALTHY","shards":[{"name":"s0","class":"A","vectorIndexingStatus":"READONLY"}]},
+				{"name":"weaviate-1","status":"UNHEALTHY","shards":[{"name":"s1","class":"A","vectorIndexingStatus":"READY"}]}
+			]}`),
+			wantDiff: []string{
+				"~ node weaviate-1 HEALTHY -> UNHEALTHY",
+				"~ shard weaviate-0/A/s0 READY -> READONLY",
+			},
+			wantUnexpected: []string{
+				"~ node weaviate-1 HEALTHY -> UNHEALTHY",
+				"~ shard weaviate-0/A/s0 READY -> READONLY",
+			},
+		},
+		{
+			name: "new shards are expected, lost shards are not",
+			cur: []byte(`{"nodes":[
+				{"name":"weaviate-0","status":"HEALTHY","shards":[{"name":"s0","class":"A","vectorIndexingStatus":"READY"},{"name":"s0","class":"B","vectorIndexingStatus":"READY"}]},
+				{"name":"weaviate-1","status":"HEALTHY"}
+			]}`),
+			wantDiff: []string{
+				"+ shard weaviate-0/B/s0 READY",
+				"- shard weaviate-1/A/s1 READY",
+			},
+			wantUnexpected: []string{"- shard weaviate-1/A/s1 READY"},
+		},
+		{
+			name:          "raft members appear with the migration",
+			cur:           nodes,
+			curStatistics: statistics,
+		},
+		{
+			name:           "raft member that is no longer ready is unexpected",
+			cur:            nodes,
+			prevStatistics: statistics,
+			curStatistics: []byte(`{"statistics":[{"name":"weaviate-0","status":"HEALTHY","ready":true},` +
+				`{"name":"weaviate-1","status":"HEALTHY","ready":false}],"synchronized":true}`),
+			wantDiff:       []string{"~ raft weaviate-1 HEALTHY ready=true -> HEALTHY ready=false"},
+			wantUnexpected: []string{"~ raft weaviate-1 HEALTHY ready=true -> HEALTHY ready=false"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prev, err := statusSnapshot(nodes, tt.prevStatistics)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cur, err := statusSnapshot(tt.cur, tt.curStatistics)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			diff, unexpected := diffStatus(prev, cur)
+			if !reflect.DeepEqual(diff, tt.wantDiff) {
+				t.Errorf("diff: wanted %v, got %v", tt.wantDiff, diff)
+			}
+			if !reflect.DeepEqual(unexpected, tt.wantUnexpected) {
+				t.Errorf("unexpected: wanted %v, got %v", tt.wantUnexpected, unexpected)
+			}
+		})
+	}
+}
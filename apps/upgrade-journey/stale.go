@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/testcontainers/testcontainers-go"
+	wcluster "github.com/weaviate/weaviate-chaos-engineering/apps/upgrade-journey/pkg/cluster"
+)
+
+// staleSnapshot is a copy of a node's data directory taken on an earlier hop
+type staleSnapshot struct {
+	hop     int
+	version string
+}
+
+// staleFault brings a node back with an old copy of its data directory, as
+// if a volume snapshot from hours ago had been restored. The first time the
+// fault runs it only takes the snapshot. Once the snapshot is at least
+// minAge hops old, the node is started on it and has to either catch up
+// with its peers or refuse to start. Afterwards the current data is put back,
+// so that the journey continues with an intact cluster.
+type staleFault struct {
+	node   int
+	minAge int
+
+	// restored is set if inject replaced the data directory
+	restored *staleSnapshot
+}
+
+func (f *staleFault) Describe() string {
+	return fmt.Sprintf("restart weaviate-%d on a data directory at least %d hops old", f.node, f.minAge)
+}
+
+func (c *cluster) stalePath(nodeId int, kind string) string {
+	return path.Join(c.RootDir, "data", "stale", fmt.Sprintf("%s-%s", c.Hostname(nodeId), kind))
+}
+
+func (f *staleFault) Inject(ctx context.Context, c *cluster) error {
+	hop := runProgress.currentHop()
+	snapshot, ok := c.staleSnapshots[f.node]
+	if ok && hop-snapshot.hop < f.minAge {
+		logger.Info("stale snapshot is not old enough yet", "node", c.Hostname(f.node),
+			"snapshot_hop", snapshot.hop)
+		return nil
+	}
+
+	if err := c.StopNode(ctx, f.node); err != nil {
+		return err
+	}
+
+	if !ok {
+		if err := c.copyData(ctx, c.VolumePath(f.node), c.stalePath(f.node, "snapshot")); err != nil {
+			return err
+		}
+		c.staleSnapshots[f.node] = staleSnapshot{hop: hop, version: c.NodeVersions[f.node]}
+		logger.Info("took stale snapshot", "node", c.Hostname(f.node), "hop", hop)
+		return c.StartStoppedNode(ctx, f.node, c.NodeVersions[f.node])
+	}
+
+	if err := c.copyData(ctx, c.VolumePath(f.node), c.stalePath(f.node, "current")); err != nil {
+		return err
+	}
+	if err := c.copyData(ctx, c.stalePath(f.node, "snapshot"), c.VolumePath(f.node)); err != nil {
+		return err
+	}
+
+	f.restored = &snapshot
+	delete(c.staleSnapshots, f.node)
+	return nil
+}
+
+func (f *staleFault) Heal(ctx context.Context, c *cluster) error {
+	if f.restored == nil {
+		return nil
+	}
+
+	start := time.Now()
+	outcome, err := f.startOnStaleData(ctx, c)
+	runReport.recordFault(runProgress.currentHop(), fmt.Sprintf("weaviate-%d on data from hop %d (%s): %s",
+		f.node, f.restored.hop, f.restored.version, outcome), start, err)
+	if err != nil {
+		return err
+	}
+
+	// put back the current data, the journey continues with it
+	if err := c.StopNode(ctx, f.node); err != nil {
+		return err
+	}
+	if err := c.copyData(ctx, c.stalePath(f.node, "current"), c.VolumePath(f.node)); err != nil {
+		return err
+	}
+	return c.StartStoppedNode(ctx, f.node, c.NodeVersions[f.node])
+}
+
+// startOnStaleData starts the node on the restored data. Both a node that
+// catches up with its peers and a node that refuses to start with an error
+// are correct. A node that starts, but keeps serving a diverging schema,
+// is not.
+func (f *staleFault) startOnStaleData(ctx context.Context, c *cluster) (string, error) {
+	version := c.NodeVersions[f.node]
+	container, err := c.StartNode(ctx, f.node, version)
+	if container != nil {
+		c.Containers[f.node] = container
+	}
+	if err != nil {
+		if container == nil {
+			return "", err
+		}
+
+		state, stateErr := container.State(ctx)
+		if stateErr == nil && !state.Running && state.ExitCode != 0 {
+			logger.Info("node refused to start on stale data", "node", c.Hostname(f.node),
+				"exit_code", state.ExitCode)
+			return "fenced", nil
+		}
+
+		c.DumpContainerLogs(container)
+		return "", fmt.Errorf("node neither started nor refused to start on stale data: %w", err)
+	}
+
+	if err := waitForSchemaConvergence(ctx, c.nodeClient(0), c.nodeClient(f.node),
+		time.Minute); err != nil {
+		return "", fmt.Errorf("stale node did not catch up: %w", err)
+	}
+
+	if isRaftVersion(version) {
+		if err := waitForRaftSynchronized(ctx, c, time.Minute); err != nil {
+			return "", fmt.Errorf("stale node did not catch up: %w", err)
+		}
+	}
+
+	if replicatedIds != nil {
+		if err := resyncReplicas(ctx, c, f.node); err != nil {
+			return "", fmt.Errorf("stale node did not catch up: %w", err)
+		}
+	}
+
+	return "caught up", nil
+}
+
+// copyData replaces the content of dst with the content of src. The files
+// are owned by the user inside the Weaviate container, so the copy happens
+// in a helper container.
+func (c *cluster) copyData(ctx context.Context, src, dst string) error {
+	if err := os.MkdirAll(dst, 0o777); err != nil {
+		return err
+	}
+
+	_, err := wcluster.RunToCompletion(ctx, testcontainers.ContainerRequest{
+		Image: "alpine:3",
+		Cmd:   []string{"sh", "-c", "find /dst -mindepth 1 -delete && cp -a /src/. /dst/"},
+		Mounts: testcontainers.Mounts(
+			testcontainers.BindMount(src, "/src"),
+			testcontainers.BindMount(dst, "/dst"),
+		),
+		Labels: runLabels(),
+	})
+	if err != nil {
+		return fmt.Errorf("copy %s to %s: %w", src, dst, err)
+	}
+
+	return nil
+}
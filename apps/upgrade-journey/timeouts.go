@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// requestTimeout is set by --request-timeout. Every single request to a
+// node gets its own deadline, so that a hung node fails the request with
+// an error that names it, instead of using up the budget of the whole
+// phase. 0 leaves requests to the deadline of their phase.
+var requestTimeout time.Duration
+
+// requestContext bounds a single request to a node
+func requestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if requestTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, requestTimeout)
+}
+
+// requestError names the request that ran into its own deadline. ctx is the
+// context the request was derived from, an error because ctx itself is
+// done is returned as it is.
+func requestError(ctx context.Context, op string, err error) error {
+	if err == nil || ctx.Err() != nil || !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	return fmt.Errorf("%s timed out after %s: %w", op, requestTimeout, err)
+}
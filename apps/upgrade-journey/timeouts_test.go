@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRequestError(t *testing.T) {
+	requestTimeout = 5 * time.Second
+	defer func() { requestTimeout = 0 }()
+
+	done, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want string
+	}{
+		{name: "no error", ctx: context.Background(), err: nil, want: ""},
+		{name: "other error", ctx: context.Background(), err: errors.New("refused"), want: "refused"},
+		{
+			name: "own deadline", ctx: context.Background(), err: context.DeadlineExceeded,
+			want: "GET /v1/nodes on node 1 timed out after 5s: context deadline exceeded",
+		},
+		{name: "parent done", ctx: done, err: context.DeadlineExceeded, want: "context deadline exceeded"},
+	}
+
+	for _, tt := range tests {
+		got := ""
+		if err := requestError(tt.ctx, "GET /v1/nodes on node 1", tt.err); err != nil {
+			got = err.Error()
+		}
+		if got != tt.want {
+			t.Errorf("%s: wanted %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
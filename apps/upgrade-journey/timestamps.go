@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// restoreTimestampSample is the number of objects of the restore class
+// whose timestamps are compared after every restore
+const restoreTimestampSample = 100
+
+// objectTimestamps are the timestamps Weaviate stores for an object, in
+// milliseconds
+type objectTimestamps struct {
+	created int64
+	updated int64
+}
+
+// timestampTracker remembers the timestamps of objects that are never
+// written to after their creation, the first time they are seen. Upgrades,
+// restarts, repairs and restores must preserve them to the millisecond.
+type timestampTracker struct {
+	sync.Mutex
+	first map[string]map[string]objectTimestamps
+}
+
+var preservedTimestamps = &timestampTracker{first: map[string]map[string]objectTimestamps{}}
+
+// compare records the timestamps of objects of class that were not seen
+// before and returns every object whose timestamps changed since, sorted by
+// id
+func (t *timestampTracker) compare(class string, stored map[string]objectTimestamps) []string {
+	t.Lock()
+	defer t.Unlock()
+
+	first, ok := t.first[class]
+	if !ok {
+		first = map[string]objectTimestamps{}
+		t.first[class] = first
+	}
+
+	var changed []string
+	for id, ts := range stored {
+		seen, ok := first[id]
+		if !ok {
+			first[id] = ts
+			continue
+		}
+
+		if ts.created != seen.created {
+			changed = append(changed, fmt.Sprintf("%s/%s: creationTimeUnix changed from %d to %d", class, id,
+				seen.created, ts.created))
+		}
+		if ts.updated != seen.updated {
+			changed = append(changed, fmt.Sprintf("%s/%s: lastUpdateTimeUnix changed from %d to %d", class, id,
+				seen.updated, ts.updated))
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// ids returns up to n ids of class whose timestamps were recorded, sorted
+func (t *timestampTracker) ids(class string, n int) []string {
+	t.Lock()
+	defer t.Unlock()
+
+	ids := make([]string, 0, len(t.first[class]))
+	for id := range t.first[class] {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	if len(ids) > n {
+		ids = ids[:n]
+	}
+	return ids
+}
+
+// check lists the objects of class on a node and compares their timestamps
+// with the recorded ones
+func (t *timestampTracker) check(ctx context.Context, nodeId int, class string) error {
+	raw, err := getRaw(ctx, nodeId, fmt.Sprintf("/v1/objects?class=%s&limit=10000", class))
+	if err != nil {
+		return fmt.Errorf("list %s: %w", class, err)
+	}
+
+	stored, err := parseObjectTimestamps(raw)
+	if err != nil {
+		return err
+	}
+	return changedTimestamps(t.compare(class, stored))
+}
+
+// checkIds fetches single objects of class from a node, query is appended
+// to every request, e.g. to read the copy of a replica
+func (t *timestampTracker) checkIds(ctx context.Context, nodeId int, class string, ids []string,
+	query string,
+) error {
+	stored := make(map[string]objectTimestamps, len(ids))
+	for _, id := range ids {
+		raw, err := getRaw(ctx, nodeId, fmt.Sprintf("/v1/objects/%s/%s%s", class, id, query))
+		if err != nil {
+			return fmt.Errorf("get %s/%s: %w", class, id, err)
+		}
+
+		var obj struct {
+			CreationTimeUnix   int64 `json:"creationTimeUnix"`
+			LastUpdateTimeUnix int64 `json:"lastUpdateTimeUnix"`
+		}
+		if err := json.Unmarshal(raw, &obj); err != nil {
+			return err
+		}
+		stored[id] = objectTimestamps{created: obj.CreationTimeUnix, updated: obj.LastUpdateTimeUnix}
+	}
+	return changedTimestamps(t.compare(class, stored))
+}
+
+func changedTimestamps(changed []string) error {
+	if len(changed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d object timestamps were not preserved:\n%s", len(changed), strings.Join(changed, "\n"))
+}
+
+// parseObjectTimestamps returns the timestamps of every object of a list
+// response by id
+func parseObjectTimestamps(raw []byte) (map[string]objectTimestamps, error) {
+	var res struct {
+		Objects []struct {
+			ID                 string `json:"id"`
+			CreationTimeUnix   int64  `json:"creationTimeUnix"`
+			LastUpdateTimeUnix int64  `json:"lastUpdateTimeUnix"`
+		} `json:"objects"`
+	}
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return nil, err
+	}
+
+	stored := make(map[string]objectTimestamps, len(res.Objects))
+	for _, obj := range res.Objects {
+		stored[obj.ID] = objectTimestamps{created: obj.CreationTimeUnix, updated: obj.LastUpdateTimeUnix}
+	}
+	return stored, nil
+}
+
+// timestampVerifier checks that the timestamps of the journey classes and
+// the replicated class never change once the objects are written
+type timestampVerifier struct{}
+
+func (v *timestampVerifier) name() string {
+	return "timestamps"
+}
+
+func (v *timestampVerifier) verify(ctx context.Context, posOfMaxVersion int) error {
+	classes := journeyClassNames()
+	if replicatedIds != nil {
+		classes = append(classes, replicatedClass)
+	}
+
+	for _, class := range classes {
+		if err := preservedTimestamps.check(ctx, 0, class); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+)
+
+func Test_timestampTracker(t *testing.T) {
+	tracker := &timestampTracker{first: map[string]map[string]objectTimestamps{}}
+
+	if changed := tracker.compare("Collection", map[string]objectTimestamps{
+		"a": {created: 100, updated: 100},
+		"b": {created: 200, updated: 300},
+	}); len(changed) != 0 {
+		t.Fatalf("first compare() = %v, want nothing", changed)
+	}
+
+	changed := tracker.compare("Collection", map[string]objectTimestamps{
+		"a": {created: 100, updated: 100},
+		"b": {created: 201, updated: 301},
+		"c": {created: 400, updated: 400},
+	})
+	if len(changed) != 2 {
+		t.Errorf("compare() = %v, want both timestamps of b", changed)
+	}
+
+	if changed := tracker.compare("Collection", map[string]objectTimestamps{"c": {created: 400, updated: 400}}); len(changed) != 0 {
+		t.Errorf("compare() = %v, want c recorded on its first sighting", changed)
+	}
+
+	if ids := tracker.ids("Collection", 2); len(ids) != 2 || ids[0] != "a" || ids[1] != "b" {
+		t.Errorf("ids() = %v, want [a b]", ids)
+	}
+}
+
+func Test_parseObjectTimestamps(t *testing.T) {
+	raw := []byte(`{"objects":[{"id":"a","creationTimeUnix":1,"lastUpdateTimeUnix":2}]}`)
+
+	got, err := parseObjectTimestamps(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["a"] != (objectTimestamps{created: 1, updated: 2}) {
+		t.Errorf("parseObjectTimestamps() = %v", got)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+
+	"github.com/weaviate/weaviate-chaos-engineering/apps/upgrade-journey/pkg/workloads"
+)
+
+// withTokenizationMatrix is set by --tokenization-matrix
+var withTokenizationMatrix bool
+
+// tokenizations is nil unless --tokenization-matrix is set
+var tokenizations *workloads.Tokenization
+
+// supportedTokenizations are the tokenizations of a text property that the
+// version can create
+func supportedTokenizations(version string) []string {
+	if !supports(version, featureTokenization) {
+		return []string{"word"}
+	}
+	out := []string{"word", "lowercase", "whitespace", "field"}
+	if supports(version, featureTrigram) {
+		out = append(out, "trigram")
+	}
+	return out
+}
+
+// importTokenizations creates the classes of the tokenizations the version
+// introduced and writes the samples of the hop to all of them
+func importTokenizations(ctx context.Context, posOfVersion int) error {
+	if tokenizations == nil {
+		return nil
+	}
+
+	version := versions[posOfVersion]
+	if err := tokenizations.CreateClasses(ctx, nodeHost(0), version, supportedTokenizations(version)); err != nil {
+		return err
+	}
+	return tokenizations.Import(ctx, nodeHost(0), posOfVersion)
+}
+
+// tokenizationVerifier checks that filters and BM25 searches on every
+// tokenization match the same samples as on the version that created it
+type tokenizationVerifier struct{}
+
+func (v *tokenizationVerifier) name() string {
+	return "tokenization"
+}
+
+func (v *tokenizationVerifier) verify(ctx context.Context, posOfMaxVersion int) error {
+	return tokenizations.Verify(ctx, nodeHost(0), supports(versions[posOfMaxVersion], featureHybrid))
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_supportedTokenizations(t *testing.T) {
+	tests := []struct {
+		version string
+		want    []string
+	}{
+		{version: "1.18.3", want: []string{"word"}},
+		{version: "1.19.0", want: []string{"word", "lowercase", "whitespace", "field"}},
+		{version: "1.24.0", want: []string{"word", "lowercase", "whitespace", "field", "trigram"}},
+	}
+	for _, tt := range tests {
+		if got := supportedTokenizations(tt.version); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("supportedTokenizations(%s) = %v, want %v", tt.version, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	wcluster "github.com/weaviate/weaviate-chaos-engineering/apps/upgrade-journey/pkg/cluster"
+)
+
+// clusterTopology is set from --raft-voters, --zones and --node-env
+var clusterTopology wcluster.Topology
+
+// parseTopology builds the topology from the flags. nodeEnv holds
+// semicolon-separated node:KEY=VALUE entries, e.g.
+// "2:LOG_LEVEL=debug;2:GOMEMLIMIT=1GiB".
+func parseTopology(voters int, zones, nodeEnv string) (wcluster.Topology, error) {
+	topology := wcluster.Topology{Voters: voters, Zones: splitList(zones)}
+
+	for _, entry := range strings.Split(nodeEnv, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+
+		rawNode, kv, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok {
+			return wcluster.Topology{}, fmt.Errorf("node env %q: must be node:KEY=VALUE", entry)
+		}
+		node, err := strconv.Atoi(rawNode)
+		if err != nil {
+			return wcluster.Topology{}, fmt.Errorf("node env %q: invalid node %q", entry, rawNode)
+		}
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || key == "" {
+			return wcluster.Topology{}, fmt.Errorf("node env %q: must be node:KEY=VALUE", entry)
+		}
+
+		if topology.Env == nil {
+			topology.Env = map[int]map[string]string{}
+		}
+		if topology.Env[node] == nil {
+			topology.Env[node] = map[string]string{}
+		}
+		topology.Env[node][key] = value
+	}
+
+	return topology, nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTopology(t *testing.T) {
+	topology, err := parseTopology(3, "a, b,a", "2:LOG_LEVEL=debug; 2:GOMEMLIMIT=1GiB;0:QUERY_MAXIMUM_RESULTS=100")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if topology.Voters != 3 {
+		t.Errorf("voters: wanted 3, got %d", topology.Voters)
+	}
+	if want := []string{"a", "b", "a"}; !reflect.DeepEqual(topology.Zones, want) {
+		t.Errorf("zones: wanted %v, got %v", want, topology.Zones)
+	}
+	want := map[int]map[string]string{
+		0: {"QUERY_MAXIMUM_RESULTS": "100"},
+		2: {"LOG_LEVEL": "debug", "GOMEMLIMIT": "1GiB"},
+	}
+	if !reflect.DeepEqual(topology.Env, want) {
+		t.Errorf("env: wanted %v, got %v", want, topology.Env)
+	}
+
+	for _, nodeEnv := range []string{"LOG_LEVEL=debug", "x:LOG_LEVEL=debug", "1:LOG_LEVEL"} {
+		if _, err := parseTopology(0, "", nodeEnv); err == nil {
+			t.Errorf("%q: expected an error", nodeEnv)
+		}
+	}
+}
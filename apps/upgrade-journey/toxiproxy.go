@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"github.com/weaviate/weaviate-chaos-engineering/apps/upgrade-journey/pkg/faults"
+)
+
+// With --toxiproxy all traffic from this harness to the nodes goes through a
+// Toxiproxy container, which allows injecting faults per client-to-node link.
+// Links between the nodes can't be proxied: peers connect to the addresses
+// the other nodes advertise through gossip, not to a configured address.
+// Use the latency and partition faults for those.
+const (
+	toxiproxyImage    = "ghcr.io/shopify/toxiproxy:2.5.0"
+	toxiproxyAPIPort  = 8474
+	proxiedClientPort = 18080
+)
+
+// useToxiproxy is set from the flags in main
+var useToxiproxy bool
+
+func (c *cluster) startToxiproxy(ctx context.Context) error {
+	ports := []string{fmt.Sprintf("%d:%d", toxiproxyAPIPort+portOffset, toxiproxyAPIPort)}
+	for i := 0; i < c.NodeCount; i++ {
+		ports = append(ports, fmt.Sprintf("%d:%d", proxiedClientPort+portOffset+i, proxiedClientPort+i))
+	}
+
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		Logger: testcontainersLogger{},
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        toxiproxyImage,
+			Networks:     []string{c.NetworkName},
+			ExposedPorts: ports,
+			Labels:       c.Labels(),
+			WaitingFor: wait.ForHTTP("/version").
+				WithPort(nat.Port(strconv.Itoa(toxiproxyAPIPort))).
+				WithStartupTimeout(30 * time.Second),
+		},
+		Started: true,
+	})
+	if err != nil {
+		return fmt.Errorf("start toxiproxy: %w", err)
+	}
+	c.AddSidecar(container)
+
+	for i := 0; i < c.NodeCount; i++ {
+		// the upstream is only resolved once a client connects, so the
+		// proxies can be created before the nodes are started
+		if err := toxiproxyAPI(ctx, http.MethodPost, "/proxies", map[string]interface{}{
+			"name":     clientProxy(i),
+			"listen":   fmt.Sprintf("0.0.0.0:%d", proxiedClientPort+i),
+			"upstream": fmt.Sprintf("%s:8080", c.Hostname(i)),
+			"enabled":  true,
+		}); err != nil {
+			return fmt.Errorf("create proxy for node %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+func clientProxy(nodeId int) string {
+	return fmt.Sprintf("client-weaviate-%d", nodeId)
+}
+
+func toxiproxyAPI(ctx context.Context, method, endpoint string, payload interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		raw, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(raw)
+	}
+
+	reqCtx, cancel := requestContext(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, method,
+		fmt.Sprintf("http://localhost:%d%s", toxiproxyAPIPort+portOffset, endpoint), body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return requestError(ctx, fmt.Sprintf("toxiproxy %s %s", method, endpoint), err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		msg, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("%s %s: status %d: %s", method, endpoint, res.StatusCode, msg)
+	}
+
+	return nil
+}
+
+// toxicTypes are the toxics Toxiproxy ships with
+var toxicTypes = map[string]bool{
+	"latency":    true,
+	"bandwidth":  true,
+	"slow_close": true,
+	"timeout":    true,
+	"reset_peer": true,
+	"slicer":     true,
+	"limit_data": true,
+}
+
+// toxicFault adds a toxic to the link between this harness and a node. Any
+// parameter other than node, type and stream is passed to the toxic as an
+// attribute, e.g. "toxic:node=1,type=latency,latency=500,jitter=100".
+type toxicFault struct {
+	node       int
+	toxicType  string
+	stream     string
+	attributes map[string]int
+}
+
+func newToxicFault(p faults.Params) (faultInjector, error) {
+	node, err := p.Node(nodeCount)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &toxicFault{
+		node:       node,
+		toxicType:  p.String("type", "latency"),
+		stream:     p.String("stream", "downstream"),
+		attributes: map[string]int{},
+	}
+	if !toxicTypes[f.toxicType] {
+		return nil, fmt.Errorf("unknown toxic type %q", f.toxicType)
+	}
+	if f.stream != "upstream" && f.stream != "downstream" {
+		return nil, fmt.Errorf("stream must be upstream or downstream")
+	}
+
+	for key, value := range p {
+		if key == "node" || key == "type" || key == "stream" {
+			continue
+		}
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("attribute %s must be an integer", key)
+		}
+		f.attributes[key] = parsed
+	}
+
+	return f, nil
+}
+
+func (f *toxicFault) Describe() string {
+	return fmt.Sprintf("%s toxic on %s link to weaviate-%d %v", f.toxicType, f.stream, f.node, f.attributes)
+}
+
+func (f *toxicFault) name() string {
+	return fmt.Sprintf("%s-%s", f.toxicType, f.stream)
+}
+
+func (f *toxicFault) Inject(ctx context.Context, c *cluster) error {
+	if !useToxiproxy {
+		return fmt.Errorf("toxics require --toxiproxy")
+	}
+
+	return toxiproxyAPI(ctx, http.MethodPost, fmt.Sprintf("/proxies/%s/toxics", clientProxy(f.node)),
+		map[string]interface{}{
+			"name":       f.name(),
+			"type":       f.toxicType,
+			"stream":     f.stream,
+			"toxicity":   1.0,
+			"attributes": f.attributes,
+		})
+}
+
+func (f *toxicFault) Heal(ctx context.Context, c *cluster) error {
+	if !useToxiproxy {
+		return nil
+	}
+
+	return toxiproxyAPI(ctx, http.MethodDelete,
+		fmt.Sprintf("/proxies/%s/toxics/%s", clientProxy(f.node), f.name()), nil)
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// The tuning scenario changes the mutable settings of an HNSW index between
+// hops. The settings of a hop must be reported by every node after the
+// following rolling update, and searches must keep finding the exact
+// neighbor with them. Its class is not part of the config drift baseline,
+// as its config changes on purpose.
+var withIndexTuning bool
+
+const (
+	tunedClass   = "TunedIndex"
+	tunedObjects = 500
+	tunedDims    = 32
+)
+
+// tuningForHop returns the mutable settings applied after the hop, they
+// differ from hop to hop and from the server defaults
+func tuningForHop(posOfVersion int) map[string]int {
+	return map[string]int{
+		"ef":                    48 + 16*posOfVersion,
+		"dynamicEfMin":          80 + 10*posOfVersion,
+		"dynamicEfMax":          400 + 50*posOfVersion,
+		"vectorCacheMaxObjects": 100000 + 1000*posOfVersion,
+	}
+}
+
+// tunedSchemaMutations are the schema paths the scenario changes from hop
+// to hop
+func tunedSchemaMutations() []string {
+	var paths []string
+	for setting := range tuningForHop(0) {
+		paths = append(paths, fmt.Sprintf("classes.[%s].vectorIndexConfig.%s", tunedClass, setting))
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// immutableTunings are settings of the index that can't be changed once the
+// class exists. Changing them must fail and leave them as they are.
+var immutableTunings = map[string]int{
+	"efConstruction": 256,
+	"maxConnections": 16,
+}
+
+// createTunedClass creates the class with the immutable settings and
+// imports vectors to search for
+func createTunedClass(ctx context.Context) error {
+	indexConfig := map[string]interface{}{}
+	for setting, value := range immutableTunings {
+		indexConfig[setting] = value
+	}
+
+	if _, err := postRaw(ctx, 0, "/v1/schema", map[string]interface{}{
+		"class":             tunedClass,
+		"vectorizer":        "none",
+		"vectorIndexType":   "hnsw",
+		"vectorIndexConfig": indexConfig,
+		"properties": []map[string]interface{}{
+			{"name": "index", "dataType": []string{"int"}},
+		},
+	}); err != nil {
+		return fmt.Errorf("create class %s: %w", tunedClass, err)
+	}
+
+	objects := make([]map[string]interface{}, 0, tunedObjects)
+	for i := 0; i < tunedObjects; i++ {
+		vector := make([]float32, tunedDims)
+		for d := range vector {
+			vector[d] = rand.Float32()
+		}
+		objects = append(objects, map[string]interface{}{
+			"class":      tunedClass,
+			"id":         uuid.New().String(),
+			"vector":     vector,
+			"properties": map[string]interface{}{"index": i},
+		})
+	}
+
+	if _, err := postRaw(ctx, 0, "/v1/batch/objects", map[string]interface{}{"objects": objects}); err != nil {
+		return fmt.Errorf("import %s: %w", tunedClass, err)
+	}
+	return nil
+}
+
+// checkIndexTuning checks the settings applied after the previous hop on
+// every node and applies the ones of this hop, except after the last hop,
+// which has no upgrade left to check them after
+func (c *cluster) checkIndexTuning(ctx context.Context, posOfVersion int) error {
+	if posOfVersion > 0 {
+		if err := c.checkTuningOnNodes(ctx, tuningForHop(posOfVersion-1)); err != nil {
+			return fmt.Errorf("settings of %s after the upgrade to %s: %w", tunedClass, versions[posOfVersion], err)
+		}
+		if err := checkTunedSearch(ctx); err != nil {
+			return err
+		}
+	}
+
+	if posOfVersion == len(versions)-1 {
+		return nil
+	}
+
+	if err := updateIndexConfig(ctx, immutableTunings, 2); err == nil {
+		return fmt.Errorf("changing the immutable settings of %s on %s succeeded", tunedClass,
+			versions[posOfVersion])
+	}
+
+	tuning := tuningForHop(posOfVersion)
+	if err := updateIndexConfig(ctx, tuning, 1); err != nil {
+		return err
+	}
+	hopLogger(posOfVersion).Info("tuned vector index", "class", tunedClass, "settings", tuning)
+
+	if err := c.checkTuningOnNodes(ctx, tuning); err != nil {
+		return fmt.Errorf("settings of %s right after the change: %w", tunedClass, err)
+	}
+	return checkTunedSearch(ctx)
+}
+
+// updateIndexConfig sets settings to their value multiplied by factor
+func updateIndexConfig(ctx context.Context, settings map[string]int, factor int) error {
+	body, err := getRaw(ctx, 0, "/v1/schema/"+tunedClass)
+	if err != nil {
+		return fmt.Errorf("get class %s: %w", tunedClass, err)
+	}
+
+	var class map[string]interface{}
+	if err := json.Unmarshal(body, &class); err != nil {
+		return err
+	}
+	indexConfig, ok := class["vectorIndexConfig"].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("class %s has no vector index config", tunedClass)
+	}
+	for setting, value := range settings {
+		indexConfig[setting] = value * factor
+	}
+
+	if _, err := sendRaw(ctx, http.MethodPut, 0, "/v1/schema/"+tunedClass, class); err != nil {
+		return fmt.Errorf("update class %s: %w", tunedClass, err)
+	}
+	return nil
+}
+
+func (c *cluster) checkTuningOnNodes(ctx context.Context, tuning map[string]int) error {
+	want := map[string]int{}
+	for setting, value := range tuning {
+		want[setting] = value
+	}
+	for setting, value := range immutableTunings {
+		want[setting] = value
+	}
+
+	for i := 0; i < c.NodeCount; i++ {
+		body, err := getRaw(ctx, i, "/v1/schema/"+tunedClass)
+		if err != nil {
+			return fmt.Errorf("%s: %w", c.Hostname(i), err)
+		}
+		if wrong, err := diffTuning(body, want); err != nil {
+			return fmt.Errorf("%s: %w", c.Hostname(i), err)
+		} else if len(wrong) > 0 {
+			return fmt.Errorf("%s: %s", c.Hostname(i), strings.Join(wrong, ", "))
+		}
+	}
+	return nil
+}
+
+// diffTuning returns the settings of the vector index of a class that don't
+// have the wanted value
+func diffTuning(class []byte, want map[string]int) ([]string, error) {
+	var parsed struct {
+		VectorIndexConfig map[string]interface{} `json:"vectorIndexConfig"`
+	}
+	if err := json.Unmarshal(class, &parsed); err != nil {
+		return nil, err
+	}
+
+	var wrong []string
+	for setting, value := range want {
+		got, ok := parsed.VectorIndexConfig[setting].(float64)
+		if !ok || int(got) != value {
+			wrong = append(wrong, fmt.Sprintf("%s is %v, want %d", setting,
+				parsed.VectorIndexConfig[setting], value))
+		}
+	}
+	sort.Strings(wrong)
+	return wrong, nil
+}
+
+// checkTunedSearch searches for the vectors of a few objects, which must
+// be found as their own nearest neighbor
+func checkTunedSearch(ctx context.Context) error {
+	body, err := getRaw(ctx, 0, fmt.Sprintf("/v1/objects?class=%s&limit=10&include=vector", tunedClass))
+	if err != nil {
+		return fmt.Errorf("list %s: %w", tunedClass, err)
+	}
+
+	var listed struct {
+		Objects []struct {
+			ID     string    `json:"id"`
+			Vector []float32 `json:"vector"`
+		} `json:"objects"`
+	}
+	if err := json.Unmarshal(body, &listed); err != nil {
+		return err
+	}
+	if len(listed.Objects) == 0 {
+		return fmt.Errorf("class %s has no objects", tunedClass)
+	}
+
+	for _, obj := range listed.Objects {
+		vector, _ := json.Marshal(obj.Vector)
+		body, err := postRaw(ctx, 0, "/v1/graphql", map[string]interface{}{
+			"query": fmt.Sprintf("{Get{%s(nearVector:{vector:%s},limit:1){_additional{id}}}}", tunedClass, vector),
+		})
+		if err != nil {
+			return fmt.Errorf("search %s: %w", tunedClass, err)
+		}
+
+		var res struct {
+			Data struct {
+				Get map[string][]struct {
+					Additional struct {
+						ID string `json:"id"`
+					} `json:"_additional"`
+				} `json:"Get"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(body, &res); err != nil {
+			return err
+		}
+		hits := res.Data.Get[tunedClass]
+		if len(hits) == 0 || hits[0].Additional.ID != obj.ID {
+			return fmt.Errorf("searching %s for the vector of %s found %v", tunedClass, obj.ID, hits)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_diffTuning(t *testing.T) {
+	class := []byte(`{"class":"TunedIndex","vectorIndexConfig":{"ef":64,"dynamicEfMin":90,"maxConnections":16}}`)
+
+	tests := []struct {
+		name  string
+		want  map[string]int
+		wrong []string
+	}{
+		{name: "equal", want: map[string]int{"ef": 64, "maxConnections": 16}},
+		{name: "changed", want: map[string]int{"ef": 48, "dynamicEfMin": 90},
+			wrong: []string{"ef is 64, want 48"}},
+		{name: "missing", want: map[string]int{"vectorCacheMaxObjects": 100000},
+			wrong: []string{"vectorCacheMaxObjects is <nil>, want 100000"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := diffTuning(class, tt.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.wrong) {
+				t.Errorf("diffTuning() = %v, want %v", got, tt.wrong)
+			}
+		})
+	}
+}
+
+func Test_tuningForHop(t *testing.T) {
+	first, second := tuningForHop(0), tuningForHop(1)
+	for setting, value := range first {
+		if second[setting] == value {
+			t.Errorf("%s is %d on both hops", setting, value)
+		}
+	}
+	if first["dynamicEfMin"] >= first["dynamicEfMax"] {
+		t.Errorf("dynamicEfMin %d is not below dynamicEfMax %d", first["dynamicEfMin"], first["dynamicEfMax"])
+	}
+}
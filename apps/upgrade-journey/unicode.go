@@ -0,0 +1,25 @@
+package main
+
+import (
+	"context"
+
+	"github.com/weaviate/weaviate-chaos-engineering/apps/upgrade-journey/pkg/workloads"
+)
+
+// withUnicodeTexts is set by --unicode-texts
+var withUnicodeTexts bool
+
+// unicodeTexts is nil unless --unicode-texts is set
+var unicodeTexts *workloads.Unicode
+
+// unicodeVerifier checks that multilingual and emoji texts are stored as
+// written and that filters on them match the same objects on every version
+type unicodeVerifier struct{}
+
+func (v *unicodeVerifier) name() string {
+	return "unicode"
+}
+
+func (v *unicodeVerifier) verify(ctx context.Context, posOfMaxVersion int) error {
+	return unicodeTexts.Verify(ctx, nodeHost(0))
+}
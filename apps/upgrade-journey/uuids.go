@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const uuidClass = "UUIDProperties"
+
+// the values of the uuid properties, fixed so that filters can name them
+const (
+	uuidA = "7b2a5c1e-0f3d-4e8a-9b6c-1d2e3f4a5b6c"
+	uuidB = "c3d4e5f6-a7b8-4c9d-8e0f-1a2b3c4d5e6f"
+	uuidC = "00000000-0000-4000-8000-000000000001"
+)
+
+// uuidVariants are the objects every hop writes, one of each. "none" has
+// no uuid and an empty uuid[], no filter may match it.
+var uuidVariants = []struct {
+	name string
+	ref  string
+	refs []string
+}{
+	{name: "first", ref: uuidA, refs: []string{uuidA, uuidB}},
+	{name: "second", ref: uuidB, refs: []string{uuidB, uuidC}},
+	{name: "none", refs: []string{}},
+}
+
+var uuidFilters = []arrayFilter{
+	{
+		name:  "ref Equal a",
+		where: fmt.Sprintf(`{path:["ref"],operator:Equal,valueText:%q}`, uuidA),
+		match: []string{"first"},
+	},
+	{
+		name:  "ref Equal c",
+		where: fmt.Sprintf(`{path:["ref"],operator:Equal,valueText:%q}`, uuidC),
+	},
+	{
+		name:  "refs Equal b",
+		where: fmt.Sprintf(`{path:["refs"],operator:Equal,valueText:%q}`, uuidB),
+		match: []string{"first", "second"},
+	},
+	{
+		name:  "refs Equal c",
+		where: fmt.Sprintf(`{path:["refs"],operator:Equal,valueText:%q}`, uuidC),
+		match: []string{"second"},
+	},
+	{
+		name:     "refs ContainsAny a c",
+		where:    fmt.Sprintf(`{path:["refs"],operator:ContainsAny,valueText:[%q,%q]}`, uuidA, uuidC),
+		match:    []string{"first", "second"},
+		contains: true,
+	},
+	{
+		name:     "refs ContainsAll b c",
+		where:    fmt.Sprintf(`{path:["refs"],operator:ContainsAll,valueText:[%q,%q]}`, uuidB, uuidC),
+		match:    []string{"second"},
+		contains: true,
+	},
+}
+
+// uuidProbe writes objects with uuid and uuid[] properties on every hop of
+// a version that has the data type
+var uuidProbe probeImports
+
+// uuidVerifier checks that the uuids read back as written and that
+// equality filters, which newer versions answer from a dedicated index,
+// find exactly the expected objects of all hops
+type uuidVerifier struct{}
+
+func (v *uuidVerifier) name() string {
+	return "uuid properties"
+}
+
+func (v *uuidVerifier) verify(ctx context.Context, posOfMaxVersion int) error {
+	if !supports(versions[posOfMaxVersion], featureUUIDType) {
+		return nil
+	}
+
+	if err := checkUUIDValues(ctx); err != nil {
+		return err
+	}
+
+	var variants []string
+	for _, variant := range uuidVariants {
+		variants = append(variants, variant.name)
+	}
+	contains := supports(versions[posOfMaxVersion], featureContainsFilters)
+	for _, filter := range uuidFilters {
+		if filter.contains && !contains {
+			continue
+		}
+		counts, err := variantCounts(ctx, uuidClass, filter.where)
+		if err != nil {
+			return fmt.Errorf("%s: %w", filter.name, err)
+		}
+		if err := checkArrayFilter(filter, variants, counts, uuidProbe.rounds()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func createUUIDClass(ctx context.Context) error {
+	if _, err := postRaw(ctx, 0, "/v1/schema", map[string]interface{}{
+		"class":      uuidClass,
+		"vectorizer": "none",
+		"properties": []map[string]interface{}{
+			{"name": "variant", "dataType": []string{"text"}},
+			{"name": "ref", "dataType": []string{"uuid"}},
+			{"name": "refs", "dataType": []string{"uuid[]"}},
+		},
+	}); err != nil {
+		return fmt.Errorf("create class %s: %w", uuidClass, err)
+	}
+	return nil
+}
+
+func importUUIDs(ctx context.Context, hop int) error {
+	var objects []map[string]interface{}
+	for i, variant := range uuidVariants {
+		properties := map[string]interface{}{"variant": variant.name, "refs": variant.refs}
+		if variant.ref != "" {
+			properties["ref"] = variant.ref
+		}
+
+		objects = append(objects, map[string]interface{}{
+			"class":      uuidClass,
+			"id":         probeID(uuidClass, hop, i),
+			"vector":     []float32{float32(i), float32(hop), 1},
+			"properties": properties,
+		})
+	}
+	if _, err := postRaw(ctx, 0, "/v1/batch/objects", map[string]interface{}{"objects": objects}); err != nil {
+		return fmt.Errorf("import %s: %w", uuidClass, err)
+	}
+	return nil
+}
+
+// checkUUIDValues checks that the uuids of every object read back as they
+// were written, whichever version wrote them
+func checkUUIDValues(ctx context.Context) error {
+	body, err := postRaw(ctx, 0, "/v1/graphql", map[string]interface{}{
+		"query": fmt.Sprintf(`{Get{%s(limit:10000){variant ref refs}}}`, uuidClass),
+	})
+	if err != nil {
+		return err
+	}
+
+	var res struct {
+		Data struct {
+			Get map[string][]struct {
+				Variant string   `json:"variant"`
+				Ref     string   `json:"ref"`
+				Refs    []string `json:"refs"`
+			} `json:"Get"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return err
+	}
+	if len(res.Errors) > 0 {
+		return fmt.Errorf("graphql: %s", res.Errors[0].Message)
+	}
+
+	for _, hit := range res.Data.Get[uuidClass] {
+		if err := diffUUIDs(hit.Variant, hit.Ref, hit.Refs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// diffUUIDs compares the uuids an object of a variant read back with the
+// ones written
+func diffUUIDs(variant, ref string, refs []string) error {
+	for _, want := range uuidVariants {
+		if want.name != variant {
+			continue
+		}
+		if ref != want.ref || strings.Join(refs, ",") != strings.Join(want.refs, ",") {
+			return fmt.Errorf("%q object of %s: wanted ref %q and refs %v, got %q and %v", variant, uuidClass,
+				want.ref, want.refs, ref, refs)
+		}
+		return nil
+	}
+	return fmt.Errorf("object of %s has unknown variant %q", uuidClass, variant)
+}
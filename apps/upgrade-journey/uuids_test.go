@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func Test_diffUUIDs(t *testing.T) {
+	tests := []struct {
+		name    string
+		variant string
+		ref     string
+		refs    []string
+		wantErr bool
+	}{
+		{name: "as written", variant: "first", ref: uuidA, refs: []string{uuidA, uuidB}},
+		{name: "none", variant: "none"},
+		{name: "lost ref", variant: "first", refs: []string{uuidA, uuidB}, wantErr: true},
+		{name: "reordered refs", variant: "second", ref: uuidB, refs: []string{uuidC, uuidB}, wantErr: true},
+		{name: "unknown variant", variant: "third", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := diffUUIDs(tt.variant, tt.ref, tt.refs)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("diffUUIDs() err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
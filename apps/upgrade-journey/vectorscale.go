@@ -0,0 +1,69 @@
+package main
+
+import "fmt"
+
+// The large vectors variant runs the load with vectors the size of real
+// embeddings, e.g. 1536 or 3072 dimensions instead of the 32 of the default
+// load. Vectors that size dominate the memory of a node, so every hop also
+// checks that the memory of a node grows no faster than the vectors it
+// holds.
+var (
+	// loadDimensions is set by --load-dimensions
+	loadDimensions int
+
+	// loadMemoryFactor is set by --load-memory-factor
+	loadMemoryFactor float64
+
+	// loadImportedFirst is the number of objects the load had imported when
+	// the resources of the first hop were sampled
+	loadImportedFirst int
+)
+
+// validateVectorScale checks the flags of the large vectors variant against
+// the load they configure
+func validateVectorScale(loadObjects int) error {
+	if loadDimensions < 1 {
+		return fmt.Errorf("--load-dimensions must be at least 1, got %d", loadDimensions)
+	}
+	if loadMemoryFactor < 0 {
+		return fmt.Errorf("--load-memory-factor must not be negative")
+	}
+	if loadMemoryFactor > 0 && loadObjects == 0 {
+		return fmt.Errorf("--load-memory-factor needs --load-objects")
+	}
+	return nil
+}
+
+// checkVectorMemory is called once the resources of a hop were sampled. The
+// memory of a node must stay below its memory after the first hop plus
+// --load-memory-factor times the raw size of every vector imported since.
+// The raw size counts all vectors on every node, which leaves room for
+// replication and an uneven spread of the shards.
+func (c *cluster) checkVectorMemory(posOfVersion int) error {
+	if posOfVersion == 0 {
+		loadImportedFirst = bulkLoad.Imported()
+		return nil
+	}
+
+	objects := bulkLoad.Imported() - loadImportedFirst
+	first, cur := runReport.nodeMemory(0), runReport.nodeMemory(posOfVersion)
+	for _, node := range sortedMapKeys(cur) {
+		limit, ok := vectorMemoryLimit(first[node], objects, loadDimensions, loadMemoryFactor)
+		if ok && cur[node] > limit {
+			return fmt.Errorf("memory of %s is %d MiB after %d more vectors of %d dimensions, above %d MiB",
+				node, cur[node]>>20, objects, loadDimensions, limit>>20)
+		}
+	}
+	return nil
+}
+
+// vectorMemoryLimit is the memory a node may use once objects more vectors
+// were imported than after the first hop. It is not ok for a node without a
+// sample of the first hop, which is never compared.
+func vectorMemoryLimit(first uint64, objects, dimensions int, factor float64) (uint64, bool) {
+	if first == 0 {
+		return 0, false
+	}
+	raw := float64(objects) * float64(dimensions) * 4
+	return first + uint64(factor*raw), true
+}
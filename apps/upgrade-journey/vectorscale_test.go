@@ -0,0 +1,30 @@
+package main
+
+import "testing"
+
+func Test_vectorMemoryLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		first      uint64
+		objects    int
+		dimensions int
+		factor     float64
+		want       uint64
+		wantOk     bool
+	}{
+		{name: "no first sample", objects: 1000, dimensions: 1536, factor: 3},
+		{name: "no new vectors", first: 1 << 30, dimensions: 1536, factor: 3, want: 1 << 30, wantOk: true},
+		{
+			name: "openai sized", first: 1 << 30, objects: 100000, dimensions: 1536, factor: 3,
+			want: 1<<30 + 3*100000*1536*4, wantOk: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := vectorMemoryLimit(tt.first, tt.objects, tt.dimensions, tt.factor)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("wanted %d, %t, got %d, %t", tt.want, tt.wantOk, got, ok)
+			}
+		})
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"regexp"
 	"sort"
@@ -18,15 +17,19 @@ import (
 	"github.com/weaviate/weaviate-go-client/v4/weaviate"
 )
 
-func buildVersionList(ctx context.Context, min, target string) ([]string, error) {
+// targetResolver turns the configured target (which may be an image tag
+// such as "preview-abc") into the semver that the target reports
+type targetResolver func(ctx context.Context, target string) (string, error)
+
+func buildVersionList(ctx context.Context, min, target string, resolve targetResolver) ([]string, error) {
 	ghReleases, err := retrieveVersionListFromGH()
 	if err != nil {
 		return nil, err
 	}
 
-	max, err := getTargetVersion(ctx, target)
+	max, err := resolve(ctx, target)
 	if err != nil {
-		log.Fatal(err)
+		return nil, fmt.Errorf("get target version: %w", err)
 	}
 
 	versions := parseSemverList(ghReleases)
@@ -36,6 +39,36 @@ func buildVersionList(ctx context.Context, min, target string) ([]string, error)
 	return append(list, target), nil
 }
 
+// buildLatestVersionList covers the newest releases without a manually
+// maintained minimum: the last n releases, followed by the channel tags
+// (e.g. "latest" or "nightly") in the given order. A target, if set, is
+// always the last hop.
+func buildLatestVersionList(n int, channels []string, target string) ([]string, error) {
+	ghReleases, err := retrieveVersionListFromGH()
+	if err != nil {
+		return nil, err
+	}
+
+	return latestReleasesAndChannels(parseSemverList(ghReleases), n, channels, target), nil
+}
+
+func latestReleasesAndChannels(versions semverList, n int, channels []string, target string) []string {
+	sort.Slice(versions, func(a, b int) bool {
+		return versions[a].less(versions[b])
+	})
+
+	if len(versions) > n {
+		versions = versions[len(versions)-n:]
+	}
+
+	list := append(versions.toStringList(), channels...)
+	if target != "" {
+		list = append(list, target)
+	}
+
+	return list
+}
+
 func retrieveVersionListFromGH() ([]string, error) {
 	// ignore pagination, for now we assume that the first page contains enough
 	// versions. This might require changing in the future and we might have to
@@ -95,35 +128,13 @@ func parseSemverList(input []string) semverList {
 	return out[:i]
 }
 
-type semver struct {
-	version *hashicorpversion.Version
-}
-
-type semverList []semver
-
-func (self semver) largerOrEqual(other semver) bool {
-	return self.version.GreaterThanOrEqual(other.version)
-}
-
-func (self semver) major() int64 {
-	return self.version.Segments64()[0]
-}
-
-func (self semver) minor() int64 {
-	return self.version.Segments64()[1]
-}
-
-func (self semver) patch() int64 {
-	return self.version.Segments64()[2]
-}
-
 func sortSemverAndTrimToMinimum(versions semverList, min, max string) semverList {
 	sort.Slice(versions, func(a, b int) bool {
-		return versions[a].version.LessThan(versions[b].version)
+		return versions[a].less(versions[b])
 	})
 
-	minV := parseSingleSemverWithoutLeadingV(min)
-	maxV := parseSingleSemverWithoutLeadingV(max)
+	minV := mustParseVersion(min)
+	maxV := mustParseVersion(max)
 
 	out := make(semverList, len(versions))
 
@@ -143,39 +154,6 @@ func sortSemverAndTrimToMinimum(versions semverList, min, max string) semverList
 	return out[:i]
 }
 
-func parseSingleSemverWithoutLeadingV(input string) semver {
-	v, ok := maybeParseSingleSemverWithoutLeadingV(input)
-	if !ok {
-		panic("not an acceptable semver")
-	}
-
-	return v
-}
-
-func maybeParseSingleSemverWithoutLeadingVForImport(input string) (semver, bool) {
-	ver, ok := maybeParseSingleSemverWithoutLeadingV(input)
-	if !ok {
-		// let's return a dummy version bc here we got a preview image
-		ver, err := hashicorpversion.NewSemver("0.0.0")
-		if err != nil {
-			panic("cannot parse 0.0.0 dummy version")
-		}
-		return semver{version: ver}, true
-	}
-	return ver, true
-}
-
-func maybeParseSingleSemverWithoutLeadingV(input string) (semver, bool) {
-	ver, err := hashicorpversion.NewSemver(input)
-	if err != nil {
-		return semver{version: nil}, false
-	}
-
-	return semver{
-		version: ver,
-	}, true
-}
-
 func (s semverList) toStringList() []string {
 	out := make([]string, len(s))
 	for i, ver := range s {
@@ -184,8 +162,19 @@ func (s semverList) toStringList() []string {
 	return out
 }
 
+// resolveTargetWithoutDocker is used when Docker must not be touched. A
+// target that is not a semver can't be resolved without starting it, so no
+// upper bound is applied in that case.
+func resolveTargetWithoutDocker(ctx context.Context, target string) (string, error) {
+	if _, ok := parseVersion(target); ok {
+		return target, nil
+	}
+
+	return "999.0.0", nil
+}
+
 func getTargetVersion(ctx context.Context, version string) (string, error) {
-	weaviateImage := fmt.Sprintf("semitechnologies/weaviate:%s", version)
+	weaviateImage := images.image(version)
 	env := map[string]string{
 		"AUTHENTICATION_ANONYMOUS_ACCESS_ENABLED": "true",
 		"LOG_LEVEL":                 "debug",
@@ -194,9 +183,11 @@ func getTargetVersion(ctx context.Context, version string) (string, error) {
 		"DEFAULT_VECTORIZER_MODULE": "none",
 	}
 	req := testcontainers.ContainerRequest{
-		Image:        weaviateImage,
-		ExposedPorts: []string{"8080/tcp"},
-		Env:          env,
+		Image:         weaviateImage,
+		ImagePlatform: images.platform,
+		ExposedPorts:  []string{"8080/tcp"},
+		Env:           env,
+		Labels:        runLabels(),
 		WaitingFor: wait.
 			ForHTTP("/v1/.well-known/ready").
 			WithPort(nat.Port("8080")).
@@ -215,7 +206,7 @@ func getTargetVersion(ctx context.Context, version string) (string, error) {
 	defer func() {
 		err := c.Terminate(ctx)
 		if err != nil {
-			log.Fatal(fmt.Errorf("cannot terminate Weaviate container that gets target version: %w", err))
+			fatal("cannot terminate Weaviate container that gets target version", "err", err)
 		}
 	}()
 	httpUri, err := c.PortEndpoint(ctx, nat.Port("8080/tcp"), "")
@@ -229,9 +220,11 @@ func getTargetVersion(ctx context.Context, version string) (string, error) {
 	}
 	client := weaviate.New(cfg)
 
-	meta, err := client.Misc().MetaGetter().Do(ctx)
+	reqCtx, cancel := requestContext(ctx)
+	meta, err := client.Misc().MetaGetter().Do(reqCtx)
+	cancel()
 	if err != nil {
-		return "", err
+		return "", requestError(ctx, "get meta", err)
 	}
 	return meta.Version, nil
 }
@@ -3,6 +3,8 @@ package main
 import (
 	"reflect"
 	"testing"
+
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
 )
 
 func Test_sortSemverAndTrimToMinimum(t *testing.T) {
@@ -53,3 +55,69 @@ func Test_sortSemverAndTrimToMinimum(t *testing.T) {
 		})
 	}
 }
+
+func Test_imageConfig(t *testing.T) {
+	ic := imageConfig{
+		repo:         "registry.example.com:5000/weaviate",
+		tagTemplate:  "{version}-arm64",
+		finalImage:   "weaviate:pr-1234",
+		finalVersion: "pr-1234",
+	}
+
+	if got, want := ic.image("1.22.3"), "registry.example.com:5000/weaviate:1.22.3-arm64"; got != want {
+		t.Errorf("image() = %s, want %s", got, want)
+	}
+
+	if got, want := ic.image("pr-1234"), "weaviate:pr-1234"; got != want {
+		t.Errorf("image() = %s, want %s", got, want)
+	}
+
+	for image, want := range map[string]string{
+		"weaviate:pr-1234":                          "pr-1234",
+		"registry.example.com:5000/weaviate":        "latest",
+		"registry.example.com:5000/weaviate:1.25.0": "1.25.0",
+	} {
+		if got := tagOf(image); got != want {
+			t.Errorf("tagOf(%s) = %s, want %s", image, got, want)
+		}
+	}
+}
+
+func Test_imageConfigArch(t *testing.T) {
+	ic := imageConfig{repo: "semitechnologies/weaviate", tagTemplate: "{version}-{arch}", platform: "linux/arm64"}
+	if got, want := ic.image("1.25.0"), "semitechnologies/weaviate:1.25.0-arm64"; got != want {
+		t.Errorf("image() = %s, want %s", got, want)
+	}
+
+	for arch, want := range map[string]string{"x86_64": "amd64", "aarch64": "arm64", "arm64": "arm64"} {
+		if got := normalizeArch(arch); got != want {
+			t.Errorf("normalizeArch(%s) = %s, want %s", arch, got, want)
+		}
+	}
+
+	amd64Only := []v1.Platform{{OS: "linux", Architecture: "amd64"}}
+	if platformsInclude(amd64Only, "linux", "arm64") {
+		t.Errorf("platformsInclude() = true for a missing arm64 variant")
+	}
+	if !platformsInclude(amd64Only, "linux", "amd64") || !platformsInclude(nil, "linux", "arm64") {
+		t.Errorf("platformsInclude() = false for an available variant")
+	}
+}
+
+func Test_latestReleasesAndChannels(t *testing.T) {
+	versions := parseSemverList([]string{
+		"v1.24.1", "v1.25.0-rc.0", "v1.23.9", "v1.25.0", "v1.24.0", "v1.25.1",
+	})
+
+	got := latestReleasesAndChannels(versions, 3, []string{"latest", "nightly"}, "")
+	want := []string{"1.24.1", "1.25.0", "1.25.1", "latest", "nightly"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("latestReleasesAndChannels() = %v, want %v", got, want)
+	}
+
+	got = latestReleasesAndChannels(versions, 10, nil, "preview-abc")
+	want = []string{"1.23.9", "1.24.0", "1.24.1", "1.25.0", "1.25.1", "preview-abc"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("latestReleasesAndChannels() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	wcluster "github.com/weaviate/weaviate-chaos-engineering/apps/upgrade-journey/pkg/cluster"
+	"github.com/weaviate/weaviate-chaos-engineering/apps/upgrade-journey/pkg/faults"
+)
+
+const (
+	zoneOutageSample  = 20
+	zoneOutageTimeout = 30 * time.Second
+)
+
+var consistencyLevels = []string{"ONE", "QUORUM", "ALL"}
+
+// withZoneOutage is set by --zone-outage
+var withZoneOutage bool
+
+// replicasNeeded is the number of replicas that have to answer a read with
+// the consistency level
+func replicasNeeded(level string, factor int) int {
+	switch level {
+	case "ONE":
+		return 1
+	case "QUORUM":
+		return factor/2 + 1
+	default:
+		return factor
+	}
+}
+
+// zoneOutage takes down every zone in turn, all of its nodes at once, while
+// the replicated class keeps serving reads through a node in another zone.
+// Every node holds a replica of every object, so whether a read with a
+// consistency level succeeds follows from how many nodes the zone takes
+// with it.
+func zoneOutage(ctx context.Context, c *cluster, hop int) error {
+	zones := c.Zones()
+	if len(zones) < 2 {
+		return fmt.Errorf("zone outage needs at least two zones, got %v", zones)
+	}
+	if replicatedIds == nil {
+		hopLogger(hop).Info("skipping zone outage, replication is not supported yet")
+		return nil
+	}
+
+	for _, zone := range zones {
+		selector, err := wcluster.ParseSelector("zone:" + zone)
+		if err != nil {
+			return err
+		}
+		down, err := selector.Nodes(c.Cluster)
+		if err != nil {
+			return err
+		}
+		serving := firstNodeOutside(c.NodeCount, down)
+		up := c.NodeCount - len(down)
+
+		if err := applyFault(ctx, c, hop, &faults.Down[*cluster]{Nodes: selector}, 0,
+			func(ctx context.Context) error {
+				return checkZoneAvailability(ctx, c, zone, serving, c.NodeCount, up)
+			}); err != nil {
+			return err
+		}
+
+		if err := c.WaitForHealthyNodes(ctx, serving, time.Minute); err != nil {
+			return fmt.Errorf("after outage of zone %s: %w", zone, err)
+		}
+	}
+
+	return nil
+}
+
+func firstNodeOutside(nodeCount int, nodes []int) int {
+	excluded := map[int]bool{}
+	for _, node := range nodes {
+		excluded[node] = true
+	}
+	for i := 0; i < nodeCount; i++ {
+		if !excluded[i] {
+			return i
+		}
+	}
+	return -1
+}
+
+// checkZoneAvailability reads a sample of the replicated objects with every
+// consistency level. A level that up replicas can serve has to succeed
+// within the timeout, which covers the time the cluster needs to notice the
+// outage. A level they can't serve must never succeed.
+func checkZoneAvailability(ctx context.Context, c *cluster, zone string, serving, factor, up int) error {
+	sample := replicatedIds
+	if len(sample) > zoneOutageSample {
+		sample = make([]string, zoneOutageSample)
+		for i := range sample {
+			sample[i] = replicatedIds[rand.Intn(len(replicatedIds))]
+		}
+	}
+
+	for _, level := range consistencyLevels {
+		available := up >= replicasNeeded(level, factor)
+
+		deadline := time.Now().Add(zoneOutageTimeout)
+		for {
+			failed, err := readReplicated(ctx, serving, level, sample)
+			if !available && failed < len(sample) {
+				return fmt.Errorf("zone %s down, %d of %d replicas up: %d reads with %s succeeded, "+
+					"which needs %d replicas", zone, up, factor, len(sample)-failed, level,
+					replicasNeeded(level, factor))
+			}
+			if !available || failed == 0 {
+				break
+			}
+
+			if time.Now().After(deadline) {
+				return fmt.Errorf("zone %s down, %d of %d replicas up: %d of %d reads with %s "+
+					"still fail after %s: %w", zone, up, factor, failed, len(sample), level,
+					zoneOutageTimeout, err)
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Second):
+			}
+		}
+
+		logger.Info("zone outage", "zone", zone, "consistency", level, "replicas_up", up,
+			"factor", factor, "available", available, "node", c.Hostname(serving))
+	}
+
+	return nil
+}
+
+// readReplicated reads the objects through a node and returns how many
+// reads failed, together with the last error
+func readReplicated(ctx context.Context, nodeId int, level string, ids []string) (int, error) {
+	failed := 0
+	var lastErr error
+	for _, id := range ids {
+		if _, err := getRaw(ctx, nodeId, fmt.Sprintf("/v1/objects/%s/%s?consistency_level=%s",
+			replicatedClass, id, level)); err != nil {
+			failed++
+			lastErr = err
+		}
+	}
+	return failed, lastErr
+}
@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestReplicasNeeded(t *testing.T) {
+	tests := []struct {
+		level  string
+		factor int
+		want   int
+	}{
+		{level: "ONE", factor: 3, want: 1},
+		{level: "QUORUM", factor: 3, want: 2},
+		{level: "QUORUM", factor: 4, want: 3},
+		{level: "QUORUM", factor: 5, want: 3},
+		{level: "ALL", factor: 5, want: 5},
+	}
+
+	for _, tt := range tests {
+		if got := replicasNeeded(tt.level, tt.factor); got != tt.want {
+			t.Errorf("%s with factor %d: wanted %d, got %d", tt.level, tt.factor, tt.want, got)
+		}
+	}
+}
+
+func TestFirstNodeOutside(t *testing.T) {
+	if got := firstNodeOutside(3, []int{0, 2}); got != 1 {
+		t.Errorf("wanted 1, got %d", got)
+	}
+	if got := firstNodeOutside(2, []int{0, 1}); got != -1 {
+		t.Errorf("wanted -1, got %d", got)
+	}
+}
@@ -0,0 +1,16 @@
+#!/bin/bash
+
+set -eou pipefail
+
+(
+  cd apps/backup-restore-version-matrix/
+
+  # backs up every version and restores it into every newer one, using the
+  # latest patch release of every minor version from MINIMUM_WEAVIATE_VERSION
+  # to WEAVIATE_VERSION
+
+  # remove any potential leftover data from previous runs
+  rm -rf data report
+
+  go run .
+)
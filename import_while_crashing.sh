@@ -39,7 +39,7 @@ docker run \
 echo "Run import script in foreground..."
 if ! docker run \
   -e 'DIMENSIONS=48' \
-  -e 'SHARDS=1' \
+  -e "SHARDS=${SHARDS:-1}" \
   -e "SIZE=$SIZE" \
   -e 'BATCH_SIZE=128' \
   -e 'ORIGIN=http://localhost:8080' \
@@ -30,6 +30,7 @@ wait_weaviate 8082
 echo "Import schema"
 if ! docker run \
   -e 'ORIGIN=http://localhost:8080' \
+  -e "CONFIG_SHARDS=${SHARDS:-}" \
   --network host \
   -t importer python3 run.py --action schema; then
   echo "Could not apply schema"
@@ -6,7 +6,7 @@ set -eou pipefail
   cd apps/upgrade-journey/
 
   # remove any potential leftover data from previous runs
-  rm -rf data
+  rm -rf data bisect-data artifacts
 
-  go run .
+  go run . "$@"
 )